@@ -12,16 +12,28 @@ import (
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
-	"go.uber.org/zap"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 
 	_ "github.com/SanExpett/diploma/docs/app"
+	"github.com/SanExpett/diploma/internal/clientip"
+	"github.com/SanExpett/diploma/internal/config"
+	"github.com/SanExpett/diploma/internal/cookies"
 	"github.com/SanExpett/diploma/internal/handlers"
+	"github.com/SanExpett/diploma/internal/health"
+	"github.com/SanExpett/diploma/internal/interceptors"
 	"github.com/SanExpett/diploma/internal/metrics"
 	"github.com/SanExpett/diploma/internal/middleware"
+	"github.com/SanExpett/diploma/internal/oidc"
+	"github.com/SanExpett/diploma/internal/rbac"
 	session "github.com/SanExpett/diploma/internal/session/proto"
+	"github.com/SanExpett/diploma/internal/signing"
+	"github.com/SanExpett/diploma/internal/tracing"
+	"github.com/SanExpett/diploma/internal/webauthn"
 	httpSwagger "github.com/swaggo/http-swagger"
 )
 
@@ -44,39 +56,106 @@ import (
 // @in header
 // @name Authorization
 func main() {
-	var (
-		frontEndPort int
-		backEndPort  int
-		serverIP     string
-	)
-	flag.IntVar(&frontEndPort, "f-port", 8080, "front-end server port")
-	flag.IntVar(&backEndPort, "b-port", 8081, "back-end server port")
-	flag.StringVar(&serverIP, "ip", "90.156.218.166", "back-end server port")
-
+	loader := config.NewLoader(flag.CommandLine, config.DefaultGateway)
 	flag.Parse()
 
-	logger, err := zap.NewDevelopment()
+	cfg, err := loader.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	logger, logLevel, err := cfg.Logging.NewLogger()
 	if err != nil {
 		log.Fatal(err)
 	}
 	sugarLogger := logger.Sugar()
 
+	shutdownTracing, err := tracing.Init(context.Background(), "gateway", tracing.Config(cfg.Tracing))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			sugarLogger.Errorf("failed to flush traces: %v", err)
+		}
+	}()
+
+	var keyManager *signing.KeyManager
+	switch cfg.JWT.KeyStore {
+	case config.KeyStoreRedis:
+		keysClient := redis.NewUniversalClient(&redis.UniversalOptions{
+			Addrs:            cfg.Redis.Addrs,
+			Password:         cfg.Redis.Password,
+			DB:               cfg.Redis.DB,
+			MasterName:       cfg.Redis.MasterName,
+			SentinelPassword: cfg.Redis.SentinelPassword,
+			IsClusterMode:    cfg.Redis.Cluster,
+		})
+		defer keysClient.Close()
+		keyManager, err = signing.NewSharedKeyManager(context.Background(), cfg.JWT.Algorithm,
+			cfg.JWT.RotationPeriod, cfg.JWT.GracePeriod, signing.NewRedisKeyStore(keysClient, "signing:keys"))
+	case config.KeyStoreFile:
+		keyManager, err = signing.NewSharedKeyManager(context.Background(), cfg.JWT.Algorithm,
+			cfg.JWT.RotationPeriod, cfg.JWT.GracePeriod, signing.NewFileKeyStore(cfg.JWT.KeyFile))
+	default:
+		keyManager, err = signing.NewKeyManager(cfg.JWT.Algorithm, cfg.JWT.RotationPeriod, cfg.JWT.GracePeriod)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	// токены, подписанные общим секретом до появления ключей с kid, принимаются до конца grace периода
+	if cfg.SecretKey != "" {
+		keyManager.AcceptLegacySecret(cfg.SecretKey)
+	}
+	rotationCtx, stopRotation := context.WithCancel(context.Background())
+	defer stopRotation()
+	keyManager.StartRotation(rotationCtx, func(err error) {
+		sugarLogger.Errorf("failed to rotate signing key: %v", err)
+	})
+
+	// внутренние методы сервисы выполняют только для вызовов с токеном gateway, подписанным его ключом
+	gatewayInterceptor := interceptors.NewGatewayUnaryClientInterceptor(keyManager.Sign)
+
 	// для локального запуска коннектиться по 127.0.0.1, в докере имя контейнера
-	authConn, err := grpc.Dial("sessions:8010", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	// запросы не направляются в сервисы со статусом NOT_SERVING в grpc.health.v1
+	authConn, err := grpc.Dial(cfg.Services.Sessions, grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultServiceConfig(health.ServiceConfig(session.Sessions_ServiceDesc.ServiceName)),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+		grpc.WithChainUnaryInterceptor(interceptors.RequestIdUnaryClientInterceptor,
+			interceptors.PrincipalUnaryClientInterceptor, gatewayInterceptor,
+			interceptors.NewDeadlineUnaryClientInterceptor(cfg.Services.Timeout)))
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	filmsConn, err := grpc.Dial("films:8020", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	filmsConn, err := grpc.Dial(cfg.Services.Films, grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultServiceConfig(health.ServiceConfig(session.Films_ServiceDesc.ServiceName)),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+		grpc.WithChainUnaryInterceptor(interceptors.RequestIdUnaryClientInterceptor,
+			interceptors.PrincipalUnaryClientInterceptor, gatewayInterceptor,
+			interceptors.NewDeadlineUnaryClientInterceptor(cfg.Services.Timeout)))
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	usersConn, err := grpc.Dial("users:8030", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	usersConn, err := grpc.Dial(cfg.Services.Users, grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultServiceConfig(health.ServiceConfig(session.Users_ServiceDesc.ServiceName)),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+		grpc.WithChainUnaryInterceptor(interceptors.RequestIdUnaryClientInterceptor,
+			interceptors.PrincipalUnaryClientInterceptor, gatewayInterceptor, interceptors.AuditUnaryClientInterceptor,
+			interceptors.NewDeadlineUnaryClientInterceptor(cfg.Services.Timeout)))
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	healthCtx, stopHealth := context.WithCancel(context.Background())
+	defer stopHealth()
+	healthWatcher := health.NewWatcher(cfg.Health.Timeout, sugarLogger)
+	healthWatcher.Add("sessions", authConn, session.Sessions_ServiceDesc.ServiceName)
+	healthWatcher.Add("films", filmsConn, session.Films_ServiceDesc.ServiceName)
+	healthWatcher.Add("users", usersConn, session.Users_ServiceDesc.ServiceName)
+	healthWatcher.Start(healthCtx, cfg.Health.Interval)
+
 	httpMetrics := metrics.NewHttpMetrics()
 	httpMetrics.Register()
 
@@ -84,15 +163,93 @@ func main() {
 	usersClient := session.NewUsersClient(usersConn)
 	sessionClient := session.NewSessionsClient(authConn)
 
-	middleware := middleware.NewMiddleware(httpMetrics, sugarLogger, serverIP)
-	authPageHandlers := handlers.NewAuthPageHandlers(&usersClient, &sessionClient, httpMetrics, sugarLogger)
-	usersPageHandlers := handlers.NewUserPageHandlers(&usersClient, &sessionClient, httpMetrics, sugarLogger)
+	policy := rbac.NewCachedPolicy(rbac.NewUsersClientLoader(&usersClient), time.Minute)
+
+	securityHeaders, err := middleware.SecurityHeadersProfile(cfg.Environment)
+	if err != nil {
+		log.Fatal(err)
+	}
+	securityHeadersMiddleware := middleware.SecurityHeadersMiddleware(securityHeaders)
+	tracingMiddleware := middleware.TracingMiddleware("gateway")
+	trustedProxies, err := clientip.ParseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		log.Fatal(err)
+	}
+	clientIPResolver := clientip.NewResolver(trustedProxies)
+	cookieConfig, err := cfg.CookieConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	cookieFactory := cookies.NewFactory(cookieConfig)
+
+	rateLimits, err := middleware.LoadRateLimits(cfg.RateLimits.Path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var rateLimitClient redis.UniversalClient
+	if len(cfg.RateLimits.Redis) > 0 {
+		// короткие таймауты: пока Redis недоступен, лимиты считаются в памяти, а не задерживают запросы
+		rateLimitClient = redis.NewUniversalClient(&redis.UniversalOptions{
+			Addrs:        cfg.RateLimits.Redis,
+			DialTimeout:  200 * time.Millisecond,
+			ReadTimeout:  100 * time.Millisecond,
+			WriteTimeout: 100 * time.Millisecond,
+		})
+		defer rateLimitClient.Close()
+	}
+	rateLimiter := middleware.NewRateLimiter(rateLimits, rateLimitClient)
+
+	// по SIGHUP без перезапуска применяются только уровень журнала и лимиты запросов
+	reloadCtx, stopReload := context.WithCancel(context.Background())
+	defer stopReload()
+	loader.StartReload(reloadCtx, func(reloaded config.Gateway) {
+		err := reloaded.Logging.SetLevel(logLevel)
+		if err != nil {
+			sugarLogger.Errorf("failed to apply log level: %v", err)
+		}
+		limits, err := middleware.LoadRateLimits(reloaded.RateLimits.Path)
+		if err != nil {
+			sugarLogger.Errorf("failed to reload rate limits: %v", err)
+			return
+		}
+		rateLimiter.SetLimits(limits)
+		sugarLogger.Infof("config reloaded")
+	}, func(err error) {
+		sugarLogger.Errorf("failed to reload config: %v", err)
+	})
+
+	oidcConfigs, err := oidc.LoadConfig(cfg.OIDCConfigPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	oidcRegistry := oidc.NewRegistry(oidcConfigs, &http.Client{Timeout: 10 * time.Second})
+
+	middleware := middleware.NewMiddleware(&sessionClient, &usersClient, keyManager, policy, httpMetrics, sugarLogger,
+		cfg.Server.IP, cfg.FrontendOrigin)
+	authPageHandlers := handlers.NewAuthPageHandlers(&usersClient, &sessionClient, keyManager, cookieFactory,
+		httpMetrics, sugarLogger)
+	usersPageHandlers := handlers.NewUserPageHandlers(&usersClient, &sessionClient, keyManager, cookieFactory,
+		httpMetrics, sugarLogger)
+	oidcHandlers := handlers.NewOIDCHandlers(authPageHandlers, oidcRegistry)
+	deviceHandlers := handlers.NewDeviceHandlers(authPageHandlers, cfg.DeviceVerifyURL)
+	magicLinkHandlers := handlers.NewMagicLinkHandlers(authPageHandlers, cfg.MagicLinkURL)
+	passkeyHandlers := handlers.NewPasskeyHandlers(authPageHandlers, webauthn.NewRelyingParty(webauthn.Config{
+		RPID:             cfg.WebAuthn.RPID,
+		RPName:           "Nimbus",
+		Origin:           cfg.WebAuthn.Origin,
+		AllowCounterless: cfg.WebAuthn.AllowCounterless,
+	}))
 	filmsPageHandlers := handlers.NewFilmsPageHandlers(&filmsClient, httpMetrics, sugarLogger)
+	auditHandlers := handlers.NewAuditHandlers(&usersClient, httpMetrics, sugarLogger)
 
 	// router := mux.NewRouter().Schemes("http").Subrouter()
 	router := mux.NewRouter()
 
-	router.Handle("/metrics", promhttp.Handler())
+	// exemplars с trace_id отдаются только в формате OpenMetrics
+	router.Handle("/metrics", promhttp.HandlerFor(prometheus.DefaultGatherer,
+		promhttp.HandlerOpts{EnableOpenMetrics: true}))
+	router.HandleFunc("/healthz", health.Liveness).Methods("GET")
+	router.HandleFunc("/readyz", healthWatcher.Readiness).Methods("GET")
 
 	// Swagger endpoint
 	router.PathPrefix("/swagger/").Handler(httpSwagger.Handler(
@@ -103,51 +260,123 @@ func main() {
 	))
 
 	router.HandleFunc("/api/auth/login", authPageHandlers.Login).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/auth/login/2fa", authPageHandlers.LoginTwoFactor).Methods("POST", "OPTIONS")
 	router.HandleFunc("/api/auth/logout", authPageHandlers.Logout).Methods("POST", "OPTIONS")
 	router.HandleFunc("/api/auth/signup", authPageHandlers.Signup).Methods("POST", "OPTIONS")
 	router.HandleFunc("/api/auth/check", authPageHandlers.Check).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/auth/refresh", authPageHandlers.Refresh).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/auth/csrf", authPageHandlers.CsrfToken).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/auth/jwks", authPageHandlers.JWKS).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/auth/sessions",
+		middleware.AuthMiddleware(authPageHandlers.Sessions)).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/auth/sessions/revoke_others",
+		middleware.AuthMiddleware(authPageHandlers.RevokeOtherSessions)).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/auth/sessions/{id}/revoke",
+		middleware.AuthMiddleware(authPageHandlers.RevokeSession)).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/auth/password/forgot", authPageHandlers.ForgotPassword).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/auth/password/reset", authPageHandlers.ResetPassword).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/auth/unknown-login", authPageHandlers.ReportUnknownLogin).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/auth/email/verify", authPageHandlers.VerifyEmail).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/auth/email/resend",
+		middleware.AuthMiddleware(authPageHandlers.ResendEmailVerification)).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/auth/2fa/enroll",
+		middleware.AuthMiddleware(authPageHandlers.EnrollTOTP)).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/auth/2fa/confirm",
+		middleware.AuthMiddleware(authPageHandlers.ConfirmTOTP)).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/auth/2fa/disable",
+		middleware.AuthMiddleware(authPageHandlers.DisableTOTP)).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/auth/oidc/providers", oidcHandlers.Providers).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/auth/oidc/{provider}/login", oidcHandlers.Login).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/auth/oidc/{provider}/link",
+		middleware.AuthMiddleware(oidcHandlers.Link)).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/auth/oidc/{provider}/callback", oidcHandlers.Callback).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/auth/magic-link", magicLinkHandlers.Request).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/auth/magic-link/login", magicLinkHandlers.Login).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/auth/passkey/register/begin",
+		middleware.AuthMiddleware(passkeyHandlers.RegisterBegin)).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/auth/passkey/register/finish",
+		middleware.AuthMiddleware(passkeyHandlers.RegisterFinish)).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/auth/passkey/login/begin", passkeyHandlers.LoginBegin).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/auth/passkey/login/finish", passkeyHandlers.LoginFinish).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/auth/device/code", deviceHandlers.Code).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/auth/device/token", deviceHandlers.Token).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/auth/device", middleware.AuthMiddleware(deviceHandlers.Info)).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/auth/device/approve",
+		middleware.AuthMiddleware(deviceHandlers.Approve)).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/auth/unlock",
+		middleware.AuthMiddleware(middleware.RequirePermission(rbac.PermissionUsersUnlock,
+			authPageHandlers.UnlockLogin))).Methods("POST", "OPTIONS")
+	router.HandleFunc("/.well-known/jwks.json", authPageHandlers.JWKS).Methods("GET", "OPTIONS")
 
 	router.HandleFunc("/api/films/all", filmsPageHandlers.GetAllFilmsPreviews).Methods("GET", "OPTIONS")
 	router.HandleFunc("/api/films/all_sub", filmsPageHandlers.GetFilmsPreviewsWithSub).Methods("GET", "OPTIONS")
 	router.HandleFunc("/api/films/{uuid}/data", filmsPageHandlers.GetFilmDataByUuid).Methods("GET", "OPTIONS")
 	router.HandleFunc("/api/films/{uuid}/actors", filmsPageHandlers.GetActorsByFilm).Methods("GET", "OPTIONS")
-	router.HandleFunc("/api/films/put_favorite", filmsPageHandlers.PutFavoriteFilm).Methods("POST", "OPTIONS")
-	router.HandleFunc("/api/films/remove_favorite", filmsPageHandlers.RemoveFavoriteFilm).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/films/put_favorite",
+		middleware.AuthMiddleware(filmsPageHandlers.PutFavoriteFilm)).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/films/remove_favorite",
+		middleware.AuthMiddleware(filmsPageHandlers.RemoveFavoriteFilm)).Methods("POST", "OPTIONS")
 	router.HandleFunc("/api/films/{uuid}/all_favorite", filmsPageHandlers.GetAllFavoriteFilms).Methods("GET", "OPTIONS")
 	router.HandleFunc("/api/films/find/short", filmsPageHandlers.ShortSearch).Methods("GET", "OPTIONS")
 	router.HandleFunc("/api/films/find/long", filmsPageHandlers.LongSearch).Methods("GET", "OPTIONS")
 	router.HandleFunc("/api/films/genres/{uuid}/all", filmsPageHandlers.GetAllFilmsByGenre).Methods("GET", "OPTIONS")
 	router.HandleFunc("/api/films/genres/preview", filmsPageHandlers.GetAllGenres).Methods("GET", "OPTIONS")
-	router.HandleFunc("/api/films/add", filmsPageHandlers.AddFilm).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/films/add", middleware.AuthMiddleware(
+		middleware.RequirePermission(rbac.PermissionFilmsManage, filmsPageHandlers.AddFilm))).Methods("POST", "OPTIONS")
 	router.HandleFunc("/api/films/top", filmsPageHandlers.GetTopFilms).Methods("GET", "OPTIONS")
-	router.HandleFunc("/api/films/comments/add", filmsPageHandlers.AddComment).Methods("POST", "OPTIONS")
-	router.HandleFunc("/api/films/comments/remove", filmsPageHandlers.RemoveComment).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/films/comments/add", middleware.AuthMiddleware(
+		middleware.RequireVerifiedEmail(filmsPageHandlers.AddComment))).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/films/comments/remove",
+		middleware.AuthMiddleware(filmsPageHandlers.RemoveComment)).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/films/comments/moderate/remove",
+		middleware.AuthMiddleware(middleware.RequirePermission(rbac.PermissionCommentsModerate,
+			filmsPageHandlers.ModerateRemoveComment))).Methods("POST", "OPTIONS")
 	router.HandleFunc("/api/films/{uuid}/comments", filmsPageHandlers.GetAllFilmComments).Methods("GET", "OPTIONS")
 
-	router.HandleFunc("/api/films/add_subscriptions", filmsPageHandlers.AddSubscriptions).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/films/add_subscriptions",
+		middleware.AuthMiddleware(middleware.RequirePermission(rbac.PermissionSubscriptionsManage,
+			filmsPageHandlers.AddSubscriptions(cfg.Postgres.DSN())))).Methods("POST", "OPTIONS")
 
+	router.HandleFunc("/api/profile/passkeys",
+		middleware.AuthMiddleware(passkeyHandlers.List)).Methods("GET", "OPTIONS")
+	router.HandleFunc("/api/profile/passkeys/{id}",
+		middleware.AuthMiddleware(passkeyHandlers.Remove)).Methods("DELETE", "OPTIONS")
 	router.HandleFunc("/api/profile/{uuid}/data", usersPageHandlers.GetProfileData).Methods("GET", "OPTIONS")
-	router.HandleFunc("/api/profile/{uuid}/edit", usersPageHandlers.ProfileEditByUuid).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/profile/{uuid}/edit",
+		middleware.AuthMiddleware(usersPageHandlers.ProfileEditByUuid)).Methods("POST", "OPTIONS")
 	router.HandleFunc("/api/profile/{uuid}/preview", usersPageHandlers.GetProfilePreview).Methods("GET", "OPTIONS")
 	router.HandleFunc("/api/profile/{uuid}/subscriptions/check",
 		usersPageHandlers.HasSubscription).Methods("POST", "OPTIONS")
 	router.HandleFunc("/api/profile/remove",
-		usersPageHandlers.RemoveUser).Methods("POST", "OPTIONS")
-	router.HandleFunc("/api/profile/{uuid}/subscriptions/pay",
-		usersPageHandlers.PaySubscription).Methods("POST", "OPTIONS")
+		middleware.AuthMiddleware(usersPageHandlers.RemoveUser)).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/admin/users/remove",
+		middleware.AuthMiddleware(middleware.RequirePermission(rbac.PermissionUsersRemove,
+			usersPageHandlers.AdminRemoveUser))).Methods("POST", "OPTIONS")
+	router.HandleFunc("/api/profile/{uuid}/subscriptions/pay", middleware.AuthMiddleware(
+		middleware.RequireVerifiedEmail(usersPageHandlers.PaySubscription))).Methods("POST", "OPTIONS")
 	router.HandleFunc("/api/subscriptions/get", usersPageHandlers.GetSubscriptions).Methods("GET", "OPTIONS")
 
+	router.HandleFunc("/api/admin/audit",
+		middleware.AuthMiddleware(middleware.RequirePermission(rbac.PermissionAuditRead,
+			auditHandlers.Events))).Methods("GET", "OPTIONS")
+
 	router.HandleFunc("/api/films",
 		middleware.AuthMiddleware(filmsPageHandlers.GetAllFilmsPreviews)).Methods("GET", "OPTIONS")
 	router.HandleFunc("/api/actors/{uuid}/data", filmsPageHandlers.GetActorByUuid).Methods("GET", "OPTIONS")
 
 	router.Use(middleware.CorsMiddleware)
+	router.Use(securityHeadersMiddleware)
 	router.Use(middleware.PanicMiddleware)
+	router.Use(tracingMiddleware)
+	router.Use(clientIPResolver.Middleware)
 	router.Use(middleware.AccessLogMiddleware)
+	router.Use(middleware.RateLimitMiddleware(rateLimiter))
+	// телевизоры при входе по коду не имеют cookie и не могут получить токен CSRF
+	router.Use(middleware.CsrfMiddleware("/api/auth/device/code", "/api/auth/device/token"))
 
 	server := &http.Server{
 		Handler: router,
-		Addr:    fmt.Sprintf(":%d", backEndPort),
+		Addr:    fmt.Sprintf(":%d", cfg.Server.Port),
 	}
 
 	stopped := make(chan struct{})
@@ -163,7 +392,7 @@ func main() {
 		}
 	}()
 
-	fmt.Printf("Starting server at %s%s\n", "localhost", fmt.Sprintf(":%d", backEndPort))
+	fmt.Printf("Starting server at %s%s\n", "localhost", fmt.Sprintf(":%d", cfg.Server.Port))
 
 	if err := server.ListenAndServe(); err != http.ErrServerClosed {
 		log.Fatal(err)
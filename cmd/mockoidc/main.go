@@ -0,0 +1,40 @@
+// mockoidc локальный OpenID Connect провайдер для ручной проверки входа через провайдеров.
+// Gateway настраивается на него файлом -oidc-config с issuer, равным -issuer
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/SanExpett/diploma/internal/oidc/oidctest"
+)
+
+func main() {
+	var (
+		addr         string
+		issuer       string
+		clientID     string
+		clientSecret string
+		user         oidctest.User
+	)
+	flag.StringVar(&addr, "addr", ":8090", "listen address")
+	flag.StringVar(&issuer, "issuer", "http://localhost:8090", "issuer, must be the address the gateway reaches")
+	flag.StringVar(&clientID, "client-id", "nimbus", "client id")
+	flag.StringVar(&clientSecret, "client-secret", "secret", "client secret")
+	flag.StringVar(&user.Subject, "sub", "mock-subject", "subject of the signed in user")
+	flag.StringVar(&user.Email, "email", "mock@example.com", "email of the signed in user")
+	flag.BoolVar(&user.EmailVerified, "email-verified", true, "whether the provider verified the email")
+	flag.StringVar(&user.Name, "name", "Mock", "name of the signed in user")
+	flag.Parse()
+
+	provider, err := oidctest.New(issuer, clientID, clientSecret)
+	if err != nil {
+		log.Fatal(err)
+	}
+	provider.SetUser(user)
+
+	fmt.Printf("Starting mock oidc provider at %s, issuer %s\n", addr, issuer)
+	log.Fatal(http.ListenAndServe(addr, provider))
+}
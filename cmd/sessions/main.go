@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
@@ -9,67 +10,169 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
-	"go.uber.org/zap"
+	"github.com/redis/go-redis/extra/redisotel/v9"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
 
+	"github.com/SanExpett/diploma/internal/config"
+	"github.com/SanExpett/diploma/internal/health"
+	"github.com/SanExpett/diploma/internal/interceptors"
 	"github.com/SanExpett/diploma/internal/metrics"
+	"github.com/SanExpett/diploma/internal/rbac"
 	session "github.com/SanExpett/diploma/internal/session/proto"
 	"github.com/SanExpett/diploma/internal/sessions/api"
 	mycache "github.com/SanExpett/diploma/internal/sessions/repository/cache"
+	"github.com/SanExpett/diploma/internal/sessions/repository/memory"
 	"github.com/SanExpett/diploma/internal/sessions/service"
+	"github.com/SanExpett/diploma/internal/signing"
+	"github.com/SanExpett/diploma/internal/tracing"
 	"github.com/gorilla/mux"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 func main() {
-	var (
-		frontEndPort int
-		backEndPort  int
-		serverIP     string
-	)
-	flag.IntVar(&frontEndPort, "f-port", 8080, "front-end server port")
-	flag.IntVar(&backEndPort, "b-port", 8010, "back-end server port")
-	flag.StringVar(&serverIP, "ip", "94.139.247.246", "back-end server port")
-
+	loader := config.NewLoader(flag.CommandLine, config.DefaultSessions)
 	flag.Parse()
 
-	logger, err := zap.NewDevelopment()
+	cfg, err := loader.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	logger, logLevel, err := cfg.Logging.NewLogger()
 	if err != nil {
 		log.Fatal(err)
 	}
 	sugarLogger := logger.Sugar()
 
-	cacheStorage := mycache.NewSessionStorage()
+	reloadCtx, stopReload := context.WithCancel(context.Background())
+	defer stopReload()
+	loader.StartReload(reloadCtx, func(reloaded config.Sessions) {
+		err := reloaded.Logging.SetLevel(logLevel)
+		if err != nil {
+			sugarLogger.Errorf("failed to apply log level: %v", err)
+		}
+	}, func(err error) {
+		sugarLogger.Errorf("failed to reload config: %v", err)
+	})
+
+	shutdownTracing, err := tracing.Init(context.Background(), "sessions", tracing.Config(cfg.Tracing))
 	if err != nil {
 		log.Fatal(err)
 	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			sugarLogger.Errorf("failed to flush traces: %v", err)
+		}
+	}()
 
 	grpcMetrics := metrics.NewGrpcMetrics("auth")
 	grpcMetrics.Register()
+	loginMetrics := metrics.NewLoginMetrics()
+	loginMetrics.Register()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// хранилище в памяти всегда доступно, поэтому проверяется только Redis
+	healthChecks := make(map[string]health.Check)
+	var sessionService *service.SessionService
+	switch cfg.Storage.Type {
+	case "redis":
+		redisClient := mycache.NewRedisClient(mycache.RedisOptions(cfg.Redis))
+		defer redisClient.Close()
+		err = redisotel.InstrumentTracing(redisClient)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		healthChecks["redis"] = func(ctx context.Context) error {
+			return redisClient.Ping(ctx).Err()
+		}
+
+		cacheStorage := mycache.NewSessionStorage(redisClient, cfg.Storage.TTL)
+		migrated, err := cacheStorage.MigrateLegacySessions(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if migrated > 0 {
+			sugarLogger.Infof("migrated sessions of %d users", migrated)
+		}
+
+		sessionService = service.NewSessionService(cacheStorage, grpcMetrics, loginMetrics, sugarLogger)
+	case "memory":
+		memoryStorage, err := memory.NewSessionStorage(cfg.Storage.TTL, cfg.Storage.SnapshotPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		memoryStorage.StartCleanup(ctx, cfg.Storage.CleanupInterval, func(err error) {
+			sugarLogger.Errorf("failed to save sessions snapshot: %v", err)
+		})
+		defer func() {
+			if err := memoryStorage.SaveSnapshot(); err != nil {
+				sugarLogger.Errorf("failed to save sessions snapshot: %v", err)
+			}
+		}()
+
+		sessionService = service.NewSessionService(memoryStorage, grpcMetrics, loginMetrics, sugarLogger)
+	default:
+		log.Fatalf("unknown session storage %q", cfg.Storage.Type)
+	}
+
+	healthCtx, stopHealth := context.WithCancel(context.Background())
+	defer stopHealth()
+	healthReporter := health.NewReporter(session.Sessions_ServiceDesc.ServiceName, healthChecks,
+		cfg.Health.Timeout, sugarLogger)
+	healthReporter.Start(healthCtx, cfg.Health.Interval)
 
 	go func() {
 		router := mux.NewRouter()
 
 		router.Handle("/metrics", promhttp.Handler())
+		router.HandleFunc("/healthz", health.Liveness)
+		router.HandleFunc("/readyz", healthReporter.Readiness)
 
 		metricsServer := &http.Server{
 			Handler: router,
-			Addr:    fmt.Sprintf(":%d", backEndPort+1),
+			Addr:    fmt.Sprintf(":%d", cfg.Server.Port+1),
 		}
 		if err := metricsServer.ListenAndServe(); err != http.ErrServerClosed {
 			log.Fatal(err)
 		}
-		fmt.Printf("Starting metrics server at %s%s\n", "localhost", fmt.Sprintf(":%d", backEndPort+1))
+		fmt.Printf("Starting metrics server at %s%s\n", "localhost", fmt.Sprintf(":%d", cfg.Server.Port+1))
 	}()
 
-	sessionService := service.NewSessionService(cacheStorage, grpcMetrics, sugarLogger)
+	// пользователь восстанавливается из access токена, подпись проверяется ключами gateway
+	keySet := signing.NewRemoteKeySet(cfg.JWKSURL, &http.Client{Timeout: cfg.Health.Timeout})
 
-	s := grpc.NewServer()
+	// политика ролей хранится в базе пользователей, у сервиса сессий нет к ней доступа
+	usersConn, err := grpc.Dial(cfg.UsersAddr, grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultServiceConfig(health.ServiceConfig(session.Users_ServiceDesc.ServiceName)),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+		grpc.WithChainUnaryInterceptor(interceptors.RequestIdUnaryClientInterceptor,
+			interceptors.NewDeadlineUnaryClientInterceptor(cfg.CallTimeout)))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer usersConn.Close()
+	usersClient := session.NewUsersClient(usersConn)
+	policy := rbac.NewCachedPolicy(rbac.NewUsersClientLoader(&usersClient), time.Minute)
+
+	s := grpc.NewServer(grpc.StatsHandler(otelgrpc.NewServerHandler()), grpc.ChainUnaryInterceptor(
+		interceptors.RequestIdUnaryServerInterceptor,
+		interceptors.NewDeadlineUnaryServerInterceptor(cfg.CallTimeout),
+		interceptors.NewPrincipalUnaryServerInterceptor(keySet.Keyfunc),
+		interceptors.NewGatewayUnaryServerInterceptor(keySet.Keyfunc),
+		interceptors.NewRbacUnaryServerInterceptor(policy, rbac.MethodPermissions, rbac.MethodOwners, sugarLogger),
+	))
 	srv := api.NewSessionServer(sessionService, sugarLogger)
 	session.RegisterSessionsServer(s, srv)
+	healthReporter.Register(s)
 
-	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", backEndPort))
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.Server.Port))
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -80,12 +183,13 @@ func main() {
 		sigint := make(chan os.Signal, 1)
 		signal.Notify(sigint, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 		<-sigint
+		healthReporter.Shutdown()
 		if err := listener.Close(); err != nil {
 			fmt.Printf("Server shutdown error: %v\n", err)
 		}
 	}()
 
-	fmt.Printf("Starting server at %s%s\n", "localhost", fmt.Sprintf(":%d", backEndPort))
+	fmt.Printf("Starting server at %s%s\n", "localhost", fmt.Sprintf(":%d", cfg.Server.Port))
 
 	err = s.Serve(listener)
 	if err != nil {
@@ -1,41 +1,17 @@
 package hepler
 
-import "os"
+import (
+	"os"
+	"path/filepath"
+)
 
-func InitUploads() error {
-	storagePath := "./uploads/"
-	_, err := os.Stat(storagePath)
-	if err != nil {
-		err = os.Mkdir(storagePath, 0755)
+// InitUploads создает каталог загрузок dir с подкаталогами для аватаров и файлов фильмов
+func InitUploads(dir string) error {
+	for _, path := range []string{dir, filepath.Join(dir, "users"), filepath.Join(dir, "films")} {
+		err := os.MkdirAll(path, 0755)
 		if err != nil {
 			return err
 		}
-		err = os.Mkdir(storagePath+"users/", 0755)
-		if err != nil {
-			return err
-		}
-		err = os.Mkdir(storagePath+"films/", 0755)
-		if err != nil {
-			return err
-		}
-	} else {
-		storagePath = "./uploads/users/"
-		_, err = os.Stat(storagePath)
-		if err != nil {
-			err = os.Mkdir(storagePath, 0755)
-			if err != nil {
-				return err
-			}
-		}
-
-		storagePath := "./uploads/films/"
-		_, err = os.Stat(storagePath)
-		if err != nil {
-			err = os.Mkdir(storagePath, 0755)
-			if err != nil {
-				return err
-			}
-		}
 	}
 
 	return nil
@@ -10,53 +10,77 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
-	"go.uber.org/zap"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
 
 	helper "github.com/SanExpett/diploma/cmd"
+	"github.com/SanExpett/diploma/internal/config"
+	"github.com/SanExpett/diploma/internal/health"
+	"github.com/SanExpett/diploma/internal/interceptors"
+	"github.com/SanExpett/diploma/internal/mailer"
 	"github.com/SanExpett/diploma/internal/metrics"
+	"github.com/SanExpett/diploma/internal/rbac"
+	rbacRepository "github.com/SanExpett/diploma/internal/rbac/repository"
 	session "github.com/SanExpett/diploma/internal/session/proto"
+	"github.com/SanExpett/diploma/internal/signing"
+	"github.com/SanExpett/diploma/internal/tracing"
 	"github.com/SanExpett/diploma/internal/users/api"
 	"github.com/SanExpett/diploma/internal/users/repository"
 	"github.com/SanExpett/diploma/internal/users/service"
 )
 
 func main() {
-	var (
-		frontEndPort int
-		backEndPort  int
-		serverIP     string
-	)
-	flag.IntVar(&frontEndPort, "f-port", 8080, "front-end server port")
-	flag.IntVar(&backEndPort, "b-port", 8030, "back-end server port")
-	flag.StringVar(&serverIP, "ip", "90.156.218.166", "back-end server port")
-
+	loader := config.NewLoader(flag.CommandLine, config.DefaultUsers)
 	flag.Parse()
 
-	err := helper.InitUploads()
+	cfg, err := loader.Load()
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	logger, err := zap.NewDevelopment()
+	err = helper.InitUploads(cfg.UploadsDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	logger, logLevel, err := cfg.Logging.NewLogger()
 	if err != nil {
 		log.Fatal(err)
 	}
 	sugarLogger := logger.Sugar()
 
-	// для локального запуска коннектиться по 127.0.0.1, в докере имя контейнера
-	pool, err := pgxpool.New(context.Background(), fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		"postgres",
-		"5432",
-		"postgres",
-		"postgres",
-		"nimbus",
-	))
+	reloadCtx, stopReload := context.WithCancel(context.Background())
+	defer stopReload()
+	loader.StartReload(reloadCtx, func(reloaded config.Users) {
+		err := reloaded.Logging.SetLevel(logLevel)
+		if err != nil {
+			sugarLogger.Errorf("failed to apply log level: %v", err)
+		}
+	}, func(err error) {
+		sugarLogger.Errorf("failed to reload config: %v", err)
+	})
+
+	shutdownTracing, err := tracing.Init(context.Background(), "users", tracing.Config(cfg.Tracing))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			sugarLogger.Errorf("failed to flush traces: %v", err)
+		}
+	}()
+
+	poolConfig, err := pgxpool.ParseConfig(cfg.Postgres.DSN())
+	if err != nil {
+		log.Fatal(err)
+	}
+	poolConfig.ConnConfig.Tracer = tracing.NewQueryTracer()
+	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -66,6 +90,12 @@ func main() {
 		log.Fatal(err)
 	}
 
+	healthCtx, stopHealth := context.WithCancel(context.Background())
+	defer stopHealth()
+	healthReporter := health.NewReporter(session.Users_ServiceDesc.ServiceName,
+		map[string]health.Check{"postgres": pool.Ping}, cfg.Health.Timeout, sugarLogger)
+	healthReporter.Start(healthCtx, cfg.Health.Interval)
+
 	grpcMetrics := metrics.NewGrpcMetrics("users")
 	grpcMetrics.Register()
 
@@ -73,24 +103,50 @@ func main() {
 		router := mux.NewRouter()
 
 		router.Handle("/metrics", promhttp.Handler())
+		router.HandleFunc("/healthz", health.Liveness)
+		router.HandleFunc("/readyz", healthReporter.Readiness)
 
 		metricsServer := &http.Server{
 			Handler: router,
-			Addr:    fmt.Sprintf(":%d", backEndPort+1),
+			Addr:    fmt.Sprintf(":%d", cfg.Server.Port+1),
 		}
 		if err := metricsServer.ListenAndServe(); err != http.ErrServerClosed {
 			log.Fatal(err)
 		}
-		fmt.Printf("Starting metrics server at %s%s\n", "localhost", fmt.Sprintf(":%d", backEndPort+1))
+		fmt.Printf("Starting metrics server at %s%s\n", "localhost", fmt.Sprintf(":%d", cfg.Server.Port+1))
 	}()
 
-	usersService := service.NewUsersService(usersStorage, grpcMetrics, sugarLogger)
+	usersMailer, err := mailer.New(mailer.Config(cfg.Mailer), sugarLogger.Infof)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	usersService := service.NewUsersService(usersStorage, usersMailer, cfg.Links.ResetURL, cfg.Links.VerifyURL,
+		cfg.Links.UnknownLoginURL,
+		grpcMetrics, sugarLogger)
+
+	retentionCtx, stopRetention := context.WithCancel(context.Background())
+	defer stopRetention()
+	usersService.StartAuditRetention(retentionCtx, cfg.AuditRetention, time.Hour)
 
-	s := grpc.NewServer()
+	policy := rbac.NewCachedPolicy(rbacRepository.NewRbacStorage(pool), time.Minute)
+
+	// пользователь восстанавливается из access токена, подпись проверяется ключами gateway
+	keySet := signing.NewRemoteKeySet(cfg.JWKSURL, &http.Client{Timeout: cfg.Health.Timeout})
+
+	s := grpc.NewServer(grpc.StatsHandler(otelgrpc.NewServerHandler()), grpc.ChainUnaryInterceptor(
+		interceptors.RequestIdUnaryServerInterceptor,
+		interceptors.NewDeadlineUnaryServerInterceptor(cfg.CallTimeout),
+		interceptors.NewPrincipalUnaryServerInterceptor(keySet.Keyfunc),
+		interceptors.NewGatewayUnaryServerInterceptor(keySet.Keyfunc),
+		interceptors.AuditUnaryServerInterceptor,
+		interceptors.NewRbacUnaryServerInterceptor(policy, rbac.MethodPermissions, rbac.MethodOwners, sugarLogger),
+	))
 	srv := api.NewUsersServer(usersService, sugarLogger)
 	session.RegisterUsersServer(s, srv)
+	healthReporter.Register(s)
 
-	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", backEndPort))
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.Server.Port))
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -101,12 +157,13 @@ func main() {
 		sigint := make(chan os.Signal, 1)
 		signal.Notify(sigint, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 		<-sigint
+		healthReporter.Shutdown()
 		if err := listener.Close(); err != nil {
 			fmt.Printf("Server shutdown error: %v\n", err)
 		}
 	}()
 
-	fmt.Printf("Starting server at %s%s\n", "localhost", fmt.Sprintf(":%d", backEndPort))
+	fmt.Printf("Starting server at %s%s\n", "localhost", fmt.Sprintf(":%d", cfg.Server.Port))
 
 	err = s.Serve(listener)
 	if err != nil {
@@ -0,0 +1,44 @@
+CREATE TABLE IF NOT EXISTS role
+(
+    name TEXT PRIMARY KEY
+);
+
+CREATE TABLE IF NOT EXISTS role_permission
+(
+    role       TEXT NOT NULL,
+    permission TEXT NOT NULL,
+    PRIMARY KEY (role, permission),
+    FOREIGN KEY (role) REFERENCES role (name) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS user_role
+(
+    user_id INTEGER NOT NULL,
+    role    TEXT    NOT NULL,
+    PRIMARY KEY (user_id, role),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (role) REFERENCES role (name) ON DELETE CASCADE
+);
+
+INSERT INTO role (name)
+VALUES ('admin'),
+       ('content_editor'),
+       ('moderator'),
+       ('support')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_permission (role, permission)
+VALUES ('admin', 'films.manage'),
+       ('admin', 'subscriptions.manage'),
+       ('admin', 'users.remove'),
+       ('admin', 'comments.moderate'),
+       ('content_editor', 'films.manage'),
+       ('moderator', 'comments.moderate'),
+       ('support', 'users.remove')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO user_role (user_id, role)
+SELECT id, 'admin'
+FROM users
+WHERE is_admin
+ON CONFLICT DO NOTHING;
@@ -0,0 +1 @@
+ALTER TABLE users ADD CONSTRAINT users_password_check CHECK (LENGTH(password) <= 64) NOT VALID;
@@ -0,0 +1,2 @@
+DELETE FROM role_permission
+WHERE permission = 'users.unlock';
@@ -0,0 +1,4 @@
+INSERT INTO role_permission (role, permission)
+VALUES ('admin', 'users.unlock'),
+       ('support', 'users.unlock')
+ON CONFLICT DO NOTHING;
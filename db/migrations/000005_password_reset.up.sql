@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS password_reset_token
+(
+    token_hash TEXT PRIMARY KEY,
+    user_id    INTEGER     NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS password_reset_token_user_id_idx ON password_reset_token (user_id);
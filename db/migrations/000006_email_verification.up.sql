@@ -0,0 +1,15 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMPTZ;
+
+-- пользователи, зарегистрированные до появления подтверждения почты, считаются подтвержденными
+UPDATE users SET email_verified_at = registered_at WHERE email_verified_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS email_verification_token
+(
+    token_hash TEXT PRIMARY KEY,
+    user_id    INTEGER     NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS email_verification_token_user_id_idx ON email_verification_token (user_id);
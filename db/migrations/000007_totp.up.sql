@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS user_totp
+(
+    user_id        INTEGER PRIMARY KEY,
+    secret         TEXT   NOT NULL,
+    confirmed_at   TIMESTAMPTZ,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+-- коды восстановления удаляются вместе с отключением второго фактора
+CREATE TABLE IF NOT EXISTS totp_recovery_code
+(
+    user_id   INTEGER NOT NULL,
+    code_hash TEXT    NOT NULL,
+    PRIMARY KEY (user_id, code_hash),
+    FOREIGN KEY (user_id) REFERENCES user_totp (user_id) ON DELETE CASCADE
+);
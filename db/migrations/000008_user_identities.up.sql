@@ -0,0 +1,14 @@
+-- внешние аккаунты OpenID Connect провайдеров, через которые пользователь может входить.
+-- subject уникален только в пределах провайдера
+CREATE TABLE IF NOT EXISTS user_identities
+(
+    provider   TEXT        NOT NULL,
+    subject    TEXT        NOT NULL,
+    user_id    INTEGER     NOT NULL,
+    email      TEXT        NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (provider, subject),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS user_identities_user_id_idx ON user_identities (user_id);
@@ -0,0 +1,8 @@
+-- одна действующая ссылка для входа на пользователя: новая ссылка заменяет предыдущую
+CREATE TABLE IF NOT EXISTS magic_link_token
+(
+    user_id    INTEGER PRIMARY KEY,
+    token_hash TEXT        NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
@@ -0,0 +1,16 @@
+-- passkey пользователей. id идентификатор credential в base64url, public_key открытый ключ в формате COSE_Key,
+-- sign_count счетчик подписей аутентификатора, по которому обнаруживаются клоны
+CREATE TABLE IF NOT EXISTS webauthn_credentials
+(
+    id           TEXT PRIMARY KEY,
+    user_id      INTEGER     NOT NULL,
+    public_key   BYTEA       NOT NULL,
+    sign_count   BIGINT      NOT NULL DEFAULT 0,
+    transports   TEXT[]      NOT NULL DEFAULT '{}',
+    name         TEXT        NOT NULL DEFAULT '',
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMPTZ,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS webauthn_credentials_user_id_idx ON webauthn_credentials (user_id);
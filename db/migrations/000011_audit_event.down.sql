@@ -0,0 +1,5 @@
+DELETE FROM role_permission
+WHERE permission = 'audit.read';
+
+DROP TABLE IF EXISTS audit_event;
+DROP FUNCTION IF EXISTS audit_event_forbid_update();
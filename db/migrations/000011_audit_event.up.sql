@@ -0,0 +1,36 @@
+-- журнал событий безопасности. actor и target хранятся email, а не ссылкой на users, чтобы записи
+-- переживали удаление аккаунта. Записи только добавляются, удаляет их только очистка по сроку хранения
+CREATE TABLE IF NOT EXISTS audit_event
+(
+    id          BIGSERIAL PRIMARY KEY,
+    occurred_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    action      TEXT        NOT NULL,
+    actor       TEXT        NOT NULL DEFAULT '',
+    target      TEXT        NOT NULL DEFAULT '',
+    ip          TEXT        NOT NULL DEFAULT '',
+    user_agent  TEXT        NOT NULL DEFAULT '',
+    request_id  TEXT        NOT NULL DEFAULT '',
+    details     TEXT        NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS audit_event_occurred_at_idx ON audit_event (occurred_at);
+CREATE INDEX IF NOT EXISTS audit_event_actor_idx ON audit_event (actor, id);
+CREATE INDEX IF NOT EXISTS audit_event_target_idx ON audit_event (target, id);
+
+CREATE OR REPLACE FUNCTION audit_event_forbid_update() RETURNS TRIGGER AS
+$$
+BEGIN
+    RAISE EXCEPTION 'audit_event is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_event_forbid_update
+    BEFORE UPDATE
+    ON audit_event
+    FOR EACH ROW
+EXECUTE FUNCTION audit_event_forbid_update();
+
+INSERT INTO role_permission (role, permission)
+VALUES ('admin', 'audit.read'),
+       ('support', 'audit.read')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS unknown_login_token;
+DROP TABLE IF EXISTS known_device;
@@ -0,0 +1,24 @@
+-- устройства, с которых пользователь уже входил. fingerprint хеш user agent и сети, из которой пришел запрос
+CREATE TABLE IF NOT EXISTS known_device
+(
+    user_id       INTEGER     NOT NULL,
+    fingerprint   TEXT        NOT NULL,
+    user_agent    TEXT        NOT NULL DEFAULT '',
+    ip            TEXT        NOT NULL DEFAULT '',
+    first_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_seen_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, fingerprint),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+-- ссылки «это был не я» из писем о входе с нового устройства
+CREATE TABLE IF NOT EXISTS unknown_login_token
+(
+    token_hash  TEXT PRIMARY KEY,
+    user_id     INTEGER     NOT NULL,
+    fingerprint TEXT        NOT NULL,
+    expires_at  TIMESTAMPTZ NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS unknown_login_token_user_id_idx ON unknown_login_token (user_id);
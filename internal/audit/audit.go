@@ -0,0 +1,67 @@
+// Package audit описывает события журнала безопасности и данные запроса, которые в него попадают.
+// Gateway кладет данные запроса в контекст, а interceptors передают их сервисам в метаданных gRPC
+package audit
+
+import (
+	"context"
+	"time"
+
+	"github.com/SanExpett/diploma/internal/domain"
+	"github.com/SanExpett/diploma/internal/principal"
+)
+
+type Action string
+
+const (
+	ActionLoginSucceeded       Action = "login.succeeded"
+	ActionLoginFailed          Action = "login.failed"
+	ActionLogout               Action = "logout"
+	ActionPasswordChanged      Action = "password.changed"
+	ActionPasswordReset        Action = "password.reset"
+	ActionUsernameChanged      Action = "username.changed"
+	ActionSubscriptionPaid     Action = "subscription.paid"
+	ActionAccountRemoved       Action = "account.removed"
+	ActionUnknownLoginReported Action = "login.reported"
+)
+
+type contextKey string
+
+const RequestInfoKey contextKey = "audit_request_info"
+
+// RequestInfo данные HTTP запроса, из-за которого произошло событие
+type RequestInfo struct {
+	Ip        string
+	UserAgent string
+	RequestId string
+}
+
+// WithRequestInfo возвращает копию контекста с данными запроса
+func WithRequestInfo(ctx context.Context, info RequestInfo) context.Context {
+	return context.WithValue(ctx, RequestInfoKey, info)
+}
+
+// RequestInfoFromContext достает данные запроса, положенные WithRequestInfo
+func RequestInfoFromContext(ctx context.Context) (RequestInfo, bool) {
+	info, ok := ctx.Value(RequestInfoKey).(RequestInfo)
+	return info, ok
+}
+
+// NewEvent создает событие над аккаунтом target. Автором считается пользователь из контекста,
+// для входа, где его еще нет, Actor заполняет вызывающий
+func NewEvent(ctx context.Context, action Action, target, details string) domain.AuditEvent {
+	event := domain.AuditEvent{
+		OccurredAt: time.Now(),
+		Action:     string(action),
+		Target:     target,
+		Details:    details,
+	}
+	if userPrincipal, ok := principal.FromContext(ctx); ok {
+		event.Actor = userPrincipal.Login
+	}
+	if info, ok := RequestInfoFromContext(ctx); ok {
+		event.Ip = info.Ip
+		event.UserAgent = info.UserAgent
+		event.RequestId = info.RequestId
+	}
+	return event
+}
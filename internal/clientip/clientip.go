@@ -0,0 +1,119 @@
+package clientip
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+type contextKey string
+
+const ipKey contextKey = "client-ip"
+
+// ParseTrustedProxies разбирает адреса балансировщиков перед gateway. Адрес без маски означает один хост
+func ParseTrustedProxies(proxies []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(proxies))
+	for _, proxy := range proxies {
+		prefix, err := netip.ParsePrefix(proxy)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(proxy)
+			if addrErr != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// Resolver определяет адрес клиента. X-Forwarded-For и X-Real-IP учитываются, только если запрос пришел
+// от доверенного балансировщика, иначе клиент мог бы подменить адрес и обойти блокировку входа и лимиты
+type Resolver struct {
+	trustedProxies []netip.Prefix
+}
+
+func NewResolver(trustedProxies []netip.Prefix) *Resolver {
+	return &Resolver{
+		trustedProxies: trustedProxies,
+	}
+}
+
+// ClientIP возвращает адрес клиента. В X-Forwarded-For берется самый правый адрес, который не принадлежит
+// доверенным балансировщикам: адреса левее него мог дописать сам клиент
+func (resolver *Resolver) ClientIP(r *http.Request) string {
+	remoteAddr := remoteIP(r)
+	if !resolver.trusted(remoteAddr) {
+		return remoteAddr
+	}
+
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	if len(hops) == 0 {
+		realIP, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP")))
+		if err != nil {
+			return remoteAddr
+		}
+		return realIP.Unmap().String()
+	}
+
+	clientIP := remoteAddr
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		clientIP = hop.Unmap().String()
+		if !resolver.trusted(clientIP) {
+			break
+		}
+	}
+	return clientIP
+}
+
+// Middleware сохраняет адрес клиента в контексте запроса для FromRequest
+func (resolver *Resolver) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), ipKey, resolver.ClientIP(r))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// FromRequest возвращает адрес, определенный Middleware, а без него адрес соединения
+func FromRequest(r *http.Request) string {
+	ip, ok := r.Context().Value(ipKey).(string)
+	if ok {
+		return ip
+	}
+	return remoteIP(r)
+}
+
+func (resolver *Resolver) trusted(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	for _, prefix := range resolver.trustedProxies {
+		if prefix.Contains(addr.Unmap()) {
+			return true
+		}
+	}
+	return false
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return host
+	}
+	return addr.Unmap().String()
+}
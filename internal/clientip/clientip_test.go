@@ -0,0 +1,84 @@
+package clientip
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolver_ClientIP(t *testing.T) {
+	trustedProxies, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.0.2.10"})
+	require.NoError(t, err)
+	resolver := NewResolver(trustedProxies)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		expected   string
+	}{
+		{
+			name:       "Прямое подключение",
+			remoteAddr: "203.0.113.5:1234",
+			expected:   "203.0.113.5",
+		},
+		{
+			name:       "Клиент подделывает заголовки",
+			remoteAddr: "203.0.113.5:1234",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.1", "X-Real-IP": "198.51.100.2"},
+			expected:   "203.0.113.5",
+		},
+		{
+			name:       "Запрос через балансировщик",
+			remoteAddr: "10.0.0.2:1234",
+			headers:    map[string]string{"X-Forwarded-For": "203.0.113.5"},
+			expected:   "203.0.113.5",
+		},
+		{
+			name:       "Клиент дописал адрес перед своим",
+			remoteAddr: "10.0.0.2:1234",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.1, 203.0.113.5, 192.0.2.10"},
+			expected:   "203.0.113.5",
+		},
+		{
+			name:       "Некорректный адрес в цепочке",
+			remoteAddr: "10.0.0.2:1234",
+			headers:    map[string]string{"X-Forwarded-For": "garbage, 10.0.0.3"},
+			expected:   "10.0.0.3",
+		},
+		{
+			name:       "X-Real-IP от балансировщика",
+			remoteAddr: "192.0.2.10:1234",
+			headers:    map[string]string{"X-Real-IP": "203.0.113.5"},
+			expected:   "203.0.113.5",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for key, value := range tt.headers {
+				r.Header.Set(key, value)
+			}
+
+			assert.Equal(t, tt.expected, resolver.ClientIP(r))
+
+			var got string
+			resolver.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = FromRequest(r)
+			})).ServeHTTP(httptest.NewRecorder(), r)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	_, err := ParseTrustedProxies([]string{"10.0.0.0/33"})
+	assert.Error(t, err)
+	_, err = ParseTrustedProxies([]string{"proxy"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,154 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/SanExpett/diploma/internal/tracing"
+)
+
+// Server порты сервиса
+type Server struct {
+	IP        string `yaml:"ip" env:"NIMBUS_IP" flag:"ip" usage:"public server address"`
+	FrontPort int    `yaml:"front_port" flag:"f-port" usage:"front-end server port"`
+	// Port порт сервиса, метрики отдаются на следующем
+	Port int `yaml:"port" env:"NIMBUS_PORT" flag:"b-port" usage:"back-end server port"`
+}
+
+func (server Server) validate() error {
+	if server.Port <= 0 || server.Port >= 65535 {
+		return fmt.Errorf("port %d is out of range", server.Port)
+	}
+	if server.FrontPort <= 0 || server.FrontPort > 65535 {
+		return fmt.Errorf("front-end port %d is out of range", server.FrontPort)
+	}
+	return nil
+}
+
+// Logging журнал сервиса. Level перечитывается без перезапуска
+type Logging struct {
+	Level string `yaml:"level" env:"NIMBUS_LOG_LEVEL" flag:"log-level" usage:"log level: debug, info, warn or error"`
+}
+
+func (logging Logging) validate() error {
+	_, err := zapcore.ParseLevel(logging.Level)
+	return err
+}
+
+// NewLogger создает журнал сервиса. Уровень журнала меняется через возвращаемый zap.AtomicLevel
+func (logging Logging) NewLogger() (*zap.Logger, zap.AtomicLevel, error) {
+	level, err := zap.ParseAtomicLevel(logging.Level)
+	if err != nil {
+		return nil, level, err
+	}
+	loggerConfig := zap.NewDevelopmentConfig()
+	loggerConfig.Level = level
+	logger, err := loggerConfig.Build()
+	return logger, level, err
+}
+
+// SetLevel применяет уровень из конфигурации к журналу, созданному NewLogger
+func (logging Logging) SetLevel(level zap.AtomicLevel) error {
+	return level.UnmarshalText([]byte(logging.Level))
+}
+
+// Postgres подключение к базе. Пароль не задается флагом, чтобы не попадать в список процессов
+type Postgres struct {
+	Host     string `yaml:"host" env:"POSTGRES_HOST" flag:"postgres-host" usage:"postgres host"`
+	Port     int    `yaml:"port" env:"POSTGRES_PORT" flag:"postgres-port" usage:"postgres port"`
+	User     string `yaml:"user" env:"POSTGRES_USER" flag:"postgres-user" usage:"postgres user"`
+	Password string `yaml:"password" env:"POSTGRES_PASSWORD"`
+	Database string `yaml:"database" env:"POSTGRES_DB" flag:"postgres-db" usage:"postgres database"`
+	SSLMode  string `yaml:"sslmode" env:"POSTGRES_SSLMODE" flag:"postgres-sslmode" usage:"postgres sslmode"`
+}
+
+func (postgres Postgres) validate() error {
+	if postgres.Host == "" || postgres.User == "" || postgres.Database == "" {
+		return errors.New("postgres host, user and database are required")
+	}
+	if postgres.Port <= 0 || postgres.Port > 65535 {
+		return fmt.Errorf("postgres port %d is out of range", postgres.Port)
+	}
+	return nil
+}
+
+// DSN строка подключения для pgx
+func (postgres Postgres) DSN() string {
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(postgres.User, postgres.Password),
+		Host:     net.JoinHostPort(postgres.Host, strconv.Itoa(postgres.Port)),
+		Path:     "/" + postgres.Database,
+		RawQuery: url.Values{"sslmode": {postgres.SSLMode}}.Encode(),
+	}
+	return dsn.String()
+}
+
+// Redis подключение к Redis. С MasterName Addrs считаются адресами Sentinel
+type Redis struct {
+	Addrs            []string `yaml:"addrs" env:"REDIS_ADDRS" flag:"redis-addrs" usage:"redis addresses"`
+	Password         string   `yaml:"password" env:"REDIS_PASSWORD"`
+	DB               int      `yaml:"db" flag:"redis-db" usage:"redis database"`
+	MasterName       string   `yaml:"master_name" flag:"redis-master" usage:"sentinel master name"`
+	SentinelPassword string   `yaml:"sentinel_password" env:"REDIS_SENTINEL_PASSWORD"`
+	Cluster          bool     `yaml:"cluster" flag:"redis-cluster" usage:"connect to redis cluster"`
+}
+
+func (redis Redis) validate() error {
+	if len(redis.Addrs) == 0 {
+		return errors.New("redis addresses are required")
+	}
+	return nil
+}
+
+// Tracing экспорт трейсов, поля совпадают с tracing.Config
+type Tracing struct {
+	Exporter    string  `yaml:"exporter" env:"NIMBUS_TRACE_EXPORTER" flag:"trace-exporter" usage:"none, stdout or otlp"`
+	Endpoint    string  `yaml:"endpoint" flag:"trace-endpoint" usage:"OTLP collector address"`
+	SampleRatio float64 `yaml:"sample_ratio" flag:"trace-sample" usage:"share of new traces to keep"`
+}
+
+func (tracingConfig Tracing) validate() error {
+	switch tracingConfig.Exporter {
+	case tracing.ExporterNone, tracing.ExporterStdout:
+	case tracing.ExporterOTLP:
+		if tracingConfig.Endpoint == "" {
+			return errors.New("otlp trace exporter requires an endpoint")
+		}
+	default:
+		return fmt.Errorf("unknown trace exporter %q", tracingConfig.Exporter)
+	}
+	if tracingConfig.SampleRatio < 0 || tracingConfig.SampleRatio > 1 {
+		return fmt.Errorf("trace sample ratio %v is out of [0, 1]", tracingConfig.SampleRatio)
+	}
+	return nil
+}
+
+// Health проверки готовности сервиса и его зависимостей
+type Health struct {
+	Interval time.Duration `yaml:"interval" flag:"health-interval" usage:"how often dependencies are checked"`
+	Timeout  time.Duration `yaml:"timeout" flag:"health-timeout" usage:"timeout of a single health check"`
+}
+
+func (health Health) validate() error {
+	if health.Interval <= 0 || health.Timeout <= 0 {
+		return errors.New("health interval and timeout must be positive")
+	}
+	return nil
+}
+
+// validateURL проверяет, что value абсолютный http(s) адрес
+func validateURL(name, value string) error {
+	parsed, err := url.Parse(value)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("%s %q must be an absolute http(s) url", name, value)
+	}
+	return nil
+}
@@ -0,0 +1,259 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"reflect"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PathEnv переменная окружения с путем к YAML файлу конфигурации, флаг -config ее перекрывает
+const PathEnv = "NIMBUS_CONFIG"
+
+// Validator конфигурация сервиса, которая проверяет свои значения
+type Validator interface {
+	Validate() error
+}
+
+// Loader собирает конфигурацию сервиса. Источники перекрывают друг друга в порядке: значения по умолчанию,
+// YAML файл, переменные окружения, флаги командной строки. Поля описываются тегами: yaml ключ в файле,
+// env переменная окружения, flag имя флага и usage его описание. Вместо переменной X можно задать X_FILE
+// с путем к файлу, из которого читается значение: так передаются секреты
+type Loader[T Validator] struct {
+	defaults func() T
+	path     string
+	flags    map[string]*flagValue
+}
+
+// NewLoader регистрирует в flagSet флаг -config и флаги полей конфигурации. defaults возвращает
+// конфигурацию по умолчанию и вызывается при каждой загрузке
+func NewLoader[T Validator](flagSet *flag.FlagSet, defaults func() T) *Loader[T] {
+	loader := &Loader[T]{
+		defaults: defaults,
+		flags:    make(map[string]*flagValue),
+	}
+	flagSet.StringVar(&loader.path, "config", os.Getenv(PathEnv), "YAML configuration file")
+
+	config := defaults()
+	// теги проверяются тестами, поэтому ошибка здесь невозможна
+	_ = walkFields(reflect.ValueOf(&config).Elem(), func(field reflect.Value, tag reflect.StructTag) error {
+		name := tag.Get("flag")
+		if name == "" {
+			return nil
+		}
+		value := &flagValue{fieldType: field.Type(), defaultValue: formatValue(field)}
+		flagSet.Var(value, name, tag.Get("usage"))
+		loader.flags[name] = value
+		return nil
+	})
+
+	return loader
+}
+
+// Load собирает и проверяет конфигурацию. Вызывается после разбора флагов
+func (loader *Loader[T]) Load() (T, error) {
+	config := loader.defaults()
+
+	if loader.path != "" {
+		data, err := os.ReadFile(loader.path)
+		if err != nil {
+			return config, fmt.Errorf("failed to read config: %w", err)
+		}
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		// опечатка в ключе иначе молча оставила бы значение по умолчанию
+		decoder.KnownFields(true)
+		err = decoder.Decode(&config)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return config, fmt.Errorf("failed to parse config %s: %w", loader.path, err)
+		}
+	}
+
+	err := walkFields(reflect.ValueOf(&config).Elem(), func(field reflect.Value, tag reflect.StructTag) error {
+		if name := tag.Get("env"); name != "" {
+			raw, ok, err := lookupEnv(name)
+			if err != nil {
+				return err
+			}
+			if ok {
+				err = setValue(field, raw)
+				if err != nil {
+					return fmt.Errorf("env %s: %w", name, err)
+				}
+			}
+		}
+		if value, ok := loader.flags[tag.Get("flag")]; ok && value.set {
+			err := setValue(field, value.raw)
+			if err != nil {
+				return fmt.Errorf("flag -%s: %w", tag.Get("flag"), err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return config, err
+	}
+
+	err = config.Validate()
+	if err != nil {
+		return config, fmt.Errorf("invalid config: %w", err)
+	}
+	return config, nil
+}
+
+// StartReload перечитывает конфигурацию по SIGHUP и передает ее apply. Конфигурация с ошибкой не применяется,
+// сервис продолжает работать с прежней. apply должен менять только настройки, безопасные без перезапуска
+func (loader *Loader[T]) StartReload(ctx context.Context, apply func(T), onError func(error)) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+
+	go func() {
+		defer signal.Stop(signals)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-signals:
+				config, err := loader.Load()
+				if err != nil {
+					onError(err)
+					continue
+				}
+				apply(config)
+			}
+		}
+	}()
+}
+
+// walkFields обходит поля структуры и вложенных в нее разделов
+func walkFields(value reflect.Value, visit func(field reflect.Value, tag reflect.StructTag) error) error {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Field(i)
+		structField := value.Type().Field(i)
+		if field.Kind() == reflect.Struct {
+			err := walkFields(field, visit)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		err := visit(field, structField.Tag)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lookupEnv читает переменную name или файл из name_FILE. Перевод строки в конце файла отбрасывается
+func lookupEnv(name string) (string, bool, error) {
+	if path, ok := os.LookupEnv(name + "_FILE"); ok {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", false, fmt.Errorf("failed to read %s_FILE: %w", name, err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), true, nil
+	}
+	raw, ok := os.LookupEnv(name)
+	return raw, ok, nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// setValue записывает в поле значение из окружения или флага. Списки задаются через запятую
+func setValue(field reflect.Value, raw string) error {
+	if field.Type() == durationType {
+		duration, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(duration))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Bool:
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		field.SetBool(value)
+	case reflect.Int:
+		value, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(value))
+	case reflect.Float64:
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(value)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported config field type %s", field.Type())
+		}
+		var values []string
+		for _, value := range strings.Split(raw, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+		field.Set(reflect.ValueOf(values))
+	default:
+		return fmt.Errorf("unsupported config field type %s", field.Type())
+	}
+	return nil
+}
+
+func formatValue(field reflect.Value) string {
+	if field.Type() == durationType {
+		return time.Duration(field.Int()).String()
+	}
+	if field.Kind() == reflect.Slice {
+		return strings.Join(field.Interface().([]string), ",")
+	}
+	return fmt.Sprint(field.Interface())
+}
+
+// flagValue запоминает значение флага, чтобы применить его поверх файла и окружения
+type flagValue struct {
+	fieldType    reflect.Type
+	defaultValue string
+	raw          string
+	set          bool
+}
+
+func (value *flagValue) String() string {
+	if value.set {
+		return value.raw
+	}
+	return value.defaultValue
+}
+
+func (value *flagValue) Set(raw string) error {
+	err := setValue(reflect.New(value.fieldType).Elem(), raw)
+	if err != nil {
+		return err
+	}
+	value.raw = raw
+	value.set = true
+	return nil
+}
+
+func (value *flagValue) IsBoolFlag() bool {
+	return value.fieldType.Kind() == reflect.Bool
+}
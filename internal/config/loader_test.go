@@ -0,0 +1,164 @@
+package config
+
+import (
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestLoader_Load(t *testing.T) {
+	tests := []struct {
+		name     string
+		yaml     string
+		env      map[string]string
+		args     []string
+		expected func(config *Films)
+		wantErr  bool
+	}{
+		{
+			name:     "Значения по умолчанию",
+			expected: func(config *Films) {},
+		},
+		{
+			name: "Файл перекрывает значения по умолчанию",
+			yaml: "server:\n  port: 9020\npostgres:\n  host: db\n",
+			expected: func(config *Films) {
+				config.Server.Port = 9020
+				config.Postgres.Host = "db"
+			},
+		},
+		{
+			name: "Окружение перекрывает файл",
+			yaml: "server:\n  port: 9020\nlogging:\n  level: info\n",
+			env:  map[string]string{"NIMBUS_PORT": "9030", "POSTGRES_PASSWORD": "secret"},
+			expected: func(config *Films) {
+				config.Server.Port = 9030
+				config.Logging.Level = "info"
+				config.Postgres.Password = "secret"
+			},
+		},
+		{
+			name: "Флаг перекрывает окружение",
+			env:  map[string]string{"NIMBUS_PORT": "9030", "NIMBUS_UPLOADS_DIR": "/data"},
+			args: []string{"-b-port", "9040", "-call-timeout", "3s"},
+			expected: func(config *Films) {
+				config.Server.Port = 9040
+				config.UploadsDir = "/data"
+				config.CallTimeout = 3 * time.Second
+			},
+		},
+		{
+			name:    "Неизвестный ключ в файле",
+			yaml:    "server:\n  prot: 9020\n",
+			wantErr: true,
+		},
+		{
+			name:    "Некорректное значение окружения",
+			env:     map[string]string{"NIMBUS_PORT": "port"},
+			wantErr: true,
+		},
+		{
+			name:    "Конфигурация не проходит проверку",
+			args:    []string{"-trace-exporter", "jaeger"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			args := tt.args
+			if tt.yaml != "" {
+				args = append([]string{"-config", writeFile(t, "films.yaml", tt.yaml)}, args...)
+			}
+			flagSet := flag.NewFlagSet("films", flag.ContinueOnError)
+			loader := NewLoader(flagSet, DefaultFilms)
+			require.NoError(t, flagSet.Parse(args))
+
+			config, err := loader.Load()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			expected := DefaultFilms()
+			tt.expected(&expected)
+			assert.Equal(t, expected, config)
+		})
+	}
+}
+
+func TestLoader_SecretFromFile(t *testing.T) {
+	t.Setenv("SECRETKEY_FILE", writeFile(t, "secret", "from-file\n"))
+	t.Setenv("POSTGRES_PASSWORD_FILE", filepath.Join(t.TempDir(), "missing"))
+
+	flagSet := flag.NewFlagSet("app", flag.ContinueOnError)
+	loader := NewLoader(flagSet, DefaultGateway)
+	require.NoError(t, flagSet.Parse(nil))
+	_, err := loader.Load()
+	assert.Error(t, err)
+
+	os.Unsetenv("POSTGRES_PASSWORD_FILE")
+	config, err := loader.Load()
+	require.NoError(t, err)
+	assert.Equal(t, "from-file", config.SecretKey)
+}
+
+func TestDefaults(t *testing.T) {
+	configs := []Validator{DefaultGateway(), DefaultFilms(), DefaultUsers(), DefaultSessions()}
+	for _, config := range configs {
+		assert.NoError(t, config.Validate())
+
+		value := reflect.New(reflect.TypeOf(config)).Elem()
+		err := walkFields(value, func(field reflect.Value, tag reflect.StructTag) error {
+			if tag.Get("flag") == "" && tag.Get("env") == "" {
+				return nil
+			}
+			return setValue(field, formatValue(field))
+		})
+		assert.NoError(t, err, "%T", config)
+	}
+}
+
+func TestLoader_StartReload(t *testing.T) {
+	path := writeFile(t, "sessions.yaml", "logging:\n  level: debug\n")
+	flagSet := flag.NewFlagSet("sessions", flag.ContinueOnError)
+	loader := NewLoader(flagSet, DefaultSessions)
+	require.NoError(t, flagSet.Parse([]string{"-config", path}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reloaded := make(chan Sessions, 1)
+	loader.StartReload(ctx, func(config Sessions) {
+		reloaded <- config
+	}, func(err error) {
+		t.Error(err)
+	})
+
+	require.NoError(t, os.WriteFile(path, []byte("logging:\n  level: warn\n"), 0600))
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGHUP))
+
+	select {
+	case config := <-reloaded:
+		assert.Equal(t, "warn", config.Logging.Level)
+	case <-time.After(5 * time.Second):
+		t.Fatal("config was not reloaded")
+	}
+}
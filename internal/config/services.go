@@ -0,0 +1,432 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/SanExpett/diploma/internal/clientip"
+	"github.com/SanExpett/diploma/internal/cookies"
+	"github.com/SanExpett/diploma/internal/middleware"
+	"github.com/SanExpett/diploma/internal/signing"
+	"github.com/SanExpett/diploma/internal/tracing"
+)
+
+// JWT подписание access токенов
+type JWT struct {
+	Algorithm      string        `yaml:"algorithm" flag:"jwt-alg" usage:"token signing algorithm: EdDSA or RS256"`
+	RotationPeriod time.Duration `yaml:"rotation_period" flag:"jwt-rotation" usage:"signing key rotation period"`
+	// GracePeriod должен быть не меньше времени жизни access токена
+	GracePeriod time.Duration `yaml:"grace_period" flag:"jwt-grace" usage:"how long retired signing keys verify tokens"`
+	// KeyStore где хранятся ключи подписи. В памяти ключи теряются при перезапуске и не общие для реплик
+	KeyStore string `yaml:"key_store" flag:"jwt-key-store" usage:"where signing keys are kept: redis, file or memory"`
+	KeyFile  string `yaml:"key_file" flag:"jwt-key-file" usage:"signing keys file with -jwt-key-store=file"`
+}
+
+const (
+	KeyStoreRedis  = "redis"
+	KeyStoreFile   = "file"
+	KeyStoreMemory = "memory"
+)
+
+// WebAuthn параметры входа по ключам доступа
+type WebAuthn struct {
+	RPID   string `yaml:"rp_id" flag:"webauthn-rp-id" usage:"domain that passkeys are bound to"`
+	Origin string `yaml:"origin" flag:"webauthn-origin" usage:"frontend origin that runs passkey ceremonies"`
+	// AllowCounterless разрешает ключи без счетчика подписей, например синхронизируемые passkey
+	AllowCounterless bool `yaml:"allow_counterless" flag:"webauthn-counterless" usage:"allow passkeys without counter"`
+}
+
+// Cookies политика cookie, разбирается в cookies.Config
+type Cookies struct {
+	Domain   string `yaml:"domain" flag:"cookie-domain" usage:"cookie domain, empty binds cookies to the gateway host"`
+	Secure   bool   `yaml:"secure" flag:"cookie-secure" usage:"send cookies over https only"`
+	SameSite string `yaml:"samesite" flag:"cookie-samesite" usage:"default SameSite mode: strict, lax or none"`
+	// Lifetime 0 оставляет cookie входа до закрытия браузера
+	Lifetime time.Duration `yaml:"lifetime" flag:"cookie-lifetime" usage:"login cookie lifetime without remember me"`
+
+	RememberLifetime time.Duration `yaml:"remember_me" flag:"cookie-remember-lifetime" usage:"remember me lifetime"`
+}
+
+// RateLimits ограничение частоты запросов. Path перечитывается без перезапуска
+type RateLimits struct {
+	// Path JSON файл с лимитами маршрутов, пустой означает лимиты по умолчанию
+	Path string `yaml:"path" flag:"rate-limits" usage:"JSON file with rate limits per route, empty uses defaults"`
+	// Redis адреса Redis, общего для реплик gateway, без них лимиты считаются в памяти
+	Redis []string `yaml:"redis" env:"RATE_LIMIT_REDIS" flag:"rate-limit-redis" usage:"redis addresses for rate limits"`
+}
+
+// Services адреса gRPC сервисов
+type Services struct {
+	Sessions string `yaml:"sessions" env:"NIMBUS_SESSIONS_ADDR" flag:"sessions-addr" usage:"sessions service address"`
+	Films    string `yaml:"films" env:"NIMBUS_FILMS_ADDR" flag:"films-addr" usage:"films service address"`
+	Users    string `yaml:"users" env:"NIMBUS_USERS_ADDR" flag:"users-addr" usage:"users service address"`
+	// Timeout дедлайн вызовов, у которых его еще нет
+	Timeout time.Duration `yaml:"timeout" flag:"grpc-timeout" usage:"deadline of calls to services"`
+}
+
+// Gateway конфигурация HTTP gateway (cmd/app)
+type Gateway struct {
+	Server  Server  `yaml:"server"`
+	Logging Logging `yaml:"logging"`
+	// Environment профиль заголовков безопасности, production также требует secure cookie
+	Environment string `yaml:"environment" env:"NIMBUS_ENV" flag:"env" usage:"environment: development or production"`
+	// TrustedProxies адреса и подсети балансировщиков, от которых принимаются X-Forwarded-For и X-Real-IP
+	TrustedProxies []string `yaml:"trusted_proxies" flag:"trusted-proxies" usage:"proxies allowed to set client ip"`
+	// FrontendOrigin origin фронтенда, которому разрешены запросы с cookie
+	FrontendOrigin string `yaml:"frontend_origin" flag:"frontend-origin" usage:"frontend origin allowed by CORS"`
+	// SecretKey общий секрет, которым подписывались токены до появления ключей с kid
+	SecretKey string `yaml:"secret_key" env:"SECRETKEY"`
+
+	JWT             JWT    `yaml:"jwt"`
+	OIDCConfigPath  string `yaml:"oidc_config" flag:"oidc-config" usage:"JSON file with OpenID Connect providers"`
+	DeviceVerifyURL string `yaml:"device_verify_url" flag:"device-verify-url" usage:"frontend page for TV codes"`
+	MagicLinkURL    string `yaml:"magic_link_url" flag:"magic-link-url" usage:"frontend page that login links lead to"`
+
+	WebAuthn   WebAuthn   `yaml:"webauthn"`
+	Cookies    Cookies    `yaml:"cookies"`
+	RateLimits RateLimits `yaml:"rate_limits"`
+	Services   Services   `yaml:"services"`
+	// Redis хранилище ключей подписи, общее для реплик gateway
+	Redis Redis `yaml:"redis"`
+	// Postgres база, в которую скрипт добавляет подписки
+	Postgres Postgres `yaml:"postgres"`
+	Tracing  Tracing  `yaml:"tracing"`
+	Health   Health   `yaml:"health"`
+}
+
+// DefaultGateway конфигурация gateway для docker-compose
+func DefaultGateway() Gateway {
+	return Gateway{
+		Server:         Server{IP: "90.156.218.166", FrontPort: 8080, Port: 8081},
+		Logging:        defaultLogging(),
+		Environment:    middleware.EnvironmentDevelopment,
+		FrontendOrigin: "http://localhost:8080",
+		JWT: JWT{
+			Algorithm:      signing.AlgorithmEdDSA,
+			RotationPeriod: 24 * time.Hour,
+			GracePeriod:    time.Hour,
+			KeyStore:       KeyStoreRedis,
+		},
+		DeviceVerifyURL: "http://localhost:8080/device",
+		MagicLinkURL:    "http://localhost:8080/magic-link",
+		WebAuthn:        WebAuthn{RPID: "localhost", Origin: "http://localhost:8080", AllowCounterless: true},
+		Cookies:         Cookies{SameSite: "lax", RememberLifetime: 30 * 24 * time.Hour},
+		RateLimits:      RateLimits{Redis: []string{"redis:6379"}},
+		Services: Services{
+			Sessions: "sessions:8010",
+			Films:    "films:8020",
+			Users:    "users:8030",
+			Timeout:  5 * time.Second,
+		},
+		Redis:    Redis{Addrs: []string{"redis:6379"}},
+		Postgres: defaultPostgres(),
+		Tracing:  defaultTracing(),
+		Health:   defaultHealth(),
+	}
+}
+
+// CookieConfig политика cookie для cookies.NewFactory
+func (gateway Gateway) CookieConfig() (cookies.Config, error) {
+	sameSite, err := cookies.ParseSameSite(gateway.Cookies.SameSite)
+	if err != nil {
+		return cookies.Config{}, err
+	}
+	cookieConfig := cookies.Config{
+		Domain: gateway.Cookies.Domain,
+		// с HSTS в production gateway доступен только по https
+		Secure:             gateway.Cookies.Secure || gateway.Environment == middleware.EnvironmentProduction,
+		SameSite:           sameSite,
+		Lifetime:           gateway.Cookies.Lifetime,
+		RememberMeLifetime: gateway.Cookies.RememberLifetime,
+	}
+	return cookieConfig, cookieConfig.Validate()
+}
+
+func (gateway Gateway) Validate() error {
+	err := firstError(gateway.Server.validate(), gateway.Logging.validate(), gateway.Postgres.validate(),
+		gateway.Tracing.validate(), gateway.Health.validate())
+	if err != nil {
+		return err
+	}
+	_, err = middleware.SecurityHeadersProfile(gateway.Environment)
+	if err != nil {
+		return err
+	}
+	_, err = clientip.ParseTrustedProxies(gateway.TrustedProxies)
+	if err != nil {
+		return err
+	}
+	if gateway.JWT.Algorithm != signing.AlgorithmEdDSA && gateway.JWT.Algorithm != signing.AlgorithmRS256 {
+		return fmt.Errorf("unknown jwt algorithm %q", gateway.JWT.Algorithm)
+	}
+	if gateway.JWT.RotationPeriod <= 0 || gateway.JWT.GracePeriod <= 0 {
+		return errors.New("jwt rotation and grace periods must be positive")
+	}
+	switch gateway.JWT.KeyStore {
+	case KeyStoreRedis:
+		err = gateway.Redis.validate()
+	case KeyStoreFile:
+		if gateway.JWT.KeyFile == "" {
+			err = errors.New("jwt key file is required")
+		}
+	case KeyStoreMemory:
+	default:
+		err = fmt.Errorf("unknown jwt key store %q", gateway.JWT.KeyStore)
+	}
+	if err != nil {
+		return err
+	}
+	err = firstError(validateURL("frontend origin", gateway.FrontendOrigin),
+		validateURL("device verify url", gateway.DeviceVerifyURL), validateURL("magic link url", gateway.MagicLinkURL),
+		validateURL("webauthn origin", gateway.WebAuthn.Origin))
+	if err != nil {
+		return err
+	}
+	if gateway.WebAuthn.RPID == "" {
+		return errors.New("webauthn rp id is required")
+	}
+	_, err = gateway.CookieConfig()
+	if err != nil {
+		return err
+	}
+	if gateway.Services.Sessions == "" || gateway.Services.Films == "" || gateway.Services.Users == "" {
+		return errors.New("service addresses are required")
+	}
+	if gateway.Services.Timeout <= 0 {
+		return errors.New("grpc timeout must be positive")
+	}
+	return nil
+}
+
+// Films конфигурация сервиса фильмов
+type Films struct {
+	Server     Server   `yaml:"server"`
+	Logging    Logging  `yaml:"logging"`
+	Postgres   Postgres `yaml:"postgres"`
+	UploadsDir string   `yaml:"uploads_dir" env:"NIMBUS_UPLOADS_DIR" flag:"uploads-dir" usage:"uploads directory"`
+	// CallTimeout дедлайн вызовов, пришедших без него
+	CallTimeout time.Duration `yaml:"call_timeout" flag:"call-timeout" usage:"default deadline of calls"`
+	// JWKSURL ключи gateway, которыми проверяются access токены пользователей в вызовах
+	JWKSURL string  `yaml:"jwks_url" flag:"jwks-url" usage:"gateway JWKS that verifies forwarded access tokens"`
+	Tracing Tracing `yaml:"tracing"`
+	Health  Health  `yaml:"health"`
+}
+
+func DefaultFilms() Films {
+	return Films{
+		Server:      Server{IP: "90.156.218.166", FrontPort: 8080, Port: 8020},
+		Logging:     defaultLogging(),
+		Postgres:    defaultPostgres(),
+		UploadsDir:  "./uploads",
+		CallTimeout: 10 * time.Second,
+		JWKSURL:     defaultJWKSURL,
+		Tracing:     defaultTracing(),
+		Health:      defaultHealth(),
+	}
+}
+
+func (films Films) Validate() error {
+	err := firstError(films.Server.validate(), films.Logging.validate(), films.Postgres.validate(),
+		films.Tracing.validate(), films.Health.validate())
+	if err != nil {
+		return err
+	}
+	if films.UploadsDir == "" {
+		return errors.New("uploads directory is required")
+	}
+	if films.CallTimeout <= 0 {
+		return errors.New("call timeout must be positive")
+	}
+	return validateURL("jwks url", films.JWKSURL)
+}
+
+// Mailer отправка писем, поля совпадают с mailer.Config
+type Mailer struct {
+	Kind         string `yaml:"kind" flag:"mailer" usage:"how to deliver mail (smtp, file or log)"`
+	SMTPAddr     string `yaml:"smtp_addr" flag:"smtp-addr" usage:"smtp server address"`
+	SMTPUsername string `yaml:"smtp_username" env:"SMTP_USERNAME"`
+	SMTPPassword string `yaml:"smtp_password" env:"SMTP_PASSWORD"`
+	From         string `yaml:"from" flag:"mail-from" usage:"sender address"`
+	FilePath     string `yaml:"file_path" flag:"mail-file" usage:"file to write mail to with -mailer=file"`
+}
+
+// Links страницы фронтенда, на которые ведут ссылки из писем
+type Links struct {
+	ResetURL        string `yaml:"reset_url" flag:"reset-url" usage:"frontend page for password reset links"`
+	VerifyURL       string `yaml:"verify_url" flag:"verify-url" usage:"frontend page for email verification links"`
+	UnknownLoginURL string `yaml:"unknown_login_url" flag:"unknown-login-url" usage:"frontend page for login alerts"`
+}
+
+// Users конфигурация сервиса пользователей
+type Users struct {
+	Server     Server   `yaml:"server"`
+	Logging    Logging  `yaml:"logging"`
+	Postgres   Postgres `yaml:"postgres"`
+	UploadsDir string   `yaml:"uploads_dir" env:"NIMBUS_UPLOADS_DIR" flag:"uploads-dir" usage:"uploads directory"`
+	Mailer     Mailer   `yaml:"mailer"`
+	Links      Links    `yaml:"links"`
+	// AuditRetention сколько хранятся события журнала безопасности
+	AuditRetention time.Duration `yaml:"audit_retention" flag:"audit-retention" usage:"audit events retention"`
+	CallTimeout    time.Duration `yaml:"call_timeout" flag:"call-timeout" usage:"default deadline of calls"`
+	// JWKSURL ключи gateway, которыми проверяются access токены пользователей в вызовах
+	JWKSURL string  `yaml:"jwks_url" flag:"jwks-url" usage:"gateway JWKS that verifies forwarded access tokens"`
+	Tracing Tracing `yaml:"tracing"`
+	Health  Health  `yaml:"health"`
+}
+
+func DefaultUsers() Users {
+	return Users{
+		Server:     Server{IP: "90.156.218.166", FrontPort: 8080, Port: 8030},
+		Logging:    defaultLogging(),
+		Postgres:   defaultPostgres(),
+		UploadsDir: "./uploads",
+		Mailer: Mailer{
+			Kind:     "log",
+			SMTPAddr: "smtp:587",
+			From:     "noreply@nimbus.ru",
+			FilePath: "mail.txt",
+		},
+		Links: Links{
+			ResetURL:        "http://localhost:8080/reset-password",
+			VerifyURL:       "http://localhost:8080/verify-email",
+			UnknownLoginURL: "http://localhost:8080/not-me",
+		},
+		AuditRetention: 365 * 24 * time.Hour,
+		CallTimeout:    10 * time.Second,
+		JWKSURL:        defaultJWKSURL,
+		Tracing:        defaultTracing(),
+		Health:         defaultHealth(),
+	}
+}
+
+func (users Users) Validate() error {
+	err := firstError(users.Server.validate(), users.Logging.validate(), users.Postgres.validate(),
+		users.Tracing.validate(), users.Health.validate())
+	if err != nil {
+		return err
+	}
+	if users.UploadsDir == "" {
+		return errors.New("uploads directory is required")
+	}
+	switch users.Mailer.Kind {
+	case "smtp", "file", "log":
+	default:
+		return fmt.Errorf("unknown mailer %q", users.Mailer.Kind)
+	}
+	err = firstError(validateURL("reset url", users.Links.ResetURL), validateURL("verify url", users.Links.VerifyURL),
+		validateURL("unknown login url", users.Links.UnknownLoginURL), validateURL("jwks url", users.JWKSURL))
+	if err != nil {
+		return err
+	}
+	if users.AuditRetention <= 0 || users.CallTimeout <= 0 {
+		return errors.New("audit retention and call timeout must be positive")
+	}
+	return nil
+}
+
+// Storage хранилище сессий
+type Storage struct {
+	Type string `yaml:"type" flag:"storage" usage:"session storage backend (redis or memory)"`
+	// TTL сколько живет сессия без активности
+	TTL time.Duration `yaml:"ttl" flag:"session-ttl" usage:"how long an inactive session lives"`
+	// SnapshotPath файл, в котором хранилище в памяти сохраняет сессии между перезапусками
+	SnapshotPath    string        `yaml:"snapshot" flag:"snapshot" usage:"in-memory sessions snapshot"`
+	CleanupInterval time.Duration `yaml:"cleanup_interval" flag:"cleanup-interval" usage:"in-memory eviction interval"`
+}
+
+// Sessions конфигурация сервиса сессий
+type Sessions struct {
+	Server      Server        `yaml:"server"`
+	Logging     Logging       `yaml:"logging"`
+	Redis       Redis         `yaml:"redis"`
+	Storage     Storage       `yaml:"storage"`
+	CallTimeout time.Duration `yaml:"call_timeout" flag:"call-timeout" usage:"default deadline of calls"`
+	// JWKSURL ключи gateway, которыми проверяются access токены пользователей в вызовах
+	JWKSURL string `yaml:"jwks_url" flag:"jwks-url" usage:"gateway JWKS that verifies forwarded access tokens"`
+	// UsersAddr сервис пользователей, из которого загружается политика ролей
+	UsersAddr string  `yaml:"users_addr" env:"NIMBUS_USERS_ADDR" flag:"users-addr" usage:"users service address"`
+	Tracing   Tracing `yaml:"tracing"`
+	Health    Health  `yaml:"health"`
+}
+
+func DefaultSessions() Sessions {
+	return Sessions{
+		Server:  Server{IP: "94.139.247.246", FrontPort: 8080, Port: 8010},
+		Logging: defaultLogging(),
+		Redis:   Redis{Addrs: []string{"redis:6379"}},
+		Storage: Storage{
+			Type:            "redis",
+			TTL:             30 * 24 * time.Hour,
+			CleanupInterval: time.Minute,
+		},
+		CallTimeout: 10 * time.Second,
+		JWKSURL:     defaultJWKSURL,
+		UsersAddr:   "users:8030",
+		Tracing:     defaultTracing(),
+		Health:      defaultHealth(),
+	}
+}
+
+func (sessions Sessions) Validate() error {
+	err := firstError(sessions.Server.validate(), sessions.Logging.validate(), sessions.Tracing.validate(),
+		sessions.Health.validate())
+	if err != nil {
+		return err
+	}
+	switch sessions.Storage.Type {
+	case "redis":
+		err = sessions.Redis.validate()
+		if err != nil {
+			return err
+		}
+	case "memory":
+		if sessions.Storage.CleanupInterval <= 0 {
+			return errors.New("cleanup interval must be positive")
+		}
+	default:
+		return fmt.Errorf("unknown session storage %q", sessions.Storage.Type)
+	}
+	if sessions.Storage.TTL <= 0 || sessions.CallTimeout <= 0 {
+		return errors.New("session ttl and call timeout must be positive")
+	}
+	if sessions.UsersAddr == "" {
+		return errors.New("users service address is required")
+	}
+	return validateURL("jwks url", sessions.JWKSURL)
+}
+
+func defaultLogging() Logging {
+	return Logging{Level: "debug"}
+}
+
+func defaultPostgres() Postgres {
+	return Postgres{
+		Host:     "postgres",
+		Port:     5432,
+		User:     "postgres",
+		Password: "postgres",
+		Database: "nimbus",
+		SSLMode:  "disable",
+	}
+}
+
+func defaultTracing() Tracing {
+	return Tracing{Exporter: tracing.ExporterNone, Endpoint: "otel-collector:4317", SampleRatio: 1}
+}
+
+// defaultJWKSURL ключи gateway в docker-compose
+const defaultJWKSURL = "http://app:8081/.well-known/jwks.json"
+
+func defaultHealth() Health {
+	return Health{Interval: 5 * time.Second, Timeout: 2 * time.Second}
+}
+
+func firstError(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package cookies
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Config политика cookie gateway, общая для всех выставляемых cookie
+type Config struct {
+	// Domain домен cookie, пустой привязывает cookie к хосту gateway
+	Domain string
+	Secure bool
+	// SameSite режим по умолчанию, сценарии с переходами с других сайтов задают свой
+	SameSite http.SameSite
+	// Lifetime время жизни cookie входа без «запомнить меня», 0 оставляет их до закрытия браузера
+	Lifetime time.Duration
+	// RememberMeLifetime время жизни cookie входа с «запомнить меня»
+	RememberMeLifetime time.Duration
+}
+
+// Validate проверяет, что браузеры примут cookie с такой политикой
+func (config Config) Validate() error {
+	if config.SameSite == http.SameSiteNoneMode && !config.Secure {
+		return errors.New("SameSite=None cookies must be secure")
+	}
+	if config.Lifetime < 0 || config.RememberMeLifetime < 0 {
+		return errors.New("cookie lifetime must not be negative")
+	}
+	return nil
+}
+
+// ParseSameSite разбирает режим SameSite из конфигурации: strict, lax или none
+func ParseSameSite(value string) (http.SameSite, error) {
+	switch strings.ToLower(value) {
+	case "strict":
+		return http.SameSiteStrictMode, nil
+	case "lax":
+		return http.SameSiteLaxMode, nil
+	case "none":
+		return http.SameSiteNoneMode, nil
+	}
+	return 0, fmt.Errorf("unknown SameSite mode %q", value)
+}
+
+// Options параметры отдельной cookie
+type Options struct {
+	// Path пустой означает "/"
+	Path string
+	// MaxAge 0 оставляет cookie до закрытия браузера
+	MaxAge time.Duration
+	// SameSite 0 берет режим из Config
+	SameSite http.SameSite
+}
+
+// Factory создает cookie по политике из Config. Все cookie gateway HttpOnly: фронтенду они не нужны,
+// данные о пользователе он получает из ответов API
+type Factory struct {
+	config Config
+}
+
+func NewFactory(config Config) *Factory {
+	return &Factory{config: config}
+}
+
+// New создает cookie name со значением value
+func (factory *Factory) New(name, value string, options Options) *http.Cookie {
+	path := options.Path
+	if path == "" {
+		path = "/"
+	}
+	sameSite := options.SameSite
+	if sameSite == 0 {
+		sameSite = factory.config.SameSite
+	}
+
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   factory.config.Domain,
+		MaxAge:   int(options.MaxAge.Seconds()),
+		Secure:   factory.config.Secure,
+		HttpOnly: true,
+		SameSite: sameSite,
+	}
+}
+
+// Expired создает cookie, удаляющую cookie name. Path и Domain должны совпадать с выставленной cookie,
+// иначе браузер ее не удалит
+func (factory *Factory) Expired(name string, options Options) *http.Cookie {
+	cookie := factory.New(name, "", options)
+	cookie.MaxAge = -1
+	return cookie
+}
+
+// LoginLifetime время жизни cookie входа
+func (factory *Factory) LoginLifetime(rememberMe bool) time.Duration {
+	if rememberMe {
+		return factory.config.RememberMeLifetime
+	}
+	return factory.config.Lifetime
+}
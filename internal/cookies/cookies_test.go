@@ -0,0 +1,73 @@
+package cookies
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFactory_New(t *testing.T) {
+	factory := NewFactory(Config{
+		Domain:             "nimbus.test",
+		Secure:             true,
+		SameSite:           http.SameSiteLaxMode,
+		Lifetime:           time.Hour,
+		RememberMeLifetime: 30 * 24 * time.Hour,
+	})
+
+	tests := []struct {
+		name             string
+		options          Options
+		expectedPath     string
+		expectedSameSite http.SameSite
+		expectedMaxAge   int
+	}{
+		{
+			name:             "Параметры по умолчанию",
+			expectedPath:     "/",
+			expectedSameSite: http.SameSiteLaxMode,
+		},
+		{
+			name:             "Свои путь, срок и SameSite",
+			options:          Options{Path: "/api/auth", MaxAge: time.Minute, SameSite: http.SameSiteStrictMode},
+			expectedPath:     "/api/auth",
+			expectedSameSite: http.SameSiteStrictMode,
+			expectedMaxAge:   60,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cookie := factory.New("refresh", "value", tt.options)
+
+			assert.Equal(t, "value", cookie.Value)
+			assert.Equal(t, tt.expectedPath, cookie.Path)
+			assert.Equal(t, "nimbus.test", cookie.Domain)
+			assert.Equal(t, tt.expectedSameSite, cookie.SameSite)
+			assert.Equal(t, tt.expectedMaxAge, cookie.MaxAge)
+			assert.True(t, cookie.Secure)
+			assert.True(t, cookie.HttpOnly)
+		})
+	}
+
+	expired := factory.Expired("refresh", Options{Path: "/api/auth"})
+	assert.Equal(t, -1, expired.MaxAge)
+	assert.Empty(t, expired.Value)
+	assert.Equal(t, "/api/auth", expired.Path)
+
+	assert.Equal(t, time.Hour, factory.LoginLifetime(false))
+	assert.Equal(t, 30*24*time.Hour, factory.LoginLifetime(true))
+}
+
+func TestConfig_Validate(t *testing.T) {
+	sameSite, err := ParseSameSite("None")
+	require.NoError(t, err)
+	assert.Error(t, Config{SameSite: sameSite}.Validate())
+	assert.NoError(t, Config{SameSite: sameSite, Secure: true}.Validate())
+
+	_, err = ParseSameSite("relaxed")
+	assert.Error(t, err)
+}
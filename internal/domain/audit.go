@@ -0,0 +1,36 @@
+package domain
+
+import "time"
+
+// AuditEvent запись журнала безопасности. Actor пользователь, который выполнил действие, Target аккаунт,
+// над которым оно выполнено, при входе и смене своего пароля они совпадают
+type AuditEvent struct {
+	Id         int64     `json:"id"`
+	OccurredAt time.Time `json:"occurredAt"`
+	Action     string    `json:"action"`
+	Actor      string    `json:"actor"`
+	Target     string    `json:"target"`
+	Ip         string    `json:"ip"`
+	UserAgent  string    `json:"userAgent"`
+	RequestId  string    `json:"requestId"`
+	Details    string    `json:"details"`
+}
+
+// AuditFilter условия выборки из журнала. Пустые поля не ограничивают выборку, Before id записи,
+// с которой продолжается постраничный вывод от новых событий к старым
+type AuditFilter struct {
+	Actor  string
+	Target string
+	Action string
+	From   time.Time
+	To     time.Time
+	Before int64
+	Limit  int
+}
+
+type AuditEventsResponse struct {
+	Status int          `json:"status"`
+	Events []AuditEvent `json:"events"`
+	// NextCursor передается в before, чтобы получить следующую страницу, 0 на последней странице
+	NextCursor int64 `json:"nextCursor,omitempty"`
+}
@@ -0,0 +1,63 @@
+package domain
+
+import "time"
+
+// Состояния авторизации устройства (RFC 8628). Pending пока пользователь не подтвердил код на другом
+// устройстве, SlowDown и Expired появляются только в ответе на опрос
+const (
+	DeviceAuthorizationPending  = "pending"
+	DeviceAuthorizationApproved = "approved"
+	DeviceAuthorizationDenied   = "denied"
+	DeviceAuthorizationSlowDown = "slow_down"
+	DeviceAuthorizationExpired  = "expired"
+)
+
+// DeviceAuthorization запрос входа с устройства без клавиатуры, например телевизора. Устройство знает
+// device code и опрашивает по нему статус, пользователь вводит короткий UserCode на телефоне или компьютере
+type DeviceAuthorization struct {
+	UserCode     string    `json:"userCode"`
+	Status       string    `json:"status"`
+	Login        string    `json:"login,omitempty"`
+	ClientName   string    `json:"clientName"`
+	Ip           string    `json:"ip"`
+	CreatedAt    time.Time `json:"createdAt"`
+	LastPolledAt time.Time `json:"lastPolledAt"`
+}
+
+// DeviceCodeResponse ответ устройству. На экране показывается UserCode и VerificationUri
+// или QR код из VerificationUriComplete
+type DeviceCodeResponse struct {
+	Status                  int    `json:"status"`
+	DeviceCode              string `json:"deviceCode"`
+	UserCode                string `json:"userCode"`
+	VerificationUri         string `json:"verificationUri"`
+	VerificationUriComplete string `json:"verificationUriComplete"`
+	ExpiresIn               int64  `json:"expiresIn"`
+	Interval                int64  `json:"interval"`
+}
+
+type DeviceTokenRequest struct {
+	DeviceCode string `json:"deviceCode"`
+}
+
+// DeviceInfoResponse что пользователь подтверждает, прежде чем впустить устройство в аккаунт
+type DeviceInfoResponse struct {
+	Status     int       `json:"status"`
+	UserCode   string    `json:"userCode"`
+	ClientName string    `json:"clientName"`
+	Ip         string    `json:"ip"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+type DeviceApproveRequest struct {
+	UserCode string `json:"userCode"`
+	Approve  bool   `json:"approve"`
+}
+
+// DeviceCode коды, выданные устройству. Через Interval устройство может снова спросить статус
+type DeviceCode struct {
+	DeviceCode string
+	UserCode   string
+	ExpiresIn  time.Duration
+	Interval   time.Duration
+}
@@ -42,6 +42,8 @@ func easyjson3e1fa5ecDecodeGithubComSanExpettDiplomaInternalDomain(in *jlexer.Le
 			out.Name = string(in.String())
 		case "password":
 			out.Password = string(in.String())
+		case "remember_me":
+			out.RememberMe = bool(in.Bool())
 		default:
 			in.SkipRecursive()
 		}
@@ -71,6 +73,11 @@ func easyjson3e1fa5ecEncodeGithubComSanExpettDiplomaInternalDomain(out *jwriter.
 		out.RawString(prefix)
 		out.String(string(in.Password))
 	}
+	{
+		const prefix string = ",\"remember_me\":"
+		out.RawString(prefix)
+		out.Bool(bool(in.RememberMe))
+	}
 	out.RawByte('}')
 }
 
@@ -220,6 +227,33 @@ func easyjson3e1fa5ecDecodeGithubComSanExpettDiplomaInternalDomain2(in *jlexer.L
 			}
 		case "hasSubscription":
 			out.HasSubscription = bool(in.Bool())
+		case "emailVerified":
+			out.EmailVerified = bool(in.Bool())
+		case "totpEnabled":
+			out.TotpEnabled = bool(in.Bool())
+		case "roles":
+			if in.IsNull() {
+				in.Skip()
+				out.Roles = nil
+			} else {
+				in.Delim('[')
+				if out.Roles == nil {
+					if !in.IsDelim(']') {
+						out.Roles = make([]string, 0, 4)
+					} else {
+						out.Roles = []string{}
+					}
+				} else {
+					out.Roles = (out.Roles)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v1 string
+					v1 = string(in.String())
+					out.Roles = append(out.Roles, v1)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
 		default:
 			in.SkipRecursive()
 		}
@@ -284,6 +318,32 @@ func easyjson3e1fa5ecEncodeGithubComSanExpettDiplomaInternalDomain2(out *jwriter
 		out.RawString(prefix)
 		out.Bool(bool(in.HasSubscription))
 	}
+	{
+		const prefix string = ",\"emailVerified\":"
+		out.RawString(prefix)
+		out.Bool(bool(in.EmailVerified))
+	}
+	{
+		const prefix string = ",\"totpEnabled\":"
+		out.RawString(prefix)
+		out.Bool(bool(in.TotpEnabled))
+	}
+	{
+		const prefix string = ",\"roles\":"
+		out.RawString(prefix)
+		if in.Roles == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+			out.RawString("null")
+		} else {
+			out.RawByte('[')
+			for v2, v3 := range in.Roles {
+				if v2 > 0 {
+					out.RawByte(',')
+				}
+				out.String(string(v3))
+			}
+			out.RawByte(']')
+		}
+	}
 	out.RawByte('}')
 }
 
@@ -0,0 +1,15 @@
+package domain
+
+import "time"
+
+// EmailVerificationStatus состояние подтверждения почты пользователя. SentRecently количество писем
+// с подтверждением, отправленных за последний период ограничения, LastSentAt время отправки последнего
+type EmailVerificationStatus struct {
+	Verified     bool
+	SentRecently uint32
+	LastSentAt   time.Time
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
@@ -0,0 +1,16 @@
+package domain
+
+// Identity внешний аккаунт пользователя у OpenID Connect провайдера. EmailVerified означает,
+// что провайдер сам подтвердил владение почтой
+type Identity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+type OIDCProvidersResponse struct {
+	Status    int      `json:"status"`
+	Providers []string `json:"providers"`
+}
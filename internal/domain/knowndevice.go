@@ -0,0 +1,17 @@
+package domain
+
+import "time"
+
+// KnownDevice устройство, с которого пользователь уже входил. Fingerprint хеш user agent и сети клиента
+type KnownDevice struct {
+	Fingerprint string
+	UserAgent   string
+	Ip          string
+	FirstSeenAt time.Time
+	LastSeenAt  time.Time
+}
+
+// ReportUnknownLoginRequest токен из письма о входе с нового устройства
+type ReportUnknownLoginRequest struct {
+	Token string `json:"token"`
+}
@@ -0,0 +1,51 @@
+package domain
+
+import "time"
+
+// LoginAttempts неудачные попытки входа по аккаунту или IP с момента последнего успешного входа
+type LoginAttempts struct {
+	Failures      uint32    `json:"failures"`
+	LastFailureAt time.Time `json:"lastFailureAt"`
+}
+
+// LoginThrottle результат проверки попытки входа. RetryAfter время, которое нужно подождать
+// перед следующей попыткой, Locked означает временную блокировку после слишком многих ошибок
+type LoginThrottle struct {
+	RetryAfter time.Duration
+	Locked     bool
+}
+
+// LoginPolicy ограничения неудачных попыток входа. После FreeAttempts ошибок перед каждой попыткой
+// нужно ждать BackoffBase, удваивающуюся с каждой ошибкой, после LockoutAttempts вход блокируется
+// на LockoutDuration
+type LoginPolicy struct {
+	FreeAttempts    uint32
+	LockoutAttempts uint32
+	BackoffBase     time.Duration
+	LockoutDuration time.Duration
+}
+
+// Throttle возвращает, сколько осталось ждать до следующей попытки входа
+func (policy LoginPolicy) Throttle(attempts LoginAttempts, now time.Time) LoginThrottle {
+	if attempts.Failures < policy.FreeAttempts {
+		return LoginThrottle{}
+	}
+
+	locked := attempts.Failures >= policy.LockoutAttempts
+	delay := policy.LockoutDuration
+	if shift := attempts.Failures - policy.FreeAttempts; !locked && shift < 32 {
+		delay = min(policy.BackoffBase<<shift, policy.LockoutDuration)
+	}
+
+	retryAfter := attempts.LastFailureAt.Add(delay).Sub(now)
+	if retryAfter <= 0 {
+		return LoginThrottle{}
+	}
+	return LoginThrottle{RetryAfter: retryAfter, Locked: locked}
+}
+
+// UnlockLoginRequest запрос администратора на снятие блокировки входа. Ip можно не указывать
+type UnlockLoginRequest struct {
+	Login string `json:"login"`
+	Ip    string `json:"ip"`
+}
@@ -0,0 +1,11 @@
+package domain
+
+type MagicLinkRequest struct {
+	Email string `json:"email"`
+}
+
+// MagicLinkLoginRequest токен из ссылки в письме, которую пользователь открыл в том же браузере,
+// где ее запросил
+type MagicLinkLoginRequest struct {
+	Token string `json:"token"`
+}
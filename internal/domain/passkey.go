@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/SanExpett/diploma/internal/webauthn"
+)
+
+// Passkey ключ WebAuthn пользователя. PublicKey открытый ключ в формате COSE_Key, LastUsedAt нулевое,
+// если по ключу еще не входили
+type Passkey struct {
+	Id         string
+	PublicKey  []byte
+	SignCount  uint32
+	Transports []string
+	Name       string
+	CreatedAt  time.Time
+	LastUsedAt time.Time
+}
+
+// PasskeyInfo passkey в профиле пользователя, без открытого ключа
+type PasskeyInfo struct {
+	Id         string     `json:"id"`
+	Name       string     `json:"name"`
+	Transports []string   `json:"transports"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+}
+
+type PasskeysResponse struct {
+	Status   int           `json:"status"`
+	Passkeys []PasskeyInfo `json:"passkeys"`
+}
+
+// PasskeyCreationOptionsResponse параметры для navigator.credentials.create({publicKey})
+type PasskeyCreationOptionsResponse struct {
+	Status    int                      `json:"status"`
+	PublicKey webauthn.CreationOptions `json:"publicKey"`
+}
+
+// PasskeyRequestOptionsResponse параметры для navigator.credentials.get({publicKey})
+type PasskeyRequestOptionsResponse struct {
+	Status    int                     `json:"status"`
+	PublicKey webauthn.RequestOptions `json:"publicKey"`
+}
+
+// PasskeyRegistrationRequest ответ аутентификатора на регистрацию и название ключа для профиля
+type PasskeyRegistrationRequest struct {
+	Name       string                       `json:"name"`
+	Credential webauthn.AttestationResponse `json:"credential"`
+}
@@ -0,0 +1,40 @@
+package domain
+
+import "time"
+
+// RefreshToken хранится в сессионном кеше под хешем самого токена. Family объединяет все токены,
+// полученные ротацией из одного входа, и совпадает с идентификатором сессии
+type RefreshToken struct {
+	Login  string `json:"login"`
+	Family string `json:"family"`
+	Used   bool   `json:"used"`
+}
+
+// Session активная сессия пользователя с данными устройства, с которого выполнен вход
+type Session struct {
+	Id         string    `json:"id"`
+	Version    uint32    `json:"version"`
+	UserAgent  string    `json:"userAgent"`
+	Ip         string    `json:"ip"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	Current    bool      `json:"current"`
+}
+
+type SessionsResponse struct {
+	Status   int       `json:"status"`
+	Sessions []Session `json:"sessions"`
+}
+
+// CsrfTokenResponse токен, который фронтенд передает в заголовке X-CSRF-Token запросов, меняющих состояние
+type CsrfTokenResponse struct {
+	Status int    `json:"status"`
+	Token  string `json:"token"`
+}
+
+// CheckResponse ответ проверки сессии. Uuid нужен фронтенду для ссылок на профиль:
+// cookie user_uuid недоступна из JavaScript
+type CheckResponse struct {
+	Status int    `json:"status"`
+	Uuid   string `json:"uuid"`
+}
@@ -0,0 +1,38 @@
+package domain
+
+// TOTP второй фактор пользователя. Пока Confirmed ложно, секрет только выдан и вход без кода не требует.
+// LastUsedStep шаг последнего принятого кода, коды с шагом не больше него повторно не принимаются
+type TOTP struct {
+	Secret       string
+	Confirmed    bool
+	LastUsedStep int64
+}
+
+type TOTPEnrollResponse struct {
+	Status          int    `json:"status"`
+	Secret          string `json:"secret"`
+	ProvisioningUri string `json:"provisioningUri"`
+}
+
+// TOTPConfirmResponse коды восстановления показываются пользователю один раз, в базе хранятся их хеши
+type TOTPConfirmResponse struct {
+	Status        int      `json:"status"`
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// TOTPCodeRequest код из приложения или код восстановления
+type TOTPCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// TwoFactorChallengeResponse ответ на вход с верным паролем, когда у пользователя включен второй фактор
+type TwoFactorChallengeResponse struct {
+	Status            int    `json:"status"`
+	TwoFactorRequired bool   `json:"twoFactorRequired"`
+	Challenge         string `json:"challenge"`
+}
+
+type TwoFactorLoginRequest struct {
+	Challenge string `json:"challenge"`
+	Code      string `json:"code"`
+}
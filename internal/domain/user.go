@@ -14,6 +14,9 @@ type User struct {
 	RegisteredAt    time.Time `json:"registeredAt"`
 	Birthday        time.Time `json:"birthday"`
 	HasSubscription bool      `json:"hasSubscription"`
+	EmailVerified   bool      `json:"emailVerified"`
+	TotpEnabled     bool      `json:"totpEnabled"`
+	Roles           []string  `json:"roles"`
 }
 
 //easyjson:json
@@ -21,6 +24,8 @@ type UserSignUp struct {
 	Email    string `json:"login"`
 	Name     string `json:"username"`
 	Password string `json:"password"`
+	// RememberMe продлевает cookie входа, чтобы сессия пережила закрытие браузера
+	RememberMe bool `json:"remember_me"`
 }
 
 //easyjson:json
@@ -67,3 +72,13 @@ type PayResponse struct {
 type RemoveUserRequest struct {
 	Login string `json:"login"`
 }
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+// ResetPasswordRequest новый пароль вместе с токеном из письма восстановления
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
@@ -22,11 +22,28 @@ func ParseError(err error) (int, error) {
 		errors.Is(err, ErrFailInExec),
 		errors.Is(err, ErrFailInExec),
 		errors.Is(err, ErrIncorrectSearchParams),
-		errors.Is(err, ErrAlreadyHaveSubscription):
+		errors.Is(err, ErrAlreadyHaveSubscription),
+		errors.Is(err, ErrInvalidResetToken),
+		errors.Is(err, ErrInvalidVerificationToken),
+		errors.Is(err, ErrEmailAlreadyVerified),
+		errors.Is(err, ErrTOTPAlreadyEnabled),
+		errors.Is(err, ErrTOTPNotEnabled),
+		errors.Is(err, ErrInvalidTOTPCode),
+		errors.Is(err, ErrIdentityAlreadyLinked),
+		errors.Is(err, ErrAccountExistsForIdentity),
+		errors.Is(err, ErrNoSuchDeviceAuthorization),
+		errors.Is(err, ErrAuthorizationPending),
+		errors.Is(err, ErrSlowDown),
+		errors.Is(err, ErrDeviceCodeExpired),
+		errors.Is(err, ErrPasskeyAlreadyRegistered),
+		errors.Is(err, ErrInvalidPasskey),
+		errors.Is(err, ErrInvalidUnknownLoginToken):
 		status = 400
 	case errors.Is(err, ErrNoSuchItemInTheCache),
 		errors.Is(err, ErrNoSuchSessionInTheCache),
 		errors.Is(err, ErrNoSuchUserInTheCache),
+		errors.Is(err, ErrNoSuchRefreshToken),
+		errors.Is(err, ErrRefreshTokenReused),
 		errors.Is(err, ErrWrongSessionVersion),
 		errors.Is(err, ErrNotAuthorised),
 		errors.Is(err, ErrTokenIsNotValid),
@@ -35,16 +52,34 @@ func ParseError(err error) (int, error) {
 		errors.Is(err, ErrWrongScore),
 		errors.Is(err, ErrCommentAlreadyExists),
 		errors.Is(err, ErrFavoriteAlreadyExists),
-		errors.Is(err, ErrNoSuchFilm):
+		errors.Is(err, ErrNoSuchFilm),
+		errors.Is(err, ErrInvalidTwoFactorChallenge),
+		errors.Is(err, ErrOIDCLoginFailed),
+		errors.Is(err, ErrInvalidMagicLink),
+		errors.Is(err, ErrPasskeyLoginFailed):
 		status = 401
-	case errors.Is(err, ErrNotFound):
+	case errors.Is(err, ErrForbidden),
+		errors.Is(err, ErrEmailNotVerified),
+		errors.Is(err, ErrDeviceAccessDenied),
+		errors.Is(err, ErrInvalidCsrfToken),
+		errors.Is(err, ErrCrossOriginRequest):
+		status = 403
+	case errors.Is(err, ErrNotFound),
+		errors.Is(err, ErrUnknownOIDCProvider),
+		errors.Is(err, ErrNoSuchPasskey):
 		status = 404
+	case errors.Is(err, ErrTooManyLoginAttempts),
+		errors.Is(err, ErrAccountLocked),
+		errors.Is(err, ErrTooManyVerificationRequests),
+		errors.Is(err, ErrRateLimited):
+		status = 429
 	case errors.Is(err, ErrInternalServerError),
 		errors.Is(err, ErrTooHighVersion),
 		errors.Is(err, ErrFailInForEachRow),
 		errors.Is(err, ErrFailedToBeginTransaction),
 		errors.Is(err, ErrFailedToCommitTransaction),
-		errors.Is(err, ErrNoActorsForFilm):
+		errors.Is(err, ErrNoActorsForFilm),
+		errors.Is(err, ErrInvalidPasswordHash):
 		status = 500
 	default:
 		status = 500
@@ -57,3 +92,47 @@ func ParseError(err error) (int, error) {
 
 	return status, currentErr
 }
+
+// errorCodes машинно-читаемые коды ошибок, по которым фронтенд показывает понятное сообщение
+var errorCodes = map[error]string{
+	ErrIncorrectLoginOrPassword:    "invalid_credentials",
+	ErrTooManyLoginAttempts:        "too_many_attempts",
+	ErrAccountLocked:               "account_locked",
+	ErrInvalidResetToken:           "invalid_reset_token",
+	ErrInvalidVerificationToken:    "invalid_verification_token",
+	ErrEmailAlreadyVerified:        "email_already_verified",
+	ErrEmailNotVerified:            "email_not_verified",
+	ErrTooManyVerificationRequests: "too_many_requests",
+	ErrTOTPAlreadyEnabled:          "totp_already_enabled",
+	ErrTOTPNotEnabled:              "totp_not_enabled",
+	ErrInvalidTOTPCode:             "invalid_totp_code",
+	ErrInvalidTwoFactorChallenge:   "invalid_2fa_challenge",
+	ErrUnknownOIDCProvider:         "unknown_provider",
+	ErrOIDCLoginFailed:             "oidc_login_failed",
+	ErrIdentityAlreadyLinked:       "identity_already_linked",
+	ErrAccountExistsForIdentity:    "account_exists",
+	ErrNoSuchDeviceAuthorization:   "invalid_user_code",
+	ErrAuthorizationPending:        "authorization_pending",
+	ErrSlowDown:                    "slow_down",
+	ErrDeviceCodeExpired:           "expired_token",
+	ErrDeviceAccessDenied:          "access_denied",
+	ErrInvalidMagicLink:            "invalid_magic_link",
+	ErrNoSuchPasskey:               "passkey_not_found",
+	ErrPasskeyAlreadyRegistered:    "passkey_already_registered",
+	ErrInvalidPasskey:              "invalid_passkey",
+	ErrPasskeyLoginFailed:          "passkey_login_failed",
+	ErrInvalidUnknownLoginToken:    "invalid_unknown_login_token",
+	ErrInvalidCsrfToken:            "invalid_csrf_token",
+	ErrCrossOriginRequest:          "cross_origin_request",
+	ErrRateLimited:                 "rate_limited",
+}
+
+// ErrorCode возвращает код ошибки для ответа клиенту или пустую строку, если код не назначен
+func ErrorCode(err error) string {
+	for codeErr, code := range errorCodes {
+		if errors.Is(err, codeErr) {
+			return code
+		}
+	}
+	return ""
+}
@@ -4,4 +4,7 @@ import "errors"
 
 var (
 	ErrIncorrectSearchParams = errors.New("incorrect search parameters")
+	ErrInvalidCsrfToken      = errors.New("csrf token is missing or invalid")
+	ErrCrossOriginRequest    = errors.New("request origin is not allowed")
+	ErrRateLimited           = errors.New("rate limit exceeded, try again later")
 )
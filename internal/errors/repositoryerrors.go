@@ -12,12 +12,21 @@ var (
 	ErrNoSuchSessionInTheCache  = errors.New("no such session in cache")
 	ErrNoSuchUserInTheCache     = errors.New("no such user in cache")
 	ErrTooHighVersion           = errors.New("too high session version")
+	ErrNoSuchRefreshToken       = errors.New("no such refresh token")
+	ErrRefreshTokenReused       = errors.New("refresh token has already been used")
+
+	ErrNoSuchDeviceAuthorization = errors.New("device code is invalid or expired")
+	ErrNoSuchPasskeyChallenge    = errors.New("passkey challenge is already used or expired")
 
 	// postgres users errors
 	ErrNoSuchUser               = errors.New("no such user with given login")
 	ErrUserAlreadyExists        = errors.New("user with given login already exists")
 	ErrIncorrectLoginOrPassword = errors.New("incorrect login or password")
 
+	ErrNoSuchPasskey              = errors.New("no such passkey")
+	ErrPasskeyAlreadyRegistered   = errors.New("passkey is already registered")
+	ErrPasskeySignCountRegression = errors.New("passkey sign count did not increase")
+
 	ErrFailInQueryRow            = errors.New("failed to query row")
 	ErrFailInQuery               = errors.New("failed to query rows")
 	ErrFailInExec                = errors.New("failed to exec script")
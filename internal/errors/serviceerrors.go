@@ -4,6 +4,7 @@ import "errors"
 
 var (
 	ErrNotAuthorised     = errors.New("not authorised")
+	ErrForbidden         = errors.New("forbidden")
 	ErrTokenIsNotValid   = errors.New("token is not valid")
 	ErrNoActiveSession   = errors.New("no active session")
 	ErrLoginIsNotValid   = errors.New("login is not valid")
@@ -15,4 +16,38 @@ var (
 	ErrNoActorsForFilm = errors.New("failed to get film's actors")
 
 	ErrAlreadyHaveSubscription = errors.New("you have already purchased subscription")
+
+	ErrInvalidPasswordHash = errors.New("invalid password hash")
+
+	ErrTooManyLoginAttempts = errors.New("too many login attempts, try again later")
+	ErrAccountLocked        = errors.New("account is temporarily locked")
+
+	ErrInvalidResetToken = errors.New("password reset token is invalid or expired")
+
+	ErrInvalidVerificationToken    = errors.New("email verification token is invalid or expired")
+	ErrEmailAlreadyVerified        = errors.New("email is already verified")
+	ErrEmailNotVerified            = errors.New("email is not verified")
+	ErrTooManyVerificationRequests = errors.New("verification email was sent recently, try again later")
+
+	ErrTOTPAlreadyEnabled        = errors.New("two-factor authentication is already enabled")
+	ErrTOTPNotEnabled            = errors.New("two-factor authentication is not enabled")
+	ErrInvalidTOTPCode           = errors.New("two-factor authentication code is invalid")
+	ErrInvalidTwoFactorChallenge = errors.New("two-factor login challenge is invalid or expired")
+
+	ErrUnknownOIDCProvider      = errors.New("unknown identity provider")
+	ErrOIDCLoginFailed          = errors.New("login with identity provider failed")
+	ErrIdentityAlreadyLinked    = errors.New("identity is already linked to another user")
+	ErrAccountExistsForIdentity = errors.New("account with this email already exists, log in and link the identity")
+
+	ErrAuthorizationPending = errors.New("device authorization is pending")
+	ErrSlowDown             = errors.New("device is polling too often, slow down")
+	ErrDeviceCodeExpired    = errors.New("device code has expired")
+	ErrDeviceAccessDenied   = errors.New("device authorization was denied")
+
+	ErrInvalidMagicLink = errors.New("login link is invalid, expired or already used")
+
+	ErrInvalidPasskey     = errors.New("passkey registration could not be verified")
+	ErrPasskeyLoginFailed = errors.New("login with passkey failed")
+
+	ErrInvalidUnknownLoginToken = errors.New("unknown login report link is invalid, expired or already used")
 )
@@ -11,6 +11,7 @@ import (
 	"github.com/SanExpett/diploma/internal/domain"
 	reqid "github.com/SanExpett/diploma/internal/requestId"
 	session "github.com/SanExpett/diploma/internal/session/proto"
+	"github.com/SanExpett/diploma/internal/tracing"
 )
 
 type FilmsService interface {
@@ -60,7 +61,7 @@ func (server *FilmsServer) GetAllFilmsPreviews(ctx context.Context,
 	films, err := server.filmsService.GetAllFilmsPreviews(ctx)
 	if err != nil {
 		// Логируем ошибку и возвращаем её клиенту
-		server.logger.Errorf("[reqid=%s] failed to get all films previews: %v\n",
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get all films previews: %v\n",
 			requestId, err)
 		return nil, fmt.Errorf("[reqid=%s] failed to get all films previews: %v\n",
 			requestId, err)
@@ -83,7 +84,8 @@ func (server *FilmsServer) GetFilmsPreviewsWithSub(ctx context.Context,
 	requestId := ctx.Value(reqid.ReqIDKey)
 	films, err := server.filmsService.GetFilmsPreviewsWithSub(ctx)
 	if err != nil {
-		server.logger.Errorf("[reqid=%s] failed to get films previews with sub: %v\n", requestId, err)
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get films previews with sub: %v\n",
+			requestId, err)
 		return nil, fmt.Errorf("[reqid=%s] failed to get films previews with sub: %v\n", requestId, err)
 	}
 
@@ -102,7 +104,7 @@ func (server *FilmsServer) GetFilmDataByUuid(ctx context.Context,
 	requestId := ctx.Value(reqid.ReqIDKey)
 	film, err := server.filmsService.GetFilmDataByUuid(ctx, req.Uuid)
 	if err != nil {
-		server.logger.Errorf("[reqid=%s] failed to get film data: %v\n", requestId, err)
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get film data: %v\n", requestId, err)
 		return nil, fmt.Errorf("[reqid=%s] failed to get film data: %v\n", requestId, err)
 	}
 
@@ -118,7 +120,7 @@ func (server *FilmsServer) GetFilmPreviewByUuid(ctx context.Context,
 	requestId := ctx.Value(reqid.ReqIDKey)
 	film, err := server.filmsService.GetFilmPreview(ctx, req.Uuid)
 	if err != nil {
-		server.logger.Errorf("[reqid=%s] failed to get film data: %v\n", requestId, err)
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get film data: %v\n", requestId, err)
 		return nil, fmt.Errorf("[reqid=%s] failed to get film data: %v\n", requestId, err)
 	}
 	filmConverted := convertFilmPreviewToProto(&film)
@@ -133,7 +135,7 @@ func (server *FilmsServer) GetActorsByFilm(ctx context.Context,
 	requestId := ctx.Value(reqid.ReqIDKey)
 	actors, err := server.filmsService.GetActorsByFilm(ctx, req.Uuid)
 	if err != nil {
-		server.logger.Errorf("[reqid=%s] failed to get all film actors: %v\n", requestId, err)
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get all film actors: %v\n", requestId, err)
 		return nil, fmt.Errorf("[reqid=%s] failed to get all film actors: %v\n", requestId, err)
 	}
 	var actorsConverted []*session.ActorPreview
@@ -151,7 +153,7 @@ func (server *FilmsServer) RemoveFilmByUuid(ctx context.Context,
 	requestId := ctx.Value(reqid.ReqIDKey)
 	err = server.filmsService.RemoveFilm(ctx, req.Uuid)
 	if err != nil {
-		server.logger.Errorf("[reqid=%s] failed to remove film data: %v\n", requestId, err)
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to remove film data: %v\n", requestId, err)
 		return nil, fmt.Errorf("[reqid=%s] failed to remove film data: %v\n", requestId, err)
 	}
 	return &session.RemoveFilmByUuidResponse{}, nil
@@ -162,7 +164,7 @@ func (server *FilmsServer) GetActorDataByUuid(ctx context.Context,
 	requestId := ctx.Value(reqid.ReqIDKey)
 	actor, err := server.filmsService.GetActorByUuid(ctx, req.Uuid)
 	if err != nil {
-		server.logger.Errorf("[reqid=%s] failed to get actor data: %v\n", requestId, err)
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get actor data: %v\n", requestId, err)
 		return nil, fmt.Errorf("[reqid=%s] failed to get actor data: %v\n", requestId, err)
 	}
 
@@ -184,7 +186,7 @@ func (server *FilmsServer) PutFavorite(ctx context.Context,
 	requestId := ctx.Value(reqid.ReqIDKey)
 	err = server.filmsService.PutFavoriteFilm(ctx, req.FilmUuid, req.UserUuid)
 	if err != nil {
-		server.logger.Errorf("[reqid=%s] failed to put favorite: %v\n", requestId, err)
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to put favorite: %v\n", requestId, err)
 		return nil, fmt.Errorf("[reqid=%s] failed to put favorite: %v\n", requestId, err)
 	}
 
@@ -196,7 +198,7 @@ func (server *FilmsServer) DeleteFavorite(ctx context.Context,
 	requestId := ctx.Value(reqid.ReqIDKey)
 	err = server.filmsService.RemoveFavoriteFilm(ctx, req.FilmUuid, req.UserUuid)
 	if err != nil {
-		server.logger.Errorf("[reqid=%s] failed to remove favorite: %v\n", requestId, err)
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to remove favorite: %v\n", requestId, err)
 		return nil, fmt.Errorf("[reqid=%s] failed to remove favorite: %v\n", requestId, err)
 	}
 
@@ -208,7 +210,7 @@ func (server *FilmsServer) GetAllFavoriteFilms(ctx context.Context,
 	requestId := ctx.Value(reqid.ReqIDKey)
 	films, err := server.filmsService.GetAllFavoriteFilms(ctx, req.UserUuid)
 	if err != nil {
-		server.logger.Errorf("[reqid=%s] failed to get favorite: %v\n", requestId, err)
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get favorite: %v\n", requestId, err)
 		return nil, fmt.Errorf("[reqid=%s] failed to get favorite: %v\n", requestId, err)
 	}
 
@@ -227,7 +229,7 @@ func (server *FilmsServer) GetAllFilmsByGenre(ctx context.Context,
 	requestId := ctx.Value(reqid.ReqIDKey)
 	films, err := server.filmsService.GetAllFilmsByGenre(ctx, req.GenreUuid)
 	if err != nil {
-		server.logger.Errorf("[reqid=%s] failed to get genre films: %v\n", requestId, err)
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get genre films: %v\n", requestId, err)
 		return nil, fmt.Errorf("[reqid=%s] failed to get genre films: %v\n", requestId, err)
 	}
 
@@ -246,7 +248,7 @@ func (server *FilmsServer) FindFilmsShort(ctx context.Context,
 	requestId := ctx.Value(reqid.ReqIDKey)
 	films, err := server.filmsService.FindFilmsShort(ctx, request.Key, int(request.Page))
 	if err != nil {
-		server.logger.Errorf("[reqid=%s] failed to get favorite: %v\n", requestId, err)
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get favorite: %v\n", requestId, err)
 		return nil, fmt.Errorf("[reqid=%s] failed to get favorite: %v\n", requestId, err)
 	}
 
@@ -265,7 +267,7 @@ func (server *FilmsServer) GetAllGenres(ctx context.Context,
 	requestId := ctx.Value(reqid.ReqIDKey)
 	genres, err := server.filmsService.GetAllGenres(ctx)
 	if err != nil {
-		server.logger.Errorf("[reqid=%s] failed to get genres: %v\n", requestId, err)
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get genres: %v\n", requestId, err)
 		return nil, fmt.Errorf("[reqid=%s] failed to get genres: %v\n", requestId, err)
 	}
 
@@ -285,7 +287,7 @@ func (server *FilmsServer) AddFilm(ctx context.Context,
 
 	err = server.filmsService.AddFilm(ctx, convertFilmToAdd(req.FilmData))
 	if err != nil {
-		server.logger.Errorf("[reqid=%s] failed to add favorite: %v\n", requestId, err)
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to add favorite: %v\n", requestId, err)
 		return nil, fmt.Errorf("[reqid=%s] failed to add favorite: %v\n", requestId, err)
 	}
 	return &session.AddFilmResponse{}, nil
@@ -296,7 +298,7 @@ func (server *FilmsServer) FindFilmsLong(ctx context.Context,
 	requestId := ctx.Value(reqid.ReqIDKey)
 	films, err := server.filmsService.FindFilmsLong(ctx, req.Key, int(req.Page))
 	if err != nil {
-		server.logger.Errorf("[reqid=%s] failed to get films: %v\n", requestId, err)
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get films: %v\n", requestId, err)
 		return nil, fmt.Errorf("[reqid=%s] failed to get films: %v\n", requestId, err)
 	}
 
@@ -316,7 +318,7 @@ func (server *FilmsServer) FindSerialsShort(ctx context.Context,
 	requestId := ctx.Value(reqid.ReqIDKey)
 	serials, err := server.filmsService.FindSerialsShort(ctx, request.Key, int(request.Page))
 	if err != nil {
-		server.logger.Errorf("[reqid=%s] failed to get serials: %v\n", requestId, err)
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get serials: %v\n", requestId, err)
 		return nil, fmt.Errorf("[reqid=%s] failed to get serials: %v\n", requestId, err)
 	}
 
@@ -335,7 +337,7 @@ func (server *FilmsServer) FindSerialsLong(ctx context.Context,
 	requestId := ctx.Value(reqid.ReqIDKey)
 	serials, err := server.filmsService.FindSerialsLong(ctx, request.Key, int(request.Page))
 	if err != nil {
-		server.logger.Errorf("[reqid=%s] failed to get serials: %v\n", requestId, err)
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get serials: %v\n", requestId, err)
 		return nil, fmt.Errorf("[reqid=%s] failed to get serials: %v\n", requestId, err)
 	}
 
@@ -355,7 +357,7 @@ func (server *FilmsServer) FindActorsShort(ctx context.Context,
 	requestId := ctx.Value(reqid.ReqIDKey)
 	actors, err := server.filmsService.FindActorsShort(ctx, request.Key, int(request.Page))
 	if err != nil {
-		server.logger.Errorf("[reqid=%s] failed to get actors: %v\n", requestId, err)
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get actors: %v\n", requestId, err)
 		return nil, fmt.Errorf("[reqid=%s] failed to get actors: %v\n", requestId, err)
 	}
 
@@ -374,7 +376,7 @@ func (server *FilmsServer) FindActorsLong(ctx context.Context,
 	requestId := ctx.Value(reqid.ReqIDKey)
 	actors, err := server.filmsService.FindActorsLong(ctx, request.Key, int(request.Page))
 	if err != nil {
-		server.logger.Errorf("[reqid=%s] failed to get actors: %v\n", requestId, err)
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get actors: %v\n", requestId, err)
 		return nil, fmt.Errorf("[reqid=%s] failed to get actors: %v\n", requestId, err)
 	}
 
@@ -394,7 +396,7 @@ func (server *FilmsServer) GetTopFilms(ctx context.Context,
 	requestId := ctx.Value(reqid.ReqIDKey)
 	films, err := server.filmsService.GetTopFilms(ctx)
 	if err != nil {
-		server.logger.Errorf("[reqid=%s] failed to get top films: %v\n", requestId, err)
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get top films: %v\n", requestId, err)
 		return nil, fmt.Errorf("[reqid=%s] failed to get top films: %v\n", requestId, err)
 	}
 
@@ -413,7 +415,7 @@ func (server *FilmsServer) GetAllFilmComments(ctx context.Context,
 	requestId := ctx.Value(reqid.ReqIDKey)
 	comments, err := server.filmsService.GetAllFilmComments(ctx, req.FilmUuid)
 	if err != nil {
-		server.logger.Errorf("[reqid=%s] failed to get all film comments: %v\n", requestId, err)
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get all film comments: %v\n", requestId, err)
 		return nil, fmt.Errorf("[reqid=%s] failed to get all film comments: %v\n", requestId, err)
 	}
 	var commentsConverted []*session.Comment
@@ -431,7 +433,7 @@ func (server *FilmsServer) AddComment(ctx context.Context,
 	requestId := ctx.Value(reqid.ReqIDKey)
 	err := server.filmsService.AddComment(ctx, convertCommentToAddToRegular(req.Comment))
 	if err != nil {
-		server.logger.Errorf("[reqid=%s] failed to add comment: %v\n", requestId, err)
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to add comment: %v\n", requestId, err)
 		return nil, fmt.Errorf("[reqid=%s] failed to add comment: %v\n", requestId, err)
 	}
 
@@ -443,7 +445,7 @@ func (server *FilmsServer) RemoveComment(ctx context.Context,
 	requestId := ctx.Value(reqid.ReqIDKey)
 	err := server.filmsService.RemoveComment(ctx, convertCommentToRemoveToRegular(req.Comment))
 	if err != nil {
-		server.logger.Errorf("[reqid=%s] failed to remove comment: %v\n", requestId, err)
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to remove comment: %v\n", requestId, err)
 		return nil, fmt.Errorf("[reqid=%s] failed to remove comment: %v\n", requestId, err)
 	}
 
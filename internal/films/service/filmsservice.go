@@ -9,6 +9,7 @@ import (
 	myerrors "github.com/SanExpett/diploma/internal/errors"
 	"github.com/SanExpett/diploma/internal/metrics"
 	"github.com/SanExpett/diploma/internal/requestId"
+	"github.com/SanExpett/diploma/internal/tracing"
 )
 
 type FilmsStorage interface {
@@ -58,8 +59,8 @@ func (service *FilmsService) GetFilmDataByUuid(ctx context.Context, uuid string)
 	service.metrics.IncRequestsTotal("GetFilmDataByUuid")
 	film, err := service.storage.GetFilmDataByUuid(uuid)
 	if err != nil {
-		service.logger.Errorf("[reqid=%s] failed to get film: %v", ctx.Value(requestId.ReqIDKey),
-			myerrors.ErrNoSuchFilm)
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get film: %v",
+			ctx.Value(requestId.ReqIDKey), myerrors.ErrNoSuchFilm)
 		return domain.CommonFilmData{}, err
 	}
 
@@ -70,7 +71,8 @@ func (service *FilmsService) AddFilm(ctx context.Context, film domain.FilmToAdd)
 	service.metrics.IncRequestsTotal("AddFilm")
 	err := service.storage.AddFilm(film)
 	if err != nil {
-		service.logger.Errorf("[reqid=%s] failed to add film: %v", ctx.Value(requestId.ReqIDKey), err)
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to add film: %v",
+			ctx.Value(requestId.ReqIDKey), err)
 		return err
 	}
 	return nil
@@ -80,7 +82,8 @@ func (service *FilmsService) RemoveFilm(ctx context.Context, uuid string) error
 	service.metrics.IncRequestsTotal("RemoveFilm")
 	err := service.storage.RemoveFilm(uuid)
 	if err != nil {
-		service.logger.Errorf("[reqid=%s] failed to remove film: %v", ctx.Value(requestId.ReqIDKey), err)
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to remove film: %v",
+			ctx.Value(requestId.ReqIDKey), err)
 		return err
 	}
 	return nil
@@ -90,8 +93,8 @@ func (service *FilmsService) GetFilmPreview(ctx context.Context, uuid string) (d
 	service.metrics.IncRequestsTotal("GetFilmPreview")
 	filmPreview, err := service.storage.GetFilmPreview(uuid)
 	if err != nil {
-		service.logger.Errorf("[reqid=%s] failed to get film preview: %v", ctx.Value(requestId.ReqIDKey),
-			err)
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get film preview: %v",
+			ctx.Value(requestId.ReqIDKey), err)
 		return domain.FilmPreview{}, err
 	}
 	return filmPreview, nil
@@ -101,7 +104,7 @@ func (service *FilmsService) GetAllFilmsPreviews(ctx context.Context) ([]domain.
 	service.metrics.IncRequestsTotal("GetAllFilmsPreviews")
 	filmPreviews, err := service.storage.GetAllFilmsPreviews()
 	if err != nil {
-		service.logger.Errorf("[reqid=%v] failed to get all films previews: %v",
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%v] failed to get all films previews: %v",
 			ctx.Value(requestId.ReqIDKey), err)
 		return nil, err
 	}
@@ -112,7 +115,7 @@ func (service *FilmsService) GetFilmsPreviewsWithSub(ctx context.Context) ([]dom
 	service.metrics.IncRequestsTotal("GetFilmsPreviewsWithSub")
 	filmPreviews, err := service.storage.GetFilmsPreviewsWithSub()
 	if err != nil {
-		service.logger.Errorf("[reqid=%v] failed to get films previews with sub: %v",
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%v] failed to get films previews with sub: %v",
 			ctx.Value(requestId.ReqIDKey), err)
 		return nil, err
 	}
@@ -123,7 +126,7 @@ func (service *FilmsService) GetAllFilmComments(ctx context.Context, filmUuid st
 	service.metrics.IncRequestsTotal("GetAllFilmComments")
 	comments, err := service.storage.GetAllFilmComments(filmUuid)
 	if err != nil {
-		service.logger.Errorf("[reqid=%s] failed to get all film comments: %v",
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get all film comments: %v",
 			ctx.Value(requestId.ReqIDKey), err)
 		return nil, err
 	}
@@ -134,8 +137,8 @@ func (service *FilmsService) GetActorsByFilm(ctx context.Context, uuid string) (
 	service.metrics.IncRequestsTotal("GetActorsByFilm")
 	actors, err := service.storage.GetActorsByFilm(uuid)
 	if err != nil {
-		service.logger.Errorf("[reqid=%s] failed to get all film actors: %v", ctx.Value(requestId.ReqIDKey),
-			err)
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get all film actors: %v",
+			ctx.Value(requestId.ReqIDKey), err)
 		return nil, err
 	}
 	return actors, nil
@@ -145,8 +148,8 @@ func (service *FilmsService) GetActorByUuid(ctx context.Context, actorUuid strin
 	service.metrics.IncRequestsTotal("GetActorByUuid")
 	actor, err := service.storage.GetActorByUuid(actorUuid)
 	if err != nil {
-		service.logger.Errorf("[reqid=%s] failed to get actor: %v", ctx.Value(requestId.ReqIDKey),
-			myerrors.ErrNoSuchActor)
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get actor: %v",
+			ctx.Value(requestId.ReqIDKey), myerrors.ErrNoSuchActor)
 		return domain.ActorData{}, err
 	}
 
@@ -157,8 +160,8 @@ func (service *FilmsService) PutFavoriteFilm(ctx context.Context, filmUuid strin
 	service.metrics.IncRequestsTotal("PutFavoriteFilm")
 	err := service.storage.PutFavoriteFilm(filmUuid, userUuid)
 	if err != nil {
-		service.logger.Errorf("[reqid=%s] failed to put favorite film: %v", ctx.Value(requestId.ReqIDKey),
-			err)
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to put favorite film: %v",
+			ctx.Value(requestId.ReqIDKey), err)
 		return err
 	}
 	return nil
@@ -168,8 +171,8 @@ func (service *FilmsService) RemoveFavoriteFilm(ctx context.Context, filmUuid st
 	service.metrics.IncRequestsTotal("RemoveFavoriteFilm")
 	err := service.storage.RemoveFavoriteFilm(filmUuid, userUuid)
 	if err != nil {
-		service.logger.Errorf("[reqid=%s] failed to remove favorite film: %v", ctx.Value(requestId.ReqIDKey),
-			err)
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to remove favorite film: %v",
+			ctx.Value(requestId.ReqIDKey), err)
 		return err
 	}
 	return nil
@@ -179,8 +182,8 @@ func (service *FilmsService) GetAllFavoriteFilms(ctx context.Context, userUuid s
 	service.metrics.IncRequestsTotal("GetAllFavoriteFilms")
 	films, err := service.storage.GetAllFavoriteFilms(userUuid)
 	if err != nil {
-		service.logger.Errorf("[reqid=%s] failed to remove favorite film: %v", ctx.Value(requestId.ReqIDKey),
-			err)
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to remove favorite film: %v",
+			ctx.Value(requestId.ReqIDKey), err)
 		return nil, err
 	}
 	return films, nil
@@ -190,8 +193,8 @@ func (service *FilmsService) GetAllFilmsByGenre(ctx context.Context, genreUuid s
 	service.metrics.IncRequestsTotal("GetAllFilmsByGenre")
 	films, err := service.storage.GetAllFilmsByGenre(genreUuid)
 	if err != nil {
-		service.logger.Errorf("[reqid=%s] failed to get genre films: %v", ctx.Value(requestId.ReqIDKey),
-			err)
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get genre films: %v",
+			ctx.Value(requestId.ReqIDKey), err)
 		return nil, err
 	}
 	return films, nil
@@ -201,8 +204,8 @@ func (service *FilmsService) GetAllGenres(ctx context.Context) ([]domain.GenreFi
 	service.metrics.IncRequestsTotal("GetAllGenres")
 	genres, err := service.storage.GetAllGenres()
 	if err != nil {
-		service.logger.Errorf("[reqid=%s] failed to get genres: %v", ctx.Value(requestId.ReqIDKey),
-			err)
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get genres: %v",
+			ctx.Value(requestId.ReqIDKey), err)
 		return nil, err
 	}
 	return genres, nil
@@ -212,8 +215,8 @@ func (service *FilmsService) FindFilmsShort(ctx context.Context, title string, p
 	service.metrics.IncRequestsTotal("FindFilmsShort")
 	films, err := service.storage.FindFilmsShort(title, page)
 	if err != nil {
-		service.logger.Errorf("[reqid=%s] failed to find films short: %v", ctx.Value(requestId.ReqIDKey),
-			err)
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to find films short: %v",
+			ctx.Value(requestId.ReqIDKey), err)
 		return nil, err
 	}
 	return films, nil
@@ -223,8 +226,8 @@ func (service *FilmsService) FindFilmsLong(ctx context.Context, title string, pa
 	service.metrics.IncRequestsTotal("FindFilmsLong")
 	films, err := service.storage.FindFilmsLong(title, page)
 	if err != nil {
-		service.logger.Errorf("[reqid=%s] failed to find films long: %v", ctx.Value(requestId.ReqIDKey),
-			err)
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to find films long: %v",
+			ctx.Value(requestId.ReqIDKey), err)
 		return domain.SearchFilms{}, err
 	}
 	return films, nil
@@ -235,8 +238,8 @@ func (service *FilmsService) FindSerialsShort(ctx context.Context, title string,
 	service.metrics.IncRequestsTotal("FindSerialsShort")
 	serials, err := service.storage.FindSerialsShort(title, page)
 	if err != nil {
-		service.logger.Errorf("[reqid=%s] failed to find serials short: %v", ctx.Value(requestId.ReqIDKey),
-			err)
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to find serials short: %v",
+			ctx.Value(requestId.ReqIDKey), err)
 		return nil, err
 	}
 	return serials, nil
@@ -246,8 +249,8 @@ func (service *FilmsService) FindSerialsLong(ctx context.Context, title string,
 	service.metrics.IncRequestsTotal("FindSerialsLong")
 	serials, err := service.storage.FindSerialsLong(title, page)
 	if err != nil {
-		service.logger.Errorf("[reqid=%s] failed to find serials long: %v", ctx.Value(requestId.ReqIDKey),
-			err)
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to find serials long: %v",
+			ctx.Value(requestId.ReqIDKey), err)
 		return domain.SearchFilms{}, err
 	}
 	return serials, nil
@@ -258,8 +261,8 @@ func (service *FilmsService) FindActorsShort(ctx context.Context, name string,
 	service.metrics.IncRequestsTotal("FindActorsShort")
 	actors, err := service.storage.FindActorsShort(name, page)
 	if err != nil {
-		service.logger.Errorf("[reqid=%s] failed to find actors short: %v", ctx.Value(requestId.ReqIDKey),
-			err)
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to find actors short: %v",
+			ctx.Value(requestId.ReqIDKey), err)
 		return nil, err
 	}
 	return actors, nil
@@ -269,8 +272,8 @@ func (service *FilmsService) FindActorsLong(ctx context.Context, name string, pa
 	service.metrics.IncRequestsTotal("FindActorsLong")
 	actors, err := service.storage.FindActorsLong(name, page)
 	if err != nil {
-		service.logger.Errorf("[reqid=%s] failed to find actors long: %v", ctx.Value(requestId.ReqIDKey),
-			err)
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to find actors long: %v",
+			ctx.Value(requestId.ReqIDKey), err)
 		return domain.SearchActors{}, err
 	}
 	return actors, nil
@@ -280,7 +283,8 @@ func (service *FilmsService) GetTopFilms(ctx context.Context) ([]domain.TopFilm,
 	service.metrics.IncRequestsTotal("GetTopFilms")
 	films, err := service.storage.GetTopFilms()
 	if err != nil {
-		service.logger.Errorf("[reqid=%s] failed to get top films: %v", ctx.Value(requestId.ReqIDKey), err)
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get top films: %v",
+			ctx.Value(requestId.ReqIDKey), err)
 		return nil, err
 	}
 	return films, nil
@@ -290,7 +294,8 @@ func (service *FilmsService) AddComment(ctx context.Context, comment domain.Comm
 	service.metrics.IncRequestsTotal("AddComment")
 	err := service.storage.AddComment(comment)
 	if err != nil {
-		service.logger.Errorf("[reqid=%s] failed to add comment: %v", ctx.Value(requestId.ReqIDKey), err)
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to add comment: %v",
+			ctx.Value(requestId.ReqIDKey), err)
 		return err
 	}
 	return nil
@@ -300,7 +305,8 @@ func (service *FilmsService) RemoveComment(ctx context.Context, comment domain.C
 	service.metrics.IncRequestsTotal("RemoveComment")
 	err := service.storage.RemoveComment(comment)
 	if err != nil {
-		service.logger.Errorf("[reqid=%s] failed to remove comment: %v", ctx.Value(requestId.ReqIDKey), err)
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to remove comment: %v",
+			ctx.Value(requestId.ReqIDKey), err)
 		return err
 	}
 	return nil
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"html"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/SanExpett/diploma/internal/domain"
+	myerrors "github.com/SanExpett/diploma/internal/errors"
+	"github.com/SanExpett/diploma/internal/metrics"
+	reqid "github.com/SanExpett/diploma/internal/requestId"
+	session "github.com/SanExpett/diploma/internal/session/proto"
+)
+
+const (
+	defaultAuditPageSize = 50
+	maxAuditPageSize     = 200
+)
+
+type AuditHandlers struct {
+	usersClient *session.UsersClient
+	metrics     *metrics.HttpMetrics
+	logger      *zap.SugaredLogger
+}
+
+func NewAuditHandlers(usersClient *session.UsersClient, metrics *metrics.HttpMetrics,
+	logger *zap.SugaredLogger) *AuditHandlers {
+	return &AuditHandlers{
+		usersClient: usersClient,
+		metrics:     metrics,
+		logger:      logger,
+	}
+}
+
+// @Summary      Журнал безопасности
+// @Description  Возвращает события журнала от новых к старым. Следующая страница запрашивается
+// @Description  с before из nextCursor предыдущей
+// @Tags         Admin
+// @Produce      json
+// @Param        actor   query     string  false  "Кто выполнил действие"
+// @Param        target  query     string  false  "Над чьим аккаунтом выполнено действие"
+// @Param        action  query     string  false  "Тип события"
+// @Param        from    query     string  false  "Начало периода в RFC 3339"
+// @Param        to      query     string  false  "Конец периода в RFC 3339, не включая"
+// @Param        before  query     int     false  "Курсор страницы"
+// @Param        limit   query     int     false  "Размер страницы, до 200"
+// @Success      200     {object}  domain.AuditEventsResponse  "Страница журнала"
+// @Failure      400     {object}  object                      "Некорректные параметры"
+// @Failure      401     {object}  object                      "Ошибка авторизации"
+// @Failure      403     {object}  object                      "Нет права audit.read"
+// @Failure      500     {object}  object                      "Внутренняя ошибка сервера"
+// @Router       /admin/audit [get]
+func (auditHandlers *AuditHandlers) Events(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	requestID := ctx.Value(reqid.ReqIDKey)
+
+	reqList, err := parseAuditQuery(r)
+	if err != nil {
+		auditHandlers.logger.Errorf("[reqid=%s] failed to parse audit query: %v\n", requestID, err)
+		err = WriteError(w, r, auditHandlers.metrics, myerrors.ErrIncorrectSearchParams)
+		if err != nil {
+			auditHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	events, err := (*auditHandlers.usersClient).ListAuditEvents(ctx, reqList)
+	if err != nil {
+		auditHandlers.logger.Errorf("[reqid=%s] failed to list audit events: %v\n", requestID, err)
+		err = WriteError(w, r, auditHandlers.metrics, err)
+		if err != nil {
+			auditHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	response := domain.AuditEventsResponse{
+		Status: http.StatusOK,
+		Events: convertAuditEventsToRegular(events.Events),
+	}
+	if len(response.Events) == int(reqList.Limit) {
+		response.NextCursor = response.Events[len(response.Events)-1].Id
+	}
+
+	jsonResponse, err := json.Marshal(response)
+	if err != nil {
+		err = WriteError(w, r, auditHandlers.metrics, err)
+		if err != nil {
+			auditHandlers.logger.Errorf("[reqid=%s] failed to marshal response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	err = WriteResponse(w, r, auditHandlers.metrics, jsonResponse, requestID)
+	if err != nil {
+		err = WriteError(w, r, auditHandlers.metrics, err)
+		if err != nil {
+			auditHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+}
+
+// recordAuditEvent отправляет событие gateway в журнал. Запрос пользователя к этому моменту уже обработан,
+// поэтому ошибка записи только логируется
+func (authPageHandlers *AuthPageHandlers) recordAuditEvent(ctx context.Context, event domain.AuditEvent) {
+	reqRecord := session.RecordAuditEventRequest{Event: &session.AuditEvent{
+		OccurredAt: convertTimeToProto(event.OccurredAt),
+		Action:     event.Action,
+		Actor:      event.Actor,
+		Target:     event.Target,
+		Ip:         event.Ip,
+		UserAgent:  event.UserAgent,
+		RequestId:  event.RequestId,
+		Details:    event.Details,
+	}}
+	_, err := (*authPageHandlers.usersClient).RecordAuditEvent(ctx, &reqRecord)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to record audit event %s: %v\n",
+			ctx.Value(reqid.ReqIDKey), event.Action, err)
+	}
+}
+
+func parseAuditQuery(r *http.Request) (*session.ListAuditEventsRequest, error) {
+	params := r.URL.Query()
+	reqList := &session.ListAuditEventsRequest{
+		Actor:  params.Get("actor"),
+		Target: params.Get("target"),
+		Action: params.Get("action"),
+		Limit:  defaultAuditPageSize,
+	}
+
+	if from := params.Get("from"); from != "" {
+		fromTime, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return nil, err
+		}
+		reqList.From = convertTimeToProto(fromTime)
+	}
+	if to := params.Get("to"); to != "" {
+		toTime, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			return nil, err
+		}
+		reqList.To = convertTimeToProto(toTime)
+	}
+	if before := params.Get("before"); before != "" {
+		cursor, err := strconv.ParseInt(before, 10, 64)
+		if err != nil || cursor <= 0 {
+			return nil, myerrors.ErrIncorrectSearchParams
+		}
+		reqList.Before = cursor
+	}
+	if limit := params.Get("limit"); limit != "" {
+		pageSize, err := strconv.Atoi(limit)
+		if err != nil || pageSize <= 0 || pageSize > maxAuditPageSize {
+			return nil, myerrors.ErrIncorrectSearchParams
+		}
+		reqList.Limit = int32(pageSize)
+	}
+
+	return reqList, nil
+}
+
+func convertAuditEventsToRegular(events []*session.AuditEvent) []domain.AuditEvent {
+	eventsRegular := make([]domain.AuditEvent, 0, len(events))
+	for _, event := range events {
+		eventsRegular = append(eventsRegular, domain.AuditEvent{
+			Id:         event.Id,
+			OccurredAt: convertProtoToTime(event.OccurredAt),
+			Action:     event.Action,
+			Actor:      html.EscapeString(event.Actor),
+			Target:     html.EscapeString(event.Target),
+			Ip:         event.Ip,
+			UserAgent:  html.EscapeString(event.UserAgent),
+			RequestId:  event.RequestId,
+			Details:    html.EscapeString(event.Details),
+		})
+	}
+	return eventsRegular
+}
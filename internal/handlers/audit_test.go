@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/SanExpett/diploma/internal/domain"
+	"github.com/SanExpett/diploma/internal/handlers/mocks"
+	"github.com/SanExpett/diploma/internal/metrics"
+	session "github.com/SanExpett/diploma/internal/session/proto"
+)
+
+func TestAuditHandlers_Events(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUsersClient := mocks.NewMockUsersClient(ctrl)
+	var usersClient session.UsersClient = mockUsersClient
+
+	handler := NewAuditHandlers(&usersClient, metrics.NewHttpMetrics(), zap.NewNop().Sugar())
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/admin/audit", handler.Events)
+
+	occurredAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	events := []*session.AuditEvent{
+		{Id: 12, OccurredAt: timestamppb.New(occurredAt), Action: "logout", Actor: "test@test.com",
+			Target: "test@test.com", UserAgent: "<script>"},
+		{Id: 10, OccurredAt: timestamppb.New(occurredAt), Action: "login.succeeded", Actor: "test@test.com",
+			Target: "test@test.com"},
+	}
+
+	tests := []struct {
+		name               string
+		query              string
+		setupMocks         func()
+		expectedStatus     int
+		expectedEvents     int
+		expectedNextCursor int64
+	}{
+		{
+			name:  "Полная страница",
+			query: "?actor=test@test.com&from=2024-05-01T00:00:00Z&before=20&limit=2",
+			setupMocks: func() {
+				mockUsersClient.EXPECT().ListAuditEvents(gomock.Any(), &session.ListAuditEventsRequest{
+					Actor:  "test@test.com",
+					From:   timestamppb.New(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)),
+					Before: 20,
+					Limit:  2,
+				}).Return(&session.ListAuditEventsResponse{Events: events}, nil)
+			},
+			expectedStatus:     http.StatusOK,
+			expectedEvents:     2,
+			expectedNextCursor: 10,
+		},
+		{
+			name:  "Последняя страница",
+			query: "?action=logout",
+			setupMocks: func() {
+				mockUsersClient.EXPECT().ListAuditEvents(gomock.Any(), &session.ListAuditEventsRequest{
+					Action: "logout",
+					Limit:  defaultAuditPageSize,
+				}).Return(&session.ListAuditEventsResponse{Events: events[:1]}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedEvents: 1,
+		},
+		{
+			name:           "Слишком большая страница",
+			query:          "?limit=1000",
+			setupMocks:     func() {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Некорректная дата",
+			query:          "?from=yesterday",
+			setupMocks:     func() {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMocks()
+
+			req := httptest.NewRequest(http.MethodGet, "/api/admin/audit"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			var response domain.AuditEventsResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tt.expectedStatus, response.Status)
+			assert.Len(t, response.Events, tt.expectedEvents)
+			assert.Equal(t, tt.expectedNextCursor, response.NextCursor)
+		})
+	}
+
+	t.Run("Экранирование user agent", func(t *testing.T) {
+		mockUsersClient.EXPECT().ListAuditEvents(gomock.Any(), gomock.Any()).
+			Return(&session.ListAuditEventsResponse{Events: events[:1]}, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/admin/audit", nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		var response domain.AuditEventsResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		require.Len(t, response.Events, 1)
+		assert.Equal(t, "&lt;script&gt;", response.Events[0].UserAgent)
+		assert.Equal(t, occurredAt, response.Events[0].OccurredAt)
+	})
+}
@@ -1,36 +1,58 @@
 package handlers
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
-	"os"
+	"strconv"
+	"time"
 
+	"github.com/gorilla/mux"
 	"github.com/mailru/easyjson"
 	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
+	"github.com/SanExpett/diploma/internal/audit"
+	"github.com/SanExpett/diploma/internal/clientip"
+	"github.com/SanExpett/diploma/internal/cookies"
 	"github.com/SanExpett/diploma/internal/domain"
 	myerrors "github.com/SanExpett/diploma/internal/errors"
 	"github.com/SanExpett/diploma/internal/metrics"
+	"github.com/SanExpett/diploma/internal/principal"
 	reqid "github.com/SanExpett/diploma/internal/requestId"
 	session "github.com/SanExpett/diploma/internal/session/proto"
+	"github.com/SanExpett/diploma/internal/signing"
 )
 
-var (
-	tokenCookieExpirationTime = 48 * 3600
+var accessTokenExpirationTime = 15 * time.Minute
+
+const (
+	// refresh токен нужен только ручкам авторизации, поэтому cookie не отправляется на остальные запросы
+	refreshTokenCookiePath = "/api/auth"
+	// rememberMeCookie отмечает вход с «запомнить меня», чтобы Refresh продлевал cookie на тот же срок
+	rememberMeCookie = "remember_me"
+	userUuidCookie   = "user_uuid"
 )
 
 type AuthPageHandlers struct {
 	usersClient    *session.UsersClient
 	sessionsClient *session.SessionsClient
+	keyManager     *signing.KeyManager
+	cookies        *cookies.Factory
 	metrics        *metrics.HttpMetrics
 	logger         *zap.SugaredLogger
 }
 
 func NewAuthPageHandlers(usersClient *session.UsersClient, sessionsClient *session.SessionsClient,
-	metrics *metrics.HttpMetrics, logger *zap.SugaredLogger) *AuthPageHandlers {
+	keyManager *signing.KeyManager, cookieFactory *cookies.Factory, metrics *metrics.HttpMetrics,
+	logger *zap.SugaredLogger) *AuthPageHandlers {
 	return &AuthPageHandlers{
 		usersClient:    usersClient,
 		sessionsClient: sessionsClient,
+		keyManager:     keyManager,
+		cookies:        cookieFactory,
 		metrics:        metrics,
 		logger:         logger,
 	}
@@ -45,6 +67,7 @@ func NewAuthPageHandlers(usersClient *session.UsersClient, sessionsClient *sessi
 // @Success      200          {object}  object             "Успешная авторизация"
 // @Failure      400          {object}  object             "Ошибка валидации"
 // @Failure      401          {object}  object             "Ошибка авторизации"
+// @Failure      429          {object}  object             "Слишком много неудачных попыток входа"
 // @Failure      500          {object}  object             "Внутренняя ошибка сервера"
 // @Router       /auth/login [post]
 func (authPageHandlers *AuthPageHandlers) Login(w http.ResponseWriter, r *http.Request) {
@@ -86,8 +109,48 @@ func (authPageHandlers *AuthPageHandlers) Login(w http.ResponseWriter, r *http.R
 		return
 	}
 
+	ip := clientip.FromRequest(r)
+	// попытка резервируется до проверки, поэтому параллельные попытки не проходят мимо ограничений
+	reqReserve := session.ReserveLoginAttemptRequest{Login: login, Ip: ip}
+	throttle, err := (*authPageHandlers.sessionsClient).ReserveLoginAttempt(ctx, &reqReserve)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+	if throttle.RetryAfter > 0 {
+		authPageHandlers.writeLoginThrottled(w, r, throttle.RetryAfter, throttle.Locked)
+		return
+	}
+
 	req := session.HasUserRequest{Login: login, Password: password}
 	has, err := (*authPageHandlers.usersClient).HasUser(ctx, &req)
+	if status.Code(err) == codes.Unauthenticated {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to login: %v\n", requestID,
+			myerrors.ErrIncorrectLoginOrPassword)
+		event := audit.NewEvent(ctx, audit.ActionLoginFailed, login, "")
+		event.Actor = login
+		authPageHandlers.recordAuditEvent(ctx, event)
+
+		reqFailure := session.RegisterLoginFailureRequest{Login: login, Ip: ip}
+		failure, failureErr := (*authPageHandlers.sessionsClient).RegisterLoginFailure(ctx, &reqFailure)
+		if failureErr != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to register login failure: %v\n", requestID,
+				failureErr)
+		} else if failure.Locked {
+			authPageHandlers.writeLoginThrottled(w, r, failure.RetryAfter, failure.Locked)
+			return
+		}
+
+		err = WriteError(w, r, authPageHandlers.metrics, myerrors.ErrIncorrectLoginOrPassword)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+	authPageHandlers.releaseLoginAttempt(ctx, login, ip)
 	if err != nil {
 		authPageHandlers.logger.Errorf("[reqid=%s] failed to check sessions: %v\n", requestID,
 			myerrors.ErrNoActiveSession)
@@ -117,7 +180,33 @@ func (authPageHandlers *AuthPageHandlers) Login(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	tokenSigned, err := GenerateTokens(user.User.Email, user.User.IsAdmin, user.User.Version)
+	if user.User.TotpEnabled {
+		authPageHandlers.writeTwoFactorChallenge(w, r, user.User.Email, inputUserData.RememberMe)
+		return
+	}
+
+	authPageHandlers.completeLogin(w, r, user.User, ip, inputUserData.RememberMe)
+}
+
+// completeLogin создает сессию пользователя, прошедшего проверку пароля и, если он включен, второго фактора,
+// и выставляет cookie с токенами. С rememberMe cookie живут дольше и переживают закрытие браузера
+func (authPageHandlers *AuthPageHandlers) completeLogin(w http.ResponseWriter, r *http.Request, user *session.User,
+	ip string, rememberMe bool) {
+	ctx := r.Context()
+	requestID := ctx.Value(reqid.ReqIDKey)
+
+	reqIssue := session.IssueRefreshTokenRequest{Login: user.Email}
+	refreshToken, err := (*authPageHandlers.sessionsClient).IssueRefreshToken(ctx, &reqIssue)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	tokenSigned, err := GenerateTokens(authPageHandlers.keyManager, user.Email, user.Uuid,
+		refreshToken.Family, user.IsAdmin, user.Roles, user.Version)
 	if err != nil {
 		err = WriteError(w, r, authPageHandlers.metrics, err)
 		if err != nil {
@@ -126,7 +215,8 @@ func (authPageHandlers *AuthPageHandlers) Login(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	reqAdd := session.AddRequest{Login: user.User.Email, Token: tokenSigned, Version: user.User.Version}
+	reqAdd := session.AddRequest{Login: user.Email, Token: refreshToken.Family, Version: user.Version,
+		UserAgent: r.UserAgent(), Ip: ip}
 	_, err = (*authPageHandlers.sessionsClient).Add(ctx, &reqAdd)
 	if err != nil {
 		err = WriteError(w, r, authPageHandlers.metrics, err)
@@ -137,26 +227,24 @@ func (authPageHandlers *AuthPageHandlers) Login(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	uuidCookie := &http.Cookie{
-		Name:     "user_uuid",
-		Value:    user.User.Uuid,
-		Path:     "/",
-		HttpOnly: false,
-		Secure:   false,
-		MaxAge:   0,
+	reqReset := session.ResetLoginAttemptsRequest{Login: user.Email}
+	_, err = (*authPageHandlers.sessionsClient).ResetLoginAttempts(ctx, &reqReset)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to reset login attempts: %v\n", requestID, err)
 	}
-	http.SetCookie(w, uuidCookie)
 
-	tokenCookie := &http.Cookie{
-		Name:     "access",
-		Value:    tokenSigned,
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   false,
-		MaxAge:   tokenCookieExpirationTime,
+	// о входе с нового устройства users сообщает пользователю письмом, вход при этом не блокируется
+	reqDevice := session.RegisterLoginDeviceRequest{Login: user.Email, UserAgent: r.UserAgent(), Ip: ip}
+	_, err = (*authPageHandlers.usersClient).RegisterLoginDevice(ctx, &reqDevice)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to register login device: %v\n", requestID, err)
 	}
 
-	http.SetCookie(w, tokenCookie)
+	event := audit.NewEvent(ctx, audit.ActionLoginSucceeded, user.Email, "")
+	event.Actor = user.Email
+	authPageHandlers.recordAuditEvent(ctx, event)
+
+	setTokenCookies(w, authPageHandlers.cookies, tokenSigned, refreshToken.RefreshToken, user.Uuid, rememberMe)
 	err = WriteSuccess(w, r, authPageHandlers.metrics)
 	if err != nil {
 		authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
@@ -187,7 +275,16 @@ func (authPageHandlers *AuthPageHandlers) Logout(w http.ResponseWriter, r *http.
 		return
 	}
 
-	tokenClaims, err := IsTokenValid(userToken, os.Getenv("SECRETKEY"))
+	tokenClaims, err := IsTokenValid(userToken, authPageHandlers.keyManager)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	userPrincipal, err := principal.FromClaims(tokenClaims)
 	if err != nil {
 		err = WriteError(w, r, authPageHandlers.metrics, err)
 		if err != nil {
@@ -195,8 +292,11 @@ func (authPageHandlers *AuthPageHandlers) Logout(w http.ResponseWriter, r *http.
 		}
 		return
 	}
+	if userPrincipal.SessionId == "" {
+		userPrincipal.SessionId = userToken.Value
+	}
 
-	reqDel := session.DeleteSessionRequest{Login: tokenClaims["Login"].(string), Token: userToken.Value}
+	reqDel := session.DeleteSessionRequest{Login: userPrincipal.Login, Token: userPrincipal.SessionId}
 	_, err = (*authPageHandlers.sessionsClient).DeleteSession(ctx, &reqDel)
 	if err != nil {
 		err = WriteError(w, r, authPageHandlers.metrics, err)
@@ -205,18 +305,20 @@ func (authPageHandlers *AuthPageHandlers) Logout(w http.ResponseWriter, r *http.
 		}
 		return
 	}
-	tokenCookie := &http.Cookie{
-		Name:     "access",
-		Value:    "",
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   false,
-		MaxAge:   0,
+
+	reqRevoke := session.RevokeRefreshTokenFamilyRequest{Login: userPrincipal.Login, Family: userPrincipal.SessionId}
+	_, err = (*authPageHandlers.sessionsClient).RevokeRefreshTokenFamily(ctx, &reqRevoke)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to revoke refresh tokens: %v\n", requestID, err)
 	}
 
-	http.SetCookie(w, tokenCookie)
+	event := audit.NewEvent(ctx, audit.ActionLogout, userPrincipal.Login, "")
+	event.Actor = userPrincipal.Login
+	authPageHandlers.recordAuditEvent(ctx, event)
 
-	reqCheck := session.GetVersionRequest{Login: tokenClaims["Login"].(string), Token: userToken.Value}
+	clearTokenCookies(w, authPageHandlers.cookies)
+
+	reqCheck := session.GetVersionRequest{Login: userPrincipal.Login, Token: userPrincipal.SessionId}
 	_, err = (*authPageHandlers.sessionsClient).GetVersion(ctx, &reqCheck)
 	if err != nil {
 		authPageHandlers.logger.Info(fmt.Sprintf("[reqid=%s] success logout", requestID))
@@ -291,7 +393,8 @@ func (authPageHandlers *AuthPageHandlers) Signup(w http.ResponseWriter, r *http.
 		return
 	}
 
-	tokenSigned, err := GenerateTokens(user.Email, false, version)
+	reqGetUser := session.GetUserRequest{Login: user.Email}
+	userForUuid, err := (*authPageHandlers.usersClient).GetUser(ctx, &reqGetUser)
 	if err != nil {
 		err = WriteError(w, r, authPageHandlers.metrics, err)
 		if err != nil {
@@ -300,8 +403,8 @@ func (authPageHandlers *AuthPageHandlers) Signup(w http.ResponseWriter, r *http.
 		return
 	}
 
-	reqAdd := session.AddRequest{Login: user.Email, Token: tokenSigned}
-	_, err = (*authPageHandlers.sessionsClient).Add(ctx, &reqAdd)
+	reqIssue := session.IssueRefreshTokenRequest{Login: user.Email}
+	refreshToken, err := (*authPageHandlers.sessionsClient).IssueRefreshToken(ctx, &reqIssue)
 	if err != nil {
 		err = WriteError(w, r, authPageHandlers.metrics, err)
 		if err != nil {
@@ -310,8 +413,8 @@ func (authPageHandlers *AuthPageHandlers) Signup(w http.ResponseWriter, r *http.
 		return
 	}
 
-	reqGetUser := session.GetUserRequest{Login: user.Email}
-	userForUuid, err := (*authPageHandlers.usersClient).GetUser(ctx, &reqGetUser)
+	tokenSigned, err := GenerateTokens(authPageHandlers.keyManager, user.Email, userForUuid.User.Uuid,
+		refreshToken.Family, false, userForUuid.User.Roles, version)
 	if err != nil {
 		err = WriteError(w, r, authPageHandlers.metrics, err)
 		if err != nil {
@@ -320,26 +423,19 @@ func (authPageHandlers *AuthPageHandlers) Signup(w http.ResponseWriter, r *http.
 		return
 	}
 
-	uuidCookie := &http.Cookie{
-		Name:     "user_uuid",
-		Value:    userForUuid.User.Uuid,
-		Path:     "/",
-		HttpOnly: false,
-		Secure:   false,
-		MaxAge:   0,
-	}
-	http.SetCookie(w, uuidCookie)
-
-	tokenCookie := &http.Cookie{
-		Name:     "access",
-		Value:    tokenSigned,
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   false,
-		MaxAge:   tokenCookieExpirationTime,
+	reqAdd := session.AddRequest{Login: user.Email, Token: refreshToken.Family, Version: version,
+		UserAgent: r.UserAgent(), Ip: clientip.FromRequest(r)}
+	_, err = (*authPageHandlers.sessionsClient).Add(ctx, &reqAdd)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
 	}
 
-	http.SetCookie(w, tokenCookie)
+	setTokenCookies(w, authPageHandlers.cookies, tokenSigned, refreshToken.RefreshToken, userForUuid.User.Uuid,
+		inputUserData.RememberMe)
 
 	err = WriteSuccess(w, r, authPageHandlers.metrics)
 	if err != nil {
@@ -360,7 +456,7 @@ func (authPageHandlers *AuthPageHandlers) Check(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	tokenClaims, err := IsTokenValid(userToken, os.Getenv("SECRETKEY"))
+	tokenClaims, err := IsTokenValid(userToken, authPageHandlers.keyManager)
 	if err != nil {
 		err = WriteError(w, r, authPageHandlers.metrics, err)
 		if err != nil {
@@ -369,7 +465,20 @@ func (authPageHandlers *AuthPageHandlers) Check(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	reqHas := session.HasSessionRequest{Login: tokenClaims["Login"].(string), Token: userToken.Value}
+	userPrincipal, err := principal.FromClaims(tokenClaims)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	if userPrincipal.SessionId == "" {
+		userPrincipal.SessionId = userToken.Value
+	}
+
+	reqHas := session.HasSessionRequest{Login: userPrincipal.Login, Token: userPrincipal.SessionId}
 	_, err = (*authPageHandlers.sessionsClient).HasSession(ctx, &reqHas)
 	if err != nil {
 		err = WriteError(w, r, authPageHandlers.metrics, myerrors.ErrNoActiveSession)
@@ -379,19 +488,228 @@ func (authPageHandlers *AuthPageHandlers) Check(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	tokenSigned, err := GenerateTokens(tokenClaims["Login"].(string), tokenClaims["IsAdmin"].(bool),
-		uint32(tokenClaims["Version"].(float64)))
+	reqVersion := session.CheckVersionRequest{Login: userPrincipal.Login, Token: userPrincipal.SessionId,
+		Version: userPrincipal.Version}
+	version, err := (*authPageHandlers.sessionsClient).CheckVersion(ctx, &reqVersion)
+	if err != nil || !version.HasSession {
+		err = WriteError(w, r, authPageHandlers.metrics, myerrors.ErrWrongSessionVersion)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	jsonResponse, err := json.Marshal(domain.CheckResponse{Status: http.StatusOK, Uuid: userPrincipal.UserUuid})
 	if err != nil {
 		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to marshal response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	err = WriteResponse(w, r, authPageHandlers.metrics, jsonResponse, requestID)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+	}
+}
+
+// @Summary      Обновление токенов
+// @Description  Обменивает refresh токен на новую пару access и refresh токенов. Повторное использование
+// @Description  уже обмененного refresh токена завершает сессию
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  object  "Токены обновлены"
+// @Failure      401  {object}  object  "Ошибка авторизации"
+// @Failure      500  {object}  object  "Внутренняя ошибка сервера"
+// @Router       /auth/refresh [post]
+func (authPageHandlers *AuthPageHandlers) Refresh(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	requestID := ctx.Value(reqid.ReqIDKey)
+
+	refreshCookie, err := r.Cookie("refresh")
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, myerrors.ErrNotAuthorised)
 		if err != nil {
 			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
 		}
 		return
 	}
 
-	reqAdd := session.AddRequest{Login: tokenClaims["Login"].(string), Token: tokenSigned,
-		Version: uint32(tokenClaims["Version"].(float64))}
-	_, err = (*authPageHandlers.sessionsClient).Add(ctx, &reqAdd)
+	// сессия и пользователь проверяются до обмена: после обмена старый токен уже не принимается,
+	// и ошибка проверки оставила бы клиента без обоих токенов
+	reqGetToken := session.GetRefreshTokenRequest{RefreshToken: refreshCookie.Value}
+	refreshToken, err := (*authPageHandlers.sessionsClient).GetRefreshToken(ctx, &reqGetToken)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to get refresh token: %v\n", requestID, err)
+		clearTokenCookies(w, authPageHandlers.cookies)
+		err = WriteError(w, r, authPageHandlers.metrics, myerrors.ErrNotAuthorised)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	reqVersion := session.GetVersionRequest{Login: refreshToken.Login, Token: refreshToken.Family}
+	version, err := (*authPageHandlers.sessionsClient).GetVersion(ctx, &reqVersion)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, myerrors.ErrNoActiveSession)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	reqGetUser := session.GetUserRequest{Login: refreshToken.Login}
+	user, err := (*authPageHandlers.usersClient).GetUser(ctx, &reqGetUser)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	tokenSigned, err := GenerateTokens(authPageHandlers.keyManager, user.User.Email, user.User.Uuid,
+		refreshToken.Family, user.User.IsAdmin, user.User.Roles, version.Version)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	reqRotate := session.RotateRefreshTokenRequest{RefreshToken: refreshCookie.Value}
+	rotated, err := (*authPageHandlers.sessionsClient).RotateRefreshToken(ctx, &reqRotate)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to rotate refresh token: %v\n", requestID, err)
+		clearTokenCookies(w, authPageHandlers.cookies)
+		err = WriteError(w, r, authPageHandlers.metrics, myerrors.ErrNotAuthorised)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	// срок cookie не продлевается дольше выбранного при входе
+	_, err = r.Cookie(rememberMeCookie)
+	rememberMe := err == nil
+	setTokenCookies(w, authPageHandlers.cookies, tokenSigned, rotated.RefreshToken, user.User.Uuid, rememberMe)
+	err = WriteSuccess(w, r, authPageHandlers.metrics)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+	}
+}
+
+// @Summary      Активные сессии
+// @Description  Возвращает сессии пользователя с данными устройств, текущая сессия отмечена флагом current
+// @Tags         Auth
+// @Produce      json
+// @Success      200  {object}  domain.SessionsResponse  "Список сессий"
+// @Failure      401  {object}  object                   "Ошибка авторизации"
+// @Failure      500  {object}  object                   "Внутренняя ошибка сервера"
+// @Router       /auth/sessions [get]
+func (authPageHandlers *AuthPageHandlers) Sessions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	requestID := ctx.Value(reqid.ReqIDKey)
+
+	userPrincipal, err := getPrincipal(r)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	reqList := session.ListSessionsRequest{Login: userPrincipal.Login}
+	sessions, err := (*authPageHandlers.sessionsClient).ListSessions(ctx, &reqList)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	response := domain.SessionsResponse{
+		Status:   http.StatusOK,
+		Sessions: convertSessionsToRegular(sessions.Sessions, userPrincipal.SessionId),
+	}
+
+	jsonResponse, err := json.Marshal(response)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to marshal response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	err = WriteResponse(w, r, authPageHandlers.metrics, jsonResponse, requestID)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+	}
+}
+
+// @Summary      Завершение сессии
+// @Description  Завершает сессию пользователя и отзывает ее refresh токены
+// @Tags         Auth
+// @Produce      json
+// @Param        id   path      string  true  "Идентификатор сессии"
+// @Success      200  {object}  object  "Сессия завершена"
+// @Failure      401  {object}  object  "Ошибка авторизации"
+// @Failure      500  {object}  object  "Внутренняя ошибка сервера"
+// @Router       /auth/sessions/{id}/revoke [post]
+func (authPageHandlers *AuthPageHandlers) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	requestID := ctx.Value(reqid.ReqIDKey)
+
+	userPrincipal, err := getPrincipal(r)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	sessionId := mux.Vars(r)["id"]
+	reqRevoke := session.RevokeSessionRequest{Login: userPrincipal.Login, Token: sessionId}
+	_, err = (*authPageHandlers.sessionsClient).RevokeSession(ctx, &reqRevoke)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	if sessionId == userPrincipal.SessionId {
+		clearTokenCookies(w, authPageHandlers.cookies)
+	}
+
+	err = WriteSuccess(w, r, authPageHandlers.metrics)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+	}
+}
+
+// @Summary      Выход на остальных устройствах
+// @Description  Завершает все сессии пользователя, кроме текущей
+// @Tags         Auth
+// @Produce      json
+// @Success      200  {object}  object  "Сессии завершены"
+// @Failure      401  {object}  object  "Ошибка авторизации"
+// @Failure      500  {object}  object  "Внутренняя ошибка сервера"
+// @Router       /auth/sessions/revoke_others [post]
+func (authPageHandlers *AuthPageHandlers) RevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	requestID := ctx.Value(reqid.ReqIDKey)
+
+	userPrincipal, err := getPrincipal(r)
 	if err != nil {
 		err = WriteError(w, r, authPageHandlers.metrics, err)
 		if err != nil {
@@ -400,17 +718,426 @@ func (authPageHandlers *AuthPageHandlers) Check(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	tokenCookie := &http.Cookie{
-		Name:     "access",
-		Value:    tokenSigned,
-		Path:     "/",
-		HttpOnly: true,
-		MaxAge:   tokenCookieExpirationTime,
+	reqRevoke := session.RevokeOtherSessionsRequest{Login: userPrincipal.Login, CurrentToken: userPrincipal.SessionId}
+	_, err = (*authPageHandlers.sessionsClient).RevokeOtherSessions(ctx, &reqRevoke)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
 	}
 
-	http.SetCookie(w, tokenCookie)
 	err = WriteSuccess(w, r, authPageHandlers.metrics)
 	if err != nil {
 		authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
 	}
 }
+
+// @Summary      Запрос восстановления пароля
+// @Description  Отправляет на email ссылку для восстановления пароля. Ответ не зависит от того,
+// @Description  зарегистрирован ли адрес
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      domain.ForgotPasswordRequest  true  "Email пользователя"
+// @Success      200      {object}  object                        "Письмо отправлено, если адрес зарегистрирован"
+// @Failure      400      {object}  object                        "Ошибка валидации"
+// @Failure      500      {object}  object                        "Внутренняя ошибка сервера"
+// @Router       /auth/password/forgot [post]
+func (authPageHandlers *AuthPageHandlers) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	requestID := ctx.Value(reqid.ReqIDKey)
+
+	var request domain.ForgotPasswordRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to decode: %v\n", requestID, myerrors.ErrFailedDecode)
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	err = ValidateLogin(request.Email)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] login is not valid: %v\n", requestID,
+			myerrors.ErrLoginIsNotValid)
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	reqReset := session.RequestPasswordResetRequest{Login: request.Email}
+	_, err = (*authPageHandlers.usersClient).RequestPasswordReset(ctx, &reqReset)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	err = WriteSuccess(w, r, authPageHandlers.metrics)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+	}
+}
+
+// @Summary      Восстановление пароля
+// @Description  Задает новый пароль по одноразовому токену из письма и завершает все сессии пользователя
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      domain.ResetPasswordRequest  true  "Токен из письма и новый пароль"
+// @Success      200      {object}  object                       "Пароль изменен"
+// @Failure      400      {object}  object                       "Ошибка валидации или недействительный токен"
+// @Failure      500      {object}  object                       "Внутренняя ошибка сервера"
+// @Router       /auth/password/reset [post]
+func (authPageHandlers *AuthPageHandlers) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	requestID := ctx.Value(reqid.ReqIDKey)
+
+	var request domain.ResetPasswordRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to decode: %v\n", requestID, myerrors.ErrFailedDecode)
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	err = ValidatePassword(request.Password)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] password is not valid: %v\n", requestID,
+			myerrors.ErrPasswordIsToShort)
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	reqReset := session.ResetPasswordRequest{Token: request.Token, NewPassword: request.Password}
+	reset, err := (*authPageHandlers.usersClient).ResetPassword(ctx, &reqReset)
+	if status.Code(err) == codes.InvalidArgument {
+		err = myerrors.ErrInvalidResetToken
+	}
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	// версии сессий хранятся в самих сессиях, поэтому после смены пароля все сессии пользователя
+	// вместе с refresh токенами завершаются, а старые access токены перестают проходить проверку версии
+	reqRevoke := session.RevokeOtherSessionsRequest{Login: reset.Login}
+	_, err = (*authPageHandlers.sessionsClient).RevokeOtherSessions(ctx, &reqRevoke)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to revoke sessions: %v\n", requestID, err)
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	reqUnlock := session.ResetLoginAttemptsRequest{Login: reset.Login}
+	_, err = (*authPageHandlers.sessionsClient).ResetLoginAttempts(ctx, &reqUnlock)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to reset login attempts: %v\n", requestID, err)
+	}
+
+	clearTokenCookies(w, authPageHandlers.cookies)
+	err = WriteSuccess(w, r, authPageHandlers.metrics)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+	}
+
+	authPageHandlers.logger.Info(fmt.Sprintf("[reqid=%s] password reset", requestID))
+}
+
+// @Summary      Сообщение о чужом входе
+// @Description  Обрабатывает ссылку «это был не я» из письма о входе с нового устройства: завершает все сессии
+// @Description  пользователя, заменяет пароль и отправляет письмо для восстановления пароля
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      domain.ReportUnknownLoginRequest  true  "Токен из письма"
+// @Success      200      {object}  object                            "Сессии завершены"
+// @Failure      400      {object}  object                            "Недействительный токен"
+// @Failure      500      {object}  object                            "Внутренняя ошибка сервера"
+// @Router       /auth/unknown-login [post]
+func (authPageHandlers *AuthPageHandlers) ReportUnknownLogin(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	requestID := ctx.Value(reqid.ReqIDKey)
+
+	var request domain.ReportUnknownLoginRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to decode: %v\n", requestID, myerrors.ErrFailedDecode)
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	reqReport := session.ReportUnknownLoginRequest{Token: request.Token}
+	report, err := (*authPageHandlers.usersClient).ReportUnknownLogin(ctx, &reqReport)
+	if status.Code(err) == codes.InvalidArgument {
+		err = myerrors.ErrInvalidUnknownLoginToken
+	}
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	// тот, кто вошел, знает пароль и мог открыть не одну сессию, поэтому завершаются все
+	reqRevoke := session.RevokeOtherSessionsRequest{Login: report.Login}
+	_, err = (*authPageHandlers.sessionsClient).RevokeOtherSessions(ctx, &reqRevoke)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to revoke sessions: %v\n", requestID, err)
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	clearTokenCookies(w, authPageHandlers.cookies)
+	err = WriteSuccess(w, r, authPageHandlers.metrics)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+	}
+
+	authPageHandlers.logger.Info(fmt.Sprintf("[reqid=%s] unknown login reported", requestID))
+}
+
+// @Summary      Подтверждение почты
+// @Description  Отмечает почту пользователя подтвержденной по одноразовому токену из письма
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      domain.VerifyEmailRequest  true  "Токен из письма"
+// @Success      200      {object}  object                     "Почта подтверждена"
+// @Failure      400      {object}  object                     "Недействительный токен"
+// @Failure      500      {object}  object                     "Внутренняя ошибка сервера"
+// @Router       /auth/email/verify [post]
+func (authPageHandlers *AuthPageHandlers) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	requestID := ctx.Value(reqid.ReqIDKey)
+
+	var request domain.VerifyEmailRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to decode: %v\n", requestID, myerrors.ErrFailedDecode)
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	reqVerify := session.VerifyEmailRequest{Token: request.Token}
+	_, err = (*authPageHandlers.usersClient).VerifyEmail(ctx, &reqVerify)
+	if status.Code(err) == codes.InvalidArgument {
+		err = myerrors.ErrInvalidVerificationToken
+	}
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	err = WriteSuccess(w, r, authPageHandlers.metrics)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+	}
+}
+
+// @Summary      Повторная отправка письма с подтверждением почты
+// @Description  Письмо можно запросить не чаще раза в минуту и не больше пяти раз в час
+// @Tags         Auth
+// @Produce      json
+// @Success      200  {object}  object  "Письмо отправлено"
+// @Failure      400  {object}  object  "Почта уже подтверждена"
+// @Failure      401  {object}  object  "Не авторизован"
+// @Failure      429  {object}  object  "Письмо недавно уже отправлялось"
+// @Failure      500  {object}  object  "Внутренняя ошибка сервера"
+// @Router       /auth/email/resend [post]
+func (authPageHandlers *AuthPageHandlers) ResendEmailVerification(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	requestID := ctx.Value(reqid.ReqIDKey)
+
+	userPrincipal, err := getPrincipal(r)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	reqResend := session.ResendEmailVerificationRequest{Login: userPrincipal.Login}
+	_, err = (*authPageHandlers.usersClient).ResendEmailVerification(ctx, &reqResend)
+	switch status.Code(err) {
+	case codes.FailedPrecondition:
+		err = myerrors.ErrEmailAlreadyVerified
+	case codes.ResourceExhausted:
+		err = myerrors.ErrTooManyVerificationRequests
+	}
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	err = WriteSuccess(w, r, authPageHandlers.metrics)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+	}
+}
+
+// @Summary      Снятие блокировки входа
+// @Description  Сбрасывает неудачные попытки входа для аккаунта и, если указан, для IP адреса
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      domain.UnlockLoginRequest  true  "Логин и IP адрес"
+// @Success      200      {object}  object                     "Блокировка снята"
+// @Failure      401      {object}  object                     "Ошибка авторизации"
+// @Failure      403      {object}  object                     "Недостаточно прав"
+// @Failure      500      {object}  object                     "Внутренняя ошибка сервера"
+// @Router       /auth/unlock [post]
+func (authPageHandlers *AuthPageHandlers) UnlockLogin(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	requestID := ctx.Value(reqid.ReqIDKey)
+
+	var request domain.UnlockLoginRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to decode: %v\n", requestID, myerrors.ErrFailedDecode)
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	reqUnlock := session.UnlockLoginRequest{Login: request.Login, Ip: request.Ip}
+	_, err = (*authPageHandlers.sessionsClient).UnlockLogin(ctx, &reqUnlock)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	err = WriteSuccess(w, r, authPageHandlers.metrics)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+	}
+}
+
+// @Summary      Открытые ключи подписи токенов
+// @Description  Возвращает JWKS с ключами, которыми подписаны действующие access токены
+// @Tags         Auth
+// @Produce      json
+// @Success      200  {object}  signing.JWKS  "Набор ключей"
+// @Failure      500  {object}  object        "Внутренняя ошибка сервера"
+// @Router       /auth/jwks [get]
+func (authPageHandlers *AuthPageHandlers) JWKS(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	requestID := ctx.Value(reqid.ReqIDKey)
+
+	jsonResponse, err := json.Marshal(authPageHandlers.keyManager.JWKS())
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to marshal response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	err = WriteResponse(w, r, authPageHandlers.metrics, jsonResponse, requestID)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+	}
+}
+
+// releaseLoginAttempt снимает зарезервированную попытку, если пароль или код не оказался неверным. Ошибка
+// не мешает входу, попытка останется учтенной как неудачная до конца окна
+func (authPageHandlers *AuthPageHandlers) releaseLoginAttempt(ctx context.Context, login, ip string) {
+	reqRelease := session.ReleaseLoginAttemptRequest{Login: login, Ip: ip}
+	_, err := (*authPageHandlers.sessionsClient).ReleaseLoginAttempt(ctx, &reqRelease)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to release login attempt: %v\n",
+			ctx.Value(reqid.ReqIDKey), err)
+	}
+}
+
+// writeLoginThrottled отвечает на отложенную попытку входа. Retry-After сообщает клиенту, через
+// сколько секунд можно попробовать снова
+func (authPageHandlers *AuthPageHandlers) writeLoginThrottled(w http.ResponseWriter, r *http.Request,
+	retryAfter int64, locked bool) {
+	requestID := r.Context().Value(reqid.ReqIDKey)
+
+	loginErr := myerrors.ErrTooManyLoginAttempts
+	if locked {
+		loginErr = myerrors.ErrAccountLocked
+	}
+	authPageHandlers.logger.Errorf("[reqid=%s] login throttled for %d seconds: %v\n", requestID, retryAfter,
+		loginErr)
+
+	w.Header().Set("Retry-After", strconv.FormatInt(retryAfter, 10))
+	err := WriteError(w, r, authPageHandlers.metrics, loginErr)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+	}
+}
+
+// setTokenCookies выставляет cookie сессии. refreshToken пустой, когда обновляется только access токен,
+// тогда остальные cookie остаются прежними
+func setTokenCookies(w http.ResponseWriter, cookieFactory *cookies.Factory, accessToken, refreshToken, userUuid string,
+	rememberMe bool) {
+	http.SetCookie(w, cookieFactory.New("access", accessToken, cookies.Options{MaxAge: accessTokenExpirationTime}))
+	if refreshToken == "" {
+		return
+	}
+
+	lifetime := cookieFactory.LoginLifetime(rememberMe)
+	http.SetCookie(w, cookieFactory.New("refresh", refreshToken,
+		cookies.Options{Path: refreshTokenCookiePath, MaxAge: lifetime}))
+	http.SetCookie(w, cookieFactory.New(userUuidCookie, userUuid, cookies.Options{MaxAge: lifetime}))
+	if rememberMe {
+		http.SetCookie(w, cookieFactory.New(rememberMeCookie, "1",
+			cookies.Options{Path: refreshTokenCookiePath, MaxAge: lifetime}))
+	} else {
+		http.SetCookie(w, cookieFactory.Expired(rememberMeCookie, cookies.Options{Path: refreshTokenCookiePath}))
+	}
+}
+
+func clearTokenCookies(w http.ResponseWriter, cookieFactory *cookies.Factory) {
+	http.SetCookie(w, cookieFactory.Expired("access", cookies.Options{}))
+	http.SetCookie(w, cookieFactory.Expired("refresh", cookies.Options{Path: refreshTokenCookiePath}))
+	http.SetCookie(w, cookieFactory.Expired(userUuidCookie, cookies.Options{}))
+	http.SetCookie(w, cookieFactory.Expired(rememberMeCookie, cookies.Options{Path: refreshTokenCookiePath}))
+}
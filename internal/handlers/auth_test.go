@@ -3,25 +3,45 @@ package handlers
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
+	"github.com/SanExpett/diploma/internal/cookies"
 	"github.com/SanExpett/diploma/internal/domain"
+	myerrors "github.com/SanExpett/diploma/internal/errors"
 	"github.com/SanExpett/diploma/internal/handlers/mocks"
 	"github.com/SanExpett/diploma/internal/metrics"
+	"github.com/SanExpett/diploma/internal/principal"
 	session "github.com/SanExpett/diploma/internal/session/proto"
+	"github.com/SanExpett/diploma/internal/signing"
 )
 
+var testCookies = cookies.NewFactory(cookies.Config{
+	SameSite:           http.SameSiteLaxMode,
+	RememberMeLifetime: 30 * 24 * time.Hour,
+})
+
 func TestAuthPageHandlers_Login(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	mockUsersClient := mocks.NewMockUsersClient(ctrl)
+	mockUsersClient.EXPECT().RecordAuditEvent(gomock.Any(), gomock.Any()).
+		Return(&session.RecordAuditEventResponse{}, nil).AnyTimes()
+	mockUsersClient.EXPECT().RegisterLoginDevice(gomock.Any(), gomock.Any()).
+		Return(&session.RegisterLoginDeviceResponse{}, nil).AnyTimes()
 	mockSessionsClient := mocks.NewMockSessionsClient(ctrl)
 
 	var usersClient session.UsersClient = mockUsersClient
@@ -30,7 +50,10 @@ func TestAuthPageHandlers_Login(t *testing.T) {
 	logger := zap.NewNop().Sugar()
 	metrics := metrics.NewHttpMetrics()
 
-	handler := NewAuthPageHandlers(&usersClient, &sessionsClient, metrics, logger)
+	keyManager, err := signing.NewKeyManager(signing.AlgorithmEdDSA, time.Hour, time.Hour)
+	assert.NoError(t, err)
+
+	handler := NewAuthPageHandlers(&usersClient, &sessionsClient, keyManager, testCookies, metrics, logger)
 
 	tests := []struct {
 		name         string
@@ -45,10 +68,19 @@ func TestAuthPageHandlers_Login(t *testing.T) {
 				Password: "password123",
 			},
 			setupMocks: func() {
+				mockSessionsClient.EXPECT().ReserveLoginAttempt(gomock.Any(), &session.ReserveLoginAttemptRequest{
+					Login: "test@test.com",
+					Ip:    "192.0.2.1",
+				}).Return(&session.ReserveLoginAttemptResponse{}, nil)
+
 				mockUsersClient.EXPECT().HasUser(gomock.Any(), &session.HasUserRequest{
 					Login:    "test@test.com",
 					Password: "password123",
 				}).Return(&session.HasUserResponse{Has: false}, nil)
+				mockSessionsClient.EXPECT().ReleaseLoginAttempt(gomock.Any(), &session.ReleaseLoginAttemptRequest{
+					Login: "test@test.com",
+					Ip:    "192.0.2.1",
+				}).Return(&session.ReleaseLoginAttemptResponse{}, nil)
 
 				mockUsersClient.EXPECT().GetUser(gomock.Any(), &session.GetUserRequest{
 					Login: "test@test.com",
@@ -61,7 +93,21 @@ func TestAuthPageHandlers_Login(t *testing.T) {
 					},
 				}, nil)
 
-				mockSessionsClient.EXPECT().Add(gomock.Any(), gomock.Any()).Return(&session.AddResponse{}, nil)
+				mockSessionsClient.EXPECT().IssueRefreshToken(gomock.Any(), &session.IssueRefreshTokenRequest{
+					Login: "test@test.com",
+				}).Return(&session.IssueRefreshTokenResponse{Family: "family", RefreshToken: "refresh-token"}, nil)
+
+				mockSessionsClient.EXPECT().Add(gomock.Any(), &session.AddRequest{
+					Login:     "test@test.com",
+					Token:     "family",
+					Version:   1,
+					UserAgent: "test-agent",
+					Ip:        "192.0.2.1",
+				}).Return(&session.AddResponse{}, nil)
+
+				mockSessionsClient.EXPECT().ResetLoginAttempts(gomock.Any(), &session.ResetLoginAttemptsRequest{
+					Login: "test@test.com",
+				}).Return(&session.ResetLoginAttemptsResponse{}, nil)
 			},
 			expectedCode: http.StatusOK,
 		},
@@ -82,6 +128,7 @@ func TestAuthPageHandlers_Login(t *testing.T) {
 
 			body, _ := json.Marshal(tt.input)
 			req := httptest.NewRequest(http.MethodPost, "/api/auth/login", bytes.NewReader(body))
+			req.Header.Set("User-Agent", "test-agent")
 			w := httptest.NewRecorder()
 
 			handler.Login(w, req)
@@ -91,11 +138,341 @@ func TestAuthPageHandlers_Login(t *testing.T) {
 	}
 }
 
+func TestAuthPageHandlers_LoginThrottling(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUsersClient := mocks.NewMockUsersClient(ctrl)
+	mockUsersClient.EXPECT().RecordAuditEvent(gomock.Any(), gomock.Any()).
+		Return(&session.RecordAuditEventResponse{}, nil).AnyTimes()
+	mockUsersClient.EXPECT().RegisterLoginDevice(gomock.Any(), gomock.Any()).
+		Return(&session.RegisterLoginDeviceResponse{}, nil).AnyTimes()
+	mockSessionsClient := mocks.NewMockSessionsClient(ctrl)
+
+	var usersClient session.UsersClient = mockUsersClient
+	var sessionsClient session.SessionsClient = mockSessionsClient
+
+	handler := NewAuthPageHandlers(&usersClient, &sessionsClient, nil, testCookies, metrics.NewHttpMetrics(),
+		zap.NewNop().Sugar())
+
+	reserveRequest := &session.ReserveLoginAttemptRequest{Login: "test@test.com", Ip: "192.0.2.1"}
+	hasUserRequest := &session.HasUserRequest{Login: "test@test.com", Password: "password123"}
+	failureRequest := &session.RegisterLoginFailureRequest{Login: "test@test.com", Ip: "192.0.2.1"}
+	wrongPassword := status.Error(codes.Unauthenticated, myerrors.ErrIncorrectLoginOrPassword.Error())
+
+	tests := []struct {
+		name               string
+		setupMocks         func()
+		expectedStatus     int
+		expectedCode       string
+		expectedRetryAfter string
+	}{
+		{
+			name: "Задержка после неудачных попыток",
+			setupMocks: func() {
+				mockSessionsClient.EXPECT().ReserveLoginAttempt(gomock.Any(), reserveRequest).
+					Return(&session.ReserveLoginAttemptResponse{RetryAfter: 4}, nil)
+			},
+			expectedStatus:     http.StatusTooManyRequests,
+			expectedCode:       "too_many_attempts",
+			expectedRetryAfter: "4",
+		},
+		{
+			name: "Аккаунт заблокирован",
+			setupMocks: func() {
+				mockSessionsClient.EXPECT().ReserveLoginAttempt(gomock.Any(), reserveRequest).
+					Return(&session.ReserveLoginAttemptResponse{RetryAfter: 1800, Locked: true}, nil)
+			},
+			expectedStatus:     http.StatusTooManyRequests,
+			expectedCode:       "account_locked",
+			expectedRetryAfter: "1800",
+		},
+		{
+			name: "Неверный пароль",
+			setupMocks: func() {
+				mockSessionsClient.EXPECT().ReserveLoginAttempt(gomock.Any(), reserveRequest).
+					Return(&session.ReserveLoginAttemptResponse{}, nil)
+				mockUsersClient.EXPECT().HasUser(gomock.Any(), hasUserRequest).Return(nil, wrongPassword)
+				mockSessionsClient.EXPECT().RegisterLoginFailure(gomock.Any(), failureRequest).
+					Return(&session.RegisterLoginFailureResponse{RetryAfter: 1}, nil)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "invalid_credentials",
+		},
+		{
+			name: "Блокировка после неверного пароля",
+			setupMocks: func() {
+				mockSessionsClient.EXPECT().ReserveLoginAttempt(gomock.Any(), reserveRequest).
+					Return(&session.ReserveLoginAttemptResponse{}, nil)
+				mockUsersClient.EXPECT().HasUser(gomock.Any(), hasUserRequest).Return(nil, wrongPassword)
+				mockSessionsClient.EXPECT().RegisterLoginFailure(gomock.Any(), failureRequest).
+					Return(&session.RegisterLoginFailureResponse{RetryAfter: 1800, Locked: true}, nil)
+			},
+			expectedStatus:     http.StatusTooManyRequests,
+			expectedCode:       "account_locked",
+			expectedRetryAfter: "1800",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMocks()
+
+			body, _ := json.Marshal(domain.UserSignUp{Email: "test@test.com", Password: "password123"})
+			req := httptest.NewRequest(http.MethodPost, "/api/auth/login", bytes.NewReader(body))
+			w := httptest.NewRecorder()
+
+			handler.Login(w, req)
+
+			var response ErrorResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tt.expectedStatus, response.Status)
+			assert.Equal(t, tt.expectedCode, response.Code)
+			assert.Equal(t, tt.expectedRetryAfter, w.Header().Get("Retry-After"))
+		})
+	}
+}
+
+func TestAuthPageHandlers_PasswordReset(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUsersClient := mocks.NewMockUsersClient(ctrl)
+	mockSessionsClient := mocks.NewMockSessionsClient(ctrl)
+
+	var usersClient session.UsersClient = mockUsersClient
+	var sessionsClient session.SessionsClient = mockSessionsClient
+
+	handler := NewAuthPageHandlers(&usersClient, &sessionsClient, nil, testCookies, metrics.NewHttpMetrics(),
+		zap.NewNop().Sugar())
+
+	resetRequest := &session.ResetPasswordRequest{Token: "reset-token", NewPassword: "newpassword123"}
+	invalidToken := status.Error(codes.InvalidArgument, myerrors.ErrInvalidResetToken.Error())
+
+	tests := []struct {
+		name           string
+		request        domain.ResetPasswordRequest
+		setupMocks     func()
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name:    "Успешная смена пароля",
+			request: domain.ResetPasswordRequest{Token: "reset-token", Password: "newpassword123"},
+			setupMocks: func() {
+				mockUsersClient.EXPECT().ResetPassword(gomock.Any(), resetRequest).
+					Return(&session.ResetPasswordResponse{Login: "test@test.com"}, nil)
+				mockSessionsClient.EXPECT().RevokeOtherSessions(gomock.Any(),
+					&session.RevokeOtherSessionsRequest{Login: "test@test.com"}).
+					Return(&session.RevokeOtherSessionsResponse{}, nil)
+				mockSessionsClient.EXPECT().ResetLoginAttempts(gomock.Any(),
+					&session.ResetLoginAttemptsRequest{Login: "test@test.com"}).
+					Return(&session.ResetLoginAttemptsResponse{}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:    "Сессии не завершены",
+			request: domain.ResetPasswordRequest{Token: "reset-token", Password: "newpassword123"},
+			setupMocks: func() {
+				mockUsersClient.EXPECT().ResetPassword(gomock.Any(), resetRequest).
+					Return(&session.ResetPasswordResponse{Login: "test@test.com"}, nil)
+				mockSessionsClient.EXPECT().RevokeOtherSessions(gomock.Any(),
+					&session.RevokeOtherSessionsRequest{Login: "test@test.com"}).
+					Return(nil, status.Error(codes.Unavailable, "sessions are down"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:    "Недействительный токен",
+			request: domain.ResetPasswordRequest{Token: "reset-token", Password: "newpassword123"},
+			setupMocks: func() {
+				mockUsersClient.EXPECT().ResetPassword(gomock.Any(), resetRequest).Return(nil, invalidToken)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "invalid_reset_token",
+		},
+		{
+			name:           "Короткий пароль",
+			request:        domain.ResetPasswordRequest{Token: "reset-token", Password: "123"},
+			setupMocks:     func() {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMocks()
+
+			body, _ := json.Marshal(tt.request)
+			req := httptest.NewRequest(http.MethodPost, "/api/auth/password/reset", bytes.NewReader(body))
+			w := httptest.NewRecorder()
+
+			handler.ResetPassword(w, req)
+
+			var response ErrorResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tt.expectedStatus, response.Status)
+			assert.Equal(t, tt.expectedCode, response.Code)
+		})
+	}
+}
+
+func TestAuthPageHandlers_ReportUnknownLogin(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUsersClient := mocks.NewMockUsersClient(ctrl)
+	mockSessionsClient := mocks.NewMockSessionsClient(ctrl)
+
+	var usersClient session.UsersClient = mockUsersClient
+	var sessionsClient session.SessionsClient = mockSessionsClient
+
+	handler := NewAuthPageHandlers(&usersClient, &sessionsClient, nil, testCookies, metrics.NewHttpMetrics(),
+		zap.NewNop().Sugar())
+
+	reportRequest := &session.ReportUnknownLoginRequest{Token: "report-token"}
+
+	tests := []struct {
+		name           string
+		setupMocks     func()
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name: "Все сессии завершены",
+			setupMocks: func() {
+				mockUsersClient.EXPECT().ReportUnknownLogin(gomock.Any(), reportRequest).
+					Return(&session.ReportUnknownLoginResponse{Login: "test@test.com"}, nil)
+				mockSessionsClient.EXPECT().RevokeOtherSessions(gomock.Any(),
+					&session.RevokeOtherSessionsRequest{Login: "test@test.com"}).
+					Return(&session.RevokeOtherSessionsResponse{}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "Ссылка уже использована",
+			setupMocks: func() {
+				mockUsersClient.EXPECT().ReportUnknownLogin(gomock.Any(), reportRequest).
+					Return(nil, status.Error(codes.InvalidArgument, myerrors.ErrInvalidUnknownLoginToken.Error()))
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "invalid_unknown_login_token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMocks()
+
+			body, _ := json.Marshal(domain.ReportUnknownLoginRequest{Token: "report-token"})
+			req := httptest.NewRequest(http.MethodPost, "/api/auth/unknown-login", bytes.NewReader(body))
+			w := httptest.NewRecorder()
+
+			handler.ReportUnknownLogin(w, req)
+
+			var response ErrorResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tt.expectedStatus, response.Status)
+			assert.Equal(t, tt.expectedCode, response.Code)
+		})
+	}
+}
+
+func TestAuthPageHandlers_EmailVerification(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUsersClient := mocks.NewMockUsersClient(ctrl)
+	mockSessionsClient := mocks.NewMockSessionsClient(ctrl)
+
+	var usersClient session.UsersClient = mockUsersClient
+	var sessionsClient session.SessionsClient = mockSessionsClient
+
+	handler := NewAuthPageHandlers(&usersClient, &sessionsClient, nil, testCookies, metrics.NewHttpMetrics(),
+		zap.NewNop().Sugar())
+
+	resendRequest := &session.ResendEmailVerificationRequest{Login: "test@test.com"}
+
+	tests := []struct {
+		name           string
+		handler        http.HandlerFunc
+		body           any
+		setupMocks     func()
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name:    "Почта подтверждена",
+			handler: handler.VerifyEmail,
+			body:    domain.VerifyEmailRequest{Token: "token"},
+			setupMocks: func() {
+				mockUsersClient.EXPECT().VerifyEmail(gomock.Any(), &session.VerifyEmailRequest{Token: "token"}).
+					Return(&session.VerifyEmailResponse{Login: "test@test.com"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:    "Недействительный токен подтверждения",
+			handler: handler.VerifyEmail,
+			body:    domain.VerifyEmailRequest{Token: "used"},
+			setupMocks: func() {
+				mockUsersClient.EXPECT().VerifyEmail(gomock.Any(), &session.VerifyEmailRequest{Token: "used"}).
+					Return(nil, status.Error(codes.InvalidArgument, myerrors.ErrInvalidVerificationToken.Error()))
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "invalid_verification_token",
+		},
+		{
+			name:    "Повторная отправка письма",
+			handler: handler.ResendEmailVerification,
+			setupMocks: func() {
+				mockUsersClient.EXPECT().ResendEmailVerification(gomock.Any(), resendRequest).
+					Return(&session.ResendEmailVerificationResponse{}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:    "Письмо запрошено слишком часто",
+			handler: handler.ResendEmailVerification,
+			setupMocks: func() {
+				mockUsersClient.EXPECT().ResendEmailVerification(gomock.Any(), resendRequest).
+					Return(nil, status.Error(codes.ResourceExhausted, myerrors.ErrTooManyVerificationRequests.Error()))
+			},
+			expectedStatus: http.StatusTooManyRequests,
+			expectedCode:   "too_many_requests",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMocks()
+
+			body, _ := json.Marshal(tt.body)
+			req := httptest.NewRequest(http.MethodPost, "/api/auth/email", bytes.NewReader(body))
+			req = req.WithContext(principal.WithPrincipal(req.Context(),
+				principal.Principal{UserUuid: "1", Login: "test@test.com"}))
+			w := httptest.NewRecorder()
+
+			tt.handler(w, req)
+
+			var response ErrorResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tt.expectedStatus, response.Status)
+			assert.Equal(t, tt.expectedCode, response.Code)
+		})
+	}
+}
+
 func TestAuthPageHandlers_Logout(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	mockUsersClient := mocks.NewMockUsersClient(ctrl)
+	mockUsersClient.EXPECT().RecordAuditEvent(gomock.Any(), gomock.Any()).
+		Return(&session.RecordAuditEventResponse{}, nil).AnyTimes()
+	mockUsersClient.EXPECT().RegisterLoginDevice(gomock.Any(), gomock.Any()).
+		Return(&session.RegisterLoginDeviceResponse{}, nil).AnyTimes()
 	mockSessionsClient := mocks.NewMockSessionsClient(ctrl)
 
 	var usersClient session.UsersClient = mockUsersClient
@@ -104,7 +481,10 @@ func TestAuthPageHandlers_Logout(t *testing.T) {
 	logger := zap.NewNop().Sugar()
 	metrics := metrics.NewHttpMetrics()
 
-	handler := NewAuthPageHandlers(&usersClient, &sessionsClient, metrics, logger)
+	keyManager, err := signing.NewKeyManager(signing.AlgorithmEdDSA, time.Hour, time.Hour)
+	assert.NoError(t, err)
+
+	handler := NewAuthPageHandlers(&usersClient, &sessionsClient, keyManager, testCookies, metrics, logger)
 
 	tests := []struct {
 		name         string
@@ -123,6 +503,8 @@ func TestAuthPageHandlers_Logout(t *testing.T) {
 			},
 			setupMocks: func() {
 				mockSessionsClient.EXPECT().DeleteSession(gomock.Any(), gomock.Any()).Return(&session.DeleteSessionResponse{}, nil)
+				mockSessionsClient.EXPECT().RevokeRefreshTokenFamily(gomock.Any(), gomock.Any()).
+					Return(&session.RevokeRefreshTokenFamilyResponse{}, nil)
 				mockSessionsClient.EXPECT().GetVersion(gomock.Any(), gomock.Any()).Return(&session.GetVersionResponse{}, nil)
 			},
 			expectedCode: http.StatusOK,
@@ -149,3 +531,281 @@ func TestAuthPageHandlers_Logout(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthPageHandlers_Refresh(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUsersClient := mocks.NewMockUsersClient(ctrl)
+	mockSessionsClient := mocks.NewMockSessionsClient(ctrl)
+
+	var usersClient session.UsersClient = mockUsersClient
+	var sessionsClient session.SessionsClient = mockSessionsClient
+
+	logger := zap.NewNop().Sugar()
+	metrics := metrics.NewHttpMetrics()
+
+	keyManager, err := signing.NewKeyManager(signing.AlgorithmEdDSA, time.Hour, time.Hour)
+	assert.NoError(t, err)
+
+	handler := NewAuthPageHandlers(&usersClient, &sessionsClient, keyManager, testCookies, metrics, logger)
+
+	getTokenRequest := &session.GetRefreshTokenRequest{RefreshToken: "refresh-token"}
+	storedToken := &session.GetRefreshTokenResponse{Login: "test@test.com", Family: "family"}
+
+	tests := []struct {
+		name                 string
+		refreshToken         string
+		rememberMe           bool
+		setupMocks           func()
+		expectedStatus       int
+		expectedRefreshToken string
+		expectedMaxAge       int
+	}{
+		{
+			name:           "Нет refresh токена",
+			setupMocks:     func() {},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:         "Повторное использование refresh токена",
+			refreshToken: "used-refresh-token",
+			setupMocks: func() {
+				mockSessionsClient.EXPECT().GetRefreshToken(gomock.Any(), &session.GetRefreshTokenRequest{
+					RefreshToken: "used-refresh-token",
+				}).Return(nil, errors.New("refresh token has already been used"))
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:         "Сессия завершена",
+			refreshToken: "refresh-token",
+			setupMocks: func() {
+				mockSessionsClient.EXPECT().GetRefreshToken(gomock.Any(), getTokenRequest).Return(storedToken, nil)
+				mockSessionsClient.EXPECT().GetVersion(gomock.Any(), gomock.Any()).
+					Return(nil, status.Error(codes.NotFound, myerrors.ErrNoSuchSessionInTheCache.Error()))
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:         "Пользователь недоступен",
+			refreshToken: "refresh-token",
+			setupMocks: func() {
+				mockSessionsClient.EXPECT().GetRefreshToken(gomock.Any(), getTokenRequest).Return(storedToken, nil)
+				mockSessionsClient.EXPECT().GetVersion(gomock.Any(), gomock.Any()).
+					Return(&session.GetVersionResponse{Version: 2}, nil)
+				mockUsersClient.EXPECT().GetUser(gomock.Any(), gomock.Any()).
+					Return(nil, status.Error(codes.Unavailable, "users are down"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:         "Успешное обновление",
+			refreshToken: "refresh-token",
+			setupMocks: func() {
+				mockSessionsClient.EXPECT().GetRefreshToken(gomock.Any(), getTokenRequest).Return(storedToken, nil)
+				mockSessionsClient.EXPECT().RotateRefreshToken(gomock.Any(), &session.RotateRefreshTokenRequest{
+					RefreshToken: "refresh-token",
+				}).Return(&session.RotateRefreshTokenResponse{
+					Login:        "test@test.com",
+					Family:       "family",
+					RefreshToken: "new-refresh-token",
+				}, nil)
+				mockSessionsClient.EXPECT().GetVersion(gomock.Any(), &session.GetVersionRequest{
+					Login: "test@test.com",
+					Token: "family",
+				}).Return(&session.GetVersionResponse{Version: 2}, nil)
+				mockUsersClient.EXPECT().GetUser(gomock.Any(), &session.GetUserRequest{
+					Login: "test@test.com",
+				}).Return(&session.GetUserResponse{User: &session.User{Email: "test@test.com", Uuid: "test-uuid"}},
+					nil)
+			},
+			expectedStatus:       http.StatusOK,
+			expectedRefreshToken: "new-refresh-token",
+		},
+		{
+			name:         "Обновление входа с «запомнить меня»",
+			refreshToken: "refresh-token",
+			rememberMe:   true,
+			setupMocks: func() {
+				mockSessionsClient.EXPECT().GetRefreshToken(gomock.Any(), getTokenRequest).Return(storedToken, nil)
+				mockSessionsClient.EXPECT().RotateRefreshToken(gomock.Any(), &session.RotateRefreshTokenRequest{
+					RefreshToken: "refresh-token",
+				}).Return(&session.RotateRefreshTokenResponse{
+					Login:        "test@test.com",
+					Family:       "family",
+					RefreshToken: "new-refresh-token",
+				}, nil)
+				mockSessionsClient.EXPECT().GetVersion(gomock.Any(), &session.GetVersionRequest{
+					Login: "test@test.com",
+					Token: "family",
+				}).Return(&session.GetVersionResponse{Version: 2}, nil)
+				mockUsersClient.EXPECT().GetUser(gomock.Any(), &session.GetUserRequest{
+					Login: "test@test.com",
+				}).Return(&session.GetUserResponse{User: &session.User{Email: "test@test.com", Uuid: "test-uuid"}},
+					nil)
+			},
+			expectedStatus:       http.StatusOK,
+			expectedRefreshToken: "new-refresh-token",
+			expectedMaxAge:       30 * 24 * 3600,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMocks()
+
+			req := httptest.NewRequest(http.MethodPost, "/api/auth/refresh", nil)
+			if tt.refreshToken != "" {
+				req.AddCookie(&http.Cookie{Name: "refresh", Value: tt.refreshToken})
+			}
+			if tt.rememberMe {
+				req.AddCookie(&http.Cookie{Name: rememberMeCookie, Value: "1"})
+			}
+			w := httptest.NewRecorder()
+
+			handler.Refresh(w, req)
+
+			var response ErrorResponse
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tt.expectedStatus, response.Status)
+
+			cookies := make(map[string]*http.Cookie)
+			for _, cookie := range w.Result().Cookies() {
+				cookies[cookie.Name] = cookie
+			}
+			if tt.expectedRefreshToken == "" {
+				return
+			}
+
+			assert.Equal(t, tt.expectedRefreshToken, cookies["refresh"].Value)
+			assert.Equal(t, refreshTokenCookiePath, cookies["refresh"].Path)
+			assert.Equal(t, tt.expectedMaxAge, cookies["refresh"].MaxAge)
+			assert.Equal(t, tt.expectedMaxAge, cookies[userUuidCookie].MaxAge)
+			assert.True(t, cookies[userUuidCookie].HttpOnly)
+			assert.Equal(t, http.SameSiteLaxMode, cookies["refresh"].SameSite)
+
+			claims, err := IsTokenValid(cookies["access"], keyManager)
+			assert.NoError(t, err)
+			userPrincipal, err := principal.FromClaims(claims)
+			assert.NoError(t, err)
+			assert.Equal(t, "family", userPrincipal.SessionId)
+			assert.Equal(t, uint32(2), userPrincipal.Version)
+		})
+	}
+}
+
+func TestAuthPageHandlers_Sessions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUsersClient := mocks.NewMockUsersClient(ctrl)
+	mockSessionsClient := mocks.NewMockSessionsClient(ctrl)
+
+	var usersClient session.UsersClient = mockUsersClient
+	var sessionsClient session.SessionsClient = mockSessionsClient
+
+	handler := NewAuthPageHandlers(&usersClient, &sessionsClient, nil, testCookies, metrics.NewHttpMetrics(),
+		zap.NewNop().Sugar())
+
+	lastSeenAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	mockSessionsClient.EXPECT().ListSessions(gomock.Any(), &session.ListSessionsRequest{
+		Login: "test@test.com",
+	}).Return(&session.ListSessionsResponse{
+		Sessions: []*session.SessionInfo{
+			{Id: "family", UserAgent: "<script>", Ip: "192.0.2.1", LastSeenAt: timestamppb.New(lastSeenAt)},
+			{Id: "other", UserAgent: "curl/8.0", Ip: "192.0.2.2", LastSeenAt: timestamppb.New(lastSeenAt)},
+		},
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/sessions", nil)
+	req = req.WithContext(principal.WithPrincipal(req.Context(), principal.Principal{
+		UserUuid:  "test-uuid",
+		Login:     "test@test.com",
+		SessionId: "family",
+	}))
+	w := httptest.NewRecorder()
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/auth/sessions", handler.Sessions)
+	router.ServeHTTP(w, req)
+
+	var response domain.SessionsResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, http.StatusOK, response.Status)
+	if !assert.Len(t, response.Sessions, 2) {
+		return
+	}
+	assert.True(t, response.Sessions[0].Current)
+	assert.Equal(t, "&lt;script&gt;", response.Sessions[0].UserAgent)
+	assert.Equal(t, lastSeenAt, response.Sessions[0].LastSeenAt)
+	assert.False(t, response.Sessions[1].Current)
+}
+
+func TestAuthPageHandlers_RevokeSession(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUsersClient := mocks.NewMockUsersClient(ctrl)
+	mockSessionsClient := mocks.NewMockSessionsClient(ctrl)
+
+	var usersClient session.UsersClient = mockUsersClient
+	var sessionsClient session.SessionsClient = mockSessionsClient
+
+	handler := NewAuthPageHandlers(&usersClient, &sessionsClient, nil, testCookies, metrics.NewHttpMetrics(),
+		zap.NewNop().Sugar())
+
+	tests := []struct {
+		name                 string
+		sessionId            string
+		setupMocks           func()
+		expectedStatus       int
+		expectedClearCookies bool
+	}{
+		{
+			name:      "Завершение другой сессии",
+			sessionId: "other",
+			setupMocks: func() {
+				mockSessionsClient.EXPECT().RevokeSession(gomock.Any(), &session.RevokeSessionRequest{
+					Login: "test@test.com",
+					Token: "other",
+				}).Return(&session.RevokeSessionResponse{}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:      "Завершение текущей сессии",
+			sessionId: "family",
+			setupMocks: func() {
+				mockSessionsClient.EXPECT().RevokeSession(gomock.Any(), &session.RevokeSessionRequest{
+					Login: "test@test.com",
+					Token: "family",
+				}).Return(&session.RevokeSessionResponse{}, nil)
+			},
+			expectedStatus:       http.StatusOK,
+			expectedClearCookies: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMocks()
+
+			req := httptest.NewRequest(http.MethodPost, "/api/auth/sessions/"+tt.sessionId+"/revoke", nil)
+			req = mux.SetURLVars(req, map[string]string{"id": tt.sessionId})
+			req = req.WithContext(principal.WithPrincipal(req.Context(), principal.Principal{
+				UserUuid:  "test-uuid",
+				Login:     "test@test.com",
+				SessionId: "family",
+			}))
+			w := httptest.NewRecorder()
+
+			handler.RevokeSession(w, req)
+
+			var response ErrorResponse
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tt.expectedStatus, response.Status)
+			assert.Equal(t, tt.expectedClearCookies, len(w.Result().Cookies()) > 0)
+		})
+	}
+}
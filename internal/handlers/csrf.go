@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+
+	"github.com/SanExpett/diploma/internal/cookies"
+	"github.com/SanExpett/diploma/internal/domain"
+	myerrors "github.com/SanExpett/diploma/internal/errors"
+	"github.com/SanExpett/diploma/internal/principal"
+	reqid "github.com/SanExpett/diploma/internal/requestId"
+	"github.com/SanExpett/diploma/internal/signing"
+)
+
+const (
+	CsrfCookieName = "csrf"
+	CsrfHeaderName = "X-CSRF-Token"
+
+	csrfTokenAudience = "csrf"
+	csrfTokenTTL      = 12 * time.Hour
+)
+
+// csrfClaims токен CSRF. Nonce совпадает со значением cookie csrf браузера, которому выдан токен,
+// Subject идентификатор сессии, пусто для токена, выданного до входа
+type csrfClaims struct {
+	jwt.StandardClaims
+	Nonce string `json:"nonce"`
+}
+
+// @Summary      Токен CSRF
+// @Description  Выдает токен для заголовка X-CSRF-Token. Токен привязан к браузеру через cookie csrf
+// @Description  и к текущей сессии, поэтому после входа его нужно запросить заново
+// @Tags         Auth
+// @Produce      json
+// @Success      200  {object}  domain.CsrfTokenResponse  "Токен CSRF"
+// @Failure      500  {object}  object                    "Внутренняя ошибка сервера"
+// @Router       /auth/csrf [get]
+func (authPageHandlers *AuthPageHandlers) CsrfToken(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	requestID := ctx.Value(reqid.ReqIDKey)
+
+	// вкладки одного браузера делят cookie, поэтому существующий nonce не заменяется,
+	// иначе новый токен в одной вкладке делал бы недействительными токены остальных
+	var nonce string
+	nonceCookie, err := r.Cookie(CsrfCookieName)
+	if err == nil && nonceCookie.Value != "" {
+		nonce = nonceCookie.Value
+	} else {
+		nonce, err = newCsrfNonce()
+		if err != nil {
+			err = WriteError(w, r, authPageHandlers.metrics, err)
+			if err != nil {
+				authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+			}
+			return
+		}
+		http.SetCookie(w, authPageHandlers.cookies.New(CsrfCookieName, nonce,
+			cookies.Options{SameSite: http.SameSiteStrictMode}))
+	}
+
+	sessionId, _ := sessionIdFromRequest(r, authPageHandlers.keyManager)
+	token, err := authPageHandlers.keyManager.Sign(csrfClaims{
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: time.Now().Add(csrfTokenTTL).Unix(),
+			Issuer:    "nimbus",
+			Audience:  csrfTokenAudience,
+			Subject:   sessionId,
+		},
+		Nonce: nonce,
+	})
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	jsonResponse, err := json.Marshal(domain.CsrfTokenResponse{Status: http.StatusOK, Token: token})
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to marshal response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	err = WriteResponse(w, r, authPageHandlers.metrics, jsonResponse, requestID)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+}
+
+// VerifyCsrfToken проверяет токен из заголовка X-CSRF-Token: подпись, срок действия, совпадение nonce
+// с cookie csrf и, если запрос пришел с access cookie, сессию. Запросы без access cookie (вход, регистрация,
+// refresh после истечения access токена) защищены только привязкой к cookie
+func VerifyCsrfToken(r *http.Request, keyManager *signing.KeyManager) error {
+	nonceCookie, err := r.Cookie(CsrfCookieName)
+	if err != nil || nonceCookie.Value == "" {
+		return fmt.Errorf("no csrf cookie: %w", myerrors.ErrInvalidCsrfToken)
+	}
+
+	var claims csrfClaims
+	parsedToken, err := jwt.ParseWithClaims(r.Header.Get(CsrfHeaderName), &claims, keyManager.Keyfunc)
+	if err != nil {
+		return fmt.Errorf("failed to parse csrf token: %v: %w", err, myerrors.ErrInvalidCsrfToken)
+	}
+	if !parsedToken.Valid || !claims.VerifyAudience(csrfTokenAudience, true) ||
+		subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonceCookie.Value)) != 1 {
+		return fmt.Errorf("csrf token does not match cookie: %w", myerrors.ErrInvalidCsrfToken)
+	}
+
+	sessionId, ok := sessionIdFromRequest(r, keyManager)
+	if ok && sessionId != claims.Subject {
+		return fmt.Errorf("csrf token is issued for another session: %w", myerrors.ErrInvalidCsrfToken)
+	}
+	return nil
+}
+
+func newCsrfNonce() (string, error) {
+	nonce := make([]byte, 32)
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(nonce), nil
+}
+
+// sessionIdFromRequest возвращает сессию из access cookie. ok false, если cookie нет или токен недействителен
+func sessionIdFromRequest(r *http.Request, keyManager *signing.KeyManager) (string, bool) {
+	accessCookie, err := r.Cookie("access")
+	if err != nil {
+		return "", false
+	}
+	claims, err := IsTokenValid(accessCookie, keyManager)
+	if err != nil {
+		return "", false
+	}
+	userPrincipal, err := principal.FromClaims(claims)
+	if err != nil {
+		return "", false
+	}
+	return userPrincipal.SessionId, true
+}
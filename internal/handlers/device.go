@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/SanExpett/diploma/internal/clientip"
+	"github.com/SanExpett/diploma/internal/domain"
+	myerrors "github.com/SanExpett/diploma/internal/errors"
+	reqid "github.com/SanExpett/diploma/internal/requestId"
+	session "github.com/SanExpett/diploma/internal/session/proto"
+)
+
+type DeviceHandlers struct {
+	authPageHandlers *AuthPageHandlers
+	verificationURL  string
+}
+
+// NewDeviceHandlers создает обработчики входа на устройствах без клавиатуры. verificationURL страница,
+// на которой пользователь вводит код с экрана устройства
+func NewDeviceHandlers(authPageHandlers *AuthPageHandlers, verificationURL string) *DeviceHandlers {
+	return &DeviceHandlers{
+		authPageHandlers: authPageHandlers,
+		verificationURL:  verificationURL,
+	}
+}
+
+// @Summary      Код для входа на устройстве
+// @Description  Выдает устройству device code для опроса и короткий код, который пользователь вводит
+// @Description  на другом устройстве по адресу verificationUri или открывает по QR коду verificationUriComplete
+// @Tags         Auth
+// @Produce      json
+// @Success      200  {object}  domain.DeviceCodeResponse  "Коды устройства"
+// @Failure      500  {object}  object                     "Внутренняя ошибка сервера"
+// @Router       /auth/device/code [post]
+func (deviceHandlers *DeviceHandlers) Code(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	requestID := ctx.Value(reqid.ReqIDKey)
+	authPageHandlers := deviceHandlers.authPageHandlers
+
+	reqStart := session.StartDeviceAuthorizationRequest{ClientName: r.UserAgent(), Ip: clientip.FromRequest(r)}
+	deviceCode, err := (*authPageHandlers.sessionsClient).StartDeviceAuthorization(ctx, &reqStart)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	response := domain.DeviceCodeResponse{
+		Status:                  http.StatusOK,
+		DeviceCode:              deviceCode.DeviceCode,
+		UserCode:                deviceCode.UserCode,
+		VerificationUri:         deviceHandlers.verificationURL,
+		VerificationUriComplete: deviceHandlers.verificationURL + "?user_code=" + url.QueryEscape(deviceCode.UserCode),
+		ExpiresIn:               deviceCode.ExpiresIn,
+		Interval:                deviceCode.Interval,
+	}
+
+	jsonResponse, err := json.Marshal(response)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to marshal response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	err = WriteResponse(w, r, authPageHandlers.metrics, jsonResponse, requestID)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+	}
+}
+
+// @Summary      Опрос статуса входа на устройстве
+// @Description  Устройство опрашивает статус не чаще interval секунд. После подтверждения кода
+// @Description  пользователем выдает устройству собственную сессию
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      domain.DeviceTokenRequest  true  "Device code"
+// @Success      200      {object}  object                     "Успешный вход"
+// @Failure      400      {object}  object                     "authorization_pending, slow_down или expired_token"
+// @Failure      403      {object}  object                     "Пользователь отклонил вход"
+// @Failure      500      {object}  object                     "Внутренняя ошибка сервера"
+// @Router       /auth/device/token [post]
+func (deviceHandlers *DeviceHandlers) Token(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	requestID := ctx.Value(reqid.ReqIDKey)
+	authPageHandlers := deviceHandlers.authPageHandlers
+
+	var request domain.DeviceTokenRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to decode: %v\n", requestID, myerrors.ErrFailedDecode)
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	reqPoll := session.PollDeviceAuthorizationRequest{DeviceCode: request.DeviceCode}
+	authorization, err := (*authPageHandlers.sessionsClient).PollDeviceAuthorization(ctx, &reqPoll)
+	if err == nil && authorization.Status != domain.DeviceAuthorizationApproved {
+		err = deviceStatusError(authorization.Status)
+	}
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	reqGetUser := session.GetUserRequest{Login: authorization.Login}
+	user, err := (*authPageHandlers.usersClient).GetUser(ctx, &reqGetUser)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	authPageHandlers.logger.Info(fmt.Sprintf("[reqid=%s] device authorization approved", requestID))
+	// телевизор не закрывают как вкладку, вход на нем запоминается
+	authPageHandlers.completeLogin(w, r, user.User, clientip.FromRequest(r), true)
+}
+
+// @Summary      Устройство по коду
+// @Description  Показывает пользователю, какое устройство просит вход, прежде чем он его подтвердит
+// @Tags         Auth
+// @Produce      json
+// @Param        user_code  query     string                     true  "Код с экрана устройства"
+// @Success      200        {object}  domain.DeviceInfoResponse  "Устройство"
+// @Failure      400        {object}  object                     "Код неверный или истек"
+// @Failure      401        {object}  object                     "Пользователь не авторизован"
+// @Security     ApiKeyAuth
+// @Router       /auth/device [get]
+func (deviceHandlers *DeviceHandlers) Info(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	requestID := ctx.Value(reqid.ReqIDKey)
+	authPageHandlers := deviceHandlers.authPageHandlers
+
+	_, err := getPrincipal(r)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	reqGet := session.GetDeviceAuthorizationRequest{UserCode: r.URL.Query().Get("user_code")}
+	authorization, err := (*authPageHandlers.sessionsClient).GetDeviceAuthorization(ctx, &reqGet)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, deviceAuthorizationError(err))
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	response := domain.DeviceInfoResponse{
+		Status:     http.StatusOK,
+		UserCode:   authorization.UserCode,
+		ClientName: authorization.ClientName,
+		Ip:         authorization.Ip,
+		CreatedAt:  authorization.CreatedAt.AsTime(),
+	}
+
+	jsonResponse, err := json.Marshal(response)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to marshal response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	err = WriteResponse(w, r, authPageHandlers.metrics, jsonResponse, requestID)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+	}
+}
+
+// @Summary      Подтверждение входа на устройстве
+// @Description  Впускает устройство с указанным кодом в аккаунт текущего пользователя или отклоняет вход
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      domain.DeviceApproveRequest  true  "Код с экрана устройства и решение"
+// @Success      200      {object}  object                       "Решение принято"
+// @Failure      400      {object}  object                       "Код неверный или истек"
+// @Failure      401      {object}  object                       "Пользователь не авторизован"
+// @Security     ApiKeyAuth
+// @Router       /auth/device/approve [post]
+func (deviceHandlers *DeviceHandlers) Approve(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	requestID := ctx.Value(reqid.ReqIDKey)
+	authPageHandlers := deviceHandlers.authPageHandlers
+
+	userPrincipal, err := getPrincipal(r)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	var request domain.DeviceApproveRequest
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to decode: %v\n", requestID, myerrors.ErrFailedDecode)
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	reqDecide := session.DecideDeviceAuthorizationRequest{UserCode: request.UserCode, Login: userPrincipal.Login,
+		Approve: request.Approve}
+	_, err = (*authPageHandlers.sessionsClient).DecideDeviceAuthorization(ctx, &reqDecide)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, deviceAuthorizationError(err))
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	err = WriteSuccess(w, r, authPageHandlers.metrics)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+	}
+
+	authPageHandlers.logger.Info(fmt.Sprintf("[reqid=%s] device authorization decided, approve=%t", requestID,
+		request.Approve))
+}
+
+// deviceStatusError ошибка, которую по RFC 8628 получает устройство, пока вход не подтвержден
+func deviceStatusError(authorizationStatus string) error {
+	switch authorizationStatus {
+	case domain.DeviceAuthorizationPending:
+		return myerrors.ErrAuthorizationPending
+	case domain.DeviceAuthorizationSlowDown:
+		return myerrors.ErrSlowDown
+	case domain.DeviceAuthorizationDenied:
+		return myerrors.ErrDeviceAccessDenied
+	}
+	return myerrors.ErrDeviceCodeExpired
+}
+
+// deviceAuthorizationError восстанавливает ошибку неизвестного кода, которую сервис сессий передает кодом gRPC
+func deviceAuthorizationError(err error) error {
+	if status.Code(err) == codes.NotFound {
+		return myerrors.ErrNoSuchDeviceAuthorization
+	}
+	return err
+}
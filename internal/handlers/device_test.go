@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/SanExpett/diploma/internal/domain"
+	myerrors "github.com/SanExpett/diploma/internal/errors"
+	"github.com/SanExpett/diploma/internal/handlers/mocks"
+	"github.com/SanExpett/diploma/internal/metrics"
+	"github.com/SanExpett/diploma/internal/principal"
+	session "github.com/SanExpett/diploma/internal/session/proto"
+	"github.com/SanExpett/diploma/internal/signing"
+)
+
+func TestDeviceHandlers_Token(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUsersClient := mocks.NewMockUsersClient(ctrl)
+	mockUsersClient.EXPECT().RecordAuditEvent(gomock.Any(), gomock.Any()).
+		Return(&session.RecordAuditEventResponse{}, nil).AnyTimes()
+	mockUsersClient.EXPECT().RegisterLoginDevice(gomock.Any(), gomock.Any()).
+		Return(&session.RegisterLoginDeviceResponse{}, nil).AnyTimes()
+	mockSessionsClient := mocks.NewMockSessionsClient(ctrl)
+
+	var usersClient session.UsersClient = mockUsersClient
+	var sessionsClient session.SessionsClient = mockSessionsClient
+
+	keyManager, err := signing.NewKeyManager(signing.AlgorithmEdDSA, time.Hour, time.Hour)
+	require.NoError(t, err)
+
+	handler := NewDeviceHandlers(NewAuthPageHandlers(&usersClient, &sessionsClient, keyManager, testCookies,
+		metrics.NewHttpMetrics(), zap.NewNop().Sugar()), "http://localhost:8080/device")
+
+	pollRequest := &session.PollDeviceAuthorizationRequest{DeviceCode: "device-code"}
+	user := &session.User{Email: "test@test.com", Uuid: "test-uuid", Version: 1}
+
+	tests := []struct {
+		name           string
+		setupMocks     func()
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name: "Ожидает подтверждения",
+			setupMocks: func() {
+				mockSessionsClient.EXPECT().PollDeviceAuthorization(gomock.Any(), pollRequest).
+					Return(&session.PollDeviceAuthorizationResponse{Status: domain.DeviceAuthorizationPending}, nil)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "authorization_pending",
+		},
+		{
+			name: "Слишком частый опрос",
+			setupMocks: func() {
+				mockSessionsClient.EXPECT().PollDeviceAuthorization(gomock.Any(), pollRequest).
+					Return(&session.PollDeviceAuthorizationResponse{Status: domain.DeviceAuthorizationSlowDown}, nil)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "slow_down",
+		},
+		{
+			name: "Пользователь отклонил вход",
+			setupMocks: func() {
+				mockSessionsClient.EXPECT().PollDeviceAuthorization(gomock.Any(), pollRequest).
+					Return(&session.PollDeviceAuthorizationResponse{Status: domain.DeviceAuthorizationDenied}, nil)
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedCode:   "access_denied",
+		},
+		{
+			name: "Код истек",
+			setupMocks: func() {
+				mockSessionsClient.EXPECT().PollDeviceAuthorization(gomock.Any(), pollRequest).
+					Return(&session.PollDeviceAuthorizationResponse{Status: domain.DeviceAuthorizationExpired}, nil)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "expired_token",
+		},
+		{
+			name: "Вход подтвержден",
+			setupMocks: func() {
+				mockSessionsClient.EXPECT().PollDeviceAuthorization(gomock.Any(), pollRequest).
+					Return(&session.PollDeviceAuthorizationResponse{Status: domain.DeviceAuthorizationApproved,
+						Login: "test@test.com"}, nil)
+				mockUsersClient.EXPECT().GetUser(gomock.Any(), &session.GetUserRequest{Login: "test@test.com"}).
+					Return(&session.GetUserResponse{User: user}, nil)
+				mockSessionsClient.EXPECT().IssueRefreshToken(gomock.Any(),
+					&session.IssueRefreshTokenRequest{Login: "test@test.com"}).
+					Return(&session.IssueRefreshTokenResponse{Family: "family", RefreshToken: "refresh-token"}, nil)
+				mockSessionsClient.EXPECT().Add(gomock.Any(), &session.AddRequest{Login: "test@test.com",
+					Token: "family", Version: 1, UserAgent: "SmartTV", Ip: "192.0.2.1"}).
+					Return(&session.AddResponse{}, nil)
+				mockSessionsClient.EXPECT().ResetLoginAttempts(gomock.Any(), gomock.Any()).
+					Return(&session.ResetLoginAttemptsResponse{}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMocks()
+
+			body, _ := json.Marshal(domain.DeviceTokenRequest{DeviceCode: "device-code"})
+			req := httptest.NewRequest(http.MethodPost, "/api/auth/device/token", bytes.NewReader(body))
+			req.Header.Set("User-Agent", "SmartTV")
+			w := httptest.NewRecorder()
+
+			handler.Token(w, req)
+
+			var response ErrorResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tt.expectedStatus, response.Status)
+			assert.Equal(t, tt.expectedCode, response.Code)
+		})
+	}
+}
+
+func TestDeviceHandlers_Approve(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUsersClient := mocks.NewMockUsersClient(ctrl)
+	mockSessionsClient := mocks.NewMockSessionsClient(ctrl)
+
+	var usersClient session.UsersClient = mockUsersClient
+	var sessionsClient session.SessionsClient = mockSessionsClient
+
+	handler := NewDeviceHandlers(NewAuthPageHandlers(&usersClient, &sessionsClient, nil, testCookies,
+		metrics.NewHttpMetrics(), zap.NewNop().Sugar()), "http://localhost:8080/device")
+
+	tests := []struct {
+		name           string
+		setupMocks     func()
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name: "Вход подтвержден",
+			setupMocks: func() {
+				mockSessionsClient.EXPECT().DecideDeviceAuthorization(gomock.Any(),
+					&session.DecideDeviceAuthorizationRequest{UserCode: "bcdf-ghjk", Login: "test@test.com",
+						Approve: true}).
+					Return(&session.DecideDeviceAuthorizationResponse{}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "Код неверный или истек",
+			setupMocks: func() {
+				mockSessionsClient.EXPECT().DecideDeviceAuthorization(gomock.Any(), gomock.Any()).
+					Return(nil, status.Error(codes.NotFound, myerrors.ErrNoSuchDeviceAuthorization.Error()))
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "invalid_user_code",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMocks()
+
+			body, _ := json.Marshal(domain.DeviceApproveRequest{UserCode: "bcdf-ghjk", Approve: true})
+			req := httptest.NewRequest(http.MethodPost, "/api/auth/device/approve", bytes.NewReader(body))
+			req = req.WithContext(principal.WithPrincipal(req.Context(),
+				principal.Principal{UserUuid: "1", Login: "test@test.com"}))
+			w := httptest.NewRecorder()
+
+			handler.Approve(w, req)
+
+			var response ErrorResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tt.expectedStatus, response.Status)
+			assert.Equal(t, tt.expectedCode, response.Code)
+		})
+	}
+}
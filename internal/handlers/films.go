@@ -3,7 +3,6 @@ package handlers
 import (
 	"fmt"
 	"net/http"
-	"os"
 	"strconv"
 
 	"github.com/gorilla/mux"
@@ -321,8 +320,17 @@ func (filmsPageHandlers *FilmsPageHandlers) PutFavoriteFilm(w http.ResponseWrite
 	ctx := r.Context()
 	requestId := ctx.Value(reqid.ReqIDKey)
 
+	userPrincipal, err := getPrincipal(r)
+	if err != nil {
+		err = WriteError(w, r, filmsPageHandlers.metrics, err)
+		if err != nil {
+			filmsPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestId, err)
+		}
+		return
+	}
+
 	var data domain.DataToFavorite
-	err := easyjson.UnmarshalFromReader(r.Body, &data)
+	err = easyjson.UnmarshalFromReader(r.Body, &data)
 	if err != nil {
 		filmsPageHandlers.logger.Errorf("[reqid=%s] failed to decode request data: %v\n", requestId, err)
 		err = WriteError(w, r, filmsPageHandlers.metrics, err)
@@ -332,7 +340,7 @@ func (filmsPageHandlers *FilmsPageHandlers) PutFavoriteFilm(w http.ResponseWrite
 		return
 	}
 
-	req := session.PutFavoriteRequest{FilmUuid: data.FilmUuid, UserUuid: data.UserUuid}
+	req := session.PutFavoriteRequest{FilmUuid: data.FilmUuid, UserUuid: userPrincipal.UserUuid}
 	_, err = (*filmsPageHandlers.client).PutFavorite(ctx, &req)
 	if err != nil {
 		filmsPageHandlers.logger.Errorf("[reqid=%s] failed to put favorite film: %v\n", requestId, err)
@@ -356,8 +364,17 @@ func (filmsPageHandlers *FilmsPageHandlers) RemoveFavoriteFilm(w http.ResponseWr
 	ctx := r.Context()
 	requestId := ctx.Value(reqid.ReqIDKey)
 
+	userPrincipal, err := getPrincipal(r)
+	if err != nil {
+		err = WriteError(w, r, filmsPageHandlers.metrics, err)
+		if err != nil {
+			filmsPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestId, err)
+		}
+		return
+	}
+
 	var data domain.DataToFavorite
-	err := easyjson.UnmarshalFromReader(r.Body, &data)
+	err = easyjson.UnmarshalFromReader(r.Body, &data)
 	if err != nil {
 		filmsPageHandlers.logger.Errorf("[reqid=%s] failed to decode request data: %v\n", requestId, err)
 		err = WriteError(w, r, filmsPageHandlers.metrics, err)
@@ -366,7 +383,7 @@ func (filmsPageHandlers *FilmsPageHandlers) RemoveFavoriteFilm(w http.ResponseWr
 		}
 	}
 
-	req := session.DeleteFavoriteRequest{FilmUuid: data.FilmUuid, UserUuid: data.UserUuid}
+	req := session.DeleteFavoriteRequest{FilmUuid: data.FilmUuid, UserUuid: userPrincipal.UserUuid}
 	_, err = (*filmsPageHandlers.client).DeleteFavorite(ctx, &req)
 	if err != nil {
 		filmsPageHandlers.logger.Errorf("[reqid=%s] failed to remove favorite film: %v\n", requestId, err)
@@ -883,16 +900,7 @@ func (filmsPageHandlers *FilmsPageHandlers) AddComment(w http.ResponseWriter, r
 	ctx := r.Context()
 	requestId := ctx.Value(reqid.ReqIDKey)
 
-	userToken, err := r.Cookie("access")
-	if err != nil {
-		err = WriteError(w, r, filmsPageHandlers.metrics, myerrors.ErrNoActiveSession)
-		if err != nil {
-			filmsPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestId, err)
-		}
-		return
-	}
-
-	_, err = IsTokenValid(userToken, os.Getenv("SECRETKEY"))
+	userPrincipal, err := getPrincipal(r)
 	if err != nil {
 		err = WriteError(w, r, filmsPageHandlers.metrics, err)
 		if err != nil {
@@ -911,6 +919,7 @@ func (filmsPageHandlers *FilmsPageHandlers) AddComment(w http.ResponseWriter, r
 		}
 		return
 	}
+	commentAddData.AuthorUuid = userPrincipal.UserUuid
 
 	req := session.AddCommentRequest{
 		Comment: convertCommentToAddToProto(commentAddData),
@@ -940,26 +949,38 @@ func (filmsPageHandlers *FilmsPageHandlers) RemoveComment(w http.ResponseWriter,
 	ctx := r.Context()
 	requestId := ctx.Value(reqid.ReqIDKey)
 
-	userToken, err := r.Cookie("access")
+	userPrincipal, err := getPrincipal(r)
 	if err != nil {
-		err = WriteError(w, r, filmsPageHandlers.metrics, myerrors.ErrNoActiveSession)
+		err = WriteError(w, r, filmsPageHandlers.metrics, err)
 		if err != nil {
 			filmsPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestId, err)
 		}
 		return
 	}
 
-	_, err = IsTokenValid(userToken, os.Getenv("SECRETKEY"))
+	var commentRemoveData domain.CommentToRemove
+	err = easyjson.UnmarshalFromReader(r.Body, &commentRemoveData)
 	if err != nil {
+		filmsPageHandlers.logger.Errorf("[reqid=%s] failed to decode request data: %v\n", requestId, err)
 		err = WriteError(w, r, filmsPageHandlers.metrics, err)
 		if err != nil {
 			filmsPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestId, err)
 		}
 		return
 	}
+	commentRemoveData.AuthorUuid = userPrincipal.UserUuid
+
+	filmsPageHandlers.removeComment(w, r, commentRemoveData)
+}
+
+// ModerateRemoveComment удаляет комментарий любого автора, доступ к нему проверяется
+// через rbac.PermissionCommentsModerate
+func (filmsPageHandlers *FilmsPageHandlers) ModerateRemoveComment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	requestId := ctx.Value(reqid.ReqIDKey)
 
 	var commentRemoveData domain.CommentToRemove
-	err = easyjson.UnmarshalFromReader(r.Body, &commentRemoveData)
+	err := easyjson.UnmarshalFromReader(r.Body, &commentRemoveData)
 	if err != nil {
 		filmsPageHandlers.logger.Errorf("[reqid=%s] failed to decode request data: %v\n", requestId, err)
 		err = WriteError(w, r, filmsPageHandlers.metrics, err)
@@ -969,10 +990,18 @@ func (filmsPageHandlers *FilmsPageHandlers) RemoveComment(w http.ResponseWriter,
 		return
 	}
 
+	filmsPageHandlers.removeComment(w, r, commentRemoveData)
+}
+
+func (filmsPageHandlers *FilmsPageHandlers) removeComment(w http.ResponseWriter, r *http.Request,
+	commentRemoveData domain.CommentToRemove) {
+	ctx := r.Context()
+	requestId := ctx.Value(reqid.ReqIDKey)
+
 	req := session.RemoveCommentRequest{
 		Comment: convertCommentToRemoveToProto(commentRemoveData),
 	}
-	_, err = (*filmsPageHandlers.client).RemoveComment(ctx, &req)
+	_, err := (*filmsPageHandlers.client).RemoveComment(ctx, &req)
 	if err != nil {
 		filmsPageHandlers.logger.Errorf("[reqid=%s] failed to remove comment: %v\n", requestId, err)
 		err = WriteError(w, r, filmsPageHandlers.metrics, err)
@@ -8,20 +8,21 @@ import (
 	"io"
 	"mime/multipart"
 	"net/http"
-	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
 	"time"
 
-	"github.com/dgrijalva/jwt-go"
+	"github.com/golang-jwt/jwt/v4"
 	"github.com/gorilla/mux"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/SanExpett/diploma/internal/domain"
 	myerrors "github.com/SanExpett/diploma/internal/errors"
 	"github.com/SanExpett/diploma/internal/metrics"
+	"github.com/SanExpett/diploma/internal/principal"
 	session "github.com/SanExpett/diploma/internal/session/proto"
+	"github.com/SanExpett/diploma/internal/signing"
 )
 
 type SuccessResponse struct {
@@ -31,6 +32,7 @@ type SuccessResponse struct {
 type ErrorResponse struct {
 	Status int    `json:"status"`
 	Err    string `json:"error"`
+	Code   string `json:"code,omitempty"`
 }
 
 func WriteSuccess(w http.ResponseWriter, req *http.Request, metrics *metrics.HttpMetrics) error {
@@ -70,6 +72,7 @@ func WriteError(w http.ResponseWriter, req *http.Request, metrics *metrics.HttpM
 	response := ErrorResponse{
 		Status: statusCode,
 		Err:    err.Error(),
+		Code:   myerrors.ErrorCode(err),
 	}
 
 	jsonResponse, err := json.Marshal(response)
@@ -98,6 +101,24 @@ func WriteError(w http.ResponseWriter, req *http.Request, metrics *metrics.HttpM
 	return nil
 }
 
+// WriteRedirect отправляет клиента по адресу location, например на страницу входа внешнего провайдера
+func WriteRedirect(w http.ResponseWriter, r *http.Request, metrics *metrics.HttpMetrics, location string) error {
+	curRoute := mux.CurrentRoute(r)
+	if curRoute == nil {
+		return fmt.Errorf("unable to get current route")
+	}
+
+	pathTemplate, err := curRoute.GetPathTemplate()
+	if err != nil {
+		return fmt.Errorf("unable to get path template: %w", err)
+	}
+
+	metrics.IncRequestsTotal(pathTemplate, r.Method, http.StatusFound)
+
+	http.Redirect(w, r, location, http.StatusFound)
+	return nil
+}
+
 func WriteResponse(w http.ResponseWriter, r *http.Request, metrics *metrics.HttpMetrics, jsonResponse any,
 	requestID any) error {
 	curRoute := mux.CurrentRoute(r)
@@ -351,6 +372,8 @@ func convertUserToRegular(user *session.User) domain.User {
 		Birthday:        convertProtoToTime(user.Birthday),
 		RegisteredAt:    convertProtoToTime(user.RegisteredAt),
 		HasSubscription: user.HasSubscription,
+		EmailVerified:   user.EmailVerified,
+		TotpEnabled:     user.TotpEnabled,
 	}
 }
 
@@ -490,13 +513,8 @@ func convertSubsToRegular(subs []*session.Subscription) []domain.Subscription {
 	return subscriptions
 }
 
-func IsTokenValid(token *http.Cookie, secretKey string) (jwt.MapClaims, error) {
-	parsedToken, err := jwt.Parse(token.Value, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(secretKey), nil
-	})
+func IsTokenValid(token *http.Cookie, keyManager *signing.KeyManager) (jwt.MapClaims, error) {
+	parsedToken, err := jwt.Parse(token.Value, keyManager.Keyfunc)
 	if err != nil {
 		return nil, err
 	}
@@ -526,6 +544,47 @@ func IsTokenValid(token *http.Cookie, secretKey string) (jwt.MapClaims, error) {
 	return claims, nil
 }
 
+func getPrincipal(r *http.Request) (principal.Principal, error) {
+	userPrincipal, ok := principal.FromContext(r.Context())
+	if !ok || userPrincipal.UserUuid == "" {
+		return principal.Principal{}, myerrors.ErrNotAuthorised
+	}
+	return userPrincipal, nil
+}
+
+func convertSessionsToRegular(sessions []*session.SessionInfo, currentSessionId string) []domain.Session {
+	sessionsRegular := make([]domain.Session, 0, len(sessions))
+	for _, userSession := range sessions {
+		sessionsRegular = append(sessionsRegular, domain.Session{
+			Id:         userSession.Id,
+			UserAgent:  html.EscapeString(userSession.UserAgent),
+			Ip:         userSession.Ip,
+			CreatedAt:  convertProtoToTime(userSession.CreatedAt),
+			LastSeenAt: convertProtoToTime(userSession.LastSeenAt),
+			Current:    userSession.Id == currentSessionId,
+		})
+	}
+	return sessionsRegular
+}
+
+func convertPasskeysToRegular(passkeys []*session.Passkey) []domain.PasskeyInfo {
+	passkeysRegular := make([]domain.PasskeyInfo, 0, len(passkeys))
+	for _, passkey := range passkeys {
+		passkeyRegular := domain.PasskeyInfo{
+			Id:         passkey.Id,
+			Name:       html.EscapeString(passkey.Name),
+			Transports: passkey.Transports,
+			CreatedAt:  convertProtoToTime(passkey.CreatedAt),
+		}
+		if passkey.LastUsedAt != nil {
+			lastUsedAt := convertProtoToTime(passkey.LastUsedAt)
+			passkeyRegular.LastUsedAt = &lastUsedAt
+		}
+		passkeysRegular = append(passkeysRegular, passkeyRegular)
+	}
+	return passkeysRegular
+}
+
 func ValidateLogin(e string) error {
 	emailRegex := regexp.MustCompile(`^[a-z0-9._%+\-]+@[a-z0-9.\-]+\.[a-z]{2,}$`)
 	if emailRegex.MatchString(e) {
@@ -551,25 +610,31 @@ func ValidatePassword(password string) error {
 type customClaims struct {
 	jwt.StandardClaims
 	Login   string
+	Uuid    string
+	Sid     string
 	IsAdmin bool
+	Roles   []string
 	Version uint32
 }
 
-func GenerateTokens(login string, isAdmin bool, version uint32) (tokenSigned string,
-	err error) {
+// GenerateTokens выпускает короткоживущий access токен. sessionId совпадает с семейством
+// refresh токенов, выданных при входе, и по нему проверяется сессия
+func GenerateTokens(keyManager *signing.KeyManager, login string, uuid string, sessionId string, isAdmin bool,
+	roles []string, version uint32) (tokenSigned string, err error) {
 	tokenCustomClaims := customClaims{
 		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(time.Hour * 48).Unix(),
+			ExpiresAt: time.Now().Add(accessTokenExpirationTime).Unix(),
 			Issuer:    "nimbus",
 		},
 		Login:   login,
+		Uuid:    uuid,
+		Sid:     sessionId,
 		IsAdmin: isAdmin,
+		Roles:   roles,
 		Version: version,
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, tokenCustomClaims)
-
-	tokenSigned, err = token.SignedString([]byte(os.Getenv("SECRETKEY")))
+	tokenSigned, err = keyManager.Sign(tokenCustomClaims)
 	if err != nil {
 		return "", fmt.Errorf("%v", err)
 	}
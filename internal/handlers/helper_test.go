@@ -3,9 +3,13 @@ package handlers
 import (
 	"fmt"
 	"testing"
+	"time"
+
+	"github.com/SanExpett/diploma/internal/signing"
 )
 
 func TestGenerateTokens(t *testing.T) {
-	token, _ := GenerateTokens("alex@gmail.com", false, 1)
+	keyManager, _ := signing.NewKeyManager(signing.AlgorithmEdDSA, time.Hour, time.Hour)
+	token, _ := GenerateTokens(keyManager, "alex@gmail.com", "1", "", false, nil, 1)
 	fmt.Println(token)
 }
@@ -0,0 +1,274 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/SanExpett/diploma/internal/clientip"
+	"github.com/SanExpett/diploma/internal/cookies"
+	"github.com/SanExpett/diploma/internal/domain"
+	myerrors "github.com/SanExpett/diploma/internal/errors"
+	reqid "github.com/SanExpett/diploma/internal/requestId"
+	session "github.com/SanExpett/diploma/internal/session/proto"
+	"github.com/SanExpett/diploma/internal/signing"
+)
+
+const (
+	magicLinkCookie         = "magic_link"
+	magicLinkCookiePath     = "/api/auth/magic-link"
+	magicLinkExpirationTime = 15 * time.Minute
+	magicLinkAudience       = "magic_link"
+)
+
+// magicLinkClaims токен из ссылки для входа. Id одноразовый идентификатор, который помнит сервис
+// пользователей, Binding хеш секрета из куки браузера, запросившего ссылку
+type magicLinkClaims struct {
+	jwt.StandardClaims
+	Binding string `json:"binding"`
+}
+
+func generateMagicLink(keyManager *signing.KeyManager, login string) (magicLinkClaims, string, string, error) {
+	tokenId, err := randomToken()
+	if err != nil {
+		return magicLinkClaims{}, "", "", err
+	}
+	binding, err := randomToken()
+	if err != nil {
+		return magicLinkClaims{}, "", "", err
+	}
+
+	claims := magicLinkClaims{
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: time.Now().Add(magicLinkExpirationTime).Unix(),
+			Issuer:    "nimbus",
+			Audience:  magicLinkAudience,
+			Subject:   login,
+			Id:        tokenId,
+		},
+		Binding: hashBinding(binding),
+	}
+	tokenSigned, err := keyManager.Sign(claims)
+	if err != nil {
+		return magicLinkClaims{}, "", "", err
+	}
+	return claims, tokenSigned, binding, nil
+}
+
+// parseMagicLink проверяет подпись, срок действия токена и то, что ссылку открыли в браузере,
+// который ее запросил
+func parseMagicLink(keyManager *signing.KeyManager, tokenSigned string, bindingCookie *http.Cookie,
+	cookieErr error) (magicLinkClaims, error) {
+	if cookieErr != nil {
+		return magicLinkClaims{}, fmt.Errorf("no binding cookie: %v: %w", cookieErr, myerrors.ErrInvalidMagicLink)
+	}
+
+	var claims magicLinkClaims
+	parsedToken, err := jwt.ParseWithClaims(tokenSigned, &claims, keyManager.Keyfunc)
+	if err != nil {
+		return magicLinkClaims{}, fmt.Errorf("%v: %w", err, myerrors.ErrInvalidMagicLink)
+	}
+	if !parsedToken.Valid || !claims.VerifyAudience(magicLinkAudience, true) || claims.Subject == "" ||
+		claims.Id == "" {
+		return magicLinkClaims{}, myerrors.ErrInvalidMagicLink
+	}
+	if subtle.ConstantTimeCompare([]byte(hashBinding(bindingCookie.Value)), []byte(claims.Binding)) != 1 {
+		return magicLinkClaims{}, fmt.Errorf("link opened in another browser: %w", myerrors.ErrInvalidMagicLink)
+	}
+	return claims, nil
+}
+
+func randomToken() (string, error) {
+	tokenBytes := make([]byte, 32)
+	_, err := rand.Read(tokenBytes)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(tokenBytes), nil
+}
+
+func hashBinding(binding string) string {
+	hash := sha256.Sum256([]byte(binding))
+	return hex.EncodeToString(hash[:])
+}
+
+type MagicLinkHandlers struct {
+	authPageHandlers *AuthPageHandlers
+	magicLinkURL     string
+}
+
+// NewMagicLinkHandlers создает обработчики входа по ссылке из письма. magicLinkURL страница фронтенда,
+// на которую ведет ссылка, она передает токен из ссылки в MagicLinkHandlers.Login
+func NewMagicLinkHandlers(authPageHandlers *AuthPageHandlers, magicLinkURL string) *MagicLinkHandlers {
+	return &MagicLinkHandlers{
+		authPageHandlers: authPageHandlers,
+		magicLinkURL:     magicLinkURL,
+	}
+}
+
+// @Summary      Ссылка для входа
+// @Description  Отправляет на email одноразовую ссылку для входа без пароля. Ссылка работает только
+// @Description  в этом браузере, предыдущие ссылки перестают действовать. Ответ не зависит от того,
+// @Description  зарегистрирован ли адрес
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      domain.MagicLinkRequest  true  "Email пользователя"
+// @Success      200      {object}  object                   "Письмо отправлено, если адрес зарегистрирован"
+// @Failure      400      {object}  object                   "Ошибка валидации"
+// @Failure      500      {object}  object                   "Внутренняя ошибка сервера"
+// @Router       /auth/magic-link [post]
+func (magicLinkHandlers *MagicLinkHandlers) Request(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	requestID := ctx.Value(reqid.ReqIDKey)
+	authPageHandlers := magicLinkHandlers.authPageHandlers
+
+	var request domain.MagicLinkRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to decode: %v\n", requestID, myerrors.ErrFailedDecode)
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	err = ValidateLogin(request.Email)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] login is not valid: %v\n", requestID,
+			myerrors.ErrLoginIsNotValid)
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	claims, tokenSigned, binding, err := generateMagicLink(authPageHandlers.keyManager, request.Email)
+	var link *url.URL
+	if err == nil {
+		link, err = url.Parse(magicLinkHandlers.magicLinkURL)
+	}
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+	query := link.Query()
+	query.Set("token", tokenSigned)
+	link.RawQuery = query.Encode()
+
+	reqSend := session.SendMagicLinkRequest{Login: request.Email, Link: link.String(), TokenId: claims.Id,
+		ExpiresAt: timestamppb.New(time.Unix(claims.ExpiresAt, 0))}
+	_, err = (*authPageHandlers.usersClient).SendMagicLink(ctx, &reqSend)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	// кука выставляется и для незарегистрированного адреса, чтобы ответы не различались
+	http.SetCookie(w, authPageHandlers.cookies.New(magicLinkCookie, binding, cookies.Options{
+		Path:     magicLinkCookiePath,
+		MaxAge:   magicLinkExpirationTime,
+		SameSite: http.SameSiteLaxMode,
+	}))
+
+	err = WriteSuccess(w, r, authPageHandlers.metrics)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+	}
+}
+
+// @Summary      Вход по ссылке
+// @Description  Гасит ссылку из письма и создает сессию. Ссылка работает один раз и только в браузере,
+// @Description  в котором ее запросили. При включенном втором факторе возвращает токен для второго шага входа
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      domain.MagicLinkLoginRequest  true  "Токен из ссылки"
+// @Success      200      {object}  object                        "Успешный вход"
+// @Failure      401      {object}  object                        "Ссылка недействительна, истекла или уже использована"
+// @Failure      500      {object}  object                        "Внутренняя ошибка сервера"
+// @Router       /auth/magic-link/login [post]
+func (magicLinkHandlers *MagicLinkHandlers) Login(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	requestID := ctx.Value(reqid.ReqIDKey)
+	authPageHandlers := magicLinkHandlers.authPageHandlers
+
+	var request domain.MagicLinkLoginRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to decode: %v\n", requestID, myerrors.ErrFailedDecode)
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	bindingCookie, cookieErr := r.Cookie(magicLinkCookie)
+	claims, err := parseMagicLink(authPageHandlers.keyManager, request.Token, bindingCookie, cookieErr)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] invalid magic link: %v\n", requestID, err)
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	reqConsume := session.ConsumeMagicLinkRequest{Login: claims.Subject, TokenId: claims.Id}
+	_, err = (*authPageHandlers.usersClient).ConsumeMagicLink(ctx, &reqConsume)
+	if status.Code(err) == codes.InvalidArgument {
+		err = myerrors.ErrInvalidMagicLink
+	}
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	// кука удаляется только после успешного входа: при неудаче по старой ссылке она еще нужна для новой
+	http.SetCookie(w, authPageHandlers.cookies.Expired(magicLinkCookie, cookies.Options{
+		Path:     magicLinkCookiePath,
+		SameSite: http.SameSiteLaxMode,
+	}))
+
+	reqGetUser := session.GetUserRequest{Login: claims.Subject}
+	user, err := (*authPageHandlers.usersClient).GetUser(ctx, &reqGetUser)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	// ссылка заменяет только пароль, второй фактор по-прежнему нужен
+	if user.User.TotpEnabled {
+		authPageHandlers.writeTwoFactorChallenge(w, r, user.User.Email, false)
+		return
+	}
+
+	authPageHandlers.completeLogin(w, r, user.User, clientip.FromRequest(r), false)
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/SanExpett/diploma/internal/domain"
+	myerrors "github.com/SanExpett/diploma/internal/errors"
+	"github.com/SanExpett/diploma/internal/handlers/mocks"
+	"github.com/SanExpett/diploma/internal/metrics"
+	session "github.com/SanExpett/diploma/internal/session/proto"
+	"github.com/SanExpett/diploma/internal/signing"
+)
+
+func TestMagicLinkHandlers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUsersClient := mocks.NewMockUsersClient(ctrl)
+	mockUsersClient.EXPECT().RecordAuditEvent(gomock.Any(), gomock.Any()).
+		Return(&session.RecordAuditEventResponse{}, nil).AnyTimes()
+	mockUsersClient.EXPECT().RegisterLoginDevice(gomock.Any(), gomock.Any()).
+		Return(&session.RegisterLoginDeviceResponse{}, nil).AnyTimes()
+	mockSessionsClient := mocks.NewMockSessionsClient(ctrl)
+
+	var usersClient session.UsersClient = mockUsersClient
+	var sessionsClient session.SessionsClient = mockSessionsClient
+
+	keyManager, err := signing.NewKeyManager(signing.AlgorithmEdDSA, time.Hour, time.Hour)
+	require.NoError(t, err)
+
+	handler := NewMagicLinkHandlers(NewAuthPageHandlers(&usersClient, &sessionsClient, keyManager, testCookies,
+		metrics.NewHttpMetrics(), zap.NewNop().Sugar()), "http://localhost:8080/magic-link")
+
+	var sent *session.SendMagicLinkRequest
+	mockUsersClient.EXPECT().SendMagicLink(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ any, req *session.SendMagicLinkRequest, _ ...any) (*session.SendMagicLinkResponse, error) {
+			sent = req
+			return &session.SendMagicLinkResponse{}, nil
+		})
+
+	body, _ := json.Marshal(domain.MagicLinkRequest{Email: "test@test.com"})
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/magic-link", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handler.Request(w, req)
+
+	var requestResponse ErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &requestResponse))
+	require.Equal(t, http.StatusOK, requestResponse.Status)
+	require.NotNil(t, sent)
+	assert.Equal(t, "test@test.com", sent.Login)
+	assert.WithinDuration(t, time.Now().Add(magicLinkExpirationTime), sent.ExpiresAt.AsTime(), time.Minute)
+
+	link, err := url.Parse(sent.Link)
+	require.NoError(t, err)
+	assert.Equal(t, "/magic-link", link.Path)
+	token := link.Query().Get("token")
+
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	bindingCookie := cookies[0]
+	assert.Equal(t, magicLinkCookie, bindingCookie.Name)
+	assert.True(t, bindingCookie.HttpOnly)
+
+	_, err = IsTokenValid(&http.Cookie{Value: token}, keyManager)
+	assert.Error(t, err, "токен из ссылки не принимается как access токен")
+
+	user := &session.User{Email: "test@test.com", Uuid: "test-uuid", Version: 1}
+	consumeRequest := &session.ConsumeMagicLinkRequest{Login: "test@test.com", TokenId: sent.TokenId}
+
+	tests := []struct {
+		name           string
+		cookie         *http.Cookie
+		setupMocks     func()
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name:           "Ссылка открыта в другом браузере",
+			cookie:         &http.Cookie{Name: magicLinkCookie, Value: "other-browser"},
+			setupMocks:     func() {},
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   "invalid_magic_link",
+		},
+		{
+			name:           "Нет куки браузера",
+			setupMocks:     func() {},
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   "invalid_magic_link",
+		},
+		{
+			name:   "Успешный вход",
+			cookie: bindingCookie,
+			setupMocks: func() {
+				mockUsersClient.EXPECT().ConsumeMagicLink(gomock.Any(), consumeRequest).
+					Return(&session.ConsumeMagicLinkResponse{}, nil)
+				mockUsersClient.EXPECT().GetUser(gomock.Any(), &session.GetUserRequest{Login: "test@test.com"}).
+					Return(&session.GetUserResponse{User: user}, nil)
+				mockSessionsClient.EXPECT().IssueRefreshToken(gomock.Any(),
+					&session.IssueRefreshTokenRequest{Login: "test@test.com"}).
+					Return(&session.IssueRefreshTokenResponse{Family: "family", RefreshToken: "refresh-token"}, nil)
+				mockSessionsClient.EXPECT().Add(gomock.Any(), gomock.Any()).Return(&session.AddResponse{}, nil)
+				mockSessionsClient.EXPECT().ResetLoginAttempts(gomock.Any(), gomock.Any()).
+					Return(&session.ResetLoginAttemptsResponse{}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:   "Ссылка уже использована",
+			cookie: bindingCookie,
+			setupMocks: func() {
+				mockUsersClient.EXPECT().ConsumeMagicLink(gomock.Any(), consumeRequest).
+					Return(nil, status.Error(codes.InvalidArgument, myerrors.ErrInvalidMagicLink.Error()))
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   "invalid_magic_link",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMocks()
+
+			body, _ := json.Marshal(domain.MagicLinkLoginRequest{Token: token})
+			req := httptest.NewRequest(http.MethodPost, "/api/auth/magic-link/login", bytes.NewReader(body))
+			if tt.cookie != nil {
+				req.AddCookie(tt.cookie)
+			}
+			w := httptest.NewRecorder()
+
+			handler.Login(w, req)
+
+			var response ErrorResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tt.expectedStatus, response.Status)
+			assert.Equal(t, tt.expectedCode, response.Code)
+		})
+	}
+}
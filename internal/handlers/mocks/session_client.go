@@ -15,5 +15,22 @@ type SessionsClient interface {
 	CheckVersion(ctx context.Context, in *proto.CheckVersionRequest, opts ...grpc.CallOption) (*proto.CheckVersionResponse, error)
 	GetVersion(ctx context.Context, in *proto.GetVersionRequest, opts ...grpc.CallOption) (*proto.GetVersionResponse, error)
 	HasSession(ctx context.Context, in *proto.HasSessionRequest, opts ...grpc.CallOption) (*proto.HasSessionResponse, error)
-	CheckAllUserSessionTokens(ctx context.Context, in *proto.CheckAllUserSessionTokensRequest, opts ...grpc.CallOption) (*proto.CheckAllUserSessionTokensResponse, error)
+	ListSessions(ctx context.Context, in *proto.ListSessionsRequest, opts ...grpc.CallOption) (*proto.ListSessionsResponse, error)
+	RevokeSession(ctx context.Context, in *proto.RevokeSessionRequest, opts ...grpc.CallOption) (*proto.RevokeSessionResponse, error)
+	RevokeOtherSessions(ctx context.Context, in *proto.RevokeOtherSessionsRequest, opts ...grpc.CallOption) (*proto.RevokeOtherSessionsResponse, error)
+	IssueRefreshToken(ctx context.Context, in *proto.IssueRefreshTokenRequest, opts ...grpc.CallOption) (*proto.IssueRefreshTokenResponse, error)
+	GetRefreshToken(ctx context.Context, in *proto.GetRefreshTokenRequest, opts ...grpc.CallOption) (*proto.GetRefreshTokenResponse, error)
+	RotateRefreshToken(ctx context.Context, in *proto.RotateRefreshTokenRequest, opts ...grpc.CallOption) (*proto.RotateRefreshTokenResponse, error)
+	RevokeRefreshTokenFamily(ctx context.Context, in *proto.RevokeRefreshTokenFamilyRequest, opts ...grpc.CallOption) (*proto.RevokeRefreshTokenFamilyResponse, error)
+	ReserveLoginAttempt(ctx context.Context, in *proto.ReserveLoginAttemptRequest, opts ...grpc.CallOption) (*proto.ReserveLoginAttemptResponse, error)
+	RegisterLoginFailure(ctx context.Context, in *proto.RegisterLoginFailureRequest, opts ...grpc.CallOption) (*proto.RegisterLoginFailureResponse, error)
+	ReleaseLoginAttempt(ctx context.Context, in *proto.ReleaseLoginAttemptRequest, opts ...grpc.CallOption) (*proto.ReleaseLoginAttemptResponse, error)
+	ResetLoginAttempts(ctx context.Context, in *proto.ResetLoginAttemptsRequest, opts ...grpc.CallOption) (*proto.ResetLoginAttemptsResponse, error)
+	UnlockLogin(ctx context.Context, in *proto.UnlockLoginRequest, opts ...grpc.CallOption) (*proto.UnlockLoginResponse, error)
+	StartDeviceAuthorization(ctx context.Context, in *proto.StartDeviceAuthorizationRequest, opts ...grpc.CallOption) (*proto.StartDeviceAuthorizationResponse, error)
+	GetDeviceAuthorization(ctx context.Context, in *proto.GetDeviceAuthorizationRequest, opts ...grpc.CallOption) (*proto.GetDeviceAuthorizationResponse, error)
+	DecideDeviceAuthorization(ctx context.Context, in *proto.DecideDeviceAuthorizationRequest, opts ...grpc.CallOption) (*proto.DecideDeviceAuthorizationResponse, error)
+	PollDeviceAuthorization(ctx context.Context, in *proto.PollDeviceAuthorizationRequest, opts ...grpc.CallOption) (*proto.PollDeviceAuthorizationResponse, error)
+	SavePasskeyChallenge(ctx context.Context, in *proto.SavePasskeyChallengeRequest, opts ...grpc.CallOption) (*proto.SavePasskeyChallengeResponse, error)
+	ConsumePasskeyChallenge(ctx context.Context, in *proto.ConsumePasskeyChallengeRequest, opts ...grpc.CallOption) (*proto.ConsumePasskeyChallengeResponse, error)
 }
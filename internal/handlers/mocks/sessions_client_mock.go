@@ -56,44 +56,64 @@ func (mr *MockSessionsClientMockRecorder) Add(ctx, in interface{}, opts ...inter
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Add", reflect.TypeOf((*MockSessionsClient)(nil).Add), varargs...)
 }
 
-// CheckAllUserSessionTokens mocks base method.
-func (m *MockSessionsClient) CheckAllUserSessionTokens(ctx context.Context, in *session.CheckAllUserSessionTokensRequest, opts ...grpc.CallOption) (*session.CheckAllUserSessionTokensResponse, error) {
+// CheckVersion mocks base method.
+func (m *MockSessionsClient) CheckVersion(ctx context.Context, in *session.CheckVersionRequest, opts ...grpc.CallOption) (*session.CheckVersionResponse, error) {
 	m.ctrl.T.Helper()
 	varargs := []interface{}{ctx, in}
 	for _, a := range opts {
 		varargs = append(varargs, a)
 	}
-	ret := m.ctrl.Call(m, "CheckAllUserSessionTokens", varargs...)
-	ret0, _ := ret[0].(*session.CheckAllUserSessionTokensResponse)
+	ret := m.ctrl.Call(m, "CheckVersion", varargs...)
+	ret0, _ := ret[0].(*session.CheckVersionResponse)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// CheckAllUserSessionTokens indicates an expected call of CheckAllUserSessionTokens.
-func (mr *MockSessionsClientMockRecorder) CheckAllUserSessionTokens(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+// CheckVersion indicates an expected call of CheckVersion.
+func (mr *MockSessionsClientMockRecorder) CheckVersion(ctx, in interface{}, opts ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	varargs := append([]interface{}{ctx, in}, opts...)
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckAllUserSessionTokens", reflect.TypeOf((*MockSessionsClient)(nil).CheckAllUserSessionTokens), varargs...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckVersion", reflect.TypeOf((*MockSessionsClient)(nil).CheckVersion), varargs...)
 }
 
-// CheckVersion mocks base method.
-func (m *MockSessionsClient) CheckVersion(ctx context.Context, in *session.CheckVersionRequest, opts ...grpc.CallOption) (*session.CheckVersionResponse, error) {
+// ConsumePasskeyChallenge mocks base method.
+func (m *MockSessionsClient) ConsumePasskeyChallenge(ctx context.Context, in *session.ConsumePasskeyChallengeRequest, opts ...grpc.CallOption) (*session.ConsumePasskeyChallengeResponse, error) {
 	m.ctrl.T.Helper()
 	varargs := []interface{}{ctx, in}
 	for _, a := range opts {
 		varargs = append(varargs, a)
 	}
-	ret := m.ctrl.Call(m, "CheckVersion", varargs...)
-	ret0, _ := ret[0].(*session.CheckVersionResponse)
+	ret := m.ctrl.Call(m, "ConsumePasskeyChallenge", varargs...)
+	ret0, _ := ret[0].(*session.ConsumePasskeyChallengeResponse)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// CheckVersion indicates an expected call of CheckVersion.
-func (mr *MockSessionsClientMockRecorder) CheckVersion(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+// ConsumePasskeyChallenge indicates an expected call of ConsumePasskeyChallenge.
+func (mr *MockSessionsClientMockRecorder) ConsumePasskeyChallenge(ctx, in interface{}, opts ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	varargs := append([]interface{}{ctx, in}, opts...)
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckVersion", reflect.TypeOf((*MockSessionsClient)(nil).CheckVersion), varargs...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumePasskeyChallenge", reflect.TypeOf((*MockSessionsClient)(nil).ConsumePasskeyChallenge), varargs...)
+}
+
+// DecideDeviceAuthorization mocks base method.
+func (m *MockSessionsClient) DecideDeviceAuthorization(ctx context.Context, in *session.DecideDeviceAuthorizationRequest, opts ...grpc.CallOption) (*session.DecideDeviceAuthorizationResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DecideDeviceAuthorization", varargs...)
+	ret0, _ := ret[0].(*session.DecideDeviceAuthorizationResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DecideDeviceAuthorization indicates an expected call of DecideDeviceAuthorization.
+func (mr *MockSessionsClientMockRecorder) DecideDeviceAuthorization(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DecideDeviceAuthorization", reflect.TypeOf((*MockSessionsClient)(nil).DecideDeviceAuthorization), varargs...)
 }
 
 // DeleteSession mocks base method.
@@ -116,6 +136,46 @@ func (mr *MockSessionsClientMockRecorder) DeleteSession(ctx, in interface{}, opt
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSession", reflect.TypeOf((*MockSessionsClient)(nil).DeleteSession), varargs...)
 }
 
+// GetDeviceAuthorization mocks base method.
+func (m *MockSessionsClient) GetDeviceAuthorization(ctx context.Context, in *session.GetDeviceAuthorizationRequest, opts ...grpc.CallOption) (*session.GetDeviceAuthorizationResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetDeviceAuthorization", varargs...)
+	ret0, _ := ret[0].(*session.GetDeviceAuthorizationResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDeviceAuthorization indicates an expected call of GetDeviceAuthorization.
+func (mr *MockSessionsClientMockRecorder) GetDeviceAuthorization(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDeviceAuthorization", reflect.TypeOf((*MockSessionsClient)(nil).GetDeviceAuthorization), varargs...)
+}
+
+// GetRefreshToken mocks base method.
+func (m *MockSessionsClient) GetRefreshToken(ctx context.Context, in *session.GetRefreshTokenRequest, opts ...grpc.CallOption) (*session.GetRefreshTokenResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetRefreshToken", varargs...)
+	ret0, _ := ret[0].(*session.GetRefreshTokenResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRefreshToken indicates an expected call of GetRefreshToken.
+func (mr *MockSessionsClientMockRecorder) GetRefreshToken(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRefreshToken", reflect.TypeOf((*MockSessionsClient)(nil).GetRefreshToken), varargs...)
+}
+
 // GetVersion mocks base method.
 func (m *MockSessionsClient) GetVersion(ctx context.Context, in *session.GetVersionRequest, opts ...grpc.CallOption) (*session.GetVersionResponse, error) {
 	m.ctrl.T.Helper()
@@ -156,6 +216,286 @@ func (mr *MockSessionsClientMockRecorder) HasSession(ctx, in interface{}, opts .
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HasSession", reflect.TypeOf((*MockSessionsClient)(nil).HasSession), varargs...)
 }
 
+// IssueRefreshToken mocks base method.
+func (m *MockSessionsClient) IssueRefreshToken(ctx context.Context, in *session.IssueRefreshTokenRequest, opts ...grpc.CallOption) (*session.IssueRefreshTokenResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "IssueRefreshToken", varargs...)
+	ret0, _ := ret[0].(*session.IssueRefreshTokenResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IssueRefreshToken indicates an expected call of IssueRefreshToken.
+func (mr *MockSessionsClientMockRecorder) IssueRefreshToken(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IssueRefreshToken", reflect.TypeOf((*MockSessionsClient)(nil).IssueRefreshToken), varargs...)
+}
+
+// ListSessions mocks base method.
+func (m *MockSessionsClient) ListSessions(ctx context.Context, in *session.ListSessionsRequest, opts ...grpc.CallOption) (*session.ListSessionsResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ListSessions", varargs...)
+	ret0, _ := ret[0].(*session.ListSessionsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSessions indicates an expected call of ListSessions.
+func (mr *MockSessionsClientMockRecorder) ListSessions(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSessions", reflect.TypeOf((*MockSessionsClient)(nil).ListSessions), varargs...)
+}
+
+// PollDeviceAuthorization mocks base method.
+func (m *MockSessionsClient) PollDeviceAuthorization(ctx context.Context, in *session.PollDeviceAuthorizationRequest, opts ...grpc.CallOption) (*session.PollDeviceAuthorizationResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "PollDeviceAuthorization", varargs...)
+	ret0, _ := ret[0].(*session.PollDeviceAuthorizationResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PollDeviceAuthorization indicates an expected call of PollDeviceAuthorization.
+func (mr *MockSessionsClientMockRecorder) PollDeviceAuthorization(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PollDeviceAuthorization", reflect.TypeOf((*MockSessionsClient)(nil).PollDeviceAuthorization), varargs...)
+}
+
+// RegisterLoginFailure mocks base method.
+func (m *MockSessionsClient) RegisterLoginFailure(ctx context.Context, in *session.RegisterLoginFailureRequest, opts ...grpc.CallOption) (*session.RegisterLoginFailureResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "RegisterLoginFailure", varargs...)
+	ret0, _ := ret[0].(*session.RegisterLoginFailureResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RegisterLoginFailure indicates an expected call of RegisterLoginFailure.
+func (mr *MockSessionsClientMockRecorder) RegisterLoginFailure(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterLoginFailure", reflect.TypeOf((*MockSessionsClient)(nil).RegisterLoginFailure), varargs...)
+}
+
+// ReleaseLoginAttempt mocks base method.
+func (m *MockSessionsClient) ReleaseLoginAttempt(ctx context.Context, in *session.ReleaseLoginAttemptRequest, opts ...grpc.CallOption) (*session.ReleaseLoginAttemptResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ReleaseLoginAttempt", varargs...)
+	ret0, _ := ret[0].(*session.ReleaseLoginAttemptResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReleaseLoginAttempt indicates an expected call of ReleaseLoginAttempt.
+func (mr *MockSessionsClientMockRecorder) ReleaseLoginAttempt(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseLoginAttempt", reflect.TypeOf((*MockSessionsClient)(nil).ReleaseLoginAttempt), varargs...)
+}
+
+// ReserveLoginAttempt mocks base method.
+func (m *MockSessionsClient) ReserveLoginAttempt(ctx context.Context, in *session.ReserveLoginAttemptRequest, opts ...grpc.CallOption) (*session.ReserveLoginAttemptResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ReserveLoginAttempt", varargs...)
+	ret0, _ := ret[0].(*session.ReserveLoginAttemptResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReserveLoginAttempt indicates an expected call of ReserveLoginAttempt.
+func (mr *MockSessionsClientMockRecorder) ReserveLoginAttempt(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReserveLoginAttempt", reflect.TypeOf((*MockSessionsClient)(nil).ReserveLoginAttempt), varargs...)
+}
+
+// ResetLoginAttempts mocks base method.
+func (m *MockSessionsClient) ResetLoginAttempts(ctx context.Context, in *session.ResetLoginAttemptsRequest, opts ...grpc.CallOption) (*session.ResetLoginAttemptsResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ResetLoginAttempts", varargs...)
+	ret0, _ := ret[0].(*session.ResetLoginAttemptsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResetLoginAttempts indicates an expected call of ResetLoginAttempts.
+func (mr *MockSessionsClientMockRecorder) ResetLoginAttempts(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetLoginAttempts", reflect.TypeOf((*MockSessionsClient)(nil).ResetLoginAttempts), varargs...)
+}
+
+// RevokeOtherSessions mocks base method.
+func (m *MockSessionsClient) RevokeOtherSessions(ctx context.Context, in *session.RevokeOtherSessionsRequest, opts ...grpc.CallOption) (*session.RevokeOtherSessionsResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "RevokeOtherSessions", varargs...)
+	ret0, _ := ret[0].(*session.RevokeOtherSessionsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevokeOtherSessions indicates an expected call of RevokeOtherSessions.
+func (mr *MockSessionsClientMockRecorder) RevokeOtherSessions(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeOtherSessions", reflect.TypeOf((*MockSessionsClient)(nil).RevokeOtherSessions), varargs...)
+}
+
+// RevokeRefreshTokenFamily mocks base method.
+func (m *MockSessionsClient) RevokeRefreshTokenFamily(ctx context.Context, in *session.RevokeRefreshTokenFamilyRequest, opts ...grpc.CallOption) (*session.RevokeRefreshTokenFamilyResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "RevokeRefreshTokenFamily", varargs...)
+	ret0, _ := ret[0].(*session.RevokeRefreshTokenFamilyResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevokeRefreshTokenFamily indicates an expected call of RevokeRefreshTokenFamily.
+func (mr *MockSessionsClientMockRecorder) RevokeRefreshTokenFamily(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeRefreshTokenFamily", reflect.TypeOf((*MockSessionsClient)(nil).RevokeRefreshTokenFamily), varargs...)
+}
+
+// RevokeSession mocks base method.
+func (m *MockSessionsClient) RevokeSession(ctx context.Context, in *session.RevokeSessionRequest, opts ...grpc.CallOption) (*session.RevokeSessionResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "RevokeSession", varargs...)
+	ret0, _ := ret[0].(*session.RevokeSessionResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevokeSession indicates an expected call of RevokeSession.
+func (mr *MockSessionsClientMockRecorder) RevokeSession(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeSession", reflect.TypeOf((*MockSessionsClient)(nil).RevokeSession), varargs...)
+}
+
+// RotateRefreshToken mocks base method.
+func (m *MockSessionsClient) RotateRefreshToken(ctx context.Context, in *session.RotateRefreshTokenRequest, opts ...grpc.CallOption) (*session.RotateRefreshTokenResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "RotateRefreshToken", varargs...)
+	ret0, _ := ret[0].(*session.RotateRefreshTokenResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RotateRefreshToken indicates an expected call of RotateRefreshToken.
+func (mr *MockSessionsClientMockRecorder) RotateRefreshToken(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateRefreshToken", reflect.TypeOf((*MockSessionsClient)(nil).RotateRefreshToken), varargs...)
+}
+
+// SavePasskeyChallenge mocks base method.
+func (m *MockSessionsClient) SavePasskeyChallenge(ctx context.Context, in *session.SavePasskeyChallengeRequest, opts ...grpc.CallOption) (*session.SavePasskeyChallengeResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "SavePasskeyChallenge", varargs...)
+	ret0, _ := ret[0].(*session.SavePasskeyChallengeResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SavePasskeyChallenge indicates an expected call of SavePasskeyChallenge.
+func (mr *MockSessionsClientMockRecorder) SavePasskeyChallenge(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SavePasskeyChallenge", reflect.TypeOf((*MockSessionsClient)(nil).SavePasskeyChallenge), varargs...)
+}
+
+// StartDeviceAuthorization mocks base method.
+func (m *MockSessionsClient) StartDeviceAuthorization(ctx context.Context, in *session.StartDeviceAuthorizationRequest, opts ...grpc.CallOption) (*session.StartDeviceAuthorizationResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "StartDeviceAuthorization", varargs...)
+	ret0, _ := ret[0].(*session.StartDeviceAuthorizationResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// StartDeviceAuthorization indicates an expected call of StartDeviceAuthorization.
+func (mr *MockSessionsClientMockRecorder) StartDeviceAuthorization(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartDeviceAuthorization", reflect.TypeOf((*MockSessionsClient)(nil).StartDeviceAuthorization), varargs...)
+}
+
+// UnlockLogin mocks base method.
+func (m *MockSessionsClient) UnlockLogin(ctx context.Context, in *session.UnlockLoginRequest, opts ...grpc.CallOption) (*session.UnlockLoginResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "UnlockLogin", varargs...)
+	ret0, _ := ret[0].(*session.UnlockLoginResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UnlockLogin indicates an expected call of UnlockLogin.
+func (mr *MockSessionsClientMockRecorder) UnlockLogin(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnlockLogin", reflect.TypeOf((*MockSessionsClient)(nil).UnlockLogin), varargs...)
+}
+
 // Update mocks base method.
 func (m *MockSessionsClient) Update(ctx context.Context, in *session.UpdateRequest, opts ...grpc.CallOption) (*session.UpdateRequestResponse, error) {
 	m.ctrl.T.Helper()
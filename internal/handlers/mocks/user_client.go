@@ -24,4 +24,25 @@ type UsersClient interface {
 	HasSubscription(ctx context.Context, in *proto.HasSubscriptionRequest, opts ...grpc.CallOption) (*proto.HasSubscriptionResponse, error)
 	GetSubscriptions(ctx context.Context, in *proto.GetSubscriptionsRequest, opts ...grpc.CallOption) (*proto.GetSubscriptionsResponse, error)
 	PaySubscription(ctx context.Context, in *proto.PaySubscriptionRequest, opts ...grpc.CallOption) (*proto.PaySubscriptionResponse, error)
+	GetRolePermissions(ctx context.Context, in *proto.GetRolePermissionsRequest, opts ...grpc.CallOption) (*proto.GetRolePermissionsResponse, error)
+	RequestPasswordReset(ctx context.Context, in *proto.RequestPasswordResetRequest, opts ...grpc.CallOption) (*proto.RequestPasswordResetResponse, error)
+	ResetPassword(ctx context.Context, in *proto.ResetPasswordRequest, opts ...grpc.CallOption) (*proto.ResetPasswordResponse, error)
+	ResendEmailVerification(ctx context.Context, in *proto.ResendEmailVerificationRequest, opts ...grpc.CallOption) (*proto.ResendEmailVerificationResponse, error)
+	VerifyEmail(ctx context.Context, in *proto.VerifyEmailRequest, opts ...grpc.CallOption) (*proto.VerifyEmailResponse, error)
+	EnrollTOTP(ctx context.Context, in *proto.EnrollTOTPRequest, opts ...grpc.CallOption) (*proto.EnrollTOTPResponse, error)
+	ConfirmTOTP(ctx context.Context, in *proto.ConfirmTOTPRequest, opts ...grpc.CallOption) (*proto.ConfirmTOTPResponse, error)
+	VerifyTOTP(ctx context.Context, in *proto.VerifyTOTPRequest, opts ...grpc.CallOption) (*proto.VerifyTOTPResponse, error)
+	DisableTOTP(ctx context.Context, in *proto.DisableTOTPRequest, opts ...grpc.CallOption) (*proto.DisableTOTPResponse, error)
+	LoginWithIdentity(ctx context.Context, in *proto.LoginWithIdentityRequest, opts ...grpc.CallOption) (*proto.LoginWithIdentityResponse, error)
+	SendMagicLink(ctx context.Context, in *proto.SendMagicLinkRequest, opts ...grpc.CallOption) (*proto.SendMagicLinkResponse, error)
+	ConsumeMagicLink(ctx context.Context, in *proto.ConsumeMagicLinkRequest, opts ...grpc.CallOption) (*proto.ConsumeMagicLinkResponse, error)
+	AddPasskey(ctx context.Context, in *proto.AddPasskeyRequest, opts ...grpc.CallOption) (*proto.AddPasskeyResponse, error)
+	GetPasskeys(ctx context.Context, in *proto.GetPasskeysRequest, opts ...grpc.CallOption) (*proto.GetPasskeysResponse, error)
+	GetPasskey(ctx context.Context, in *proto.GetPasskeyRequest, opts ...grpc.CallOption) (*proto.GetPasskeyResponse, error)
+	UpdatePasskeySignCount(ctx context.Context, in *proto.UpdatePasskeySignCountRequest, opts ...grpc.CallOption) (*proto.UpdatePasskeySignCountResponse, error)
+	RemovePasskey(ctx context.Context, in *proto.RemovePasskeyRequest, opts ...grpc.CallOption) (*proto.RemovePasskeyResponse, error)
+	RecordAuditEvent(ctx context.Context, in *proto.RecordAuditEventRequest, opts ...grpc.CallOption) (*proto.RecordAuditEventResponse, error)
+	ListAuditEvents(ctx context.Context, in *proto.ListAuditEventsRequest, opts ...grpc.CallOption) (*proto.ListAuditEventsResponse, error)
+	RegisterLoginDevice(ctx context.Context, in *proto.RegisterLoginDeviceRequest, opts ...grpc.CallOption) (*proto.RegisterLoginDeviceResponse, error)
+	ReportUnknownLogin(ctx context.Context, in *proto.ReportUnknownLoginRequest, opts ...grpc.CallOption) (*proto.ReportUnknownLoginResponse, error)
 }
@@ -36,6 +36,26 @@ func (m *MockUsersClient) EXPECT() *MockUsersClientMockRecorder {
 	return m.recorder
 }
 
+// AddPasskey mocks base method.
+func (m *MockUsersClient) AddPasskey(ctx context.Context, in *session.AddPasskeyRequest, opts ...grpc.CallOption) (*session.AddPasskeyResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "AddPasskey", varargs...)
+	ret0, _ := ret[0].(*session.AddPasskeyResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddPasskey indicates an expected call of AddPasskey.
+func (mr *MockUsersClientMockRecorder) AddPasskey(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddPasskey", reflect.TypeOf((*MockUsersClient)(nil).AddPasskey), varargs...)
+}
+
 // ChangeUserAvatarByUuid mocks base method.
 func (m *MockUsersClient) ChangeUserAvatarByUuid(ctx context.Context, in *session.ChangeUserAvatarByUuidRequest, opts ...grpc.CallOption) (*session.ChangeUserAvatarByUuidResponse, error) {
 	m.ctrl.T.Helper()
@@ -136,6 +156,46 @@ func (mr *MockUsersClientMockRecorder) ChangeUserPasswordByUuid(ctx, in interfac
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeUserPasswordByUuid", reflect.TypeOf((*MockUsersClient)(nil).ChangeUserPasswordByUuid), varargs...)
 }
 
+// ConfirmTOTP mocks base method.
+func (m *MockUsersClient) ConfirmTOTP(ctx context.Context, in *session.ConfirmTOTPRequest, opts ...grpc.CallOption) (*session.ConfirmTOTPResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ConfirmTOTP", varargs...)
+	ret0, _ := ret[0].(*session.ConfirmTOTPResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConfirmTOTP indicates an expected call of ConfirmTOTP.
+func (mr *MockUsersClientMockRecorder) ConfirmTOTP(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConfirmTOTP", reflect.TypeOf((*MockUsersClient)(nil).ConfirmTOTP), varargs...)
+}
+
+// ConsumeMagicLink mocks base method.
+func (m *MockUsersClient) ConsumeMagicLink(ctx context.Context, in *session.ConsumeMagicLinkRequest, opts ...grpc.CallOption) (*session.ConsumeMagicLinkResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ConsumeMagicLink", varargs...)
+	ret0, _ := ret[0].(*session.ConsumeMagicLinkResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConsumeMagicLink indicates an expected call of ConsumeMagicLink.
+func (mr *MockUsersClientMockRecorder) ConsumeMagicLink(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeMagicLink", reflect.TypeOf((*MockUsersClient)(nil).ConsumeMagicLink), varargs...)
+}
+
 // CreateUser mocks base method.
 func (m *MockUsersClient) CreateUser(ctx context.Context, in *session.CreateUserRequest, opts ...grpc.CallOption) (*session.CreateUserResponse, error) {
 	m.ctrl.T.Helper()
@@ -156,6 +216,106 @@ func (mr *MockUsersClientMockRecorder) CreateUser(ctx, in interface{}, opts ...i
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockUsersClient)(nil).CreateUser), varargs...)
 }
 
+// DisableTOTP mocks base method.
+func (m *MockUsersClient) DisableTOTP(ctx context.Context, in *session.DisableTOTPRequest, opts ...grpc.CallOption) (*session.DisableTOTPResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DisableTOTP", varargs...)
+	ret0, _ := ret[0].(*session.DisableTOTPResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DisableTOTP indicates an expected call of DisableTOTP.
+func (mr *MockUsersClientMockRecorder) DisableTOTP(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisableTOTP", reflect.TypeOf((*MockUsersClient)(nil).DisableTOTP), varargs...)
+}
+
+// EnrollTOTP mocks base method.
+func (m *MockUsersClient) EnrollTOTP(ctx context.Context, in *session.EnrollTOTPRequest, opts ...grpc.CallOption) (*session.EnrollTOTPResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "EnrollTOTP", varargs...)
+	ret0, _ := ret[0].(*session.EnrollTOTPResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EnrollTOTP indicates an expected call of EnrollTOTP.
+func (mr *MockUsersClientMockRecorder) EnrollTOTP(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnrollTOTP", reflect.TypeOf((*MockUsersClient)(nil).EnrollTOTP), varargs...)
+}
+
+// GetPasskey mocks base method.
+func (m *MockUsersClient) GetPasskey(ctx context.Context, in *session.GetPasskeyRequest, opts ...grpc.CallOption) (*session.GetPasskeyResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetPasskey", varargs...)
+	ret0, _ := ret[0].(*session.GetPasskeyResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPasskey indicates an expected call of GetPasskey.
+func (mr *MockUsersClientMockRecorder) GetPasskey(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPasskey", reflect.TypeOf((*MockUsersClient)(nil).GetPasskey), varargs...)
+}
+
+// GetPasskeys mocks base method.
+func (m *MockUsersClient) GetPasskeys(ctx context.Context, in *session.GetPasskeysRequest, opts ...grpc.CallOption) (*session.GetPasskeysResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetPasskeys", varargs...)
+	ret0, _ := ret[0].(*session.GetPasskeysResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPasskeys indicates an expected call of GetPasskeys.
+func (mr *MockUsersClientMockRecorder) GetPasskeys(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPasskeys", reflect.TypeOf((*MockUsersClient)(nil).GetPasskeys), varargs...)
+}
+
+// GetRolePermissions mocks base method.
+func (m *MockUsersClient) GetRolePermissions(ctx context.Context, in *session.GetRolePermissionsRequest, opts ...grpc.CallOption) (*session.GetRolePermissionsResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetRolePermissions", varargs...)
+	ret0, _ := ret[0].(*session.GetRolePermissionsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRolePermissions indicates an expected call of GetRolePermissions.
+func (mr *MockUsersClientMockRecorder) GetRolePermissions(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRolePermissions", reflect.TypeOf((*MockUsersClient)(nil).GetRolePermissions), varargs...)
+}
+
 // GetSubscriptions mocks base method.
 func (m *MockUsersClient) GetSubscriptions(ctx context.Context, in *session.GetSubscriptionsRequest, opts ...grpc.CallOption) (*session.GetSubscriptionsResponse, error) {
 	m.ctrl.T.Helper()
@@ -276,6 +436,46 @@ func (mr *MockUsersClientMockRecorder) HasUser(ctx, in interface{}, opts ...inte
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HasUser", reflect.TypeOf((*MockUsersClient)(nil).HasUser), varargs...)
 }
 
+// ListAuditEvents mocks base method.
+func (m *MockUsersClient) ListAuditEvents(ctx context.Context, in *session.ListAuditEventsRequest, opts ...grpc.CallOption) (*session.ListAuditEventsResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ListAuditEvents", varargs...)
+	ret0, _ := ret[0].(*session.ListAuditEventsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAuditEvents indicates an expected call of ListAuditEvents.
+func (mr *MockUsersClientMockRecorder) ListAuditEvents(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAuditEvents", reflect.TypeOf((*MockUsersClient)(nil).ListAuditEvents), varargs...)
+}
+
+// LoginWithIdentity mocks base method.
+func (m *MockUsersClient) LoginWithIdentity(ctx context.Context, in *session.LoginWithIdentityRequest, opts ...grpc.CallOption) (*session.LoginWithIdentityResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "LoginWithIdentity", varargs...)
+	ret0, _ := ret[0].(*session.LoginWithIdentityResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoginWithIdentity indicates an expected call of LoginWithIdentity.
+func (mr *MockUsersClientMockRecorder) LoginWithIdentity(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoginWithIdentity", reflect.TypeOf((*MockUsersClient)(nil).LoginWithIdentity), varargs...)
+}
+
 // PaySubscription mocks base method.
 func (m *MockUsersClient) PaySubscription(ctx context.Context, in *session.PaySubscriptionRequest, opts ...grpc.CallOption) (*session.PaySubscriptionResponse, error) {
 	m.ctrl.T.Helper()
@@ -296,6 +496,66 @@ func (mr *MockUsersClientMockRecorder) PaySubscription(ctx, in interface{}, opts
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PaySubscription", reflect.TypeOf((*MockUsersClient)(nil).PaySubscription), varargs...)
 }
 
+// RecordAuditEvent mocks base method.
+func (m *MockUsersClient) RecordAuditEvent(ctx context.Context, in *session.RecordAuditEventRequest, opts ...grpc.CallOption) (*session.RecordAuditEventResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "RecordAuditEvent", varargs...)
+	ret0, _ := ret[0].(*session.RecordAuditEventResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RecordAuditEvent indicates an expected call of RecordAuditEvent.
+func (mr *MockUsersClientMockRecorder) RecordAuditEvent(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordAuditEvent", reflect.TypeOf((*MockUsersClient)(nil).RecordAuditEvent), varargs...)
+}
+
+// RegisterLoginDevice mocks base method.
+func (m *MockUsersClient) RegisterLoginDevice(ctx context.Context, in *session.RegisterLoginDeviceRequest, opts ...grpc.CallOption) (*session.RegisterLoginDeviceResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "RegisterLoginDevice", varargs...)
+	ret0, _ := ret[0].(*session.RegisterLoginDeviceResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RegisterLoginDevice indicates an expected call of RegisterLoginDevice.
+func (mr *MockUsersClientMockRecorder) RegisterLoginDevice(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterLoginDevice", reflect.TypeOf((*MockUsersClient)(nil).RegisterLoginDevice), varargs...)
+}
+
+// RemovePasskey mocks base method.
+func (m *MockUsersClient) RemovePasskey(ctx context.Context, in *session.RemovePasskeyRequest, opts ...grpc.CallOption) (*session.RemovePasskeyResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "RemovePasskey", varargs...)
+	ret0, _ := ret[0].(*session.RemovePasskeyResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RemovePasskey indicates an expected call of RemovePasskey.
+func (mr *MockUsersClientMockRecorder) RemovePasskey(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemovePasskey", reflect.TypeOf((*MockUsersClient)(nil).RemovePasskey), varargs...)
+}
+
 // RemoveUser mocks base method.
 func (m *MockUsersClient) RemoveUser(ctx context.Context, in *session.RemoveUserRequest, opts ...grpc.CallOption) (*session.RemoveUserResponse, error) {
 	m.ctrl.T.Helper()
@@ -315,3 +575,163 @@ func (mr *MockUsersClientMockRecorder) RemoveUser(ctx, in interface{}, opts ...i
 	varargs := append([]interface{}{ctx, in}, opts...)
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveUser", reflect.TypeOf((*MockUsersClient)(nil).RemoveUser), varargs...)
 }
+
+// ReportUnknownLogin mocks base method.
+func (m *MockUsersClient) ReportUnknownLogin(ctx context.Context, in *session.ReportUnknownLoginRequest, opts ...grpc.CallOption) (*session.ReportUnknownLoginResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ReportUnknownLogin", varargs...)
+	ret0, _ := ret[0].(*session.ReportUnknownLoginResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReportUnknownLogin indicates an expected call of ReportUnknownLogin.
+func (mr *MockUsersClientMockRecorder) ReportUnknownLogin(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReportUnknownLogin", reflect.TypeOf((*MockUsersClient)(nil).ReportUnknownLogin), varargs...)
+}
+
+// RequestPasswordReset mocks base method.
+func (m *MockUsersClient) RequestPasswordReset(ctx context.Context, in *session.RequestPasswordResetRequest, opts ...grpc.CallOption) (*session.RequestPasswordResetResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "RequestPasswordReset", varargs...)
+	ret0, _ := ret[0].(*session.RequestPasswordResetResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RequestPasswordReset indicates an expected call of RequestPasswordReset.
+func (mr *MockUsersClientMockRecorder) RequestPasswordReset(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RequestPasswordReset", reflect.TypeOf((*MockUsersClient)(nil).RequestPasswordReset), varargs...)
+}
+
+// ResendEmailVerification mocks base method.
+func (m *MockUsersClient) ResendEmailVerification(ctx context.Context, in *session.ResendEmailVerificationRequest, opts ...grpc.CallOption) (*session.ResendEmailVerificationResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ResendEmailVerification", varargs...)
+	ret0, _ := ret[0].(*session.ResendEmailVerificationResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResendEmailVerification indicates an expected call of ResendEmailVerification.
+func (mr *MockUsersClientMockRecorder) ResendEmailVerification(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResendEmailVerification", reflect.TypeOf((*MockUsersClient)(nil).ResendEmailVerification), varargs...)
+}
+
+// ResetPassword mocks base method.
+func (m *MockUsersClient) ResetPassword(ctx context.Context, in *session.ResetPasswordRequest, opts ...grpc.CallOption) (*session.ResetPasswordResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ResetPassword", varargs...)
+	ret0, _ := ret[0].(*session.ResetPasswordResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResetPassword indicates an expected call of ResetPassword.
+func (mr *MockUsersClientMockRecorder) ResetPassword(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetPassword", reflect.TypeOf((*MockUsersClient)(nil).ResetPassword), varargs...)
+}
+
+// SendMagicLink mocks base method.
+func (m *MockUsersClient) SendMagicLink(ctx context.Context, in *session.SendMagicLinkRequest, opts ...grpc.CallOption) (*session.SendMagicLinkResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "SendMagicLink", varargs...)
+	ret0, _ := ret[0].(*session.SendMagicLinkResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SendMagicLink indicates an expected call of SendMagicLink.
+func (mr *MockUsersClientMockRecorder) SendMagicLink(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendMagicLink", reflect.TypeOf((*MockUsersClient)(nil).SendMagicLink), varargs...)
+}
+
+// UpdatePasskeySignCount mocks base method.
+func (m *MockUsersClient) UpdatePasskeySignCount(ctx context.Context, in *session.UpdatePasskeySignCountRequest, opts ...grpc.CallOption) (*session.UpdatePasskeySignCountResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "UpdatePasskeySignCount", varargs...)
+	ret0, _ := ret[0].(*session.UpdatePasskeySignCountResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdatePasskeySignCount indicates an expected call of UpdatePasskeySignCount.
+func (mr *MockUsersClientMockRecorder) UpdatePasskeySignCount(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePasskeySignCount", reflect.TypeOf((*MockUsersClient)(nil).UpdatePasskeySignCount), varargs...)
+}
+
+// VerifyEmail mocks base method.
+func (m *MockUsersClient) VerifyEmail(ctx context.Context, in *session.VerifyEmailRequest, opts ...grpc.CallOption) (*session.VerifyEmailResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "VerifyEmail", varargs...)
+	ret0, _ := ret[0].(*session.VerifyEmailResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VerifyEmail indicates an expected call of VerifyEmail.
+func (mr *MockUsersClientMockRecorder) VerifyEmail(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyEmail", reflect.TypeOf((*MockUsersClient)(nil).VerifyEmail), varargs...)
+}
+
+// VerifyTOTP mocks base method.
+func (m *MockUsersClient) VerifyTOTP(ctx context.Context, in *session.VerifyTOTPRequest, opts ...grpc.CallOption) (*session.VerifyTOTPResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "VerifyTOTP", varargs...)
+	ret0, _ := ret[0].(*session.VerifyTOTPResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VerifyTOTP indicates an expected call of VerifyTOTP.
+func (mr *MockUsersClientMockRecorder) VerifyTOTP(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyTOTP", reflect.TypeOf((*MockUsersClient)(nil).VerifyTOTP), varargs...)
+}
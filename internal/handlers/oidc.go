@@ -0,0 +1,342 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/gorilla/mux"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/SanExpett/diploma/internal/clientip"
+	"github.com/SanExpett/diploma/internal/cookies"
+	"github.com/SanExpett/diploma/internal/domain"
+	myerrors "github.com/SanExpett/diploma/internal/errors"
+	"github.com/SanExpett/diploma/internal/oidc"
+	reqid "github.com/SanExpett/diploma/internal/requestId"
+	session "github.com/SanExpett/diploma/internal/session/proto"
+	"github.com/SanExpett/diploma/internal/signing"
+)
+
+const (
+	oidcStateCookie         = "oidc_state"
+	oidcStateCookiePath     = "/api/auth/oidc"
+	oidcStateExpirationTime = 10 * time.Minute
+	oidcStateAudience       = "oidc"
+)
+
+// oidcStateClaims состояние входа через провайдера между редиректом на провайдера и callback. Хранится
+// в подписанной куке, поэтому gateway не держит его у себя, а подменить его клиент не может
+type oidcStateClaims struct {
+	jwt.StandardClaims
+	Provider string `json:"provider"`
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	// LinkLogin логин вошедшего пользователя, к которому привязывается аккаунт, пусто при обычном входе
+	LinkLogin string `json:"linkLogin,omitempty"`
+}
+
+func newOIDCState(provider, linkLogin string) (oidcStateClaims, error) {
+	state, err := oidc.RandomString()
+	if err != nil {
+		return oidcStateClaims{}, err
+	}
+	nonce, err := oidc.RandomString()
+	if err != nil {
+		return oidcStateClaims{}, err
+	}
+	verifier, err := oidc.RandomString()
+	if err != nil {
+		return oidcStateClaims{}, err
+	}
+
+	return oidcStateClaims{
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: time.Now().Add(oidcStateExpirationTime).Unix(),
+			Issuer:    "nimbus",
+			Audience:  oidcStateAudience,
+		},
+		Provider:  provider,
+		State:     state,
+		Nonce:     nonce,
+		Verifier:  verifier,
+		LinkLogin: linkLogin,
+	}, nil
+}
+
+func parseOIDCState(keyManager *signing.KeyManager, stateSigned string) (oidcStateClaims, error) {
+	var claims oidcStateClaims
+	parsedToken, err := jwt.ParseWithClaims(stateSigned, &claims, keyManager.Keyfunc)
+	if err != nil {
+		return oidcStateClaims{}, err
+	}
+	if !parsedToken.Valid || !claims.VerifyAudience(oidcStateAudience, true) {
+		return oidcStateClaims{}, fmt.Errorf("invalid oidc state")
+	}
+	return claims, nil
+}
+
+type OIDCHandlers struct {
+	authPageHandlers *AuthPageHandlers
+	registry         *oidc.Registry
+}
+
+// NewOIDCHandlers создает обработчики входа через внешних провайдеров. Сессия после входа выдается
+// так же, как при входе по паролю
+func NewOIDCHandlers(authPageHandlers *AuthPageHandlers, registry *oidc.Registry) *OIDCHandlers {
+	return &OIDCHandlers{
+		authPageHandlers: authPageHandlers,
+		registry:         registry,
+	}
+}
+
+// @Summary      Провайдеры входа
+// @Description  Возвращает имена настроенных OpenID Connect провайдеров
+// @Tags         Auth
+// @Produce      json
+// @Success      200  {object}  domain.OIDCProvidersResponse  "Имена провайдеров"
+// @Router       /auth/oidc/providers [get]
+func (oidcHandlers *OIDCHandlers) Providers(w http.ResponseWriter, r *http.Request) {
+	requestID := r.Context().Value(reqid.ReqIDKey)
+	authPageHandlers := oidcHandlers.authPageHandlers
+
+	response := domain.OIDCProvidersResponse{
+		Status:    http.StatusOK,
+		Providers: oidcHandlers.registry.Names(),
+	}
+
+	jsonResponse, err := json.Marshal(response)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to marshal response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	err = WriteResponse(w, r, authPageHandlers.metrics, jsonResponse, requestID)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+	}
+}
+
+// @Summary      Вход через провайдера
+// @Description  Перенаправляет на страницу входа провайдера (authorization code flow с PKCE)
+// @Tags         Auth
+// @Param        provider  path  string  true  "Имя провайдера"
+// @Success      302  "Редирект на провайдера"
+// @Failure      404  {object}  object  "Неизвестный провайдер"
+// @Router       /auth/oidc/{provider}/login [get]
+func (oidcHandlers *OIDCHandlers) Login(w http.ResponseWriter, r *http.Request) {
+	oidcHandlers.startAuthorization(w, r, "")
+}
+
+// @Summary      Привязка аккаунта провайдера
+// @Description  Перенаправляет на страницу входа провайдера, после возврата аккаунт привязывается
+// @Description  к текущему пользователю
+// @Tags         Auth
+// @Param        provider  path  string  true  "Имя провайдера"
+// @Success      302  "Редирект на провайдера"
+// @Failure      401  {object}  object  "Пользователь не авторизован"
+// @Failure      404  {object}  object  "Неизвестный провайдер"
+// @Security     ApiKeyAuth
+// @Router       /auth/oidc/{provider}/link [get]
+func (oidcHandlers *OIDCHandlers) Link(w http.ResponseWriter, r *http.Request) {
+	requestID := r.Context().Value(reqid.ReqIDKey)
+	authPageHandlers := oidcHandlers.authPageHandlers
+
+	userPrincipal, err := getPrincipal(r)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	oidcHandlers.startAuthorization(w, r, userPrincipal.Login)
+}
+
+func (oidcHandlers *OIDCHandlers) startAuthorization(w http.ResponseWriter, r *http.Request, linkLogin string) {
+	ctx := r.Context()
+	requestID := ctx.Value(reqid.ReqIDKey)
+	authPageHandlers := oidcHandlers.authPageHandlers
+
+	provider, ok := oidcHandlers.registry.Provider(mux.Vars(r)["provider"])
+	if !ok {
+		err := WriteError(w, r, authPageHandlers.metrics, myerrors.ErrUnknownOIDCProvider)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	claims, err := newOIDCState(provider.Name(), linkLogin)
+	var authURL, stateSigned string
+	if err == nil {
+		authURL, err = provider.AuthCodeURL(ctx, claims.State, claims.Nonce, oidc.CodeChallenge(claims.Verifier))
+	}
+	if err == nil {
+		stateSigned, err = authPageHandlers.keyManager.Sign(claims)
+	}
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to start oidc login: %v\n", requestID, err)
+		err = WriteError(w, r, authPageHandlers.metrics, myerrors.ErrOIDCLoginFailed)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	// Lax, потому что провайдер возвращает пользователя обычной навигацией с другого сайта
+	http.SetCookie(w, authPageHandlers.cookies.New(oidcStateCookie, stateSigned, cookies.Options{
+		Path:     oidcStateCookiePath,
+		MaxAge:   oidcStateExpirationTime,
+		SameSite: http.SameSiteLaxMode,
+	}))
+
+	err = WriteRedirect(w, r, authPageHandlers.metrics, authURL)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+	}
+}
+
+// @Summary      Возврат от провайдера
+// @Description  Проверяет state, меняет код на id_token, проверяет его по ключам провайдера и входит
+// @Description  под привязанным пользователем. Новый пользователь создается, если почта еще не занята.
+// @Description  При включенном втором факторе возвращает токен для второго шага входа
+// @Tags         Auth
+// @Produce      json
+// @Param        provider  path   string  true  "Имя провайдера"
+// @Param        code      query  string  true  "Код авторизации"
+// @Param        state     query  string  true  "State из запроса авторизации"
+// @Success      200  {object}  object  "Успешный вход или привязка"
+// @Failure      400  {object}  object  "Аккаунт уже привязан или почта занята другим пользователем"
+// @Failure      401  {object}  object  "Вход через провайдера не удался"
+// @Failure      404  {object}  object  "Неизвестный провайдер"
+// @Router       /auth/oidc/{provider}/callback [get]
+func (oidcHandlers *OIDCHandlers) Callback(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	requestID := ctx.Value(reqid.ReqIDKey)
+	authPageHandlers := oidcHandlers.authPageHandlers
+
+	// state одноразовый, кука удаляется при любом исходе
+	stateCookie, cookieErr := r.Cookie(oidcStateCookie)
+	http.SetCookie(w, authPageHandlers.cookies.Expired(oidcStateCookie, cookies.Options{
+		Path:     oidcStateCookiePath,
+		SameSite: http.SameSiteLaxMode,
+	}))
+
+	provider, ok := oidcHandlers.registry.Provider(mux.Vars(r)["provider"])
+	if !ok {
+		err := WriteError(w, r, authPageHandlers.metrics, myerrors.ErrUnknownOIDCProvider)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	identity, linkLogin, err := oidcHandlers.identityFromCallback(r, provider, stateCookie, cookieErr)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] oidc login failed: %v\n", requestID, err)
+		err = WriteError(w, r, authPageHandlers.metrics, myerrors.ErrOIDCLoginFailed)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	reqLogin := session.LoginWithIdentityRequest{
+		Provider:      identity.Provider,
+		Subject:       identity.Subject,
+		Email:         identity.Email,
+		EmailVerified: identity.EmailVerified,
+		Name:          identity.Name,
+		LinkLogin:     linkLogin,
+	}
+	user, err := (*authPageHandlers.usersClient).LoginWithIdentity(ctx, &reqLogin)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, identityError(err))
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	if linkLogin != "" {
+		err = WriteSuccess(w, r, authPageHandlers.metrics)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		authPageHandlers.logger.Info(fmt.Sprintf("[reqid=%s] identity linked", requestID))
+		return
+	}
+
+	// провайдер заменяет только пароль, второй фактор по-прежнему нужен
+	if user.User.TotpEnabled {
+		authPageHandlers.writeTwoFactorChallenge(w, r, user.User.Email, false)
+		return
+	}
+
+	authPageHandlers.completeLogin(w, r, user.User, clientip.FromRequest(r), false)
+}
+
+// identityFromCallback проверяет state из куки и ответа провайдера, меняет код на id_token и достает из него
+// внешний аккаунт пользователя
+func (oidcHandlers *OIDCHandlers) identityFromCallback(r *http.Request, provider *oidc.Provider,
+	stateCookie *http.Cookie, cookieErr error) (domain.Identity, string, error) {
+	ctx := r.Context()
+	if cookieErr != nil {
+		return domain.Identity{}, "", fmt.Errorf("no state cookie: %w", cookieErr)
+	}
+
+	state, err := parseOIDCState(oidcHandlers.authPageHandlers.keyManager, stateCookie.Value)
+	if err != nil {
+		return domain.Identity{}, "", fmt.Errorf("failed to parse state cookie: %w", err)
+	}
+	if state.Provider != provider.Name() {
+		return domain.Identity{}, "", fmt.Errorf("state was issued for provider %q", state.Provider)
+	}
+
+	query := r.URL.Query()
+	if providerErr := query.Get("error"); providerErr != "" {
+		return domain.Identity{}, "", fmt.Errorf("provider returned error %q", providerErr)
+	}
+	if subtle.ConstantTimeCompare([]byte(query.Get("state")), []byte(state.State)) != 1 {
+		return domain.Identity{}, "", fmt.Errorf("state mismatch")
+	}
+
+	idToken, err := provider.Exchange(ctx, query.Get("code"), state.Verifier)
+	if err != nil {
+		return domain.Identity{}, "", err
+	}
+
+	claims, err := provider.VerifyIDToken(ctx, idToken, state.Nonce)
+	if err != nil {
+		return domain.Identity{}, "", err
+	}
+
+	return domain.Identity{
+		Provider:      provider.Name(),
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		Name:          claims.Name,
+	}, state.LinkLogin, nil
+}
+
+// identityError восстанавливает ошибки привязки, которые сервис пользователей передает кодами gRPC
+func identityError(err error) error {
+	switch status.Code(err) {
+	case codes.AlreadyExists:
+		return myerrors.ErrIdentityAlreadyLinked
+	case codes.FailedPrecondition:
+		return myerrors.ErrAccountExistsForIdentity
+	}
+	return err
+}
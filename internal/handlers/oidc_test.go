@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	myerrors "github.com/SanExpett/diploma/internal/errors"
+	"github.com/SanExpett/diploma/internal/handlers/mocks"
+	"github.com/SanExpett/diploma/internal/metrics"
+	"github.com/SanExpett/diploma/internal/oidc"
+	"github.com/SanExpett/diploma/internal/oidc/oidctest"
+	session "github.com/SanExpett/diploma/internal/session/proto"
+	"github.com/SanExpett/diploma/internal/signing"
+)
+
+func TestOIDCHandlers_Callback(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUsersClient := mocks.NewMockUsersClient(ctrl)
+	mockUsersClient.EXPECT().RecordAuditEvent(gomock.Any(), gomock.Any()).
+		Return(&session.RecordAuditEventResponse{}, nil).AnyTimes()
+	mockUsersClient.EXPECT().RegisterLoginDevice(gomock.Any(), gomock.Any()).
+		Return(&session.RegisterLoginDeviceResponse{}, nil).AnyTimes()
+	mockSessionsClient := mocks.NewMockSessionsClient(ctrl)
+
+	var usersClient session.UsersClient = mockUsersClient
+	var sessionsClient session.SessionsClient = mockSessionsClient
+
+	keyManager, err := signing.NewKeyManager(signing.AlgorithmEdDSA, time.Hour, time.Hour)
+	require.NoError(t, err)
+
+	provider, server, err := oidctest.NewServer("client", "secret")
+	require.NoError(t, err)
+	defer server.Close()
+	provider.SetUser(oidctest.User{Subject: "42", Email: "test@test.com", EmailVerified: true, Name: "Test"})
+
+	registry := oidc.NewRegistry([]oidc.ProviderConfig{
+		provider.Config("mock", "http://localhost/api/auth/oidc/mock/callback"),
+	}, server.Client())
+	authPageHandlers := NewAuthPageHandlers(&usersClient, &sessionsClient, keyManager, testCookies,
+		metrics.NewHttpMetrics(), zap.NewNop().Sugar())
+	handler := NewOIDCHandlers(authPageHandlers, registry)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/auth/oidc/{provider}/login", handler.Login)
+	router.HandleFunc("/api/auth/oidc/{provider}/callback", handler.Callback)
+
+	// startLogin проходит редирект на провайдера и возвращает адрес callback и куку со state
+	startLogin := func(t *testing.T) (*url.URL, *http.Cookie) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/auth/oidc/mock/login", nil))
+		require.Equal(t, http.StatusFound, w.Code)
+
+		var stateCookie *http.Cookie
+		for _, cookie := range w.Result().Cookies() {
+			if cookie.Name == oidcStateCookie {
+				stateCookie = cookie
+			}
+		}
+		require.NotNil(t, stateCookie)
+		assert.True(t, stateCookie.HttpOnly)
+
+		client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}}
+		response, err := client.Get(w.Header().Get("Location"))
+		require.NoError(t, err)
+		defer response.Body.Close()
+		require.Equal(t, http.StatusFound, response.StatusCode)
+
+		callbackURL, err := url.Parse(response.Header.Get("Location"))
+		require.NoError(t, err)
+		return callbackURL, stateCookie
+	}
+
+	identityRequest := &session.LoginWithIdentityRequest{Provider: "mock", Subject: "42", Email: "test@test.com",
+		EmailVerified: true, Name: "Test"}
+	user := &session.User{Email: "test@test.com", Uuid: "test-uuid", Version: 1}
+
+	tests := []struct {
+		name           string
+		modify         func(callbackURL *url.URL, stateCookie **http.Cookie)
+		setupMocks     func()
+		expectedStatus int
+		expectedCode   string
+		expectSession  bool
+	}{
+		{
+			name:   "Успешный вход",
+			modify: func(*url.URL, **http.Cookie) {},
+			setupMocks: func() {
+				mockUsersClient.EXPECT().LoginWithIdentity(gomock.Any(), identityRequest).
+					Return(&session.LoginWithIdentityResponse{User: user}, nil)
+				mockSessionsClient.EXPECT().IssueRefreshToken(gomock.Any(),
+					&session.IssueRefreshTokenRequest{Login: "test@test.com"}).
+					Return(&session.IssueRefreshTokenResponse{Family: "family", RefreshToken: "refresh-token"}, nil)
+				mockSessionsClient.EXPECT().Add(gomock.Any(), gomock.Any()).Return(&session.AddResponse{}, nil)
+				mockSessionsClient.EXPECT().ResetLoginAttempts(gomock.Any(), gomock.Any()).
+					Return(&session.ResetLoginAttemptsResponse{}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectSession:  true,
+		},
+		{
+			name: "Подмененный state",
+			modify: func(callbackURL *url.URL, _ **http.Cookie) {
+				query := callbackURL.Query()
+				query.Set("state", "forged")
+				callbackURL.RawQuery = query.Encode()
+			},
+			setupMocks:     func() {},
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   "oidc_login_failed",
+		},
+		{
+			name:           "Нет куки со state",
+			modify:         func(_ *url.URL, stateCookie **http.Cookie) { *stateCookie = nil },
+			setupMocks:     func() {},
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   "oidc_login_failed",
+		},
+		{
+			name: "Неизвестный провайдер",
+			modify: func(callbackURL *url.URL, _ **http.Cookie) {
+				callbackURL.Path = "/api/auth/oidc/other/callback"
+			},
+			setupMocks:     func() {},
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   "unknown_provider",
+		},
+		{
+			name:   "Почта занята другим пользователем",
+			modify: func(*url.URL, **http.Cookie) {},
+			setupMocks: func() {
+				mockUsersClient.EXPECT().LoginWithIdentity(gomock.Any(), identityRequest).
+					Return(nil, status.Error(codes.FailedPrecondition, myerrors.ErrAccountExistsForIdentity.Error()))
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "account_exists",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			callbackURL, stateCookie := startLogin(t)
+			test.modify(callbackURL, &stateCookie)
+			test.setupMocks()
+
+			req := httptest.NewRequest(http.MethodGet, callbackURL.RequestURI(), nil)
+			if stateCookie != nil {
+				req.AddCookie(stateCookie)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			var response ErrorResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, test.expectedStatus, response.Status)
+			assert.Equal(t, test.expectedCode, response.Code)
+
+			hasSession := false
+			for _, cookie := range w.Result().Cookies() {
+				if cookie.Name == "access" && cookie.Value != "" {
+					hasSession = true
+				}
+			}
+			assert.Equal(t, test.expectSession, hasSession)
+		})
+	}
+}
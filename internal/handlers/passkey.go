@@ -0,0 +1,582 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/gorilla/mux"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/SanExpett/diploma/internal/clientip"
+	"github.com/SanExpett/diploma/internal/cookies"
+	"github.com/SanExpett/diploma/internal/domain"
+	myerrors "github.com/SanExpett/diploma/internal/errors"
+	reqid "github.com/SanExpett/diploma/internal/requestId"
+	session "github.com/SanExpett/diploma/internal/session/proto"
+	"github.com/SanExpett/diploma/internal/signing"
+	"github.com/SanExpett/diploma/internal/webauthn"
+)
+
+const (
+	passkeyStateCookie     = "webauthn_state"
+	passkeyStateCookiePath = "/api/auth/passkey"
+	passkeyStateAudience   = "webauthn"
+
+	passkeyCeremonyRegister = "register"
+	passkeyCeremonyLogin    = "login"
+
+	defaultPasskeyName   = "Passkey"
+	maxPasskeyNameLength = 64
+)
+
+// passkeyStateClaims challenge церемонии WebAuthn между выдачей параметров и ответом аутентификатора.
+// Subject логин пользователя, который регистрирует ключ, пусто при входе. Id сохраняется в сервисе сессий
+// и погашается при ответе, поэтому состояние нельзя предъявить повторно
+type passkeyStateClaims struct {
+	jwt.StandardClaims
+	Ceremony  string `json:"ceremony"`
+	Challenge string `json:"challenge"`
+}
+
+func newPasskeyState(ctx context.Context, keyManager *signing.KeyManager, sessionsClient session.SessionsClient,
+	ceremony, login string) (string, string, error) {
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return "", "", err
+	}
+	stateId, err := webauthn.NewChallenge()
+	if err != nil {
+		return "", "", err
+	}
+
+	expiresAt := time.Now().Add(webauthn.CeremonyTimeout)
+	reqSave := session.SavePasskeyChallengeRequest{Id: stateId, ExpiresAt: timestamppb.New(expiresAt)}
+	_, err = sessionsClient.SavePasskeyChallenge(ctx, &reqSave)
+	if err != nil {
+		return "", "", err
+	}
+
+	claims := passkeyStateClaims{
+		StandardClaims: jwt.StandardClaims{
+			Id:        stateId,
+			ExpiresAt: expiresAt.Unix(),
+			Issuer:    "nimbus",
+			Audience:  passkeyStateAudience,
+			Subject:   login,
+		},
+		Ceremony:  ceremony,
+		Challenge: challenge,
+	}
+	stateSigned, err := keyManager.Sign(claims)
+	if err != nil {
+		return "", "", err
+	}
+	return challenge, stateSigned, nil
+}
+
+func parsePasskeyState(keyManager *signing.KeyManager, stateCookie *http.Cookie, cookieErr error,
+	ceremony string) (passkeyStateClaims, error) {
+	if cookieErr != nil {
+		return passkeyStateClaims{}, fmt.Errorf("no state cookie: %w", cookieErr)
+	}
+
+	var claims passkeyStateClaims
+	parsedToken, err := jwt.ParseWithClaims(stateCookie.Value, &claims, keyManager.Keyfunc)
+	if err != nil {
+		return passkeyStateClaims{}, err
+	}
+	if !parsedToken.Valid || !claims.VerifyAudience(passkeyStateAudience, true) || claims.Ceremony != ceremony ||
+		claims.Challenge == "" || claims.Id == "" {
+		return passkeyStateClaims{}, fmt.Errorf("invalid webauthn state")
+	}
+	return claims, nil
+}
+
+// consumePasskeyState гасит challenge состояния. Из одновременных ответов с одним состоянием проходит один,
+// остальные и повторы получают ErrNoSuchPasskeyChallenge
+func consumePasskeyState(ctx context.Context, sessionsClient session.SessionsClient,
+	state passkeyStateClaims) error {
+	reqConsume := session.ConsumePasskeyChallengeRequest{Id: state.Id}
+	_, err := sessionsClient.ConsumePasskeyChallenge(ctx, &reqConsume)
+	if status.Code(err) == codes.NotFound {
+		return myerrors.ErrNoSuchPasskeyChallenge
+	}
+	return err
+}
+
+// setPasskeyStateCookie выставляет состояние церемонии, пустое значение удаляет куку. Strict, потому что
+// церемония целиком идет запросами со страницы нашего сайта
+func setPasskeyStateCookie(w http.ResponseWriter, cookieFactory *cookies.Factory, stateSigned string) {
+	options := cookies.Options{
+		Path:     passkeyStateCookiePath,
+		MaxAge:   webauthn.CeremonyTimeout,
+		SameSite: http.SameSiteStrictMode,
+	}
+	if stateSigned == "" {
+		http.SetCookie(w, cookieFactory.Expired(passkeyStateCookie, options))
+		return
+	}
+	http.SetCookie(w, cookieFactory.New(passkeyStateCookie, stateSigned, options))
+}
+
+func passkeyName(name string) string {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return defaultPasskeyName
+	}
+	if utf8.RuneCountInString(name) > maxPasskeyNameLength {
+		name = string([]rune(name)[:maxPasskeyNameLength])
+	}
+	return name
+}
+
+type PasskeyHandlers struct {
+	authPageHandlers *AuthPageHandlers
+	relyingParty     *webauthn.RelyingParty
+}
+
+// NewPasskeyHandlers создает обработчики регистрации и входа по passkey. relyingParty задает домен
+// и origin фронтенда, к которым привязываются ключи
+func NewPasskeyHandlers(authPageHandlers *AuthPageHandlers, relyingParty *webauthn.RelyingParty) *PasskeyHandlers {
+	return &PasskeyHandlers{
+		authPageHandlers: authPageHandlers,
+		relyingParty:     relyingParty,
+	}
+}
+
+// @Summary      Начало регистрации passkey
+// @Description  Возвращает параметры для navigator.credentials.create. Challenge действует 5 минут
+// @Tags         Auth
+// @Produce      json
+// @Success      200  {object}  domain.PasskeyCreationOptionsResponse  "Параметры регистрации"
+// @Failure      401  {object}  object                                 "Ошибка авторизации"
+// @Failure      500  {object}  object                                 "Внутренняя ошибка сервера"
+// @Router       /auth/passkey/register/begin [post]
+func (passkeyHandlers *PasskeyHandlers) RegisterBegin(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	requestID := ctx.Value(reqid.ReqIDKey)
+	authPageHandlers := passkeyHandlers.authPageHandlers
+
+	userPrincipal, err := getPrincipal(r)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	reqGetUser := session.GetUserRequest{Login: userPrincipal.Login}
+	user, err := (*authPageHandlers.usersClient).GetUser(ctx, &reqGetUser)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	reqGetPasskeys := session.GetPasskeysRequest{Login: userPrincipal.Login}
+	passkeys, err := (*authPageHandlers.usersClient).GetPasskeys(ctx, &reqGetPasskeys)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	challenge, stateSigned, err := newPasskeyState(ctx, authPageHandlers.keyManager,
+		*authPageHandlers.sessionsClient, passkeyCeremonyRegister, userPrincipal.Login)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	exclude := make([]webauthn.CredentialDescriptor, 0, len(passkeys.Passkeys))
+	for _, passkey := range passkeys.Passkeys {
+		exclude = append(exclude, webauthn.CredentialDescriptor{
+			Type:       "public-key",
+			ID:         passkey.Id,
+			Transports: passkey.Transports,
+		})
+	}
+	// user handle uuid пользователя: он не меняется и не раскрывает email
+	userEntity := webauthn.UserEntity{
+		ID:          webauthn.EncodeUserHandle([]byte(user.User.Uuid)),
+		Name:        user.User.Email,
+		DisplayName: user.User.Username,
+	}
+	response := domain.PasskeyCreationOptionsResponse{
+		Status:    http.StatusOK,
+		PublicKey: passkeyHandlers.relyingParty.CreationOptions(userEntity, challenge, exclude),
+	}
+
+	jsonResponse, err := json.Marshal(response)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to marshal response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	setPasskeyStateCookie(w, authPageHandlers.cookies, stateSigned)
+	err = WriteResponse(w, r, authPageHandlers.metrics, jsonResponse, requestID)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+	}
+}
+
+// @Summary      Завершение регистрации passkey
+// @Description  Проверяет ответ аутентификатора и сохраняет новый passkey в профиле. Аттестация
+// @Description  не проверяется
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      domain.PasskeyRegistrationRequest  true  "Ответ navigator.credentials.create"
+// @Success      200      {object}  object                             "Passkey сохранен"
+// @Failure      400      {object}  object                             "Ответ аутентификатора не прошел проверку"
+// @Failure      401      {object}  object                             "Ошибка авторизации"
+// @Failure      500      {object}  object                             "Внутренняя ошибка сервера"
+// @Router       /auth/passkey/register/finish [post]
+func (passkeyHandlers *PasskeyHandlers) RegisterFinish(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	requestID := ctx.Value(reqid.ReqIDKey)
+	authPageHandlers := passkeyHandlers.authPageHandlers
+
+	userPrincipal, err := getPrincipal(r)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	var request domain.PasskeyRegistrationRequest
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to decode: %v\n", requestID, myerrors.ErrFailedDecode)
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	// кука удаляется при любом исходе, повтор того же состояния отклоняет сервис сессий
+	stateCookie, cookieErr := r.Cookie(passkeyStateCookie)
+	setPasskeyStateCookie(w, authPageHandlers.cookies, "")
+
+	state, err := parsePasskeyState(authPageHandlers.keyManager, stateCookie, cookieErr, passkeyCeremonyRegister)
+	if err == nil && state.Subject != userPrincipal.Login {
+		err = fmt.Errorf("registration was started by another user")
+	}
+	if err == nil {
+		err = consumePasskeyState(ctx, *authPageHandlers.sessionsClient, state)
+		if err != nil && !errors.Is(err, myerrors.ErrNoSuchPasskeyChallenge) {
+			err = WriteError(w, r, authPageHandlers.metrics, err)
+			if err != nil {
+				authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+			}
+			return
+		}
+	}
+	var credential webauthn.Credential
+	if err == nil {
+		credential, err = passkeyHandlers.relyingParty.VerifyRegistration(request.Credential, state.Challenge)
+	}
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] passkey registration failed: %v\n", requestID, err)
+		err = WriteError(w, r, authPageHandlers.metrics, myerrors.ErrInvalidPasskey)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	reqAdd := session.AddPasskeyRequest{Login: userPrincipal.Login, Passkey: &session.Passkey{
+		Id:         credential.ID,
+		PublicKey:  credential.PublicKey,
+		SignCount:  credential.SignCount,
+		Transports: credential.Transports,
+		Name:       passkeyName(request.Name),
+	}}
+	_, err = (*authPageHandlers.usersClient).AddPasskey(ctx, &reqAdd)
+	if status.Code(err) == codes.AlreadyExists {
+		err = myerrors.ErrPasskeyAlreadyRegistered
+	}
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	err = WriteSuccess(w, r, authPageHandlers.metrics)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+	}
+}
+
+// @Summary      Начало входа по passkey
+// @Description  Возвращает параметры для navigator.credentials.get. Аккаунт выбирается на аутентификаторе
+// @Tags         Auth
+// @Produce      json
+// @Success      200  {object}  domain.PasskeyRequestOptionsResponse  "Параметры входа"
+// @Failure      500  {object}  object                                "Внутренняя ошибка сервера"
+// @Router       /auth/passkey/login/begin [post]
+func (passkeyHandlers *PasskeyHandlers) LoginBegin(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	requestID := ctx.Value(reqid.ReqIDKey)
+	authPageHandlers := passkeyHandlers.authPageHandlers
+
+	challenge, stateSigned, err := newPasskeyState(ctx, authPageHandlers.keyManager,
+		*authPageHandlers.sessionsClient, passkeyCeremonyLogin, "")
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	response := domain.PasskeyRequestOptionsResponse{
+		Status:    http.StatusOK,
+		PublicKey: passkeyHandlers.relyingParty.RequestOptions(challenge),
+	}
+
+	jsonResponse, err := json.Marshal(response)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to marshal response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	setPasskeyStateCookie(w, authPageHandlers.cookies, stateSigned)
+	err = WriteResponse(w, r, authPageHandlers.metrics, jsonResponse, requestID)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+	}
+}
+
+// @Summary      Вход по passkey
+// @Description  Проверяет подпись аутентификатора и создает сессию. Passkey проверяет пользователя сам,
+// @Description  поэтому второй фактор не запрашивается
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      webauthn.AssertionResponse  true  "Ответ navigator.credentials.get"
+// @Success      200      {object}  object                      "Успешный вход"
+// @Failure      401      {object}  object                      "Passkey не прошел проверку"
+// @Failure      500      {object}  object                      "Внутренняя ошибка сервера"
+// @Router       /auth/passkey/login/finish [post]
+func (passkeyHandlers *PasskeyHandlers) LoginFinish(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	requestID := ctx.Value(reqid.ReqIDKey)
+	authPageHandlers := passkeyHandlers.authPageHandlers
+
+	var request webauthn.AssertionResponse
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to decode: %v\n", requestID, myerrors.ErrFailedDecode)
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	stateCookie, cookieErr := r.Cookie(passkeyStateCookie)
+	setPasskeyStateCookie(w, authPageHandlers.cookies, "")
+
+	user, err := passkeyHandlers.verifyAssertion(r, request, stateCookie, cookieErr)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] passkey login failed: %v\n", requestID, err)
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	authPageHandlers.completeLogin(w, r, user, clientip.FromRequest(r), false)
+}
+
+// verifyAssertion проверяет ответ аутентификатора, сдвигает счетчик подписей и возвращает владельца ключа.
+// Непрошедшая проверка дает ErrPasskeyLoginFailed, ошибки сервиса пользователей возвращаются как есть
+func (passkeyHandlers *PasskeyHandlers) verifyAssertion(r *http.Request, response webauthn.AssertionResponse,
+	stateCookie *http.Cookie, cookieErr error) (*session.User, error) {
+	ctx := r.Context()
+	usersClient := *passkeyHandlers.authPageHandlers.usersClient
+
+	state, err := parsePasskeyState(passkeyHandlers.authPageHandlers.keyManager, stateCookie, cookieErr,
+		passkeyCeremonyLogin)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", err, myerrors.ErrPasskeyLoginFailed)
+	}
+	err = consumePasskeyState(ctx, *passkeyHandlers.authPageHandlers.sessionsClient, state)
+	if errors.Is(err, myerrors.ErrNoSuchPasskeyChallenge) {
+		return nil, fmt.Errorf("%v: %w", err, myerrors.ErrPasskeyLoginFailed)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	reqGetPasskey := session.GetPasskeyRequest{Id: response.ID}
+	passkey, err := usersClient.GetPasskey(ctx, &reqGetPasskey)
+	if status.Code(err) == codes.NotFound {
+		return nil, fmt.Errorf("unknown credential: %w", myerrors.ErrPasskeyLoginFailed)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	signCount, err := passkeyHandlers.relyingParty.VerifyAssertion(response, state.Challenge, webauthn.Credential{
+		ID:         passkey.Passkey.Id,
+		PublicKey:  passkey.Passkey.PublicKey,
+		SignCount:  passkey.Passkey.SignCount,
+		Transports: passkey.Passkey.Transports,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", err, myerrors.ErrPasskeyLoginFailed)
+	}
+
+	reqGetUser := session.GetUserRequest{Login: passkey.Login}
+	user, err := usersClient.GetUser(ctx, &reqGetUser)
+	if err != nil {
+		return nil, err
+	}
+	userHandle, err := response.UserHandle()
+	if err == nil && !bytes.Equal(userHandle, []byte(user.User.Uuid)) {
+		err = fmt.Errorf("user handle does not match passkey owner")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", err, myerrors.ErrPasskeyLoginFailed)
+	}
+
+	// счетчик сдвигается в базе условным обновлением: если ключ успел войти с большим счетчиком,
+	// например его клон, вход отклоняется
+	reqUpdate := session.UpdatePasskeySignCountRequest{Id: passkey.Passkey.Id, SignCount: signCount}
+	_, err = usersClient.UpdatePasskeySignCount(ctx, &reqUpdate)
+	if status.Code(err) == codes.FailedPrecondition {
+		return nil, fmt.Errorf("sign count regression: %w", myerrors.ErrPasskeyLoginFailed)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return user.User, nil
+}
+
+// @Summary      Passkey пользователя
+// @Description  Возвращает passkey, зарегистрированные в профиле
+// @Tags         Users
+// @Produce      json
+// @Success      200  {object}  domain.PasskeysResponse  "Список passkey"
+// @Failure      401  {object}  object                   "Ошибка авторизации"
+// @Failure      500  {object}  object                   "Внутренняя ошибка сервера"
+// @Router       /profile/passkeys [get]
+func (passkeyHandlers *PasskeyHandlers) List(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	requestID := ctx.Value(reqid.ReqIDKey)
+	authPageHandlers := passkeyHandlers.authPageHandlers
+
+	userPrincipal, err := getPrincipal(r)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	reqGetPasskeys := session.GetPasskeysRequest{Login: userPrincipal.Login}
+	passkeys, err := (*authPageHandlers.usersClient).GetPasskeys(ctx, &reqGetPasskeys)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	response := domain.PasskeysResponse{
+		Status:   http.StatusOK,
+		Passkeys: convertPasskeysToRegular(passkeys.Passkeys),
+	}
+
+	jsonResponse, err := json.Marshal(response)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to marshal response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	err = WriteResponse(w, r, authPageHandlers.metrics, jsonResponse, requestID)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+	}
+}
+
+// @Summary      Удаление passkey
+// @Description  Удаляет passkey из профиля, войти по нему больше нельзя
+// @Tags         Users
+// @Produce      json
+// @Param        id   path      string  true  "Идентификатор passkey"
+// @Success      200  {object}  object  "Passkey удален"
+// @Failure      401  {object}  object  "Ошибка авторизации"
+// @Failure      404  {object}  object  "Passkey не найден"
+// @Failure      500  {object}  object  "Внутренняя ошибка сервера"
+// @Router       /profile/passkeys/{id} [delete]
+func (passkeyHandlers *PasskeyHandlers) Remove(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	requestID := ctx.Value(reqid.ReqIDKey)
+	authPageHandlers := passkeyHandlers.authPageHandlers
+
+	userPrincipal, err := getPrincipal(r)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	reqRemove := session.RemovePasskeyRequest{Login: userPrincipal.Login, Id: mux.Vars(r)["id"]}
+	_, err = (*authPageHandlers.usersClient).RemovePasskey(ctx, &reqRemove)
+	if status.Code(err) == codes.NotFound {
+		err = myerrors.ErrNoSuchPasskey
+	}
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	err = WriteSuccess(w, r, authPageHandlers.metrics)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+	}
+}
@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/SanExpett/diploma/internal/domain"
+	myerrors "github.com/SanExpett/diploma/internal/errors"
+	"github.com/SanExpett/diploma/internal/handlers/mocks"
+	"github.com/SanExpett/diploma/internal/metrics"
+	"github.com/SanExpett/diploma/internal/principal"
+	session "github.com/SanExpett/diploma/internal/session/proto"
+	"github.com/SanExpett/diploma/internal/signing"
+	"github.com/SanExpett/diploma/internal/webauthn"
+	"github.com/SanExpett/diploma/internal/webauthn/webauthntest"
+)
+
+func TestPasskeyHandlers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUsersClient := mocks.NewMockUsersClient(ctrl)
+	mockUsersClient.EXPECT().RecordAuditEvent(gomock.Any(), gomock.Any()).
+		Return(&session.RecordAuditEventResponse{}, nil).AnyTimes()
+	mockUsersClient.EXPECT().RegisterLoginDevice(gomock.Any(), gomock.Any()).
+		Return(&session.RegisterLoginDeviceResponse{}, nil).AnyTimes()
+	mockSessionsClient := mocks.NewMockSessionsClient(ctrl)
+	mockSessionsClient.EXPECT().SavePasskeyChallenge(gomock.Any(), gomock.Any()).
+		Return(&session.SavePasskeyChallengeResponse{}, nil).AnyTimes()
+	consumeChallenge := func() {
+		mockSessionsClient.EXPECT().ConsumePasskeyChallenge(gomock.Any(), gomock.Any()).
+			Return(&session.ConsumePasskeyChallengeResponse{}, nil)
+	}
+
+	var usersClient session.UsersClient = mockUsersClient
+	var sessionsClient session.SessionsClient = mockSessionsClient
+
+	keyManager, err := signing.NewKeyManager(signing.AlgorithmEdDSA, time.Hour, time.Hour)
+	require.NoError(t, err)
+
+	handler := NewPasskeyHandlers(NewAuthPageHandlers(&usersClient, &sessionsClient, keyManager, testCookies,
+		metrics.NewHttpMetrics(), zap.NewNop().Sugar()), webauthn.NewRelyingParty(webauthn.Config{
+		RPID:   "localhost",
+		RPName: "Nimbus",
+		Origin: "http://localhost:8080",
+	}))
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/auth/passkey/register/begin", handler.RegisterBegin)
+	router.HandleFunc("/api/auth/passkey/register/finish", handler.RegisterFinish)
+	router.HandleFunc("/api/auth/passkey/login/begin", handler.LoginBegin)
+	router.HandleFunc("/api/auth/passkey/login/finish", handler.LoginFinish)
+
+	user := &session.User{Email: "test@test.com", Uuid: "test-uuid", Username: "tester", Version: 1}
+	authenticator := webauthntest.New("http://localhost:8080")
+
+	withPrincipal := func(req *http.Request) *http.Request {
+		return req.WithContext(principal.WithPrincipal(req.Context(), principal.Principal{
+			UserUuid: user.Uuid,
+			Login:    user.Email,
+		}))
+	}
+
+	mockUsersClient.EXPECT().GetUser(gomock.Any(), &session.GetUserRequest{Login: user.Email}).
+		Return(&session.GetUserResponse{User: user}, nil)
+	mockUsersClient.EXPECT().GetPasskeys(gomock.Any(), &session.GetPasskeysRequest{Login: user.Email}).
+		Return(&session.GetPasskeysResponse{}, nil)
+
+	req := withPrincipal(httptest.NewRequest(http.MethodPost, "/api/auth/passkey/register/begin", nil))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var creationOptions domain.PasskeyCreationOptionsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &creationOptions))
+	require.Equal(t, http.StatusOK, creationOptions.Status)
+	assert.Equal(t, webauthn.EncodeUserHandle([]byte(user.Uuid)), creationOptions.PublicKey.User.ID)
+	require.Len(t, w.Result().Cookies(), 1)
+	registerState := w.Result().Cookies()[0]
+
+	attestation, err := authenticator.Register(creationOptions.PublicKey)
+	require.NoError(t, err)
+
+	var stored *session.Passkey
+	consumeChallenge()
+	mockUsersClient.EXPECT().AddPasskey(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ any, req *session.AddPasskeyRequest, _ ...any) (*session.AddPasskeyResponse, error) {
+			stored = req.Passkey
+			return &session.AddPasskeyResponse{}, nil
+		})
+
+	body, _ := json.Marshal(domain.PasskeyRegistrationRequest{Name: "Ноутбук", Credential: attestation})
+	req = withPrincipal(httptest.NewRequest(http.MethodPost, "/api/auth/passkey/register/finish",
+		bytes.NewReader(body)))
+	req.AddCookie(registerState)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var registerResponse ErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &registerResponse))
+	require.Equal(t, http.StatusOK, registerResponse.Status)
+	require.NotNil(t, stored)
+	assert.Equal(t, attestation.ID, stored.Id)
+	assert.Equal(t, "Ноутбук", stored.Name)
+	assert.Equal(t, []string{"internal"}, stored.Transports)
+
+	req = httptest.NewRequest(http.MethodPost, "/api/auth/passkey/login/begin", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var requestOptions domain.PasskeyRequestOptionsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &requestOptions))
+	require.Equal(t, http.StatusOK, requestOptions.Status)
+	require.Len(t, w.Result().Cookies(), 1)
+	loginState := w.Result().Cookies()[0]
+
+	assertion, err := authenticator.Login(requestOptions.PublicKey)
+	require.NoError(t, err)
+
+	getPasskeyRequest := &session.GetPasskeyRequest{Id: assertion.ID}
+
+	tests := []struct {
+		name           string
+		cookie         *http.Cookie
+		setupMocks     func()
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name:           "Нет куки с challenge",
+			setupMocks:     func() {},
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   "passkey_login_failed",
+		},
+		{
+			name:           "Кука от регистрации",
+			cookie:         &http.Cookie{Name: passkeyStateCookie, Value: registerState.Value},
+			setupMocks:     func() {},
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   "passkey_login_failed",
+		},
+		{
+			name:   "Challenge уже использован",
+			cookie: loginState,
+			setupMocks: func() {
+				mockSessionsClient.EXPECT().ConsumePasskeyChallenge(gomock.Any(), gomock.Any()).
+					Return(nil, status.Error(codes.NotFound, myerrors.ErrNoSuchPasskeyChallenge.Error()))
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   "passkey_login_failed",
+		},
+		{
+			name:   "Сервис сессий недоступен",
+			cookie: loginState,
+			setupMocks: func() {
+				mockSessionsClient.EXPECT().ConsumePasskeyChallenge(gomock.Any(), gomock.Any()).
+					Return(nil, status.Error(codes.Unavailable, "connection refused"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:   "Ключ удален из профиля",
+			cookie: loginState,
+			setupMocks: func() {
+				consumeChallenge()
+				mockUsersClient.EXPECT().GetPasskey(gomock.Any(), getPasskeyRequest).
+					Return(nil, status.Error(codes.NotFound, myerrors.ErrNoSuchPasskey.Error()))
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   "passkey_login_failed",
+		},
+		{
+			name:   "User handle другого пользователя",
+			cookie: loginState,
+			setupMocks: func() {
+				consumeChallenge()
+				mockUsersClient.EXPECT().GetPasskey(gomock.Any(), getPasskeyRequest).
+					Return(&session.GetPasskeyResponse{Login: "other@test.com", Passkey: stored}, nil)
+				mockUsersClient.EXPECT().GetUser(gomock.Any(), &session.GetUserRequest{Login: "other@test.com"}).
+					Return(&session.GetUserResponse{User: &session.User{Email: "other@test.com", Uuid: "other"}}, nil)
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   "passkey_login_failed",
+		},
+		{
+			name:   "Успешный вход",
+			cookie: loginState,
+			setupMocks: func() {
+				consumeChallenge()
+				mockUsersClient.EXPECT().GetPasskey(gomock.Any(), getPasskeyRequest).
+					Return(&session.GetPasskeyResponse{Login: user.Email, Passkey: stored}, nil)
+				mockUsersClient.EXPECT().GetUser(gomock.Any(), &session.GetUserRequest{Login: user.Email}).
+					Return(&session.GetUserResponse{User: user}, nil)
+				mockUsersClient.EXPECT().UpdatePasskeySignCount(gomock.Any(),
+					&session.UpdatePasskeySignCountRequest{Id: stored.Id, SignCount: 1}).
+					Return(&session.UpdatePasskeySignCountResponse{}, nil)
+				mockSessionsClient.EXPECT().IssueRefreshToken(gomock.Any(),
+					&session.IssueRefreshTokenRequest{Login: user.Email}).
+					Return(&session.IssueRefreshTokenResponse{Family: "family", RefreshToken: "refresh-token"}, nil)
+				mockSessionsClient.EXPECT().Add(gomock.Any(), gomock.Any()).Return(&session.AddResponse{}, nil)
+				mockSessionsClient.EXPECT().ResetLoginAttempts(gomock.Any(), gomock.Any()).
+					Return(&session.ResetLoginAttemptsResponse{}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:   "Повтор ответа после одновременного входа",
+			cookie: loginState,
+			setupMocks: func() {
+				consumeChallenge()
+				mockUsersClient.EXPECT().GetPasskey(gomock.Any(), getPasskeyRequest).
+					Return(&session.GetPasskeyResponse{Login: user.Email, Passkey: stored}, nil)
+				mockUsersClient.EXPECT().GetUser(gomock.Any(), &session.GetUserRequest{Login: user.Email}).
+					Return(&session.GetUserResponse{User: user}, nil)
+				mockUsersClient.EXPECT().UpdatePasskeySignCount(gomock.Any(), gomock.Any()).
+					Return(nil, status.Error(codes.FailedPrecondition,
+						myerrors.ErrPasskeySignCountRegression.Error()))
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   "passkey_login_failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMocks()
+
+			body, _ := json.Marshal(assertion)
+			req := httptest.NewRequest(http.MethodPost, "/api/auth/passkey/login/finish", bytes.NewReader(body))
+			if tt.cookie != nil {
+				req.AddCookie(tt.cookie)
+			}
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			var response ErrorResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tt.expectedStatus, response.Status)
+			assert.Equal(t, tt.expectedCode, response.Code)
+		})
+	}
+
+	t.Run("Сохраненный счетчик не меньше присланного", func(t *testing.T) {
+		usedPasskey := &session.Passkey{Id: stored.Id, PublicKey: stored.PublicKey, SignCount: 1}
+		consumeChallenge()
+		mockUsersClient.EXPECT().GetPasskey(gomock.Any(), getPasskeyRequest).
+			Return(&session.GetPasskeyResponse{Login: user.Email, Passkey: usedPasskey}, nil)
+
+		body, _ := json.Marshal(assertion)
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/passkey/login/finish", bytes.NewReader(body))
+		req.AddCookie(loginState)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		var response ErrorResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, http.StatusUnauthorized, response.Status)
+		assert.Equal(t, "passkey_login_failed", response.Code)
+	})
+}
@@ -44,48 +44,44 @@ func (storage *storage) CreateSubscription(sub domain.Subscription) error {
 	return nil
 }
 
-func (filmsPageHandlers *FilmsPageHandlers) AddSubscriptions(w http.ResponseWriter, r *http.Request) {
-	pool, err := pgxpool.New(context.Background(), fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		"postgres",
-		"5432",
-		"postgres",
-		"postgres",
-		"nimbus",
-	))
-	if err != nil {
-		log.Fatal(err)
-	}
+// AddSubscriptions добавляет в базу postgresDSN тарифы подписки
+func (filmsPageHandlers *FilmsPageHandlers) AddSubscriptions(postgresDSN string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		pool, err := pgxpool.New(context.Background(), postgresDSN)
+		if err != nil {
+			log.Fatal(err)
+		}
 
-	s, err := newStorage(pool)
-	if err != nil {
-		log.Fatalf("failed to create storage: %v\n", err)
+		s, err := newStorage(pool)
+		if err != nil {
+			log.Fatalf("failed to create storage: %v\n", err)
 
-		return
-	}
+			return
+		}
 
-	subs := []domain.Subscription{
-		{
-			Title:       "Ежемесячный платеж",
-			Description: "Наслаждайтесь обширной библиотекой фильмов и сериалов с разнообразным контентом.",
-			Amount:      299,
-			Duration:    1,
-		},
-		{
-			Title:       "Ежегодный платеж",
-			Description: "Покупка на 12 месяцев без продления. Выгоднее на 30%: 208₽ в месяц вместо 299₽ в месяц за ежемесячную подписку",
-			Amount:      2490,
-			Duration:    12,
-		},
-	}
+		subs := []domain.Subscription{
+			{
+				Title:       "Ежемесячный платеж",
+				Description: "Наслаждайтесь обширной библиотекой фильмов и сериалов с разнообразным контентом.",
+				Amount:      299,
+				Duration:    1,
+			},
+			{
+				Title:       "Ежегодный платеж",
+				Description: "Покупка на 12 месяцев без продления. Выгоднее на 30%: 208₽ в месяц вместо 299₽ в месяц за ежемесячную подписку",
+				Amount:      2490,
+				Duration:    12,
+			},
+		}
 
-	for _, sub := range subs {
-		err = s.CreateSubscription(sub)
-		if err != nil {
-			log.Fatalf("failed to create subscription: %v \n", err)
-			return
+		for _, sub := range subs {
+			err = s.CreateSubscription(sub)
+			if err != nil {
+				log.Fatalf("failed to create subscription: %v \n", err)
+				return
+			}
 		}
-	}
 
-	return
+		return
+	}
 }
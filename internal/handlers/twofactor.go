@@ -0,0 +1,380 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/SanExpett/diploma/internal/clientip"
+	"github.com/SanExpett/diploma/internal/domain"
+	myerrors "github.com/SanExpett/diploma/internal/errors"
+	reqid "github.com/SanExpett/diploma/internal/requestId"
+	session "github.com/SanExpett/diploma/internal/session/proto"
+	"github.com/SanExpett/diploma/internal/signing"
+)
+
+const (
+	twoFactorChallengeExpirationTime = 5 * time.Minute
+	twoFactorChallengeAudience       = "2fa"
+)
+
+// twoFactorChallengeClaims токен, который выдается после проверки пароля и обменивается на сессию
+// вместе с кодом второго фактора. В нем нет claims access токена, поэтому IsTokenValid его не примет.
+// RememberMe переносит выбор пользователя с первого шага входа
+type twoFactorChallengeClaims struct {
+	jwt.StandardClaims
+	RememberMe bool `json:"remember_me,omitempty"`
+}
+
+func generateTwoFactorChallenge(keyManager *signing.KeyManager, login string, rememberMe bool) (string, error) {
+	claims := twoFactorChallengeClaims{
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: time.Now().Add(twoFactorChallengeExpirationTime).Unix(),
+			Issuer:    "nimbus",
+			Audience:  twoFactorChallengeAudience,
+			Subject:   login,
+		},
+		RememberMe: rememberMe,
+	}
+	return keyManager.Sign(claims)
+}
+
+// parseTwoFactorChallenge проверяет подпись и срок действия токена, логин пользователя в Subject
+func parseTwoFactorChallenge(keyManager *signing.KeyManager, challenge string) (twoFactorChallengeClaims, error) {
+	var claims twoFactorChallengeClaims
+	parsedToken, err := jwt.ParseWithClaims(challenge, &claims, keyManager.Keyfunc)
+	if err != nil {
+		return twoFactorChallengeClaims{}, fmt.Errorf("%v: %w", err, myerrors.ErrInvalidTwoFactorChallenge)
+	}
+	if !parsedToken.Valid || !claims.VerifyAudience(twoFactorChallengeAudience, true) || claims.Subject == "" {
+		return twoFactorChallengeClaims{}, myerrors.ErrInvalidTwoFactorChallenge
+	}
+	return claims, nil
+}
+
+// twoFactorError восстанавливает ошибки второго фактора, которые сервис пользователей передает кодами gRPC
+func twoFactorError(err error) error {
+	switch status.Code(err) {
+	case codes.InvalidArgument:
+		return myerrors.ErrInvalidTOTPCode
+	case codes.FailedPrecondition:
+		return myerrors.ErrTOTPNotEnabled
+	case codes.AlreadyExists:
+		return myerrors.ErrTOTPAlreadyEnabled
+	}
+	return err
+}
+
+func (authPageHandlers *AuthPageHandlers) writeTwoFactorChallenge(w http.ResponseWriter, r *http.Request,
+	login string, rememberMe bool) {
+	requestID := r.Context().Value(reqid.ReqIDKey)
+
+	challenge, err := generateTwoFactorChallenge(authPageHandlers.keyManager, login, rememberMe)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	response := domain.TwoFactorChallengeResponse{
+		Status:            http.StatusOK,
+		TwoFactorRequired: true,
+		Challenge:         challenge,
+	}
+
+	jsonResponse, err := json.Marshal(response)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to marshal response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	err = WriteResponse(w, r, authPageHandlers.metrics, jsonResponse, requestID)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+	}
+
+	authPageHandlers.logger.Info(fmt.Sprintf("[reqid=%s] password accepted, waiting for second factor",
+		requestID))
+}
+
+// @Summary      Второй шаг входа
+// @Description  Обменивает токен, выданный после проверки пароля, и код второго фактора на сессию.
+// @Description  Вместо кода из приложения можно передать код восстановления
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      domain.TwoFactorLoginRequest  true  "Токен после проверки пароля и код"
+// @Success      200      {object}  object                        "Успешный вход"
+// @Failure      400      {object}  object                        "Неверный код"
+// @Failure      401      {object}  object                        "Недействительный или истекший токен"
+// @Failure      429      {object}  object                        "Слишком много неудачных попыток"
+// @Failure      500      {object}  object                        "Внутренняя ошибка сервера"
+// @Router       /auth/login/2fa [post]
+func (authPageHandlers *AuthPageHandlers) LoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	requestID := ctx.Value(reqid.ReqIDKey)
+
+	var request domain.TwoFactorLoginRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to decode: %v\n", requestID, myerrors.ErrFailedDecode)
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	challenge, err := parseTwoFactorChallenge(authPageHandlers.keyManager, request.Challenge)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] invalid two-factor challenge: %v\n", requestID, err)
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+	login := challenge.Subject
+
+	ip := clientip.FromRequest(r)
+	// попытка резервируется до проверки, поэтому параллельные попытки не проходят мимо ограничений
+	reqReserve := session.ReserveLoginAttemptRequest{Login: login, Ip: ip}
+	throttle, err := (*authPageHandlers.sessionsClient).ReserveLoginAttempt(ctx, &reqReserve)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+	if throttle.RetryAfter > 0 {
+		authPageHandlers.writeLoginThrottled(w, r, throttle.RetryAfter, throttle.Locked)
+		return
+	}
+
+	reqVerify := session.VerifyTOTPRequest{Login: login, Code: request.Code}
+	_, err = (*authPageHandlers.usersClient).VerifyTOTP(ctx, &reqVerify)
+	if status.Code(err) == codes.InvalidArgument {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to login: %v\n", requestID, myerrors.ErrInvalidTOTPCode)
+		// неверные коды считаются вместе с неверными паролями, иначе код можно было бы перебрать
+		reqFailure := session.RegisterLoginFailureRequest{Login: login, Ip: ip}
+		failure, failureErr := (*authPageHandlers.sessionsClient).RegisterLoginFailure(ctx, &reqFailure)
+		if failureErr != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to register login failure: %v\n", requestID,
+				failureErr)
+		} else if failure.Locked {
+			authPageHandlers.writeLoginThrottled(w, r, failure.RetryAfter, failure.Locked)
+			return
+		}
+
+		err = WriteError(w, r, authPageHandlers.metrics, myerrors.ErrInvalidTOTPCode)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+	authPageHandlers.releaseLoginAttempt(ctx, login, ip)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, twoFactorError(err))
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	reqGetUser := session.GetUserRequest{Login: login}
+	user, err := (*authPageHandlers.usersClient).GetUser(ctx, &reqGetUser)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	authPageHandlers.completeLogin(w, r, user.User, ip, challenge.RememberMe)
+}
+
+// @Summary      Подключение второго фактора
+// @Description  Выдает секрет и otpauth:// ссылку для QR кода. Второй фактор включается после подтверждения
+// @Tags         Auth
+// @Produce      json
+// @Success      200  {object}  domain.TOTPEnrollResponse  "Секрет и ссылка для приложения"
+// @Failure      400  {object}  object                     "Второй фактор уже включен"
+// @Failure      401  {object}  object                     "Не авторизован"
+// @Failure      500  {object}  object                     "Внутренняя ошибка сервера"
+// @Router       /auth/2fa/enroll [post]
+func (authPageHandlers *AuthPageHandlers) EnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	requestID := ctx.Value(reqid.ReqIDKey)
+
+	userPrincipal, err := getPrincipal(r)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	reqEnroll := session.EnrollTOTPRequest{Login: userPrincipal.Login}
+	enrollment, err := (*authPageHandlers.usersClient).EnrollTOTP(ctx, &reqEnroll)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, twoFactorError(err))
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	response := domain.TOTPEnrollResponse{
+		Status:          http.StatusOK,
+		Secret:          enrollment.Secret,
+		ProvisioningUri: enrollment.ProvisioningUri,
+	}
+
+	jsonResponse, err := json.Marshal(response)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to marshal response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	err = WriteResponse(w, r, authPageHandlers.metrics, jsonResponse, requestID)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+	}
+}
+
+// @Summary      Подтверждение второго фактора
+// @Description  Включает второй фактор по коду из приложения и возвращает коды восстановления.
+// @Description  Коды восстановления показываются только один раз
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      domain.TOTPCodeRequest      true  "Код из приложения"
+// @Success      200      {object}  domain.TOTPConfirmResponse  "Коды восстановления"
+// @Failure      400      {object}  object                      "Неверный код"
+// @Failure      401      {object}  object                      "Не авторизован"
+// @Failure      500      {object}  object                      "Внутренняя ошибка сервера"
+// @Router       /auth/2fa/confirm [post]
+func (authPageHandlers *AuthPageHandlers) ConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	requestID := ctx.Value(reqid.ReqIDKey)
+
+	userPrincipal, err := getPrincipal(r)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	var request domain.TOTPCodeRequest
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to decode: %v\n", requestID, myerrors.ErrFailedDecode)
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	reqConfirm := session.ConfirmTOTPRequest{Login: userPrincipal.Login, Code: request.Code}
+	confirmation, err := (*authPageHandlers.usersClient).ConfirmTOTP(ctx, &reqConfirm)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, twoFactorError(err))
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	response := domain.TOTPConfirmResponse{
+		Status:        http.StatusOK,
+		RecoveryCodes: confirmation.RecoveryCodes,
+	}
+
+	jsonResponse, err := json.Marshal(response)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to marshal response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	err = WriteResponse(w, r, authPageHandlers.metrics, jsonResponse, requestID)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+	}
+
+	authPageHandlers.logger.Info(fmt.Sprintf("[reqid=%s] two-factor authentication enabled", requestID))
+}
+
+// @Summary      Отключение второго фактора
+// @Description  Отключает второй фактор по действующему коду из приложения или коду восстановления
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      domain.TOTPCodeRequest  true  "Код из приложения или код восстановления"
+// @Success      200      {object}  object                  "Второй фактор отключен"
+// @Failure      400      {object}  object                  "Неверный код или второй фактор не включен"
+// @Failure      401      {object}  object                  "Не авторизован"
+// @Failure      500      {object}  object                  "Внутренняя ошибка сервера"
+// @Router       /auth/2fa/disable [post]
+func (authPageHandlers *AuthPageHandlers) DisableTOTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	requestID := ctx.Value(reqid.ReqIDKey)
+
+	userPrincipal, err := getPrincipal(r)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	var request domain.TOTPCodeRequest
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to decode: %v\n", requestID, myerrors.ErrFailedDecode)
+		err = WriteError(w, r, authPageHandlers.metrics, err)
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	reqDisable := session.DisableTOTPRequest{Login: userPrincipal.Login, Code: request.Code}
+	_, err = (*authPageHandlers.usersClient).DisableTOTP(ctx, &reqDisable)
+	if err != nil {
+		err = WriteError(w, r, authPageHandlers.metrics, twoFactorError(err))
+		if err != nil {
+			authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+		}
+		return
+	}
+
+	err = WriteSuccess(w, r, authPageHandlers.metrics)
+	if err != nil {
+		authPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestID, err)
+	}
+
+	authPageHandlers.logger.Info(fmt.Sprintf("[reqid=%s] two-factor authentication disabled", requestID))
+}
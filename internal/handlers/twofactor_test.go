@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/SanExpett/diploma/internal/domain"
+	myerrors "github.com/SanExpett/diploma/internal/errors"
+	"github.com/SanExpett/diploma/internal/handlers/mocks"
+	"github.com/SanExpett/diploma/internal/metrics"
+	session "github.com/SanExpett/diploma/internal/session/proto"
+	"github.com/SanExpett/diploma/internal/signing"
+)
+
+func TestAuthPageHandlers_LoginTwoFactor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUsersClient := mocks.NewMockUsersClient(ctrl)
+	mockUsersClient.EXPECT().RecordAuditEvent(gomock.Any(), gomock.Any()).
+		Return(&session.RecordAuditEventResponse{}, nil).AnyTimes()
+	mockUsersClient.EXPECT().RegisterLoginDevice(gomock.Any(), gomock.Any()).
+		Return(&session.RegisterLoginDeviceResponse{}, nil).AnyTimes()
+	mockSessionsClient := mocks.NewMockSessionsClient(ctrl)
+
+	var usersClient session.UsersClient = mockUsersClient
+	var sessionsClient session.SessionsClient = mockSessionsClient
+
+	keyManager, err := signing.NewKeyManager(signing.AlgorithmEdDSA, time.Hour, time.Hour)
+	require.NoError(t, err)
+
+	handler := NewAuthPageHandlers(&usersClient, &sessionsClient, keyManager, testCookies, metrics.NewHttpMetrics(),
+		zap.NewNop().Sugar())
+
+	reserveRequest := &session.ReserveLoginAttemptRequest{Login: "test@test.com", Ip: "192.0.2.1"}
+	releaseRequest := &session.ReleaseLoginAttemptRequest{Login: "test@test.com", Ip: "192.0.2.1"}
+	user := &session.User{Email: "test@test.com", Uuid: "test-uuid", Version: 1, TotpEnabled: true}
+
+	mockSessionsClient.EXPECT().ReserveLoginAttempt(gomock.Any(), reserveRequest).
+		Return(&session.ReserveLoginAttemptResponse{}, nil)
+	mockUsersClient.EXPECT().HasUser(gomock.Any(), gomock.Any()).Return(&session.HasUserResponse{}, nil)
+	mockSessionsClient.EXPECT().ReleaseLoginAttempt(gomock.Any(), releaseRequest).
+		Return(&session.ReleaseLoginAttemptResponse{}, nil)
+	mockUsersClient.EXPECT().GetUser(gomock.Any(), &session.GetUserRequest{Login: "test@test.com"}).
+		Return(&session.GetUserResponse{User: user}, nil)
+
+	body, _ := json.Marshal(domain.UserSignUp{Email: "test@test.com", Password: "password123"})
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/login", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/auth/login", handler.Login)
+	router.ServeHTTP(w, req)
+
+	var challengeResponse domain.TwoFactorChallengeResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &challengeResponse))
+	require.True(t, challengeResponse.TwoFactorRequired)
+	require.NotEmpty(t, challengeResponse.Challenge)
+	assert.Empty(t, w.Result().Cookies(), "сессия создается только после второго фактора")
+
+	_, err = IsTokenValid(&http.Cookie{Value: challengeResponse.Challenge}, keyManager)
+	assert.Error(t, err, "токен второго шага не принимается как access токен")
+
+	tests := []struct {
+		name           string
+		challenge      string
+		setupMocks     func()
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name:      "Верный код",
+			challenge: challengeResponse.Challenge,
+			setupMocks: func() {
+				mockSessionsClient.EXPECT().ReserveLoginAttempt(gomock.Any(), reserveRequest).
+					Return(&session.ReserveLoginAttemptResponse{}, nil)
+				mockUsersClient.EXPECT().VerifyTOTP(gomock.Any(),
+					&session.VerifyTOTPRequest{Login: "test@test.com", Code: "123456"}).
+					Return(&session.VerifyTOTPResponse{}, nil)
+				mockSessionsClient.EXPECT().ReleaseLoginAttempt(gomock.Any(), releaseRequest).
+					Return(&session.ReleaseLoginAttemptResponse{}, nil)
+				mockUsersClient.EXPECT().GetUser(gomock.Any(), &session.GetUserRequest{Login: "test@test.com"}).
+					Return(&session.GetUserResponse{User: user}, nil)
+				mockSessionsClient.EXPECT().IssueRefreshToken(gomock.Any(),
+					&session.IssueRefreshTokenRequest{Login: "test@test.com"}).
+					Return(&session.IssueRefreshTokenResponse{Family: "family", RefreshToken: "refresh-token"}, nil)
+				mockSessionsClient.EXPECT().Add(gomock.Any(), gomock.Any()).Return(&session.AddResponse{}, nil)
+				mockSessionsClient.EXPECT().ResetLoginAttempts(gomock.Any(),
+					&session.ResetLoginAttemptsRequest{Login: "test@test.com"}).
+					Return(&session.ResetLoginAttemptsResponse{}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:      "Неверный код",
+			challenge: challengeResponse.Challenge,
+			setupMocks: func() {
+				mockSessionsClient.EXPECT().ReserveLoginAttempt(gomock.Any(), reserveRequest).
+					Return(&session.ReserveLoginAttemptResponse{}, nil)
+				mockUsersClient.EXPECT().VerifyTOTP(gomock.Any(), gomock.Any()).
+					Return(nil, status.Error(codes.InvalidArgument, myerrors.ErrInvalidTOTPCode.Error()))
+				mockSessionsClient.EXPECT().RegisterLoginFailure(gomock.Any(),
+					&session.RegisterLoginFailureRequest{Login: "test@test.com", Ip: "192.0.2.1"}).
+					Return(&session.RegisterLoginFailureResponse{RetryAfter: 1}, nil)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "invalid_totp_code",
+		},
+		{
+			name:           "Поддельный токен второго шага",
+			challenge:      "not-a-token",
+			setupMocks:     func() {},
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   "invalid_2fa_challenge",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMocks()
+
+			body, _ := json.Marshal(domain.TwoFactorLoginRequest{Challenge: tt.challenge, Code: "123456"})
+			req := httptest.NewRequest(http.MethodPost, "/api/auth/login/2fa", bytes.NewReader(body))
+			w := httptest.NewRecorder()
+
+			handler.LoginTwoFactor(w, req)
+
+			var response ErrorResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tt.expectedStatus, response.Status)
+			assert.Equal(t, tt.expectedCode, response.Code)
+		})
+	}
+}
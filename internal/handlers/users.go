@@ -3,31 +3,37 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
-	"os"
 
 	"github.com/gorilla/mux"
 	"github.com/mailru/easyjson"
 	"go.uber.org/zap"
 
+	"github.com/SanExpett/diploma/internal/cookies"
 	"github.com/SanExpett/diploma/internal/domain"
 	myerrors "github.com/SanExpett/diploma/internal/errors"
 	"github.com/SanExpett/diploma/internal/metrics"
 	reqid "github.com/SanExpett/diploma/internal/requestId"
 	session "github.com/SanExpett/diploma/internal/session/proto"
+	"github.com/SanExpett/diploma/internal/signing"
 )
 
 type UserPageHandlers struct {
 	usersClient    *session.UsersClient
 	sessionsClient *session.SessionsClient
+	keyManager     *signing.KeyManager
+	cookies        *cookies.Factory
 	metrics        *metrics.HttpMetrics
 	logger         *zap.SugaredLogger
 }
 
 func NewUserPageHandlers(usersClient *session.UsersClient, sessionsClient *session.SessionsClient,
-	metrics *metrics.HttpMetrics, logger *zap.SugaredLogger) *UserPageHandlers {
+	keyManager *signing.KeyManager, cookieFactory *cookies.Factory, metrics *metrics.HttpMetrics,
+	logger *zap.SugaredLogger) *UserPageHandlers {
 	return &UserPageHandlers{
 		usersClient:    usersClient,
 		sessionsClient: sessionsClient,
+		keyManager:     keyManager,
+		cookies:        cookieFactory,
 		metrics:        metrics,
 		logger:         logger,
 	}
@@ -119,16 +125,10 @@ func (UserPageHandlers *UserPageHandlers) ProfileEditByUuid(w http.ResponseWrite
 	ctx := r.Context()
 	requestId := ctx.Value(reqid.ReqIDKey)
 
-	userToken, err := r.Cookie("access")
-	if err != nil {
-		err = WriteError(w, r, UserPageHandlers.metrics, myerrors.ErrNoActiveSession)
-		if err != nil {
-			UserPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestId, err)
-		}
-		return
+	userPrincipal, err := getPrincipal(r)
+	if err == nil && userPrincipal.UserUuid != mux.Vars(r)["uuid"] {
+		err = myerrors.ErrNotAuthorised
 	}
-
-	_, err = IsTokenValid(userToken, os.Getenv("SECRETKEY"))
 	if err != nil {
 		err = WriteError(w, r, UserPageHandlers.metrics, err)
 		if err != nil {
@@ -137,7 +137,7 @@ func (UserPageHandlers *UserPageHandlers) ProfileEditByUuid(w http.ResponseWrite
 		return
 	}
 
-	uuid := mux.Vars(r)["uuid"]
+	uuid := userPrincipal.UserUuid
 	req := session.GetUserDataByUuidRequest{Uuid: uuid}
 	getUserByDataRes, err := (*UserPageHandlers.usersClient).GetUserDataByUuid(ctx, &req)
 	if err != nil {
@@ -172,6 +172,14 @@ func (UserPageHandlers *UserPageHandlers) ProfileEditByUuid(w http.ResponseWrite
 		}
 		currUserProto = changePassRes.User
 
+		// после смены пароля остальные устройства должны войти заново
+		reqRevoke := session.RevokeOtherSessionsRequest{Login: currUserProto.Email,
+			CurrentToken: userPrincipal.SessionId}
+		_, err = (*UserPageHandlers.sessionsClient).RevokeOtherSessions(ctx, &reqRevoke)
+		if err != nil {
+			UserPageHandlers.logger.Errorf("[reqid=%s] failed to revoke other sessions: %v\n", requestId, err)
+		}
+
 	case "chUsername":
 		err = ValidateUsername(newData)
 		if err != nil {
@@ -217,9 +225,9 @@ func (UserPageHandlers *UserPageHandlers) ProfileEditByUuid(w http.ResponseWrite
 		currUserProto = changeAvatarRes.User
 	}
 
-	version := currUserProto.Version + 1
-
-	tokenSigned, err := GenerateTokens(currUserProto.Email, false, version)
+	// после изменения профиля старые access токены этой сессии перестают приниматься
+	reqUpdate := session.UpdateRequest{Login: currUserProto.Email, Token: userPrincipal.SessionId}
+	_, err = (*UserPageHandlers.sessionsClient).Update(ctx, &reqUpdate)
 	if err != nil {
 		err = WriteError(w, r, UserPageHandlers.metrics, err)
 		if err != nil {
@@ -228,8 +236,8 @@ func (UserPageHandlers *UserPageHandlers) ProfileEditByUuid(w http.ResponseWrite
 		return
 	}
 
-	reqAdd := session.AddRequest{Login: currUserProto.Email, Token: tokenSigned, Version: version}
-	_, err = (*UserPageHandlers.sessionsClient).Add(ctx, &reqAdd)
+	tokenSigned, err := GenerateTokens(UserPageHandlers.keyManager, currUserProto.Email, currUserProto.Uuid,
+		userPrincipal.SessionId, currUserProto.IsAdmin, currUserProto.Roles, userPrincipal.Version+1)
 	if err != nil {
 		err = WriteError(w, r, UserPageHandlers.metrics, err)
 		if err != nil {
@@ -238,16 +246,7 @@ func (UserPageHandlers *UserPageHandlers) ProfileEditByUuid(w http.ResponseWrite
 		return
 	}
 
-	tokenCookie := &http.Cookie{
-		Name:     "access",
-		Value:    tokenSigned,
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   false,
-		MaxAge:   tokenCookieExpirationTime,
-	}
-
-	http.SetCookie(w, tokenCookie)
+	setTokenCookies(w, UserPageHandlers.cookies, tokenSigned, "", "", false)
 
 	err = WriteSuccess(w, r, UserPageHandlers.metrics)
 	if err != nil {
@@ -338,8 +337,20 @@ func (UserPageHandlers *UserPageHandlers) PaySubscription(w http.ResponseWriter,
 	ctx := r.Context()
 	requestId := ctx.Value(reqid.ReqIDKey)
 
+	userPrincipal, err := getPrincipal(r)
+	if err == nil && userPrincipal.UserUuid != mux.Vars(r)["uuid"] {
+		err = myerrors.ErrNotAuthorised
+	}
+	if err != nil {
+		err = WriteError(w, r, UserPageHandlers.metrics, err)
+		if err != nil {
+			UserPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestId, err)
+		}
+		return
+	}
+
 	var request payRequest
-	err := json.NewDecoder(r.Body).Decode(&request)
+	err = json.NewDecoder(r.Body).Decode(&request)
 	if err != nil {
 		UserPageHandlers.logger.Errorf("[reqid=%s] failed to decode: %v\n", requestId, myerrors.ErrFailedDecode)
 		err = WriteError(w, r, UserPageHandlers.metrics, err)
@@ -349,7 +360,7 @@ func (UserPageHandlers *UserPageHandlers) PaySubscription(w http.ResponseWriter,
 		return
 	}
 
-	uuid := mux.Vars(r)["uuid"]
+	uuid := userPrincipal.UserUuid
 	req := session.HasSubscriptionRequest{Uuid: uuid}
 	stat, err := (*UserPageHandlers.usersClient).HasSubscription(ctx, &req)
 	if err != nil {
@@ -402,10 +413,49 @@ func (UserPageHandlers *UserPageHandlers) PaySubscription(w http.ResponseWriter,
 	}
 }
 
+// RemoveUser удаляет аккаунт пользователя, от имени которого выполняется запрос. Логин в запросе
+// можно не указывать, а чужой логин отклоняется: другие аккаунты удаляет AdminRemoveUser
 func (UserPageHandlers *UserPageHandlers) RemoveUser(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	requestId := ctx.Value(reqid.ReqIDKey)
 
+	userPrincipal, err := getPrincipal(r)
+	if err != nil {
+		err = WriteError(w, r, UserPageHandlers.metrics, err)
+		if err != nil {
+			UserPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestId, err)
+		}
+		return
+	}
+
+	var request domain.RemoveUserRequest
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		UserPageHandlers.logger.Errorf("[reqid=%s] failed to decode: %v\n", requestId, myerrors.ErrFailedDecode)
+		err = WriteError(w, r, UserPageHandlers.metrics, err)
+		if err != nil {
+			UserPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestId, err)
+		}
+		return
+	}
+
+	if request.Login != "" && request.Login != userPrincipal.Login {
+		err = WriteError(w, r, UserPageHandlers.metrics, myerrors.ErrForbidden)
+		if err != nil {
+			UserPageHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestId, err)
+		}
+		return
+	}
+
+	UserPageHandlers.removeUser(w, r, userPrincipal.Login)
+}
+
+// AdminRemoveUser удаляет аккаунт любого пользователя, доступ к нему проверяется
+// через rbac.PermissionUsersRemove
+func (UserPageHandlers *UserPageHandlers) AdminRemoveUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	requestId := ctx.Value(reqid.ReqIDKey)
+
 	var request domain.RemoveUserRequest
 	err := json.NewDecoder(r.Body).Decode(&request)
 	if err != nil {
@@ -417,8 +467,15 @@ func (UserPageHandlers *UserPageHandlers) RemoveUser(w http.ResponseWriter, r *h
 		return
 	}
 
-	_, err = (*UserPageHandlers.usersClient).RemoveUser(ctx, &session.RemoveUserRequest{
-		Login: request.Login,
+	UserPageHandlers.removeUser(w, r, request.Login)
+}
+
+func (UserPageHandlers *UserPageHandlers) removeUser(w http.ResponseWriter, r *http.Request, login string) {
+	ctx := r.Context()
+	requestId := ctx.Value(reqid.ReqIDKey)
+
+	_, err := (*UserPageHandlers.usersClient).RemoveUser(ctx, &session.RemoveUserRequest{
+		Login: login,
 	})
 	if err != nil {
 		err = WriteError(w, r, UserPageHandlers.metrics, err)
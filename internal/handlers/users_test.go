@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/SanExpett/diploma/internal/domain"
+	"github.com/SanExpett/diploma/internal/handlers/mocks"
+	"github.com/SanExpett/diploma/internal/metrics"
+	"github.com/SanExpett/diploma/internal/principal"
+	session "github.com/SanExpett/diploma/internal/session/proto"
+)
+
+func TestUserPageHandlers_RemoveUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUsersClient := mocks.NewMockUsersClient(ctrl)
+	var usersClient session.UsersClient = mockUsersClient
+
+	handler := NewUserPageHandlers(&usersClient, nil, nil, testCookies, metrics.NewHttpMetrics(),
+		zap.NewNop().Sugar())
+
+	tests := []struct {
+		name           string
+		login          string
+		admin          bool
+		setupMocks     func()
+		expectedStatus int
+	}{
+		{
+			name:  "Удаление своего аккаунта",
+			login: "test@test.com",
+			setupMocks: func() {
+				mockUsersClient.EXPECT().RemoveUser(gomock.Any(), &session.RemoveUserRequest{Login: "test@test.com"}).
+					Return(&session.RemoveUserResponse{}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "Логин не указан",
+			setupMocks: func() {
+				mockUsersClient.EXPECT().RemoveUser(gomock.Any(), &session.RemoveUserRequest{Login: "test@test.com"}).
+					Return(&session.RemoveUserResponse{}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Удаление чужого аккаунта",
+			login:          "other@test.com",
+			setupMocks:     func() {},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:  "Администратор удаляет чужой аккаунт",
+			login: "other@test.com",
+			admin: true,
+			setupMocks: func() {
+				mockUsersClient.EXPECT().RemoveUser(gomock.Any(), &session.RemoveUserRequest{Login: "other@test.com"}).
+					Return(&session.RemoveUserResponse{}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMocks()
+
+			body, _ := json.Marshal(domain.RemoveUserRequest{Login: tt.login})
+			req := httptest.NewRequest(http.MethodPost, "/api/profile/remove", bytes.NewReader(body))
+			req = req.WithContext(principal.WithPrincipal(req.Context(),
+				principal.Principal{UserUuid: "1", Login: "test@test.com"}))
+			w := httptest.NewRecorder()
+
+			if tt.admin {
+				handler.AdminRemoveUser(w, req)
+			} else {
+				handler.RemoveUser(w, req)
+			}
+
+			var response ErrorResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tt.expectedStatus, response.Status)
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	grpcHealth "google.golang.org/grpc/health"
+	healthProto "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Check проверка зависимости сервиса, например ping базы
+type Check func(ctx context.Context) error
+
+// Report ответ /healthz и /readyz. Details ошибки проверок или статусы сервисов по имени
+type Report struct {
+	Status  string            `json:"status"`
+	Details map[string]string `json:"details,omitempty"`
+}
+
+// Reporter выставляет статус grpc.health.v1 сервиса по результатам проверок его зависимостей
+type Reporter struct {
+	server  *grpcHealth.Server
+	service string
+	checks  map[string]Check
+	timeout time.Duration
+	logger  *zap.SugaredLogger
+
+	mu     sync.RWMutex
+	status healthProto.HealthCheckResponse_ServingStatus
+	errors map[string]string
+}
+
+// NewReporter создает Reporter для service, полного имени gRPC сервиса. До первой проверки
+// сервис считается неготовым
+func NewReporter(service string, checks map[string]Check, timeout time.Duration,
+	logger *zap.SugaredLogger) *Reporter {
+	reporter := &Reporter{
+		server:  grpcHealth.NewServer(),
+		service: service,
+		checks:  checks,
+		timeout: timeout,
+		logger:  logger,
+		status:  healthProto.HealthCheckResponse_NOT_SERVING,
+	}
+	reporter.server.SetServingStatus("", reporter.status)
+	reporter.server.SetServingStatus(service, reporter.status)
+	return reporter
+}
+
+// Register регистрирует grpc.health.v1 в server
+func (reporter *Reporter) Register(server *grpc.Server) {
+	healthProto.RegisterHealthServer(server, reporter.server)
+}
+
+// Start проверяет зависимости сразу и затем каждые interval до отмены ctx
+func (reporter *Reporter) Start(ctx context.Context, interval time.Duration) {
+	reporter.Check(ctx)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				reporter.Check(ctx)
+			}
+		}
+	}()
+}
+
+// Check выполняет проверки и обновляет статус сервиса
+func (reporter *Reporter) Check(ctx context.Context) {
+	errors := make(map[string]string)
+	for name, check := range reporter.checks {
+		checkCtx, cancel := context.WithTimeout(ctx, reporter.timeout)
+		err := check(checkCtx)
+		cancel()
+		if err != nil {
+			errors[name] = err.Error()
+		}
+	}
+
+	status := healthProto.HealthCheckResponse_SERVING
+	if len(errors) > 0 {
+		status = healthProto.HealthCheckResponse_NOT_SERVING
+	}
+
+	reporter.mu.Lock()
+	changed := reporter.status != status
+	reporter.status = status
+	reporter.errors = errors
+	reporter.mu.Unlock()
+
+	if changed {
+		if status == healthProto.HealthCheckResponse_SERVING {
+			reporter.logger.Infof("%s is serving", reporter.service)
+		} else {
+			reporter.logger.Warnf("%s is not serving: %v", reporter.service, errors)
+		}
+	}
+	reporter.server.SetServingStatus("", status)
+	reporter.server.SetServingStatus(reporter.service, status)
+}
+
+// Shutdown переводит сервис в NOT_SERVING до остановки, чтобы gateway перестал направлять в него запросы
+func (reporter *Reporter) Shutdown() {
+	reporter.mu.Lock()
+	reporter.status = healthProto.HealthCheckResponse_NOT_SERVING
+	reporter.errors = map[string]string{"server": "shutting down"}
+	reporter.mu.Unlock()
+	reporter.server.Shutdown()
+}
+
+// Readiness отвечает 200, пока зависимости сервиса доступны, и 503 с ошибками проверок иначе
+func (reporter *Reporter) Readiness(w http.ResponseWriter, r *http.Request) {
+	reporter.mu.RLock()
+	report := Report{Status: reporter.status.String(), Details: reporter.errors}
+	serving := reporter.status == healthProto.HealthCheckResponse_SERVING
+	reporter.mu.RUnlock()
+
+	writeReport(w, serving, report)
+}
+
+// Liveness отвечает 200, пока процесс способен обрабатывать HTTP запросы
+func Liveness(w http.ResponseWriter, r *http.Request) {
+	writeReport(w, true, Report{Status: healthProto.HealthCheckResponse_SERVING.String()})
+}
+
+// ServiceConfig конфигурация клиента, с которой gRPC следит за статусом service через grpc.health.v1
+// и не направляет запросы в экземпляры со статусом NOT_SERVING
+func ServiceConfig(service string) string {
+	return fmt.Sprintf(`{"loadBalancingConfig":[{"round_robin":{}}],"healthCheckConfig":{"serviceName":%q}}`, service)
+}
+
+func writeReport(w http.ResponseWriter, serving bool, report Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if !serving {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(report)
+}
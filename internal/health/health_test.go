@@ -0,0 +1,159 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthProto "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const testService = "session.Films"
+
+// startServer запускает gRPC сервер с grpc.health.v1 и возвращает подключение к нему
+func startServer(t *testing.T, reporter *Reporter, options ...grpc.DialOption) *grpc.ClientConn {
+	t.Helper()
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	reporter.Register(server)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	options = append(options, grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}))
+	conn, err := grpc.NewClient("passthrough:///bufnet", options...)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+	return conn
+}
+
+func TestReporter(t *testing.T) {
+	tests := []struct {
+		name           string
+		checkErr       error
+		shutdown       bool
+		expectedStatus healthProto.HealthCheckResponse_ServingStatus
+		expectedCode   int
+	}{
+		{
+			name:           "Зависимости доступны",
+			expectedStatus: healthProto.HealthCheckResponse_SERVING,
+			expectedCode:   http.StatusOK,
+		},
+		{
+			name:           "База недоступна",
+			checkErr:       errors.New("connection refused"),
+			expectedStatus: healthProto.HealthCheckResponse_NOT_SERVING,
+			expectedCode:   http.StatusServiceUnavailable,
+		},
+		{
+			name:           "Сервис останавливается",
+			shutdown:       true,
+			expectedStatus: healthProto.HealthCheckResponse_NOT_SERVING,
+			expectedCode:   http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reporter := NewReporter(testService, map[string]Check{
+				"postgres": func(ctx context.Context) error {
+					return tt.checkErr
+				},
+			}, time.Second, zap.NewNop().Sugar())
+			conn := startServer(t, reporter)
+
+			reporter.Check(context.Background())
+			if tt.shutdown {
+				reporter.Shutdown()
+			}
+
+			client := healthProto.NewHealthClient(conn)
+			for _, service := range []string{"", testService} {
+				response, err := client.Check(context.Background(), &healthProto.HealthCheckRequest{Service: service})
+				require.NoError(t, err)
+				assert.Equal(t, tt.expectedStatus, response.GetStatus())
+			}
+
+			recorder := httptest.NewRecorder()
+			reporter.Readiness(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			assert.Equal(t, tt.expectedCode, recorder.Code)
+			if tt.checkErr != nil {
+				assert.Contains(t, recorder.Body.String(), tt.checkErr.Error())
+			}
+		})
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	var checkErr error
+	reporter := NewReporter(testService, map[string]Check{
+		"postgres": func(ctx context.Context) error {
+			return checkErr
+		},
+	}, time.Second, zap.NewNop().Sugar())
+	conn := startServer(t, reporter)
+
+	watcher := NewWatcher(time.Second, zap.NewNop().Sugar())
+	watcher.Add("films", conn, testService)
+	assert.False(t, watcher.Serving())
+
+	reporter.Check(context.Background())
+	watcher.Check(context.Background())
+	assert.True(t, watcher.Serving())
+	recorder := httptest.NewRecorder()
+	watcher.Readiness(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	checkErr = errors.New("connection refused")
+	reporter.Check(context.Background())
+	watcher.Check(context.Background())
+	assert.False(t, watcher.Serving())
+	recorder = httptest.NewRecorder()
+	watcher.Readiness(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"films":"NOT_SERVING"`)
+}
+
+func TestServiceConfig(t *testing.T) {
+	reporter := NewReporter(testService, nil, time.Second, zap.NewNop().Sugar())
+	conn := startServer(t, reporter, grpc.WithDefaultServiceConfig(ServiceConfig(testService)))
+	client := healthProto.NewHealthClient(conn)
+
+	// пока сервис не готов, запросы в него не направляются
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	_, err := client.Check(ctx, &healthProto.HealthCheckRequest{Service: testService})
+	assert.Contains(t, []codes.Code{codes.Unavailable, codes.DeadlineExceeded}, status.Code(err))
+
+	reporter.Check(context.Background())
+	assert.Eventually(t, func() bool {
+		response, err := client.Check(context.Background(), &healthProto.HealthCheckRequest{Service: testService})
+		return err == nil && response.GetStatus() == healthProto.HealthCheckResponse_SERVING
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestLiveness(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	Liveness(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"status":"SERVING"}`, recorder.Body.String())
+}
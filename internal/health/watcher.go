@@ -0,0 +1,116 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	healthProto "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+type downstream struct {
+	client  healthProto.HealthClient
+	service string
+}
+
+// Watcher опрашивает grpc.health.v1 сервисов, к которым обращается gateway
+type Watcher struct {
+	downstreams map[string]downstream
+	timeout     time.Duration
+	logger      *zap.SugaredLogger
+
+	mu       sync.RWMutex
+	statuses map[string]healthProto.HealthCheckResponse_ServingStatus
+}
+
+func NewWatcher(timeout time.Duration, logger *zap.SugaredLogger) *Watcher {
+	return &Watcher{
+		downstreams: make(map[string]downstream),
+		timeout:     timeout,
+		logger:      logger,
+		statuses:    make(map[string]healthProto.HealthCheckResponse_ServingStatus),
+	}
+}
+
+// Add добавляет сервис name, доступный через conn. service полное имя gRPC сервиса. Вызывается до Start
+func (watcher *Watcher) Add(name string, conn grpc.ClientConnInterface, service string) {
+	watcher.downstreams[name] = downstream{client: healthProto.NewHealthClient(conn), service: service}
+	watcher.statuses[name] = healthProto.HealthCheckResponse_UNKNOWN
+}
+
+// Start опрашивает сервисы сразу и затем каждые interval до отмены ctx
+func (watcher *Watcher) Start(ctx context.Context, interval time.Duration) {
+	watcher.Check(ctx)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				watcher.Check(ctx)
+			}
+		}
+	}()
+}
+
+// Check опрашивает сервисы. Недоступный сервис считается NOT_SERVING
+func (watcher *Watcher) Check(ctx context.Context) {
+	for name, downstream := range watcher.downstreams {
+		checkCtx, cancel := context.WithTimeout(ctx, watcher.timeout)
+		response, err := downstream.client.Check(checkCtx, &healthProto.HealthCheckRequest{Service: downstream.service})
+		cancel()
+
+		status := healthProto.HealthCheckResponse_NOT_SERVING
+		if err == nil {
+			status = response.GetStatus()
+		}
+
+		watcher.mu.Lock()
+		previous := watcher.statuses[name]
+		watcher.statuses[name] = status
+		watcher.mu.Unlock()
+
+		if previous != status {
+			if err != nil {
+				watcher.logger.Warnf("%s is %s: %v", name, status, err)
+			} else {
+				watcher.logger.Infof("%s is %s", name, status)
+			}
+		}
+	}
+}
+
+// Serving сообщает, что все сервисы готовы принимать запросы
+func (watcher *Watcher) Serving() bool {
+	watcher.mu.RLock()
+	defer watcher.mu.RUnlock()
+	for _, status := range watcher.statuses {
+		if status != healthProto.HealthCheckResponse_SERVING {
+			return false
+		}
+	}
+	return true
+}
+
+// Readiness отвечает 200, если все сервисы готовы, и 503 иначе. В ответе статусы сервисов по имени
+func (watcher *Watcher) Readiness(w http.ResponseWriter, r *http.Request) {
+	watcher.mu.RLock()
+	details := make(map[string]string, len(watcher.statuses))
+	for name, status := range watcher.statuses {
+		details[name] = status.String()
+	}
+	watcher.mu.RUnlock()
+
+	serving := watcher.Serving()
+	status := healthProto.HealthCheckResponse_SERVING
+	if !serving {
+		status = healthProto.HealthCheckResponse_NOT_SERVING
+	}
+	writeReport(w, serving, Report{Status: status.String(), Details: details})
+}
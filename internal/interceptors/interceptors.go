@@ -0,0 +1,303 @@
+package interceptors
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/SanExpett/diploma/internal/audit"
+	"github.com/SanExpett/diploma/internal/principal"
+	"github.com/SanExpett/diploma/internal/rbac"
+	reqid "github.com/SanExpett/diploma/internal/requestId"
+	"github.com/SanExpett/diploma/internal/tracing"
+)
+
+const (
+	requestIdKey = "x-request-id"
+
+	authorizationKey = "authorization"
+	bearerPrefix     = "Bearer "
+
+	auditIpKey        = "x-audit-ip"
+	auditUserAgentKey = "x-audit-user-agent"
+	auditRequestIdKey = "x-audit-request-id"
+
+	gatewayTokenKey = "x-gateway-token"
+	gatewaySubject  = "gateway"
+	gatewayAudience = "services"
+	gatewayTokenTTL = 5 * time.Minute
+)
+
+type contextKey string
+
+const gatewayKey contextKey = "gateway"
+
+// RequestIdUnaryClientInterceptor передает идентификатор HTTP запроса в метаданных исходящего gRPC запроса,
+// чтобы записи журналов gateway и сервисов об одном запросе можно было связать
+func RequestIdUnaryClientInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn,
+	invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	requestId, ok := ctx.Value(reqid.ReqIDKey).(string)
+	if ok && requestId != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, requestIdKey, requestId)
+	}
+
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+// RequestIdUnaryServerInterceptor кладет в контекст идентификатор запроса из метаданных.
+// Вызовы без идентификатора или с некорректным получают новый
+func RequestIdUnaryServerInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (any, error) {
+	var requestId string
+	md, ok := metadata.FromIncomingContext(ctx)
+	if ok {
+		if requestIds := md.Get(requestIdKey); len(requestIds) > 0 {
+			requestId = requestIds[0]
+		}
+	}
+
+	requestId = reqid.RequestIDOrGenerate(requestId)
+	tracing.SetRequestId(ctx, requestId)
+	return handler(context.WithValue(ctx, reqid.ReqIDKey, requestId), req)
+}
+
+// NewDeadlineUnaryClientInterceptor ограничивает исходящий вызов временем timeout, если у контекста еще нет дедлайна.
+// Дедлайн передается сервису в заголовке grpc-timeout
+func NewDeadlineUnaryClientInterceptor(timeout time.Duration) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if _, ok := ctx.Deadline(); !ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// NewDeadlineUnaryServerInterceptor ограничивает вызов без дедлайна временем timeout и не начинает обработку
+// вызовов, дедлайн которых уже истек: их результат клиенту больше не нужен
+func NewDeadlineUnaryServerInterceptor(timeout time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if _, ok := ctx.Deadline(); !ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		if ctx.Err() != nil {
+			return nil, status.FromContextError(ctx.Err()).Err()
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// PrincipalUnaryClientInterceptor передает access токен пользователя, положенный в контекст AuthMiddleware,
+// в метаданных исходящего gRPC запроса
+func PrincipalUnaryClientInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn,
+	invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	token, ok := principal.TokenFromContext(ctx)
+	if ok {
+		ctx = metadata.AppendToOutgoingContext(ctx, authorizationKey, bearerPrefix+token)
+	}
+
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+// NewPrincipalUnaryServerInterceptor восстанавливает пользователя из access токена во входящих метаданных.
+// Подпись токена проверяется ключом из keyfunc, поэтому подделать пользователя, не имея ключа gateway,
+// нельзя. Вызовы без токена выполняются без пользователя, вызовы с недействительным токеном отклоняются
+func NewPrincipalUnaryServerInterceptor(keyfunc jwt.Keyfunc) grpc.UnaryServerInterceptor {
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{jwt.SigningMethodEdDSA.Alg(), jwt.SigningMethodRS256.Alg()}))
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, ok := metadata.FromIncomingContext(ctx)
+		if !ok || len(md.Get(authorizationKey)) == 0 {
+			return handler(ctx, req)
+		}
+
+		token, ok := strings.CutPrefix(md.Get(authorizationKey)[0], bearerPrefix)
+		if !ok {
+			return nil, status.Error(codes.Unauthenticated, "not authorised")
+		}
+		claims := jwt.MapClaims{}
+		_, err := parser.ParseWithClaims(token, claims, keyfunc)
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, "not authorised")
+		}
+		userPrincipal, err := principal.FromClaims(claims)
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, "not authorised")
+		}
+		if userPrincipal.SessionId == "" {
+			userPrincipal.SessionId = token
+		}
+
+		return handler(principal.WithToken(principal.WithPrincipal(ctx, userPrincipal), token), req)
+	}
+}
+
+// NewGatewayUnaryClientInterceptor передает в метаданных исходящего gRPC запроса токен gateway,
+// подписанный sign. Токен выпускается заново, когда прошла половина его срока действия
+func NewGatewayUnaryClientInterceptor(sign func(claims jwt.Claims) (string, error)) grpc.UnaryClientInterceptor {
+	var mu sync.Mutex
+	var token string
+	var refreshAt time.Time
+
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		mu.Lock()
+		now := time.Now()
+		if now.After(refreshAt) {
+			tokenSigned, err := sign(jwt.StandardClaims{
+				Subject:   gatewaySubject,
+				Audience:  gatewayAudience,
+				Issuer:    "nimbus",
+				IssuedAt:  now.Unix(),
+				ExpiresAt: now.Add(gatewayTokenTTL).Unix(),
+			})
+			if err != nil {
+				mu.Unlock()
+				return status.Error(codes.Internal, "failed to sign gateway token")
+			}
+			token, refreshAt = tokenSigned, now.Add(gatewayTokenTTL/2)
+		}
+		gatewayToken := token
+		mu.Unlock()
+
+		ctx = metadata.AppendToOutgoingContext(ctx, gatewayTokenKey, gatewayToken)
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// NewGatewayUnaryServerInterceptor отмечает вызовы, которые сделал gateway от своего имени. Подпись токена
+// gateway проверяется ключом из keyfunc. Вызовы без токена выполняются как вызовы не от gateway,
+// вызовы с недействительным токеном отклоняются
+func NewGatewayUnaryServerInterceptor(keyfunc jwt.Keyfunc) grpc.UnaryServerInterceptor {
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{jwt.SigningMethodEdDSA.Alg(), jwt.SigningMethodRS256.Alg()}))
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, ok := metadata.FromIncomingContext(ctx)
+		if !ok || len(md.Get(gatewayTokenKey)) == 0 {
+			return handler(ctx, req)
+		}
+
+		claims := &jwt.StandardClaims{}
+		_, err := parser.ParseWithClaims(md.Get(gatewayTokenKey)[0], claims, keyfunc)
+		if err != nil || claims.Subject != gatewaySubject || !claims.VerifyAudience(gatewayAudience, true) {
+			return nil, status.Error(codes.Unauthenticated, "not authorised")
+		}
+
+		return handler(context.WithValue(ctx, gatewayKey, true), req)
+	}
+}
+
+// AuditUnaryClientInterceptor передает данные HTTP запроса для журнала безопасности
+// в метаданных исходящего gRPC запроса
+func AuditUnaryClientInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn,
+	invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	info, ok := audit.RequestInfoFromContext(ctx)
+	if ok {
+		ctx = metadata.AppendToOutgoingContext(ctx,
+			auditIpKey, info.Ip,
+			auditUserAgentKey, info.UserAgent,
+			auditRequestIdKey, info.RequestId,
+		)
+	}
+
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+// AuditUnaryServerInterceptor восстанавливает данные HTTP запроса из метаданных входящего запроса
+func AuditUnaryServerInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (any, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return handler(ctx, req)
+	}
+
+	requestInfo := audit.RequestInfo{}
+	if ips := md.Get(auditIpKey); len(ips) > 0 {
+		requestInfo.Ip = ips[0]
+	}
+	if userAgents := md.Get(auditUserAgentKey); len(userAgents) > 0 {
+		requestInfo.UserAgent = userAgents[0]
+	}
+	if requestIds := md.Get(auditRequestIdKey); len(requestIds) > 0 {
+		requestInfo.RequestId = requestIds[0]
+	}
+	if requestInfo == (audit.RequestInfo{}) {
+		return handler(ctx, req)
+	}
+
+	return handler(audit.WithRequestInfo(ctx, requestInfo), req)
+}
+
+// NewRbacUnaryServerInterceptor отклоняет вызовы методов, если у пользователя нет роли с правом
+// из methodPermissions. Вызовы методов, которых нет в methodPermissions, отклоняются. Методы из methodOwners
+// пользователь вызывает только для своих данных, если у него нет права менять чужие. Внутренние методы
+// доступны только gateway
+func NewRbacUnaryServerInterceptor(policy *rbac.CachedPolicy, methodPermissions map[string]rbac.Permission,
+	methodOwners map[string]rbac.Ownership, logger *zap.SugaredLogger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		requestId := ctx.Value(reqid.ReqIDKey)
+
+		permission, ok := methodPermissions[info.FullMethod]
+		if !ok {
+			logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] call of %s without permissions\n",
+				requestId, info.FullMethod)
+			return nil, status.Error(codes.PermissionDenied, "forbidden")
+		}
+		if permission == rbac.PermissionPublic {
+			return handler(ctx, req)
+		}
+		if permission == rbac.PermissionInternal {
+			if fromGateway, _ := ctx.Value(gatewayKey).(bool); !fromGateway {
+				logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] call of internal %s not from gateway\n",
+					requestId, info.FullMethod)
+				return nil, status.Error(codes.PermissionDenied, "forbidden")
+			}
+			return handler(ctx, req)
+		}
+
+		userPrincipal, ok := principal.FromContext(ctx)
+		if !ok {
+			logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] unauthenticated call of %s\n", requestId, info.FullMethod)
+			return nil, status.Error(codes.Unauthenticated, "not authorised")
+		}
+		if permission == rbac.PermissionAuthenticated {
+			ownership, ok := methodOwners[info.FullMethod]
+			if !ok || ownership.IsOwner(req, userPrincipal.UserUuid, userPrincipal.Login) {
+				return handler(ctx, req)
+			}
+			if ownership.Permission == "" {
+				logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] user %s calls %s for another user\n",
+					requestId, userPrincipal.Login, info.FullMethod)
+				return nil, status.Error(codes.PermissionDenied, "forbidden")
+			}
+			permission = ownership.Permission
+		}
+
+		allowed, err := policy.Allowed(ctx, userPrincipal.Roles, permission)
+		if err != nil {
+			logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to load rbac policy: %v\n", requestId, err)
+			return nil, status.Error(codes.Internal, "failed to check permissions")
+		}
+		if !allowed {
+			logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] user %s has no permission %s for %s\n",
+				requestId, userPrincipal.Login, permission, info.FullMethod)
+			return nil, status.Error(codes.PermissionDenied, "forbidden")
+		}
+
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,453 @@
+package interceptors
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/SanExpett/diploma/internal/audit"
+	"github.com/SanExpett/diploma/internal/principal"
+	"github.com/SanExpett/diploma/internal/rbac"
+	reqid "github.com/SanExpett/diploma/internal/requestId"
+	session "github.com/SanExpett/diploma/internal/session/proto"
+	"github.com/SanExpett/diploma/internal/signing"
+)
+
+type staticLoader map[rbac.Role][]rbac.Permission
+
+func (loader staticLoader) LoadPolicy(ctx context.Context) (map[rbac.Role][]rbac.Permission, error) {
+	return loader, nil
+}
+
+func TestPrincipalInterceptors(t *testing.T) {
+	keyManager, err := signing.NewKeyManager(signing.AlgorithmEdDSA, time.Hour, time.Hour)
+	require.NoError(t, err)
+	foreignKeyManager, err := signing.NewKeyManager(signing.AlgorithmEdDSA, time.Hour, time.Hour)
+	require.NoError(t, err)
+
+	claims := jwt.MapClaims{
+		"Uuid":    "test-uuid",
+		"Login":   "test@test.com",
+		"Sid":     "session-id",
+		"IsAdmin": true,
+		"Roles":   []string{"admin", "moderator"},
+		"Version": 3,
+	}
+	token, err := keyManager.Sign(claims)
+	require.NoError(t, err)
+	foreignToken, err := foreignKeyManager.Sign(claims)
+	require.NoError(t, err)
+
+	var outgoing metadata.MD
+	invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn,
+		opts ...grpc.CallOption) error {
+		outgoing, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	}
+	err = PrincipalUnaryClientInterceptor(principal.WithToken(context.Background(), token),
+		"/session.Films/AddFilm", nil, nil, nil, invoker)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		md            metadata.MD
+		expected      principal.Principal
+		hasPrincipal  bool
+		expectedCode  codes.Code
+		handlerCalled bool
+	}{
+		{
+			name: "Токен от gateway",
+			md:   outgoing,
+			expected: principal.Principal{
+				UserUuid:  "test-uuid",
+				Login:     "test@test.com",
+				SessionId: "session-id",
+				IsAdmin:   true,
+				Roles:     []string{"admin", "moderator"},
+				Version:   3,
+			},
+			hasPrincipal:  true,
+			handlerCalled: true,
+		},
+		{
+			name:          "Нет токена",
+			md:            metadata.Pairs("x-principal-login", "admin@test.com", "x-principal-roles", "admin"),
+			handlerCalled: true,
+		},
+		{
+			name:         "Токен подписан чужим ключом",
+			md:           metadata.Pairs("authorization", "Bearer "+foreignToken),
+			expectedCode: codes.Unauthenticated,
+		},
+		{
+			name:         "Токен без схемы Bearer",
+			md:           metadata.Pairs("authorization", token),
+			expectedCode: codes.Unauthenticated,
+		},
+	}
+
+	interceptor := NewPrincipalUnaryServerInterceptor(keyManager.Keyfunc)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got principal.Principal
+			var hasPrincipal, handlerCalled bool
+			handler := func(ctx context.Context, req any) (any, error) {
+				handlerCalled = true
+				got, hasPrincipal = principal.FromContext(ctx)
+				return nil, nil
+			}
+			_, err := interceptor(metadata.NewIncomingContext(context.Background(), tt.md), nil,
+				&grpc.UnaryServerInfo{FullMethod: "/session.Films/AddFilm"}, handler)
+
+			assert.Equal(t, tt.expectedCode, status.Code(err))
+			assert.Equal(t, tt.handlerCalled, handlerCalled)
+			assert.Equal(t, tt.hasPrincipal, hasPrincipal)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestGatewayInterceptors(t *testing.T) {
+	keyManager, err := signing.NewKeyManager(signing.AlgorithmEdDSA, time.Hour, time.Hour)
+	require.NoError(t, err)
+	foreignKeyManager, err := signing.NewKeyManager(signing.AlgorithmEdDSA, time.Hour, time.Hour)
+	require.NoError(t, err)
+
+	var outgoing metadata.MD
+	invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn,
+		opts ...grpc.CallOption) error {
+		outgoing, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	}
+	err = NewGatewayUnaryClientInterceptor(keyManager.Sign)(context.Background(), "/session.Sessions/Add", nil, nil,
+		nil, invoker)
+	require.NoError(t, err)
+
+	foreignToken, err := foreignKeyManager.Sign(jwt.StandardClaims{Subject: "gateway", Audience: "services",
+		ExpiresAt: time.Now().Add(time.Minute).Unix()})
+	require.NoError(t, err)
+	userToken, err := keyManager.Sign(jwt.MapClaims{"Login": "test@test.com", "IsAdmin": false, "Version": 1})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		md            metadata.MD
+		expectedCode  codes.Code
+		handlerCalled bool
+		fromGateway   bool
+	}{
+		{
+			name:          "Токен от gateway",
+			md:            outgoing,
+			handlerCalled: true,
+			fromGateway:   true,
+		},
+		{
+			name:          "Нет токена",
+			md:            metadata.Pairs("authorization", "Bearer "+userToken),
+			handlerCalled: true,
+		},
+		{
+			name:         "Токен подписан чужим ключом",
+			md:           metadata.Pairs("x-gateway-token", foreignToken),
+			expectedCode: codes.Unauthenticated,
+		},
+		{
+			name:         "Токен пользователя вместо токена gateway",
+			md:           metadata.Pairs("x-gateway-token", userToken),
+			expectedCode: codes.Unauthenticated,
+		},
+	}
+
+	interceptor := NewGatewayUnaryServerInterceptor(keyManager.Keyfunc)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var handlerCalled, fromGateway bool
+			handler := func(ctx context.Context, req any) (any, error) {
+				handlerCalled = true
+				fromGateway, _ = ctx.Value(gatewayKey).(bool)
+				return nil, nil
+			}
+			_, err := interceptor(metadata.NewIncomingContext(context.Background(), tt.md), nil,
+				&grpc.UnaryServerInfo{FullMethod: "/session.Sessions/Add"}, handler)
+
+			assert.Equal(t, tt.expectedCode, status.Code(err))
+			assert.Equal(t, tt.handlerCalled, handlerCalled)
+			assert.Equal(t, tt.fromGateway, fromGateway)
+		})
+	}
+}
+
+func TestRequestIdInterceptors(t *testing.T) {
+	var outgoing metadata.MD
+	invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn,
+		opts ...grpc.CallOption) error {
+		outgoing, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	}
+	err := RequestIdUnaryClientInterceptor(context.WithValue(context.Background(), reqid.ReqIDKey, "request-id"),
+		"/session.Films/GetTopFilms", nil, nil, nil, invoker)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		expected string
+	}{
+		{
+			name:     "Идентификатор от gateway",
+			ctx:      metadata.NewIncomingContext(context.Background(), outgoing),
+			expected: "request-id",
+		},
+		{
+			name: "Нет идентификатора",
+			ctx:  context.Background(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			handler := func(ctx context.Context, req any) (any, error) {
+				got, _ = ctx.Value(reqid.ReqIDKey).(string)
+				return nil, nil
+			}
+			_, err := RequestIdUnaryServerInterceptor(tt.ctx, nil,
+				&grpc.UnaryServerInfo{FullMethod: "/session.Films/GetTopFilms"}, handler)
+			require.NoError(t, err)
+
+			require.NotEmpty(t, got)
+			if tt.expected != "" {
+				assert.Equal(t, tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestDeadlineInterceptors(t *testing.T) {
+	var deadline time.Time
+	invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn,
+		opts ...grpc.CallOption) error {
+		deadline, _ = ctx.Deadline()
+		return nil
+	}
+	err := NewDeadlineUnaryClientInterceptor(time.Second)(context.Background(), "/session.Films/GetTopFilms",
+		nil, nil, nil, invoker)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Second), deadline, 100*time.Millisecond)
+
+	called := false
+	handler := func(ctx context.Context, req any) (any, error) {
+		called = true
+		return nil, nil
+	}
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	_, err = NewDeadlineUnaryServerInterceptor(time.Second)(expired, nil,
+		&grpc.UnaryServerInfo{FullMethod: "/session.Films/GetTopFilms"}, handler)
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	assert.False(t, called)
+}
+
+func TestAuditInterceptors(t *testing.T) {
+	requestInfo := audit.RequestInfo{
+		Ip:        "192.0.2.1",
+		UserAgent: "Mozilla/5.0",
+		RequestId: "request-id",
+	}
+
+	var outgoing metadata.MD
+	invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn,
+		opts ...grpc.CallOption) error {
+		outgoing, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	}
+	err := AuditUnaryClientInterceptor(audit.WithRequestInfo(context.Background(), requestInfo),
+		"/session.Users/ChangeUserPassword", nil, nil, nil, invoker)
+	require.NoError(t, err)
+
+	var got audit.RequestInfo
+	handler := func(ctx context.Context, req any) (any, error) {
+		got, _ = audit.RequestInfoFromContext(ctx)
+		return nil, nil
+	}
+	_, err = AuditUnaryServerInterceptor(metadata.NewIncomingContext(context.Background(), outgoing), nil,
+		&grpc.UnaryServerInfo{FullMethod: "/session.Users/ChangeUserPassword"}, handler)
+	require.NoError(t, err)
+
+	assert.Equal(t, requestInfo, got)
+}
+
+func TestRbacUnaryServerInterceptor(t *testing.T) {
+	policy := rbac.NewCachedPolicy(staticLoader{
+		rbac.RoleContentEditor: {rbac.PermissionFilmsManage},
+		rbac.RoleModerator:     {rbac.PermissionCommentsModerate},
+		rbac.RoleSupport:       {rbac.PermissionUsersRemove},
+	}, time.Minute)
+	interceptor := NewRbacUnaryServerInterceptor(policy, rbac.MethodPermissions, rbac.MethodOwners,
+		zap.NewNop().Sugar())
+
+	handler := func(ctx context.Context, req any) (any, error) {
+		return "ok", nil
+	}
+	editor := principal.WithPrincipal(context.Background(), principal.Principal{
+		UserUuid: "editor-uuid",
+		Login:    "editor@test.com",
+		Roles:    []string{"content_editor"},
+	})
+	moderator := principal.WithPrincipal(context.Background(), principal.Principal{
+		UserUuid: "moderator-uuid",
+		Login:    "moderator@test.com",
+		Roles:    []string{"moderator"},
+	})
+	support := principal.WithPrincipal(context.Background(), principal.Principal{
+		UserUuid: "support-uuid",
+		Login:    "support@test.com",
+		Roles:    []string{"support"},
+	})
+	editorComment := &session.RemoveCommentRequest{Comment: &session.CommentToRemove{AuthorUuid: "editor-uuid"}}
+
+	tests := []struct {
+		name         string
+		ctx          context.Context
+		method       string
+		req          any
+		expectedCode codes.Code
+	}{
+		{
+			name:         "Публичный метод",
+			ctx:          context.Background(),
+			method:       "/session.Films/GetTopFilms",
+			expectedCode: codes.OK,
+		},
+		{
+			name:         "Внутренний метод от gateway",
+			ctx:          context.WithValue(context.Background(), gatewayKey, true),
+			method:       "/session.Sessions/RotateRefreshToken",
+			expectedCode: codes.OK,
+		},
+		{
+			name:         "Внутренний метод не от gateway",
+			ctx:          context.Background(),
+			method:       "/session.Sessions/RotateRefreshToken",
+			expectedCode: codes.PermissionDenied,
+		},
+		{
+			name:         "Внутренний метод от пользователя не через gateway",
+			ctx:          editor,
+			method:       "/session.Users/RecordAuditEvent",
+			expectedCode: codes.PermissionDenied,
+		},
+		{
+			name:         "Метод не указан в правах",
+			ctx:          editor,
+			method:       "/session.Films/Unknown",
+			expectedCode: codes.PermissionDenied,
+		},
+		{
+			name:         "Метод для пользователей без пользователя",
+			ctx:          context.Background(),
+			method:       "/session.Films/PutFavorite",
+			expectedCode: codes.Unauthenticated,
+		},
+		{
+			name:         "Метод для пользователей",
+			ctx:          editor,
+			method:       "/session.Films/PutFavorite",
+			req:          &session.PutFavoriteRequest{UserUuid: "editor-uuid"},
+			expectedCode: codes.OK,
+		},
+		{
+			name:         "Избранное другого пользователя",
+			ctx:          editor,
+			method:       "/session.Films/PutFavorite",
+			req:          &session.PutFavoriteRequest{UserUuid: "moderator-uuid"},
+			expectedCode: codes.PermissionDenied,
+		},
+		{
+			name:         "Смена пароля другого пользователя",
+			ctx:          editor,
+			method:       "/session.Users/ChangeUserPasswordByUuid",
+			req:          &session.ChangeUserPasswordByUuidRequest{Uuid: "moderator-uuid"},
+			expectedCode: codes.PermissionDenied,
+		},
+		{
+			name: "Администратор меняет пароль другого пользователя",
+			ctx: principal.WithPrincipal(context.Background(), principal.Principal{UserUuid: "admin-uuid",
+				Login: "admin@test.com", Roles: []string{"admin"}}),
+			method:       "/session.Users/ChangeUserPasswordByUuid",
+			req:          &session.ChangeUserPasswordByUuidRequest{Uuid: "moderator-uuid"},
+			expectedCode: codes.PermissionDenied,
+		},
+		{
+			name:         "Удаление своего комментария",
+			ctx:          editor,
+			method:       "/session.Films/RemoveComment",
+			req:          editorComment,
+			expectedCode: codes.OK,
+		},
+		{
+			name:         "Модератор удаляет чужой комментарий",
+			ctx:          moderator,
+			method:       "/session.Films/RemoveComment",
+			req:          editorComment,
+			expectedCode: codes.OK,
+		},
+		{
+			name:         "Удаление своего аккаунта",
+			ctx:          editor,
+			method:       "/session.Users/RemoveUser",
+			req:          &session.RemoveUserRequest{Login: "editor@test.com"},
+			expectedCode: codes.OK,
+		},
+		{
+			name:         "Поддержка удаляет пользователя",
+			ctx:          support,
+			method:       "/session.Users/RemoveUser",
+			req:          &session.RemoveUserRequest{Login: "editor@test.com"},
+			expectedCode: codes.OK,
+		},
+		{
+			name:         "Редактор снимает блокировку входа",
+			ctx:          editor,
+			method:       "/session.Sessions/UnlockLogin",
+			expectedCode: codes.PermissionDenied,
+		},
+		{
+			name:         "Нет пользователя",
+			ctx:          context.Background(),
+			method:       "/session.Films/RemoveFilmByUuid",
+			expectedCode: codes.Unauthenticated,
+		},
+		{
+			name:         "Редактор удаляет фильм",
+			ctx:          editor,
+			method:       "/session.Films/RemoveFilmByUuid",
+			expectedCode: codes.OK,
+		},
+		{
+			name:         "Редактор удаляет пользователя",
+			ctx:          editor,
+			method:       "/session.Users/RemoveUser",
+			req:          &session.RemoveUserRequest{Login: "support@test.com"},
+			expectedCode: codes.PermissionDenied,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := interceptor(tt.ctx, tt.req, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+			assert.Equal(t, tt.expectedCode, status.Code(err))
+		})
+	}
+}
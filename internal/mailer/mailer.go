@@ -0,0 +1,68 @@
+// Package mailer отправляет письма пользователям. Реализации взаимозаменяемы: в проде письма уходят
+// через SMTP, при локальном запуске складываются в файл или в лог
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Message письмо пользователю в виде простого текста
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// Config параметры для выбора реализации через New
+type Config struct {
+	// Kind smtp, file или log
+	Kind string
+	// SMTPAddr адрес SMTP сервера в виде host:port
+	SMTPAddr     string
+	SMTPUsername string
+	SMTPPassword string
+	From         string
+	// FilePath файл, в который пишет file
+	FilePath string
+}
+
+// New создает отправителя писем по конфигурации
+func New(config Config, logf func(template string, args ...interface{})) (Mailer, error) {
+	switch config.Kind {
+	case "smtp":
+		return NewSMTPMailer(config.SMTPAddr, config.SMTPUsername, config.SMTPPassword, config.From)
+	case "file":
+		return NewFileMailer(config.FilePath), nil
+	case "log":
+		return NewLogMailer(logf), nil
+	default:
+		return nil, fmt.Errorf("unknown mailer %q", config.Kind)
+	}
+}
+
+// format собирает письмо в формате RFC 5322. Переводы строк в заголовках запрещены, чтобы через
+// адрес или тему нельзя было дописать свои заголовки
+func format(from string, message Message) ([]byte, error) {
+	for _, header := range []string{from, message.To, message.Subject} {
+		if strings.ContainsAny(header, "\r\n") {
+			return nil, fmt.Errorf("mail header contains line break")
+		}
+	}
+
+	var builder strings.Builder
+	builder.WriteString("From: " + from + "\r\n")
+	builder.WriteString("To: " + message.To + "\r\n")
+	builder.WriteString("Subject: " + message.Subject + "\r\n")
+	builder.WriteString("MIME-Version: 1.0\r\n")
+	builder.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	builder.WriteString("\r\n")
+	builder.WriteString(strings.ReplaceAll(message.Body, "\n", "\r\n"))
+
+	return []byte(builder.String()), nil
+}
@@ -0,0 +1,63 @@
+package mailer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileMailer_Send(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mail.txt")
+	mailer := NewFileMailer(path)
+
+	require.NoError(t, mailer.Send(context.Background(), Message{
+		To:      "test@test.com",
+		Subject: "Восстановление пароля",
+		Body:    "first\nsecond",
+	}))
+	require.NoError(t, mailer.Send(context.Background(), Message{To: "other@test.com", Subject: "Second"}))
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "To: test@test.com\r\n")
+	assert.Contains(t, string(content), "Subject: Восстановление пароля\r\n")
+	assert.Contains(t, string(content), "first\r\nsecond")
+	assert.Contains(t, string(content), "To: other@test.com\r\n", "письма дописываются в конец файла")
+}
+
+func TestFormat_RejectsHeaderInjection(t *testing.T) {
+	_, err := format(localFrom, Message{To: "test@test.com\r\nBcc: victim@test.com", Subject: "subject"})
+	assert.Error(t, err)
+
+	_, err = format(localFrom, Message{To: "test@test.com", Subject: "subject\nBcc: victim@test.com"})
+	assert.Error(t, err)
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr bool
+	}{
+		{name: "SMTP", config: Config{Kind: "smtp", SMTPAddr: "localhost:25", From: "noreply@test.com"}},
+		{name: "SMTP без порта", config: Config{Kind: "smtp", SMTPAddr: "localhost"}, wantErr: true},
+		{name: "Файл", config: Config{Kind: "file", FilePath: "mail.txt"}},
+		{name: "Лог", config: Config{Kind: "log"}},
+		{name: "Неизвестный", config: Config{Kind: "pigeon"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.config, t.Logf)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
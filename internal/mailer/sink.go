@@ -0,0 +1,64 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+const localFrom = "noreply@localhost"
+
+// FileMailer дописывает письма в файл. Нужен для локального запуска, когда SMTP сервера нет
+type FileMailer struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileMailer(path string) *FileMailer {
+	return &FileMailer{
+		path: path,
+	}
+}
+
+func (mailer *FileMailer) Send(_ context.Context, message Message) error {
+	messageBytes, err := format(localFrom, message)
+	if err != nil {
+		return err
+	}
+
+	mailer.mu.Lock()
+	defer mailer.mu.Unlock()
+
+	file, err := os.OpenFile(mailer.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open mail file: %w", err)
+	}
+
+	_, err = fmt.Fprintf(file, "=== %s\r\n%s\r\n\r\n", time.Now().Format(time.RFC3339), messageBytes)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write mail file: %w", err)
+	}
+
+	return nil
+}
+
+// LogMailer пишет письма в лог сервиса
+type LogMailer struct {
+	logf func(template string, args ...interface{})
+}
+
+func NewLogMailer(logf func(template string, args ...interface{})) *LogMailer {
+	return &LogMailer{
+		logf: logf,
+	}
+}
+
+func (mailer *LogMailer) Send(_ context.Context, message Message) error {
+	mailer.logf("mail to %s: %s\n%s", message.To, message.Subject, message.Body)
+	return nil
+}
@@ -0,0 +1,48 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+)
+
+// SMTPMailer отправляет письма через SMTP сервер. Если сервер поддерживает STARTTLS, соединение
+// шифруется до передачи логина и пароля
+type SMTPMailer struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+func NewSMTPMailer(addr, username, password, from string) (*SMTPMailer, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid smtp address: %w", err)
+	}
+
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return &SMTPMailer{
+		addr: addr,
+		auth: auth,
+		from: from,
+	}, nil
+}
+
+func (mailer *SMTPMailer) Send(_ context.Context, message Message) error {
+	messageBytes, err := format(mailer.from, message)
+	if err != nil {
+		return err
+	}
+
+	err = smtp.SendMail(mailer.addr, mailer.auth, mailer.from, []string{message.To}, messageBytes)
+	if err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+
+	return nil
+}
@@ -10,6 +10,7 @@ import (
 type HttpMetrics struct {
 	requestsTotal   *prometheus.CounterVec   // Счетчик общего количества HTTP-запросов
 	requestDuration *prometheus.HistogramVec // Гистограмма длительности HTTP-запросов
+	rateLimited     *prometheus.CounterVec   // Счетчик запросов, отклоненных ограничением частоты
 }
 
 // NewHttpMetrics создает новый экземпляр структуры HttpMetrics с инициализированными метриками
@@ -30,6 +31,13 @@ func NewHttpMetrics() *HttpMetrics {
 			},
 			[]string{"endpoint", "method"},
 		),
+		rateLimited: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "http_requests_rate_limited_total",
+				Help: "Total amount of http requests rejected by rate limits",
+			},
+			[]string{"endpoint", "scope"},
+		),
 	}
 }
 
@@ -37,6 +45,7 @@ func NewHttpMetrics() *HttpMetrics {
 func (httpMetrics *HttpMetrics) Register() {
 	prometheus.MustRegister(httpMetrics.requestsTotal)
 	prometheus.MustRegister(httpMetrics.requestDuration)
+	prometheus.MustRegister(httpMetrics.rateLimited)
 }
 
 // IncRequestsTotal увеличивает счетчик общего количества запросов для указанного эндпоинта, метода и статуса
@@ -44,7 +53,19 @@ func (httpMetrics *HttpMetrics) IncRequestsTotal(endpoint, method string, status
 	httpMetrics.requestsTotal.WithLabelValues(endpoint, method, fmt.Sprintf("%d", status)).Inc()
 }
 
-// IncRequestDuration добавляет значение длительности запроса в гистограмму для указанного эндпоинта и метода
-func (httpMetrics *HttpMetrics) IncRequestDuration(endpoint, method string, duration float64) {
-	httpMetrics.requestDuration.WithLabelValues(endpoint, method).Observe(duration)
+// IncRequestDuration добавляет значение длительности запроса в гистограмму для указанного эндпоинта и метода.
+// Непустой traceId сохраняется как exemplar, чтобы от выброса на графике можно было перейти к трейсу
+func (httpMetrics *HttpMetrics) IncRequestDuration(endpoint, method string, duration float64, traceId string) {
+	observer := httpMetrics.requestDuration.WithLabelValues(endpoint, method)
+	if exemplarObserver, ok := observer.(prometheus.ExemplarObserver); ok && traceId != "" {
+		exemplarObserver.ObserveWithExemplar(duration, prometheus.Labels{"trace_id": traceId})
+		return
+	}
+	observer.Observe(duration)
+}
+
+// IncRateLimited увеличивает счетчик отклоненных ограничением частоты запросов к эндпоинту.
+// scope показывает, по чему считался лимит: ip или user
+func (httpMetrics *HttpMetrics) IncRateLimited(endpoint, scope string) {
+	httpMetrics.rateLimited.WithLabelValues(endpoint, scope).Inc()
 }
@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// LoginMetrics метрики защиты входа от подбора пароля
+type LoginMetrics struct {
+	failuresTotal *prometheus.CounterVec // Счетчик неудачных попыток входа по аккаунтам и IP
+	lockoutsTotal *prometheus.CounterVec // Счетчик блокировок входа по аккаунтам и IP
+	unlocksTotal  prometheus.Counter     // Счетчик ручных разблокировок аккаунтов администратором
+}
+
+// NewLoginMetrics создает новый экземпляр LoginMetrics
+func NewLoginMetrics() *LoginMetrics {
+	return &LoginMetrics{
+		failuresTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "login_failures_total",
+				Help: "Total amount of failed login attempts",
+			},
+			[]string{"scope"},
+		),
+		lockoutsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "login_lockouts_total",
+				Help: "Total amount of temporary login lockouts",
+			},
+			[]string{"scope"},
+		),
+		unlocksTotal: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "login_unlocks_total",
+				Help: "Total amount of accounts unlocked by administrators",
+			},
+		),
+	}
+}
+
+// Register регистрирует метрики в стандартном реестре Prometheus
+func (loginMetrics *LoginMetrics) Register() {
+	prometheus.MustRegister(loginMetrics.failuresTotal)
+	prometheus.MustRegister(loginMetrics.lockoutsTotal)
+	prometheus.MustRegister(loginMetrics.unlocksTotal)
+}
+
+// IncFailuresTotal увеличивает счетчик неудачных попыток входа для scope (account или ip)
+func (loginMetrics *LoginMetrics) IncFailuresTotal(scope string) {
+	loginMetrics.failuresTotal.WithLabelValues(scope).Inc()
+}
+
+// IncLockoutsTotal увеличивает счетчик блокировок входа для scope (account или ip)
+func (loginMetrics *LoginMetrics) IncLockoutsTotal(scope string) {
+	loginMetrics.lockoutsTotal.WithLabelValues(scope).Inc()
+}
+
+// IncUnlocksTotal увеличивает счетчик ручных разблокировок
+func (loginMetrics *LoginMetrics) IncUnlocksTotal() {
+	loginMetrics.unlocksTotal.Inc()
+}
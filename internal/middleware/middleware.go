@@ -4,39 +4,62 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"net/url"
 	"time"
 
+	"github.com/golang-jwt/jwt/v4"
 	"go.uber.org/zap"
 
+	"github.com/SanExpett/diploma/internal/audit"
+	"github.com/SanExpett/diploma/internal/clientip"
 	myerrors "github.com/SanExpett/diploma/internal/errors"
 	"github.com/SanExpett/diploma/internal/handlers"
 	"github.com/SanExpett/diploma/internal/metrics"
+	"github.com/SanExpett/diploma/internal/principal"
+	"github.com/SanExpett/diploma/internal/rbac"
 	reqid "github.com/SanExpett/diploma/internal/requestId"
+	session "github.com/SanExpett/diploma/internal/session/proto"
+	"github.com/SanExpett/diploma/internal/signing"
+	"github.com/SanExpett/diploma/internal/tracing"
 	"github.com/gorilla/mux"
 )
 
 type Middleware struct {
-	metrics  *metrics.HttpMetrics
-	logger   *zap.SugaredLogger
-	serverIP string
+	sessionsClient *session.SessionsClient
+	usersClient    *session.UsersClient
+	keyManager     *signing.KeyManager
+	policy         *rbac.CachedPolicy
+	metrics        *metrics.HttpMetrics
+	logger         *zap.SugaredLogger
+	serverIP       string
+	// frontendOrigin origin фронтенда, которому разрешены запросы с cookie
+	frontendOrigin string
 }
 
-func NewMiddleware(metrics *metrics.HttpMetrics, logger *zap.SugaredLogger, serverIP string) *Middleware {
+func NewMiddleware(sessionsClient *session.SessionsClient, usersClient *session.UsersClient,
+	keyManager *signing.KeyManager, policy *rbac.CachedPolicy, metrics *metrics.HttpMetrics,
+	logger *zap.SugaredLogger, serverIP, frontendOrigin string) *Middleware {
 	return &Middleware{
-		metrics:  metrics,
-		logger:   logger,
-		serverIP: serverIP,
+		sessionsClient: sessionsClient,
+		usersClient:    usersClient,
+		keyManager:     keyManager,
+		policy:         policy,
+		metrics:        metrics,
+		logger:         logger,
+		serverIP:       serverIP,
+		frontendOrigin: frontendOrigin,
 	}
 }
 
 func (middlewareHandlers *Middleware) CorsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		//w.Header().Set("Access-Control-Allow-Origin", fmt.Sprintf("http://%s:8080", middlewareHandlers.serverIP))
-		w.Header().Set("Access-Control-Allow-Origin", fmt.Sprintf("http://localhost:8080"))
+		w.Header().Set("Access-Control-Allow-Origin", middlewareHandlers.frontendOrigin)
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
 		w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
 		w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, "+
-			"Accept-Encoding, X-CSRF-Token, Authorization")
+			"Accept-Encoding, X-CSRF-Token, X-Request-ID, Authorization")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID")
 
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusNoContent)
@@ -47,6 +70,68 @@ func (middlewareHandlers *Middleware) CorsMiddleware(next http.Handler) http.Han
 	})
 }
 
+// CsrfMiddleware защищает запросы, меняющие состояние, от подделки с чужих сайтов: Origin или Referer должен
+// совпадать с фронтендом или самим gateway, а заголовок X-CSRF-Token нести токен, выданный этому браузеру.
+// exemptPaths пути для клиентов без браузера и cookie, например телевизоров при входе по коду
+func (middlewareHandlers *Middleware) CsrfMiddleware(exemptPaths ...string) mux.MiddlewareFunc {
+	exempt := make(map[string]bool, len(exemptPaths))
+	for _, path := range exemptPaths {
+		exempt[path] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isSafeMethod(r.Method) || exempt[r.URL.Path] {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			requestId := r.Context().Value(reqid.ReqIDKey)
+			err := checkOrigin(r, middlewareHandlers.frontendOrigin)
+			if err == nil {
+				err = handlers.VerifyCsrfToken(r, middlewareHandlers.keyManager)
+			}
+			if err != nil {
+				middlewareHandlers.logger.Errorf("[reqid=%s] csrf check failed: %v\n", requestId, err)
+				err = handlers.WriteError(w, r, middlewareHandlers.metrics, err)
+				if err != nil {
+					middlewareHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestId, err)
+				}
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// checkOrigin сверяет источник запроса с фронтендом. Браузеры присылают Origin или хотя бы Referer
+// с запросами, меняющими состояние, поэтому запрос без обоих заголовков пришел не из браузера
+// и защищается только токеном
+func checkOrigin(r *http.Request, frontendOrigin string) error {
+	origin := r.Header.Get("Origin")
+	if origin == "" && r.Referer() != "" {
+		referer, err := url.Parse(r.Referer())
+		if err != nil {
+			return fmt.Errorf("failed to parse referer: %v: %w", err, myerrors.ErrCrossOriginRequest)
+		}
+		origin = referer.Scheme + "://" + referer.Host
+	}
+	if origin == "" || origin == frontendOrigin {
+		return nil
+	}
+
+	originURL, err := url.Parse(origin)
+	if err == nil && originURL.Host == r.Host {
+		return nil
+	}
+	return fmt.Errorf("origin %q: %w", origin, myerrors.ErrCrossOriginRequest)
+}
+
 func (middlewareHandlers *Middleware) PanicMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
@@ -65,31 +150,214 @@ func (middlewareHandlers *Middleware) PanicMiddleware(next http.Handler) http.Ha
 	})
 }
 
+// AuthMiddleware пропускает запрос дальше только с действующей сессией и кладет в контекст
+// principal.Principal пользователя, от имени которого он выполняется
 func (middlewareHandlers *Middleware) AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// here will be our new check function
+		ctx := r.Context()
+		requestId := ctx.Value(reqid.ReqIDKey)
+
+		userPrincipal, err := middlewareHandlers.authenticate(r)
+		if err != nil {
+			middlewareHandlers.logger.Errorf("[reqid=%s] failed to authenticate request: %v\n", requestId, err)
+			err = handlers.WriteError(w, r, middlewareHandlers.metrics, err)
+			if err != nil {
+				middlewareHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestId, err)
+			}
+			return
+		}
+
+		// сервисы восстанавливают пользователя из токена и проверяют его подпись сами
+		accessToken, _ := r.Cookie("access")
+		token, err := middlewareHandlers.forwardedToken(accessToken.Value, userPrincipal)
+		if err != nil {
+			middlewareHandlers.logger.Errorf("[reqid=%s] failed to sign token for services: %v\n", requestId, err)
+			err = handlers.WriteError(w, r, middlewareHandlers.metrics, myerrors.ErrInternalServerError)
+			if err != nil {
+				middlewareHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestId, err)
+			}
+			return
+		}
+		ctx = principal.WithToken(principal.WithPrincipal(ctx, userPrincipal), token)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	}
+}
+
+// RequirePermission пропускает запрос только если у пользователя есть роль с правом permission.
+// Должен вызываться внутри AuthMiddleware, которое кладет пользователя в контекст
+func (middlewareHandlers *Middleware) RequirePermission(permission rbac.Permission,
+	next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		requestId := ctx.Value(reqid.ReqIDKey)
+
+		err := middlewareHandlers.authorize(r, permission)
+		if err != nil {
+			middlewareHandlers.logger.Errorf("[reqid=%s] failed to authorize request: %v\n", requestId, err)
+			err = handlers.WriteError(w, r, middlewareHandlers.metrics, err)
+			if err != nil {
+				middlewareHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestId, err)
+			}
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
+
+// RequireVerifiedEmail пропускает запрос только от пользователя с подтвержденной почтой.
+// Должен вызываться внутри AuthMiddleware, которое кладет пользователя в контекст
+func (middlewareHandlers *Middleware) RequireVerifiedEmail(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		requestId := ctx.Value(reqid.ReqIDKey)
+
+		err := middlewareHandlers.checkEmailVerified(r)
+		if err != nil {
+			middlewareHandlers.logger.Errorf("[reqid=%s] email is not verified: %v\n", requestId, err)
+			err = handlers.WriteError(w, r, middlewareHandlers.metrics, err)
+			if err != nil {
+				middlewareHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestId, err)
+			}
+			return
+		}
 
 		next.ServeHTTP(w, r)
 	}
 }
 
+func (middlewareHandlers *Middleware) checkEmailVerified(r *http.Request) error {
+	userPrincipal, ok := principal.FromContext(r.Context())
+	if !ok {
+		return myerrors.ErrNotAuthorised
+	}
+
+	reqUser := session.GetUserRequest{Login: userPrincipal.Login}
+	user, err := (*middlewareHandlers.usersClient).GetUser(r.Context(), &reqUser)
+	if err != nil {
+		return err
+	}
+	if !user.User.EmailVerified {
+		return myerrors.ErrEmailNotVerified
+	}
+
+	return nil
+}
+
+func (middlewareHandlers *Middleware) authorize(r *http.Request, permission rbac.Permission) error {
+	userPrincipal, ok := principal.FromContext(r.Context())
+	if !ok {
+		return myerrors.ErrNotAuthorised
+	}
+
+	allowed, err := middlewareHandlers.policy.Allowed(r.Context(), userPrincipal.Roles, permission)
+	if err != nil {
+		return fmt.Errorf("failed to load rbac policy: %v: %w", err, myerrors.ErrInternalServerError)
+	}
+	if !allowed {
+		return fmt.Errorf("no permission %s for %s: %w", permission, userPrincipal.Login, myerrors.ErrForbidden)
+	}
+
+	return nil
+}
+
+func (middlewareHandlers *Middleware) authenticate(r *http.Request) (principal.Principal, error) {
+	ctx := r.Context()
+
+	userToken, err := r.Cookie("access")
+	if err != nil {
+		return principal.Principal{}, myerrors.ErrNoActiveSession
+	}
+
+	claims, err := handlers.IsTokenValid(userToken, middlewareHandlers.keyManager)
+	if err != nil {
+		return principal.Principal{}, fmt.Errorf("%v: %w", err, myerrors.ErrNotAuthorised)
+	}
+
+	userPrincipal, err := principal.FromClaims(claims)
+	if err != nil {
+		return principal.Principal{}, err
+	}
+	// токены, выпущенные до появления refresh токенов, сами являются ключом сессии
+	if userPrincipal.SessionId == "" {
+		userPrincipal.SessionId = userToken.Value
+	}
+
+	reqHas := session.HasSessionRequest{Login: userPrincipal.Login, Token: userPrincipal.SessionId}
+	_, err = (*middlewareHandlers.sessionsClient).HasSession(ctx, &reqHas)
+	if err != nil {
+		return principal.Principal{}, fmt.Errorf("%v: %w", err, myerrors.ErrNoActiveSession)
+	}
+
+	reqVersion := session.CheckVersionRequest{Login: userPrincipal.Login, Token: userPrincipal.SessionId,
+		Version: userPrincipal.Version}
+	version, err := (*middlewareHandlers.sessionsClient).CheckVersion(ctx, &reqVersion)
+	if err != nil {
+		return principal.Principal{}, fmt.Errorf("%v: %w", err, myerrors.ErrWrongSessionVersion)
+	}
+	if !version.HasSession {
+		return principal.Principal{}, myerrors.ErrWrongSessionVersion
+	}
+
+	// токены, выпущенные до появления Uuid в claims, не содержат его
+	if userPrincipal.UserUuid == "" {
+		reqUser := session.GetUserRequest{Login: userPrincipal.Login}
+		user, err := (*middlewareHandlers.usersClient).GetUser(ctx, &reqUser)
+		if err != nil {
+			return principal.Principal{}, err
+		}
+		userPrincipal.UserUuid = user.User.Uuid
+	}
+
+	return userPrincipal, nil
+}
+
+// forwardedToken возвращает access токен, который передается сервисам. Сервисы не принимают токены,
+// подписанные общим секретом, и не находят пользователя в токенах без Uuid, поэтому вместо них
+// передается токен с данными userPrincipal, подписанный текущим ключом
+func (middlewareHandlers *Middleware) forwardedToken(accessToken string,
+	userPrincipal principal.Principal) (string, error) {
+	token, _, err := jwt.NewParser().ParseUnverified(accessToken, jwt.MapClaims{})
+	if err != nil {
+		return "", err
+	}
+	kid, _ := token.Header["kid"].(string)
+	uuid, _ := token.Claims.(jwt.MapClaims)["Uuid"].(string)
+	if kid != "" && uuid != "" {
+		return accessToken, nil
+	}
+
+	return handlers.GenerateTokens(middlewareHandlers.keyManager, userPrincipal.Login, userPrincipal.UserUuid,
+		userPrincipal.SessionId, userPrincipal.IsAdmin, userPrincipal.Roles, userPrincipal.Version)
+}
+
 func (middlewareHandlers *Middleware) AccessLogMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		reqId := reqid.GenerateRequestID()
+		// идентификатор от балансировщика или клиента сохраняется, чтобы по нему можно было найти запрос в журналах
+		reqId := reqid.RequestIDOrGenerate(r.Header.Get(reqid.HeaderName))
+		w.Header().Set(reqid.HeaderName, reqId)
 		ctx := r.Context()
 		ctx = context.WithValue(ctx, reqid.ReqIDKey, reqId)
+		ctx = audit.WithRequestInfo(ctx, audit.RequestInfo{
+			Ip:        clientip.FromRequest(r),
+			UserAgent: r.UserAgent(),
+			RequestId: reqId,
+		})
+		tracing.SetRequestId(ctx, reqId)
+		traceId := tracing.TraceId(ctx)
 		middlewareHandlers.logger.Info("request accessLog", "path", r.URL.Path)
 		start := time.Now()
 		next.ServeHTTP(w, r.WithContext(ctx))
-		middlewareHandlers.logger.Info(fmt.Sprintf("requestProcessed reqid[%s], method[%s], URLPath[%s], "+
-			"time = [%s];",
-			reqId, r.Method, r.URL.Path, time.Since(start)))
+		middlewareHandlers.logger.Info(fmt.Sprintf("requestProcessed reqid[%s], traceid[%s], method[%s], "+
+			"URLPath[%s], time = [%s];",
+			reqId, traceId, r.Method, r.URL.Path, time.Since(start)))
 
 		pathTemplate, err := mux.CurrentRoute(r).GetPathTemplate()
 		if err != nil {
 			middlewareHandlers.logger.Errorf("unable to get path template: %v", err)
 		}
 
-		middlewareHandlers.metrics.IncRequestDuration(pathTemplate, r.Method, float64(time.Since(start).Milliseconds()))
+		middlewareHandlers.metrics.IncRequestDuration(pathTemplate, r.Method, float64(time.Since(start).Milliseconds()),
+			traceId)
 	})
 }
@@ -0,0 +1,742 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/SanExpett/diploma/internal/cookies"
+	"github.com/SanExpett/diploma/internal/domain"
+	"github.com/SanExpett/diploma/internal/handlers"
+	"github.com/SanExpett/diploma/internal/handlers/mocks"
+	"github.com/SanExpett/diploma/internal/metrics"
+	"github.com/SanExpett/diploma/internal/principal"
+	"github.com/SanExpett/diploma/internal/rbac"
+	session "github.com/SanExpett/diploma/internal/session/proto"
+	"github.com/SanExpett/diploma/internal/signing"
+)
+
+const frontendOrigin = "http://localhost:8080"
+
+func TestMiddleware_AuthMiddleware(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUsersClient := mocks.NewMockUsersClient(ctrl)
+	mockSessionsClient := mocks.NewMockSessionsClient(ctrl)
+
+	var usersClient session.UsersClient = mockUsersClient
+	var sessionsClient session.SessionsClient = mockSessionsClient
+
+	keyManager, err := signing.NewKeyManager(signing.AlgorithmEdDSA, time.Hour, time.Hour)
+	assert.NoError(t, err)
+
+	policy := rbac.NewCachedPolicy(rbac.NewUsersClientLoader(&usersClient), time.Minute)
+	middleware := NewMiddleware(&sessionsClient, &usersClient, keyManager, policy, metrics.NewHttpMetrics(),
+		zap.NewNop().Sugar(), "", frontendOrigin)
+
+	token, err := handlers.GenerateTokens(keyManager, "test@test.com", "test-uuid", "test-session", false, nil, 1)
+	assert.NoError(t, err)
+	tokenWithoutUuid, err := handlers.GenerateTokens(keyManager, "test@test.com", "", "test-session", false, nil, 1)
+	assert.NoError(t, err)
+	tokenWithoutSession, err := handlers.GenerateTokens(keyManager, "test@test.com", "test-uuid", "", false, nil, 1)
+	assert.NoError(t, err)
+	keyManager.AcceptLegacySecret("legacy-secret")
+	legacyToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"Login":   "test@test.com",
+		"IsAdmin": false,
+		"Version": 1,
+	}).SignedString([]byte("legacy-secret"))
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name              string
+		token             string
+		setupMocks        func()
+		expectedStatus    int
+		expectedPrincipal *principal.Principal
+		resigned          bool
+	}{
+		{
+			name:           "Нет cookie",
+			setupMocks:     func() {},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Невалидный токен",
+			token:          "invalid-token",
+			setupMocks:     func() {},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:  "Нет сессии",
+			token: token,
+			setupMocks: func() {
+				mockSessionsClient.EXPECT().HasSession(gomock.Any(), &session.HasSessionRequest{
+					Login: "test@test.com",
+					Token: "test-session",
+				}).Return(nil, errors.New("no such session"))
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:  "Другая версия сессии",
+			token: token,
+			setupMocks: func() {
+				mockSessionsClient.EXPECT().HasSession(gomock.Any(), gomock.Any()).
+					Return(&session.HasSessionResponse{}, nil)
+				mockSessionsClient.EXPECT().CheckVersion(gomock.Any(), &session.CheckVersionRequest{
+					Login:   "test@test.com",
+					Token:   "test-session",
+					Version: 1,
+				}).Return(&session.CheckVersionResponse{HasSession: false}, nil)
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:  "Успешная аутентификация",
+			token: token,
+			setupMocks: func() {
+				mockSessionsClient.EXPECT().HasSession(gomock.Any(), gomock.Any()).
+					Return(&session.HasSessionResponse{}, nil)
+				mockSessionsClient.EXPECT().CheckVersion(gomock.Any(), gomock.Any()).
+					Return(&session.CheckVersionResponse{HasSession: true}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedPrincipal: &principal.Principal{
+				UserUuid:  "test-uuid",
+				Login:     "test@test.com",
+				SessionId: "test-session",
+				Version:   1,
+			},
+		},
+		{
+			name:  "Токен без uuid",
+			token: tokenWithoutUuid,
+			setupMocks: func() {
+				mockSessionsClient.EXPECT().HasSession(gomock.Any(), gomock.Any()).
+					Return(&session.HasSessionResponse{}, nil)
+				mockSessionsClient.EXPECT().CheckVersion(gomock.Any(), gomock.Any()).
+					Return(&session.CheckVersionResponse{HasSession: true}, nil)
+				mockUsersClient.EXPECT().GetUser(gomock.Any(), &session.GetUserRequest{
+					Login: "test@test.com",
+				}).Return(&session.GetUserResponse{User: &session.User{Uuid: "test-uuid"}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedPrincipal: &principal.Principal{
+				UserUuid:  "test-uuid",
+				Login:     "test@test.com",
+				SessionId: "test-session",
+				Version:   1,
+			},
+			resigned: true,
+		},
+		{
+			name:  "Токен без идентификатора сессии",
+			token: tokenWithoutSession,
+			setupMocks: func() {
+				mockSessionsClient.EXPECT().HasSession(gomock.Any(), &session.HasSessionRequest{
+					Login: "test@test.com",
+					Token: tokenWithoutSession,
+				}).Return(&session.HasSessionResponse{}, nil)
+				mockSessionsClient.EXPECT().CheckVersion(gomock.Any(), gomock.Any()).
+					Return(&session.CheckVersionResponse{HasSession: true}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedPrincipal: &principal.Principal{
+				UserUuid:  "test-uuid",
+				Login:     "test@test.com",
+				SessionId: tokenWithoutSession,
+				Version:   1,
+			},
+		},
+		{
+			name:  "Токен, подписанный общим секретом",
+			token: legacyToken,
+			setupMocks: func() {
+				mockSessionsClient.EXPECT().HasSession(gomock.Any(), &session.HasSessionRequest{
+					Login: "test@test.com",
+					Token: legacyToken,
+				}).Return(&session.HasSessionResponse{}, nil)
+				mockSessionsClient.EXPECT().CheckVersion(gomock.Any(), gomock.Any()).
+					Return(&session.CheckVersionResponse{HasSession: true}, nil)
+				mockUsersClient.EXPECT().GetUser(gomock.Any(), gomock.Any()).
+					Return(&session.GetUserResponse{User: &session.User{Uuid: "test-uuid"}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedPrincipal: &principal.Principal{
+				UserUuid:  "test-uuid",
+				Login:     "test@test.com",
+				SessionId: legacyToken,
+				Version:   1,
+			},
+			resigned: true,
+		},
+	}
+
+	// сервисы принимают только токены, подписанные ключом с kid
+	servicesParser := jwt.NewParser(jwt.WithValidMethods([]string{jwt.SigningMethodEdDSA.Alg()}))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMocks()
+
+			var gotPrincipal *principal.Principal
+			next := func(w http.ResponseWriter, r *http.Request) {
+				userPrincipal, ok := principal.FromContext(r.Context())
+				assert.True(t, ok)
+				gotPrincipal = &userPrincipal
+
+				token, ok := principal.TokenFromContext(r.Context())
+				assert.True(t, ok)
+				if !tt.resigned {
+					assert.Equal(t, tt.token, token)
+					return
+				}
+				claims := jwt.MapClaims{}
+				_, err := servicesParser.ParseWithClaims(token, claims, keyManager.Keyfunc)
+				require.NoError(t, err)
+				forwarded, err := principal.FromClaims(claims)
+				require.NoError(t, err)
+				assert.Equal(t, userPrincipal, forwarded)
+				w.WriteHeader(http.StatusOK)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/api/films/put_favorite", nil)
+			if tt.token != "" {
+				req.AddCookie(&http.Cookie{Name: "access", Value: tt.token})
+			}
+			w := httptest.NewRecorder()
+
+			middleware.AuthMiddleware(next)(w, req)
+
+			assert.Equal(t, tt.expectedPrincipal, gotPrincipal)
+			if tt.expectedPrincipal == nil {
+				var response handlers.ErrorResponse
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, tt.expectedStatus, response.Status)
+			}
+		})
+	}
+}
+
+func TestMiddleware_RequirePermission(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUsersClient := mocks.NewMockUsersClient(ctrl)
+	mockSessionsClient := mocks.NewMockSessionsClient(ctrl)
+
+	var usersClient session.UsersClient = mockUsersClient
+	var sessionsClient session.SessionsClient = mockSessionsClient
+
+	mockUsersClient.EXPECT().GetRolePermissions(gomock.Any(), gomock.Any()).Return(
+		&session.GetRolePermissionsResponse{
+			Roles: []*session.RolePermissions{
+				{Role: "admin", Permissions: []string{"films.manage", "users.remove"}},
+				{Role: "content_editor", Permissions: []string{"films.manage"}},
+			},
+		}, nil)
+
+	policy := rbac.NewCachedPolicy(rbac.NewUsersClientLoader(&usersClient), time.Minute)
+	middleware := NewMiddleware(&sessionsClient, &usersClient, nil, policy, metrics.NewHttpMetrics(),
+		zap.NewNop().Sugar(), "", frontendOrigin)
+
+	tests := []struct {
+		name           string
+		principal      *principal.Principal
+		permission     rbac.Permission
+		expectedCalled bool
+		expectedStatus int
+	}{
+		{
+			name:           "Нет пользователя",
+			permission:     rbac.PermissionFilmsManage,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Редактор добавляет фильм",
+			principal:      &principal.Principal{Login: "editor@test.com", Roles: []string{"content_editor"}},
+			permission:     rbac.PermissionFilmsManage,
+			expectedCalled: true,
+		},
+		{
+			name:           "Редактор удаляет пользователя",
+			principal:      &principal.Principal{Login: "editor@test.com", Roles: []string{"content_editor"}},
+			permission:     rbac.PermissionUsersRemove,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "Пользователь без ролей",
+			principal:      &principal.Principal{Login: "user@test.com"},
+			permission:     rbac.PermissionFilmsManage,
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			next := func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				w.WriteHeader(http.StatusOK)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/api/films/add", nil)
+			if tt.principal != nil {
+				req = req.WithContext(principal.WithPrincipal(req.Context(), *tt.principal))
+			}
+			w := httptest.NewRecorder()
+
+			middleware.RequirePermission(tt.permission, next)(w, req)
+
+			assert.Equal(t, tt.expectedCalled, called)
+			if !tt.expectedCalled {
+				var response handlers.ErrorResponse
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, tt.expectedStatus, response.Status)
+			}
+		})
+	}
+}
+
+func TestMiddleware_RequireVerifiedEmail(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUsersClient := mocks.NewMockUsersClient(ctrl)
+	mockSessionsClient := mocks.NewMockSessionsClient(ctrl)
+
+	var usersClient session.UsersClient = mockUsersClient
+	var sessionsClient session.SessionsClient = mockSessionsClient
+
+	middleware := NewMiddleware(&sessionsClient, &usersClient, nil, nil, metrics.NewHttpMetrics(),
+		zap.NewNop().Sugar(), "", frontendOrigin)
+
+	tests := []struct {
+		name           string
+		emailVerified  bool
+		expectedCalled bool
+		expectedCode   string
+	}{
+		{
+			name:           "Почта подтверждена",
+			emailVerified:  true,
+			expectedCalled: true,
+		},
+		{
+			name:         "Почта не подтверждена",
+			expectedCode: "email_not_verified",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUsersClient.EXPECT().GetUser(gomock.Any(), &session.GetUserRequest{Login: "user@test.com"}).
+				Return(&session.GetUserResponse{User: &session.User{EmailVerified: tt.emailVerified}}, nil)
+
+			called := false
+			next := func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				w.WriteHeader(http.StatusOK)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/api/films/comments/add", nil)
+			req = req.WithContext(principal.WithPrincipal(req.Context(), principal.Principal{Login: "user@test.com"}))
+			w := httptest.NewRecorder()
+
+			middleware.RequireVerifiedEmail(next)(w, req)
+
+			assert.Equal(t, tt.expectedCalled, called)
+			if !tt.expectedCalled {
+				var response handlers.ErrorResponse
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, http.StatusForbidden, response.Status)
+				assert.Equal(t, tt.expectedCode, response.Code)
+			}
+		})
+	}
+}
+
+func TestMiddleware_CsrfMiddleware(t *testing.T) {
+	keyManager, err := signing.NewKeyManager(signing.AlgorithmEdDSA, time.Hour, time.Hour)
+	assert.NoError(t, err)
+
+	middleware := NewMiddleware(nil, nil, keyManager, nil, metrics.NewHttpMetrics(), zap.NewNop().Sugar(), "",
+		frontendOrigin)
+	authHandlers := handlers.NewAuthPageHandlers(nil, nil, keyManager, cookies.NewFactory(cookies.Config{}),
+		metrics.NewHttpMetrics(), zap.NewNop().Sugar())
+	router := mux.NewRouter()
+	router.HandleFunc("/api/auth/csrf", authHandlers.CsrfToken)
+
+	accessToken, err := handlers.GenerateTokens(keyManager, "test@test.com", "test-uuid", "test-session", false, nil, 1)
+	assert.NoError(t, err)
+	otherAccessToken, err := handlers.GenerateTokens(keyManager, "test@test.com", "test-uuid", "other-session", false,
+		nil, 1)
+	assert.NoError(t, err)
+
+	// issueToken получает токен у обработчика /auth/csrf так же, как фронтенд
+	issueToken := func(access string) (string, *http.Cookie) {
+		req := httptest.NewRequest(http.MethodGet, "/api/auth/csrf", nil)
+		if access != "" {
+			req.AddCookie(&http.Cookie{Name: "access", Value: access})
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response domain.CsrfTokenResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		cookies := w.Result().Cookies()
+		assert.Len(t, cookies, 1)
+		return response.Token, cookies[0]
+	}
+
+	anonymousToken, nonceCookie := issueToken("")
+	sessionToken, sessionNonceCookie := issueToken(accessToken)
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		origin         string
+		referer        string
+		token          string
+		cookies        []*http.Cookie
+		expectedCalled bool
+		expectedCode   string
+	}{
+		{
+			name:           "GET без токена",
+			method:         http.MethodGet,
+			path:           "/api/films/all",
+			expectedCalled: true,
+		},
+		{
+			name:           "Путь без проверки",
+			method:         http.MethodPost,
+			path:           "/api/auth/device/code",
+			expectedCalled: true,
+		},
+		{
+			name:         "Нет токена",
+			method:       http.MethodPost,
+			path:         "/api/auth/login",
+			origin:       frontendOrigin,
+			cookies:      []*http.Cookie{nonceCookie},
+			expectedCode: "invalid_csrf_token",
+		},
+		{
+			name:         "Чужой origin",
+			method:       http.MethodPost,
+			path:         "/api/auth/login",
+			origin:       "https://evil.test",
+			token:        anonymousToken,
+			cookies:      []*http.Cookie{nonceCookie},
+			expectedCode: "cross_origin_request",
+		},
+		{
+			name:         "Чужой referer",
+			method:       http.MethodPost,
+			path:         "/api/auth/login",
+			referer:      "https://evil.test/page",
+			token:        anonymousToken,
+			cookies:      []*http.Cookie{nonceCookie},
+			expectedCode: "cross_origin_request",
+		},
+		{
+			name:         "Токен другого браузера",
+			method:       http.MethodPost,
+			path:         "/api/auth/login",
+			origin:       frontendOrigin,
+			token:        anonymousToken,
+			cookies:      []*http.Cookie{{Name: handlers.CsrfCookieName, Value: "other"}},
+			expectedCode: "invalid_csrf_token",
+		},
+		{
+			name:   "Токен другой сессии",
+			method: http.MethodPost,
+			path:   "/api/auth/logout",
+			origin: frontendOrigin,
+			token:  sessionToken,
+			cookies: []*http.Cookie{sessionNonceCookie,
+				{Name: "access", Value: otherAccessToken}},
+			expectedCode: "invalid_csrf_token",
+		},
+		{
+			name:           "Токен до входа",
+			method:         http.MethodPost,
+			path:           "/api/auth/login",
+			origin:         frontendOrigin,
+			token:          anonymousToken,
+			cookies:        []*http.Cookie{nonceCookie},
+			expectedCalled: true,
+		},
+		{
+			name:    "Токен сессии",
+			method:  http.MethodPost,
+			path:    "/api/auth/logout",
+			referer: frontendOrigin + "/profile",
+			token:   sessionToken,
+			cookies: []*http.Cookie{sessionNonceCookie,
+				{Name: "access", Value: accessToken}},
+			expectedCalled: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.referer != "" {
+				req.Header.Set("Referer", tt.referer)
+			}
+			if tt.token != "" {
+				req.Header.Set(handlers.CsrfHeaderName, tt.token)
+			}
+			for _, cookie := range tt.cookies {
+				req.AddCookie(cookie)
+			}
+			w := httptest.NewRecorder()
+
+			middleware.CsrfMiddleware("/api/auth/device/code")(next).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCalled, called)
+			if !tt.expectedCalled {
+				var response handlers.ErrorResponse
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, http.StatusForbidden, response.Status)
+				assert.Equal(t, tt.expectedCode, response.Code)
+			}
+		})
+	}
+}
+
+func TestSecurityHeadersMiddleware(t *testing.T) {
+	tests := []struct {
+		name         string
+		environment  string
+		expectedHSTS string
+		expectedErr  bool
+	}{
+		{
+			name:        "Разработка",
+			environment: EnvironmentDevelopment,
+		},
+		{
+			name:         "Production",
+			environment:  EnvironmentProduction,
+			expectedHSTS: "max-age=31536000; includeSubDomains",
+		},
+		{
+			name:        "Неизвестное окружение",
+			environment: "staging",
+			expectedErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers, err := SecurityHeadersProfile(tt.environment)
+			if tt.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusForbidden)
+			})
+			w := httptest.NewRecorder()
+
+			SecurityHeadersMiddleware(headers)(next).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+			assert.Equal(t, "DENY", w.Header().Get("X-Frame-Options"))
+			assert.Contains(t, w.Header().Get("Content-Security-Policy"), "frame-ancestors 'none'")
+			assert.NotEmpty(t, w.Header().Get("Referrer-Policy"))
+			assert.Equal(t, tt.expectedHSTS, w.Header().Get("Strict-Transport-Security"))
+			_, hasHSTS := w.Header()["Strict-Transport-Security"]
+			assert.Equal(t, tt.expectedHSTS != "", hasHSTS)
+		})
+	}
+}
+
+func TestMiddleware_RateLimitMiddleware(t *testing.T) {
+	keyManager, err := signing.NewKeyManager(signing.AlgorithmEdDSA, time.Hour, time.Hour)
+	assert.NoError(t, err)
+
+	middleware := NewMiddleware(nil, nil, keyManager, nil, metrics.NewHttpMetrics(), zap.NewNop().Sugar(), "",
+		frontendOrigin)
+	limiter := NewRateLimiter(map[string]RateLimit{
+		"/api/auth/login":         {Requests: 2, Period: time.Minute, Burst: 2, Key: RateLimitByIP},
+		"/api/films/comments/add": {Requests: 1, Period: time.Minute, Burst: 1, Key: RateLimitByUser},
+	}, nil)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	limiter.now = func() time.Time { return now }
+
+	router := mux.NewRouter()
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	router.HandleFunc("/api/auth/login", ok)
+	router.HandleFunc("/api/films/comments/add", ok)
+	router.HandleFunc("/api/films/all", ok)
+	router.Use(middleware.RateLimitMiddleware(limiter))
+
+	firstUser, err := handlers.GenerateTokens(keyManager, "first@test.com", "first", "session", false, nil, 1)
+	assert.NoError(t, err)
+	secondUser, err := handlers.GenerateTokens(keyManager, "second@test.com", "second", "session", false, nil, 1)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name               string
+		method             string
+		path               string
+		access             string
+		advance            time.Duration
+		expectedStatus     int
+		expectedRemaining  string
+		expectedRetryAfter string
+	}{
+		{
+			name:              "Первый запрос",
+			path:              "/api/auth/login",
+			expectedStatus:    http.StatusOK,
+			expectedRemaining: "1",
+		},
+		{
+			name:              "Запрос в пределах burst",
+			path:              "/api/auth/login",
+			expectedStatus:    http.StatusOK,
+			expectedRemaining: "0",
+		},
+		{
+			name:               "Лимит исчерпан",
+			path:               "/api/auth/login",
+			expectedStatus:     http.StatusTooManyRequests,
+			expectedRemaining:  "0",
+			expectedRetryAfter: "30",
+		},
+		{
+			name:           "Preflight не расходует лимит",
+			method:         http.MethodOptions,
+			path:           "/api/auth/login",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Маршрут без лимита",
+			path:           "/api/films/all",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:              "Лимит восстановился",
+			path:              "/api/auth/login",
+			advance:           30 * time.Second,
+			expectedStatus:    http.StatusOK,
+			expectedRemaining: "0",
+		},
+		{
+			name:              "Лимит пользователя",
+			path:              "/api/films/comments/add",
+			access:            firstUser,
+			expectedStatus:    http.StatusOK,
+			expectedRemaining: "0",
+		},
+		{
+			name:               "Лимит пользователя исчерпан",
+			path:               "/api/films/comments/add",
+			access:             firstUser,
+			expectedStatus:     http.StatusTooManyRequests,
+			expectedRemaining:  "0",
+			expectedRetryAfter: "60",
+		},
+		{
+			name:              "Другой пользователь с того же адреса",
+			path:              "/api/films/comments/add",
+			access:            secondUser,
+			expectedStatus:    http.StatusOK,
+			expectedRemaining: "0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = now.Add(tt.advance)
+			method := tt.method
+			if method == "" {
+				method = http.MethodPost
+			}
+
+			req := httptest.NewRequest(method, tt.path, nil)
+			if tt.access != "" {
+				req.AddCookie(&http.Cookie{Name: "access", Value: tt.access})
+			}
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedRemaining, w.Header().Get("RateLimit-Remaining"))
+			assert.Equal(t, tt.expectedRetryAfter, w.Header().Get("Retry-After"))
+			if tt.expectedStatus == http.StatusOK {
+				assert.Empty(t, w.Body.String())
+				return
+			}
+
+			var response handlers.ErrorResponse
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tt.expectedStatus, response.Status)
+			assert.Equal(t, "rate_limited", response.Code)
+		})
+	}
+}
+
+func TestRateLimiter_Redis(t *testing.T) {
+	server := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer redisClient.Close()
+
+	limit := RateLimit{Requests: 3, Period: time.Minute, Burst: 3, Key: RateLimitByIP}
+	limits := map[string]RateLimit{"/api/auth/login": limit}
+	// две реплики gateway с общим Redis
+	first := NewRateLimiter(limits, redisClient)
+	second := NewRateLimiter(limits, redisClient)
+
+	for i := 0; i < 3; i++ {
+		result, err := first.allow(context.Background(), "login:ip:192.0.2.1", limit)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 2-i, result.Remaining)
+	}
+
+	result, err := second.allow(context.Background(), "login:ip:192.0.2.1", limit)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Positive(t, result.RetryAfter)
+	assert.True(t, server.Exists(rateLimitPrefix+"login:ip:192.0.2.1"))
+
+	server.Close()
+
+	result, err = second.allow(context.Background(), "login:ip:192.0.2.1", limit)
+	assert.Error(t, err)
+	assert.True(t, result.Allowed, "без Redis лимит считается в памяти реплики")
+	assert.Equal(t, 2, result.Remaining)
+}
@@ -0,0 +1,337 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/SanExpett/diploma/internal/clientip"
+	myerrors "github.com/SanExpett/diploma/internal/errors"
+	"github.com/SanExpett/diploma/internal/handlers"
+	"github.com/SanExpett/diploma/internal/principal"
+	reqid "github.com/SanExpett/diploma/internal/requestId"
+)
+
+const (
+	RateLimitByIP   = "ip"
+	RateLimitByUser = "user"
+
+	rateLimitPrefix        = "rate_limit:"
+	rateLimitSweepInterval = time.Minute
+)
+
+// RateLimit лимит запросов к маршруту: в среднем Requests запросов за Period, из них не больше Burst подряд.
+// Key выбирает, кому принадлежит лимит: адресу клиента или пользователю. Запросы без входа
+// к маршруту с лимитом на пользователя считаются по адресу
+type RateLimit struct {
+	Requests int
+	Period   time.Duration
+	Burst    int
+	Key      string
+}
+
+// interval промежуток между запросами при равномерной нагрузке
+func (limit RateLimit) interval() time.Duration {
+	return limit.Period / time.Duration(limit.Requests)
+}
+
+// capacity насколько TAT может опережать текущее время, чтобы запрос еще был разрешен
+func (limit RateLimit) capacity() time.Duration {
+	return limit.interval() * time.Duration(limit.Burst)
+}
+
+// DefaultRateLimits лимиты маршрутов, которые чаще всего перегружают перебором и спамом
+func DefaultRateLimits() map[string]RateLimit {
+	login := RateLimit{Requests: 10, Period: time.Minute, Burst: 5, Key: RateLimitByIP}
+	search := RateLimit{Requests: 60, Period: time.Minute, Burst: 20, Key: RateLimitByIP}
+	return map[string]RateLimit{
+		"/api/auth/login":           login,
+		"/api/auth/login/2fa":       login,
+		"/api/auth/signup":          {Requests: 10, Period: time.Hour, Burst: 3, Key: RateLimitByIP},
+		"/api/auth/password/forgot": {Requests: 5, Period: time.Hour, Burst: 2, Key: RateLimitByIP},
+		"/api/films/find/short":     search,
+		"/api/films/find/long":      search,
+		"/api/films/comments/add":   {Requests: 10, Period: time.Minute, Burst: 3, Key: RateLimitByUser},
+	}
+}
+
+type rateLimitConfig struct {
+	Requests int    `json:"requests"`
+	Period   string `json:"period"`
+	Burst    int    `json:"burst,omitempty"`
+	Key      string `json:"key"`
+}
+
+// LoadRateLimits читает лимиты из JSON файла: шаблон маршрута mux и лимит, период в формате time.Duration.
+// Пустой путь означает лимиты по умолчанию
+func LoadRateLimits(path string) (map[string]RateLimit, error) {
+	if path == "" {
+		return DefaultRateLimits(), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rate limits: %w", err)
+	}
+
+	var configs map[string]rateLimitConfig
+	err = json.Unmarshal(data, &configs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse rate limits: %w", err)
+	}
+
+	limits := make(map[string]RateLimit, len(configs))
+	for route, config := range configs {
+		period, err := time.ParseDuration(config.Period)
+		if err != nil || period <= 0 || config.Requests <= 0 {
+			return nil, fmt.Errorf("rate limit %q: requests and period must be positive", route)
+		}
+		if config.Key != RateLimitByIP && config.Key != RateLimitByUser {
+			return nil, fmt.Errorf("rate limit %q: key must be %s or %s", route, RateLimitByIP, RateLimitByUser)
+		}
+		// без burst запросы разрешены только равномерно
+		burst := config.Burst
+		if burst <= 0 {
+			burst = 1
+		}
+		limits[route] = RateLimit{Requests: config.Requests, Period: period, Burst: burst, Key: config.Key}
+	}
+
+	return limits, nil
+}
+
+// rateLimitStore хранит TAT алгоритма GCRA: время, к которому клиент израсходует лимит при равномерной нагрузке.
+// take разрешает запрос и возвращает новый TAT или отклоняет его и возвращает текущий
+type rateLimitStore interface {
+	take(ctx context.Context, key string, limit RateLimit, now time.Time) (bool, time.Time, error)
+}
+
+// TAT читается и сдвигается одним скриптом, чтобы параллельные запросы через разные реплики gateway
+// не расходовали лимит дважды. Ключ живет, пока TAT не наступит, после этого лимит снова полон
+var takeRateLimitScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local interval = tonumber(ARGV[2])
+local capacity = tonumber(ARGV[3])
+local tat = tonumber(redis.call('GET', KEYS[1]))
+if not tat or tat < now then
+	tat = now
+end
+local newTat = tat + interval
+if now < newTat - capacity then
+	return {0, tat}
+end
+redis.call('SET', KEYS[1], newTat, 'PX', newTat - now)
+return {1, newTat}
+`)
+
+type redisRateLimitStore struct {
+	redisClient redis.UniversalClient
+}
+
+func (store *redisRateLimitStore) take(ctx context.Context, key string, limit RateLimit,
+	now time.Time) (bool, time.Time, error) {
+	result, err := takeRateLimitScript.Run(ctx, store.redisClient, []string{rateLimitPrefix + key},
+		now.UnixMilli(), limit.interval().Milliseconds(), limit.capacity().Milliseconds()).Int64Slice()
+	if err != nil {
+		return false, time.Time{}, err
+	}
+	if len(result) != 2 {
+		return false, time.Time{}, fmt.Errorf("unexpected rate limit script result %v", result)
+	}
+
+	return result[0] == 1, time.UnixMilli(result[1]), nil
+}
+
+// memoryRateLimitStore хранит лимиты в памяти реплики. Истекшие записи удаляются раз в rateLimitSweepInterval
+type memoryRateLimitStore struct {
+	mu        sync.Mutex
+	tats      map[string]time.Time
+	nextSweep time.Time
+}
+
+func newMemoryRateLimitStore() *memoryRateLimitStore {
+	return &memoryRateLimitStore{tats: make(map[string]time.Time)}
+}
+
+func (store *memoryRateLimitStore) take(_ context.Context, key string, limit RateLimit,
+	now time.Time) (bool, time.Time, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if now.After(store.nextSweep) {
+		for storedKey, tat := range store.tats {
+			if !tat.After(now) {
+				delete(store.tats, storedKey)
+			}
+		}
+		store.nextSweep = now.Add(rateLimitSweepInterval)
+	}
+
+	tat, ok := store.tats[key]
+	if !ok || tat.Before(now) {
+		tat = now
+	}
+	newTat := tat.Add(limit.interval())
+	if now.Before(newTat.Add(-limit.capacity())) {
+		return false, tat, nil
+	}
+
+	store.tats[key] = newTat
+	return true, newTat, nil
+}
+
+// rateLimitResult решение по запросу и значения заголовков RateLimit-*
+type rateLimitResult struct {
+	Allowed    bool
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+func newRateLimitResult(limit RateLimit, allowed bool, tat time.Time, now time.Time) rateLimitResult {
+	result := rateLimitResult{Allowed: allowed, Reset: tat.Sub(now)}
+	if !allowed {
+		result.RetryAfter = tat.Add(limit.interval() - limit.capacity()).Sub(now)
+		return result
+	}
+	result.Remaining = int((limit.capacity() - result.Reset) / limit.interval())
+	return result
+}
+
+// RateLimiter ограничивает частоту запросов к маршрутам по алгоритму GCRA. Лимиты хранятся в Redis, чтобы
+// действовать на все реплики gateway. Пока Redis недоступен, каждая реплика считает лимиты в своей памяти
+type RateLimiter struct {
+	mu     sync.RWMutex
+	limits map[string]RateLimit
+	redis  rateLimitStore
+	memory *memoryRateLimitStore
+	now    func() time.Time
+}
+
+// NewRateLimiter создает ограничитель. Без redisClient лимиты считаются только в памяти
+func NewRateLimiter(limits map[string]RateLimit, redisClient redis.UniversalClient) *RateLimiter {
+	limiter := &RateLimiter{
+		limits: limits,
+		memory: newMemoryRateLimitStore(),
+		now:    time.Now,
+	}
+	if redisClient != nil {
+		limiter.redis = &redisRateLimitStore{redisClient: redisClient}
+	}
+	return limiter
+}
+
+// SetLimits заменяет лимиты маршрутов, уже израсходованные лимиты сохраняются
+func (limiter *RateLimiter) SetLimits(limits map[string]RateLimit) {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+	limiter.limits = limits
+}
+
+func (limiter *RateLimiter) limit(pathTemplate string) (RateLimit, bool) {
+	limiter.mu.RLock()
+	defer limiter.mu.RUnlock()
+	limit, ok := limiter.limits[pathTemplate]
+	return limit, ok
+}
+
+// allow расходует лимит key. Ошибка Redis возвращается вместе с решением, принятым по памяти реплики
+func (limiter *RateLimiter) allow(ctx context.Context, key string, limit RateLimit) (rateLimitResult, error) {
+	now := limiter.now()
+	var redisErr error
+	if limiter.redis != nil {
+		allowed, tat, err := limiter.redis.take(ctx, key, limit, now)
+		if err == nil {
+			return newRateLimitResult(limit, allowed, tat, now), nil
+		}
+		redisErr = err
+	}
+
+	allowed, tat, _ := limiter.memory.take(ctx, key, limit, now)
+	return newRateLimitResult(limit, allowed, tat, now), redisErr
+}
+
+// RateLimitMiddleware ограничивает частоту запросов к маршрутам, для которых в limiter задан лимит.
+// Ответы несут заголовки RateLimit-*, отклоненные запросы получают 429 с Retry-After
+func (middlewareHandlers *Middleware) RateLimitMiddleware(limiter *RateLimiter) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := mux.CurrentRoute(r)
+			if route == nil || r.Method == http.MethodOptions {
+				next.ServeHTTP(w, r)
+				return
+			}
+			pathTemplate, err := route.GetPathTemplate()
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			limit, ok := limiter.limit(pathTemplate)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			requestId := r.Context().Value(reqid.ReqIDKey)
+			scope, subject := middlewareHandlers.rateLimitSubject(r, limit)
+			result, err := limiter.allow(r.Context(), pathTemplate+":"+scope+":"+subject, limit)
+			if err != nil {
+				middlewareHandlers.logger.Errorf("[reqid=%s] rate limit store is unavailable, using memory: %v\n",
+					requestId, err)
+			}
+
+			w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d;burst=%d", limit.Requests,
+				int(limit.Period.Seconds()), limit.Burst))
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(limit.Burst))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+			if result.Allowed {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			middlewareHandlers.metrics.IncRateLimited(pathTemplate, scope)
+			middlewareHandlers.logger.Errorf("[reqid=%s] rate limit exceeded for %s by %s\n", requestId,
+				pathTemplate, scope)
+			w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			err = handlers.WriteError(w, r, middlewareHandlers.metrics, myerrors.ErrRateLimited)
+			if err != nil {
+				middlewareHandlers.logger.Errorf("[reqid=%s] failed to write response: %v\n", requestId, err)
+			}
+		})
+	}
+}
+
+// rateLimitSubject возвращает, по чему считается лимит запроса. Пользователь определяется только
+// по подписи access токена, без запроса к сервису сессий: лимит должен оставаться дешевым
+func (middlewareHandlers *Middleware) rateLimitSubject(r *http.Request, limit RateLimit) (string, string) {
+	if limit.Key == RateLimitByUser {
+		accessCookie, err := r.Cookie("access")
+		if err == nil {
+			claims, err := handlers.IsTokenValid(accessCookie, middlewareHandlers.keyManager)
+			if err == nil {
+				userPrincipal, err := principal.FromClaims(claims)
+				if err == nil && userPrincipal.Login != "" {
+					return RateLimitByUser, userPrincipal.Login
+				}
+			}
+		}
+	}
+	return RateLimitByIP, clientip.FromRequest(r)
+}
+
+func ceilSeconds(duration time.Duration) int {
+	if duration <= 0 {
+		return 0
+	}
+	return int(math.Ceil(duration.Seconds()))
+}
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	EnvironmentDevelopment = "development"
+	EnvironmentProduction  = "production"
+)
+
+// SecurityHeaders заголовки безопасности, которые gateway добавляет к каждому ответу. Пустые не выставляются
+type SecurityHeaders struct {
+	ContentSecurityPolicy   string
+	StrictTransportSecurity string
+	ReferrerPolicy          string
+	FrameOptions            string
+}
+
+var securityProfiles = map[string]SecurityHeaders{
+	// локально gateway работает по http, а swagger UI использует встроенные скрипты и стили
+	EnvironmentDevelopment: {
+		ContentSecurityPolicy: "default-src 'self'; script-src 'self' 'unsafe-inline'; " +
+			"style-src 'self' 'unsafe-inline'; img-src 'self' data:; frame-ancestors 'none'",
+		ReferrerPolicy: "strict-origin-when-cross-origin",
+		FrameOptions:   "DENY",
+	},
+	// gateway отдает только JSON, поэтому ответам не нужно загружать никаких ресурсов
+	EnvironmentProduction: {
+		ContentSecurityPolicy:   "default-src 'none'; frame-ancestors 'none'",
+		StrictTransportSecurity: "max-age=31536000; includeSubDomains",
+		ReferrerPolicy:          "no-referrer",
+		FrameOptions:            "DENY",
+	},
+}
+
+// SecurityHeadersProfile возвращает заголовки безопасности окружения environment
+func SecurityHeadersProfile(environment string) (SecurityHeaders, error) {
+	headers, ok := securityProfiles[environment]
+	if !ok {
+		return SecurityHeaders{}, fmt.Errorf("unknown environment %q", environment)
+	}
+	return headers, nil
+}
+
+// SecurityHeadersMiddleware выставляет заголовки безопасности до обработчика, чтобы они были и в ответах с ошибкой
+func SecurityHeadersMiddleware(headers SecurityHeaders) mux.MiddlewareFunc {
+	values := map[string]string{
+		"Content-Security-Policy":   headers.ContentSecurityPolicy,
+		"Strict-Transport-Security": headers.StrictTransportSecurity,
+		"Referrer-Policy":           headers.ReferrerPolicy,
+		"X-Frame-Options":           headers.FrameOptions,
+		"X-Content-Type-Options":    "nosniff",
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for name, value := range values {
+				if value != "" {
+					w.Header().Set(name, value)
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
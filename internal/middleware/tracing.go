@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// TracingMiddleware начинает спан HTTP запроса или продолжает трейс из заголовка traceparent.
+// Спан называется по шаблону маршрута, чтобы запросы к разным фильмам не давали разные имена
+func TracingMiddleware(service string) mux.MiddlewareFunc {
+	return otelhttp.NewMiddleware(service, otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+		route := mux.CurrentRoute(r)
+		if route == nil {
+			return r.Method
+		}
+		pathTemplate, err := route.GetPathTemplate()
+		if err != nil {
+			return r.Method
+		}
+		return r.Method + " " + pathTemplate
+	}))
+}
@@ -0,0 +1,49 @@
+// Package oidc реализует вход через внешних OpenID Connect провайдеров: authorization code flow с PKCE
+// и проверку id_token по ключам провайдера
+package oidc
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// ProviderConfig настройки одного провайдера. Адреса эндпоинтов можно не указывать, тогда они берутся
+// из discovery документа издателя
+type ProviderConfig struct {
+	Name         string   `json:"name"`
+	Issuer       string   `json:"issuer"`
+	ClientID     string   `json:"clientId"`
+	ClientSecret string   `json:"clientSecret"`
+	RedirectURL  string   `json:"redirectUrl"`
+	Scopes       []string `json:"scopes,omitempty"`
+	AuthURL      string   `json:"authUrl,omitempty"`
+	TokenURL     string   `json:"tokenUrl,omitempty"`
+	JWKSURL      string   `json:"jwksUrl,omitempty"`
+}
+
+// LoadConfig читает список провайдеров из JSON файла. Пустой путь означает, что вход через провайдеров выключен
+func LoadConfig(path string) ([]ProviderConfig, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read oidc config: %w", err)
+	}
+
+	var configs []ProviderConfig
+	err = json.Unmarshal(data, &configs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse oidc config: %w", err)
+	}
+
+	for _, config := range configs {
+		if config.Name == "" || config.Issuer == "" || config.ClientID == "" || config.RedirectURL == "" {
+			return nil, fmt.Errorf("oidc provider %q: name, issuer, clientId and redirectUrl are required", config.Name)
+		}
+	}
+
+	return configs, nil
+}
@@ -0,0 +1,231 @@
+// Package oidctest локальный OpenID Connect провайдер для тестов и ручной проверки входа через провайдеров.
+// Авторизация одобряется сразу, без страницы входа, от имени пользователя User
+package oidctest
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+
+	"github.com/SanExpett/diploma/internal/oidc"
+	"github.com/SanExpett/diploma/internal/signing"
+)
+
+// User пользователь, от имени которого провайдер выдает id_token
+type User struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+type authorization struct {
+	user          User
+	redirectURI   string
+	nonce         string
+	codeChallenge string
+}
+
+// IDTokenClaims claims, которые провайдер кладет в id_token
+type IDTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce         string `json:"nonce,omitempty"`
+	Email         string `json:"email,omitempty"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name,omitempty"`
+}
+
+type Provider struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+
+	mu    sync.Mutex
+	user  User
+	codes map[string]authorization
+	keys  *signing.KeyManager
+}
+
+func New(issuer, clientID, clientSecret string) (*Provider, error) {
+	keys, err := signing.NewKeyManager(signing.AlgorithmRS256, 24*time.Hour, time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Provider{
+		Issuer:       issuer,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		user:         User{Subject: "mock-subject", Email: "mock@example.com", EmailVerified: true, Name: "Mock"},
+		codes:        make(map[string]authorization),
+		keys:         keys,
+	}, nil
+}
+
+// NewServer запускает провайдера на httptest сервере, издателем становится адрес сервера
+func NewServer(clientID, clientSecret string) (*Provider, *httptest.Server, error) {
+	server := httptest.NewUnstartedServer(nil)
+	provider, err := New("http://"+server.Listener.Addr().String(), clientID, clientSecret)
+	if err != nil {
+		return nil, nil, err
+	}
+	server.Config.Handler = provider
+	server.Start()
+
+	return provider, server, nil
+}
+
+// Config конфигурация relying party для этого провайдера, адреса эндпоинтов берутся из discovery
+func (provider *Provider) Config(name, redirectURL string) oidc.ProviderConfig {
+	return oidc.ProviderConfig{
+		Name:         name,
+		Issuer:       provider.Issuer,
+		ClientID:     provider.ClientID,
+		ClientSecret: provider.ClientSecret,
+		RedirectURL:  redirectURL,
+	}
+}
+
+// SetUser меняет пользователя для следующих авторизаций
+func (provider *Provider) SetUser(user User) {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	provider.user = user
+}
+
+// Sign подписывает произвольные claims ключом провайдера, чтобы тесты могли собрать некорректный id_token
+func (provider *Provider) Sign(claims jwt.Claims) (string, error) {
+	return provider.keys.Sign(claims)
+}
+
+func (provider *Provider) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/.well-known/openid-configuration":
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"issuer":                                provider.Issuer,
+			"authorization_endpoint":                provider.Issuer + "/authorize",
+			"token_endpoint":                        provider.Issuer + "/token",
+			"jwks_uri":                              provider.Issuer + "/jwks",
+			"response_types_supported":              []string{"code"},
+			"id_token_signing_alg_values_supported": []string{signing.AlgorithmRS256},
+			"code_challenge_methods_supported":      []string{"S256"},
+		})
+	case "/jwks":
+		writeJSON(w, http.StatusOK, provider.keys.JWKS())
+	case "/authorize":
+		provider.authorize(w, r)
+	case "/token":
+		provider.token(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (provider *Provider) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	redirectURI, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || query.Get("redirect_uri") == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	if query.Get("client_id") != provider.ClientID || query.Get("response_type") != "code" ||
+		query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+
+	code, err := randomCode()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	provider.mu.Lock()
+	provider.codes[code] = authorization{
+		user:          provider.user,
+		redirectURI:   redirectURI.String(),
+		nonce:         query.Get("nonce"),
+		codeChallenge: query.Get("code_challenge"),
+	}
+	provider.mu.Unlock()
+
+	callback := redirectURI.Query()
+	callback.Set("code", code)
+	callback.Set("state", query.Get("state"))
+	redirectURI.RawQuery = callback.Encode()
+
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func (provider *Provider) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.ParseForm() != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	if r.PostForm.Get("client_id") != provider.ClientID || r.PostForm.Get("client_secret") != provider.ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	// код одноразовый даже при неудачной попытке обмена
+	provider.mu.Lock()
+	auth, ok := provider.codes[r.PostForm.Get("code")]
+	delete(provider.codes, r.PostForm.Get("code"))
+	provider.mu.Unlock()
+
+	if !ok || r.PostForm.Get("grant_type") != "authorization_code" ||
+		r.PostForm.Get("redirect_uri") != auth.redirectURI ||
+		oidc.CodeChallenge(r.PostForm.Get("code_verifier")) != auth.codeChallenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	idToken, err := provider.keys.Sign(IDTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    provider.Issuer,
+			Subject:   auth.user.Subject,
+			Audience:  jwt.ClaimStrings{provider.ClientID},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(5 * time.Minute)),
+		},
+		Nonce:         auth.nonce,
+		Email:         auth.user.Email,
+		EmailVerified: auth.user.EmailVerified,
+		Name:          auth.user.Name,
+	})
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": "mock-access-token",
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+func randomCode() (string, error) {
+	buf := make([]byte, 16)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate code: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,284 @@
+package oidc
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+
+	"github.com/SanExpett/diploma/internal/signing"
+)
+
+// jwksRefreshInterval не дает перезапрашивать ключи на каждый токен с неизвестным kid
+const jwksRefreshInterval = time.Minute
+
+// idTokenAlgorithms алгоритмы подписи id_token, которые мы принимаем. none и HMAC исключены намеренно
+var idTokenAlgorithms = []string{"RS256", "ES256", "EdDSA"}
+
+var ErrInvalidIDToken = errors.New("invalid id token")
+
+// Claims данные пользователя из проверенного id_token
+type Claims struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+type idTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce         string `json:"nonce"`
+	AuthorizedBy  string `json:"azp,omitempty"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+}
+
+type discoveryDocument struct {
+	Issuer        string `json:"issuer"`
+	AuthEndpoint  string `json:"authorization_endpoint"`
+	TokenEndpoint string `json:"token_endpoint"`
+	JWKSURI       string `json:"jwks_uri"`
+}
+
+type tokenResponse struct {
+	IDToken string `json:"id_token"`
+	Error   string `json:"error"`
+}
+
+// Provider relying party для одного провайдера. Discovery и ключи запрашиваются лениво и кешируются
+type Provider struct {
+	config ProviderConfig
+	client *http.Client
+
+	mu            sync.Mutex
+	discovered    bool
+	keys          map[string]interface{}
+	keysFetchedAt time.Time
+}
+
+func NewProvider(config ProviderConfig, client *http.Client) *Provider {
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid", "email", "profile"}
+	}
+	return &Provider{
+		config: config,
+		client: client,
+	}
+}
+
+func (provider *Provider) Name() string {
+	return provider.config.Name
+}
+
+// AuthCodeURL адрес страницы провайдера, на которую отправляется пользователь
+func (provider *Provider) AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error) {
+	err := provider.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", provider.config.ClientID)
+	query.Set("redirect_uri", provider.config.RedirectURL)
+	query.Set("scope", strings.Join(provider.config.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", codeChallenge)
+	query.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(provider.config.AuthURL, "?") {
+		separator = "&"
+	}
+	return provider.config.AuthURL + separator + query.Encode(), nil
+}
+
+// Exchange меняет код авторизации на id_token
+func (provider *Provider) Exchange(ctx context.Context, code, verifier string) (string, error) {
+	err := provider.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", provider.config.RedirectURL)
+	form.Set("client_id", provider.config.ClientID)
+	form.Set("client_secret", provider.config.ClientSecret)
+	form.Set("code_verifier", verifier)
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, provider.config.TokenURL,
+		strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to build token request: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+
+	response, err := provider.client.Do(request)
+	if err != nil {
+		return "", fmt.Errorf("failed to exchange code: %w", err)
+	}
+	defer response.Body.Close()
+
+	var token tokenResponse
+	err = json.NewDecoder(io.LimitReader(response.Body, 1<<20)).Decode(&token)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode token response: %w", err)
+	}
+	if response.StatusCode != http.StatusOK || token.Error != "" {
+		return "", fmt.Errorf("token endpoint returned %d: %s", response.StatusCode, token.Error)
+	}
+	if token.IDToken == "" {
+		return "", fmt.Errorf("token response has no id_token")
+	}
+
+	return token.IDToken, nil
+}
+
+// VerifyIDToken проверяет подпись id_token по ключам провайдера, издателя, аудиторию, срок и nonce
+func (provider *Provider) VerifyIDToken(ctx context.Context, raw, nonce string) (Claims, error) {
+	err := provider.discover(ctx)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	claims := &idTokenClaims{}
+	_, err = jwt.ParseWithClaims(raw, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return provider.key(ctx, kid)
+	}, jwt.WithValidMethods(idTokenAlgorithms))
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	switch {
+	case claims.Issuer != provider.config.Issuer:
+		return Claims{}, fmt.Errorf("%w: unexpected issuer %q", ErrInvalidIDToken, claims.Issuer)
+	case !claims.VerifyAudience(provider.config.ClientID, true):
+		return Claims{}, fmt.Errorf("%w: unexpected audience", ErrInvalidIDToken)
+	case len(claims.Audience) > 1 && claims.AuthorizedBy != provider.config.ClientID:
+		return Claims{}, fmt.Errorf("%w: unexpected authorized party", ErrInvalidIDToken)
+	case claims.ExpiresAt == nil:
+		return Claims{}, fmt.Errorf("%w: no expiration", ErrInvalidIDToken)
+	case claims.Subject == "":
+		return Claims{}, fmt.Errorf("%w: no subject", ErrInvalidIDToken)
+	case subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1:
+		return Claims{}, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+
+	return Claims{
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		Name:          claims.Name,
+	}, nil
+}
+
+// discover заполняет незаданные в конфигурации адреса из /.well-known/openid-configuration
+func (provider *Provider) discover(ctx context.Context) error {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+
+	if provider.discovered {
+		return nil
+	}
+	if provider.config.AuthURL != "" && provider.config.TokenURL != "" && provider.config.JWKSURL != "" {
+		provider.discovered = true
+		return nil
+	}
+
+	var document discoveryDocument
+	err := provider.getJSON(ctx, strings.TrimSuffix(provider.config.Issuer, "/")+"/.well-known/openid-configuration",
+		&document)
+	if err != nil {
+		return fmt.Errorf("failed to discover provider %s: %w", provider.config.Name, err)
+	}
+	if document.Issuer != provider.config.Issuer {
+		return fmt.Errorf("provider %s discovery issuer %q does not match config", provider.config.Name,
+			document.Issuer)
+	}
+
+	if provider.config.AuthURL == "" {
+		provider.config.AuthURL = document.AuthEndpoint
+	}
+	if provider.config.TokenURL == "" {
+		provider.config.TokenURL = document.TokenEndpoint
+	}
+	if provider.config.JWKSURL == "" {
+		provider.config.JWKSURL = document.JWKSURI
+	}
+	provider.discovered = true
+
+	return nil
+}
+
+// key ищет открытый ключ по kid. При неизвестном kid ключи перезапрашиваются, так как провайдер мог их ротировать
+func (provider *Provider) key(ctx context.Context, kid string) (interface{}, error) {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+
+	if key, ok := provider.keys[kid]; ok {
+		return key, nil
+	}
+	if provider.keys != nil && time.Since(provider.keysFetchedAt) < jwksRefreshInterval {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+
+	var jwks signing.JWKS
+	err := provider.getJSON(ctx, provider.config.JWKSURL, &jwks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch jwks: %w", err)
+	}
+
+	keys := make(map[string]interface{}, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		publicKey, err := jwk.PublicKey()
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = publicKey
+	}
+	provider.keys = keys
+	provider.keysFetchedAt = time.Now()
+
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key %q", kid)
+}
+
+func (provider *Provider) getJSON(ctx context.Context, target string, result interface{}) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Accept", "application/json")
+
+	response, err := provider.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %d", target, response.StatusCode)
+	}
+
+	return json.NewDecoder(io.LimitReader(response.Body, 1<<20)).Decode(result)
+}
@@ -0,0 +1,162 @@
+package oidc_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/SanExpett/diploma/internal/oidc"
+	"github.com/SanExpett/diploma/internal/oidc/oidctest"
+)
+
+const redirectURL = "http://localhost:8081/api/auth/oidc/mock/callback"
+
+func newProvider(t *testing.T) (*oidctest.Provider, *oidc.Provider) {
+	mock, server, err := oidctest.NewServer("client", "secret")
+	require.NoError(t, err)
+	t.Cleanup(server.Close)
+
+	registry := oidc.NewRegistry([]oidc.ProviderConfig{mock.Config("mock", redirectURL)}, server.Client())
+	provider, ok := registry.Provider("mock")
+	require.True(t, ok)
+
+	return mock, provider
+}
+
+// authorize проходит страницу провайдера и возвращает код из редиректа на callback
+func authorize(t *testing.T, provider *oidc.Provider, state, nonce, verifier string) string {
+	authURL, err := provider.AuthCodeURL(context.Background(), state, nonce, oidc.CodeChallenge(verifier))
+	require.NoError(t, err)
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	response, err := client.Get(authURL)
+	require.NoError(t, err)
+	defer response.Body.Close()
+	require.Equal(t, http.StatusFound, response.StatusCode)
+
+	location, err := url.Parse(response.Header.Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, state, location.Query().Get("state"))
+
+	return location.Query().Get("code")
+}
+
+func TestProvider_CodeFlow(t *testing.T) {
+	mock, provider := newProvider(t)
+	mock.SetUser(oidctest.User{Subject: "42", Email: "user@example.com", EmailVerified: true, Name: "User"})
+
+	code := authorize(t, provider, "state", "nonce", "verifier")
+
+	idToken, err := provider.Exchange(context.Background(), code, "verifier")
+	require.NoError(t, err)
+
+	claims, err := provider.VerifyIDToken(context.Background(), idToken, "nonce")
+	require.NoError(t, err)
+	assert.Equal(t, oidc.Claims{Subject: "42", Email: "user@example.com", EmailVerified: true, Name: "User"}, claims)
+
+	// код одноразовый
+	_, err = provider.Exchange(context.Background(), code, "verifier")
+	assert.Error(t, err)
+}
+
+func TestProvider_ExchangeWrongVerifier(t *testing.T) {
+	_, provider := newProvider(t)
+
+	code := authorize(t, provider, "state", "nonce", "verifier")
+
+	_, err := provider.Exchange(context.Background(), code, "other")
+	assert.Error(t, err)
+}
+
+func TestProvider_VerifyIDToken(t *testing.T) {
+	mock, provider := newProvider(t)
+
+	valid := func() oidctest.IDTokenClaims {
+		return oidctest.IDTokenClaims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				Issuer:    mock.Issuer,
+				Subject:   "42",
+				Audience:  jwt.ClaimStrings{"client"},
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+			},
+			Nonce: "nonce",
+		}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(claims *oidctest.IDTokenClaims)
+		nonce   string
+		wantErr bool
+	}{
+		{
+			name:   "Корректный токен",
+			modify: func(*oidctest.IDTokenClaims) {},
+			nonce:  "nonce",
+		},
+		{
+			name:    "Другой nonce",
+			modify:  func(*oidctest.IDTokenClaims) {},
+			nonce:   "other",
+			wantErr: true,
+		},
+		{
+			name:    "Чужой издатель",
+			modify:  func(claims *oidctest.IDTokenClaims) { claims.Issuer = "http://evil.example.com" },
+			nonce:   "nonce",
+			wantErr: true,
+		},
+		{
+			name:    "Чужая аудитория",
+			modify:  func(claims *oidctest.IDTokenClaims) { claims.Audience = jwt.ClaimStrings{"other"} },
+			nonce:   "nonce",
+			wantErr: true,
+		},
+		{
+			name: "Истекший токен",
+			modify: func(claims *oidctest.IDTokenClaims) {
+				claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+			},
+			nonce:   "nonce",
+			wantErr: true,
+		},
+		{
+			name:    "Без срока действия",
+			modify:  func(claims *oidctest.IDTokenClaims) { claims.ExpiresAt = nil },
+			nonce:   "nonce",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			claims := valid()
+			test.modify(&claims)
+			idToken, err := mock.Sign(claims)
+			require.NoError(t, err)
+
+			_, err = provider.VerifyIDToken(context.Background(), idToken, test.nonce)
+			if test.wantErr {
+				assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	t.Run("Подпись HMAC", func(t *testing.T) {
+		idToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, valid()).SignedString([]byte("secret"))
+		require.NoError(t, err)
+
+		_, err = provider.VerifyIDToken(context.Background(), idToken, "nonce")
+		assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+	})
+}
@@ -0,0 +1,35 @@
+package oidc
+
+import (
+	"net/http"
+	"sort"
+)
+
+// Registry провайдеры, доступные для входа, по имени
+type Registry struct {
+	providers map[string]*Provider
+}
+
+func NewRegistry(configs []ProviderConfig, client *http.Client) *Registry {
+	providers := make(map[string]*Provider, len(configs))
+	for _, config := range configs {
+		providers[config.Name] = NewProvider(config, client)
+	}
+	return &Registry{providers: providers}
+}
+
+// Provider возвращает провайдера по имени, false если такого нет в конфигурации
+func (registry *Registry) Provider(name string) (*Provider, bool) {
+	provider, ok := registry.providers[name]
+	return provider, ok
+}
+
+// Names имена провайдеров в алфавитном порядке, чтобы фронтенд показывал кнопки стабильно
+func (registry *Registry) Names() []string {
+	names := make([]string, 0, len(registry.providers))
+	for name := range registry.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
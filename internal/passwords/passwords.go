@@ -0,0 +1,158 @@
+package passwords
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+
+	myerrors "github.com/SanExpett/diploma/internal/errors"
+)
+
+const argon2idPrefix = "$argon2id$"
+
+// Params параметры argon2id, с которыми хешируются новые пароли
+type Params struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultParams соответствуют рекомендациям OWASP для argon2id
+var DefaultParams = Params{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 2,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// Hash хеширует пароль argon2id с параметрами по умолчанию
+func Hash(password string) (string, error) {
+	return HashWithParams(password, DefaultParams)
+}
+
+// HashWithParams возвращает хеш в формате PHC: $argon2id$v=19$m=...,t=...,p=...$salt$hash,
+// поэтому алгоритм и параметры хранятся вместе с хешем
+func HashWithParams(password string, params Params) (string, error) {
+	salt := make([]byte, params.SaltLength)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism,
+		params.KeyLength)
+
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2idPrefix, argon2.Version, params.Memory,
+		params.Iterations, params.Parallelism, base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify сравнивает пароль с сохраненным значением за постоянное время. needsRehash означает,
+// что значение нужно перехешировать: это bcrypt, хеш со слабыми параметрами или пароль,
+// сохраненный открытым текстом до появления хеширования
+func Verify(password, encoded string) (ok bool, needsRehash bool, err error) {
+	switch {
+	case strings.HasPrefix(encoded, argon2idPrefix):
+		return verifyArgon2id(password, encoded)
+	case isBcrypt(encoded):
+		err = bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+		if err != nil {
+			if err == bcrypt.ErrMismatchedHashAndPassword {
+				return false, false, nil
+			}
+			return false, false, fmt.Errorf("failed to compare bcrypt hash: %w: %w", err,
+				myerrors.ErrInvalidPasswordHash)
+		}
+		return true, true, nil
+	default:
+		ok = subtle.ConstantTimeCompare([]byte(password), []byte(encoded)) == 1
+		return ok, ok, nil
+	}
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     string
+)
+
+// VerifyDummy сравнивает пароль с хешем с параметрами по умолчанию и ничего не возвращает. Вызывается,
+// когда пользователя нет, чтобы ответ занимал столько же времени, сколько проверка настоящего пароля
+func VerifyDummy(password string) {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = Hash("dummy password")
+	})
+	_, _, _ = Verify(password, dummyHash)
+}
+
+func isBcrypt(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") ||
+		strings.HasPrefix(encoded, "$2y$")
+}
+
+func verifyArgon2id(password, encoded string) (bool, bool, error) {
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false, false, err
+	}
+
+	otherKey := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism,
+		params.KeyLength)
+	if subtle.ConstantTimeCompare(key, otherKey) != 1 {
+		return false, false, nil
+	}
+
+	return true, isWeaker(params, DefaultParams), nil
+}
+
+func decodeArgon2id(encoded string) (Params, []byte, []byte, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return Params{}, nil, nil, fmt.Errorf("wrong number of argon2id hash parts: %w",
+			myerrors.ErrInvalidPasswordHash)
+	}
+
+	var version int
+	_, err := fmt.Sscanf(parts[2], "v=%d", &version)
+	if err != nil || version != argon2.Version {
+		return Params{}, nil, nil, fmt.Errorf("unsupported argon2id version %q: %w", parts[2],
+			myerrors.ErrInvalidPasswordHash)
+	}
+
+	var params Params
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism)
+	if err != nil {
+		return Params{}, nil, nil, fmt.Errorf("failed to parse argon2id params: %w: %w", err,
+			myerrors.ErrInvalidPasswordHash)
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return Params{}, nil, nil, fmt.Errorf("failed to decode argon2id salt: %w: %w", err,
+			myerrors.ErrInvalidPasswordHash)
+	}
+	params.SaltLength = uint32(len(salt))
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return Params{}, nil, nil, fmt.Errorf("failed to decode argon2id hash: %w: %w", err,
+			myerrors.ErrInvalidPasswordHash)
+	}
+	params.KeyLength = uint32(len(key))
+
+	return params, salt, key, nil
+}
+
+func isWeaker(params, target Params) bool {
+	return params.Memory < target.Memory || params.Iterations < target.Iterations ||
+		params.Parallelism < target.Parallelism || params.SaltLength < target.SaltLength ||
+		params.KeyLength < target.KeyLength
+}
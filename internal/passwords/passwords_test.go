@@ -0,0 +1,110 @@
+package passwords
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	myerrors "github.com/SanExpett/diploma/internal/errors"
+)
+
+func TestHash(t *testing.T) {
+	first, err := Hash("123456789")
+	require.NoError(t, err)
+	second, err := Hash("123456789")
+	require.NoError(t, err)
+
+	assert.Contains(t, first, "$argon2id$v=19$m=65536,t=3,p=2$")
+	assert.NotEqual(t, first, second)
+}
+
+func TestVerify(t *testing.T) {
+	argon2idHash, err := Hash("123456789")
+	require.NoError(t, err)
+	weakArgon2idHash, err := HashWithParams("123456789", Params{
+		Memory:      1024,
+		Iterations:  1,
+		Parallelism: 1,
+		SaltLength:  16,
+		KeyLength:   32,
+	})
+	require.NoError(t, err)
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("123456789"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name                string
+		password            string
+		encoded             string
+		expectedOk          bool
+		expectedNeedsRehash bool
+		expectedErr         error
+	}{
+		{
+			name:       "Верный пароль argon2id",
+			password:   "123456789",
+			encoded:    argon2idHash,
+			expectedOk: true,
+		},
+		{
+			name:     "Неверный пароль argon2id",
+			password: "987654321",
+			encoded:  argon2idHash,
+		},
+		{
+			name:                "Argon2id со слабыми параметрами",
+			password:            "123456789",
+			encoded:             weakArgon2idHash,
+			expectedOk:          true,
+			expectedNeedsRehash: true,
+		},
+		{
+			name:                "Верный пароль bcrypt",
+			password:            "123456789",
+			encoded:             string(bcryptHash),
+			expectedOk:          true,
+			expectedNeedsRehash: true,
+		},
+		{
+			name:     "Неверный пароль bcrypt",
+			password: "987654321",
+			encoded:  string(bcryptHash),
+		},
+		{
+			name:                "Пароль открытым текстом",
+			password:            "123456789",
+			encoded:             "123456789",
+			expectedOk:          true,
+			expectedNeedsRehash: true,
+		},
+		{
+			name:     "Неверный пароль открытым текстом",
+			password: "987654321",
+			encoded:  "123456789",
+		},
+		{
+			name:        "Поврежденный хеш argon2id",
+			password:    "123456789",
+			encoded:     "$argon2id$v=19$m=65536,t=3,p=2$broken",
+			expectedErr: myerrors.ErrInvalidPasswordHash,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, needsRehash, err := Verify(tt.password, tt.encoded)
+
+			assert.ErrorIs(t, err, tt.expectedErr)
+			assert.Equal(t, tt.expectedOk, ok)
+			assert.Equal(t, tt.expectedNeedsRehash, needsRehash)
+		})
+	}
+}
+
+func TestVerifyDummy(t *testing.T) {
+	VerifyDummy("123456789")
+	assert.True(t, strings.HasPrefix(dummyHash, argon2idPrefix))
+}
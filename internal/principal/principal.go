@@ -0,0 +1,89 @@
+package principal
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v4"
+
+	myerrors "github.com/SanExpett/diploma/internal/errors"
+	"github.com/SanExpett/diploma/internal/rbac"
+)
+
+type contextKey string
+
+const (
+	PrincipalKey contextKey = "principal"
+	TokenKey     contextKey = "token"
+)
+
+// Principal описывает аутентифицированного пользователя, от имени которого выполняется запрос
+type Principal struct {
+	UserUuid  string
+	Login     string
+	SessionId string
+	IsAdmin   bool
+	Roles     []string
+	Version   uint32
+}
+
+// WithPrincipal возвращает копию контекста с сохраненным в нем пользователем
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, PrincipalKey, principal)
+}
+
+// FromContext достает пользователя из контекста, если он был туда положен AuthMiddleware
+func FromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(PrincipalKey).(Principal)
+	return principal, ok
+}
+
+// WithToken сохраняет access токен, которым подтвержден пользователь, чтобы передать его сервисам
+func WithToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, TokenKey, token)
+}
+
+// TokenFromContext достает access токен, положенный WithToken
+func TokenFromContext(ctx context.Context) (string, bool) {
+	token, ok := ctx.Value(TokenKey).(string)
+	return token, ok && token != ""
+}
+
+// FromClaims собирает пользователя из claims проверенного access токена.
+// Uuid и Roles могут отсутствовать в токенах, выпущенных до их появления, тогда uuid остается пустым,
+// а роли восстанавливаются по флагу IsAdmin.
+func FromClaims(claims jwt.MapClaims) (Principal, error) {
+	login, ok := claims["Login"].(string)
+	if !ok {
+		return Principal{}, fmt.Errorf("invalid token: %w", myerrors.ErrNotAuthorised)
+	}
+	isAdmin, ok := claims["IsAdmin"].(bool)
+	if !ok {
+		return Principal{}, fmt.Errorf("invalid token: %w", myerrors.ErrNotAuthorised)
+	}
+	version, ok := claims["Version"].(float64)
+	if !ok {
+		return Principal{}, fmt.Errorf("invalid token: %w", myerrors.ErrNotAuthorised)
+	}
+	uuid, _ := claims["Uuid"].(string)
+	sessionId, _ := claims["Sid"].(string)
+
+	var roles []string
+	rolesClaim, _ := claims["Roles"].([]interface{})
+	for _, role := range rolesClaim {
+		roleName, ok := role.(string)
+		if !ok {
+			return Principal{}, fmt.Errorf("invalid token: %w", myerrors.ErrNotAuthorised)
+		}
+		roles = append(roles, roleName)
+	}
+
+	return Principal{
+		UserUuid:  uuid,
+		Login:     login,
+		SessionId: sessionId,
+		IsAdmin:   isAdmin,
+		Roles:     rbac.RolesWithLegacyAdmin(roles, isAdmin),
+		Version:   uint32(version),
+	}, nil
+}
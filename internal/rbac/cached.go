@@ -0,0 +1,58 @@
+package rbac
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type PolicyLoader interface {
+	LoadPolicy(ctx context.Context) (map[Role][]Permission, error)
+}
+
+// CachedPolicy загружает политику через PolicyLoader и перечитывает ее не чаще, чем раз в ttl.
+// Если перечитать политику не удалось, используется последняя загруженная
+type CachedPolicy struct {
+	loader   PolicyLoader
+	ttl      time.Duration
+	mu       sync.Mutex
+	policy   *Policy
+	loadedAt time.Time
+}
+
+func NewCachedPolicy(loader PolicyLoader, ttl time.Duration) *CachedPolicy {
+	return &CachedPolicy{
+		loader: loader,
+		ttl:    ttl,
+	}
+}
+
+func (cached *CachedPolicy) Policy(ctx context.Context) (*Policy, error) {
+	cached.mu.Lock()
+	defer cached.mu.Unlock()
+
+	if cached.policy != nil && time.Since(cached.loadedAt) < cached.ttl {
+		return cached.policy, nil
+	}
+
+	rolePermissions, err := cached.loader.LoadPolicy(ctx)
+	if err != nil {
+		if cached.policy != nil {
+			return cached.policy, nil
+		}
+		return nil, err
+	}
+
+	cached.policy = NewPolicy(rolePermissions)
+	cached.loadedAt = time.Now()
+
+	return cached.policy, nil
+}
+
+func (cached *CachedPolicy) Allowed(ctx context.Context, roles []string, permission Permission) (bool, error) {
+	policy, err := cached.Policy(ctx)
+	if err != nil {
+		return false, err
+	}
+	return policy.Allowed(roles, permission), nil
+}
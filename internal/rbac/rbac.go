@@ -0,0 +1,264 @@
+package rbac
+
+import (
+	healthProto "google.golang.org/grpc/health/grpc_health_v1"
+
+	session "github.com/SanExpett/diploma/internal/session/proto"
+)
+
+type Role string
+
+const (
+	RoleAdmin         Role = "admin"
+	RoleContentEditor Role = "content_editor"
+	RoleModerator     Role = "moderator"
+	RoleSupport       Role = "support"
+)
+
+type Permission string
+
+const (
+	PermissionFilmsManage         Permission = "films.manage"
+	PermissionSubscriptionsManage Permission = "subscriptions.manage"
+	PermissionUsersRemove         Permission = "users.remove"
+	PermissionCommentsModerate    Permission = "comments.moderate"
+	PermissionUsersUnlock         Permission = "users.unlock"
+	PermissionAuditRead           Permission = "audit.read"
+)
+
+const (
+	// PermissionPublic метод доступен любому клиенту без пользователя
+	PermissionPublic Permission = "public"
+	// PermissionInternal метод вызывает только gateway от своего имени, в том числе до аутентификации
+	// пользователя. Вызов должен нести подписанный ключом gateway токен сервиса
+	PermissionInternal Permission = "internal"
+	// PermissionAuthenticated метод доступен любому пользователю с проверенным access токеном
+	PermissionAuthenticated Permission = "authenticated"
+)
+
+// MethodPermissions содержит права, которые требуются для вызова gRPC методов.
+// Вызовы методов, которых нет в списке, отклоняются
+var MethodPermissions = map[string]Permission{
+	healthProto.Health_Check_FullMethodName: PermissionPublic,
+
+	session.Films_GetAllFilmsPreviews_FullMethodName:     PermissionPublic,
+	session.Films_GetFilmsPreviewsWithSub_FullMethodName: PermissionPublic,
+	session.Films_GetFilmDataByUuid_FullMethodName:       PermissionPublic,
+	session.Films_GetFilmPreviewByUuid_FullMethodName:    PermissionPublic,
+	session.Films_GetActorDataByUuid_FullMethodName:      PermissionPublic,
+	session.Films_GetActorsByFilm_FullMethodName:         PermissionPublic,
+	session.Films_GetAllFavoriteFilms_FullMethodName:     PermissionPublic,
+	session.Films_GetAllFilmsByGenre_FullMethodName:      PermissionPublic,
+	session.Films_GetAllGenres_FullMethodName:            PermissionPublic,
+	session.Films_FindFilmsShort_FullMethodName:          PermissionPublic,
+	session.Films_FindFilmsLong_FullMethodName:           PermissionPublic,
+	session.Films_FindSerialsShort_FullMethodName:        PermissionPublic,
+	session.Films_FindSerialsLong_FullMethodName:         PermissionPublic,
+	session.Films_FindActorsShort_FullMethodName:         PermissionPublic,
+	session.Films_FindActorsLong_FullMethodName:          PermissionPublic,
+	session.Films_GetTopFilms_FullMethodName:             PermissionPublic,
+	session.Films_GetAllFilmComments_FullMethodName:      PermissionPublic,
+	session.Films_PutFavorite_FullMethodName:             PermissionAuthenticated,
+	session.Films_DeleteFavorite_FullMethodName:          PermissionAuthenticated,
+	session.Films_AddComment_FullMethodName:              PermissionAuthenticated,
+	session.Films_RemoveComment_FullMethodName:           PermissionAuthenticated,
+	session.Films_AddFilm_FullMethodName:                 PermissionFilmsManage,
+	session.Films_RemoveFilmByUuid_FullMethodName:        PermissionFilmsManage,
+
+	// сессии, попытки входа и refresh токены нужны gateway, пока пользователь еще не подтвержден,
+	// поэтому их вызывает только gateway
+	session.Sessions_Add_FullMethodName:                       PermissionInternal,
+	session.Sessions_DeleteSession_FullMethodName:             PermissionInternal,
+	session.Sessions_CheckVersion_FullMethodName:              PermissionInternal,
+	session.Sessions_GetVersion_FullMethodName:                PermissionInternal,
+	session.Sessions_HasSession_FullMethodName:                PermissionInternal,
+	session.Sessions_IssueRefreshToken_FullMethodName:         PermissionInternal,
+	session.Sessions_GetRefreshToken_FullMethodName:           PermissionInternal,
+	session.Sessions_RotateRefreshToken_FullMethodName:        PermissionInternal,
+	session.Sessions_RevokeRefreshTokenFamily_FullMethodName:  PermissionInternal,
+	session.Sessions_RevokeOtherSessions_FullMethodName:       PermissionInternal,
+	session.Sessions_ReserveLoginAttempt_FullMethodName:       PermissionInternal,
+	session.Sessions_RegisterLoginFailure_FullMethodName:      PermissionInternal,
+	session.Sessions_ReleaseLoginAttempt_FullMethodName:       PermissionInternal,
+	session.Sessions_ResetLoginAttempts_FullMethodName:        PermissionInternal,
+	session.Sessions_StartDeviceAuthorization_FullMethodName:  PermissionInternal,
+	session.Sessions_PollDeviceAuthorization_FullMethodName:   PermissionInternal,
+	session.Sessions_SavePasskeyChallenge_FullMethodName:      PermissionInternal,
+	session.Sessions_ConsumePasskeyChallenge_FullMethodName:   PermissionInternal,
+	session.Sessions_Update_FullMethodName:                    PermissionAuthenticated,
+	session.Sessions_ListSessions_FullMethodName:              PermissionAuthenticated,
+	session.Sessions_RevokeSession_FullMethodName:             PermissionAuthenticated,
+	session.Sessions_GetDeviceAuthorization_FullMethodName:    PermissionAuthenticated,
+	session.Sessions_DecideDeviceAuthorization_FullMethodName: PermissionAuthenticated,
+	session.Sessions_UnlockLogin_FullMethodName:               PermissionUsersUnlock,
+
+	// политику ролей загружает сервис сессий, у которого нет ключа gateway
+	session.Users_GetRolePermissions_FullMethodName:       PermissionPublic,
+	session.Users_CreateUser_FullMethodName:               PermissionInternal,
+	session.Users_HasUser_FullMethodName:                  PermissionInternal,
+	session.Users_GetUser_FullMethodName:                  PermissionInternal,
+	session.Users_GetUserDataByUuid_FullMethodName:        PermissionInternal,
+	session.Users_GetUserPreview_FullMethodName:           PermissionInternal,
+	session.Users_HasSubscription_FullMethodName:          PermissionInternal,
+	session.Users_GetSubscriptions_FullMethodName:         PermissionInternal,
+	session.Users_RequestPasswordReset_FullMethodName:     PermissionInternal,
+	session.Users_ResetPassword_FullMethodName:            PermissionInternal,
+	session.Users_VerifyEmail_FullMethodName:              PermissionInternal,
+	session.Users_VerifyTOTP_FullMethodName:               PermissionInternal,
+	session.Users_LoginWithIdentity_FullMethodName:        PermissionInternal,
+	session.Users_SendMagicLink_FullMethodName:            PermissionInternal,
+	session.Users_ConsumeMagicLink_FullMethodName:         PermissionInternal,
+	session.Users_GetPasskey_FullMethodName:               PermissionInternal,
+	session.Users_UpdatePasskeySignCount_FullMethodName:   PermissionInternal,
+	session.Users_RecordAuditEvent_FullMethodName:         PermissionInternal,
+	session.Users_RegisterLoginDevice_FullMethodName:      PermissionInternal,
+	session.Users_ReportUnknownLogin_FullMethodName:       PermissionInternal,
+	session.Users_ChangeUserPassword_FullMethodName:       PermissionAuthenticated,
+	session.Users_ChangeUserName_FullMethodName:           PermissionAuthenticated,
+	session.Users_ChangeUserPasswordByUuid_FullMethodName: PermissionAuthenticated,
+	session.Users_ChangeUserNameByUuid_FullMethodName:     PermissionAuthenticated,
+	session.Users_ChangeUserAvatarByUuid_FullMethodName:   PermissionAuthenticated,
+	session.Users_PaySubscription_FullMethodName:          PermissionAuthenticated,
+	session.Users_ResendEmailVerification_FullMethodName:  PermissionAuthenticated,
+	session.Users_EnrollTOTP_FullMethodName:               PermissionAuthenticated,
+	session.Users_ConfirmTOTP_FullMethodName:              PermissionAuthenticated,
+	session.Users_DisableTOTP_FullMethodName:              PermissionAuthenticated,
+	session.Users_AddPasskey_FullMethodName:               PermissionAuthenticated,
+	session.Users_GetPasskeys_FullMethodName:              PermissionAuthenticated,
+	session.Users_RemovePasskey_FullMethodName:            PermissionAuthenticated,
+	session.Users_RemoveUser_FullMethodName:               PermissionAuthenticated,
+	session.Users_ListAuditEvents_FullMethodName:          PermissionAuditRead,
+}
+
+// Ownership описывает метод, который меняет или читает данные одного пользователя. Owner возвращает uuid
+// или логин владельца данных из запроса. Данные других пользователей доступны только с правом Permission,
+// а без него только владельцу
+type Ownership struct {
+	Owner      func(req any) (uuid, login string)
+	Permission Permission
+}
+
+// IsOwner сообщает, что запрос req меняет данные пользователя с userUuid и login
+func (ownership Ownership) IsOwner(req any, userUuid, login string) bool {
+	ownerUuid, ownerLogin := ownership.Owner(req)
+	return (ownerUuid != "" && ownerUuid == userUuid) || (ownerLogin != "" && ownerLogin == login)
+}
+
+// MethodOwners содержит методы, доступные пользователю только для его собственных данных. Права на смену
+// учетных данных, сессий и паролей чужого пользователя нет ни у одной роли: это равносильно захвату аккаунта
+var MethodOwners = map[string]Ownership{
+	session.Films_PutFavorite_FullMethodName:    {Owner: requestUserUuid},
+	session.Films_DeleteFavorite_FullMethodName: {Owner: requestUserUuid},
+	session.Films_AddComment_FullMethodName:     {Owner: commentAuthor},
+	session.Films_RemoveComment_FullMethodName:  {Owner: commentAuthor, Permission: PermissionCommentsModerate},
+
+	session.Sessions_Update_FullMethodName:                    {Owner: requestLogin},
+	session.Sessions_ListSessions_FullMethodName:              {Owner: requestLogin},
+	session.Sessions_RevokeSession_FullMethodName:             {Owner: requestLogin},
+	session.Sessions_DecideDeviceAuthorization_FullMethodName: {Owner: requestLogin},
+
+	session.Users_ChangeUserPassword_FullMethodName:       {Owner: requestLogin},
+	session.Users_ChangeUserName_FullMethodName:           {Owner: requestLogin},
+	session.Users_ChangeUserPasswordByUuid_FullMethodName: {Owner: requestUuid},
+	session.Users_ChangeUserNameByUuid_FullMethodName:     {Owner: requestUuid},
+	session.Users_ChangeUserAvatarByUuid_FullMethodName:   {Owner: requestUuid},
+	session.Users_PaySubscription_FullMethodName:          {Owner: requestUuid, Permission: PermissionSubscriptionsManage},
+	session.Users_ResendEmailVerification_FullMethodName:  {Owner: requestLogin},
+	session.Users_EnrollTOTP_FullMethodName:               {Owner: requestLogin},
+	session.Users_ConfirmTOTP_FullMethodName:              {Owner: requestLogin},
+	session.Users_DisableTOTP_FullMethodName:              {Owner: requestLogin},
+	session.Users_AddPasskey_FullMethodName:               {Owner: requestLogin},
+	session.Users_GetPasskeys_FullMethodName:              {Owner: requestLogin},
+	session.Users_RemovePasskey_FullMethodName:            {Owner: requestLogin},
+	session.Users_RemoveUser_FullMethodName:               {Owner: requestLogin, Permission: PermissionUsersRemove},
+}
+
+func requestLogin(req any) (string, string) {
+	request, ok := req.(interface{ GetLogin() string })
+	if !ok {
+		return "", ""
+	}
+	return "", request.GetLogin()
+}
+
+func requestUuid(req any) (string, string) {
+	request, ok := req.(interface{ GetUuid() string })
+	if !ok {
+		return "", ""
+	}
+	return request.GetUuid(), ""
+}
+
+func requestUserUuid(req any) (string, string) {
+	request, ok := req.(interface{ GetUserUuid() string })
+	if !ok {
+		return "", ""
+	}
+	return request.GetUserUuid(), ""
+}
+
+func commentAuthor(req any) (string, string) {
+	switch request := req.(type) {
+	case *session.AddCommentRequest:
+		return request.GetComment().GetAuthorUuid(), ""
+	case *session.RemoveCommentRequest:
+		return request.GetComment().GetAuthorUuid(), ""
+	default:
+		return "", ""
+	}
+}
+
+// Policy хранит соответствие ролей и выданных им прав
+type Policy struct {
+	permissions map[Role]map[Permission]struct{}
+}
+
+func NewPolicy(rolePermissions map[Role][]Permission) *Policy {
+	permissions := make(map[Role]map[Permission]struct{}, len(rolePermissions))
+	for role, rolePerms := range rolePermissions {
+		permissions[role] = make(map[Permission]struct{}, len(rolePerms))
+		for _, permission := range rolePerms {
+			permissions[role][permission] = struct{}{}
+		}
+	}
+
+	return &Policy{
+		permissions: permissions,
+	}
+}
+
+// Allowed проверяет, есть ли право permission хотя бы у одной из ролей
+func (policy *Policy) Allowed(roles []string, permission Permission) bool {
+	for _, role := range roles {
+		if _, ok := policy.permissions[Role(role)][permission]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// RolePermissions возвращает политику в виде списка прав для каждой роли
+func (policy *Policy) RolePermissions() map[Role][]Permission {
+	rolePermissions := make(map[Role][]Permission, len(policy.permissions))
+	for role, permissions := range policy.permissions {
+		rolePermissions[role] = make([]Permission, 0, len(permissions))
+		for permission := range permissions {
+			rolePermissions[role] = append(rolePermissions[role], permission)
+		}
+	}
+	return rolePermissions
+}
+
+// RolesWithLegacyAdmin добавляет роль администратора пользователям с флагом is_admin,
+// чтобы токены, выпущенные до появления ролей, продолжали работать
+func RolesWithLegacyAdmin(roles []string, isAdmin bool) []string {
+	if !isAdmin {
+		return roles
+	}
+	for _, role := range roles {
+		if Role(role) == RoleAdmin {
+			return roles
+		}
+	}
+	return append(roles, string(RoleAdmin))
+}
@@ -0,0 +1,101 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	session "github.com/SanExpett/diploma/internal/session/proto"
+)
+
+func TestPolicy_Allowed(t *testing.T) {
+	policy := NewPolicy(map[Role][]Permission{
+		RoleAdmin:         {PermissionFilmsManage, PermissionUsersRemove},
+		RoleContentEditor: {PermissionFilmsManage},
+		RoleModerator:     {PermissionCommentsModerate},
+	})
+
+	assert.True(t, policy.Allowed([]string{"admin"}, PermissionUsersRemove))
+	assert.True(t, policy.Allowed([]string{"moderator", "content_editor"}, PermissionFilmsManage))
+	assert.False(t, policy.Allowed([]string{"content_editor"}, PermissionUsersRemove))
+	assert.False(t, policy.Allowed([]string{"support"}, PermissionFilmsManage))
+	assert.False(t, policy.Allowed(nil, PermissionFilmsManage))
+}
+
+func TestRolesWithLegacyAdmin(t *testing.T) {
+	assert.Equal(t, []string{"admin"}, RolesWithLegacyAdmin(nil, true))
+	assert.Equal(t, []string{"moderator", "admin"}, RolesWithLegacyAdmin([]string{"moderator"}, true))
+	assert.Equal(t, []string{"admin"}, RolesWithLegacyAdmin([]string{"admin"}, true))
+	assert.Nil(t, RolesWithLegacyAdmin(nil, false))
+}
+
+type stubLoader struct {
+	calls int
+	err   error
+}
+
+func (loader *stubLoader) LoadPolicy(ctx context.Context) (map[Role][]Permission, error) {
+	loader.calls++
+	if loader.err != nil {
+		return nil, loader.err
+	}
+	return map[Role][]Permission{RoleAdmin: {PermissionFilmsManage}}, nil
+}
+
+func TestCachedPolicy(t *testing.T) {
+	loader := &stubLoader{}
+	cached := NewCachedPolicy(loader, time.Hour)
+
+	allowed, err := cached.Allowed(context.Background(), []string{"admin"}, PermissionFilmsManage)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	_, err = cached.Allowed(context.Background(), []string{"admin"}, PermissionFilmsManage)
+	require.NoError(t, err)
+	assert.Equal(t, 1, loader.calls)
+
+	expired := NewCachedPolicy(loader, 0)
+	_, err = expired.Policy(context.Background())
+	require.NoError(t, err)
+
+	loader.err = errors.New("db is down")
+	allowed, err = expired.Allowed(context.Background(), []string{"admin"}, PermissionFilmsManage)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	empty := NewCachedPolicy(loader, time.Hour)
+	_, err = empty.Policy(context.Background())
+	assert.Error(t, err)
+}
+
+func TestMethodPermissions_CoverAllMethods(t *testing.T) {
+	for _, service := range []grpc.ServiceDesc{session.Films_ServiceDesc, session.Sessions_ServiceDesc,
+		session.Users_ServiceDesc} {
+		for _, method := range service.Methods {
+			fullMethod := "/" + service.ServiceName + "/" + method.MethodName
+			assert.Contains(t, MethodPermissions, fullMethod)
+		}
+	}
+}
+
+func TestMethodOwners(t *testing.T) {
+	for method, ownership := range MethodOwners {
+		assert.Equal(t, PermissionAuthenticated, MethodPermissions[method], method)
+		assert.False(t, ownership.IsOwner(nil, "", ""), method)
+	}
+
+	ownership := MethodOwners[session.Users_ChangeUserPasswordByUuid_FullMethodName]
+	req := &session.ChangeUserPasswordByUuidRequest{Uuid: "uuid"}
+	assert.True(t, ownership.IsOwner(req, "uuid", "test@test.com"))
+	assert.False(t, ownership.IsOwner(req, "other-uuid", "test@test.com"))
+
+	ownership = MethodOwners[session.Films_RemoveComment_FullMethodName]
+	assert.True(t, ownership.IsOwner(&session.RemoveCommentRequest{
+		Comment: &session.CommentToRemove{AuthorUuid: "uuid"}}, "uuid", ""))
+	assert.Equal(t, PermissionCommentsModerate, ownership.Permission)
+}
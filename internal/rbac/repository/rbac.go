@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	myerrors "github.com/SanExpett/diploma/internal/errors"
+	"github.com/SanExpett/diploma/internal/rbac"
+)
+
+type PgxIface interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+type RbacStorage struct {
+	pool PgxIface
+}
+
+func NewRbacStorage(pool PgxIface) *RbacStorage {
+	return &RbacStorage{
+		pool: pool,
+	}
+}
+
+const getRolePermissions = `
+		SELECT role, permission
+		FROM role_permission;`
+
+func (storage *RbacStorage) LoadPolicy(ctx context.Context) (map[rbac.Role][]rbac.Permission, error) {
+	rows, err := storage.pool.Query(ctx, getRolePermissions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role permissions: %w: %w", err,
+			myerrors.ErrFailInQuery)
+	}
+	defer rows.Close()
+
+	rolePermissions := make(map[rbac.Role][]rbac.Permission)
+	for rows.Next() {
+		var role, permission string
+		err = rows.Scan(&role, &permission)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan role permission: %w: %w", err,
+				myerrors.ErrFailInForEachRow)
+		}
+		rolePermissions[rbac.Role(role)] = append(rolePermissions[rbac.Role(role)], rbac.Permission(permission))
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read role permissions: %w: %w", err,
+			myerrors.ErrFailInForEachRow)
+	}
+
+	return rolePermissions, nil
+}
@@ -0,0 +1,34 @@
+package rbac
+
+import (
+	"context"
+
+	session "github.com/SanExpett/diploma/internal/session/proto"
+)
+
+// UsersClientLoader загружает политику из сервиса пользователей, у gateway нет доступа к базе
+type UsersClientLoader struct {
+	usersClient *session.UsersClient
+}
+
+func NewUsersClientLoader(usersClient *session.UsersClient) *UsersClientLoader {
+	return &UsersClientLoader{
+		usersClient: usersClient,
+	}
+}
+
+func (loader *UsersClientLoader) LoadPolicy(ctx context.Context) (map[Role][]Permission, error) {
+	res, err := (*loader.usersClient).GetRolePermissions(ctx, &session.GetRolePermissionsRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	rolePermissions := make(map[Role][]Permission, len(res.Roles))
+	for _, role := range res.Roles {
+		for _, permission := range role.Permissions {
+			rolePermissions[Role(role.Role)] = append(rolePermissions[Role(role.Role)], Permission(permission))
+		}
+	}
+
+	return rolePermissions, nil
+}
@@ -9,8 +9,14 @@ type contextKey string
 
 const ReqIDKey contextKey = "req_id"
 
+// HeaderName заголовок, в котором клиент может передать свой id запроса, а gateway возвращает использованный
+const HeaderName = "X-Request-ID"
+
 const symbols = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ1234567890"
 
+// maxRequestIDLength ограничивает id от клиента, чтобы им нельзя было раздуть логи
+const maxRequestIDLength = 64
+
 func GenerateRequestID() string {
 	b := make([]byte, 10)
 	for i := range b {
@@ -26,3 +32,19 @@ func GenerateReqIdCTX(reqId string) context.Context {
 
 	return ctx
 }
+
+// RequestIDOrGenerate возвращает id, пришедший от клиента или другого сервиса, или новый, если id нет
+// или он недопустим. Разрешены только буквы, цифры и -_.: , чтобы id нельзя было подделать строку лога
+func RequestIDOrGenerate(requestID string) string {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return GenerateRequestID()
+	}
+	for _, symbol := range requestID {
+		isLetter := symbol >= 'a' && symbol <= 'z' || symbol >= 'A' && symbol <= 'Z'
+		isDigit := symbol >= '0' && symbol <= '9'
+		if !isLetter && !isDigit && symbol != '-' && symbol != '_' && symbol != '.' && symbol != ':' {
+			return GenerateRequestID()
+		}
+	}
+	return requestID
+}
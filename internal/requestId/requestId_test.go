@@ -2,9 +2,51 @@ package requestId
 
 import (
 	"fmt"
+	"strings"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestGenerateRequestID(t *testing.T) {
 	fmt.Println(GenerateRequestID())
 }
+
+func TestRequestIDOrGenerate(t *testing.T) {
+	tests := []struct {
+		name      string
+		requestID string
+		expectNew bool
+	}{
+		{
+			name:      "Id клиента",
+			requestID: "3f2b9c1e-7d4a-4e8b-9a61-0c5d2e8f7a13",
+		},
+		{
+			name:      "Нет id",
+			expectNew: true,
+		},
+		{
+			name:      "Перевод строки",
+			requestID: "abc\nfake log line",
+			expectNew: true,
+		},
+		{
+			name:      "Слишком длинный id",
+			requestID: strings.Repeat("a", maxRequestIDLength+1),
+			expectNew: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requestID := RequestIDOrGenerate(tt.requestID)
+			if tt.expectNew {
+				assert.NotEqual(t, tt.requestID, requestID)
+				assert.Len(t, requestID, 10)
+				return
+			}
+			assert.Equal(t, tt.requestID, requestID)
+		})
+	}
+}
@@ -25,7 +25,24 @@ const (
 	Sessions_CheckVersion_FullMethodName              = "/session.Sessions/CheckVersion"
 	Sessions_GetVersion_FullMethodName                = "/session.Sessions/GetVersion"
 	Sessions_HasSession_FullMethodName                = "/session.Sessions/HasSession"
-	Sessions_CheckAllUserSessionTokens_FullMethodName = "/session.Sessions/CheckAllUserSessionTokens"
+	Sessions_ListSessions_FullMethodName              = "/session.Sessions/ListSessions"
+	Sessions_RevokeSession_FullMethodName             = "/session.Sessions/RevokeSession"
+	Sessions_RevokeOtherSessions_FullMethodName       = "/session.Sessions/RevokeOtherSessions"
+	Sessions_IssueRefreshToken_FullMethodName         = "/session.Sessions/IssueRefreshToken"
+	Sessions_GetRefreshToken_FullMethodName           = "/session.Sessions/GetRefreshToken"
+	Sessions_RotateRefreshToken_FullMethodName        = "/session.Sessions/RotateRefreshToken"
+	Sessions_RevokeRefreshTokenFamily_FullMethodName  = "/session.Sessions/RevokeRefreshTokenFamily"
+	Sessions_ReserveLoginAttempt_FullMethodName       = "/session.Sessions/ReserveLoginAttempt"
+	Sessions_RegisterLoginFailure_FullMethodName      = "/session.Sessions/RegisterLoginFailure"
+	Sessions_ReleaseLoginAttempt_FullMethodName       = "/session.Sessions/ReleaseLoginAttempt"
+	Sessions_ResetLoginAttempts_FullMethodName        = "/session.Sessions/ResetLoginAttempts"
+	Sessions_UnlockLogin_FullMethodName               = "/session.Sessions/UnlockLogin"
+	Sessions_StartDeviceAuthorization_FullMethodName  = "/session.Sessions/StartDeviceAuthorization"
+	Sessions_GetDeviceAuthorization_FullMethodName    = "/session.Sessions/GetDeviceAuthorization"
+	Sessions_DecideDeviceAuthorization_FullMethodName = "/session.Sessions/DecideDeviceAuthorization"
+	Sessions_PollDeviceAuthorization_FullMethodName   = "/session.Sessions/PollDeviceAuthorization"
+	Sessions_SavePasskeyChallenge_FullMethodName      = "/session.Sessions/SavePasskeyChallenge"
+	Sessions_ConsumePasskeyChallenge_FullMethodName   = "/session.Sessions/ConsumePasskeyChallenge"
 )
 
 // SessionsClient is the client API for Sessions service.
@@ -38,7 +55,24 @@ type SessionsClient interface {
 	CheckVersion(ctx context.Context, in *CheckVersionRequest, opts ...grpc.CallOption) (*CheckVersionResponse, error)
 	GetVersion(ctx context.Context, in *GetVersionRequest, opts ...grpc.CallOption) (*GetVersionResponse, error)
 	HasSession(ctx context.Context, in *HasSessionRequest, opts ...grpc.CallOption) (*HasSessionResponse, error)
-	CheckAllUserSessionTokens(ctx context.Context, in *CheckAllUserSessionTokensRequest, opts ...grpc.CallOption) (*CheckAllUserSessionTokensResponse, error)
+	ListSessions(ctx context.Context, in *ListSessionsRequest, opts ...grpc.CallOption) (*ListSessionsResponse, error)
+	RevokeSession(ctx context.Context, in *RevokeSessionRequest, opts ...grpc.CallOption) (*RevokeSessionResponse, error)
+	RevokeOtherSessions(ctx context.Context, in *RevokeOtherSessionsRequest, opts ...grpc.CallOption) (*RevokeOtherSessionsResponse, error)
+	IssueRefreshToken(ctx context.Context, in *IssueRefreshTokenRequest, opts ...grpc.CallOption) (*IssueRefreshTokenResponse, error)
+	GetRefreshToken(ctx context.Context, in *GetRefreshTokenRequest, opts ...grpc.CallOption) (*GetRefreshTokenResponse, error)
+	RotateRefreshToken(ctx context.Context, in *RotateRefreshTokenRequest, opts ...grpc.CallOption) (*RotateRefreshTokenResponse, error)
+	RevokeRefreshTokenFamily(ctx context.Context, in *RevokeRefreshTokenFamilyRequest, opts ...grpc.CallOption) (*RevokeRefreshTokenFamilyResponse, error)
+	ReserveLoginAttempt(ctx context.Context, in *ReserveLoginAttemptRequest, opts ...grpc.CallOption) (*ReserveLoginAttemptResponse, error)
+	RegisterLoginFailure(ctx context.Context, in *RegisterLoginFailureRequest, opts ...grpc.CallOption) (*RegisterLoginFailureResponse, error)
+	ReleaseLoginAttempt(ctx context.Context, in *ReleaseLoginAttemptRequest, opts ...grpc.CallOption) (*ReleaseLoginAttemptResponse, error)
+	ResetLoginAttempts(ctx context.Context, in *ResetLoginAttemptsRequest, opts ...grpc.CallOption) (*ResetLoginAttemptsResponse, error)
+	UnlockLogin(ctx context.Context, in *UnlockLoginRequest, opts ...grpc.CallOption) (*UnlockLoginResponse, error)
+	StartDeviceAuthorization(ctx context.Context, in *StartDeviceAuthorizationRequest, opts ...grpc.CallOption) (*StartDeviceAuthorizationResponse, error)
+	GetDeviceAuthorization(ctx context.Context, in *GetDeviceAuthorizationRequest, opts ...grpc.CallOption) (*GetDeviceAuthorizationResponse, error)
+	DecideDeviceAuthorization(ctx context.Context, in *DecideDeviceAuthorizationRequest, opts ...grpc.CallOption) (*DecideDeviceAuthorizationResponse, error)
+	PollDeviceAuthorization(ctx context.Context, in *PollDeviceAuthorizationRequest, opts ...grpc.CallOption) (*PollDeviceAuthorizationResponse, error)
+	SavePasskeyChallenge(ctx context.Context, in *SavePasskeyChallengeRequest, opts ...grpc.CallOption) (*SavePasskeyChallengeResponse, error)
+	ConsumePasskeyChallenge(ctx context.Context, in *ConsumePasskeyChallengeRequest, opts ...grpc.CallOption) (*ConsumePasskeyChallengeResponse, error)
 }
 
 type sessionsClient struct {
@@ -103,9 +137,162 @@ func (c *sessionsClient) HasSession(ctx context.Context, in *HasSessionRequest,
 	return out, nil
 }
 
-func (c *sessionsClient) CheckAllUserSessionTokens(ctx context.Context, in *CheckAllUserSessionTokensRequest, opts ...grpc.CallOption) (*CheckAllUserSessionTokensResponse, error) {
-	out := new(CheckAllUserSessionTokensResponse)
-	err := c.cc.Invoke(ctx, Sessions_CheckAllUserSessionTokens_FullMethodName, in, out, opts...)
+func (c *sessionsClient) ListSessions(ctx context.Context, in *ListSessionsRequest, opts ...grpc.CallOption) (*ListSessionsResponse, error) {
+	out := new(ListSessionsResponse)
+	err := c.cc.Invoke(ctx, Sessions_ListSessions_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sessionsClient) RevokeSession(ctx context.Context, in *RevokeSessionRequest, opts ...grpc.CallOption) (*RevokeSessionResponse, error) {
+	out := new(RevokeSessionResponse)
+	err := c.cc.Invoke(ctx, Sessions_RevokeSession_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sessionsClient) RevokeOtherSessions(ctx context.Context, in *RevokeOtherSessionsRequest, opts ...grpc.CallOption) (*RevokeOtherSessionsResponse, error) {
+	out := new(RevokeOtherSessionsResponse)
+	err := c.cc.Invoke(ctx, Sessions_RevokeOtherSessions_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sessionsClient) IssueRefreshToken(ctx context.Context, in *IssueRefreshTokenRequest, opts ...grpc.CallOption) (*IssueRefreshTokenResponse, error) {
+	out := new(IssueRefreshTokenResponse)
+	err := c.cc.Invoke(ctx, Sessions_IssueRefreshToken_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sessionsClient) GetRefreshToken(ctx context.Context, in *GetRefreshTokenRequest, opts ...grpc.CallOption) (*GetRefreshTokenResponse, error) {
+	out := new(GetRefreshTokenResponse)
+	err := c.cc.Invoke(ctx, Sessions_GetRefreshToken_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sessionsClient) RotateRefreshToken(ctx context.Context, in *RotateRefreshTokenRequest, opts ...grpc.CallOption) (*RotateRefreshTokenResponse, error) {
+	out := new(RotateRefreshTokenResponse)
+	err := c.cc.Invoke(ctx, Sessions_RotateRefreshToken_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sessionsClient) RevokeRefreshTokenFamily(ctx context.Context, in *RevokeRefreshTokenFamilyRequest, opts ...grpc.CallOption) (*RevokeRefreshTokenFamilyResponse, error) {
+	out := new(RevokeRefreshTokenFamilyResponse)
+	err := c.cc.Invoke(ctx, Sessions_RevokeRefreshTokenFamily_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sessionsClient) ReserveLoginAttempt(ctx context.Context, in *ReserveLoginAttemptRequest, opts ...grpc.CallOption) (*ReserveLoginAttemptResponse, error) {
+	out := new(ReserveLoginAttemptResponse)
+	err := c.cc.Invoke(ctx, Sessions_ReserveLoginAttempt_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sessionsClient) RegisterLoginFailure(ctx context.Context, in *RegisterLoginFailureRequest, opts ...grpc.CallOption) (*RegisterLoginFailureResponse, error) {
+	out := new(RegisterLoginFailureResponse)
+	err := c.cc.Invoke(ctx, Sessions_RegisterLoginFailure_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sessionsClient) ReleaseLoginAttempt(ctx context.Context, in *ReleaseLoginAttemptRequest, opts ...grpc.CallOption) (*ReleaseLoginAttemptResponse, error) {
+	out := new(ReleaseLoginAttemptResponse)
+	err := c.cc.Invoke(ctx, Sessions_ReleaseLoginAttempt_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sessionsClient) ResetLoginAttempts(ctx context.Context, in *ResetLoginAttemptsRequest, opts ...grpc.CallOption) (*ResetLoginAttemptsResponse, error) {
+	out := new(ResetLoginAttemptsResponse)
+	err := c.cc.Invoke(ctx, Sessions_ResetLoginAttempts_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sessionsClient) UnlockLogin(ctx context.Context, in *UnlockLoginRequest, opts ...grpc.CallOption) (*UnlockLoginResponse, error) {
+	out := new(UnlockLoginResponse)
+	err := c.cc.Invoke(ctx, Sessions_UnlockLogin_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sessionsClient) StartDeviceAuthorization(ctx context.Context, in *StartDeviceAuthorizationRequest, opts ...grpc.CallOption) (*StartDeviceAuthorizationResponse, error) {
+	out := new(StartDeviceAuthorizationResponse)
+	err := c.cc.Invoke(ctx, Sessions_StartDeviceAuthorization_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sessionsClient) GetDeviceAuthorization(ctx context.Context, in *GetDeviceAuthorizationRequest, opts ...grpc.CallOption) (*GetDeviceAuthorizationResponse, error) {
+	out := new(GetDeviceAuthorizationResponse)
+	err := c.cc.Invoke(ctx, Sessions_GetDeviceAuthorization_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sessionsClient) DecideDeviceAuthorization(ctx context.Context, in *DecideDeviceAuthorizationRequest, opts ...grpc.CallOption) (*DecideDeviceAuthorizationResponse, error) {
+	out := new(DecideDeviceAuthorizationResponse)
+	err := c.cc.Invoke(ctx, Sessions_DecideDeviceAuthorization_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sessionsClient) PollDeviceAuthorization(ctx context.Context, in *PollDeviceAuthorizationRequest, opts ...grpc.CallOption) (*PollDeviceAuthorizationResponse, error) {
+	out := new(PollDeviceAuthorizationResponse)
+	err := c.cc.Invoke(ctx, Sessions_PollDeviceAuthorization_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sessionsClient) SavePasskeyChallenge(ctx context.Context, in *SavePasskeyChallengeRequest, opts ...grpc.CallOption) (*SavePasskeyChallengeResponse, error) {
+	out := new(SavePasskeyChallengeResponse)
+	err := c.cc.Invoke(ctx, Sessions_SavePasskeyChallenge_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sessionsClient) ConsumePasskeyChallenge(ctx context.Context, in *ConsumePasskeyChallengeRequest, opts ...grpc.CallOption) (*ConsumePasskeyChallengeResponse, error) {
+	out := new(ConsumePasskeyChallengeResponse)
+	err := c.cc.Invoke(ctx, Sessions_ConsumePasskeyChallenge_FullMethodName, in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -122,7 +309,24 @@ type SessionsServer interface {
 	CheckVersion(context.Context, *CheckVersionRequest) (*CheckVersionResponse, error)
 	GetVersion(context.Context, *GetVersionRequest) (*GetVersionResponse, error)
 	HasSession(context.Context, *HasSessionRequest) (*HasSessionResponse, error)
-	CheckAllUserSessionTokens(context.Context, *CheckAllUserSessionTokensRequest) (*CheckAllUserSessionTokensResponse, error)
+	ListSessions(context.Context, *ListSessionsRequest) (*ListSessionsResponse, error)
+	RevokeSession(context.Context, *RevokeSessionRequest) (*RevokeSessionResponse, error)
+	RevokeOtherSessions(context.Context, *RevokeOtherSessionsRequest) (*RevokeOtherSessionsResponse, error)
+	IssueRefreshToken(context.Context, *IssueRefreshTokenRequest) (*IssueRefreshTokenResponse, error)
+	GetRefreshToken(context.Context, *GetRefreshTokenRequest) (*GetRefreshTokenResponse, error)
+	RotateRefreshToken(context.Context, *RotateRefreshTokenRequest) (*RotateRefreshTokenResponse, error)
+	RevokeRefreshTokenFamily(context.Context, *RevokeRefreshTokenFamilyRequest) (*RevokeRefreshTokenFamilyResponse, error)
+	ReserveLoginAttempt(context.Context, *ReserveLoginAttemptRequest) (*ReserveLoginAttemptResponse, error)
+	RegisterLoginFailure(context.Context, *RegisterLoginFailureRequest) (*RegisterLoginFailureResponse, error)
+	ReleaseLoginAttempt(context.Context, *ReleaseLoginAttemptRequest) (*ReleaseLoginAttemptResponse, error)
+	ResetLoginAttempts(context.Context, *ResetLoginAttemptsRequest) (*ResetLoginAttemptsResponse, error)
+	UnlockLogin(context.Context, *UnlockLoginRequest) (*UnlockLoginResponse, error)
+	StartDeviceAuthorization(context.Context, *StartDeviceAuthorizationRequest) (*StartDeviceAuthorizationResponse, error)
+	GetDeviceAuthorization(context.Context, *GetDeviceAuthorizationRequest) (*GetDeviceAuthorizationResponse, error)
+	DecideDeviceAuthorization(context.Context, *DecideDeviceAuthorizationRequest) (*DecideDeviceAuthorizationResponse, error)
+	PollDeviceAuthorization(context.Context, *PollDeviceAuthorizationRequest) (*PollDeviceAuthorizationResponse, error)
+	SavePasskeyChallenge(context.Context, *SavePasskeyChallengeRequest) (*SavePasskeyChallengeResponse, error)
+	ConsumePasskeyChallenge(context.Context, *ConsumePasskeyChallengeRequest) (*ConsumePasskeyChallengeResponse, error)
 }
 
 // UnimplementedSessionsServer must be embedded to have forward compatible implementations.
@@ -147,8 +351,59 @@ func (UnimplementedSessionsServer) GetVersion(context.Context, *GetVersionReques
 func (UnimplementedSessionsServer) HasSession(context.Context, *HasSessionRequest) (*HasSessionResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method HasSession not implemented")
 }
-func (UnimplementedSessionsServer) CheckAllUserSessionTokens(context.Context, *CheckAllUserSessionTokensRequest) (*CheckAllUserSessionTokensResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method CheckAllUserSessionTokens not implemented")
+func (UnimplementedSessionsServer) ListSessions(context.Context, *ListSessionsRequest) (*ListSessionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSessions not implemented")
+}
+func (UnimplementedSessionsServer) RevokeSession(context.Context, *RevokeSessionRequest) (*RevokeSessionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevokeSession not implemented")
+}
+func (UnimplementedSessionsServer) RevokeOtherSessions(context.Context, *RevokeOtherSessionsRequest) (*RevokeOtherSessionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevokeOtherSessions not implemented")
+}
+func (UnimplementedSessionsServer) IssueRefreshToken(context.Context, *IssueRefreshTokenRequest) (*IssueRefreshTokenResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method IssueRefreshToken not implemented")
+}
+func (UnimplementedSessionsServer) GetRefreshToken(context.Context, *GetRefreshTokenRequest) (*GetRefreshTokenResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRefreshToken not implemented")
+}
+func (UnimplementedSessionsServer) RotateRefreshToken(context.Context, *RotateRefreshTokenRequest) (*RotateRefreshTokenResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RotateRefreshToken not implemented")
+}
+func (UnimplementedSessionsServer) RevokeRefreshTokenFamily(context.Context, *RevokeRefreshTokenFamilyRequest) (*RevokeRefreshTokenFamilyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevokeRefreshTokenFamily not implemented")
+}
+func (UnimplementedSessionsServer) ReserveLoginAttempt(context.Context, *ReserveLoginAttemptRequest) (*ReserveLoginAttemptResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReserveLoginAttempt not implemented")
+}
+func (UnimplementedSessionsServer) RegisterLoginFailure(context.Context, *RegisterLoginFailureRequest) (*RegisterLoginFailureResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RegisterLoginFailure not implemented")
+}
+func (UnimplementedSessionsServer) ReleaseLoginAttempt(context.Context, *ReleaseLoginAttemptRequest) (*ReleaseLoginAttemptResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReleaseLoginAttempt not implemented")
+}
+func (UnimplementedSessionsServer) ResetLoginAttempts(context.Context, *ResetLoginAttemptsRequest) (*ResetLoginAttemptsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResetLoginAttempts not implemented")
+}
+func (UnimplementedSessionsServer) UnlockLogin(context.Context, *UnlockLoginRequest) (*UnlockLoginResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnlockLogin not implemented")
+}
+func (UnimplementedSessionsServer) StartDeviceAuthorization(context.Context, *StartDeviceAuthorizationRequest) (*StartDeviceAuthorizationResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StartDeviceAuthorization not implemented")
+}
+func (UnimplementedSessionsServer) GetDeviceAuthorization(context.Context, *GetDeviceAuthorizationRequest) (*GetDeviceAuthorizationResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDeviceAuthorization not implemented")
+}
+func (UnimplementedSessionsServer) DecideDeviceAuthorization(context.Context, *DecideDeviceAuthorizationRequest) (*DecideDeviceAuthorizationResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DecideDeviceAuthorization not implemented")
+}
+func (UnimplementedSessionsServer) PollDeviceAuthorization(context.Context, *PollDeviceAuthorizationRequest) (*PollDeviceAuthorizationResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PollDeviceAuthorization not implemented")
+}
+func (UnimplementedSessionsServer) SavePasskeyChallenge(context.Context, *SavePasskeyChallengeRequest) (*SavePasskeyChallengeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SavePasskeyChallenge not implemented")
+}
+func (UnimplementedSessionsServer) ConsumePasskeyChallenge(context.Context, *ConsumePasskeyChallengeRequest) (*ConsumePasskeyChallengeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ConsumePasskeyChallenge not implemented")
 }
 func (UnimplementedSessionsServer) mustEmbedUnimplementedSessionsServer() {}
 
@@ -271,20 +526,326 @@ func _Sessions_HasSession_Handler(srv interface{}, ctx context.Context, dec func
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Sessions_CheckAllUserSessionTokens_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(CheckAllUserSessionTokensRequest)
+func _Sessions_ListSessions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSessionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SessionsServer).ListSessions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sessions_ListSessions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SessionsServer).ListSessions(ctx, req.(*ListSessionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sessions_RevokeSession_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevokeSessionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SessionsServer).RevokeSession(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sessions_RevokeSession_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SessionsServer).RevokeSession(ctx, req.(*RevokeSessionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sessions_RevokeOtherSessions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevokeOtherSessionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SessionsServer).RevokeOtherSessions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sessions_RevokeOtherSessions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SessionsServer).RevokeOtherSessions(ctx, req.(*RevokeOtherSessionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sessions_IssueRefreshToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IssueRefreshTokenRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SessionsServer).IssueRefreshToken(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sessions_IssueRefreshToken_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SessionsServer).IssueRefreshToken(ctx, req.(*IssueRefreshTokenRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sessions_GetRefreshToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRefreshTokenRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SessionsServer).GetRefreshToken(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sessions_GetRefreshToken_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SessionsServer).GetRefreshToken(ctx, req.(*GetRefreshTokenRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sessions_RotateRefreshToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RotateRefreshTokenRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SessionsServer).RotateRefreshToken(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sessions_RotateRefreshToken_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SessionsServer).RotateRefreshToken(ctx, req.(*RotateRefreshTokenRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sessions_RevokeRefreshTokenFamily_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevokeRefreshTokenFamilyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SessionsServer).RevokeRefreshTokenFamily(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sessions_RevokeRefreshTokenFamily_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SessionsServer).RevokeRefreshTokenFamily(ctx, req.(*RevokeRefreshTokenFamilyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sessions_ReserveLoginAttempt_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReserveLoginAttemptRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SessionsServer).ReserveLoginAttempt(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sessions_ReserveLoginAttempt_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SessionsServer).ReserveLoginAttempt(ctx, req.(*ReserveLoginAttemptRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sessions_RegisterLoginFailure_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegisterLoginFailureRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SessionsServer).RegisterLoginFailure(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sessions_RegisterLoginFailure_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SessionsServer).RegisterLoginFailure(ctx, req.(*RegisterLoginFailureRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sessions_ReleaseLoginAttempt_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReleaseLoginAttemptRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SessionsServer).ReleaseLoginAttempt(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sessions_ReleaseLoginAttempt_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SessionsServer).ReleaseLoginAttempt(ctx, req.(*ReleaseLoginAttemptRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sessions_ResetLoginAttempts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResetLoginAttemptsRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(SessionsServer).CheckAllUserSessionTokens(ctx, in)
+		return srv.(SessionsServer).ResetLoginAttempts(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: Sessions_CheckAllUserSessionTokens_FullMethodName,
+		FullMethod: Sessions_ResetLoginAttempts_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(SessionsServer).CheckAllUserSessionTokens(ctx, req.(*CheckAllUserSessionTokensRequest))
+		return srv.(SessionsServer).ResetLoginAttempts(ctx, req.(*ResetLoginAttemptsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sessions_UnlockLogin_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnlockLoginRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SessionsServer).UnlockLogin(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sessions_UnlockLogin_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SessionsServer).UnlockLogin(ctx, req.(*UnlockLoginRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sessions_StartDeviceAuthorization_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StartDeviceAuthorizationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SessionsServer).StartDeviceAuthorization(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sessions_StartDeviceAuthorization_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SessionsServer).StartDeviceAuthorization(ctx, req.(*StartDeviceAuthorizationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sessions_GetDeviceAuthorization_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDeviceAuthorizationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SessionsServer).GetDeviceAuthorization(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sessions_GetDeviceAuthorization_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SessionsServer).GetDeviceAuthorization(ctx, req.(*GetDeviceAuthorizationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sessions_DecideDeviceAuthorization_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DecideDeviceAuthorizationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SessionsServer).DecideDeviceAuthorization(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sessions_DecideDeviceAuthorization_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SessionsServer).DecideDeviceAuthorization(ctx, req.(*DecideDeviceAuthorizationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sessions_PollDeviceAuthorization_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PollDeviceAuthorizationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SessionsServer).PollDeviceAuthorization(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sessions_PollDeviceAuthorization_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SessionsServer).PollDeviceAuthorization(ctx, req.(*PollDeviceAuthorizationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sessions_SavePasskeyChallenge_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SavePasskeyChallengeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SessionsServer).SavePasskeyChallenge(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sessions_SavePasskeyChallenge_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SessionsServer).SavePasskeyChallenge(ctx, req.(*SavePasskeyChallengeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sessions_ConsumePasskeyChallenge_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ConsumePasskeyChallengeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SessionsServer).ConsumePasskeyChallenge(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sessions_ConsumePasskeyChallenge_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SessionsServer).ConsumePasskeyChallenge(ctx, req.(*ConsumePasskeyChallengeRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
@@ -321,8 +882,76 @@ var Sessions_ServiceDesc = grpc.ServiceDesc{
 			Handler:    _Sessions_HasSession_Handler,
 		},
 		{
-			MethodName: "CheckAllUserSessionTokens",
-			Handler:    _Sessions_CheckAllUserSessionTokens_Handler,
+			MethodName: "ListSessions",
+			Handler:    _Sessions_ListSessions_Handler,
+		},
+		{
+			MethodName: "RevokeSession",
+			Handler:    _Sessions_RevokeSession_Handler,
+		},
+		{
+			MethodName: "RevokeOtherSessions",
+			Handler:    _Sessions_RevokeOtherSessions_Handler,
+		},
+		{
+			MethodName: "IssueRefreshToken",
+			Handler:    _Sessions_IssueRefreshToken_Handler,
+		},
+		{
+			MethodName: "GetRefreshToken",
+			Handler:    _Sessions_GetRefreshToken_Handler,
+		},
+		{
+			MethodName: "RotateRefreshToken",
+			Handler:    _Sessions_RotateRefreshToken_Handler,
+		},
+		{
+			MethodName: "RevokeRefreshTokenFamily",
+			Handler:    _Sessions_RevokeRefreshTokenFamily_Handler,
+		},
+		{
+			MethodName: "ReserveLoginAttempt",
+			Handler:    _Sessions_ReserveLoginAttempt_Handler,
+		},
+		{
+			MethodName: "RegisterLoginFailure",
+			Handler:    _Sessions_RegisterLoginFailure_Handler,
+		},
+		{
+			MethodName: "ReleaseLoginAttempt",
+			Handler:    _Sessions_ReleaseLoginAttempt_Handler,
+		},
+		{
+			MethodName: "ResetLoginAttempts",
+			Handler:    _Sessions_ResetLoginAttempts_Handler,
+		},
+		{
+			MethodName: "UnlockLogin",
+			Handler:    _Sessions_UnlockLogin_Handler,
+		},
+		{
+			MethodName: "StartDeviceAuthorization",
+			Handler:    _Sessions_StartDeviceAuthorization_Handler,
+		},
+		{
+			MethodName: "GetDeviceAuthorization",
+			Handler:    _Sessions_GetDeviceAuthorization_Handler,
+		},
+		{
+			MethodName: "DecideDeviceAuthorization",
+			Handler:    _Sessions_DecideDeviceAuthorization_Handler,
+		},
+		{
+			MethodName: "PollDeviceAuthorization",
+			Handler:    _Sessions_PollDeviceAuthorization_Handler,
+		},
+		{
+			MethodName: "SavePasskeyChallenge",
+			Handler:    _Sessions_SavePasskeyChallenge_Handler,
+		},
+		{
+			MethodName: "ConsumePasskeyChallenge",
+			Handler:    _Sessions_ConsumePasskeyChallenge_Handler,
 		},
 	},
 	Streams:  []grpc.StreamDesc{},
@@ -2,21 +2,48 @@ package api
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
+
 	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
+	"github.com/SanExpett/diploma/internal/domain"
+	myerrors "github.com/SanExpett/diploma/internal/errors"
 	reqid "github.com/SanExpett/diploma/internal/requestId"
 	session "github.com/SanExpett/diploma/internal/session/proto"
+	"github.com/SanExpett/diploma/internal/tracing"
 )
 
 type SessionService interface {
-	Add(ctx context.Context, login string, token string, version uint32) (err error)
+	Add(ctx context.Context, login string, session domain.Session) (err error)
 	DeleteSession(ctx context.Context, login string, token string) (err error)
 	Update(ctx context.Context, login string, token string) (err error)
 	CheckVersion(ctx context.Context, login string, token string, usersVersion uint32) (hasSession bool, err error)
 	GetVersion(ctx context.Context, login string, token string) (version uint32, err error)
 	HasSession(ctx context.Context, login string, token string) error
-	CheckAllUserSessionTokens(ctx context.Context, login string) error
+	ListSessions(ctx context.Context, login string) ([]domain.Session, error)
+	RevokeSession(ctx context.Context, login string, token string) error
+	RevokeOtherSessions(ctx context.Context, login string, currentToken string) (revoked uint32, err error)
+	IssueRefreshToken(ctx context.Context, login string) (family string, refreshToken string, err error)
+	GetRefreshToken(ctx context.Context, refreshToken string) (login string, family string, err error)
+	RotateRefreshToken(ctx context.Context, refreshToken string) (login string, family string,
+		newRefreshToken string, err error)
+	RevokeRefreshTokenFamily(ctx context.Context, login string, family string) error
+	ReserveLoginAttempt(ctx context.Context, login string, ip string) (domain.LoginThrottle, error)
+	RegisterLoginFailure(ctx context.Context, login string, ip string) (domain.LoginThrottle, error)
+	ReleaseLoginAttempt(ctx context.Context, login string, ip string) error
+	ResetLoginAttempts(ctx context.Context, login string) error
+	UnlockLogin(ctx context.Context, login string, ip string) error
+	StartDeviceAuthorization(ctx context.Context, clientName string, ip string) (domain.DeviceCode, error)
+	GetDeviceAuthorization(ctx context.Context, userCode string) (domain.DeviceAuthorization, error)
+	DecideDeviceAuthorization(ctx context.Context, userCode string, login string, approve bool) error
+	PollDeviceAuthorization(ctx context.Context, deviceCode string) (status string, login string, err error)
+	SavePasskeyChallenge(ctx context.Context, challengeId string, expiresAt time.Time) error
+	ConsumePasskeyChallenge(ctx context.Context, challengeId string) error
 }
 
 type SessionSever struct {
@@ -33,9 +60,14 @@ func NewSessionServer(sessionsService SessionService, logger *zap.SugaredLogger)
 
 func (server *SessionSever) Add(ctx context.Context, req *session.AddRequest) (res *session.AddResponse, err error) {
 	requestId := ctx.Value(reqid.ReqIDKey)
-	err = server.sessionsService.Add(ctx, req.Login, req.Token, req.Version)
+	err = server.sessionsService.Add(ctx, req.Login, domain.Session{
+		Id:        req.Token,
+		Version:   req.Version,
+		UserAgent: req.UserAgent,
+		Ip:        req.Ip,
+	})
 	if err != nil {
-		server.logger.Errorf("[reqid=%s] failed to add session: %v\n", requestId, err)
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to add session: %v\n", requestId, err)
 		return nil, err
 	}
 	return &session.AddResponse{}, nil
@@ -46,7 +78,7 @@ func (server *SessionSever) DeleteSession(ctx context.Context,
 	requestId := ctx.Value(reqid.ReqIDKey)
 	err = server.sessionsService.DeleteSession(ctx, req.Login, req.Token)
 	if err != nil {
-		server.logger.Errorf("[reqid=%s] failed to delete session: %v\n", requestId, err)
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to delete session: %v\n", requestId, err)
 		return nil, fmt.Errorf("[reqid=%s] failed to delete session: %v\n", requestId, err)
 	}
 	return &session.DeleteSessionResponse{}, nil
@@ -57,7 +89,7 @@ func (server *SessionSever) Update(ctx context.Context,
 	requestId := ctx.Value(reqid.ReqIDKey)
 	err = server.sessionsService.Update(ctx, req.Login, req.Token)
 	if err != nil {
-		server.logger.Errorf("[reqid=%s] failed to update session: %v\n", requestId, err)
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to update session: %v\n", requestId, err)
 		return nil, fmt.Errorf("[reqid=%s] failed to update session: %v\n", requestId, err)
 	}
 	return &session.UpdateRequestResponse{}, nil
@@ -68,7 +100,7 @@ func (server *SessionSever) CheckVersion(ctx context.Context,
 	requestId := ctx.Value(reqid.ReqIDKey)
 	has, err := server.sessionsService.CheckVersion(ctx, req.Login, req.Token, req.Version)
 	if err != nil {
-		server.logger.Errorf("[reqid=%s] failed to check session version: %v\n", requestId, err)
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to check session version: %v\n", requestId, err)
 		return nil, fmt.Errorf("[reqid=%s] failed to check session version: %v\n", requestId, err)
 	}
 	return &session.CheckVersionResponse{
@@ -81,7 +113,7 @@ func (server *SessionSever) GetVersion(ctx context.Context,
 	requestId := ctx.Value(reqid.ReqIDKey)
 	version, err := server.sessionsService.GetVersion(ctx, req.Login, req.Token)
 	if err != nil {
-		server.logger.Errorf("[reqid=%s] failed to check session version: %v\n", requestId, err)
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to check session version: %v\n", requestId, err)
 		return nil, fmt.Errorf("[reqid=%s] failed to check session version: %v\n", requestId, err)
 	}
 	return &session.GetVersionResponse{
@@ -94,19 +126,279 @@ func (server *SessionSever) HasSession(ctx context.Context,
 	requestId := ctx.Value(reqid.ReqIDKey)
 	err = server.sessionsService.HasSession(ctx, req.Login, req.Token)
 	if err != nil {
-		server.logger.Errorf("[reqid=%s] failed to check session: %v\n", requestId, err)
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to check session: %v\n", requestId, err)
 		return nil, fmt.Errorf("[reqid=%s] failed to check session: %v\n", requestId, err)
 	}
 	return &session.HasSessionResponse{}, nil
 }
 
-func (server *SessionSever) CheckAllUserSessionTokens(ctx context.Context,
-	req *session.CheckAllUserSessionTokensRequest) (res *session.CheckAllUserSessionTokensResponse, err error) {
+func (server *SessionSever) ListSessions(ctx context.Context,
+	req *session.ListSessionsRequest) (res *session.ListSessionsResponse, err error) {
+	requestId := ctx.Value(reqid.ReqIDKey)
+	sessions, err := server.sessionsService.ListSessions(ctx, req.Login)
+	if err != nil {
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to list sessions: %v\n", requestId, err)
+		return nil, fmt.Errorf("[reqid=%s] failed to list sessions: %v\n", requestId, err)
+	}
+
+	sessionsProto := make([]*session.SessionInfo, 0, len(sessions))
+	for _, userSession := range sessions {
+		sessionsProto = append(sessionsProto, convertSessionToProto(userSession))
+	}
+	return &session.ListSessionsResponse{
+		Sessions: sessionsProto,
+	}, nil
+}
+
+func (server *SessionSever) RevokeSession(ctx context.Context,
+	req *session.RevokeSessionRequest) (res *session.RevokeSessionResponse, err error) {
+	requestId := ctx.Value(reqid.ReqIDKey)
+	err = server.sessionsService.RevokeSession(ctx, req.Login, req.Token)
+	if err != nil {
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to revoke session: %v\n", requestId, err)
+		return nil, fmt.Errorf("[reqid=%s] failed to revoke session: %v\n", requestId, err)
+	}
+	return &session.RevokeSessionResponse{}, nil
+}
+
+func (server *SessionSever) RevokeOtherSessions(ctx context.Context,
+	req *session.RevokeOtherSessionsRequest) (res *session.RevokeOtherSessionsResponse, err error) {
+	requestId := ctx.Value(reqid.ReqIDKey)
+	revoked, err := server.sessionsService.RevokeOtherSessions(ctx, req.Login, req.CurrentToken)
+	if err != nil {
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to revoke other sessions: %v\n", requestId, err)
+		return nil, fmt.Errorf("[reqid=%s] failed to revoke other sessions: %v\n", requestId, err)
+	}
+	return &session.RevokeOtherSessionsResponse{
+		Revoked: revoked,
+	}, nil
+}
+
+func (server *SessionSever) IssueRefreshToken(ctx context.Context,
+	req *session.IssueRefreshTokenRequest) (res *session.IssueRefreshTokenResponse, err error) {
+	requestId := ctx.Value(reqid.ReqIDKey)
+	family, refreshToken, err := server.sessionsService.IssueRefreshToken(ctx, req.Login)
+	if err != nil {
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to issue refresh token: %v\n", requestId, err)
+		return nil, fmt.Errorf("[reqid=%s] failed to issue refresh token: %v\n", requestId, err)
+	}
+	return &session.IssueRefreshTokenResponse{
+		Family:       family,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+func (server *SessionSever) GetRefreshToken(ctx context.Context,
+	req *session.GetRefreshTokenRequest) (res *session.GetRefreshTokenResponse, err error) {
+	requestId := ctx.Value(reqid.ReqIDKey)
+	login, family, err := server.sessionsService.GetRefreshToken(ctx, req.RefreshToken)
+	if err != nil {
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get refresh token: %v\n", requestId, err)
+		return nil, fmt.Errorf("[reqid=%s] failed to get refresh token: %v\n", requestId, err)
+	}
+	return &session.GetRefreshTokenResponse{
+		Login:  login,
+		Family: family,
+	}, nil
+}
+
+func (server *SessionSever) RotateRefreshToken(ctx context.Context,
+	req *session.RotateRefreshTokenRequest) (res *session.RotateRefreshTokenResponse, err error) {
+	requestId := ctx.Value(reqid.ReqIDKey)
+	login, family, refreshToken, err := server.sessionsService.RotateRefreshToken(ctx, req.RefreshToken)
+	if err != nil {
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to rotate refresh token: %v\n", requestId, err)
+		return nil, fmt.Errorf("[reqid=%s] failed to rotate refresh token: %v\n", requestId, err)
+	}
+	return &session.RotateRefreshTokenResponse{
+		Login:        login,
+		Family:       family,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+func (server *SessionSever) RevokeRefreshTokenFamily(ctx context.Context,
+	req *session.RevokeRefreshTokenFamilyRequest) (res *session.RevokeRefreshTokenFamilyResponse, err error) {
 	requestId := ctx.Value(reqid.ReqIDKey)
-	err = server.sessionsService.CheckAllUserSessionTokens(ctx, req.Login)
+	err = server.sessionsService.RevokeRefreshTokenFamily(ctx, req.Login, req.Family)
+	if err != nil {
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to revoke refresh token family: %v\n",
+			requestId, err)
+		return nil, fmt.Errorf("[reqid=%s] failed to revoke refresh token family: %v\n", requestId, err)
+	}
+	return &session.RevokeRefreshTokenFamilyResponse{}, nil
+}
+
+func (server *SessionSever) ReserveLoginAttempt(ctx context.Context,
+	req *session.ReserveLoginAttemptRequest) (res *session.ReserveLoginAttemptResponse, err error) {
+	requestId := ctx.Value(reqid.ReqIDKey)
+	throttle, err := server.sessionsService.ReserveLoginAttempt(ctx, req.Login, req.Ip)
+	if err != nil {
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to reserve login attempt: %v\n", requestId, err)
+		return nil, fmt.Errorf("[reqid=%s] failed to reserve login attempt: %v\n", requestId, err)
+	}
+	return &session.ReserveLoginAttemptResponse{
+		RetryAfter: retryAfterSeconds(throttle.RetryAfter),
+		Locked:     throttle.Locked,
+	}, nil
+}
+
+func (server *SessionSever) RegisterLoginFailure(ctx context.Context,
+	req *session.RegisterLoginFailureRequest) (res *session.RegisterLoginFailureResponse, err error) {
+	requestId := ctx.Value(reqid.ReqIDKey)
+	throttle, err := server.sessionsService.RegisterLoginFailure(ctx, req.Login, req.Ip)
+	if err != nil {
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to register login failure: %v\n",
+			requestId, err)
+		return nil, fmt.Errorf("[reqid=%s] failed to register login failure: %v\n", requestId, err)
+	}
+	return &session.RegisterLoginFailureResponse{
+		RetryAfter: retryAfterSeconds(throttle.RetryAfter),
+		Locked:     throttle.Locked,
+	}, nil
+}
+
+func (server *SessionSever) ReleaseLoginAttempt(ctx context.Context,
+	req *session.ReleaseLoginAttemptRequest) (res *session.ReleaseLoginAttemptResponse, err error) {
+	requestId := ctx.Value(reqid.ReqIDKey)
+	err = server.sessionsService.ReleaseLoginAttempt(ctx, req.Login, req.Ip)
+	if err != nil {
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to release login attempt: %v\n", requestId, err)
+		return nil, fmt.Errorf("[reqid=%s] failed to release login attempt: %v\n", requestId, err)
+	}
+	return &session.ReleaseLoginAttemptResponse{}, nil
+}
+
+func (server *SessionSever) ResetLoginAttempts(ctx context.Context,
+	req *session.ResetLoginAttemptsRequest) (res *session.ResetLoginAttemptsResponse, err error) {
+	requestId := ctx.Value(reqid.ReqIDKey)
+	err = server.sessionsService.ResetLoginAttempts(ctx, req.Login)
+	if err != nil {
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to reset login attempts: %v\n", requestId, err)
+		return nil, fmt.Errorf("[reqid=%s] failed to reset login attempts: %v\n", requestId, err)
+	}
+	return &session.ResetLoginAttemptsResponse{}, nil
+}
+
+func (server *SessionSever) UnlockLogin(ctx context.Context,
+	req *session.UnlockLoginRequest) (res *session.UnlockLoginResponse, err error) {
+	requestId := ctx.Value(reqid.ReqIDKey)
+	err = server.sessionsService.UnlockLogin(ctx, req.Login, req.Ip)
+	if err != nil {
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to unlock login: %v\n", requestId, err)
+		return nil, fmt.Errorf("[reqid=%s] failed to unlock login: %v\n", requestId, err)
+	}
+	return &session.UnlockLoginResponse{}, nil
+}
+
+func (server *SessionSever) StartDeviceAuthorization(ctx context.Context,
+	req *session.StartDeviceAuthorizationRequest) (res *session.StartDeviceAuthorizationResponse, err error) {
+	requestId := ctx.Value(reqid.ReqIDKey)
+	deviceCode, err := server.sessionsService.StartDeviceAuthorization(ctx, req.ClientName, req.Ip)
+	if err != nil {
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to start device authorization: %v\n",
+			requestId, err)
+		return nil, fmt.Errorf("[reqid=%s] failed to start device authorization: %v\n", requestId, err)
+	}
+	return &session.StartDeviceAuthorizationResponse{
+		DeviceCode: deviceCode.DeviceCode,
+		UserCode:   deviceCode.UserCode,
+		ExpiresIn:  int64(deviceCode.ExpiresIn / time.Second),
+		Interval:   int64(deviceCode.Interval / time.Second),
+	}, nil
+}
+
+func (server *SessionSever) GetDeviceAuthorization(ctx context.Context,
+	req *session.GetDeviceAuthorizationRequest) (res *session.GetDeviceAuthorizationResponse, err error) {
+	requestId := ctx.Value(reqid.ReqIDKey)
+	authorization, err := server.sessionsService.GetDeviceAuthorization(ctx, req.UserCode)
+	if errors.Is(err, myerrors.ErrNoSuchDeviceAuthorization) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get device authorization: %v\n",
+			requestId, err)
+		return nil, fmt.Errorf("[reqid=%s] failed to get device authorization: %v\n", requestId, err)
+	}
+	return &session.GetDeviceAuthorizationResponse{
+		UserCode:   authorization.UserCode,
+		ClientName: authorization.ClientName,
+		Ip:         authorization.Ip,
+		CreatedAt:  timestamppb.New(authorization.CreatedAt),
+	}, nil
+}
+
+func (server *SessionSever) DecideDeviceAuthorization(ctx context.Context,
+	req *session.DecideDeviceAuthorizationRequest) (res *session.DecideDeviceAuthorizationResponse, err error) {
+	requestId := ctx.Value(reqid.ReqIDKey)
+	err = server.sessionsService.DecideDeviceAuthorization(ctx, req.UserCode, req.Login, req.Approve)
+	if errors.Is(err, myerrors.ErrNoSuchDeviceAuthorization) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to decide device authorization: %v\n",
+			requestId, err)
+		return nil, fmt.Errorf("[reqid=%s] failed to decide device authorization: %v\n", requestId, err)
+	}
+	return &session.DecideDeviceAuthorizationResponse{}, nil
+}
+
+func (server *SessionSever) PollDeviceAuthorization(ctx context.Context,
+	req *session.PollDeviceAuthorizationRequest) (res *session.PollDeviceAuthorizationResponse, err error) {
+	requestId := ctx.Value(reqid.ReqIDKey)
+	authorizationStatus, login, err := server.sessionsService.PollDeviceAuthorization(ctx, req.DeviceCode)
+	if err != nil {
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to poll device authorization: %v\n",
+			requestId, err)
+		return nil, fmt.Errorf("[reqid=%s] failed to poll device authorization: %v\n", requestId, err)
+	}
+	return &session.PollDeviceAuthorizationResponse{
+		Status: authorizationStatus,
+		Login:  login,
+	}, nil
+}
+
+// retryAfterSeconds округляет задержку вверх до целых секунд, чтобы клиент не повторил попытку раньше
+func retryAfterSeconds(retryAfter time.Duration) int64 {
+	return int64((retryAfter + time.Second - 1) / time.Second)
+}
+
+func convertSessionToProto(userSession domain.Session) *session.SessionInfo {
+	return &session.SessionInfo{
+		Id:         userSession.Id,
+		UserAgent:  userSession.UserAgent,
+		Ip:         userSession.Ip,
+		CreatedAt:  timestamppb.New(userSession.CreatedAt),
+		LastSeenAt: timestamppb.New(userSession.LastSeenAt),
+	}
+}
+
+func (server *SessionSever) SavePasskeyChallenge(ctx context.Context,
+	req *session.SavePasskeyChallengeRequest) (res *session.SavePasskeyChallengeResponse, err error) {
+	requestId := ctx.Value(reqid.ReqIDKey)
+	err = server.sessionsService.SavePasskeyChallenge(ctx, req.Id, req.ExpiresAt.AsTime())
+	if errors.Is(err, myerrors.ErrItemsIsAlreadyInTheCache) {
+		return nil, status.Error(codes.AlreadyExists, err.Error())
+	}
+	if err != nil {
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to save passkey challenge: %v\n",
+			requestId, err)
+		return nil, fmt.Errorf("[reqid=%s] failed to save passkey challenge: %v\n", requestId, err)
+	}
+	return &session.SavePasskeyChallengeResponse{}, nil
+}
+
+func (server *SessionSever) ConsumePasskeyChallenge(ctx context.Context,
+	req *session.ConsumePasskeyChallengeRequest) (res *session.ConsumePasskeyChallengeResponse, err error) {
+	requestId := ctx.Value(reqid.ReqIDKey)
+	err = server.sessionsService.ConsumePasskeyChallenge(ctx, req.Id)
+	if errors.Is(err, myerrors.ErrNoSuchPasskeyChallenge) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
 	if err != nil {
-		server.logger.Errorf("[reqid=%s] failed to check all user session tokens: %v\n", requestId, err)
-		return nil, fmt.Errorf("[reqid=%s] failed to check all user session tokens: %v\n", requestId, err)
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to consume passkey challenge: %v\n",
+			requestId, err)
+		return nil, fmt.Errorf("[reqid=%s] failed to consume passkey challenge: %v\n", requestId, err)
 	}
-	return &session.CheckAllUserSessionTokensResponse{}, nil
+	return &session.ConsumePasskeyChallengeResponse{}, nil
 }
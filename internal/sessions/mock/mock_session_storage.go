@@ -6,7 +6,9 @@ package mock
 
 import (
 	reflect "reflect"
+	time "time"
 
+	domain "github.com/SanExpett/diploma/internal/domain"
 	gomock "github.com/golang/mock/gomock"
 )
 
@@ -34,33 +36,18 @@ func (m *MocksessionStorage) EXPECT() *MocksessionStorageMockRecorder {
 }
 
 // Add mocks base method.
-func (m *MocksessionStorage) Add(login, token string, version uint32) error {
+func (m *MocksessionStorage) Add(login string, session domain.Session) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Add", login, token, version)
+	ret := m.ctrl.Call(m, "Add", login, session)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Add indicates an expected call of Add.
-func (mr *MocksessionStorageMockRecorder) Add(login, token, version interface{}) *gomock.Call {
+func (mr *MocksessionStorageMockRecorder) Add(login, session interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Add",
-		reflect.TypeOf((*MocksessionStorage)(nil).Add), login, token, version)
-}
-
-// CheckAllUserSessionTokens mocks base method.
-func (m *MocksessionStorage) CheckAllUserSessionTokens(login string) error {
-	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CheckAllUserSessionTokens", login)
-	ret0, _ := ret[0].(error)
-	return ret0
-}
-
-// CheckAllUserSessionTokens indicates an expected call of CheckAllUserSessionTokens.
-func (mr *MocksessionStorageMockRecorder) CheckAllUserSessionTokens(login interface{}) *gomock.Call {
-	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckAllUserSessionTokens",
-		reflect.TypeOf((*MocksessionStorage)(nil).CheckAllUserSessionTokens), login)
+		reflect.TypeOf((*MocksessionStorage)(nil).Add), login, session)
 }
 
 // CheckVersion mocks base method.
@@ -79,6 +66,53 @@ func (mr *MocksessionStorageMockRecorder) CheckVersion(login, token, usersVersio
 		reflect.TypeOf((*MocksessionStorage)(nil).CheckVersion), login, token, usersVersion)
 }
 
+// ConsumePasskeyChallenge mocks base method.
+func (m *MocksessionStorage) ConsumePasskeyChallenge(challengeId string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumePasskeyChallenge", challengeId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ConsumePasskeyChallenge indicates an expected call of ConsumePasskeyChallenge.
+func (mr *MocksessionStorageMockRecorder) ConsumePasskeyChallenge(challengeId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumePasskeyChallenge",
+		reflect.TypeOf((*MocksessionStorage)(nil).ConsumePasskeyChallenge), challengeId)
+}
+
+// ConsumeRefreshToken mocks base method.
+func (m *MocksessionStorage) ConsumeRefreshToken(tokenHash string) (domain.RefreshToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumeRefreshToken", tokenHash)
+	ret0, _ := ret[0].(domain.RefreshToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConsumeRefreshToken indicates an expected call of ConsumeRefreshToken.
+func (mr *MocksessionStorageMockRecorder) ConsumeRefreshToken(tokenHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeRefreshToken",
+		reflect.TypeOf((*MocksessionStorage)(nil).ConsumeRefreshToken), tokenHash)
+}
+
+// DecideDeviceAuthorization mocks base method.
+func (m *MocksessionStorage) DecideDeviceAuthorization(userCode, login string, approved bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DecideDeviceAuthorization", userCode, login, approved)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DecideDeviceAuthorization indicates an expected call of DecideDeviceAuthorization.
+func (mr *MocksessionStorageMockRecorder) DecideDeviceAuthorization(userCode, login,
+	approved interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DecideDeviceAuthorization",
+		reflect.TypeOf((*MocksessionStorage)(nil).DecideDeviceAuthorization), userCode, login, approved)
+}
+
 // DeleteSession mocks base method.
 func (m *MocksessionStorage) DeleteSession(login, token string) error {
 	m.ctrl.T.Helper()
@@ -94,6 +128,54 @@ func (mr *MocksessionStorageMockRecorder) DeleteSession(login, token interface{}
 		reflect.TypeOf((*MocksessionStorage)(nil).DeleteSession), login, token)
 }
 
+// GetDeviceAuthorization mocks base method.
+func (m *MocksessionStorage) GetDeviceAuthorization(userCode string) (domain.DeviceAuthorization, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDeviceAuthorization", userCode)
+	ret0, _ := ret[0].(domain.DeviceAuthorization)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDeviceAuthorization indicates an expected call of GetDeviceAuthorization.
+func (mr *MocksessionStorageMockRecorder) GetDeviceAuthorization(userCode interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDeviceAuthorization",
+		reflect.TypeOf((*MocksessionStorage)(nil).GetDeviceAuthorization), userCode)
+}
+
+// GetLoginAttempts mocks base method.
+func (m *MocksessionStorage) GetLoginAttempts(key string) (domain.LoginAttempts, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLoginAttempts", key)
+	ret0, _ := ret[0].(domain.LoginAttempts)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLoginAttempts indicates an expected call of GetLoginAttempts.
+func (mr *MocksessionStorageMockRecorder) GetLoginAttempts(key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLoginAttempts",
+		reflect.TypeOf((*MocksessionStorage)(nil).GetLoginAttempts), key)
+}
+
+// GetRefreshToken mocks base method.
+func (m *MocksessionStorage) GetRefreshToken(tokenHash string) (domain.RefreshToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRefreshToken", tokenHash)
+	ret0, _ := ret[0].(domain.RefreshToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRefreshToken indicates an expected call of GetRefreshToken.
+func (mr *MocksessionStorageMockRecorder) GetRefreshToken(tokenHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRefreshToken",
+		reflect.TypeOf((*MocksessionStorage)(nil).GetRefreshToken), tokenHash)
+}
+
 // GetVersion mocks base method.
 func (m *MocksessionStorage) GetVersion(login, token string) (uint32, error) {
 	m.ctrl.T.Helper()
@@ -125,6 +207,165 @@ func (mr *MocksessionStorageMockRecorder) HasSession(login, token interface{}) *
 		reflect.TypeOf((*MocksessionStorage)(nil).HasSession), login, token)
 }
 
+// ListSessions mocks base method.
+func (m *MocksessionStorage) ListSessions(login string) ([]domain.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSessions", login)
+	ret0, _ := ret[0].([]domain.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSessions indicates an expected call of ListSessions.
+func (mr *MocksessionStorageMockRecorder) ListSessions(login interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSessions",
+		reflect.TypeOf((*MocksessionStorage)(nil).ListSessions), login)
+}
+
+// PollDeviceAuthorization mocks base method.
+func (m *MocksessionStorage) PollDeviceAuthorization(deviceCodeHash string,
+	polledAt time.Time) (domain.DeviceAuthorization, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PollDeviceAuthorization", deviceCodeHash, polledAt)
+	ret0, _ := ret[0].(domain.DeviceAuthorization)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PollDeviceAuthorization indicates an expected call of PollDeviceAuthorization.
+func (mr *MocksessionStorageMockRecorder) PollDeviceAuthorization(deviceCodeHash, polledAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PollDeviceAuthorization",
+		reflect.TypeOf((*MocksessionStorage)(nil).PollDeviceAuthorization), deviceCodeHash, polledAt)
+}
+
+// ReleaseLoginAttempt mocks base method.
+func (m *MocksessionStorage) ReleaseLoginAttempt(key string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseLoginAttempt", key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReleaseLoginAttempt indicates an expected call of ReleaseLoginAttempt.
+func (mr *MocksessionStorageMockRecorder) ReleaseLoginAttempt(key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseLoginAttempt",
+		reflect.TypeOf((*MocksessionStorage)(nil).ReleaseLoginAttempt), key)
+}
+
+// ReserveLoginAttempt mocks base method.
+func (m *MocksessionStorage) ReserveLoginAttempt(key string, now time.Time, window time.Duration,
+	policy domain.LoginPolicy) (domain.LoginAttempts, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReserveLoginAttempt", key, now, window, policy)
+	ret0, _ := ret[0].(domain.LoginAttempts)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ReserveLoginAttempt indicates an expected call of ReserveLoginAttempt.
+func (mr *MocksessionStorageMockRecorder) ReserveLoginAttempt(key, now, window, policy interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReserveLoginAttempt",
+		reflect.TypeOf((*MocksessionStorage)(nil).ReserveLoginAttempt), key, now, window, policy)
+}
+
+// ResetLoginAttempts mocks base method.
+func (m *MocksessionStorage) ResetLoginAttempts(key string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetLoginAttempts", key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResetLoginAttempts indicates an expected call of ResetLoginAttempts.
+func (mr *MocksessionStorageMockRecorder) ResetLoginAttempts(key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetLoginAttempts",
+		reflect.TypeOf((*MocksessionStorage)(nil).ResetLoginAttempts), key)
+}
+
+// RevokeRefreshTokenFamily mocks base method.
+func (m *MocksessionStorage) RevokeRefreshTokenFamily(family string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeRefreshTokenFamily", family)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeRefreshTokenFamily indicates an expected call of RevokeRefreshTokenFamily.
+func (mr *MocksessionStorageMockRecorder) RevokeRefreshTokenFamily(family interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeRefreshTokenFamily",
+		reflect.TypeOf((*MocksessionStorage)(nil).RevokeRefreshTokenFamily), family)
+}
+
+// SaveDeviceAuthorization mocks base method.
+func (m *MocksessionStorage) SaveDeviceAuthorization(deviceCodeHash string,
+	authorization domain.DeviceAuthorization, ttl time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveDeviceAuthorization", deviceCodeHash, authorization, ttl)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveDeviceAuthorization indicates an expected call of SaveDeviceAuthorization.
+func (mr *MocksessionStorageMockRecorder) SaveDeviceAuthorization(deviceCodeHash, authorization,
+	ttl interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveDeviceAuthorization",
+		reflect.TypeOf((*MocksessionStorage)(nil).SaveDeviceAuthorization), deviceCodeHash, authorization, ttl)
+}
+
+// SavePasskeyChallenge mocks base method.
+func (m *MocksessionStorage) SavePasskeyChallenge(challengeId string, expiresAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SavePasskeyChallenge", challengeId, expiresAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SavePasskeyChallenge indicates an expected call of SavePasskeyChallenge.
+func (mr *MocksessionStorageMockRecorder) SavePasskeyChallenge(challengeId, expiresAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SavePasskeyChallenge",
+		reflect.TypeOf((*MocksessionStorage)(nil).SavePasskeyChallenge), challengeId, expiresAt)
+}
+
+// SaveRefreshToken mocks base method.
+func (m *MocksessionStorage) SaveRefreshToken(tokenHash string, refreshToken domain.RefreshToken,
+	ttl time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveRefreshToken", tokenHash, refreshToken, ttl)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveRefreshToken indicates an expected call of SaveRefreshToken.
+func (mr *MocksessionStorageMockRecorder) SaveRefreshToken(tokenHash, refreshToken, ttl interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveRefreshToken",
+		reflect.TypeOf((*MocksessionStorage)(nil).SaveRefreshToken), tokenHash, refreshToken, ttl)
+}
+
+// TouchSession mocks base method.
+func (m *MocksessionStorage) TouchSession(login, token string, seenAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TouchSession", login, token, seenAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TouchSession indicates an expected call of TouchSession.
+func (mr *MocksessionStorageMockRecorder) TouchSession(login, token, seenAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TouchSession",
+		reflect.TypeOf((*MocksessionStorage)(nil).TouchSession), login, token, seenAt)
+}
+
 // Update mocks base method.
 func (m *MocksessionStorage) Update(login, token string) error {
 	m.ctrl.T.Helper()
@@ -4,10 +4,13 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
+	"sort"
+	"strconv"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 
+	"github.com/SanExpett/diploma/internal/domain"
 	myerrors "github.com/SanExpett/diploma/internal/errors"
 )
 
@@ -15,218 +18,381 @@ var (
 	maxVersion uint32 = 255
 )
 
+const (
+	lastSeenInterval = time.Minute
+
+	sessionPrefix      = "session:"
+	sessionIndexPrefix = "sessions:"
+
+	refreshTokenPrefix       = "refresh:"
+	refreshTokenFamilyPrefix = "refresh_family:"
+)
+
+// Коды ошибок, которые возвращают скрипты изменения сессий
+const (
+	scriptNoSuchUser     = -1
+	scriptNoSuchSession  = -2
+	scriptTooHighVersion = -3
+)
+
+// Каждая сессия хранится в отдельном хеше со своим TTL, множество sessions:{login} хранит идентификаторы
+// сессий пользователя. Логин в фигурных скобках кладет все ключи пользователя в один слот Redis Cluster,
+// поэтому скрипты могут менять их атомарно
+var (
+	addScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[2]) == 1 then
+	return 0
+end
+redis.call('HSET', KEYS[2], 'version', ARGV[3], 'userAgent', ARGV[4], 'ip', ARGV[5],
+	'createdAt', ARGV[6], 'lastSeenAt', ARGV[7])
+redis.call('PEXPIRE', KEYS[2], ARGV[2])
+redis.call('SADD', KEYS[1], ARGV[1])
+redis.call('PEXPIRE', KEYS[1], ARGV[2])
+return 1
+`)
+
+	deleteScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return -1
+end
+local removed = redis.call('SREM', KEYS[1], ARGV[1])
+local deleted = redis.call('DEL', KEYS[2])
+if removed == 0 and deleted == 0 then
+	return -2
+end
+return 1
+`)
+
+	updateScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return -1
+end
+local version = redis.call('HGET', KEYS[2], 'version')
+if not version then
+	return -2
+end
+if tonumber(version) >= tonumber(ARGV[1]) then
+	return -3
+end
+return redis.call('HINCRBY', KEYS[2], 'version', 1)
+`)
+
+	touchScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return -1
+end
+local lastSeenAt = redis.call('HGET', KEYS[2], 'lastSeenAt')
+if not lastSeenAt then
+	return -2
+end
+if tonumber(ARGV[1]) - tonumber(lastSeenAt) < tonumber(ARGV[2]) then
+	return 0
+end
+redis.call('HSET', KEYS[2], 'lastSeenAt', ARGV[1])
+redis.call('PEXPIRE', KEYS[2], ARGV[3])
+redis.call('PEXPIRE', KEYS[1], ARGV[3])
+return 1
+`)
+)
+
 type SessionStorage struct {
-	redisClient *redis.Client
+	redisClient redis.UniversalClient
+	sessionTTL  time.Duration
 }
 
-func NewSessionStorage() *SessionStorage {
-	client := redis.NewClient(&redis.Options{
-		Addr:     "redis:6379",
-		Password: "",
-		DB:       0,
-	})
-
+// NewSessionStorage создает хранилище, в котором сессия живет sessionTTL с последней активности
+func NewSessionStorage(redisClient redis.UniversalClient, sessionTTL time.Duration) *SessionStorage {
 	return &SessionStorage{
-		redisClient: client,
+		redisClient: redisClient,
+		sessionTTL:  sessionTTL,
 	}
 }
 
-func (sessionStorage *SessionStorage) Add(login string, token string, version uint32) error {
+func (sessionStorage *SessionStorage) Add(login string, session domain.Session) error {
 	ctx := context.Background()
 
-	// Получаем текущие сессии пользователя
-	sessions := make(map[string]uint32)
-	val, err := sessionStorage.redisClient.Get(ctx, login).Result()
-	if err != nil && err != redis.Nil {
+	added, err := addScript.Run(ctx, sessionStorage.redisClient,
+		[]string{sessionIndexKey(login), sessionKey(login, session.Id)},
+		session.Id, sessionStorage.sessionTTL.Milliseconds(), session.Version, session.UserAgent, session.Ip,
+		session.CreatedAt.UnixMilli(), session.LastSeenAt.UnixMilli()).Int()
+	if err != nil {
 		return err
 	}
 
-	if err != redis.Nil {
-		if err := json.Unmarshal([]byte(val), &sessions); err != nil {
-			return err
-		}
-
-		if _, exists := sessions[token]; exists {
-			return myerrors.ErrItemsIsAlreadyInTheCache
-		}
+	if added == 0 {
+		return myerrors.ErrItemsIsAlreadyInTheCache
 	}
 
-	sessions[token] = version
+	return nil
+}
 
-	// Сохраняем обновленные сессии
-	sessionsJSON, err := json.Marshal(sessions)
+func (sessionStorage *SessionStorage) DeleteSession(login string, token string) error {
+	code, err := deleteScript.Run(context.Background(), sessionStorage.redisClient,
+		[]string{sessionIndexKey(login), sessionKey(login, token)}, token).Int()
 	if err != nil {
 		return err
 	}
 
-	return sessionStorage.redisClient.Set(ctx, login, sessionsJSON, 0).Err()
+	return scriptError(code)
 }
 
-func (sessionStorage *SessionStorage) DeleteSession(login string, token string) error {
-	ctx := context.Background()
-
-	sessions := make(map[string]uint32)
-	val, err := sessionStorage.redisClient.Get(ctx, login).Result()
-	if err == redis.Nil {
-		return myerrors.ErrNoSuchUserInTheCache
-	}
+func (sessionStorage *SessionStorage) Update(login string, token string) error {
+	code, err := updateScript.Run(context.Background(), sessionStorage.redisClient,
+		[]string{sessionIndexKey(login), sessionKey(login, token)}, maxVersion).Int()
 	if err != nil {
 		return err
 	}
 
-	if err := json.Unmarshal([]byte(val), &sessions); err != nil {
-		return err
+	return scriptError(code)
+}
+
+func (sessionStorage *SessionStorage) CheckVersion(login string, token string, usersVersion uint32) (bool, error) {
+	version, err := sessionStorage.GetVersion(login, token)
+	if err != nil {
+		return false, err
 	}
 
-	if _, exists := sessions[token]; !exists {
-		return myerrors.ErrNoSuchSessionInTheCache
+	if version == usersVersion {
+		return true, nil
 	}
 
-	delete(sessions, token)
+	return false, myerrors.ErrWrongSessionVersion
+}
 
-	sessionsJSON, err := json.Marshal(sessions)
+// HasSession проверяет наличие активной сессии для пользователя
+// Возвращает:
+//   - nil если сессия существует
+//   - ErrNoSuchUser если сессия не найдена или истекла
+//   - другие ошибки при проблемах с Redis
+func (sessionStorage *SessionStorage) HasSession(login string, token string) error {
+	exists, err := sessionStorage.redisClient.Exists(context.Background(), sessionKey(login, token)).Result()
 	if err != nil {
 		return err
 	}
+	if exists == 0 {
+		return myerrors.ErrNoSuchUser
+	}
+	return nil
+}
 
-	return sessionStorage.redisClient.Set(ctx, login, sessionsJSON, 0).Err()
+func (sessionStorage *SessionStorage) GetVersion(login string, token string) (uint32, error) {
+	version, err := sessionStorage.redisClient.HGet(context.Background(), sessionKey(login, token),
+		"version").Uint64()
+	if errors.Is(err, redis.Nil) {
+		return 0, myerrors.ErrNoSuchItemInTheCache
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return uint32(version), nil
 }
 
-func (sessionStorage *SessionStorage) Update(login string, token string) error {
+// ListSessions возвращает сессии пользователя, начиная с последней активной. Идентификаторы
+// истекших сессий удаляются из индекса
+func (sessionStorage *SessionStorage) ListSessions(login string) ([]domain.Session, error) {
 	ctx := context.Background()
+	indexKey := sessionIndexKey(login)
 
-	sessions := make(map[string]uint32)
-	val, err := sessionStorage.redisClient.Get(ctx, login).Result()
-	if err == redis.Nil {
-		return myerrors.ErrNoSuchUserInTheCache
-	}
+	sessionIds, err := sessionStorage.redisClient.SMembers(ctx, indexKey).Result()
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if len(sessionIds) == 0 {
+		return nil, myerrors.ErrNoSuchUserInTheCache
 	}
 
-	if err := json.Unmarshal([]byte(val), &sessions); err != nil {
-		return err
+	cmds := make([]*redis.MapStringStringCmd, 0, len(sessionIds))
+	_, err = sessionStorage.redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, sessionId := range sessionIds {
+			cmds = append(cmds, pipe.HGetAll(ctx, sessionKey(login, sessionId)))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	version, exists := sessions[token]
-	if !exists {
-		return myerrors.ErrNoSuchSessionInTheCache
+	sessions := make([]domain.Session, 0, len(sessionIds))
+	expiredIds := make([]interface{}, 0)
+	for i, cmd := range cmds {
+		fields := cmd.Val()
+		if len(fields) == 0 {
+			expiredIds = append(expiredIds, sessionIds[i])
+			continue
+		}
+		sessions = append(sessions, sessionFromHash(sessionIds[i], fields))
 	}
 
-	if version == maxVersion {
-		return myerrors.ErrTooHighVersion
+	if len(expiredIds) > 0 {
+		err = sessionStorage.redisClient.SRem(ctx, indexKey, expiredIds...).Err()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(sessions) == 0 {
+		return nil, myerrors.ErrNoSuchUserInTheCache
 	}
 
-	sessions[token] = version + 1
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+	})
 
-	sessionsJSON, err := json.Marshal(sessions)
+	return sessions, nil
+}
+
+// TouchSession отмечает активность сессии и продлевает ее TTL. Чтобы не писать в Redis на каждый
+// запрос, время обновляется не чаще раза в lastSeenInterval
+func (sessionStorage *SessionStorage) TouchSession(login string, token string, seenAt time.Time) error {
+	code, err := touchScript.Run(context.Background(), sessionStorage.redisClient,
+		[]string{sessionIndexKey(login), sessionKey(login, token)},
+		seenAt.UnixMilli(), lastSeenInterval.Milliseconds(), sessionStorage.sessionTTL.Milliseconds()).Int()
 	if err != nil {
 		return err
 	}
 
-	return sessionStorage.redisClient.Set(ctx, login, sessionsJSON, 0).Err()
+	return scriptError(code)
 }
 
-func (sessionStorage *SessionStorage) CheckVersion(login string, token string, usersVersion uint32) (bool, error) {
-	ctx := context.Background()
+func sessionKey(login string, token string) string {
+	return sessionPrefix + "{" + login + "}:" + token
+}
 
-	sessions := make(map[string]uint32)
-	val, err := sessionStorage.redisClient.Get(ctx, login).Result()
-	if err == redis.Nil {
-		return false, myerrors.ErrNoSuchItemInTheCache
-	}
-	if err != nil {
-		return false, err
-	}
+func sessionIndexKey(login string) string {
+	return sessionIndexPrefix + "{" + login + "}"
+}
 
-	if err := json.Unmarshal([]byte(val), &sessions); err != nil {
-		return false, err
+func scriptError(code int) error {
+	switch code {
+	case scriptNoSuchUser:
+		return myerrors.ErrNoSuchUserInTheCache
+	case scriptNoSuchSession:
+		return myerrors.ErrNoSuchSessionInTheCache
+	case scriptTooHighVersion:
+		return myerrors.ErrTooHighVersion
+	default:
+		return nil
 	}
+}
 
-	version, exists := sessions[token]
-	if !exists {
-		return false, myerrors.ErrNoSuchItemInTheCache
+func sessionFromHash(sessionId string, fields map[string]string) domain.Session {
+	version, _ := strconv.ParseUint(fields["version"], 10, 32)
+	createdAt, _ := strconv.ParseInt(fields["createdAt"], 10, 64)
+	lastSeenAt, _ := strconv.ParseInt(fields["lastSeenAt"], 10, 64)
+
+	return domain.Session{
+		Id:         sessionId,
+		Version:    uint32(version),
+		UserAgent:  fields["userAgent"],
+		Ip:         fields["ip"],
+		CreatedAt:  time.UnixMilli(createdAt),
+		LastSeenAt: time.UnixMilli(lastSeenAt),
 	}
+}
 
-	if version == usersVersion {
-		return true, nil
+// SaveRefreshToken сохраняет refresh токен и добавляет его в семейство, чтобы при повторном
+// использовании токена можно было отозвать все токены, выданные после того же входа. Ключи токена и
+// семейства лежат в разных слотах Redis Cluster, поэтому пишутся без транзакции: токен, не попавший в
+// семейство, все равно перестает работать вместе с сессией
+func (sessionStorage *SessionStorage) SaveRefreshToken(tokenHash string, refreshToken domain.RefreshToken,
+	ttl time.Duration) error {
+	ctx := context.Background()
+
+	refreshTokenJSON, err := json.Marshal(refreshToken)
+	if err != nil {
+		return err
 	}
 
-	return false, myerrors.ErrWrongSessionVersion
+	familyKey := refreshTokenFamilyPrefix + refreshToken.Family
+	_, err = sessionStorage.redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.SAdd(ctx, familyKey, tokenHash)
+		pipe.Expire(ctx, familyKey, ttl)
+		pipe.Set(ctx, refreshTokenPrefix+tokenHash, refreshTokenJSON, ttl)
+		return nil
+	})
+
+	return err
 }
 
-// HasSession проверяет наличие активной сессии для пользователя
-// Возвращает:
-//   - nil если сессия существует
-//   - ErrNoSuchUser если пользователь или токен не найдены
-//   - другие ошибки при проблемах с Redis или JSON
-func (sessionStorage *SessionStorage) HasSession(login string, token string) error {
-	// Подготавливаем хеш-таблицу для хранения сессий пользователя
-	sessions := make(map[string]uint32)
-	// Получаем данные из Redis по ключу = логину пользователя
-	val, err := sessionStorage.redisClient.Get(context.Background(), login).Result()
+// GetRefreshToken возвращает refresh токен, не помечая его использованным. Для использованного токена
+// возвращает ErrRefreshTokenReused вместе с токеном
+func (sessionStorage *SessionStorage) GetRefreshToken(tokenHash string) (domain.RefreshToken, error) {
+	val, err := sessionStorage.redisClient.Get(context.Background(), refreshTokenPrefix+tokenHash).Result()
 	if errors.Is(err, redis.Nil) {
-		return myerrors.ErrNoSuchUser
+		return domain.RefreshToken{}, myerrors.ErrNoSuchRefreshToken
 	}
-	// Обрабатываем другие ошибки Redis
 	if err != nil {
-		return err
+		return domain.RefreshToken{}, err
 	}
-	// Декодируем JSON с сессиями пользователя
-	if err = json.Unmarshal([]byte(val), &sessions); err != nil {
-		return err
+
+	var refreshToken domain.RefreshToken
+	if err = json.Unmarshal([]byte(val), &refreshToken); err != nil {
+		return domain.RefreshToken{}, err
 	}
-	// Проверяем наличие конкретного токена в списке сессий
-	if _, exists := sessions[token]; !exists {
-		return myerrors.ErrNoSuchUser
+	if refreshToken.Used {
+		return refreshToken, myerrors.ErrRefreshTokenReused
 	}
-	// Если все проверки пройдены - сессия существует
-	return nil
+	return refreshToken, nil
 }
 
-func (sessionStorage *SessionStorage) GetVersion(login string, token string) (uint32, error) {
+// ConsumeRefreshToken помечает refresh токен использованным. Использованный токен остается в кеше
+// до истечения срока действия, и повторная попытка возвращает ErrRefreshTokenReused вместе с токеном
+func (sessionStorage *SessionStorage) ConsumeRefreshToken(tokenHash string) (domain.RefreshToken, error) {
 	ctx := context.Background()
+	key := refreshTokenPrefix + tokenHash
 
-	sessions := make(map[string]uint32)
-	val, err := sessionStorage.redisClient.Get(ctx, login).Result()
-	if err == redis.Nil {
-		return 0, myerrors.ErrNoSuchItemInTheCache
-	}
-	if err != nil {
-		return 0, err
-	}
+	var refreshToken domain.RefreshToken
+	err := sessionStorage.redisClient.Watch(ctx, func(tx *redis.Tx) error {
+		val, err := tx.Get(ctx, key).Result()
+		if errors.Is(err, redis.Nil) {
+			return myerrors.ErrNoSuchRefreshToken
+		}
+		if err != nil {
+			return err
+		}
 
-	if err := json.Unmarshal([]byte(val), &sessions); err != nil {
-		return 0, err
-	}
+		if err = json.Unmarshal([]byte(val), &refreshToken); err != nil {
+			return err
+		}
 
-	version, exists := sessions[token]
-	if !exists {
-		return 0, myerrors.ErrNoSuchItemInTheCache
-	}
+		if refreshToken.Used {
+			return myerrors.ErrRefreshTokenReused
+		}
+
+		usedToken := refreshToken
+		usedToken.Used = true
+		usedTokenJSON, err := json.Marshal(usedToken)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.SetArgs(ctx, key, usedTokenJSON, redis.SetArgs{KeepTTL: true})
+			return nil
+		})
+		return err
+	}, key)
 
-	return version, nil
+	return refreshToken, err
 }
 
-func (sessionStorage *SessionStorage) CheckAllUserSessionTokens(login string) error {
+func (sessionStorage *SessionStorage) RevokeRefreshTokenFamily(family string) error {
 	ctx := context.Background()
+	familyKey := refreshTokenFamilyPrefix + family
 
-	sessions := make(map[string]uint32)
-	val, err := sessionStorage.redisClient.Get(ctx, login).Result()
-	if err == redis.Nil {
-		return myerrors.ErrNoSuchUserInTheCache
-	}
+	tokenHashes, err := sessionStorage.redisClient.SMembers(ctx, familyKey).Result()
 	if err != nil {
 		return err
 	}
 
-	if err := json.Unmarshal([]byte(val), &sessions); err != nil {
-		return err
-	}
-
-	for token, version := range sessions {
-		fmt.Println("token:", token, "version:", version)
-	}
+	// ключи токенов лежат в разных слотах кластера, поэтому удаляются по одному
+	_, err = sessionStorage.redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, tokenHash := range tokenHashes {
+			pipe.Del(ctx, refreshTokenPrefix+tokenHash)
+		}
+		pipe.Del(ctx, familyKey)
+		return nil
+	})
 
-	return nil
+	return err
 }
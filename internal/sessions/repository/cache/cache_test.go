@@ -1,12 +1,35 @@
 package cache
 
 import (
+	"context"
 	"reflect"
 	"testing"
+	"time"
 
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/SanExpett/diploma/internal/domain"
+	"github.com/SanExpett/diploma/internal/sessions/repository/storagetest"
 )
 
+const testSessionTTL = storagetest.SessionTTL
+
+func newTestRedis(t *testing.T) (*miniredis.Miniredis, *SessionStorage) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	return server, NewSessionStorage(client, testSessionTTL)
+}
+
+func newTestSessionStorage(t *testing.T) *SessionStorage {
+	_, storage := newTestRedis(t)
+	return storage
+}
+
 func TestAddSession(t *testing.T) {
 	validCases := []struct {
 		testName string
@@ -65,11 +88,11 @@ func TestAddSession(t *testing.T) {
 		},
 	}
 
-	storage := NewSessionStorage()
+	storage := newTestSessionStorage(t)
 
 	for _, currentCase := range validCases {
 		t.Run(currentCase.testName, func(t *testing.T) {
-			err := storage.Add(currentCase.login, currentCase.token, currentCase.version)
+			err := storage.Add(currentCase.login, domain.Session{Id: currentCase.token, Version: currentCase.version})
 
 			if err != nil {
 				t.Error(err)
@@ -79,7 +102,7 @@ func TestAddSession(t *testing.T) {
 
 	for _, currentCase := range invalidCases {
 		t.Run(currentCase.testName, func(t *testing.T) {
-			err := storage.Add(currentCase.login, currentCase.token, currentCase.version)
+			err := storage.Add(currentCase.login, domain.Session{Id: currentCase.token, Version: currentCase.version})
 
 			if err == nil {
 				t.Error("no error returned")
@@ -146,10 +169,10 @@ func TestDeleteSession(t *testing.T) {
 		},
 	}
 
-	storage := NewSessionStorage()
+	storage := newTestSessionStorage(t)
 
 	for _, currentCase := range validCases {
-		err := storage.Add(currentCase.login, currentCase.token, currentCase.version)
+		err := storage.Add(currentCase.login, domain.Session{Id: currentCase.token, Version: currentCase.version})
 		if err != nil {
 		}
 	}
@@ -207,10 +230,10 @@ func TestHasSession(t *testing.T) {
 		},
 	}
 
-	storage := NewSessionStorage()
+	storage := newTestSessionStorage(t)
 
 	for _, currentCase := range validCases {
-		err := storage.Add(currentCase.login, currentCase.token, 1)
+		err := storage.Add(currentCase.login, domain.Session{Id: currentCase.token, Version: 1})
 		if err != nil {
 		}
 	}
@@ -288,10 +311,10 @@ func TestGetVersion(t *testing.T) {
 		},
 	}
 
-	storage := NewSessionStorage()
+	storage := newTestSessionStorage(t)
 
 	for _, currentCase := range validCases {
-		err := storage.Add(currentCase.login, currentCase.token, currentCase.version)
+		err := storage.Add(currentCase.login, domain.Session{Id: currentCase.token, Version: currentCase.version})
 		if err != nil {
 		}
 	}
@@ -376,10 +399,10 @@ func TestCheckVersion(t *testing.T) {
 		},
 	}
 
-	storage := NewSessionStorage()
+	storage := newTestSessionStorage(t)
 
 	for _, currentCase := range validCases {
-		err := storage.Add(currentCase.login, currentCase.token, currentCase.version)
+		err := storage.Add(currentCase.login, domain.Session{Id: currentCase.token, Version: currentCase.version})
 		if err != nil {
 		}
 	}
@@ -444,10 +467,10 @@ func TestUpdateVersion(t *testing.T) {
 		},
 	}
 
-	storage := NewSessionStorage()
+	storage := newTestSessionStorage(t)
 
 	for _, currentCase := range validCases {
-		err := storage.Add(currentCase.login, currentCase.token, currentCase.version)
+		err := storage.Add(currentCase.login, domain.Session{Id: currentCase.token, Version: currentCase.version})
 		if err != nil {
 		}
 	}
@@ -474,18 +497,54 @@ func TestUpdateVersion(t *testing.T) {
 	}
 }
 
-func TestSessionStorage_CheckAllUserSessionTokens(t *testing.T) {
-	storage := NewSessionStorage()
+func TestSessionStorage_Contract(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) (storagetest.Storage, func(d time.Duration)) {
+		server, storage := newTestRedis(t)
+		return storage, server.FastForward
+	})
+}
 
-	// Set up some mock data in the cache
-	mockCacheData := map[string]uint32{
-		"token1": 1,
-		"token2": 2,
-	}
-	mockLogin := "testUser"
-	storage.cacheStorage.Set(mockLogin, mockCacheData, 0)
+func TestSessionStorage_MigrationContract(t *testing.T) {
+	storagetest.RunMigration(t, func(t *testing.T) (storagetest.LegacyStorage, func(key, value string),
+		func(key string) bool) {
+		server, storage := newTestRedis(t)
+		set := func(key, value string) {
+			require.NoError(t, server.Set(key, value))
+		}
+		return storage, set, server.Exists
+	})
+}
+
+func TestSessionStorage_MigrateLegacySessions(t *testing.T) {
+	server, storage := newTestRedis(t)
+
+	require.NoError(t, server.Set("old@test.com", `{"token1":1,"token2":3}`))
+	require.NoError(t, server.Set("new@test.com", `{"family":{"version":2,"userAgent":"curl/8.0","ip":"192.0.2.1"}}`))
+	require.NoError(t, server.Set(refreshTokenPrefix+"hash", `{"login":"new@test.com","family":"family"}`))
 
-	err := storage.CheckAllUserSessionTokens(mockLogin)
+	migrated, err := storage.MigrateLegacySessions(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, migrated)
 
+	version, err := storage.GetVersion("old@test.com", "token2")
 	assert.NoError(t, err)
+	assert.Equal(t, uint32(3), version)
+
+	sessions, err := storage.ListSessions("new@test.com")
+	require.NoError(t, err)
+	assert.Equal(t, "curl/8.0", sessions[0].UserAgent)
+	assert.Equal(t, "192.0.2.1", sessions[0].Ip)
+	assert.False(t, sessions[0].CreatedAt.IsZero())
+
+	assert.False(t, server.Exists("old@test.com"))
+	assert.False(t, server.Exists("new@test.com"))
+	assert.True(t, server.Exists(refreshTokenPrefix+"hash"))
+	assert.Equal(t, testSessionTTL, server.TTL(sessionKey("old@test.com", "token1")))
+
+	// повторный запуск не просматривает ключи
+	require.NoError(t, server.Set("late@test.com", `{"token1":1}`))
+	migrated, err = storage.MigrateLegacySessions(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, migrated)
+	assert.True(t, server.Exists("late@test.com"))
 }
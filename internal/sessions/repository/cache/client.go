@@ -0,0 +1,27 @@
+package cache
+
+import (
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisOptions параметры подключения к Redis. С MasterName Addrs считаются адресами Sentinel,
+// с Cluster или несколькими адресами без MasterName подключение идет к Redis Cluster
+type RedisOptions struct {
+	Addrs            []string
+	Password         string
+	DB               int
+	MasterName       string
+	SentinelPassword string
+	Cluster          bool
+}
+
+func NewRedisClient(options RedisOptions) redis.UniversalClient {
+	return redis.NewUniversalClient(&redis.UniversalOptions{
+		Addrs:            options.Addrs,
+		Password:         options.Password,
+		DB:               options.DB,
+		MasterName:       options.MasterName,
+		SentinelPassword: options.SentinelPassword,
+		IsClusterMode:    options.Cluster,
+	})
+}
@@ -0,0 +1,159 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/SanExpett/diploma/internal/domain"
+	myerrors "github.com/SanExpett/diploma/internal/errors"
+)
+
+// Авторизация хранится под пользовательским кодом, отдельный ключ по хешу device code указывает на нее
+const (
+	deviceAuthorizationPrefix = "device:"
+	deviceCodePrefix          = "device_code:"
+)
+
+// SaveDeviceAuthorization сохраняет новую авторизацию устройства на ttl. Если такой пользовательский код
+// уже выдан, возвращает ErrItemsIsAlreadyInTheCache
+func (sessionStorage *SessionStorage) SaveDeviceAuthorization(deviceCodeHash string,
+	authorization domain.DeviceAuthorization, ttl time.Duration) error {
+	ctx := context.Background()
+
+	authorizationJSON, err := json.Marshal(authorization)
+	if err != nil {
+		return err
+	}
+
+	saved, err := sessionStorage.redisClient.SetNX(ctx, deviceAuthorizationPrefix+authorization.UserCode,
+		authorizationJSON, ttl).Result()
+	if err != nil {
+		return err
+	}
+	if !saved {
+		return myerrors.ErrItemsIsAlreadyInTheCache
+	}
+
+	return sessionStorage.redisClient.Set(ctx, deviceCodePrefix+deviceCodeHash, authorization.UserCode, ttl).Err()
+}
+
+// GetDeviceAuthorization возвращает авторизацию по пользовательскому коду
+func (sessionStorage *SessionStorage) GetDeviceAuthorization(userCode string) (domain.DeviceAuthorization, error) {
+	val, err := sessionStorage.redisClient.Get(context.Background(), deviceAuthorizationPrefix+userCode).Result()
+	if errors.Is(err, redis.Nil) {
+		return domain.DeviceAuthorization{}, myerrors.ErrNoSuchDeviceAuthorization
+	}
+	if err != nil {
+		return domain.DeviceAuthorization{}, err
+	}
+
+	var authorization domain.DeviceAuthorization
+	err = json.Unmarshal([]byte(val), &authorization)
+	return authorization, err
+}
+
+// DecideDeviceAuthorization подтверждает или отклоняет авторизацию от имени login. Решение принимается один
+// раз, для уже решенной авторизации возвращается ErrNoSuchDeviceAuthorization
+func (sessionStorage *SessionStorage) DecideDeviceAuthorization(userCode, login string, approved bool) error {
+	ctx := context.Background()
+	key := deviceAuthorizationPrefix + userCode
+
+	return sessionStorage.redisClient.Watch(ctx, func(tx *redis.Tx) error {
+		authorization, err := getDeviceAuthorization(ctx, tx, key)
+		if err != nil {
+			return err
+		}
+		if authorization.Status != domain.DeviceAuthorizationPending {
+			return myerrors.ErrNoSuchDeviceAuthorization
+		}
+
+		authorization.Status = domain.DeviceAuthorizationDenied
+		if approved {
+			authorization.Status = domain.DeviceAuthorizationApproved
+			authorization.Login = login
+		}
+		authorizationJSON, err := json.Marshal(authorization)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.SetArgs(ctx, key, authorizationJSON, redis.SetArgs{KeepTTL: true})
+			return nil
+		})
+		return err
+	}, key)
+}
+
+// PollDeviceAuthorization возвращает авторизацию по хешу device code с временем предыдущего опроса
+// и запоминает время текущего. Решенная авторизация возвращается один раз и удаляется
+func (sessionStorage *SessionStorage) PollDeviceAuthorization(deviceCodeHash string,
+	polledAt time.Time) (domain.DeviceAuthorization, error) {
+	ctx := context.Background()
+	codeKey := deviceCodePrefix + deviceCodeHash
+
+	userCode, err := sessionStorage.redisClient.Get(ctx, codeKey).Result()
+	if errors.Is(err, redis.Nil) {
+		return domain.DeviceAuthorization{}, myerrors.ErrNoSuchDeviceAuthorization
+	}
+	if err != nil {
+		return domain.DeviceAuthorization{}, err
+	}
+
+	key := deviceAuthorizationPrefix + userCode
+	var authorization domain.DeviceAuthorization
+	err = sessionStorage.redisClient.Watch(ctx, func(tx *redis.Tx) error {
+		authorization, err = getDeviceAuthorization(ctx, tx, key)
+		if err != nil {
+			return err
+		}
+
+		if authorization.Status != domain.DeviceAuthorizationPending {
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.Del(ctx, key)
+				return nil
+			})
+			return err
+		}
+
+		polled := authorization
+		polled.LastPolledAt = polledAt
+		polledJSON, err := json.Marshal(polled)
+		if err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.SetArgs(ctx, key, polledJSON, redis.SetArgs{KeepTTL: true})
+			return nil
+		})
+		return err
+	}, key)
+	if err != nil {
+		return domain.DeviceAuthorization{}, err
+	}
+
+	// ключи лежат в разных слотах кластера, указатель удаляется отдельно. Если он переживет авторизацию,
+	// следующий опрос просто получит ErrNoSuchDeviceAuthorization
+	if authorization.Status != domain.DeviceAuthorizationPending {
+		err = sessionStorage.redisClient.Del(ctx, codeKey).Err()
+	}
+	return authorization, err
+}
+
+func getDeviceAuthorization(ctx context.Context, tx *redis.Tx, key string) (domain.DeviceAuthorization, error) {
+	val, err := tx.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return domain.DeviceAuthorization{}, myerrors.ErrNoSuchDeviceAuthorization
+	}
+	if err != nil {
+		return domain.DeviceAuthorization{}, err
+	}
+
+	var authorization domain.DeviceAuthorization
+	err = json.Unmarshal([]byte(val), &authorization)
+	return authorization, err
+}
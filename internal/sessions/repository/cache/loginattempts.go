@@ -0,0 +1,100 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/SanExpett/diploma/internal/domain"
+)
+
+const loginAttemptsPrefix = "login_attempts:"
+
+// Проверка ограничений и резервирование попытки выполняются одним скриптом, поэтому параллельные попытки
+// входа не проходят мимо задержки. Задержка считается так же, как в domain.LoginPolicy.Throttle.
+// TTL продлевается с каждой попыткой, поэтому счетчик сбрасывается только после window без неудачных попыток
+var reserveLoginAttemptScript = redis.NewScript(`
+local failures = tonumber(redis.call('HGET', KEYS[1], 'failures') or '0')
+local lastFailureAt = tonumber(redis.call('HGET', KEYS[1], 'lastFailureAt') or '0')
+local now = tonumber(ARGV[1])
+local freeAttempts = tonumber(ARGV[3])
+if failures >= freeAttempts then
+  local delay = tonumber(ARGV[6])
+  if failures < tonumber(ARGV[4]) then
+    delay = math.min(tonumber(ARGV[5]) * 2 ^ (failures - freeAttempts), delay)
+  end
+  if lastFailureAt + delay > now then
+    return {0, failures, lastFailureAt}
+  end
+end
+failures = redis.call('HINCRBY', KEYS[1], 'failures', 1)
+redis.call('HSET', KEYS[1], 'lastFailureAt', ARGV[1])
+redis.call('PEXPIRE', KEYS[1], ARGV[2])
+return {1, failures, now}
+`)
+
+var releaseLoginAttemptScript = redis.NewScript(`
+if tonumber(redis.call('HGET', KEYS[1], 'failures') or '0') > 0 then
+  redis.call('HINCRBY', KEYS[1], 'failures', -1)
+end
+return 0
+`)
+
+// GetLoginAttempts возвращает неудачные попытки входа по ключу аккаунта или IP
+func (sessionStorage *SessionStorage) GetLoginAttempts(key string) (domain.LoginAttempts, error) {
+	fields, err := sessionStorage.redisClient.HGetAll(context.Background(), loginAttemptsKey(key)).Result()
+	if err != nil {
+		return domain.LoginAttempts{}, err
+	}
+
+	return loginAttemptsFromHash(fields), nil
+}
+
+// ReserveLoginAttempt заранее учитывает попытку входа как неудачную, если policy ее разрешает. Возвращает
+// попытки после резервирования или, если попытка не разрешена, текущие попытки и false
+func (sessionStorage *SessionStorage) ReserveLoginAttempt(key string, now time.Time, window time.Duration,
+	policy domain.LoginPolicy) (domain.LoginAttempts, bool, error) {
+	result, err := reserveLoginAttemptScript.Run(context.Background(), sessionStorage.redisClient,
+		[]string{loginAttemptsKey(key)}, now.UnixMilli(), window.Milliseconds(), policy.FreeAttempts,
+		policy.LockoutAttempts, policy.BackoffBase.Milliseconds(), policy.LockoutDuration.Milliseconds(),
+	).Int64Slice()
+	if err != nil {
+		return domain.LoginAttempts{}, false, err
+	}
+
+	return domain.LoginAttempts{
+		Failures:      uint32(result[1]),
+		LastFailureAt: time.UnixMilli(result[2]),
+	}, result[0] == 1, nil
+}
+
+// ReleaseLoginAttempt снимает зарезервированную попытку входа
+func (sessionStorage *SessionStorage) ReleaseLoginAttempt(key string) error {
+	return releaseLoginAttemptScript.Run(context.Background(), sessionStorage.redisClient,
+		[]string{loginAttemptsKey(key)}).Err()
+}
+
+// ResetLoginAttempts сбрасывает неудачные попытки входа
+func (sessionStorage *SessionStorage) ResetLoginAttempts(key string) error {
+	return sessionStorage.redisClient.Del(context.Background(), loginAttemptsKey(key)).Err()
+}
+
+func loginAttemptsKey(key string) string {
+	return loginAttemptsPrefix + key
+}
+
+func loginAttemptsFromHash(fields map[string]string) domain.LoginAttempts {
+	if len(fields) == 0 {
+		return domain.LoginAttempts{}
+	}
+
+	failures, _ := strconv.ParseUint(fields["failures"], 10, 32)
+	lastFailureAt, _ := strconv.ParseInt(fields["lastFailureAt"], 10, 64)
+
+	return domain.LoginAttempts{
+		Failures:      uint32(failures),
+		LastFailureAt: time.UnixMilli(lastFailureAt),
+	}
+}
@@ -0,0 +1,171 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/SanExpett/diploma/internal/domain"
+	myerrors "github.com/SanExpett/diploma/internal/errors"
+)
+
+const (
+	migrateScanCount = 100
+
+	// migrationVersionKey номер последней выполненной миграции хранилища
+	migrationVersionKey = "sessions_migration_version"
+	// legacySessionsMigration номер миграции сессий из JSON под ключом-логином
+	legacySessionsMigration = 1
+)
+
+// ownedKeyPrefixes префиксы ключей, которые хранилище создает само. Под ними нет сессий старого формата
+var ownedKeyPrefixes = []string{
+	sessionPrefix,
+	sessionIndexPrefix,
+	refreshTokenPrefix,
+	refreshTokenFamilyPrefix,
+	deviceAuthorizationPrefix,
+	deviceCodePrefix,
+	loginAttemptsPrefix,
+	passkeyChallengePrefix,
+}
+
+// legacyLoginRegex формат логина, под которым хранились сессии старого формата, совпадает с проверкой
+// логина при регистрации
+var legacyLoginRegex = regexp.MustCompile(`^[a-z0-9._%+\-]+@[a-z0-9.\-]+\.[a-z]{2,}$`)
+
+// MigrateLegacySessions переносит сессии, которые раньше хранились одним JSON под ключом-логином,
+// в хеши по сессиям и удаляет старые ключи. Возвращает количество перенесенных пользователей.
+// После переноса записывает номер миграции, и при следующих запусках ключи не просматриваются
+func (sessionStorage *SessionStorage) MigrateLegacySessions(ctx context.Context) (int, error) {
+	version, err := sessionStorage.redisClient.Get(ctx, migrationVersionKey).Int()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return 0, err
+	}
+	if version >= legacySessionsMigration {
+		return 0, nil
+	}
+
+	migrated := 0
+	clusterClient, isCluster := sessionStorage.redisClient.(*redis.ClusterClient)
+	if isCluster {
+		err = clusterClient.ForEachMaster(ctx, func(ctx context.Context, master *redis.Client) error {
+			masterMigrated, err := sessionStorage.migrateLegacySessions(ctx, master)
+			migrated += masterMigrated
+			return err
+		})
+	} else {
+		migrated, err = sessionStorage.migrateLegacySessions(ctx, sessionStorage.redisClient)
+	}
+	if err != nil {
+		return migrated, err
+	}
+
+	return migrated, sessionStorage.redisClient.Set(ctx, migrationVersionKey, legacySessionsMigration, 0).Err()
+}
+
+func (sessionStorage *SessionStorage) migrateLegacySessions(ctx context.Context, scanner redis.Cmdable) (int,
+	error) {
+	migrated := 0
+	var cursor uint64
+	for {
+		keys, nextCursor, err := scanner.ScanType(ctx, cursor, "*", migrateScanCount, "string").Result()
+		if err != nil {
+			return migrated, err
+		}
+
+		for _, login := range keys {
+			if !isLegacySessionsKey(login) {
+				continue
+			}
+
+			isMigrated, err := sessionStorage.migrateLegacyLogin(ctx, login)
+			if err != nil {
+				return migrated, err
+			}
+			if isMigrated {
+				migrated++
+			}
+		}
+
+		cursor = nextCursor
+		if cursor == 0 {
+			return migrated, nil
+		}
+	}
+}
+
+// isLegacySessionsKey сообщает, что под ключом key могут храниться сессии старого формата. Ключи
+// хранилища и ключи, не похожие на логин, не трогаются, даже если в них лежит JSON
+func isLegacySessionsKey(key string) bool {
+	if key == migrationVersionKey {
+		return false
+	}
+	for _, prefix := range ownedKeyPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return false
+		}
+	}
+	return legacyLoginRegex.MatchString(key)
+}
+
+func (sessionStorage *SessionStorage) migrateLegacyLogin(ctx context.Context, login string) (bool, error) {
+	val, err := sessionStorage.redisClient.Get(ctx, login).Result()
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	sessions, err := decodeLegacySessions(val)
+	if err != nil {
+		// строковый ключ другого формата, не сессии
+		return false, nil
+	}
+
+	now := time.Now()
+	for _, session := range sessions {
+		if session.CreatedAt.IsZero() {
+			session.CreatedAt = now
+		}
+		if session.LastSeenAt.IsZero() {
+			session.LastSeenAt = now
+		}
+
+		err = sessionStorage.Add(login, session)
+		if err != nil && !errors.Is(err, myerrors.ErrItemsIsAlreadyInTheCache) {
+			return false, err
+		}
+	}
+
+	return true, sessionStorage.redisClient.Del(ctx, login).Err()
+}
+
+// decodeLegacySessions разбирает JSON со словарем сессий. В первых версиях значением была только
+// версия сессии, позже объект с данными устройства
+func decodeLegacySessions(val string) ([]domain.Session, error) {
+	rawSessions := make(map[string]json.RawMessage)
+	if err := json.Unmarshal([]byte(val), &rawSessions); err != nil {
+		return nil, err
+	}
+
+	sessions := make([]domain.Session, 0, len(rawSessions))
+	for token, rawSession := range rawSessions {
+		var session domain.Session
+		if err := json.Unmarshal(rawSession, &session); err != nil {
+			if err = json.Unmarshal(rawSession, &session.Version); err != nil {
+				return nil, err
+			}
+		}
+		session.Id = token
+		sessions = append(sessions, session)
+	}
+
+	return sessions, nil
+}
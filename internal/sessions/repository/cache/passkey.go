@@ -0,0 +1,37 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	myerrors "github.com/SanExpett/diploma/internal/errors"
+)
+
+const passkeyChallengePrefix = "passkey_challenge:"
+
+// SavePasskeyChallenge сохраняет challenge церемонии WebAuthn до expiresAt. Если challenge с таким
+// идентификатором уже сохранен, возвращает ErrItemsIsAlreadyInTheCache
+func (sessionStorage *SessionStorage) SavePasskeyChallenge(challengeId string, expiresAt time.Time) error {
+	saved, err := sessionStorage.redisClient.SetNX(context.Background(), passkeyChallengePrefix+challengeId, 1,
+		time.Until(expiresAt)).Result()
+	if err != nil {
+		return err
+	}
+	if !saved {
+		return myerrors.ErrItemsIsAlreadyInTheCache
+	}
+	return nil
+}
+
+// ConsumePasskeyChallenge удаляет challenge. DEL атомарен, поэтому из одновременных попыток challenge
+// достается одной, остальные и попытки с истекшим challenge получают ErrNoSuchPasskeyChallenge
+func (sessionStorage *SessionStorage) ConsumePasskeyChallenge(challengeId string) error {
+	deleted, err := sessionStorage.redisClient.Del(context.Background(), passkeyChallengePrefix+challengeId).Result()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return myerrors.ErrNoSuchPasskeyChallenge
+	}
+	return nil
+}
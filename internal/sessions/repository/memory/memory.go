@@ -0,0 +1,631 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/SanExpett/diploma/internal/domain"
+	myerrors "github.com/SanExpett/diploma/internal/errors"
+)
+
+var (
+	maxVersion uint32 = 255
+)
+
+const lastSeenInterval = time.Minute
+
+type sessionEntry struct {
+	Session   domain.Session `json:"session"`
+	ExpiresAt time.Time      `json:"expiresAt"`
+}
+
+type refreshTokenEntry struct {
+	RefreshToken domain.RefreshToken `json:"refreshToken"`
+	ExpiresAt    time.Time           `json:"expiresAt"`
+}
+
+type familyEntry struct {
+	TokenHashes map[string]struct{} `json:"tokenHashes"`
+	ExpiresAt   time.Time           `json:"expiresAt"`
+}
+
+type loginAttemptsEntry struct {
+	Attempts  domain.LoginAttempts `json:"attempts"`
+	ExpiresAt time.Time            `json:"expiresAt"`
+}
+
+type deviceAuthorizationEntry struct {
+	Authorization  domain.DeviceAuthorization `json:"authorization"`
+	DeviceCodeHash string                     `json:"deviceCodeHash"`
+	ExpiresAt      time.Time                  `json:"expiresAt"`
+}
+
+// snapshot содержимое хранилища, которое сохраняется на диск между перезапусками
+type snapshot struct {
+	Sessions      map[string]map[string]*sessionEntry `json:"sessions"`
+	RefreshTokens map[string]*refreshTokenEntry       `json:"refreshTokens"`
+	Families      map[string]*familyEntry             `json:"families"`
+	LoginAttempts map[string]*loginAttemptsEntry      `json:"loginAttempts"`
+	// DeviceAuthorizations по пользовательскому коду, DeviceCodes пользовательский код по хешу device code
+	DeviceAuthorizations map[string]*deviceAuthorizationEntry `json:"deviceAuthorizations"`
+	DeviceCodes          map[string]string                    `json:"deviceCodes"`
+	// PasskeyChallenges срок действия еще не использованных challenge WebAuthn по их идентификатору
+	PasskeyChallenges map[string]time.Time `json:"passkeyChallenges"`
+}
+
+// SessionStorage хранит сессии и refresh токены в памяти процесса. Подходит для локальной разработки
+// и тестов, ведет себя так же, как хранилище в Redis
+type SessionStorage struct {
+	mu           sync.Mutex
+	data         snapshot
+	sessionTTL   time.Duration
+	snapshotPath string
+	now          func() time.Time
+}
+
+// NewSessionStorage создает хранилище, в котором сессия живет sessionTTL с последней активности.
+// Если snapshotPath не пустой, хранилище загружает из него сохраненное состояние
+func NewSessionStorage(sessionTTL time.Duration, snapshotPath string) (*SessionStorage, error) {
+	storage := &SessionStorage{
+		data: snapshot{
+			Sessions:      make(map[string]map[string]*sessionEntry),
+			RefreshTokens: make(map[string]*refreshTokenEntry),
+			Families:      make(map[string]*familyEntry),
+			LoginAttempts: make(map[string]*loginAttemptsEntry),
+
+			DeviceAuthorizations: make(map[string]*deviceAuthorizationEntry),
+			DeviceCodes:          make(map[string]string),
+			PasskeyChallenges:    make(map[string]time.Time),
+		},
+		sessionTTL:   sessionTTL,
+		snapshotPath: snapshotPath,
+		now:          time.Now,
+	}
+
+	if snapshotPath == "" {
+		return storage, nil
+	}
+
+	snapshotJSON, err := os.ReadFile(snapshotPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return storage, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sessions snapshot: %w", err)
+	}
+
+	if err = json.Unmarshal(snapshotJSON, &storage.data); err != nil {
+		return nil, fmt.Errorf("failed to decode sessions snapshot: %w", err)
+	}
+	// снимки, сохраненные до учета попыток входа, не содержат этого поля
+	if storage.data.LoginAttempts == nil {
+		storage.data.LoginAttempts = make(map[string]*loginAttemptsEntry)
+	}
+	if storage.data.DeviceAuthorizations == nil {
+		storage.data.DeviceAuthorizations = make(map[string]*deviceAuthorizationEntry)
+		storage.data.DeviceCodes = make(map[string]string)
+	}
+	if storage.data.PasskeyChallenges == nil {
+		storage.data.PasskeyChallenges = make(map[string]time.Time)
+	}
+	storage.removeExpired()
+
+	return storage, nil
+}
+
+// StartCleanup раз в interval удаляет истекшие записи и сохраняет снимок, пока не отменен ctx
+func (storage *SessionStorage) StartCleanup(ctx context.Context, interval time.Duration,
+	onError func(err error)) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				storage.mu.Lock()
+				storage.removeExpired()
+				storage.mu.Unlock()
+
+				err := storage.SaveSnapshot()
+				if err != nil {
+					onError(err)
+				}
+			}
+		}
+	}()
+}
+
+// SaveSnapshot записывает состояние хранилища на диск. Файл заменяется целиком, чтобы при падении
+// процесса не остался наполовину записанный снимок
+func (storage *SessionStorage) SaveSnapshot() error {
+	if storage.snapshotPath == "" {
+		return nil
+	}
+
+	storage.mu.Lock()
+	snapshotJSON, err := json.Marshal(storage.data)
+	storage.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode sessions snapshot: %w", err)
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(storage.snapshotPath), filepath.Base(storage.snapshotPath)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create sessions snapshot: %w", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	_, err = tmpFile.Write(snapshotJSON)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write sessions snapshot: %w", err)
+	}
+
+	return os.Rename(tmpFile.Name(), storage.snapshotPath)
+}
+
+func (storage *SessionStorage) Add(login string, session domain.Session) error {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	sessions := storage.userSessions(login)
+	if sessions == nil {
+		sessions = make(map[string]*sessionEntry)
+		storage.data.Sessions[login] = sessions
+	}
+
+	if _, exists := sessions[session.Id]; exists {
+		return myerrors.ErrItemsIsAlreadyInTheCache
+	}
+
+	sessions[session.Id] = &sessionEntry{
+		Session:   session,
+		ExpiresAt: storage.now().Add(storage.sessionTTL),
+	}
+
+	return nil
+}
+
+func (storage *SessionStorage) DeleteSession(login string, token string) error {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	sessions := storage.userSessions(login)
+	if sessions == nil {
+		return myerrors.ErrNoSuchUserInTheCache
+	}
+
+	if _, exists := sessions[token]; !exists {
+		return myerrors.ErrNoSuchSessionInTheCache
+	}
+
+	delete(sessions, token)
+	if len(sessions) == 0 {
+		delete(storage.data.Sessions, login)
+	}
+
+	return nil
+}
+
+func (storage *SessionStorage) Update(login string, token string) error {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	sessions := storage.userSessions(login)
+	if sessions == nil {
+		return myerrors.ErrNoSuchUserInTheCache
+	}
+
+	entry, exists := sessions[token]
+	if !exists {
+		return myerrors.ErrNoSuchSessionInTheCache
+	}
+
+	if entry.Session.Version >= maxVersion {
+		return myerrors.ErrTooHighVersion
+	}
+
+	entry.Session.Version++
+
+	return nil
+}
+
+func (storage *SessionStorage) CheckVersion(login string, token string, usersVersion uint32) (bool, error) {
+	version, err := storage.GetVersion(login, token)
+	if err != nil {
+		return false, err
+	}
+
+	if version == usersVersion {
+		return true, nil
+	}
+
+	return false, myerrors.ErrWrongSessionVersion
+}
+
+func (storage *SessionStorage) GetVersion(login string, token string) (uint32, error) {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	entry, exists := storage.userSessions(login)[token]
+	if !exists {
+		return 0, myerrors.ErrNoSuchItemInTheCache
+	}
+
+	return entry.Session.Version, nil
+}
+
+func (storage *SessionStorage) HasSession(login string, token string) error {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	if _, exists := storage.userSessions(login)[token]; !exists {
+		return myerrors.ErrNoSuchUser
+	}
+
+	return nil
+}
+
+// ListSessions возвращает сессии пользователя, начиная с последней активной
+func (storage *SessionStorage) ListSessions(login string) ([]domain.Session, error) {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	sessions := storage.userSessions(login)
+	if len(sessions) == 0 {
+		return nil, myerrors.ErrNoSuchUserInTheCache
+	}
+
+	sessionsList := make([]domain.Session, 0, len(sessions))
+	for _, entry := range sessions {
+		sessionsList = append(sessionsList, entry.Session)
+	}
+	sort.Slice(sessionsList, func(i, j int) bool {
+		return sessionsList[i].LastSeenAt.After(sessionsList[j].LastSeenAt)
+	})
+
+	return sessionsList, nil
+}
+
+// TouchSession отмечает активность сессии и продлевает ее, но не чаще раза в lastSeenInterval
+func (storage *SessionStorage) TouchSession(login string, token string, seenAt time.Time) error {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	sessions := storage.userSessions(login)
+	if sessions == nil {
+		return myerrors.ErrNoSuchUserInTheCache
+	}
+
+	entry, exists := sessions[token]
+	if !exists {
+		return myerrors.ErrNoSuchSessionInTheCache
+	}
+
+	if seenAt.Sub(entry.Session.LastSeenAt) < lastSeenInterval {
+		return nil
+	}
+
+	entry.Session.LastSeenAt = seenAt
+	entry.ExpiresAt = storage.now().Add(storage.sessionTTL)
+
+	return nil
+}
+
+func (storage *SessionStorage) SaveRefreshToken(tokenHash string, refreshToken domain.RefreshToken,
+	ttl time.Duration) error {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	expiresAt := storage.now().Add(ttl)
+	storage.data.RefreshTokens[tokenHash] = &refreshTokenEntry{
+		RefreshToken: refreshToken,
+		ExpiresAt:    expiresAt,
+	}
+
+	family, exists := storage.data.Families[refreshToken.Family]
+	if !exists || storage.isExpired(family.ExpiresAt) {
+		family = &familyEntry{TokenHashes: make(map[string]struct{})}
+		storage.data.Families[refreshToken.Family] = family
+	}
+	family.TokenHashes[tokenHash] = struct{}{}
+	family.ExpiresAt = expiresAt
+
+	return nil
+}
+
+// GetRefreshToken возвращает refresh токен, не помечая его использованным. Для использованного токена
+// возвращает ErrRefreshTokenReused вместе с токеном
+func (storage *SessionStorage) GetRefreshToken(tokenHash string) (domain.RefreshToken, error) {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	entry, exists := storage.data.RefreshTokens[tokenHash]
+	if !exists || storage.isExpired(entry.ExpiresAt) {
+		return domain.RefreshToken{}, myerrors.ErrNoSuchRefreshToken
+	}
+	if entry.RefreshToken.Used {
+		return entry.RefreshToken, myerrors.ErrRefreshTokenReused
+	}
+
+	return entry.RefreshToken, nil
+}
+
+// ConsumeRefreshToken помечает refresh токен использованным. Повторная попытка возвращает
+// ErrRefreshTokenReused вместе с токеном
+func (storage *SessionStorage) ConsumeRefreshToken(tokenHash string) (domain.RefreshToken, error) {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	entry, exists := storage.data.RefreshTokens[tokenHash]
+	if !exists || storage.isExpired(entry.ExpiresAt) {
+		return domain.RefreshToken{}, myerrors.ErrNoSuchRefreshToken
+	}
+
+	if entry.RefreshToken.Used {
+		return entry.RefreshToken, myerrors.ErrRefreshTokenReused
+	}
+
+	refreshToken := entry.RefreshToken
+	entry.RefreshToken.Used = true
+
+	return refreshToken, nil
+}
+
+func (storage *SessionStorage) RevokeRefreshTokenFamily(family string) error {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	familyTokens, exists := storage.data.Families[family]
+	if !exists {
+		return nil
+	}
+
+	for tokenHash := range familyTokens.TokenHashes {
+		delete(storage.data.RefreshTokens, tokenHash)
+	}
+	delete(storage.data.Families, family)
+
+	return nil
+}
+
+// GetLoginAttempts возвращает неудачные попытки входа по ключу аккаунта или IP
+func (storage *SessionStorage) GetLoginAttempts(key string) (domain.LoginAttempts, error) {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	entry, exists := storage.data.LoginAttempts[key]
+	if !exists || storage.isExpired(entry.ExpiresAt) {
+		return domain.LoginAttempts{}, nil
+	}
+
+	return entry.Attempts, nil
+}
+
+// ReserveLoginAttempt заранее учитывает попытку входа как неудачную, если policy ее разрешает. Счетчик
+// сбрасывается после window без неудачных попыток
+func (storage *SessionStorage) ReserveLoginAttempt(key string, now time.Time, window time.Duration,
+	policy domain.LoginPolicy) (domain.LoginAttempts, bool, error) {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	entry, exists := storage.data.LoginAttempts[key]
+	if !exists || storage.isExpired(entry.ExpiresAt) {
+		entry = &loginAttemptsEntry{}
+		storage.data.LoginAttempts[key] = entry
+	}
+	if policy.Throttle(entry.Attempts, now).RetryAfter > 0 {
+		return entry.Attempts, false, nil
+	}
+
+	entry.Attempts.Failures++
+	entry.Attempts.LastFailureAt = now
+	entry.ExpiresAt = storage.now().Add(window)
+
+	return entry.Attempts, true, nil
+}
+
+// ReleaseLoginAttempt снимает зарезервированную попытку входа
+func (storage *SessionStorage) ReleaseLoginAttempt(key string) error {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	entry, exists := storage.data.LoginAttempts[key]
+	if exists && !storage.isExpired(entry.ExpiresAt) && entry.Attempts.Failures > 0 {
+		entry.Attempts.Failures--
+	}
+
+	return nil
+}
+
+// ResetLoginAttempts сбрасывает неудачные попытки входа
+func (storage *SessionStorage) ResetLoginAttempts(key string) error {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	delete(storage.data.LoginAttempts, key)
+
+	return nil
+}
+
+// SaveDeviceAuthorization сохраняет новую авторизацию устройства на ttl. Если такой пользовательский код
+// уже выдан, возвращает ErrItemsIsAlreadyInTheCache
+func (storage *SessionStorage) SaveDeviceAuthorization(deviceCodeHash string,
+	authorization domain.DeviceAuthorization, ttl time.Duration) error {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	if entry, exists := storage.data.DeviceAuthorizations[authorization.UserCode]; exists {
+		if !storage.isExpired(entry.ExpiresAt) {
+			return myerrors.ErrItemsIsAlreadyInTheCache
+		}
+		delete(storage.data.DeviceCodes, entry.DeviceCodeHash)
+	}
+
+	storage.data.DeviceAuthorizations[authorization.UserCode] = &deviceAuthorizationEntry{
+		Authorization:  authorization,
+		DeviceCodeHash: deviceCodeHash,
+		ExpiresAt:      storage.now().Add(ttl),
+	}
+	storage.data.DeviceCodes[deviceCodeHash] = authorization.UserCode
+
+	return nil
+}
+
+// GetDeviceAuthorization возвращает авторизацию по пользовательскому коду
+func (storage *SessionStorage) GetDeviceAuthorization(userCode string) (domain.DeviceAuthorization, error) {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	entry, exists := storage.data.DeviceAuthorizations[userCode]
+	if !exists || storage.isExpired(entry.ExpiresAt) {
+		return domain.DeviceAuthorization{}, myerrors.ErrNoSuchDeviceAuthorization
+	}
+
+	return entry.Authorization, nil
+}
+
+// DecideDeviceAuthorization подтверждает или отклоняет авторизацию от имени login. Решение принимается один
+// раз, для уже решенной авторизации возвращается ErrNoSuchDeviceAuthorization
+func (storage *SessionStorage) DecideDeviceAuthorization(userCode, login string, approved bool) error {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	entry, exists := storage.data.DeviceAuthorizations[userCode]
+	if !exists || storage.isExpired(entry.ExpiresAt) ||
+		entry.Authorization.Status != domain.DeviceAuthorizationPending {
+		return myerrors.ErrNoSuchDeviceAuthorization
+	}
+
+	entry.Authorization.Status = domain.DeviceAuthorizationDenied
+	if approved {
+		entry.Authorization.Status = domain.DeviceAuthorizationApproved
+		entry.Authorization.Login = login
+	}
+
+	return nil
+}
+
+// PollDeviceAuthorization возвращает авторизацию по хешу device code с временем предыдущего опроса
+// и запоминает время текущего. Решенная авторизация возвращается один раз и удаляется
+func (storage *SessionStorage) PollDeviceAuthorization(deviceCodeHash string,
+	polledAt time.Time) (domain.DeviceAuthorization, error) {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	entry, exists := storage.data.DeviceAuthorizations[storage.data.DeviceCodes[deviceCodeHash]]
+	if !exists || entry.DeviceCodeHash != deviceCodeHash || storage.isExpired(entry.ExpiresAt) {
+		return domain.DeviceAuthorization{}, myerrors.ErrNoSuchDeviceAuthorization
+	}
+
+	authorization := entry.Authorization
+	if authorization.Status != domain.DeviceAuthorizationPending {
+		delete(storage.data.DeviceAuthorizations, authorization.UserCode)
+		delete(storage.data.DeviceCodes, deviceCodeHash)
+		return authorization, nil
+	}
+
+	entry.Authorization.LastPolledAt = polledAt
+	return authorization, nil
+}
+
+// SavePasskeyChallenge сохраняет challenge церемонии WebAuthn до expiresAt. Если challenge с таким
+// идентификатором уже сохранен, возвращает ErrItemsIsAlreadyInTheCache
+func (storage *SessionStorage) SavePasskeyChallenge(challengeId string, expiresAt time.Time) error {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	if savedExpiresAt, exists := storage.data.PasskeyChallenges[challengeId]; exists &&
+		!storage.isExpired(savedExpiresAt) {
+		return myerrors.ErrItemsIsAlreadyInTheCache
+	}
+	storage.data.PasskeyChallenges[challengeId] = expiresAt
+
+	return nil
+}
+
+// ConsumePasskeyChallenge удаляет challenge. Использованный или истекший challenge дает ErrNoSuchPasskeyChallenge
+func (storage *SessionStorage) ConsumePasskeyChallenge(challengeId string) error {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+
+	expiresAt, exists := storage.data.PasskeyChallenges[challengeId]
+	delete(storage.data.PasskeyChallenges, challengeId)
+	if !exists || storage.isExpired(expiresAt) {
+		return myerrors.ErrNoSuchPasskeyChallenge
+	}
+
+	return nil
+}
+
+// userSessions возвращает неистекшие сессии пользователя или nil, если их нет
+func (storage *SessionStorage) userSessions(login string) map[string]*sessionEntry {
+	sessions, exists := storage.data.Sessions[login]
+	if !exists {
+		return nil
+	}
+
+	for token, entry := range sessions {
+		if storage.isExpired(entry.ExpiresAt) {
+			delete(sessions, token)
+		}
+	}
+	if len(sessions) == 0 {
+		delete(storage.data.Sessions, login)
+		return nil
+	}
+
+	return sessions
+}
+
+func (storage *SessionStorage) removeExpired() {
+	for login := range storage.data.Sessions {
+		storage.userSessions(login)
+	}
+
+	for tokenHash, entry := range storage.data.RefreshTokens {
+		if storage.isExpired(entry.ExpiresAt) {
+			delete(storage.data.RefreshTokens, tokenHash)
+		}
+	}
+
+	for family, entry := range storage.data.Families {
+		if storage.isExpired(entry.ExpiresAt) {
+			delete(storage.data.Families, family)
+		}
+	}
+
+	for key, entry := range storage.data.LoginAttempts {
+		if storage.isExpired(entry.ExpiresAt) {
+			delete(storage.data.LoginAttempts, key)
+		}
+	}
+
+	for userCode, entry := range storage.data.DeviceAuthorizations {
+		if storage.isExpired(entry.ExpiresAt) {
+			delete(storage.data.DeviceAuthorizations, userCode)
+			delete(storage.data.DeviceCodes, entry.DeviceCodeHash)
+		}
+	}
+
+	for challengeId, expiresAt := range storage.data.PasskeyChallenges {
+		if storage.isExpired(expiresAt) {
+			delete(storage.data.PasskeyChallenges, challengeId)
+		}
+	}
+}
+
+func (storage *SessionStorage) isExpired(expiresAt time.Time) bool {
+	return !storage.now().Before(expiresAt)
+}
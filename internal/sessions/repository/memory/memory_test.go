@@ -0,0 +1,93 @@
+package memory
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/SanExpett/diploma/internal/domain"
+	"github.com/SanExpett/diploma/internal/sessions/repository/storagetest"
+)
+
+// testClock время хранилища, которое тест сдвигает вручную
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (clock *testClock) Now() time.Time {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	return clock.now
+}
+
+func (clock *testClock) Advance(d time.Duration) {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	clock.now = clock.now.Add(d)
+}
+
+func newTestSessionStorage(t *testing.T, snapshotPath string) (*SessionStorage, *testClock) {
+	storage, err := NewSessionStorage(storagetest.SessionTTL, snapshotPath)
+	require.NoError(t, err)
+
+	clock := &testClock{now: time.Now()}
+	storage.now = clock.Now
+
+	return storage, clock
+}
+
+func TestSessionStorage_Contract(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) (storagetest.Storage, func(d time.Duration)) {
+		storage, clock := newTestSessionStorage(t, "")
+		return storage, clock.Advance
+	})
+}
+
+func TestSessionStorage_Snapshot(t *testing.T) {
+	snapshotPath := filepath.Join(t.TempDir(), "sessions.json")
+
+	storage, _ := newTestSessionStorage(t, snapshotPath)
+	require.NoError(t, storage.Add("test@test.com", domain.Session{Id: "token", Version: 3}))
+	require.NoError(t, storage.SaveRefreshToken("hash", domain.RefreshToken{Login: "test@test.com",
+		Family: "token"}, time.Hour))
+	require.NoError(t, storage.SaveSnapshot())
+
+	restored, err := NewSessionStorage(storagetest.SessionTTL, snapshotPath)
+	require.NoError(t, err)
+
+	version, err := restored.GetVersion("test@test.com", "token")
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(3), version)
+
+	require.NoError(t, restored.RevokeRefreshTokenFamily("token"))
+	_, err = restored.ConsumeRefreshToken("hash")
+	assert.Error(t, err, "семейство refresh токенов восстанавливается из снимка")
+}
+
+func TestSessionStorage_StartCleanup(t *testing.T) {
+	storage, clock := newTestSessionStorage(t, "")
+	require.NoError(t, storage.Add("test@test.com", domain.Session{Id: "token", Version: 1}))
+	require.NoError(t, storage.SaveRefreshToken("hash", domain.RefreshToken{Login: "test@test.com",
+		Family: "token"}, time.Minute))
+
+	clock.Advance(storagetest.SessionTTL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	storage.StartCleanup(ctx, time.Millisecond, func(err error) {
+		t.Error(err)
+	})
+
+	assert.Eventually(t, func() bool {
+		storage.mu.Lock()
+		defer storage.mu.Unlock()
+		return len(storage.data.Sessions) == 0 && len(storage.data.RefreshTokens) == 0 &&
+			len(storage.data.Families) == 0
+	}, time.Second, time.Millisecond)
+}
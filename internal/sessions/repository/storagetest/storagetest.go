@@ -0,0 +1,546 @@
+// Package storagetest содержит общие тесты хранилищ сессий, чтобы реализации в Redis и в памяти
+// вели себя одинаково
+package storagetest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/SanExpett/diploma/internal/domain"
+	myerrors "github.com/SanExpett/diploma/internal/errors"
+)
+
+// SessionTTL время жизни сессии, с которым Factory должна создавать хранилище
+const SessionTTL = time.Hour
+
+type Storage interface {
+	Add(login string, session domain.Session) error
+	DeleteSession(login string, token string) error
+	Update(login string, token string) error
+	CheckVersion(login string, token string, usersVersion uint32) (bool, error)
+	GetVersion(login string, token string) (uint32, error)
+	HasSession(login string, token string) error
+	ListSessions(login string) ([]domain.Session, error)
+	TouchSession(login string, token string, seenAt time.Time) error
+	SaveRefreshToken(tokenHash string, refreshToken domain.RefreshToken, ttl time.Duration) error
+	GetRefreshToken(tokenHash string) (domain.RefreshToken, error)
+	ConsumeRefreshToken(tokenHash string) (domain.RefreshToken, error)
+	RevokeRefreshTokenFamily(family string) error
+	GetLoginAttempts(key string) (domain.LoginAttempts, error)
+	ReserveLoginAttempt(key string, now time.Time, window time.Duration,
+		policy domain.LoginPolicy) (domain.LoginAttempts, bool, error)
+	ReleaseLoginAttempt(key string) error
+	ResetLoginAttempts(key string) error
+	SaveDeviceAuthorization(deviceCodeHash string, authorization domain.DeviceAuthorization, ttl time.Duration) error
+	GetDeviceAuthorization(userCode string) (domain.DeviceAuthorization, error)
+	DecideDeviceAuthorization(userCode, login string, approved bool) error
+	PollDeviceAuthorization(deviceCodeHash string, polledAt time.Time) (domain.DeviceAuthorization, error)
+	SavePasskeyChallenge(challengeId string, expiresAt time.Time) error
+	ConsumePasskeyChallenge(challengeId string) error
+}
+
+// Factory создает пустое хранилище с временем жизни сессии SessionTTL и функцию,
+// которая сдвигает время хранилища вперед
+type Factory func(t *testing.T) (storage Storage, advance func(d time.Duration))
+
+// Run запускает тесты контракта хранилища сессий
+func Run(t *testing.T, newStorage Factory) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, storage Storage, advance func(d time.Duration))
+	}{
+		{"Добавление и проверка сессии", testAddSession},
+		{"Удаление сессии", testDeleteSession},
+		{"Обновление версии", testUpdateSession},
+		{"Список сессий", testListSessions},
+		{"Одновременные входы", testConcurrentAdd},
+		{"Истечение сессии", testSessionExpires},
+		{"Отметка активности", testTouchSession},
+		{"Refresh токены", testRefreshTokens},
+		{"Истечение refresh токена", testRefreshTokenExpires},
+		{"Неудачные попытки входа", testLoginAttempts},
+		{"Одновременные попытки входа", testConcurrentLoginAttempts},
+		{"Сброс попыток входа по истечении окна", testLoginAttemptsExpire},
+		{"Авторизация устройства", testDeviceAuthorization},
+		{"Отклонение авторизации устройства", testDeviceAuthorizationDenied},
+		{"Истечение авторизации устройства", testDeviceAuthorizationExpires},
+		{"Challenge passkey", testPasskeyChallenge},
+		{"Одновременное использование challenge passkey", testConcurrentPasskeyChallenge},
+		{"Истечение challenge passkey", testPasskeyChallengeExpires},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage, advance := newStorage(t)
+			tt.test(t, storage, advance)
+		})
+	}
+}
+
+// LegacyStorage хранилище, которое переносит сессии, хранившиеся одним JSON под ключом-логином
+type LegacyStorage interface {
+	Storage
+	MigrateLegacySessions(ctx context.Context) (int, error)
+}
+
+// LegacyFactory создает пустое хранилище, функцию, которая записывает строковый ключ в обход хранилища,
+// и функцию, которая проверяет, что ключ есть
+type LegacyFactory func(t *testing.T) (storage LegacyStorage, set func(key, value string),
+	exists func(key string) bool)
+
+// RunMigration запускает тесты переноса сессий старого формата
+func RunMigration(t *testing.T, newStorage LegacyFactory) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, storage LegacyStorage, set func(key, value string), exists func(key string) bool)
+	}{
+		{"Перенос сессий по логину", testMigrateLegacySessions},
+		{"Чужие ключи не удаляются", testMigrationKeepsForeignKeys},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage, set, exists := newStorage(t)
+			tt.test(t, storage, set, exists)
+		})
+	}
+}
+
+func testMigrateLegacySessions(t *testing.T, storage LegacyStorage, set func(key, value string),
+	exists func(key string) bool) {
+	set("old@test.com", `{"token":2}`)
+
+	migrated, err := storage.MigrateLegacySessions(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, migrated)
+
+	version, err := storage.GetVersion("old@test.com", "token")
+	require.NoError(t, err)
+	assert.Equal(t, uint32(2), version)
+	assert.False(t, exists("old@test.com"))
+}
+
+func testMigrationKeepsForeignKeys(t *testing.T, storage LegacyStorage, set func(key, value string),
+	exists func(key string) bool) {
+	// значения разбираются как сессии старого формата, но ключи не логины или принадлежат хранилищу
+	foreignKeys := []string{
+		"app:config",
+		"Admin@Test.com",
+		"feature_flags",
+		"device_code:user@test.com",
+		"login_attempts:user@test.com",
+		"passkey_challenge:user@test.com",
+		"refresh_family:user@test.com",
+	}
+	for _, key := range foreignKeys {
+		set(key, `{"settings":{"version":1}}`)
+	}
+
+	migrated, err := storage.MigrateLegacySessions(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, migrated)
+
+	for _, key := range foreignKeys {
+		assert.True(t, exists(key), key)
+	}
+}
+
+func testAddSession(t *testing.T, storage Storage, _ func(d time.Duration)) {
+	require.NoError(t, storage.Add("test@test.com", domain.Session{Id: "token", Version: 2}))
+	assert.ErrorIs(t, storage.Add("test@test.com", domain.Session{Id: "token", Version: 1}),
+		myerrors.ErrItemsIsAlreadyInTheCache)
+
+	assert.NoError(t, storage.HasSession("test@test.com", "token"))
+	assert.ErrorIs(t, storage.HasSession("test@test.com", "unknown"), myerrors.ErrNoSuchUser)
+	assert.ErrorIs(t, storage.HasSession("unknown@test.com", "token"), myerrors.ErrNoSuchUser)
+
+	version, err := storage.GetVersion("test@test.com", "token")
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(2), version)
+	_, err = storage.GetVersion("test@test.com", "unknown")
+	assert.ErrorIs(t, err, myerrors.ErrNoSuchItemInTheCache)
+
+	hasSession, err := storage.CheckVersion("test@test.com", "token", 2)
+	assert.NoError(t, err)
+	assert.True(t, hasSession)
+	hasSession, err = storage.CheckVersion("test@test.com", "token", 1)
+	assert.ErrorIs(t, err, myerrors.ErrWrongSessionVersion)
+	assert.False(t, hasSession)
+}
+
+func testDeleteSession(t *testing.T, storage Storage, _ func(d time.Duration)) {
+	require.NoError(t, storage.Add("test@test.com", domain.Session{Id: "token1", Version: 1}))
+	require.NoError(t, storage.Add("test@test.com", domain.Session{Id: "token2", Version: 1}))
+
+	assert.ErrorIs(t, storage.DeleteSession("unknown@test.com", "token1"), myerrors.ErrNoSuchUserInTheCache)
+	assert.ErrorIs(t, storage.DeleteSession("test@test.com", "unknown"), myerrors.ErrNoSuchSessionInTheCache)
+
+	assert.NoError(t, storage.DeleteSession("test@test.com", "token1"))
+	assert.Error(t, storage.HasSession("test@test.com", "token1"))
+	assert.NoError(t, storage.HasSession("test@test.com", "token2"))
+
+	assert.NoError(t, storage.DeleteSession("test@test.com", "token2"))
+	assert.ErrorIs(t, storage.DeleteSession("test@test.com", "token2"), myerrors.ErrNoSuchUserInTheCache)
+}
+
+func testUpdateSession(t *testing.T, storage Storage, _ func(d time.Duration)) {
+	require.NoError(t, storage.Add("test@test.com", domain.Session{Id: "token", Version: 1}))
+	require.NoError(t, storage.Add("test@test.com", domain.Session{Id: "old", Version: 255}))
+
+	assert.NoError(t, storage.Update("test@test.com", "token"))
+	version, err := storage.GetVersion("test@test.com", "token")
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(2), version)
+
+	assert.ErrorIs(t, storage.Update("test@test.com", "old"), myerrors.ErrTooHighVersion)
+	assert.ErrorIs(t, storage.Update("test@test.com", "unknown"), myerrors.ErrNoSuchSessionInTheCache)
+	assert.ErrorIs(t, storage.Update("unknown@test.com", "token"), myerrors.ErrNoSuchUserInTheCache)
+}
+
+func testListSessions(t *testing.T, storage Storage, _ func(d time.Duration)) {
+	_, err := storage.ListSessions("test@test.com")
+	assert.ErrorIs(t, err, myerrors.ErrNoSuchUserInTheCache)
+
+	now := time.Now().Truncate(time.Millisecond)
+	require.NoError(t, storage.Add("test@test.com", domain.Session{
+		Id:         "token1",
+		Version:    1,
+		UserAgent:  "curl/8.0",
+		Ip:         "192.0.2.1",
+		CreatedAt:  now.Add(-time.Hour),
+		LastSeenAt: now.Add(-time.Hour),
+	}))
+	require.NoError(t, storage.Add("test@test.com", domain.Session{
+		Id:         "token2",
+		Version:    1,
+		CreatedAt:  now,
+		LastSeenAt: now,
+	}))
+
+	sessions, err := storage.ListSessions("test@test.com")
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	assert.Equal(t, "token2", sessions[0].Id)
+	assert.Equal(t, "token1", sessions[1].Id)
+	assert.Equal(t, "curl/8.0", sessions[1].UserAgent)
+	assert.Equal(t, "192.0.2.1", sessions[1].Ip)
+	assert.True(t, now.Add(-time.Hour).Equal(sessions[1].CreatedAt))
+}
+
+func testConcurrentAdd(t *testing.T, storage Storage, _ func(d time.Duration)) {
+	const sessionsCount = 20
+	var wg sync.WaitGroup
+	for i := 0; i < sessionsCount; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			assert.NoError(t, storage.Add("test@test.com", domain.Session{Id: fmt.Sprintf("token%d", i), Version: 1}))
+		}(i)
+	}
+	wg.Wait()
+
+	sessions, err := storage.ListSessions("test@test.com")
+	require.NoError(t, err)
+	assert.Len(t, sessions, sessionsCount, "одновременные входы не теряют сессии")
+}
+
+func testSessionExpires(t *testing.T, storage Storage, advance func(d time.Duration)) {
+	now := time.Now()
+	require.NoError(t, storage.Add("test@test.com", domain.Session{Id: "token1", Version: 1, LastSeenAt: now}))
+	require.NoError(t, storage.Add("test@test.com", domain.Session{Id: "token2", Version: 1, LastSeenAt: now}))
+
+	advance(SessionTTL / 2)
+	require.NoError(t, storage.TouchSession("test@test.com", "token2", now.Add(SessionTTL/2)))
+
+	advance(SessionTTL/2 + time.Second)
+	assert.Error(t, storage.HasSession("test@test.com", "token1"), "сессия без активности истекает")
+	assert.NoError(t, storage.HasSession("test@test.com", "token2"), "активность продлевает сессию")
+
+	sessions, err := storage.ListSessions("test@test.com")
+	require.NoError(t, err)
+	assert.Len(t, sessions, 1)
+
+	advance(SessionTTL)
+	_, err = storage.ListSessions("test@test.com")
+	assert.ErrorIs(t, err, myerrors.ErrNoSuchUserInTheCache)
+}
+
+func testTouchSession(t *testing.T, storage Storage, _ func(d time.Duration)) {
+	now := time.Now().Truncate(time.Millisecond)
+	require.NoError(t, storage.Add("test@test.com", domain.Session{Id: "token", Version: 1, LastSeenAt: now}))
+
+	require.NoError(t, storage.TouchSession("test@test.com", "token", now.Add(30*time.Second)))
+	sessions, err := storage.ListSessions("test@test.com")
+	require.NoError(t, err)
+	assert.True(t, now.Equal(sessions[0].LastSeenAt), "активность отмечается не чаще раза в минуту")
+
+	require.NoError(t, storage.TouchSession("test@test.com", "token", now.Add(time.Minute)))
+	sessions, err = storage.ListSessions("test@test.com")
+	require.NoError(t, err)
+	assert.True(t, now.Add(time.Minute).Equal(sessions[0].LastSeenAt))
+
+	assert.ErrorIs(t, storage.TouchSession("test@test.com", "unknown", now), myerrors.ErrNoSuchSessionInTheCache)
+	assert.ErrorIs(t, storage.TouchSession("unknown@test.com", "token", now), myerrors.ErrNoSuchUserInTheCache)
+}
+
+func testRefreshTokens(t *testing.T, storage Storage, _ func(d time.Duration)) {
+	refreshToken := domain.RefreshToken{Login: "test@test.com", Family: "family"}
+	require.NoError(t, storage.SaveRefreshToken("hash1", refreshToken, SessionTTL))
+	require.NoError(t, storage.SaveRefreshToken("hash2", refreshToken, SessionTTL))
+
+	stored, err := storage.GetRefreshToken("hash1")
+	assert.NoError(t, err)
+	assert.Equal(t, refreshToken, stored)
+
+	consumed, err := storage.ConsumeRefreshToken("hash1")
+	assert.NoError(t, err, "чтение не помечает токен использованным")
+	assert.Equal(t, refreshToken, consumed)
+
+	stored, err = storage.GetRefreshToken("hash1")
+	assert.ErrorIs(t, err, myerrors.ErrRefreshTokenReused)
+	assert.Equal(t, refreshToken.Family, stored.Family)
+
+	consumed, err = storage.ConsumeRefreshToken("hash1")
+	assert.ErrorIs(t, err, myerrors.ErrRefreshTokenReused)
+	assert.Equal(t, refreshToken.Family, consumed.Family)
+	assert.Equal(t, refreshToken.Login, consumed.Login)
+
+	_, err = storage.ConsumeRefreshToken("unknown")
+	assert.ErrorIs(t, err, myerrors.ErrNoSuchRefreshToken)
+	_, err = storage.GetRefreshToken("unknown")
+	assert.ErrorIs(t, err, myerrors.ErrNoSuchRefreshToken)
+
+	require.NoError(t, storage.RevokeRefreshTokenFamily("family"))
+	_, err = storage.ConsumeRefreshToken("hash2")
+	assert.ErrorIs(t, err, myerrors.ErrNoSuchRefreshToken)
+
+	assert.NoError(t, storage.RevokeRefreshTokenFamily("unknown"))
+}
+
+func testRefreshTokenExpires(t *testing.T, storage Storage, advance func(d time.Duration)) {
+	refreshToken := domain.RefreshToken{Login: "test@test.com", Family: "family"}
+	require.NoError(t, storage.SaveRefreshToken("hash", refreshToken, time.Minute))
+
+	advance(time.Minute + time.Second)
+
+	_, err := storage.ConsumeRefreshToken("hash")
+	assert.ErrorIs(t, err, myerrors.ErrNoSuchRefreshToken)
+}
+
+// testLoginPolicy пропускает три попытки без задержки и затем блокирует вход на час
+var testLoginPolicy = domain.LoginPolicy{FreeAttempts: 3, LockoutAttempts: 3, BackoffBase: time.Second,
+	LockoutDuration: time.Hour}
+
+func testLoginAttempts(t *testing.T, storage Storage, _ func(d time.Duration)) {
+	attempts, err := storage.GetLoginAttempts("login:test@test.com")
+	require.NoError(t, err)
+	assert.Equal(t, domain.LoginAttempts{}, attempts)
+
+	now := time.Now().Truncate(time.Millisecond)
+	_, reserved, err := storage.ReserveLoginAttempt("login:test@test.com", now.Add(-time.Second), time.Hour,
+		testLoginPolicy)
+	require.NoError(t, err)
+	assert.True(t, reserved)
+	attempts, reserved, err = storage.ReserveLoginAttempt("login:test@test.com", now, time.Hour, testLoginPolicy)
+	require.NoError(t, err)
+	assert.True(t, reserved)
+	assert.Equal(t, uint32(2), attempts.Failures)
+	assert.True(t, now.Equal(attempts.LastFailureAt))
+
+	attempts, err = storage.GetLoginAttempts("login:test@test.com")
+	require.NoError(t, err)
+	assert.Equal(t, uint32(2), attempts.Failures)
+	assert.True(t, now.Equal(attempts.LastFailureAt))
+
+	attempts, err = storage.GetLoginAttempts("ip:192.0.2.1")
+	require.NoError(t, err)
+	assert.Zero(t, attempts.Failures, "счетчики разных ключей независимы")
+
+	require.NoError(t, storage.ReleaseLoginAttempt("login:test@test.com"))
+	attempts, err = storage.GetLoginAttempts("login:test@test.com")
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1), attempts.Failures, "успешная попытка снимается")
+
+	_, reserved, err = storage.ReserveLoginAttempt("login:test@test.com", now, time.Hour, testLoginPolicy)
+	require.NoError(t, err)
+	assert.True(t, reserved)
+	_, reserved, err = storage.ReserveLoginAttempt("login:test@test.com", now, time.Hour, testLoginPolicy)
+	require.NoError(t, err)
+	assert.True(t, reserved)
+	attempts, reserved, err = storage.ReserveLoginAttempt("login:test@test.com", now, time.Hour, testLoginPolicy)
+	require.NoError(t, err)
+	assert.False(t, reserved, "после блокировки попытки не резервируются")
+	assert.Equal(t, uint32(3), attempts.Failures)
+
+	_, reserved, err = storage.ReserveLoginAttempt("login:test@test.com", now.Add(time.Hour), time.Hour,
+		testLoginPolicy)
+	require.NoError(t, err)
+	assert.True(t, reserved, "блокировка истекла")
+
+	require.NoError(t, storage.ResetLoginAttempts("login:test@test.com"))
+	attempts, err = storage.GetLoginAttempts("login:test@test.com")
+	require.NoError(t, err)
+	assert.Zero(t, attempts.Failures)
+}
+
+func testConcurrentLoginAttempts(t *testing.T, storage Storage, _ func(d time.Duration)) {
+	now := time.Now()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	reservedCount := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, reserved, err := storage.ReserveLoginAttempt("login:test@test.com", now, time.Hour, testLoginPolicy)
+			if err == nil && reserved {
+				mu.Lock()
+				reservedCount++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int(testLoginPolicy.LockoutAttempts), reservedCount)
+}
+
+func testLoginAttemptsExpire(t *testing.T, storage Storage, advance func(d time.Duration)) {
+	_, _, err := storage.ReserveLoginAttempt("login:test@test.com", time.Now(), time.Minute, testLoginPolicy)
+	require.NoError(t, err)
+
+	advance(time.Minute / 2)
+	_, _, err = storage.ReserveLoginAttempt("login:test@test.com", time.Now(), time.Minute, testLoginPolicy)
+	require.NoError(t, err)
+
+	advance(time.Minute / 2)
+	attempts, err := storage.GetLoginAttempts("login:test@test.com")
+	require.NoError(t, err)
+	assert.Equal(t, uint32(2), attempts.Failures, "новая попытка продлевает окно")
+
+	advance(time.Minute)
+	attempts, err = storage.GetLoginAttempts("login:test@test.com")
+	require.NoError(t, err)
+	assert.Zero(t, attempts.Failures)
+}
+
+func testDeviceAuthorization(t *testing.T, storage Storage, _ func(d time.Duration)) {
+	createdAt := time.Now().Truncate(time.Millisecond)
+	authorization := domain.DeviceAuthorization{
+		UserCode:   "BCDF-GHJK",
+		Status:     domain.DeviceAuthorizationPending,
+		ClientName: "Smart TV",
+		Ip:         "192.0.2.1",
+		CreatedAt:  createdAt,
+	}
+	require.NoError(t, storage.SaveDeviceAuthorization("hash", authorization, time.Minute))
+	assert.ErrorIs(t, storage.SaveDeviceAuthorization("other", authorization, time.Minute),
+		myerrors.ErrItemsIsAlreadyInTheCache, "пользовательский код не выдается дважды")
+
+	saved, err := storage.GetDeviceAuthorization("BCDF-GHJK")
+	require.NoError(t, err)
+	assert.Equal(t, "Smart TV", saved.ClientName)
+	assert.True(t, createdAt.Equal(saved.CreatedAt))
+
+	polledAt := createdAt.Add(5 * time.Second)
+	polled, err := storage.PollDeviceAuthorization("hash", polledAt)
+	require.NoError(t, err)
+	assert.Equal(t, domain.DeviceAuthorizationPending, polled.Status)
+	assert.True(t, polled.LastPolledAt.IsZero(), "возвращается время предыдущего опроса")
+
+	polled, err = storage.PollDeviceAuthorization("hash", polledAt.Add(5*time.Second))
+	require.NoError(t, err)
+	assert.True(t, polledAt.Equal(polled.LastPolledAt))
+
+	require.NoError(t, storage.DecideDeviceAuthorization("BCDF-GHJK", "test@test.com", true))
+	assert.ErrorIs(t, storage.DecideDeviceAuthorization("BCDF-GHJK", "other@test.com", true),
+		myerrors.ErrNoSuchDeviceAuthorization, "решение принимается один раз")
+
+	polled, err = storage.PollDeviceAuthorization("hash", polledAt.Add(10*time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, domain.DeviceAuthorizationApproved, polled.Status)
+	assert.Equal(t, "test@test.com", polled.Login)
+
+	_, err = storage.PollDeviceAuthorization("hash", polledAt.Add(15*time.Second))
+	assert.ErrorIs(t, err, myerrors.ErrNoSuchDeviceAuthorization, "подтвержденный код обменивается один раз")
+	_, err = storage.GetDeviceAuthorization("BCDF-GHJK")
+	assert.ErrorIs(t, err, myerrors.ErrNoSuchDeviceAuthorization)
+}
+
+func testDeviceAuthorizationDenied(t *testing.T, storage Storage, _ func(d time.Duration)) {
+	authorization := domain.DeviceAuthorization{UserCode: "BCDF-GHJK", Status: domain.DeviceAuthorizationPending}
+	require.NoError(t, storage.SaveDeviceAuthorization("hash", authorization, time.Minute))
+
+	require.NoError(t, storage.DecideDeviceAuthorization("BCDF-GHJK", "test@test.com", false))
+
+	polled, err := storage.PollDeviceAuthorization("hash", time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, domain.DeviceAuthorizationDenied, polled.Status)
+	assert.Empty(t, polled.Login)
+
+	assert.ErrorIs(t, storage.DecideDeviceAuthorization("UNKN-OWNN", "test@test.com", true),
+		myerrors.ErrNoSuchDeviceAuthorization)
+}
+
+func testDeviceAuthorizationExpires(t *testing.T, storage Storage, advance func(d time.Duration)) {
+	authorization := domain.DeviceAuthorization{UserCode: "BCDF-GHJK", Status: domain.DeviceAuthorizationPending}
+	require.NoError(t, storage.SaveDeviceAuthorization("hash", authorization, time.Minute))
+
+	advance(time.Minute + time.Second)
+
+	_, err := storage.PollDeviceAuthorization("hash", time.Now())
+	assert.ErrorIs(t, err, myerrors.ErrNoSuchDeviceAuthorization)
+	assert.ErrorIs(t, storage.DecideDeviceAuthorization("BCDF-GHJK", "test@test.com", true),
+		myerrors.ErrNoSuchDeviceAuthorization)
+
+	require.NoError(t, storage.SaveDeviceAuthorization("new", authorization, time.Minute),
+		"истекший пользовательский код можно выдать снова")
+}
+
+func testPasskeyChallenge(t *testing.T, storage Storage, _ func(d time.Duration)) {
+	require.NoError(t, storage.SavePasskeyChallenge("challenge", time.Now().Add(time.Minute)))
+	assert.ErrorIs(t, storage.SavePasskeyChallenge("challenge", time.Now().Add(time.Minute)),
+		myerrors.ErrItemsIsAlreadyInTheCache)
+
+	require.NoError(t, storage.ConsumePasskeyChallenge("challenge"))
+	assert.ErrorIs(t, storage.ConsumePasskeyChallenge("challenge"), myerrors.ErrNoSuchPasskeyChallenge,
+		"challenge используется один раз")
+	assert.ErrorIs(t, storage.ConsumePasskeyChallenge("unknown"), myerrors.ErrNoSuchPasskeyChallenge)
+}
+
+func testConcurrentPasskeyChallenge(t *testing.T, storage Storage, _ func(d time.Duration)) {
+	require.NoError(t, storage.SavePasskeyChallenge("challenge", time.Now().Add(time.Minute)))
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	consumed := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if storage.ConsumePasskeyChallenge("challenge") == nil {
+				mu.Lock()
+				consumed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, consumed)
+}
+
+func testPasskeyChallengeExpires(t *testing.T, storage Storage, advance func(d time.Duration)) {
+	require.NoError(t, storage.SavePasskeyChallenge("challenge", time.Now().Add(time.Minute)))
+
+	advance(time.Minute + time.Second)
+
+	assert.ErrorIs(t, storage.ConsumePasskeyChallenge("challenge"), myerrors.ErrNoSuchPasskeyChallenge)
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/SanExpett/diploma/internal/domain"
+	myerrors "github.com/SanExpett/diploma/internal/errors"
+	"github.com/SanExpett/diploma/internal/requestId"
+	"github.com/SanExpett/diploma/internal/tracing"
+)
+
+const (
+	deviceCodeTTL = 10 * time.Minute
+	// devicePollInterval как часто устройство может спрашивать статус, более частый опрос получает slow_down
+	devicePollInterval = 5 * time.Second
+
+	userCodeLength = 8
+	// userCodeAttempts сколько раз пробовать новый пользовательский код, если случайно выпал уже выданный
+	userCodeAttempts = 3
+)
+
+// userCodeAlphabet буквы пользовательского кода по RFC 8628: без гласных, чтобы из кода не складывались
+// слова, и без цифр, которые легко спутать с буквами при вводе с экрана телевизора
+const userCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+
+// StartDeviceAuthorization выдает устройству device code, по которому оно опрашивает статус,
+// и короткий код, который пользователь вводит на другом устройстве
+func (service *SessionService) StartDeviceAuthorization(ctx context.Context, clientName,
+	ip string) (domain.DeviceCode, error) {
+	service.metrics.IncRequestsTotal("StartDeviceAuthorization")
+	deviceCodeBytes := make([]byte, 32)
+	_, err := rand.Read(deviceCodeBytes)
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to generate device code: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return domain.DeviceCode{}, err
+	}
+	deviceCode := base64.RawURLEncoding.EncodeToString(deviceCodeBytes)
+
+	for attempt := 0; attempt < userCodeAttempts; attempt++ {
+		userCode, err := generateUserCode()
+		if err != nil {
+			service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to generate user code: %v",
+				ctx.Value(requestId.ReqIDKey), err)
+			return domain.DeviceCode{}, err
+		}
+
+		err = service.sessionStorage.SaveDeviceAuthorization(hashDeviceCode(deviceCode), domain.DeviceAuthorization{
+			UserCode:   userCode,
+			Status:     domain.DeviceAuthorizationPending,
+			ClientName: clientName,
+			Ip:         ip,
+			CreatedAt:  time.Now(),
+		}, deviceCodeTTL)
+		if errors.Is(err, myerrors.ErrItemsIsAlreadyInTheCache) {
+			continue
+		}
+		if err != nil {
+			service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to save device authorization: %v",
+				ctx.Value(requestId.ReqIDKey), err)
+			return domain.DeviceCode{}, err
+		}
+
+		return domain.DeviceCode{
+			DeviceCode: deviceCode,
+			UserCode:   userCode,
+			ExpiresIn:  deviceCodeTTL,
+			Interval:   devicePollInterval,
+		}, nil
+	}
+
+	err = fmt.Errorf("failed to find free user code: %w", myerrors.ErrItemsIsAlreadyInTheCache)
+	service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to start device authorization: %v",
+		ctx.Value(requestId.ReqIDKey), err)
+	return domain.DeviceCode{}, err
+}
+
+// GetDeviceAuthorization возвращает авторизацию по коду, который ввел пользователь, чтобы показать ему,
+// какое устройство он впускает в аккаунт
+func (service *SessionService) GetDeviceAuthorization(ctx context.Context,
+	userCode string) (domain.DeviceAuthorization, error) {
+	service.metrics.IncRequestsTotal("GetDeviceAuthorization")
+	authorization, err := service.sessionStorage.GetDeviceAuthorization(NormalizeUserCode(userCode))
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get device authorization: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return domain.DeviceAuthorization{}, err
+	}
+	if authorization.Status != domain.DeviceAuthorizationPending {
+		return domain.DeviceAuthorization{}, myerrors.ErrNoSuchDeviceAuthorization
+	}
+	return authorization, nil
+}
+
+// DecideDeviceAuthorization подтверждает вход устройства в аккаунт login или отклоняет его
+func (service *SessionService) DecideDeviceAuthorization(ctx context.Context, userCode, login string,
+	approve bool) error {
+	service.metrics.IncRequestsTotal("DecideDeviceAuthorization")
+	err := service.sessionStorage.DecideDeviceAuthorization(NormalizeUserCode(userCode), login, approve)
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to decide device authorization: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return err
+	}
+	return nil
+}
+
+// PollDeviceAuthorization возвращает статус авторизации устройства и, после подтверждения, логин
+// пользователя. Неизвестный или истекший device code дает статус expired
+func (service *SessionService) PollDeviceAuthorization(ctx context.Context, deviceCode string) (status string,
+	login string, err error) {
+	service.metrics.IncRequestsTotal("PollDeviceAuthorization")
+	now := time.Now()
+	authorization, err := service.sessionStorage.PollDeviceAuthorization(hashDeviceCode(deviceCode), now)
+	if errors.Is(err, myerrors.ErrNoSuchDeviceAuthorization) {
+		return domain.DeviceAuthorizationExpired, "", nil
+	}
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to poll device authorization: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return "", "", err
+	}
+
+	if authorization.Status == domain.DeviceAuthorizationPending && !authorization.LastPolledAt.IsZero() &&
+		now.Sub(authorization.LastPolledAt) < devicePollInterval {
+		return domain.DeviceAuthorizationSlowDown, "", nil
+	}
+	return authorization.Status, authorization.Login, nil
+}
+
+// NormalizeUserCode приводит введенный пользователем код к виду XXXX-XXXX: регистр и разделители
+// при вводе не важны
+func NormalizeUserCode(userCode string) string {
+	var builder strings.Builder
+	for _, char := range strings.ToUpper(userCode) {
+		if strings.ContainsRune(userCodeAlphabet, char) {
+			builder.WriteRune(char)
+		}
+	}
+
+	normalized := builder.String()
+	if len(normalized) != userCodeLength {
+		return normalized
+	}
+	return normalized[:userCodeLength/2] + "-" + normalized[userCodeLength/2:]
+}
+
+func generateUserCode() (string, error) {
+	code := make([]byte, 0, userCodeLength)
+	buf := make([]byte, 1)
+	for len(code) < userCodeLength {
+		_, err := rand.Read(buf)
+		if err != nil {
+			return "", err
+		}
+		// отбрасываем байты из неполного последнего круга, чтобы все буквы выпадали с равной вероятностью
+		if int(buf[0]) >= 256/len(userCodeAlphabet)*len(userCodeAlphabet) {
+			continue
+		}
+		code = append(code, userCodeAlphabet[int(buf[0])%len(userCodeAlphabet)])
+	}
+	return NormalizeUserCode(string(code)), nil
+}
+
+// hashDeviceCode ключ для хранения device code, чтобы сами коды не лежали в кеше
+func hashDeviceCode(deviceCode string) string {
+	hash := sha256.Sum256([]byte(deviceCode))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/SanExpett/diploma/internal/domain"
+	myerrors "github.com/SanExpett/diploma/internal/errors"
+	"github.com/SanExpett/diploma/internal/metrics"
+	mockService "github.com/SanExpett/diploma/internal/sessions/mock"
+)
+
+func TestNormalizeUserCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		userCode string
+		want     string
+	}{
+		{
+			name:     "Уже нормализован",
+			userCode: "BCDF-GHJK",
+			want:     "BCDF-GHJK",
+		},
+		{
+			name:     "Строчные буквы без дефиса",
+			userCode: "bcdfghjk",
+			want:     "BCDF-GHJK",
+		},
+		{
+			name:     "Пробелы и лишние разделители",
+			userCode: " bcdf ghjk ",
+			want:     "BCDF-GHJK",
+		},
+		{
+			name:     "Неполный код",
+			userCode: "bcd",
+			want:     "BCD",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, NormalizeUserCode(tt.userCode))
+		})
+	}
+}
+
+func TestStartDeviceAuthorization(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mockService.NewMocksessionStorage(ctrl)
+	service := NewSessionService(mockStorage, metrics.NewGrpcMetrics("sessions"), nil, zap.NewExample().Sugar())
+
+	var savedHash string
+	gomock.InOrder(
+		mockStorage.EXPECT().SaveDeviceAuthorization(gomock.Any(), gomock.Any(), deviceCodeTTL).
+			Return(myerrors.ErrItemsIsAlreadyInTheCache),
+		mockStorage.EXPECT().SaveDeviceAuthorization(gomock.Any(), gomock.Any(), deviceCodeTTL).
+			DoAndReturn(func(hash string, authorization domain.DeviceAuthorization, _ time.Duration) error {
+				savedHash = hash
+				assert.Equal(t, domain.DeviceAuthorizationPending, authorization.Status)
+				assert.Equal(t, "Smart TV", authorization.ClientName)
+				return nil
+			}),
+	)
+
+	deviceCode, err := service.StartDeviceAuthorization(context.Background(), "Smart TV", "192.0.2.1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, hashDeviceCode(deviceCode.DeviceCode), savedHash)
+	assert.Equal(t, deviceCode.UserCode, NormalizeUserCode(deviceCode.UserCode))
+	assert.Len(t, deviceCode.UserCode, userCodeLength+1)
+	assert.Equal(t, devicePollInterval, deviceCode.Interval)
+}
+
+func TestPollDeviceAuthorization(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name          string
+		authorization domain.DeviceAuthorization
+		storageErr    error
+		wantStatus    string
+		wantLogin     string
+	}{
+		{
+			name:          "Первый опрос",
+			authorization: domain.DeviceAuthorization{Status: domain.DeviceAuthorizationPending},
+			wantStatus:    domain.DeviceAuthorizationPending,
+		},
+		{
+			name: "Слишком частый опрос",
+			authorization: domain.DeviceAuthorization{Status: domain.DeviceAuthorizationPending,
+				LastPolledAt: now.Add(-time.Second)},
+			wantStatus: domain.DeviceAuthorizationSlowDown,
+		},
+		{
+			name: "Подтверждено",
+			authorization: domain.DeviceAuthorization{Status: domain.DeviceAuthorizationApproved,
+				Login: "testuser", LastPolledAt: now.Add(-time.Second)},
+			wantStatus: domain.DeviceAuthorizationApproved,
+			wantLogin:  "testuser",
+		},
+		{
+			name:       "Код истек",
+			storageErr: myerrors.ErrNoSuchDeviceAuthorization,
+			wantStatus: domain.DeviceAuthorizationExpired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStorage := mockService.NewMocksessionStorage(ctrl)
+			service := NewSessionService(mockStorage, metrics.NewGrpcMetrics("sessions"), nil,
+				zap.NewExample().Sugar())
+
+			mockStorage.EXPECT().PollDeviceAuthorization(hashDeviceCode("device-code"), gomock.Any()).
+				Return(tt.authorization, tt.storageErr)
+
+			status, login, err := service.PollDeviceAuthorization(context.Background(), "device-code")
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, status)
+			assert.Equal(t, tt.wantLogin, login)
+		})
+	}
+}
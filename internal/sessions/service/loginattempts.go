@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/SanExpett/diploma/internal/domain"
+	"github.com/SanExpett/diploma/internal/requestId"
+	"github.com/SanExpett/diploma/internal/tracing"
+)
+
+const (
+	// loginAttemptsWindow через это время без неудачных попыток счетчики сбрасываются
+	loginAttemptsWindow = time.Hour
+	// loginBackoffBase задержка после первой платной попытки, дальше она удваивается
+	loginBackoffBase = time.Second
+	// loginLockoutDuration время блокировки. Оно меньше окна, поэтому первая же ошибка после
+	// разблокировки снова блокирует вход
+	loginLockoutDuration = 30 * time.Minute
+)
+
+// loginPolicy ограничения неудачных попыток входа для аккаунта или IP
+type loginPolicy struct {
+	domain.LoginPolicy
+	scope     string
+	keyPrefix string
+}
+
+var (
+	accountLoginPolicy = loginPolicy{
+		LoginPolicy: domain.LoginPolicy{FreeAttempts: 5, LockoutAttempts: 10, BackoffBase: loginBackoffBase,
+			LockoutDuration: loginLockoutDuration},
+		scope:     "account",
+		keyPrefix: "login:",
+	}
+	// с одного IP могут входить несколько пользователей, например из офиса, поэтому лимиты выше
+	ipLoginPolicy = loginPolicy{
+		LoginPolicy: domain.LoginPolicy{FreeAttempts: 20, LockoutAttempts: 100, BackoffBase: loginBackoffBase,
+			LockoutDuration: loginLockoutDuration},
+		scope:     "ip",
+		keyPrefix: "ip:",
+	}
+)
+
+// ReserveLoginAttempt резервирует попытку входа в аккаунт login с адреса ip, если ограничения ее разрешают.
+// Проверка и резервирование атомарны, поэтому одновременные попытки не проходят мимо ограничений.
+// Зарезервированная попытка считается неудачной, пока ReleaseLoginAttempt не подтвердит успешную проверку.
+// Если попытка не разрешена, возвращается ненулевая задержка
+func (service *SessionService) ReserveLoginAttempt(ctx context.Context, login, ip string) (domain.LoginThrottle,
+	error) {
+	service.metrics.IncRequestsTotal("ReserveLoginAttempt")
+	// хранилище считает время в миллисекундах, так решение хранилища и задержка ниже совпадают
+	now := time.UnixMilli(time.Now().UnixMilli())
+
+	// адрес проверяется первым: с заблокированного адреса нельзя увеличивать счетчик чужого аккаунта
+	for _, counter := range loginCounters(login, ip) {
+		attempts, reserved, err := service.sessionStorage.ReserveLoginAttempt(counter.key, now,
+			loginAttemptsWindow, counter.policy.LoginPolicy)
+		if err != nil {
+			service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to reserve login attempt: %v",
+				ctx.Value(requestId.ReqIDKey), err)
+			return domain.LoginThrottle{}, err
+		}
+		if !reserved {
+			return counter.policy.Throttle(attempts, now), nil
+		}
+	}
+	return domain.LoginThrottle{}, nil
+}
+
+// RegisterLoginFailure учитывает неудачный вход попыткой, зарезервированной ReserveLoginAttempt, и возвращает
+// задержку до следующей попытки. Счетчики уже увеличены при резервировании
+func (service *SessionService) RegisterLoginFailure(ctx context.Context, login, ip string) (domain.LoginThrottle,
+	error) {
+	service.metrics.IncRequestsTotal("RegisterLoginFailure")
+	now := time.Now()
+
+	var throttle domain.LoginThrottle
+	for _, counter := range loginCounters(login, ip) {
+		attempts, err := service.sessionStorage.GetLoginAttempts(counter.key)
+		if err != nil {
+			service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get login attempts: %v",
+				ctx.Value(requestId.ReqIDKey), err)
+			return domain.LoginThrottle{}, err
+		}
+		service.loginMetrics.IncFailuresTotal(counter.policy.scope)
+
+		counterThrottle := counter.policy.Throttle(attempts, now)
+		if counterThrottle.Locked {
+			service.loginMetrics.IncLockoutsTotal(counter.policy.scope)
+			service.logger.With(tracing.TraceField(ctx)).Warnf("[reqid=%s] login locked for %s after %d failures",
+				ctx.Value(requestId.ReqIDKey), counter.key, attempts.Failures)
+		}
+		throttle = mergeThrottles(throttle, counterThrottle)
+	}
+	return throttle, nil
+}
+
+// ReleaseLoginAttempt снимает попытку, зарезервированную ReserveLoginAttempt, когда пароль или код подошел
+func (service *SessionService) ReleaseLoginAttempt(ctx context.Context, login, ip string) error {
+	service.metrics.IncRequestsTotal("ReleaseLoginAttempt")
+	for _, counter := range loginCounters(login, ip) {
+		err := service.sessionStorage.ReleaseLoginAttempt(counter.key)
+		if err != nil {
+			service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to release login attempt: %v",
+				ctx.Value(requestId.ReqIDKey), err)
+			return err
+		}
+	}
+	return nil
+}
+
+// ResetLoginAttempts сбрасывает неудачные попытки аккаунта после успешного входа. Счетчик IP не
+// сбрасывается, чтобы перебор паролей разных аккаунтов нельзя было обнулять входом в свой
+func (service *SessionService) ResetLoginAttempts(ctx context.Context, login string) error {
+	service.metrics.IncRequestsTotal("ResetLoginAttempts")
+	err := service.sessionStorage.ResetLoginAttempts(accountLoginPolicy.keyPrefix + login)
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to reset login attempts: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return err
+	}
+	return nil
+}
+
+// UnlockLogin снимает блокировку входа с аккаунта и, если передан ip, с адреса
+func (service *SessionService) UnlockLogin(ctx context.Context, login, ip string) error {
+	service.metrics.IncRequestsTotal("UnlockLogin")
+	for _, counter := range loginCounters(login, ip) {
+		err := service.sessionStorage.ResetLoginAttempts(counter.key)
+		if err != nil {
+			service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to unlock login: %v",
+				ctx.Value(requestId.ReqIDKey), err)
+			return err
+		}
+	}
+
+	service.loginMetrics.IncUnlocksTotal()
+	service.logger.With(tracing.TraceField(ctx)).Infof("[reqid=%s] login unlocked for %s %s",
+		ctx.Value(requestId.ReqIDKey), login, ip)
+	return nil
+}
+
+type loginCounter struct {
+	policy loginPolicy
+	key    string
+}
+
+// loginCounters возвращает счетчики неудачных попыток для адреса и аккаунта. Пустые значения
+// пропускаются
+func loginCounters(login, ip string) []loginCounter {
+	counters := make([]loginCounter, 0, 2)
+	if ip != "" {
+		counters = append(counters, loginCounter{policy: ipLoginPolicy, key: ipLoginPolicy.keyPrefix + ip})
+	}
+	if login != "" {
+		counters = append(counters, loginCounter{policy: accountLoginPolicy, key: accountLoginPolicy.keyPrefix + login})
+	}
+	return counters
+}
+
+func mergeThrottles(first, second domain.LoginThrottle) domain.LoginThrottle {
+	return domain.LoginThrottle{
+		RetryAfter: max(first.RetryAfter, second.RetryAfter),
+		Locked:     first.Locked || second.Locked,
+	}
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/SanExpett/diploma/internal/domain"
+	"github.com/SanExpett/diploma/internal/metrics"
+	mockService "github.com/SanExpett/diploma/internal/sessions/mock"
+)
+
+func TestLoginPolicyThrottle(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		attempts domain.LoginAttempts
+		want     domain.LoginThrottle
+	}{
+		{
+			name:     "Бесплатные попытки",
+			attempts: domain.LoginAttempts{Failures: 4, LastFailureAt: now},
+			want:     domain.LoginThrottle{},
+		},
+		{
+			name:     "Первая задержка",
+			attempts: domain.LoginAttempts{Failures: 5, LastFailureAt: now},
+			want:     domain.LoginThrottle{RetryAfter: time.Second},
+		},
+		{
+			name:     "Задержка удваивается",
+			attempts: domain.LoginAttempts{Failures: 8, LastFailureAt: now.Add(-3 * time.Second)},
+			want:     domain.LoginThrottle{RetryAfter: 5 * time.Second},
+		},
+		{
+			name:     "Задержка прошла",
+			attempts: domain.LoginAttempts{Failures: 9, LastFailureAt: now.Add(-time.Minute)},
+			want:     domain.LoginThrottle{},
+		},
+		{
+			name:     "Блокировка",
+			attempts: domain.LoginAttempts{Failures: 10, LastFailureAt: now},
+			want:     domain.LoginThrottle{RetryAfter: loginLockoutDuration, Locked: true},
+		},
+		{
+			name:     "Блокировка истекла",
+			attempts: domain.LoginAttempts{Failures: 12, LastFailureAt: now.Add(-loginLockoutDuration)},
+			want:     domain.LoginThrottle{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, accountLoginPolicy.Throttle(tt.attempts, now))
+		})
+	}
+
+	throttle := ipLoginPolicy.Throttle(domain.LoginAttempts{Failures: 99, LastFailureAt: now}, now)
+	assert.Equal(t, domain.LoginThrottle{RetryAfter: loginLockoutDuration}, throttle,
+		"задержка не превышает время блокировки")
+}
+
+func TestReserveLoginAttempt(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mockService.NewMocksessionStorage(ctrl)
+	service := NewSessionService(mockStorage, metrics.NewGrpcMetrics("sessions"), metrics.NewLoginMetrics(),
+		zap.NewExample().Sugar())
+
+	gomock.InOrder(
+		mockStorage.EXPECT().ReserveLoginAttempt("ip:192.0.2.1", gomock.Any(), loginAttemptsWindow,
+			ipLoginPolicy.LoginPolicy).Return(domain.LoginAttempts{Failures: 1}, true, nil),
+		mockStorage.EXPECT().ReserveLoginAttempt("login:testuser", gomock.Any(), loginAttemptsWindow,
+			accountLoginPolicy.LoginPolicy).Return(domain.LoginAttempts{Failures: 1}, true, nil),
+	)
+
+	throttle, err := service.ReserveLoginAttempt(context.Background(), "testuser", "192.0.2.1")
+
+	assert.NoError(t, err)
+	assert.Zero(t, throttle.RetryAfter)
+
+	mockStorage.EXPECT().ReserveLoginAttempt("ip:192.0.2.1", gomock.Any(), loginAttemptsWindow,
+		ipLoginPolicy.LoginPolicy).
+		DoAndReturn(func(_ string, now time.Time, _ time.Duration,
+			_ domain.LoginPolicy) (domain.LoginAttempts, bool, error) {
+			return domain.LoginAttempts{Failures: 100, LastFailureAt: now}, false, nil
+		})
+
+	throttle, err = service.ReserveLoginAttempt(context.Background(), "testuser", "192.0.2.1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, domain.LoginThrottle{RetryAfter: loginLockoutDuration, Locked: true}, throttle,
+		"с заблокированного адреса счетчик аккаунта не меняется")
+
+	mockStorage.EXPECT().ReserveLoginAttempt("login:testuser", gomock.Any(), loginAttemptsWindow,
+		accountLoginPolicy.LoginPolicy).Return(domain.LoginAttempts{}, false, errors.New(""))
+
+	_, err = service.ReserveLoginAttempt(context.Background(), "testuser", "")
+
+	assert.Error(t, err)
+}
+
+func TestRegisterLoginFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mockService.NewMocksessionStorage(ctrl)
+	service := NewSessionService(mockStorage, metrics.NewGrpcMetrics("sessions"), metrics.NewLoginMetrics(),
+		zap.NewExample().Sugar())
+
+	now := time.Now()
+	mockStorage.EXPECT().GetLoginAttempts("login:testuser").
+		Return(domain.LoginAttempts{Failures: 10, LastFailureAt: now}, nil)
+	mockStorage.EXPECT().GetLoginAttempts("ip:192.0.2.1").
+		Return(domain.LoginAttempts{Failures: 1, LastFailureAt: now}, nil)
+
+	throttle, err := service.RegisterLoginFailure(context.Background(), "testuser", "192.0.2.1")
+
+	assert.NoError(t, err)
+	assert.True(t, throttle.Locked)
+	assert.Greater(t, throttle.RetryAfter, loginLockoutDuration-time.Minute)
+}
+
+func TestResetLoginAttempts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mockService.NewMocksessionStorage(ctrl)
+	service := NewSessionService(mockStorage, metrics.NewGrpcMetrics("sessions"), metrics.NewLoginMetrics(),
+		zap.NewExample().Sugar())
+
+	mockStorage.EXPECT().ResetLoginAttempts("login:testuser").Return(nil)
+
+	assert.NoError(t, service.ResetLoginAttempts(context.Background(), "testuser"))
+
+	mockStorage.EXPECT().ReleaseLoginAttempt("login:testuser").Return(nil)
+	mockStorage.EXPECT().ReleaseLoginAttempt("ip:192.0.2.1").Return(nil)
+
+	assert.NoError(t, service.ReleaseLoginAttempt(context.Background(), "testuser", "192.0.2.1"))
+
+	mockStorage.EXPECT().ResetLoginAttempts("login:testuser").Return(nil)
+	mockStorage.EXPECT().ResetLoginAttempts("ip:192.0.2.1").Return(nil)
+
+	assert.NoError(t, service.UnlockLogin(context.Background(), "testuser", "192.0.2.1"))
+}
@@ -0,0 +1,37 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	myerrors "github.com/SanExpett/diploma/internal/errors"
+	"github.com/SanExpett/diploma/internal/requestId"
+	"github.com/SanExpett/diploma/internal/tracing"
+)
+
+// SavePasskeyChallenge запоминает challenge церемонии WebAuthn, чтобы ответ аутентификатора на него
+// можно было принять только один раз
+func (service *SessionService) SavePasskeyChallenge(ctx context.Context, challengeId string,
+	expiresAt time.Time) error {
+	service.metrics.IncRequestsTotal("SavePasskeyChallenge")
+	err := service.sessionStorage.SavePasskeyChallenge(challengeId, expiresAt)
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to save passkey challenge: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return err
+	}
+	return nil
+}
+
+// ConsumePasskeyChallenge использует challenge. Повторное использование и истекший challenge дают
+// ErrNoSuchPasskeyChallenge
+func (service *SessionService) ConsumePasskeyChallenge(ctx context.Context, challengeId string) error {
+	service.metrics.IncRequestsTotal("ConsumePasskeyChallenge")
+	err := service.sessionStorage.ConsumePasskeyChallenge(challengeId)
+	if err != nil && !errors.Is(err, myerrors.ErrNoSuchPasskeyChallenge) {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to consume passkey challenge: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+	}
+	return err
+}
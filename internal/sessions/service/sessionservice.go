@@ -2,43 +2,76 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
 
 	"go.uber.org/zap"
 
+	"github.com/SanExpett/diploma/internal/domain"
+	myerrors "github.com/SanExpett/diploma/internal/errors"
 	"github.com/SanExpett/diploma/internal/metrics"
 	"github.com/SanExpett/diploma/internal/requestId"
+	"github.com/SanExpett/diploma/internal/tracing"
 )
 
+const refreshTokenTTL = 30 * 24 * time.Hour
+
 type sessionStorage interface {
-	Add(login string, token string, version uint32) (err error)
+	Add(login string, session domain.Session) (err error)
 	DeleteSession(login string, token string) (err error)
 	Update(login string, token string) (err error)
 	CheckVersion(login string, token string, usersVersion uint32) (hasSession bool, err error)
 	GetVersion(login string, token string) (version uint32, err error)
 	HasSession(login string, token string) error
-	CheckAllUserSessionTokens(login string) error
+	ListSessions(login string) ([]domain.Session, error)
+	TouchSession(login string, token string, seenAt time.Time) error
+	SaveRefreshToken(tokenHash string, refreshToken domain.RefreshToken, ttl time.Duration) error
+	GetRefreshToken(tokenHash string) (domain.RefreshToken, error)
+	ConsumeRefreshToken(tokenHash string) (domain.RefreshToken, error)
+	RevokeRefreshTokenFamily(family string) error
+	GetLoginAttempts(key string) (domain.LoginAttempts, error)
+	ReserveLoginAttempt(key string, now time.Time, window time.Duration,
+		policy domain.LoginPolicy) (domain.LoginAttempts, bool, error)
+	ReleaseLoginAttempt(key string) error
+	ResetLoginAttempts(key string) error
+	SaveDeviceAuthorization(deviceCodeHash string, authorization domain.DeviceAuthorization, ttl time.Duration) error
+	GetDeviceAuthorization(userCode string) (domain.DeviceAuthorization, error)
+	DecideDeviceAuthorization(userCode, login string, approved bool) error
+	PollDeviceAuthorization(deviceCodeHash string, polledAt time.Time) (domain.DeviceAuthorization, error)
+	SavePasskeyChallenge(challengeId string, expiresAt time.Time) error
+	ConsumePasskeyChallenge(challengeId string) error
 }
 
 type SessionService struct {
 	sessionStorage sessionStorage
 	metrics        *metrics.GrpcMetrics
+	loginMetrics   *metrics.LoginMetrics
 	logger         *zap.SugaredLogger
 }
 
 func NewSessionService(sessionStorage sessionStorage, metrics *metrics.GrpcMetrics,
-	logger *zap.SugaredLogger) *SessionService {
+	loginMetrics *metrics.LoginMetrics, logger *zap.SugaredLogger) *SessionService {
 	return &SessionService{
 		sessionStorage: sessionStorage,
 		metrics:        metrics,
+		loginMetrics:   loginMetrics,
 		logger:         logger,
 	}
 }
 
-func (service *SessionService) Add(ctx context.Context, login, token string, version uint32) (err error) {
+func (service *SessionService) Add(ctx context.Context, login string, session domain.Session) (err error) {
 	service.metrics.IncRequestsTotal("Add")
-	err = service.sessionStorage.Add(login, token, version)
+	session.CreatedAt = time.Now()
+	session.LastSeenAt = session.CreatedAt
+	err = service.sessionStorage.Add(login, session)
 	if err != nil {
-		service.logger.Errorf("[reqid=%s] failed to add session: %v", ctx.Value(requestId.ReqIDKey), err)
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to add session: %v",
+			ctx.Value(requestId.ReqIDKey), err)
 		return err
 	}
 	return nil
@@ -48,7 +81,7 @@ func (service *SessionService) DeleteSession(ctx context.Context, login, token s
 	service.metrics.IncRequestsTotal("DeleteSession")
 	err = service.sessionStorage.DeleteSession(login, token)
 	if err != nil {
-		service.logger.Errorf("[reqid=%s] failed to delete session: %v",
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to delete session: %v",
 			ctx.Value(requestId.ReqIDKey), err)
 		return err
 	}
@@ -59,7 +92,8 @@ func (service *SessionService) Update(ctx context.Context, login, token string)
 	service.metrics.IncRequestsTotal("Update")
 	err = service.sessionStorage.Update(login, token)
 	if err != nil {
-		service.logger.Errorf("[reqid=%s] failed to update session: %v", ctx.Value(requestId.ReqIDKey), err)
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to update session: %v",
+			ctx.Value(requestId.ReqIDKey), err)
 		return err
 	}
 	return nil
@@ -70,7 +104,8 @@ func (service *SessionService) CheckVersion(ctx context.Context, login, token st
 	service.metrics.IncRequestsTotal("CheckVersion")
 	hasSession, err = service.sessionStorage.CheckVersion(login, token, usersVersion)
 	if err != nil {
-		service.logger.Errorf("[reqid=%s] failed to check version: %v", ctx.Value(requestId.ReqIDKey), err)
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to check version: %v",
+			ctx.Value(requestId.ReqIDKey), err)
 		return hasSession, err
 	}
 	return hasSession, nil
@@ -80,7 +115,8 @@ func (service *SessionService) GetVersion(ctx context.Context, login, token stri
 	service.metrics.IncRequestsTotal("GetVersion")
 	version, err = service.sessionStorage.GetVersion(login, token)
 	if err != nil {
-		service.logger.Errorf("[reqid=%s] failed to get version: %v", ctx.Value(requestId.ReqIDKey), err)
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get version: %v",
+			ctx.Value(requestId.ReqIDKey), err)
 		return version, err
 	}
 	return version, nil
@@ -90,19 +126,210 @@ func (service *SessionService) HasSession(ctx context.Context, login, token stri
 	service.metrics.IncRequestsTotal("HasSession")
 	err = service.sessionStorage.HasSession(login, token)
 	if err != nil {
-		service.logger.Errorf("[reqid=%s] failed to has session: %v", ctx.Value(requestId.ReqIDKey), err)
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to has session: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return err
+	}
+
+	err = service.sessionStorage.TouchSession(login, token, time.Now())
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to touch session: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+	}
+	return nil
+}
+
+func (service *SessionService) ListSessions(ctx context.Context, login string) ([]domain.Session, error) {
+	service.metrics.IncRequestsTotal("ListSessions")
+	sessions, err := service.sessionStorage.ListSessions(login)
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to list sessions: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// RevokeSession завершает сессию и отзывает ее refresh токены
+func (service *SessionService) RevokeSession(ctx context.Context, login, token string) error {
+	service.metrics.IncRequestsTotal("RevokeSession")
+	err := service.sessionStorage.DeleteSession(login, token)
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to revoke session: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return err
+	}
+
+	err = service.sessionStorage.RevokeRefreshTokenFamily(token)
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to revoke token family: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return err
+	}
+	return nil
+}
+
+// RevokeOtherSessions завершает все сессии пользователя, кроме текущей, и возвращает их количество
+func (service *SessionService) RevokeOtherSessions(ctx context.Context, login, currentToken string) (uint32,
+	error) {
+	service.metrics.IncRequestsTotal("RevokeOtherSessions")
+	sessions, err := service.sessionStorage.ListSessions(login)
+	if errors.Is(err, myerrors.ErrNoSuchUserInTheCache) {
+		return 0, nil
+	}
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to list sessions: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return 0, err
+	}
+
+	var revoked uint32
+	for _, session := range sessions {
+		if session.Id == currentToken {
+			continue
+		}
+
+		err = service.revokeRefreshTokenFamily(login, session.Id)
+		if err != nil {
+			service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to revoke session: %v",
+				ctx.Value(requestId.ReqIDKey), err)
+			return revoked, err
+		}
+		revoked++
+	}
+	return revoked, nil
+}
+
+// IssueRefreshToken начинает новое семейство refresh токенов. Идентификатор семейства используется
+// gateway как идентификатор сессии
+func (service *SessionService) IssueRefreshToken(ctx context.Context, login string) (family string,
+	refreshToken string, err error) {
+	service.metrics.IncRequestsTotal("IssueRefreshToken")
+	familyBytes := make([]byte, 16)
+	_, err = rand.Read(familyBytes)
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to generate token family: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return "", "", err
+	}
+	family = hex.EncodeToString(familyBytes)
+
+	refreshToken, err = service.saveRefreshToken(login, family)
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to issue refresh token: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return "", "", err
+	}
+	return family, refreshToken, nil
+}
+
+// GetRefreshToken возвращает владельца и семейство refresh токена, не обменивая его, чтобы gateway мог
+// проверить сессию до обмена. Уже обмененный токен отзывает семейство так же, как RotateRefreshToken
+func (service *SessionService) GetRefreshToken(ctx context.Context, refreshToken string) (login string,
+	family string, err error) {
+	service.metrics.IncRequestsTotal("GetRefreshToken")
+	storedToken, err := service.sessionStorage.GetRefreshToken(hashRefreshToken(refreshToken))
+	if errors.Is(err, myerrors.ErrRefreshTokenReused) {
+		service.revokeReusedRefreshToken(ctx, storedToken)
+		return "", "", err
+	}
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get refresh token: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return "", "", err
+	}
+	return storedToken.Login, storedToken.Family, nil
+}
+
+// RotateRefreshToken обменивает refresh токен на новый из того же семейства. Повторное
+// использование уже обмененного токена означает, что он утек, поэтому отзывается все семейство
+// вместе с сессией
+func (service *SessionService) RotateRefreshToken(ctx context.Context, refreshToken string) (login string,
+	family string, newRefreshToken string, err error) {
+	service.metrics.IncRequestsTotal("RotateRefreshToken")
+	storedToken, err := service.sessionStorage.ConsumeRefreshToken(hashRefreshToken(refreshToken))
+	if errors.Is(err, myerrors.ErrRefreshTokenReused) {
+		service.revokeReusedRefreshToken(ctx, storedToken)
+		return "", "", "", err
+	}
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to consume refresh token: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return "", "", "", err
+	}
+
+	newRefreshToken, err = service.saveRefreshToken(storedToken.Login, storedToken.Family)
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to issue refresh token: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return "", "", "", err
+	}
+
+	// обновление токенов считается активностью и продлевает сессию вместе с refresh токеном
+	err = service.sessionStorage.TouchSession(storedToken.Login, storedToken.Family, time.Now())
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to touch session: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+	}
+	return storedToken.Login, storedToken.Family, newRefreshToken, nil
+}
+
+func (service *SessionService) RevokeRefreshTokenFamily(ctx context.Context, login, family string) error {
+	service.metrics.IncRequestsTotal("RevokeRefreshTokenFamily")
+	err := service.revokeRefreshTokenFamily(login, family)
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to revoke token family: %v",
+			ctx.Value(requestId.ReqIDKey), err)
 		return err
 	}
 	return nil
 }
 
-func (service *SessionService) CheckAllUserSessionTokens(ctx context.Context, login string) error {
-	service.metrics.IncRequestsTotal("CheckAllUserSessionTokens")
-	err := service.sessionStorage.CheckAllUserSessionTokens(login)
+func (service *SessionService) revokeReusedRefreshToken(ctx context.Context, storedToken domain.RefreshToken) {
+	service.logger.With(tracing.TraceField(ctx)).Warnf(
+		"[reqid=%s] refresh token reuse detected for %s, revoking family %s", ctx.Value(requestId.ReqIDKey),
+		storedToken.Login, storedToken.Family)
+	err := service.revokeRefreshTokenFamily(storedToken.Login, storedToken.Family)
 	if err != nil {
-		service.logger.Errorf("[reqid=%s] failed to check all user's session tokens: %v",
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to revoke token family: %v",
 			ctx.Value(requestId.ReqIDKey), err)
+	}
+}
+
+func (service *SessionService) saveRefreshToken(login, family string) (string, error) {
+	tokenBytes := make([]byte, 32)
+	_, err := rand.Read(tokenBytes)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	refreshToken := base64.RawURLEncoding.EncodeToString(tokenBytes)
+
+	err = service.sessionStorage.SaveRefreshToken(hashRefreshToken(refreshToken), domain.RefreshToken{
+		Login:  login,
+		Family: family,
+	}, refreshTokenTTL)
+	if err != nil {
+		return "", err
+	}
+	return refreshToken, nil
+}
+
+func (service *SessionService) revokeRefreshTokenFamily(login, family string) error {
+	err := service.sessionStorage.RevokeRefreshTokenFamily(family)
+	if err != nil {
+		return err
+	}
+
+	err = service.sessionStorage.DeleteSession(login, family)
+	if err != nil && !errors.Is(err, myerrors.ErrNoSuchSessionInTheCache) &&
+		!errors.Is(err, myerrors.ErrNoSuchUserInTheCache) {
 		return err
 	}
 	return nil
 }
+
+// hashRefreshToken возвращает ключ для хранения токена, чтобы сами токены не лежали в кеше
+func hashRefreshToken(refreshToken string) string {
+	hash := sha256.Sum256([]byte(refreshToken))
+	return hex.EncodeToString(hash[:])
+}
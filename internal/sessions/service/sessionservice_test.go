@@ -7,6 +7,8 @@ import (
 
 	"go.uber.org/zap"
 
+	"github.com/SanExpett/diploma/internal/domain"
+	myerrors "github.com/SanExpett/diploma/internal/errors"
 	"github.com/SanExpett/diploma/internal/metrics"
 	mockService "github.com/SanExpett/diploma/internal/sessions/mock"
 	"github.com/golang/mock/gomock"
@@ -22,23 +24,28 @@ func TestAddSession(t *testing.T) {
 
 	metrics := metrics.NewGrpcMetrics("sessions")
 
-	service := NewSessionService(mockStorage, metrics, mockLogger)
+	service := NewSessionService(mockStorage, metrics, nil, mockLogger)
 
 	login := "testuser"
-	token := "token123"
-	version := uint32(1)
+	session := domain.Session{Id: "token123", Version: 1, UserAgent: "Mozilla/5.0", Ip: "127.0.0.1"}
 
-	mockStorage.EXPECT().Add(login, token, version).Return(nil)
+	mockStorage.EXPECT().Add(login, gomock.Any()).DoAndReturn(func(_ string, savedSession domain.Session) error {
+		assert.Equal(t, session.Id, savedSession.Id)
+		assert.Equal(t, session.UserAgent, savedSession.UserAgent)
+		assert.False(t, savedSession.CreatedAt.IsZero())
+		assert.Equal(t, savedSession.CreatedAt, savedSession.LastSeenAt)
+		return nil
+	})
 
-	err := service.Add(context.Background(), login, token, version)
+	err := service.Add(context.Background(), login, session)
 
 	if err != nil {
 		t.Errorf("AddSession returned an unexpected error: %v", err)
 	}
 
-	mockStorage.EXPECT().Add(login, token, version).Return(errors.New(""))
+	mockStorage.EXPECT().Add(login, gomock.Any()).Return(errors.New(""))
 
-	err = service.Add(context.Background(), login, token, version)
+	err = service.Add(context.Background(), login, session)
 
 	assert.Error(t, err)
 }
@@ -52,7 +59,7 @@ func TestDeleteSession(t *testing.T) {
 
 	metrics := metrics.NewGrpcMetrics("sessions")
 
-	service := NewSessionService(mockStorage, metrics, mockLogger)
+	service := NewSessionService(mockStorage, metrics, nil, mockLogger)
 
 	login := "testuser"
 	token := "token123"
@@ -81,7 +88,7 @@ func TestUpdateSession(t *testing.T) {
 
 	metrics := metrics.NewGrpcMetrics("sessions")
 
-	service := NewSessionService(mockStorage, metrics, mockLogger)
+	service := NewSessionService(mockStorage, metrics, nil, mockLogger)
 
 	login := "testuser"
 	token := "token123"
@@ -110,7 +117,7 @@ func TestCheckVersion(t *testing.T) {
 
 	metrics := metrics.NewGrpcMetrics("sessions")
 
-	service := NewSessionService(mockStorage, metrics, mockLogger)
+	service := NewSessionService(mockStorage, metrics, nil, mockLogger)
 
 	login := "testuser"
 	token := "token123"
@@ -144,7 +151,7 @@ func TestGetVersion(t *testing.T) {
 
 	metrics := metrics.NewGrpcMetrics("sessions")
 
-	service := NewSessionService(mockStorage, metrics, mockLogger)
+	service := NewSessionService(mockStorage, metrics, nil, mockLogger)
 
 	login := "testuser"
 	token := "token123"
@@ -178,12 +185,13 @@ func TestHasSession(t *testing.T) {
 
 	metrics := metrics.NewGrpcMetrics("sessions")
 
-	service := NewSessionService(mockStorage, metrics, mockLogger)
+	service := NewSessionService(mockStorage, metrics, nil, mockLogger)
 
 	login := "testuser"
 	token := "token123"
 
 	mockStorage.EXPECT().HasSession(login, token).Return(nil)
+	mockStorage.EXPECT().TouchSession(login, token, gomock.Any()).Return(nil)
 
 	err := service.HasSession(context.Background(), login, token)
 
@@ -191,6 +199,13 @@ func TestHasSession(t *testing.T) {
 		t.Errorf("HasSession returned an unexpected error: %v", err)
 	}
 
+	mockStorage.EXPECT().HasSession(login, token).Return(nil)
+	mockStorage.EXPECT().TouchSession(login, token, gomock.Any()).Return(errors.New(""))
+
+	err = service.HasSession(context.Background(), login, token)
+
+	assert.NoError(t, err, "ошибка обновления времени активности не влияет на проверку сессии")
+
 	mockStorage.EXPECT().HasSession(login, token).Return(errors.New(""))
 
 	err = service.HasSession(context.Background(), login, token)
@@ -198,7 +213,7 @@ func TestHasSession(t *testing.T) {
 	assert.Error(t, err)
 }
 
-func TestCheckAllUserSessionTokens(t *testing.T) {
+func TestListSessions(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
@@ -207,21 +222,218 @@ func TestCheckAllUserSessionTokens(t *testing.T) {
 
 	metrics := metrics.NewGrpcMetrics("sessions")
 
-	service := NewSessionService(mockStorage, metrics, mockLogger)
+	service := NewSessionService(mockStorage, metrics, nil, mockLogger)
 
 	login := "testuser"
+	sessions := []domain.Session{{Id: "token1", Version: 1}, {Id: "token2", Version: 2}}
 
-	mockStorage.EXPECT().CheckAllUserSessionTokens(login).Return(nil)
+	mockStorage.EXPECT().ListSessions(login).Return(sessions, nil)
 
-	err := service.CheckAllUserSessionTokens(context.Background(), login)
+	gotSessions, err := service.ListSessions(context.Background(), login)
 
-	if err != nil {
-		t.Errorf("CheckAllUserSessionTokens returned an unexpected error: %v", err)
+	assert.NoError(t, err)
+	assert.Equal(t, sessions, gotSessions)
+
+	mockStorage.EXPECT().ListSessions(login).Return(nil, errors.New(""))
+
+	_, err = service.ListSessions(context.Background(), login)
+
+	assert.Error(t, err)
+}
+
+func TestRevokeSession(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mockService.NewMocksessionStorage(ctrl)
+	mockLogger := zap.NewExample().Sugar()
+
+	metrics := metrics.NewGrpcMetrics("sessions")
+
+	service := NewSessionService(mockStorage, metrics, nil, mockLogger)
+
+	mockStorage.EXPECT().DeleteSession("testuser", "family").Return(nil)
+	mockStorage.EXPECT().RevokeRefreshTokenFamily("family").Return(nil)
+
+	err := service.RevokeSession(context.Background(), "testuser", "family")
+
+	assert.NoError(t, err)
+
+	mockStorage.EXPECT().DeleteSession("testuser", "family").Return(myerrors.ErrNoSuchSessionInTheCache)
+
+	err = service.RevokeSession(context.Background(), "testuser", "family")
+
+	assert.ErrorIs(t, err, myerrors.ErrNoSuchSessionInTheCache)
+}
+
+func TestRevokeOtherSessions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mockService.NewMocksessionStorage(ctrl)
+	mockLogger := zap.NewExample().Sugar()
+
+	metrics := metrics.NewGrpcMetrics("sessions")
+
+	service := NewSessionService(mockStorage, metrics, nil, mockLogger)
+
+	mockStorage.EXPECT().ListSessions("testuser").Return([]domain.Session{
+		{Id: "current"}, {Id: "other1"}, {Id: "other2"},
+	}, nil)
+	for _, family := range []string{"other1", "other2"} {
+		mockStorage.EXPECT().RevokeRefreshTokenFamily(family).Return(nil)
+		mockStorage.EXPECT().DeleteSession("testuser", family).Return(nil)
 	}
 
-	mockStorage.EXPECT().CheckAllUserSessionTokens(login).Return(errors.New(""))
+	revoked, err := service.RevokeOtherSessions(context.Background(), "testuser", "current")
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(2), revoked)
+
+	mockStorage.EXPECT().ListSessions("testuser").Return(nil, myerrors.ErrNoSuchUserInTheCache)
+
+	revoked, err = service.RevokeOtherSessions(context.Background(), "testuser", "current")
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(0), revoked)
+}
+
+func TestIssueRefreshToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mockService.NewMocksessionStorage(ctrl)
+	mockLogger := zap.NewExample().Sugar()
+
+	metrics := metrics.NewGrpcMetrics("sessions")
+
+	service := NewSessionService(mockStorage, metrics, nil, mockLogger)
+
+	login := "testuser"
+
+	var savedHash string
+	var savedToken domain.RefreshToken
+	mockStorage.EXPECT().SaveRefreshToken(gomock.Any(), gomock.Any(), refreshTokenTTL).DoAndReturn(
+		func(tokenHash string, refreshToken domain.RefreshToken, _ any) error {
+			savedHash = tokenHash
+			savedToken = refreshToken
+			return nil
+		})
+
+	family, refreshToken, err := service.IssueRefreshToken(context.Background(), login)
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, family)
+	assert.Equal(t, hashRefreshToken(refreshToken), savedHash)
+	assert.Equal(t, domain.RefreshToken{Login: login, Family: family}, savedToken)
+}
+
+func TestRotateRefreshToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mockService.NewMocksessionStorage(ctrl)
+	mockLogger := zap.NewExample().Sugar()
+
+	metrics := metrics.NewGrpcMetrics("sessions")
+
+	service := NewSessionService(mockStorage, metrics, nil, mockLogger)
+
+	storedToken := domain.RefreshToken{Login: "testuser", Family: "family"}
+
+	mockStorage.EXPECT().ConsumeRefreshToken(hashRefreshToken("refresh-token")).Return(storedToken, nil)
+	mockStorage.EXPECT().SaveRefreshToken(gomock.Any(), storedToken, refreshTokenTTL).Return(nil)
+	mockStorage.EXPECT().TouchSession("testuser", "family", gomock.Any()).Return(nil)
+
+	login, family, newRefreshToken, err := service.RotateRefreshToken(context.Background(), "refresh-token")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "testuser", login)
+	assert.Equal(t, "family", family)
+	assert.NotEqual(t, "refresh-token", newRefreshToken)
+
+	mockStorage.EXPECT().ConsumeRefreshToken(hashRefreshToken("refresh-token")).
+		Return(domain.RefreshToken{}, myerrors.ErrNoSuchRefreshToken)
+
+	_, _, _, err = service.RotateRefreshToken(context.Background(), "refresh-token")
+
+	assert.ErrorIs(t, err, myerrors.ErrNoSuchRefreshToken)
+}
+
+func TestGetRefreshToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mockService.NewMocksessionStorage(ctrl)
+	mockLogger := zap.NewExample().Sugar()
+
+	metrics := metrics.NewGrpcMetrics("sessions")
+
+	service := NewSessionService(mockStorage, metrics, nil, mockLogger)
+
+	mockStorage.EXPECT().GetRefreshToken(hashRefreshToken("refresh-token")).
+		Return(domain.RefreshToken{Login: "testuser", Family: "family"}, nil)
+
+	login, family, err := service.GetRefreshToken(context.Background(), "refresh-token")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "testuser", login)
+	assert.Equal(t, "family", family)
+
+	mockStorage.EXPECT().GetRefreshToken(hashRefreshToken("refresh-token")).
+		Return(domain.RefreshToken{Login: "testuser", Family: "family", Used: true}, myerrors.ErrRefreshTokenReused)
+	mockStorage.EXPECT().RevokeRefreshTokenFamily("family").Return(nil)
+	mockStorage.EXPECT().DeleteSession("testuser", "family").Return(nil)
+
+	_, _, err = service.GetRefreshToken(context.Background(), "refresh-token")
+
+	assert.ErrorIs(t, err, myerrors.ErrRefreshTokenReused, "обмененный токен отзывает семейство до обмена")
+}
+
+func TestRotateRefreshTokenReuse(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mockService.NewMocksessionStorage(ctrl)
+	mockLogger := zap.NewExample().Sugar()
+
+	metrics := metrics.NewGrpcMetrics("sessions")
+
+	service := NewSessionService(mockStorage, metrics, nil, mockLogger)
+
+	storedToken := domain.RefreshToken{Login: "testuser", Family: "family", Used: true}
+
+	mockStorage.EXPECT().ConsumeRefreshToken(hashRefreshToken("refresh-token")).
+		Return(storedToken, myerrors.ErrRefreshTokenReused)
+	mockStorage.EXPECT().RevokeRefreshTokenFamily("family").Return(nil)
+	mockStorage.EXPECT().DeleteSession("testuser", "family").Return(nil)
+
+	_, _, _, err := service.RotateRefreshToken(context.Background(), "refresh-token")
+
+	assert.ErrorIs(t, err, myerrors.ErrRefreshTokenReused)
+}
+
+func TestRevokeRefreshTokenFamily(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mockService.NewMocksessionStorage(ctrl)
+	mockLogger := zap.NewExample().Sugar()
+
+	metrics := metrics.NewGrpcMetrics("sessions")
+
+	service := NewSessionService(mockStorage, metrics, nil, mockLogger)
+
+	mockStorage.EXPECT().RevokeRefreshTokenFamily("family").Return(nil)
+	mockStorage.EXPECT().DeleteSession("testuser", "family").Return(myerrors.ErrNoSuchSessionInTheCache)
+
+	err := service.RevokeRefreshTokenFamily(context.Background(), "testuser", "family")
+
+	assert.NoError(t, err)
+
+	mockStorage.EXPECT().RevokeRefreshTokenFamily("family").Return(errors.New(""))
 
-	err = service.CheckAllUserSessionTokens(context.Background(), login)
+	err = service.RevokeRefreshTokenFamily(context.Background(), "testuser", "family")
 
 	assert.Error(t, err)
 }
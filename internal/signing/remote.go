@@ -0,0 +1,121 @@
+package signing
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+
+	myerrors "github.com/SanExpett/diploma/internal/errors"
+)
+
+type remoteKey struct {
+	algorithm string
+	publicKey crypto.PublicKey
+}
+
+// RemoteKeySet проверяет токены gateway по его JWKS. Ключи запрашиваются лениво и перезапрашиваются,
+// когда токен подписан ключом с неизвестным kid, например после ротации
+type RemoteKeySet struct {
+	url    string
+	client *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]remoteKey
+	fetchedAt time.Time
+}
+
+func NewRemoteKeySet(url string, client *http.Client) *RemoteKeySet {
+	return &RemoteKeySet{
+		url:    url,
+		client: client,
+	}
+}
+
+// Keyfunc находит открытый ключ по kid для jwt.Parse. Токены без kid не принимаются
+func (keySet *RemoteKeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, fmt.Errorf("token without key id: %w", myerrors.ErrTokenIsNotValid)
+	}
+
+	key, err := keySet.key(kid)
+	if err != nil {
+		return nil, err
+	}
+	if token.Method.Alg() != key.algorithm {
+		return nil, fmt.Errorf("unexpected signing method %s for key %s: %w", token.Method.Alg(), kid,
+			myerrors.ErrTokenIsNotValid)
+	}
+	return key.publicKey, nil
+}
+
+func (keySet *RemoteKeySet) key(kid string) (remoteKey, error) {
+	keySet.mu.Lock()
+	defer keySet.mu.Unlock()
+
+	if key, ok := keySet.keys[kid]; ok {
+		return key, nil
+	}
+	if time.Since(keySet.fetchedAt) < keyReloadInterval {
+		return remoteKey{}, fmt.Errorf("%w %s: %w", errUnknownKey, kid, myerrors.ErrTokenIsNotValid)
+	}
+	keySet.fetchedAt = time.Now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), keyReloadTimeout)
+	defer cancel()
+	keys, err := keySet.fetch(ctx)
+	if err != nil {
+		return remoteKey{}, fmt.Errorf("failed to fetch jwks: %w", err)
+	}
+	keySet.keys = keys
+
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	return remoteKey{}, fmt.Errorf("%w %s: %w", errUnknownKey, kid, myerrors.ErrTokenIsNotValid)
+}
+
+func (keySet *RemoteKeySet) fetch(ctx context.Context) (map[string]remoteKey, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, keySet.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Accept", "application/json")
+
+	response, err := keySet.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %d", keySet.url, response.StatusCode)
+	}
+
+	var jwks JWKS
+	err = json.NewDecoder(io.LimitReader(response.Body, 1<<20)).Decode(&jwks)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]remoteKey, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "sig" || signingMethod(jwk.Alg) == nil {
+			continue
+		}
+		publicKey, err := jwk.PublicKey()
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = remoteKey{algorithm: jwk.Alg, publicKey: publicKey}
+	}
+
+	return keys, nil
+}
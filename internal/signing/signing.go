@@ -0,0 +1,479 @@
+package signing
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+
+	myerrors "github.com/SanExpett/diploma/internal/errors"
+)
+
+const (
+	AlgorithmEdDSA = "EdDSA"
+	AlgorithmRS256 = "RS256"
+
+	rsaKeyBits = 2048
+
+	// keySyncInterval как часто реплики перечитывают общий набор ключей
+	keySyncInterval = time.Minute
+	// keyReloadInterval не дает перечитывать хранилище на каждый токен с неизвестным kid
+	keyReloadInterval = 10 * time.Second
+	keyReloadTimeout  = 2 * time.Second
+)
+
+// Key ключ подписи токенов. Ключ подписывает новые токены, пока не выведен из оборота,
+// после этого еще gracePeriod используется только для проверки
+type Key struct {
+	Id        string
+	Algorithm string
+	CreatedAt time.Time
+	RetiredAt time.Time
+	signKey   crypto.Signer
+	verifyKey crypto.PublicKey
+}
+
+// KeyManager хранит текущий ключ подписи и ключи, выведенные из оборота не раньше, чем gracePeriod назад.
+// Текущий ключ всегда первый в keys. Без store ключи живут только в памяти процесса
+type KeyManager struct {
+	mu             sync.RWMutex
+	algorithm      string
+	rotationPeriod time.Duration
+	gracePeriod    time.Duration
+	current        *Key
+	keys           []*Key
+	legacySecret   []byte
+	legacyUntil    time.Time
+	store          KeyStore
+	reloadedAt     time.Time
+	now            func() time.Time
+}
+
+// NewKeyManager создает KeyManager с ключами в памяти процесса. После перезапуска выданные им токены
+// перестают проходить проверку, поэтому он подходит только для одной реплики и тестов
+func NewKeyManager(algorithm string, rotationPeriod, gracePeriod time.Duration) (*KeyManager, error) {
+	manager, err := newKeyManager(algorithm, rotationPeriod, gracePeriod)
+	if err != nil {
+		return nil, err
+	}
+
+	err = manager.Rotate()
+	if err != nil {
+		return nil, err
+	}
+
+	return manager, nil
+}
+
+// NewSharedKeyManager создает KeyManager, набор ключей которого хранится в store. Набор переживает
+// перезапуски и общий для всех реплик gateway: токены, подписанные одной репликой, принимают остальные
+func NewSharedKeyManager(ctx context.Context, algorithm string, rotationPeriod, gracePeriod time.Duration,
+	store KeyStore) (*KeyManager, error) {
+	manager, err := newKeyManager(algorithm, rotationPeriod, gracePeriod)
+	if err != nil {
+		return nil, err
+	}
+	manager.store = store
+
+	err = manager.sync(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return manager, nil
+}
+
+func newKeyManager(algorithm string, rotationPeriod, gracePeriod time.Duration) (*KeyManager, error) {
+	if signingMethod(algorithm) == nil {
+		return nil, fmt.Errorf("unsupported signing algorithm %q", algorithm)
+	}
+
+	return &KeyManager{
+		algorithm:      algorithm,
+		rotationPeriod: rotationPeriod,
+		gracePeriod:    gracePeriod,
+		now:            time.Now,
+	}, nil
+}
+
+// AcceptLegacySecret разрешает в течение gracePeriod проверять HS256 токены без kid,
+// подписанные общим секретом до перехода на ключи с идентификаторами
+func (manager *KeyManager) AcceptLegacySecret(secret string) {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	manager.legacySecret = []byte(secret)
+	manager.legacyUntil = manager.now().Add(manager.gracePeriod)
+}
+
+// Rotate выпускает новый ключ подписи, выводит текущий из оборота и удаляет ключи,
+// у которых закончился gracePeriod
+func (manager *KeyManager) Rotate() error {
+	if manager.store != nil {
+		return manager.sync(context.Background(), true)
+	}
+
+	manager.mu.RLock()
+	keys := manager.keys
+	manager.mu.RUnlock()
+
+	now := manager.now()
+	keys, err := manager.rotateKeys(keys, now)
+	if err != nil {
+		return err
+	}
+
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+	manager.setKeys(keys, now)
+
+	return nil
+}
+
+// StartRotation меняет ключ подписи раз в rotationPeriod, пока не отменен ctx. С общим хранилищем
+// набор перечитывается чаще, чтобы реплика узнала о ключе, выпущенном другой репликой
+func (manager *KeyManager) StartRotation(ctx context.Context, onError func(err error)) {
+	interval := manager.rotationPeriod
+	if manager.store != nil && keySyncInterval < interval {
+		interval = keySyncInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				var err error
+				if manager.store != nil {
+					err = manager.sync(ctx, false)
+				} else {
+					err = manager.Rotate()
+				}
+				if err != nil {
+					onError(err)
+				}
+			}
+		}
+	}()
+}
+
+// sync перечитывает набор из хранилища и ротирует его, если текущему ключу больше rotationPeriod или force.
+// Хранилище меняет набор атомарно, поэтому при одновременной проверке новый ключ выпускает одна реплика
+func (manager *KeyManager) sync(ctx context.Context, force bool) error {
+	now := manager.now()
+
+	var keys []*Key
+	err := manager.store.Update(ctx, func(data []byte) ([]byte, error) {
+		stored, err := decodeKeys(data)
+		if err != nil {
+			return nil, err
+		}
+		keys = stored
+		if !force && len(stored) > 0 && stored[0].Algorithm == manager.algorithm &&
+			now.Sub(stored[0].CreatedAt) < manager.rotationPeriod {
+			return nil, nil
+		}
+
+		keys, err = manager.rotateKeys(stored, now)
+		if err != nil {
+			return nil, err
+		}
+		return encodeKeys(keys)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to sync signing keys: %w", err)
+	}
+
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+	manager.setKeys(keys, now)
+	manager.reloadedAt = now
+
+	return nil
+}
+
+// reload перечитывает набор из хранилища, если токен подписан ключом, которого еще нет в памяти
+func (manager *KeyManager) reload() bool {
+	if manager.store == nil {
+		return false
+	}
+
+	now := manager.now()
+	manager.mu.Lock()
+	if now.Sub(manager.reloadedAt) < keyReloadInterval {
+		manager.mu.Unlock()
+		return false
+	}
+	manager.reloadedAt = now
+	manager.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), keyReloadTimeout)
+	defer cancel()
+	data, err := manager.store.Load(ctx)
+	if err != nil {
+		return false
+	}
+	keys, err := decodeKeys(data)
+	if err != nil || len(keys) == 0 {
+		return false
+	}
+
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+	manager.setKeys(keys, now)
+
+	return true
+}
+
+// rotateKeys выпускает новый ключ и выводит из оборота первый ключ keys. Ключи keys не изменяются
+func (manager *KeyManager) rotateKeys(keys []*Key, now time.Time) ([]*Key, error) {
+	key, err := generateKey(manager.algorithm, now)
+	if err != nil {
+		return nil, err
+	}
+
+	rotated := []*Key{key}
+	for i, oldKey := range keys {
+		if i == 0 && oldKey.RetiredAt.IsZero() {
+			retiredKey := *oldKey
+			retiredKey.RetiredAt = now
+			oldKey = &retiredKey
+		}
+		if now.Sub(oldKey.RetiredAt) < manager.gracePeriod {
+			rotated = append(rotated, oldKey)
+		}
+	}
+
+	return rotated, nil
+}
+
+// setKeys делает первый ключ текущим и отбрасывает ключи, у которых закончился gracePeriod.
+// Вызывается под mu
+func (manager *KeyManager) setKeys(keys []*Key, now time.Time) {
+	active := make([]*Key, 0, len(keys))
+	for i, key := range keys {
+		if i == 0 || now.Sub(key.RetiredAt) < manager.gracePeriod {
+			active = append(active, key)
+		}
+	}
+
+	manager.current = active[0]
+	manager.keys = active
+}
+
+// Sign подписывает claims текущим ключом и указывает его kid в заголовке токена
+func (manager *KeyManager) Sign(claims jwt.Claims) (string, error) {
+	manager.mu.RLock()
+	key := manager.current
+	manager.mu.RUnlock()
+
+	token := jwt.NewWithClaims(signingMethod(key.Algorithm), claims)
+	token.Header["kid"] = key.Id
+
+	tokenSigned, err := token.SignedString(key.signKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+
+	return tokenSigned, nil
+}
+
+// Keyfunc находит ключ проверки по kid из заголовка токена для jwt.Parse. Неизвестный kid
+// с общим хранилищем означает, что ключ выпустила другая реплика, и набор перечитывается
+func (manager *KeyManager) Keyfunc(token *jwt.Token) (interface{}, error) {
+	key, err := manager.verifyKey(token)
+	if errors.Is(err, errUnknownKey) && manager.reload() {
+		return manager.verifyKey(token)
+	}
+	return key, err
+}
+
+var errUnknownKey = errors.New("unknown key id")
+
+func (manager *KeyManager) verifyKey(token *jwt.Token) (interface{}, error) {
+	manager.mu.RLock()
+	defer manager.mu.RUnlock()
+
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		_, isHMAC := token.Method.(*jwt.SigningMethodHMAC)
+		if !isHMAC || manager.legacySecret == nil || !manager.now().Before(manager.legacyUntil) {
+			return nil, fmt.Errorf("token without key id: %w", myerrors.ErrTokenIsNotValid)
+		}
+		return manager.legacySecret, nil
+	}
+
+	for _, key := range manager.keys {
+		if key.Id != kid {
+			continue
+		}
+		if token.Method.Alg() != key.Algorithm {
+			return nil, fmt.Errorf("unexpected signing method %s for key %s: %w", token.Method.Alg(), kid,
+				myerrors.ErrTokenIsNotValid)
+		}
+		return key.verifyKey, nil
+	}
+
+	return nil, fmt.Errorf("%w %s: %w", errUnknownKey, kid, myerrors.ErrTokenIsNotValid)
+}
+
+// JWKS возвращает открытые ключи, которыми можно проверить действующие токены
+func (manager *KeyManager) JWKS() JWKS {
+	manager.mu.RLock()
+	defer manager.mu.RUnlock()
+
+	jwks := JWKS{Keys: make([]JWK, 0, len(manager.keys))}
+	for _, key := range manager.keys {
+		jwks.Keys = append(jwks.Keys, key.jwk())
+	}
+
+	return jwks
+}
+
+func signingMethod(algorithm string) jwt.SigningMethod {
+	switch algorithm {
+	case AlgorithmEdDSA:
+		return jwt.SigningMethodEdDSA
+	case AlgorithmRS256:
+		return jwt.SigningMethodRS256
+	default:
+		return nil
+	}
+}
+
+func generateKey(algorithm string, now time.Time) (*Key, error) {
+	var (
+		signKey   crypto.Signer
+		verifyKey crypto.PublicKey
+	)
+
+	switch algorithm {
+	case AlgorithmEdDSA:
+		publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate ed25519 key: %w", err)
+		}
+		signKey, verifyKey = privateKey, publicKey
+	case AlgorithmRS256:
+		privateKey, err := rsa.GenerateKey(rand.Reader, rsaKeyBits)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate rsa key: %w", err)
+		}
+		signKey, verifyKey = privateKey, &privateKey.PublicKey
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", algorithm)
+	}
+
+	publicKeyDER, err := x509.MarshalPKIXPublicKey(verifyKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal public key: %w", err)
+	}
+	thumbprint := sha256.Sum256(publicKeyDER)
+
+	return &Key{
+		Id:        base64.RawURLEncoding.EncodeToString(thumbprint[:12]),
+		Algorithm: algorithm,
+		CreatedAt: now,
+		signKey:   signKey,
+		verifyKey: verifyKey,
+	}, nil
+}
+
+// JWK открытый ключ в формате RFC 7517
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+func (key *Key) jwk() JWK {
+	jwk := JWK{
+		Kid: key.Id,
+		Alg: key.Algorithm,
+		Use: "sig",
+	}
+
+	switch publicKey := key.verifyKey.(type) {
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+	}
+
+	return jwk
+}
+
+// PublicKey восстанавливает открытый ключ из JWK, чтобы проверять токены, подписанные чужими ключами,
+// например id_token провайдеров входа
+func (jwk JWK) PublicKey() (crypto.PublicKey, error) {
+	switch jwk.Kty {
+	case "OKP":
+		if jwk.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported OKP curve %q", jwk.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid ed25519 key %s", jwk.Kid)
+		}
+		return ed25519.PublicKey(x), nil
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rsa modulus in key %s: %w", jwk.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rsa exponent in key %s: %w", jwk.Kid, err)
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		if jwk.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported EC curve %q", jwk.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ec key %s: %w", jwk.Kid, err)
+		}
+		y, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ec key %s: %w", jwk.Kid, err)
+		}
+		publicKey := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !publicKey.Curve.IsOnCurve(publicKey.X, publicKey.Y) {
+			return nil, fmt.Errorf("ec key %s is not on curve", jwk.Kid)
+		}
+		return publicKey, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", jwk.Kty)
+	}
+}
@@ -0,0 +1,254 @@
+package signing
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func parse(manager *KeyManager, tokenSigned string) (*jwt.Token, error) {
+	return jwt.Parse(tokenSigned, manager.Keyfunc)
+}
+
+func TestKeyManager_SignAndVerify(t *testing.T) {
+	for _, algorithm := range []string{AlgorithmEdDSA, AlgorithmRS256} {
+		t.Run(algorithm, func(t *testing.T) {
+			manager, err := NewKeyManager(algorithm, time.Hour, time.Hour)
+			require.NoError(t, err)
+
+			tokenSigned, err := manager.Sign(jwt.MapClaims{"Login": "test@test.com"})
+			require.NoError(t, err)
+
+			token, err := parse(manager, tokenSigned)
+			require.NoError(t, err)
+			assert.Equal(t, algorithm, token.Method.Alg())
+			assert.Equal(t, manager.current.Id, token.Header["kid"])
+		})
+	}
+}
+
+func TestKeyManager_UnsupportedAlgorithm(t *testing.T) {
+	_, err := NewKeyManager("HS256", time.Hour, time.Hour)
+	assert.Error(t, err)
+}
+
+func TestKeyManager_Rotate(t *testing.T) {
+	now := time.Now()
+	manager, err := NewKeyManager(AlgorithmEdDSA, time.Hour, 15*time.Minute)
+	require.NoError(t, err)
+	manager.now = func() time.Time { return now }
+
+	oldToken, err := manager.Sign(jwt.MapClaims{"Login": "test@test.com"})
+	require.NoError(t, err)
+
+	require.NoError(t, manager.Rotate())
+
+	newToken, err := manager.Sign(jwt.MapClaims{"Login": "test@test.com"})
+	require.NoError(t, err)
+
+	_, err = parse(manager, oldToken)
+	assert.NoError(t, err, "токен старого ключа принимается в течение grace периода")
+	_, err = parse(manager, newToken)
+	assert.NoError(t, err)
+	assert.Len(t, manager.JWKS().Keys, 2)
+
+	now = now.Add(20 * time.Minute)
+	require.NoError(t, manager.Rotate())
+
+	_, err = parse(manager, oldToken)
+	assert.Error(t, err, "ключ удаляется после grace периода")
+	_, err = parse(manager, newToken)
+	assert.NoError(t, err)
+	assert.Len(t, manager.JWKS().Keys, 2)
+}
+
+func TestKeyManager_LegacySecret(t *testing.T) {
+	now := time.Now()
+	manager, err := NewKeyManager(AlgorithmEdDSA, time.Hour, 15*time.Minute)
+	require.NoError(t, err)
+	manager.now = func() time.Time { return now }
+
+	legacyToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"Login": "test@test.com"}).
+		SignedString([]byte("secret"))
+	require.NoError(t, err)
+
+	_, err = parse(manager, legacyToken)
+	assert.Error(t, err, "без общего секрета HS256 токены не принимаются")
+
+	manager.AcceptLegacySecret("secret")
+	_, err = parse(manager, legacyToken)
+	assert.NoError(t, err)
+
+	now = now.Add(20 * time.Minute)
+	_, err = parse(manager, legacyToken)
+	assert.Error(t, err)
+}
+
+func TestKeyManager_RejectsForeignKeyAlgorithm(t *testing.T) {
+	manager, err := NewKeyManager(AlgorithmEdDSA, time.Hour, time.Hour)
+	require.NoError(t, err)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"Login": "test@test.com"})
+	token.Header["kid"] = manager.current.Id
+	tokenSigned, err := token.SignedString([]byte("secret"))
+	require.NoError(t, err)
+
+	_, err = parse(manager, tokenSigned)
+	assert.Error(t, err)
+}
+
+func TestKeyManager_JWKS(t *testing.T) {
+	manager, err := NewKeyManager(AlgorithmEdDSA, time.Hour, time.Hour)
+	require.NoError(t, err)
+
+	jwks := manager.JWKS()
+	require.Len(t, jwks.Keys, 1)
+	assert.Equal(t, JWK{
+		Kty: "OKP",
+		Kid: manager.current.Id,
+		Alg: AlgorithmEdDSA,
+		Use: "sig",
+		Crv: "Ed25519",
+		X:   jwks.Keys[0].X,
+	}, jwks.Keys[0])
+	assert.NotEmpty(t, jwks.Keys[0].X)
+
+	manager, err = NewKeyManager(AlgorithmRS256, time.Hour, time.Hour)
+	require.NoError(t, err)
+
+	jwks = manager.JWKS()
+	require.Len(t, jwks.Keys, 1)
+	assert.Equal(t, "RSA", jwks.Keys[0].Kty)
+	assert.Equal(t, "AQAB", jwks.Keys[0].E)
+	assert.NotEmpty(t, jwks.Keys[0].N)
+}
+
+func TestJWK_PublicKey(t *testing.T) {
+	for _, algorithm := range []string{AlgorithmEdDSA, AlgorithmRS256} {
+		t.Run(algorithm, func(t *testing.T) {
+			manager, err := NewKeyManager(algorithm, time.Hour, time.Hour)
+			require.NoError(t, err)
+
+			tokenSigned, err := manager.Sign(jwt.RegisteredClaims{Subject: "login"})
+			require.NoError(t, err)
+
+			publicKey, err := manager.JWKS().Keys[0].PublicKey()
+			require.NoError(t, err)
+
+			token, err := jwt.Parse(tokenSigned, func(*jwt.Token) (interface{}, error) { return publicKey, nil })
+			require.NoError(t, err)
+			assert.True(t, token.Valid)
+		})
+	}
+
+	_, err := JWK{Kty: "oct"}.PublicKey()
+	assert.Error(t, err)
+}
+
+func TestSharedKeyManager(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	stores := []struct {
+		name  string
+		store KeyStore
+	}{
+		{name: "Redis", store: NewRedisKeyStore(client, "signing:keys")},
+		{name: "Файл", store: NewFileKeyStore(filepath.Join(t.TempDir(), "keys.json"))},
+	}
+
+	for _, tt := range stores {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			first, err := NewSharedKeyManager(ctx, AlgorithmEdDSA, time.Hour, 15*time.Minute, tt.store)
+			require.NoError(t, err)
+			tokenSigned, err := first.Sign(jwt.MapClaims{"Login": "test@test.com"})
+			require.NoError(t, err)
+
+			// перезапуск или другая реплика получают тот же набор ключей
+			second, err := NewSharedKeyManager(ctx, AlgorithmEdDSA, time.Hour, 15*time.Minute, tt.store)
+			require.NoError(t, err)
+			assert.Equal(t, first.current.Id, second.current.Id)
+			_, err = parse(second, tokenSigned)
+			assert.NoError(t, err)
+
+			// ключ, выпущенный одной репликой, другая находит в хранилище
+			require.NoError(t, first.Rotate())
+			second.now = func() time.Time { return time.Now().Add(keyReloadInterval) }
+			newToken, err := first.Sign(jwt.MapClaims{"Login": "test@test.com"})
+			require.NoError(t, err)
+			_, err = parse(second, newToken)
+			assert.NoError(t, err)
+			_, err = parse(second, tokenSigned)
+			assert.NoError(t, err, "токен старого ключа принимается в течение grace периода")
+			assert.Len(t, second.JWKS().Keys, 2)
+		})
+	}
+}
+
+func TestSharedKeyManager_RotatesExpiredKey(t *testing.T) {
+	now := time.Now()
+	store := NewFileKeyStore(filepath.Join(t.TempDir(), "keys.json"))
+	manager, err := NewSharedKeyManager(context.Background(), AlgorithmEdDSA, time.Hour, 15*time.Minute, store)
+	require.NoError(t, err)
+	manager.now = func() time.Time { return now }
+	oldKey := manager.current.Id
+
+	require.NoError(t, manager.sync(context.Background(), false))
+	assert.Equal(t, oldKey, manager.current.Id, "ключ моложе rotationPeriod не меняется")
+
+	now = now.Add(2 * time.Hour)
+	require.NoError(t, manager.sync(context.Background(), false))
+	assert.NotEqual(t, oldKey, manager.current.Id)
+}
+
+func TestRemoteKeySet(t *testing.T) {
+	manager, err := NewKeyManager(AlgorithmEdDSA, time.Hour, time.Hour)
+	require.NoError(t, err)
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_ = json.NewEncoder(w).Encode(manager.JWKS())
+	}))
+	defer server.Close()
+	keySet := NewRemoteKeySet(server.URL, server.Client())
+
+	tokenSigned, err := manager.Sign(jwt.MapClaims{"Login": "test@test.com"})
+	require.NoError(t, err)
+	_, err = jwt.Parse(tokenSigned, keySet.Keyfunc)
+	require.NoError(t, err)
+	_, err = jwt.Parse(tokenSigned, keySet.Keyfunc)
+	require.NoError(t, err)
+	assert.Equal(t, 1, requests)
+
+	// ключ с неизвестным kid запрашивается заново, но не чаще keyReloadInterval
+	require.NoError(t, manager.Rotate())
+	tokenSigned, err = manager.Sign(jwt.MapClaims{"Login": "test@test.com"})
+	require.NoError(t, err)
+	_, err = jwt.Parse(tokenSigned, keySet.Keyfunc)
+	assert.Error(t, err)
+	assert.Equal(t, 1, requests)
+
+	keySet.fetchedAt = time.Now().Add(-keyReloadInterval)
+	_, err = jwt.Parse(tokenSigned, keySet.Keyfunc)
+	require.NoError(t, err)
+	assert.Equal(t, 2, requests)
+
+	foreign, err := NewKeyManager(AlgorithmEdDSA, time.Hour, time.Hour)
+	require.NoError(t, err)
+	tokenSigned, err = foreign.Sign(jwt.MapClaims{"Login": "test@test.com"})
+	require.NoError(t, err)
+	_, err = jwt.Parse(tokenSigned, keySet.Keyfunc)
+	assert.Error(t, err)
+}
@@ -0,0 +1,194 @@
+package signing
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// storeUpdateAttempts сколько раз Update повторяется, если набор одновременно изменила другая реплика
+const storeUpdateAttempts = 5
+
+// KeyStore общее хранилище набора ключей подписи
+type KeyStore interface {
+	// Load возвращает сохраненный набор или nil, если набор еще не создан
+	Load(ctx context.Context) ([]byte, error)
+	// Update атомарно заменяет набор результатом update. update получает текущий набор или nil
+	// и возвращает nil, если набор менять не нужно. Может вызывать update несколько раз
+	Update(ctx context.Context, update func(data []byte) ([]byte, error)) error
+}
+
+// storedKey ключ в хранилище. Закрытый ключ хранится в PKCS #8, поэтому доступ к хранилищу
+// должен быть только у gateway
+type storedKey struct {
+	Id         string    `json:"kid"`
+	Algorithm  string    `json:"alg"`
+	CreatedAt  time.Time `json:"created_at"`
+	RetiredAt  time.Time `json:"retired_at"`
+	PrivateKey []byte    `json:"private_key"`
+}
+
+func encodeKeys(keys []*Key) ([]byte, error) {
+	stored := make([]storedKey, 0, len(keys))
+	for _, key := range keys {
+		privateKey, err := x509.MarshalPKCS8PrivateKey(key.signKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal key %s: %w", key.Id, err)
+		}
+		stored = append(stored, storedKey{
+			Id:         key.Id,
+			Algorithm:  key.Algorithm,
+			CreatedAt:  key.CreatedAt,
+			RetiredAt:  key.RetiredAt,
+			PrivateKey: privateKey,
+		})
+	}
+
+	return json.Marshal(stored)
+}
+
+func decodeKeys(data []byte) ([]*Key, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	var stored []storedKey
+	err := json.Unmarshal(data, &stored)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse key set: %w", err)
+	}
+
+	keys := make([]*Key, 0, len(stored))
+	for _, storedKey := range stored {
+		if signingMethod(storedKey.Algorithm) == nil {
+			return nil, fmt.Errorf("unsupported algorithm %q of key %s", storedKey.Algorithm, storedKey.Id)
+		}
+		privateKey, err := x509.ParsePKCS8PrivateKey(storedKey.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse key %s: %w", storedKey.Id, err)
+		}
+		signKey, ok := privateKey.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("key %s can not sign", storedKey.Id)
+		}
+		keys = append(keys, &Key{
+			Id:        storedKey.Id,
+			Algorithm: storedKey.Algorithm,
+			CreatedAt: storedKey.CreatedAt,
+			RetiredAt: storedKey.RetiredAt,
+			signKey:   signKey,
+			verifyKey: signKey.Public(),
+		})
+	}
+
+	return keys, nil
+}
+
+// RedisKeyStore хранит набор ключей в Redis, общем для реплик gateway
+type RedisKeyStore struct {
+	client redis.UniversalClient
+	key    string
+}
+
+func NewRedisKeyStore(client redis.UniversalClient, key string) *RedisKeyStore {
+	return &RedisKeyStore{
+		client: client,
+		key:    key,
+	}
+}
+
+func (store *RedisKeyStore) Load(ctx context.Context) ([]byte, error) {
+	data, err := store.client.Get(ctx, store.key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// Update меняет набор в транзакции с WATCH: если набор изменился после чтения, транзакция
+// не выполняется и update вызывается заново
+func (store *RedisKeyStore) Update(ctx context.Context, update func(data []byte) ([]byte, error)) error {
+	for attempt := 0; attempt < storeUpdateAttempts; attempt++ {
+		err := store.client.Watch(ctx, func(tx *redis.Tx) error {
+			data, err := tx.Get(ctx, store.key).Bytes()
+			if err != nil && !errors.Is(err, redis.Nil) {
+				return err
+			}
+			updated, err := update(data)
+			if err != nil || updated == nil {
+				return err
+			}
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.Set(ctx, store.key, updated, 0)
+				return nil
+			})
+			return err
+		}, store.key)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+	}
+
+	return fmt.Errorf("key set %s is changed concurrently", store.key)
+}
+
+// FileKeyStore хранит набор ключей в файле, например на примонтированном томе. Изменения согласованы
+// только внутри процесса, поэтому файл не должен разделяться репликами на разных машинах
+type FileKeyStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileKeyStore(path string) *FileKeyStore {
+	return &FileKeyStore{
+		path: path,
+	}
+}
+
+func (store *FileKeyStore) Load(ctx context.Context) ([]byte, error) {
+	data, err := os.ReadFile(store.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// Update записывает набор во временный файл и переименовывает его, чтобы файл не оказался записан частично
+func (store *FileKeyStore) Update(ctx context.Context, update func(data []byte) ([]byte, error)) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	data, err := store.Load(ctx)
+	if err != nil {
+		return err
+	}
+	updated, err := update(data)
+	if err != nil || updated == nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(store.path), filepath.Base(store.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	_, err = file.Write(updated)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), store.path)
+}
@@ -0,0 +1,89 @@
+package totp
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rfcSecret секрет из тестовых векторов RFC 6238 для SHA1
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		time     int64
+		expected string
+	}{
+		{name: "59 секунд", time: 59, expected: "287082"},
+		{name: "1111111109", time: 1111111109, expected: "081804"},
+		{name: "1111111111", time: 1111111111, expected: "050471"},
+		{name: "1234567890", time: 1234567890, expected: "005924"},
+		{name: "2000000000", time: 2000000000, expected: "279037"},
+		{name: "20000000000", time: 20000000000, expected: "353130"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := Code(rfcSecret, Step(time.Unix(tt.time, 0)))
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, code)
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := Step(now)
+
+	previous, err := Code(rfcSecret, current-1)
+	require.NoError(t, err)
+	old, err := Code(rfcSecret, current-2)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		code         string
+		expectedOk   bool
+		expectedStep int64
+	}{
+		{name: "Текущий код", code: "050471", expectedOk: true, expectedStep: current},
+		{name: "Код предыдущего шага", code: previous, expectedOk: true, expectedStep: current - 1},
+		{name: "Устаревший код", code: old},
+		{name: "Неверный код", code: "000000"},
+		{name: "Код неверной длины", code: "50471"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok, err := Verify(rfcSecret, tt.code, now)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedOk, ok)
+			assert.Equal(t, tt.expectedStep, step)
+		})
+	}
+}
+
+func TestGenerateSecretAndProvisioningURI(t *testing.T) {
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+	assert.Len(t, secret, 32)
+
+	code, err := Code(secret, Step(time.Now()))
+	require.NoError(t, err)
+	_, ok, err := Verify(secret, code, time.Now())
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	uri, err := url.Parse(ProvisioningURI("Nimbus", "user@test.com", secret))
+	require.NoError(t, err)
+	assert.Equal(t, "otpauth", uri.Scheme)
+	assert.Equal(t, "totp", uri.Host)
+	assert.Equal(t, "/Nimbus:user@test.com", uri.Path)
+	assert.Equal(t, secret, uri.Query().Get("secret"))
+	assert.Equal(t, "Nimbus", uri.Query().Get("issuer"))
+}
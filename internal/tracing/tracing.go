@@ -0,0 +1,98 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+
+	// RequestIdKey атрибут спана с идентификатором запроса, по нему трейс связывается с записями журналов
+	RequestIdKey = attribute.Key("request.id")
+)
+
+// Config настройки экспорта трейсов
+type Config struct {
+	// Exporter куда отправлять спаны: none, stdout или otlp
+	Exporter string
+	// Endpoint адрес OTLP коллектора по gRPC
+	Endpoint string
+	// SampleRatio доля сохраняемых трейсов, начатых в этом сервисе. Для продолженных трейсов
+	// решение принимает тот, кто трейс начал
+	SampleRatio float64
+}
+
+// Init настраивает глобальный провайдер трейсов и передачу контекста трейса в заголовках traceparent.
+// Возвращает функцию, которая отправляет накопленные спаны при остановке сервиса
+func Init(ctx context.Context, service string, config Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{},
+		propagation.Baggage{}))
+
+	if config.SampleRatio < 0 || config.SampleRatio > 1 {
+		return nil, fmt.Errorf("trace sample ratio %v is out of [0, 1]", config.SampleRatio)
+	}
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch config.Exporter {
+	case ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterOTLP:
+		exporter, err = otlptracegrpc.New(ctx, otlptracegrpc.WithEndpoint(config.Endpoint),
+			otlptracegrpc.WithInsecure())
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", config.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", service))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// TraceId возвращает идентификатор сохраняемого трейса из контекста, пусто, если трейс не записывается
+func TraceId(ctx context.Context) string {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsSampled() {
+		return ""
+	}
+	return spanContext.TraceID().String()
+}
+
+// TraceField поле журнала с идентификатором трейса из контекста, как в журнале запросов gateway.
+// Если трейс не записывается, поле не добавляется
+func TraceField(ctx context.Context) zap.Field {
+	traceId := TraceId(ctx)
+	if traceId == "" {
+		return zap.Skip()
+	}
+	return zap.String("traceid", traceId)
+}
+
+// SetRequestId добавляет идентификатор запроса к текущему спану
+func SetRequestId(ctx context.Context, requestId string) {
+	trace.SpanFromContext(ctx).SetAttributes(RequestIdKey.String(requestId))
+}
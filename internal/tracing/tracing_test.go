@@ -0,0 +1,89 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
+)
+
+func TestQueryTracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	queryTracer := &QueryTracer{tracer: provider.Tracer(pgxTracerName)}
+
+	tests := []struct {
+		name           string
+		sql            string
+		err            error
+		expectedName   string
+		expectedStatus codes.Code
+	}{
+		{
+			name:           "Успешный запрос",
+			sql:            "SELECT uuid, title FROM film WHERE uuid = $1",
+			expectedName:   "postgres SELECT",
+			expectedStatus: codes.Unset,
+		},
+		{
+			name:           "Запрос с ошибкой",
+			sql:            "\n\t\tinsert INTO film (uuid) VALUES ($1)",
+			err:            errors.New("duplicate key"),
+			expectedName:   "postgres INSERT",
+			expectedStatus: codes.Error,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := queryTracer.TraceQueryStart(context.Background(), nil,
+				pgx.TraceQueryStartData{SQL: tt.sql, Args: []any{"secret"}})
+			queryTracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{CommandTag: pgconn.NewCommandTag("SELECT 1"),
+				Err: tt.err})
+
+			spans := recorder.Ended()
+			require.NotEmpty(t, spans)
+			span := spans[len(spans)-1]
+			assert.Equal(t, tt.expectedName, span.Name())
+			assert.Equal(t, tt.expectedStatus, span.Status().Code)
+			for _, attribute := range span.Attributes() {
+				assert.NotContains(t, attribute.Value.Emit(), "secret")
+			}
+		})
+	}
+}
+
+func TestInit(t *testing.T) {
+	_, err := Init(context.Background(), "test", Config{Exporter: "jaeger", SampleRatio: 1})
+	assert.Error(t, err)
+
+	_, err = Init(context.Background(), "test", Config{Exporter: ExporterNone, SampleRatio: 2})
+	assert.Error(t, err)
+
+	shutdown, err := Init(context.Background(), "test", Config{Exporter: ExporterNone, SampleRatio: 1})
+	require.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+	assert.Empty(t, TraceId(context.Background()))
+}
+
+func TestTraceField(t *testing.T) {
+	assert.Equal(t, zap.Skip(), TraceField(context.Background()))
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSampler(sdktrace.AlwaysSample()))
+	ctx, span := provider.Tracer("test").Start(context.Background(), "test")
+	defer span.End()
+	assert.Equal(t, zap.String("traceid", span.SpanContext().TraceID().String()), TraceField(ctx))
+
+	provider = sdktrace.NewTracerProvider(sdktrace.WithSampler(sdktrace.NeverSample()))
+	ctx, span = provider.Tracer("test").Start(context.Background(), "test")
+	defer span.End()
+	assert.Equal(t, zap.Skip(), TraceField(ctx))
+}
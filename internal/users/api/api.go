@@ -2,15 +2,21 @@ package api
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/SanExpett/diploma/internal/domain"
+	myerrors "github.com/SanExpett/diploma/internal/errors"
+	"github.com/SanExpett/diploma/internal/rbac"
 	reqid "github.com/SanExpett/diploma/internal/requestId"
 	session "github.com/SanExpett/diploma/internal/session/proto"
+	"github.com/SanExpett/diploma/internal/tracing"
 )
 
 type UsersService interface {
@@ -29,6 +35,27 @@ type UsersService interface {
 	PaySubscription(ctx context.Context, uuid, subId string) (string, error)
 	GetSubscriptions(ctx context.Context) ([]domain.Subscription, error)
 	GetSubscription(ctx context.Context, uuid string) (domain.Subscription, error)
+	GetRolePermissions(ctx context.Context) (map[rbac.Role][]rbac.Permission, error)
+	RequestPasswordReset(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, newPassword string) (string, error)
+	ResendEmailVerification(ctx context.Context, email string) error
+	VerifyEmail(ctx context.Context, token string) (string, error)
+	EnrollTOTP(ctx context.Context, email string) (string, string, error)
+	ConfirmTOTP(ctx context.Context, email, code string) ([]string, error)
+	VerifyTOTP(ctx context.Context, email, code string) error
+	DisableTOTP(ctx context.Context, email, code string) error
+	LoginWithIdentity(ctx context.Context, identity domain.Identity, linkLogin string) (domain.User, error)
+	SendMagicLink(ctx context.Context, email, link, tokenId string, expiresAt time.Time) error
+	ConsumeMagicLink(ctx context.Context, email, tokenId string) error
+	AddPasskey(ctx context.Context, email string, passkey domain.Passkey) error
+	GetPasskeys(ctx context.Context, email string) ([]domain.Passkey, error)
+	GetPasskey(ctx context.Context, id string) (string, domain.Passkey, error)
+	UpdatePasskeySignCount(ctx context.Context, id string, signCount uint32) error
+	RemovePasskey(ctx context.Context, email, id string) error
+	RecordAuditEvent(ctx context.Context, event domain.AuditEvent) error
+	ListAuditEvents(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEvent, error)
+	RegisterLoginDevice(ctx context.Context, login, userAgent, ip string) (bool, error)
+	ReportUnknownLogin(ctx context.Context, token string) (string, error)
 }
 
 type UsersServer struct {
@@ -47,15 +74,15 @@ func (server *UsersServer) CreateUser(ctx context.Context,
 	req *session.CreateUserRequest) (res *session.CreateUserResponse, err error) {
 	requestId := ctx.Value(reqid.ReqIDKey)
 
-	server.logger.Infof("[reqid=%s] creating user started: %v\n", requestId, req.User)
+	server.logger.With(tracing.TraceField(ctx)).Infof("[reqid=%s] creating user started: %v\n", requestId, req.User)
 
 	err = server.usersService.CreateUser(ctx, convertUserSignUpToRegular(req.User))
 	if err != nil {
-		server.logger.Errorf("[reqid=%s] failed to create user: %v\n", requestId, err)
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to create user: %v\n", requestId, err)
 		return nil, fmt.Errorf("[reqid=%s] failed to create user: %v\n", requestId, err)
 	}
 
-	server.logger.Infof("[reqid=%s] creating user finished: %v\n", requestId, req.User)
+	server.logger.With(tracing.TraceField(ctx)).Infof("[reqid=%s] creating user finished: %v\n", requestId, req.User)
 
 	return res, nil
 }
@@ -65,7 +92,7 @@ func (server *UsersServer) RemoveUser(ctx context.Context,
 	requestId := ctx.Value(reqid.ReqIDKey)
 	err = server.usersService.RemoveUser(ctx, req.Login)
 	if err != nil {
-		server.logger.Errorf("[reqid=%s] failed to remove user: %v\n", requestId, err)
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to remove user: %v\n", requestId, err)
 		return nil, fmt.Errorf("[reqid=%s] failed to remove user: %v\n", requestId, err)
 	}
 	return res, nil
@@ -75,8 +102,13 @@ func (server *UsersServer) HasUser(ctx context.Context,
 	req *session.HasUserRequest) (res *session.HasUserResponse, err error) {
 	requestId := ctx.Value(reqid.ReqIDKey)
 	err = server.usersService.HasUser(ctx, req.Login, req.Password)
+	// gateway отличает неверные учетные данные от сбоев по коду, чтобы считать неудачные попытки входа
+	if errors.Is(err, myerrors.ErrIncorrectLoginOrPassword) {
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to has user: %v\n", requestId, err)
+		return nil, status.Error(codes.Unauthenticated, myerrors.ErrIncorrectLoginOrPassword.Error())
+	}
 	if err != nil {
-		server.logger.Errorf("[reqid=%s] failed to has user: %v\n", requestId, err)
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to has user: %v\n", requestId, err)
 		return nil, fmt.Errorf("[reqid=%s] failed to has user: %v\n", requestId, err)
 	}
 	return res, nil
@@ -87,7 +119,7 @@ func (server *UsersServer) GetUser(ctx context.Context,
 	requestId := ctx.Value(reqid.ReqIDKey)
 	user, err := server.usersService.GetUser(ctx, req.Login)
 	if err != nil {
-		server.logger.Errorf("[reqid=%s] failed to get user: %v\n", requestId, err)
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get user: %v\n", requestId, err)
 		return nil, fmt.Errorf("[reqid=%s] failed to get user: %v\n", requestId, err)
 	}
 	return &session.GetUserResponse{
@@ -100,7 +132,7 @@ func (server *UsersServer) ChangeUserPassword(ctx context.Context,
 	requestId := ctx.Value(reqid.ReqIDKey)
 	user, err := server.usersService.ChangeUserPassword(ctx, req.Login, req.NewPassword)
 	if err != nil {
-		server.logger.Errorf("[reqid=%s] failed to change user: %v\n", requestId, err)
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to change user: %v\n", requestId, err)
 		return nil, fmt.Errorf("[reqid=%s] failed to change user: %v\n", requestId, err)
 	}
 	return &session.ChangeUserPasswordResponse{
@@ -113,7 +145,7 @@ func (server *UsersServer) ChangeUserName(ctx context.Context,
 	requestId := ctx.Value(reqid.ReqIDKey)
 	user, err := server.usersService.ChangeUserName(ctx, req.Login, req.NewUsername)
 	if err != nil {
-		server.logger.Errorf("[reqid=%s] failed to change user: %v\n", requestId, err)
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to change user: %v\n", requestId, err)
 		return nil, fmt.Errorf("[reqid=%s] failed to change user: %v\n", requestId, err)
 	}
 	return &session.ChangeUserNameResponse{
@@ -126,7 +158,7 @@ func (server *UsersServer) GetUserDataByUuid(ctx context.Context,
 	requestId := ctx.Value(reqid.ReqIDKey)
 	user, err := server.usersService.GetUserDataByUuid(ctx, req.Uuid)
 	if err != nil {
-		server.logger.Errorf("[reqid=%s] failed to get user: %v\n", requestId, err)
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get user: %v\n", requestId, err)
 		return nil, fmt.Errorf("[reqid=%s] failed to get user: %v\n", requestId, err)
 	}
 	return &session.GetUserDataByUuidResponse{
@@ -139,7 +171,7 @@ func (server *UsersServer) GetUserPreview(ctx context.Context,
 	requestId := ctx.Value(reqid.ReqIDKey)
 	user, err := server.usersService.GetUserPreview(ctx, req.Uuid)
 	if err != nil {
-		server.logger.Errorf("[reqid=%s] failed to get user: %v\n", requestId, err)
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get user: %v\n", requestId, err)
 		return nil, fmt.Errorf("[reqid=%s] failed to get user: %v\n", requestId, err)
 	}
 	return &session.GetUserPreviewResponse{
@@ -152,7 +184,7 @@ func (server *UsersServer) ChangeUserPasswordByUuid(ctx context.Context,
 	requestId := ctx.Value(reqid.ReqIDKey)
 	user, err := server.usersService.ChangeUserPasswordByUuid(ctx, req.Uuid, req.NewPassword)
 	if err != nil {
-		server.logger.Errorf("[reqid=%s] failed to change user password: %v\n", requestId, err)
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to change user password: %v\n", requestId, err)
 		return nil, fmt.Errorf("[reqid=%s] failed to change user password: %v\n", requestId, err)
 	}
 	return &session.ChangeUserPasswordByUuidResponse{
@@ -165,7 +197,7 @@ func (server *UsersServer) ChangeUserNameByUuid(ctx context.Context,
 	requestId := ctx.Value(reqid.ReqIDKey)
 	user, err := server.usersService.ChangeUserNameByUuid(ctx, req.Uuid, req.NewUsername)
 	if err != nil {
-		server.logger.Errorf("[reqid=%s] failed to change username: %v\n", requestId, err)
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to change username: %v\n", requestId, err)
 		return nil, fmt.Errorf("[reqid=%s] failed to change username: %v\n", requestId, err)
 	}
 	return &session.ChangeUserNameByUuidResponse{
@@ -178,7 +210,7 @@ func (server *UsersServer) ChangeUserAvatarByUuid(ctx context.Context,
 	requestId := ctx.Value(reqid.ReqIDKey)
 	user, err := server.usersService.ChangeUserAvatarByUuid(ctx, req.Uuid, req.NewAvatar)
 	if err != nil {
-		server.logger.Errorf("[reqid=%s] failed to change user avatar: %v\n", requestId, err)
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to change user avatar: %v\n", requestId, err)
 		return nil, fmt.Errorf("[reqid=%s] failed to change user avatar: %v\n", requestId, err)
 	}
 	return &session.ChangeUserAvatarByUuidResponse{
@@ -191,7 +223,8 @@ func (server *UsersServer) HasSubscription(ctx context.Context,
 	requestId := ctx.Value(reqid.ReqIDKey)
 	stat, err := server.usersService.HasSubscription(ctx, req.Uuid)
 	if err != nil {
-		server.logger.Errorf("[reqid=%s] failed to check user subscription: %v\n", requestId, err)
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to check user subscription: %v\n",
+			requestId, err)
 		return nil, fmt.Errorf("[reqid=%s] failed to check user subscription: %v\n", requestId, err)
 	}
 	return &session.HasSubscriptionResponse{
@@ -204,7 +237,7 @@ func (server *UsersServer) GetSubscriptions(ctx context.Context,
 	requestId := ctx.Value(reqid.ReqIDKey)
 	subs, err := server.usersService.GetSubscriptions(ctx)
 	if err != nil {
-		server.logger.Errorf("[reqid=%s] failed to get subs: %v\n", requestId, err)
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get subs: %v\n", requestId, err)
 		return nil, fmt.Errorf("[reqid=%s] failed to get subs: %v\n", requestId, err)
 	}
 	return &session.GetSubscriptionsResponse{
@@ -217,7 +250,7 @@ func (server *UsersServer) PaySubscription(ctx context.Context,
 	requestId := ctx.Value(reqid.ReqIDKey)
 	response, err := server.usersService.PaySubscription(ctx, req.Uuid, req.SubId)
 	if err != nil {
-		server.logger.Errorf("[reqid=%s] failed to pay: %v\n", requestId, err)
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to pay: %v\n", requestId, err)
 		return nil, fmt.Errorf("[reqid=%s] failed to pay: %v\n", requestId, err)
 	}
 	return &session.PaySubscriptionResponse{
@@ -225,6 +258,346 @@ func (server *UsersServer) PaySubscription(ctx context.Context,
 	}, nil
 }
 
+func (server *UsersServer) GetRolePermissions(ctx context.Context,
+	req *session.GetRolePermissionsRequest) (res *session.GetRolePermissionsResponse, err error) {
+	requestId := ctx.Value(reqid.ReqIDKey)
+	rolePermissions, err := server.usersService.GetRolePermissions(ctx)
+	if err != nil {
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get role permissions: %v\n", requestId, err)
+		return nil, fmt.Errorf("[reqid=%s] failed to get role permissions: %v\n", requestId, err)
+	}
+	return &session.GetRolePermissionsResponse{
+		Roles: convertRolePermissionsToProto(rolePermissions),
+	}, nil
+}
+
+func (server *UsersServer) RequestPasswordReset(ctx context.Context,
+	req *session.RequestPasswordResetRequest) (res *session.RequestPasswordResetResponse, err error) {
+	requestId := ctx.Value(reqid.ReqIDKey)
+	err = server.usersService.RequestPasswordReset(ctx, req.Login)
+	if err != nil {
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to request password reset: %v\n",
+			requestId, err)
+		return nil, fmt.Errorf("[reqid=%s] failed to request password reset: %v\n", requestId, err)
+	}
+	return &session.RequestPasswordResetResponse{}, nil
+}
+
+func (server *UsersServer) ResetPassword(ctx context.Context,
+	req *session.ResetPasswordRequest) (res *session.ResetPasswordResponse, err error) {
+	requestId := ctx.Value(reqid.ReqIDKey)
+	login, err := server.usersService.ResetPassword(ctx, req.Token, req.NewPassword)
+	if errors.Is(err, myerrors.ErrInvalidResetToken) {
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to reset password: %v\n", requestId, err)
+		return nil, status.Error(codes.InvalidArgument, myerrors.ErrInvalidResetToken.Error())
+	}
+	if err != nil {
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to reset password: %v\n", requestId, err)
+		return nil, fmt.Errorf("[reqid=%s] failed to reset password: %v\n", requestId, err)
+	}
+	return &session.ResetPasswordResponse{
+		Login: login,
+	}, nil
+}
+
+func (server *UsersServer) ResendEmailVerification(ctx context.Context,
+	req *session.ResendEmailVerificationRequest) (res *session.ResendEmailVerificationResponse, err error) {
+	requestId := ctx.Value(reqid.ReqIDKey)
+	err = server.usersService.ResendEmailVerification(ctx, req.Login)
+	if errors.Is(err, myerrors.ErrEmailAlreadyVerified) {
+		return nil, status.Error(codes.FailedPrecondition, myerrors.ErrEmailAlreadyVerified.Error())
+	}
+	if errors.Is(err, myerrors.ErrTooManyVerificationRequests) {
+		return nil, status.Error(codes.ResourceExhausted, myerrors.ErrTooManyVerificationRequests.Error())
+	}
+	if err != nil {
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to resend email verification: %v\n",
+			requestId, err)
+		return nil, fmt.Errorf("[reqid=%s] failed to resend email verification: %v\n", requestId, err)
+	}
+	return &session.ResendEmailVerificationResponse{}, nil
+}
+
+func (server *UsersServer) VerifyEmail(ctx context.Context,
+	req *session.VerifyEmailRequest) (res *session.VerifyEmailResponse, err error) {
+	requestId := ctx.Value(reqid.ReqIDKey)
+	login, err := server.usersService.VerifyEmail(ctx, req.Token)
+	if errors.Is(err, myerrors.ErrInvalidVerificationToken) {
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to verify email: %v\n", requestId, err)
+		return nil, status.Error(codes.InvalidArgument, myerrors.ErrInvalidVerificationToken.Error())
+	}
+	if err != nil {
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to verify email: %v\n", requestId, err)
+		return nil, fmt.Errorf("[reqid=%s] failed to verify email: %v\n", requestId, err)
+	}
+	return &session.VerifyEmailResponse{
+		Login: login,
+	}, nil
+}
+
+func (server *UsersServer) EnrollTOTP(ctx context.Context,
+	req *session.EnrollTOTPRequest) (res *session.EnrollTOTPResponse, err error) {
+	requestId := ctx.Value(reqid.ReqIDKey)
+	secret, provisioningUri, err := server.usersService.EnrollTOTP(ctx, req.Login)
+	if err != nil {
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to enroll totp: %v\n", requestId, err)
+		return nil, totpStatusError(requestId, "failed to enroll totp", err)
+	}
+	return &session.EnrollTOTPResponse{
+		Secret:          secret,
+		ProvisioningUri: provisioningUri,
+	}, nil
+}
+
+func (server *UsersServer) ConfirmTOTP(ctx context.Context,
+	req *session.ConfirmTOTPRequest) (res *session.ConfirmTOTPResponse, err error) {
+	requestId := ctx.Value(reqid.ReqIDKey)
+	recoveryCodes, err := server.usersService.ConfirmTOTP(ctx, req.Login, req.Code)
+	if err != nil {
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to confirm totp: %v\n", requestId, err)
+		return nil, totpStatusError(requestId, "failed to confirm totp", err)
+	}
+	return &session.ConfirmTOTPResponse{
+		RecoveryCodes: recoveryCodes,
+	}, nil
+}
+
+func (server *UsersServer) VerifyTOTP(ctx context.Context,
+	req *session.VerifyTOTPRequest) (res *session.VerifyTOTPResponse, err error) {
+	requestId := ctx.Value(reqid.ReqIDKey)
+	err = server.usersService.VerifyTOTP(ctx, req.Login, req.Code)
+	if err != nil {
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to verify totp: %v\n", requestId, err)
+		return nil, totpStatusError(requestId, "failed to verify totp", err)
+	}
+	return &session.VerifyTOTPResponse{}, nil
+}
+
+func (server *UsersServer) DisableTOTP(ctx context.Context,
+	req *session.DisableTOTPRequest) (res *session.DisableTOTPResponse, err error) {
+	requestId := ctx.Value(reqid.ReqIDKey)
+	err = server.usersService.DisableTOTP(ctx, req.Login, req.Code)
+	if err != nil {
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to disable totp: %v\n", requestId, err)
+		return nil, totpStatusError(requestId, "failed to disable totp", err)
+	}
+	return &session.DisableTOTPResponse{}, nil
+}
+
+func (server *UsersServer) LoginWithIdentity(ctx context.Context,
+	req *session.LoginWithIdentityRequest) (res *session.LoginWithIdentityResponse, err error) {
+	requestId := ctx.Value(reqid.ReqIDKey)
+	user, err := server.usersService.LoginWithIdentity(ctx, domain.Identity{
+		Provider:      req.Provider,
+		Subject:       req.Subject,
+		Email:         req.Email,
+		EmailVerified: req.EmailVerified,
+		Name:          req.Name,
+	}, req.LinkLogin)
+	if err != nil {
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to login with identity: %v\n", requestId, err)
+		// gateway различает отказы в привязке по кодам gRPC
+		switch {
+		case errors.Is(err, myerrors.ErrIdentityAlreadyLinked):
+			return nil, status.Error(codes.AlreadyExists, myerrors.ErrIdentityAlreadyLinked.Error())
+		case errors.Is(err, myerrors.ErrAccountExistsForIdentity):
+			return nil, status.Error(codes.FailedPrecondition, myerrors.ErrAccountExistsForIdentity.Error())
+		}
+		return nil, fmt.Errorf("[reqid=%s] failed to login with identity: %v\n", requestId, err)
+	}
+	return &session.LoginWithIdentityResponse{User: convertUserToProto(user)}, nil
+}
+
+func (server *UsersServer) SendMagicLink(ctx context.Context,
+	req *session.SendMagicLinkRequest) (res *session.SendMagicLinkResponse, err error) {
+	requestId := ctx.Value(reqid.ReqIDKey)
+	err = server.usersService.SendMagicLink(ctx, req.Login, req.Link, req.TokenId, req.ExpiresAt.AsTime())
+	if err != nil {
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to send magic link: %v\n", requestId, err)
+		return nil, fmt.Errorf("[reqid=%s] failed to send magic link: %v\n", requestId, err)
+	}
+	return &session.SendMagicLinkResponse{}, nil
+}
+
+func (server *UsersServer) ConsumeMagicLink(ctx context.Context,
+	req *session.ConsumeMagicLinkRequest) (res *session.ConsumeMagicLinkResponse, err error) {
+	requestId := ctx.Value(reqid.ReqIDKey)
+	err = server.usersService.ConsumeMagicLink(ctx, req.Login, req.TokenId)
+	if errors.Is(err, myerrors.ErrInvalidMagicLink) {
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to consume magic link: %v\n", requestId, err)
+		return nil, status.Error(codes.InvalidArgument, myerrors.ErrInvalidMagicLink.Error())
+	}
+	if err != nil {
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to consume magic link: %v\n", requestId, err)
+		return nil, fmt.Errorf("[reqid=%s] failed to consume magic link: %v\n", requestId, err)
+	}
+	return &session.ConsumeMagicLinkResponse{}, nil
+}
+
+func (server *UsersServer) AddPasskey(ctx context.Context,
+	req *session.AddPasskeyRequest) (res *session.AddPasskeyResponse, err error) {
+	requestId := ctx.Value(reqid.ReqIDKey)
+	err = server.usersService.AddPasskey(ctx, req.Login, convertPasskeyFromProto(req.Passkey))
+	if errors.Is(err, myerrors.ErrPasskeyAlreadyRegistered) {
+		return nil, status.Error(codes.AlreadyExists, myerrors.ErrPasskeyAlreadyRegistered.Error())
+	}
+	if err != nil {
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to add passkey: %v\n", requestId, err)
+		return nil, fmt.Errorf("[reqid=%s] failed to add passkey: %v\n", requestId, err)
+	}
+	return &session.AddPasskeyResponse{}, nil
+}
+
+func (server *UsersServer) GetPasskeys(ctx context.Context,
+	req *session.GetPasskeysRequest) (res *session.GetPasskeysResponse, err error) {
+	requestId := ctx.Value(reqid.ReqIDKey)
+	passkeys, err := server.usersService.GetPasskeys(ctx, req.Login)
+	if err != nil {
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get passkeys: %v\n", requestId, err)
+		return nil, fmt.Errorf("[reqid=%s] failed to get passkeys: %v\n", requestId, err)
+	}
+
+	passkeysProto := make([]*session.Passkey, 0, len(passkeys))
+	for _, passkey := range passkeys {
+		passkeysProto = append(passkeysProto, convertPasskeyToProto(passkey))
+	}
+	return &session.GetPasskeysResponse{
+		Passkeys: passkeysProto,
+	}, nil
+}
+
+func (server *UsersServer) GetPasskey(ctx context.Context,
+	req *session.GetPasskeyRequest) (res *session.GetPasskeyResponse, err error) {
+	requestId := ctx.Value(reqid.ReqIDKey)
+	login, passkey, err := server.usersService.GetPasskey(ctx, req.Id)
+	if errors.Is(err, myerrors.ErrNoSuchPasskey) {
+		return nil, status.Error(codes.NotFound, myerrors.ErrNoSuchPasskey.Error())
+	}
+	if err != nil {
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get passkey: %v\n", requestId, err)
+		return nil, fmt.Errorf("[reqid=%s] failed to get passkey: %v\n", requestId, err)
+	}
+	return &session.GetPasskeyResponse{
+		Login:   login,
+		Passkey: convertPasskeyToProto(passkey),
+	}, nil
+}
+
+func (server *UsersServer) UpdatePasskeySignCount(ctx context.Context,
+	req *session.UpdatePasskeySignCountRequest) (res *session.UpdatePasskeySignCountResponse, err error) {
+	requestId := ctx.Value(reqid.ReqIDKey)
+	err = server.usersService.UpdatePasskeySignCount(ctx, req.Id, req.SignCount)
+	if errors.Is(err, myerrors.ErrPasskeySignCountRegression) {
+		return nil, status.Error(codes.FailedPrecondition, myerrors.ErrPasskeySignCountRegression.Error())
+	}
+	if err != nil {
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to update passkey sign count: %v\n",
+			requestId, err)
+		return nil, fmt.Errorf("[reqid=%s] failed to update passkey sign count: %v\n", requestId, err)
+	}
+	return &session.UpdatePasskeySignCountResponse{}, nil
+}
+
+func (server *UsersServer) RemovePasskey(ctx context.Context,
+	req *session.RemovePasskeyRequest) (res *session.RemovePasskeyResponse, err error) {
+	requestId := ctx.Value(reqid.ReqIDKey)
+	err = server.usersService.RemovePasskey(ctx, req.Login, req.Id)
+	if errors.Is(err, myerrors.ErrNoSuchPasskey) {
+		return nil, status.Error(codes.NotFound, myerrors.ErrNoSuchPasskey.Error())
+	}
+	if err != nil {
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to remove passkey: %v\n", requestId, err)
+		return nil, fmt.Errorf("[reqid=%s] failed to remove passkey: %v\n", requestId, err)
+	}
+	return &session.RemovePasskeyResponse{}, nil
+}
+
+func (server *UsersServer) RecordAuditEvent(ctx context.Context,
+	req *session.RecordAuditEventRequest) (res *session.RecordAuditEventResponse, err error) {
+	requestId := ctx.Value(reqid.ReqIDKey)
+	err = server.usersService.RecordAuditEvent(ctx, convertAuditEventFromProto(req.Event))
+	if err != nil {
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to record audit event: %v\n", requestId, err)
+		return nil, fmt.Errorf("[reqid=%s] failed to record audit event: %v\n", requestId, err)
+	}
+	return &session.RecordAuditEventResponse{}, nil
+}
+
+func (server *UsersServer) ListAuditEvents(ctx context.Context,
+	req *session.ListAuditEventsRequest) (res *session.ListAuditEventsResponse, err error) {
+	requestId := ctx.Value(reqid.ReqIDKey)
+	filter := domain.AuditFilter{
+		Actor:  req.Actor,
+		Target: req.Target,
+		Action: req.Action,
+		Before: req.Before,
+		Limit:  int(req.Limit),
+	}
+	if req.From != nil {
+		filter.From = req.From.AsTime()
+	}
+	if req.To != nil {
+		filter.To = req.To.AsTime()
+	}
+
+	events, err := server.usersService.ListAuditEvents(ctx, filter)
+	if err != nil {
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to list audit events: %v\n", requestId, err)
+		return nil, fmt.Errorf("[reqid=%s] failed to list audit events: %v\n", requestId, err)
+	}
+
+	eventsProto := make([]*session.AuditEvent, 0, len(events))
+	for _, event := range events {
+		eventsProto = append(eventsProto, convertAuditEventToProto(event))
+	}
+	return &session.ListAuditEventsResponse{
+		Events: eventsProto,
+	}, nil
+}
+
+func (server *UsersServer) RegisterLoginDevice(ctx context.Context,
+	req *session.RegisterLoginDeviceRequest) (res *session.RegisterLoginDeviceResponse, err error) {
+	requestId := ctx.Value(reqid.ReqIDKey)
+	newDevice, err := server.usersService.RegisterLoginDevice(ctx, req.Login, req.UserAgent, req.Ip)
+	if err != nil {
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to register login device: %v\n", requestId, err)
+		return nil, fmt.Errorf("[reqid=%s] failed to register login device: %v\n", requestId, err)
+	}
+	return &session.RegisterLoginDeviceResponse{
+		NewDevice: newDevice,
+	}, nil
+}
+
+func (server *UsersServer) ReportUnknownLogin(ctx context.Context,
+	req *session.ReportUnknownLoginRequest) (res *session.ReportUnknownLoginResponse, err error) {
+	requestId := ctx.Value(reqid.ReqIDKey)
+	login, err := server.usersService.ReportUnknownLogin(ctx, req.Token)
+	if errors.Is(err, myerrors.ErrInvalidUnknownLoginToken) {
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to report unknown login: %v\n", requestId, err)
+		return nil, status.Error(codes.InvalidArgument, myerrors.ErrInvalidUnknownLoginToken.Error())
+	}
+	if err != nil {
+		server.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to report unknown login: %v\n", requestId, err)
+		return nil, fmt.Errorf("[reqid=%s] failed to report unknown login: %v\n", requestId, err)
+	}
+	return &session.ReportUnknownLoginResponse{
+		Login: login,
+	}, nil
+}
+
+// totpStatusError передает ошибки второго фактора кодами gRPC, чтобы gateway мог их различить
+func totpStatusError(requestId any, message string, err error) error {
+	switch {
+	case errors.Is(err, myerrors.ErrInvalidTOTPCode):
+		return status.Error(codes.InvalidArgument, myerrors.ErrInvalidTOTPCode.Error())
+	case errors.Is(err, myerrors.ErrTOTPNotEnabled):
+		return status.Error(codes.FailedPrecondition, myerrors.ErrTOTPNotEnabled.Error())
+	case errors.Is(err, myerrors.ErrTOTPAlreadyEnabled):
+		return status.Error(codes.AlreadyExists, myerrors.ErrTOTPAlreadyEnabled.Error())
+	}
+	return fmt.Errorf("[reqid=%s] %s: %v\n", requestId, message, err)
+}
+
 func convertUserSignUpToRegular(user *session.UserSignUp) domain.UserSignUp {
 	return domain.UserSignUp{
 		Email:    user.Email,
@@ -238,14 +611,71 @@ func convertUserToProto(user domain.User) *session.User {
 		Uuid:            user.Uuid,
 		Email:           user.Email,
 		Username:        user.Name,
-		Password:        user.Password,
 		IsAdmin:         user.IsAdmin,
 		Version:         user.Version,
 		RegisteredAt:    convertTimeToProto(user.RegisteredAt),
 		Birthday:        convertTimeToProto(user.Birthday),
 		Avatar:          user.Avatar,
 		HasSubscription: user.HasSubscription,
+		Roles:           user.Roles,
+		EmailVerified:   user.EmailVerified,
+		TotpEnabled:     user.TotpEnabled,
+	}
+}
+
+func convertPasskeyToProto(passkey domain.Passkey) *session.Passkey {
+	passkeyProto := &session.Passkey{
+		Id:         passkey.Id,
+		PublicKey:  passkey.PublicKey,
+		SignCount:  passkey.SignCount,
+		Transports: passkey.Transports,
+		Name:       passkey.Name,
+		CreatedAt:  convertTimeToProto(passkey.CreatedAt),
+	}
+	if !passkey.LastUsedAt.IsZero() {
+		passkeyProto.LastUsedAt = convertTimeToProto(passkey.LastUsedAt)
 	}
+	return passkeyProto
+}
+
+func convertPasskeyFromProto(passkey *session.Passkey) domain.Passkey {
+	return domain.Passkey{
+		Id:         passkey.GetId(),
+		PublicKey:  passkey.GetPublicKey(),
+		SignCount:  passkey.GetSignCount(),
+		Transports: passkey.GetTransports(),
+		Name:       passkey.GetName(),
+	}
+}
+
+func convertAuditEventToProto(event domain.AuditEvent) *session.AuditEvent {
+	return &session.AuditEvent{
+		Id:         event.Id,
+		OccurredAt: convertTimeToProto(event.OccurredAt),
+		Action:     event.Action,
+		Actor:      event.Actor,
+		Target:     event.Target,
+		Ip:         event.Ip,
+		UserAgent:  event.UserAgent,
+		RequestId:  event.RequestId,
+		Details:    event.Details,
+	}
+}
+
+func convertAuditEventFromProto(event *session.AuditEvent) domain.AuditEvent {
+	auditEvent := domain.AuditEvent{
+		Action:    event.GetAction(),
+		Actor:     event.GetActor(),
+		Target:    event.GetTarget(),
+		Ip:        event.GetIp(),
+		UserAgent: event.GetUserAgent(),
+		RequestId: event.GetRequestId(),
+		Details:   event.GetDetails(),
+	}
+	if event.GetOccurredAt() != nil {
+		auditEvent.OccurredAt = event.GetOccurredAt().AsTime()
+	}
+	return auditEvent
 }
 
 func convertTimeToProto(time time.Time) *timestamppb.Timestamp {
@@ -275,3 +705,18 @@ func convertSubsToProto(subs []domain.Subscription) []*session.Subscription {
 	}
 	return protoSubs
 }
+
+func convertRolePermissionsToProto(rolePermissions map[rbac.Role][]rbac.Permission) []*session.RolePermissions {
+	protoRoles := make([]*session.RolePermissions, 0, len(rolePermissions))
+	for role, permissions := range rolePermissions {
+		protoPermissions := make([]string, 0, len(permissions))
+		for _, permission := range permissions {
+			protoPermissions = append(protoPermissions, string(permission))
+		}
+		protoRoles = append(protoRoles, &session.RolePermissions{
+			Role:        string(role),
+			Permissions: protoPermissions,
+		})
+	}
+	return protoRoles
+}
@@ -2,12 +2,17 @@ package api_test
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/SanExpett/diploma/internal/domain"
+	myerrors "github.com/SanExpett/diploma/internal/errors"
 	session "github.com/SanExpett/diploma/internal/session/proto"
 	"github.com/SanExpett/diploma/internal/users/api"
 	"github.com/SanExpett/diploma/internal/users/mocks"
@@ -67,6 +72,15 @@ func TestUsersServer_HasUser(t *testing.T) {
 	_, err := server.HasUser(context.Background(), req)
 
 	assert.NoError(t, err)
+
+	server = api.NewUsersServer(mockUsersService, zap.NewNop().Sugar())
+
+	mockUsersService.EXPECT().HasUser(gomock.Any(), "test@example.com", "password").
+		Return(fmt.Errorf("failed to compare passwords: %w", myerrors.ErrIncorrectLoginOrPassword))
+
+	_, err = server.HasUser(context.Background(), req)
+
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
 }
 
 func TestUsersServer_GetUser(t *testing.T) {
@@ -5,9 +5,12 @@
 package mocks
 
 import (
+	context "context"
 	reflect "reflect"
+	time "time"
 
 	domain "github.com/SanExpett/diploma/internal/domain"
+	rbac "github.com/SanExpett/diploma/internal/rbac"
 	gomock "github.com/golang/mock/gomock"
 )
 
@@ -34,6 +37,34 @@ func (m *MockusersStorage) EXPECT() *MockusersStorageMockRecorder {
 	return m.recorder
 }
 
+// AddAuditEvent mocks base method.
+func (m *MockusersStorage) AddAuditEvent(event domain.AuditEvent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddAuditEvent", event)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddAuditEvent indicates an expected call of AddAuditEvent.
+func (mr *MockusersStorageMockRecorder) AddAuditEvent(event interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddAuditEvent", reflect.TypeOf((*MockusersStorage)(nil).AddAuditEvent), event)
+}
+
+// AddPasskey mocks base method.
+func (m *MockusersStorage) AddPasskey(email string, passkey domain.Passkey) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddPasskey", email, passkey)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddPasskey indicates an expected call of AddPasskey.
+func (mr *MockusersStorageMockRecorder) AddPasskey(email, passkey interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddPasskey", reflect.TypeOf((*MockusersStorage)(nil).AddPasskey), email, passkey)
+}
+
 // AddSubscription mocks base method.
 func (m *MockusersStorage) AddSubscription(uuid, newDate string) error {
 	m.ctrl.T.Helper()
@@ -123,6 +154,34 @@ func (mr *MockusersStorageMockRecorder) ChangeUserPasswordByUuid(uuid, newPasswo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeUserPasswordByUuid", reflect.TypeOf((*MockusersStorage)(nil).ChangeUserPasswordByUuid), uuid, newPassword)
 }
 
+// ConfirmTOTP mocks base method.
+func (m *MockusersStorage) ConfirmTOTP(email string, step int64, recoveryCodeHashes []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConfirmTOTP", email, step, recoveryCodeHashes)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ConfirmTOTP indicates an expected call of ConfirmTOTP.
+func (mr *MockusersStorageMockRecorder) ConfirmTOTP(email, step, recoveryCodeHashes interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConfirmTOTP", reflect.TypeOf((*MockusersStorage)(nil).ConfirmTOTP), email, step, recoveryCodeHashes)
+}
+
+// ConsumeMagicLinkToken mocks base method.
+func (m *MockusersStorage) ConsumeMagicLinkToken(email, tokenHash string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumeMagicLinkToken", email, tokenHash)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ConsumeMagicLinkToken indicates an expected call of ConsumeMagicLinkToken.
+func (mr *MockusersStorageMockRecorder) ConsumeMagicLinkToken(email, tokenHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeMagicLinkToken", reflect.TypeOf((*MockusersStorage)(nil).ConsumeMagicLinkToken), email, tokenHash)
+}
+
 // CreateUser mocks base method.
 func (m *MockusersStorage) CreateUser(user domain.UserSignUp) error {
 	m.ctrl.T.Helper()
@@ -137,6 +196,125 @@ func (mr *MockusersStorageMockRecorder) CreateUser(user interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockusersStorage)(nil).CreateUser), user)
 }
 
+// CreateUserWithIdentity mocks base method.
+func (m *MockusersStorage) CreateUserWithIdentity(user domain.UserSignUp, identity domain.Identity) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateUserWithIdentity", user, identity)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateUserWithIdentity indicates an expected call of CreateUserWithIdentity.
+func (mr *MockusersStorageMockRecorder) CreateUserWithIdentity(user, identity interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUserWithIdentity", reflect.TypeOf((*MockusersStorage)(nil).CreateUserWithIdentity), user, identity)
+}
+
+// DisableTOTP mocks base method.
+func (m *MockusersStorage) DisableTOTP(email string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisableTOTP", email)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DisableTOTP indicates an expected call of DisableTOTP.
+func (mr *MockusersStorageMockRecorder) DisableTOTP(email interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisableTOTP", reflect.TypeOf((*MockusersStorage)(nil).DisableTOTP), email)
+}
+
+// GetAuditEvents mocks base method.
+func (m *MockusersStorage) GetAuditEvents(filter domain.AuditFilter) ([]domain.AuditEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAuditEvents", filter)
+	ret0, _ := ret[0].([]domain.AuditEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAuditEvents indicates an expected call of GetAuditEvents.
+func (mr *MockusersStorageMockRecorder) GetAuditEvents(filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAuditEvents", reflect.TypeOf((*MockusersStorage)(nil).GetAuditEvents), filter)
+}
+
+// GetEmailVerificationStatus mocks base method.
+func (m *MockusersStorage) GetEmailVerificationStatus(email string, since time.Time) (domain.EmailVerificationStatus, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetEmailVerificationStatus", email, since)
+	ret0, _ := ret[0].(domain.EmailVerificationStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetEmailVerificationStatus indicates an expected call of GetEmailVerificationStatus.
+func (mr *MockusersStorageMockRecorder) GetEmailVerificationStatus(email, since interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEmailVerificationStatus", reflect.TypeOf((*MockusersStorage)(nil).GetEmailVerificationStatus), email, since)
+}
+
+// GetKnownDevices mocks base method.
+func (m *MockusersStorage) GetKnownDevices(email string) ([]domain.KnownDevice, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetKnownDevices", email)
+	ret0, _ := ret[0].([]domain.KnownDevice)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetKnownDevices indicates an expected call of GetKnownDevices.
+func (mr *MockusersStorageMockRecorder) GetKnownDevices(email interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetKnownDevices", reflect.TypeOf((*MockusersStorage)(nil).GetKnownDevices), email)
+}
+
+// GetPasskey mocks base method.
+func (m *MockusersStorage) GetPasskey(id string) (string, domain.Passkey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPasskey", id)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(domain.Passkey)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetPasskey indicates an expected call of GetPasskey.
+func (mr *MockusersStorageMockRecorder) GetPasskey(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPasskey", reflect.TypeOf((*MockusersStorage)(nil).GetPasskey), id)
+}
+
+// GetPasskeys mocks base method.
+func (m *MockusersStorage) GetPasskeys(email string) ([]domain.Passkey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPasskeys", email)
+	ret0, _ := ret[0].([]domain.Passkey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPasskeys indicates an expected call of GetPasskeys.
+func (mr *MockusersStorageMockRecorder) GetPasskeys(email interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPasskeys", reflect.TypeOf((*MockusersStorage)(nil).GetPasskeys), email)
+}
+
+// GetPasswordHash mocks base method.
+func (m *MockusersStorage) GetPasswordHash(email string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPasswordHash", email)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPasswordHash indicates an expected call of GetPasswordHash.
+func (mr *MockusersStorageMockRecorder) GetPasswordHash(email interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPasswordHash", reflect.TypeOf((*MockusersStorage)(nil).GetPasswordHash), email)
+}
+
 // GetSubscription mocks base method.
 func (m *MockusersStorage) GetSubscription(uuid string) (domain.Subscription, error) {
 	m.ctrl.T.Helper()
@@ -167,6 +345,21 @@ func (mr *MockusersStorageMockRecorder) GetSubscriptions() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSubscriptions", reflect.TypeOf((*MockusersStorage)(nil).GetSubscriptions))
 }
 
+// GetTOTP mocks base method.
+func (m *MockusersStorage) GetTOTP(email string) (domain.TOTP, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTOTP", email)
+	ret0, _ := ret[0].(domain.TOTP)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTOTP indicates an expected call of GetTOTP.
+func (mr *MockusersStorageMockRecorder) GetTOTP(email interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTOTP", reflect.TypeOf((*MockusersStorage)(nil).GetTOTP), email)
+}
+
 // GetUser mocks base method.
 func (m *MockusersStorage) GetUser(email string) (domain.User, error) {
 	m.ctrl.T.Helper()
@@ -182,6 +375,21 @@ func (mr *MockusersStorageMockRecorder) GetUser(email interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUser", reflect.TypeOf((*MockusersStorage)(nil).GetUser), email)
 }
 
+// GetUserByIdentity mocks base method.
+func (m *MockusersStorage) GetUserByIdentity(provider, subject string) (domain.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByIdentity", provider, subject)
+	ret0, _ := ret[0].(domain.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserByIdentity indicates an expected call of GetUserByIdentity.
+func (mr *MockusersStorageMockRecorder) GetUserByIdentity(provider, subject interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByIdentity", reflect.TypeOf((*MockusersStorage)(nil).GetUserByIdentity), provider, subject)
+}
+
 // GetUserDataByUuid mocks base method.
 func (m *MockusersStorage) GetUserDataByUuid(uuid string) (domain.User, error) {
 	m.ctrl.T.Helper()
@@ -227,18 +435,76 @@ func (mr *MockusersStorageMockRecorder) HasSubscription(uuid interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HasSubscription", reflect.TypeOf((*MockusersStorage)(nil).HasSubscription), uuid)
 }
 
-// HasUser mocks base method.
-func (m *MockusersStorage) HasUser(email, password string) error {
+// LinkIdentity mocks base method.
+func (m *MockusersStorage) LinkIdentity(email string, identity domain.Identity) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "HasUser", email, password)
+	ret := m.ctrl.Call(m, "LinkIdentity", email, identity)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// HasUser indicates an expected call of HasUser.
-func (mr *MockusersStorageMockRecorder) HasUser(email, password interface{}) *gomock.Call {
+// LinkIdentity indicates an expected call of LinkIdentity.
+func (mr *MockusersStorageMockRecorder) LinkIdentity(email, identity interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HasUser", reflect.TypeOf((*MockusersStorage)(nil).HasUser), email, password)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LinkIdentity", reflect.TypeOf((*MockusersStorage)(nil).LinkIdentity), email, identity)
+}
+
+// LoadPolicy mocks base method.
+func (m *MockusersStorage) LoadPolicy(ctx context.Context) (map[rbac.Role][]rbac.Permission, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadPolicy", ctx)
+	ret0, _ := ret[0].(map[rbac.Role][]rbac.Permission)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoadPolicy indicates an expected call of LoadPolicy.
+func (mr *MockusersStorageMockRecorder) LoadPolicy(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadPolicy", reflect.TypeOf((*MockusersStorage)(nil).LoadPolicy), ctx)
+}
+
+// MarkEmailVerified mocks base method.
+func (m *MockusersStorage) MarkEmailVerified(email string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkEmailVerified", email)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkEmailVerified indicates an expected call of MarkEmailVerified.
+func (mr *MockusersStorageMockRecorder) MarkEmailVerified(email interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkEmailVerified", reflect.TypeOf((*MockusersStorage)(nil).MarkEmailVerified), email)
+}
+
+// RemoveAuditEventsBefore mocks base method.
+func (m *MockusersStorage) RemoveAuditEventsBefore(before time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveAuditEventsBefore", before)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RemoveAuditEventsBefore indicates an expected call of RemoveAuditEventsBefore.
+func (mr *MockusersStorageMockRecorder) RemoveAuditEventsBefore(before interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveAuditEventsBefore", reflect.TypeOf((*MockusersStorage)(nil).RemoveAuditEventsBefore), before)
+}
+
+// RemovePasskey mocks base method.
+func (m *MockusersStorage) RemovePasskey(email, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemovePasskey", email, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemovePasskey indicates an expected call of RemovePasskey.
+func (mr *MockusersStorageMockRecorder) RemovePasskey(email, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemovePasskey", reflect.TypeOf((*MockusersStorage)(nil).RemovePasskey), email, id)
 }
 
 // RemoveUser mocks base method.
@@ -254,3 +520,188 @@ func (mr *MockusersStorageMockRecorder) RemoveUser(email interface{}) *gomock.Ca
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveUser", reflect.TypeOf((*MockusersStorage)(nil).RemoveUser), email)
 }
+
+// ReportUnknownLogin mocks base method.
+func (m *MockusersStorage) ReportUnknownLogin(tokenHash, passwordHash string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReportUnknownLogin", tokenHash, passwordHash)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReportUnknownLogin indicates an expected call of ReportUnknownLogin.
+func (mr *MockusersStorageMockRecorder) ReportUnknownLogin(tokenHash, passwordHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReportUnknownLogin", reflect.TypeOf((*MockusersStorage)(nil).ReportUnknownLogin), tokenHash, passwordHash)
+}
+
+// ResetPassword mocks base method.
+func (m *MockusersStorage) ResetPassword(tokenHash, passwordHash string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetPassword", tokenHash, passwordHash)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResetPassword indicates an expected call of ResetPassword.
+func (mr *MockusersStorageMockRecorder) ResetPassword(tokenHash, passwordHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetPassword", reflect.TypeOf((*MockusersStorage)(nil).ResetPassword), tokenHash, passwordHash)
+}
+
+// SaveEmailVerificationToken mocks base method.
+func (m *MockusersStorage) SaveEmailVerificationToken(email, tokenHash string, expiresAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveEmailVerificationToken", email, tokenHash, expiresAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveEmailVerificationToken indicates an expected call of SaveEmailVerificationToken.
+func (mr *MockusersStorageMockRecorder) SaveEmailVerificationToken(email, tokenHash, expiresAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveEmailVerificationToken", reflect.TypeOf((*MockusersStorage)(nil).SaveEmailVerificationToken), email, tokenHash, expiresAt)
+}
+
+// SaveKnownDevice mocks base method.
+func (m *MockusersStorage) SaveKnownDevice(email string, device domain.KnownDevice) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveKnownDevice", email, device)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveKnownDevice indicates an expected call of SaveKnownDevice.
+func (mr *MockusersStorageMockRecorder) SaveKnownDevice(email, device interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveKnownDevice", reflect.TypeOf((*MockusersStorage)(nil).SaveKnownDevice), email, device)
+}
+
+// SaveMagicLinkToken mocks base method.
+func (m *MockusersStorage) SaveMagicLinkToken(email, tokenHash string, expiresAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveMagicLinkToken", email, tokenHash, expiresAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveMagicLinkToken indicates an expected call of SaveMagicLinkToken.
+func (mr *MockusersStorageMockRecorder) SaveMagicLinkToken(email, tokenHash, expiresAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveMagicLinkToken", reflect.TypeOf((*MockusersStorage)(nil).SaveMagicLinkToken), email, tokenHash, expiresAt)
+}
+
+// SavePasswordResetToken mocks base method.
+func (m *MockusersStorage) SavePasswordResetToken(email, tokenHash string, expiresAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SavePasswordResetToken", email, tokenHash, expiresAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SavePasswordResetToken indicates an expected call of SavePasswordResetToken.
+func (mr *MockusersStorageMockRecorder) SavePasswordResetToken(email, tokenHash, expiresAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SavePasswordResetToken", reflect.TypeOf((*MockusersStorage)(nil).SavePasswordResetToken), email, tokenHash, expiresAt)
+}
+
+// SaveTOTPSecret mocks base method.
+func (m *MockusersStorage) SaveTOTPSecret(email, secret string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveTOTPSecret", email, secret)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveTOTPSecret indicates an expected call of SaveTOTPSecret.
+func (mr *MockusersStorageMockRecorder) SaveTOTPSecret(email, secret interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveTOTPSecret", reflect.TypeOf((*MockusersStorage)(nil).SaveTOTPSecret), email, secret)
+}
+
+// SaveUnknownLoginToken mocks base method.
+func (m *MockusersStorage) SaveUnknownLoginToken(email, tokenHash, fingerprint string, expiresAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveUnknownLoginToken", email, tokenHash, fingerprint, expiresAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveUnknownLoginToken indicates an expected call of SaveUnknownLoginToken.
+func (mr *MockusersStorageMockRecorder) SaveUnknownLoginToken(email, tokenHash, fingerprint, expiresAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveUnknownLoginToken", reflect.TypeOf((*MockusersStorage)(nil).SaveUnknownLoginToken), email, tokenHash, fingerprint, expiresAt)
+}
+
+// UpdatePasskeySignCount mocks base method.
+func (m *MockusersStorage) UpdatePasskeySignCount(id string, signCount uint32) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePasskeySignCount", id, signCount)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePasskeySignCount indicates an expected call of UpdatePasskeySignCount.
+func (mr *MockusersStorageMockRecorder) UpdatePasskeySignCount(id, signCount interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePasskeySignCount", reflect.TypeOf((*MockusersStorage)(nil).UpdatePasskeySignCount), id, signCount)
+}
+
+// UpdatePasswordHash mocks base method.
+func (m *MockusersStorage) UpdatePasswordHash(email, passwordHash string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePasswordHash", email, passwordHash)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePasswordHash indicates an expected call of UpdatePasswordHash.
+func (mr *MockusersStorageMockRecorder) UpdatePasswordHash(email, passwordHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePasswordHash", reflect.TypeOf((*MockusersStorage)(nil).UpdatePasswordHash), email, passwordHash)
+}
+
+// UseRecoveryCode mocks base method.
+func (m *MockusersStorage) UseRecoveryCode(email, codeHash string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseRecoveryCode", email, codeHash)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UseRecoveryCode indicates an expected call of UseRecoveryCode.
+func (mr *MockusersStorageMockRecorder) UseRecoveryCode(email, codeHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseRecoveryCode", reflect.TypeOf((*MockusersStorage)(nil).UseRecoveryCode), email, codeHash)
+}
+
+// UseTOTPStep mocks base method.
+func (m *MockusersStorage) UseTOTPStep(email string, step int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseTOTPStep", email, step)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UseTOTPStep indicates an expected call of UseTOTPStep.
+func (mr *MockusersStorageMockRecorder) UseTOTPStep(email, step interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseTOTPStep", reflect.TypeOf((*MockusersStorage)(nil).UseTOTPStep), email, step)
+}
+
+// VerifyEmail mocks base method.
+func (m *MockusersStorage) VerifyEmail(tokenHash string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyEmail", tokenHash)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VerifyEmail indicates an expected call of VerifyEmail.
+func (mr *MockusersStorageMockRecorder) VerifyEmail(tokenHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyEmail", reflect.TypeOf((*MockusersStorage)(nil).VerifyEmail), tokenHash)
+}
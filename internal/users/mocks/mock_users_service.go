@@ -7,8 +7,10 @@ package mocks
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	domain "github.com/SanExpett/diploma/internal/domain"
+	rbac "github.com/SanExpett/diploma/internal/rbac"
 	gomock "github.com/golang/mock/gomock"
 )
 
@@ -35,6 +37,20 @@ func (m *MockUsersService) EXPECT() *MockUsersServiceMockRecorder {
 	return m.recorder
 }
 
+// AddPasskey mocks base method.
+func (m *MockUsersService) AddPasskey(ctx context.Context, email string, passkey domain.Passkey) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddPasskey", ctx, email, passkey)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddPasskey indicates an expected call of AddPasskey.
+func (mr *MockUsersServiceMockRecorder) AddPasskey(ctx, email, passkey interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddPasskey", reflect.TypeOf((*MockUsersService)(nil).AddPasskey), ctx, email, passkey)
+}
+
 // ChangeUserAvatarByUuid mocks base method.
 func (m *MockUsersService) ChangeUserAvatarByUuid(ctx context.Context, uuid, newAvatar string) (domain.User, error) {
 	m.ctrl.T.Helper()
@@ -110,6 +126,35 @@ func (mr *MockUsersServiceMockRecorder) ChangeUserPasswordByUuid(ctx, uuid, newP
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeUserPasswordByUuid", reflect.TypeOf((*MockUsersService)(nil).ChangeUserPasswordByUuid), ctx, uuid, newPassword)
 }
 
+// ConfirmTOTP mocks base method.
+func (m *MockUsersService) ConfirmTOTP(ctx context.Context, email, code string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConfirmTOTP", ctx, email, code)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConfirmTOTP indicates an expected call of ConfirmTOTP.
+func (mr *MockUsersServiceMockRecorder) ConfirmTOTP(ctx, email, code interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConfirmTOTP", reflect.TypeOf((*MockUsersService)(nil).ConfirmTOTP), ctx, email, code)
+}
+
+// ConsumeMagicLink mocks base method.
+func (m *MockUsersService) ConsumeMagicLink(ctx context.Context, email, tokenId string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumeMagicLink", ctx, email, tokenId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ConsumeMagicLink indicates an expected call of ConsumeMagicLink.
+func (mr *MockUsersServiceMockRecorder) ConsumeMagicLink(ctx, email, tokenId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeMagicLink", reflect.TypeOf((*MockUsersService)(nil).ConsumeMagicLink), ctx, email, tokenId)
+}
+
 // CreateUser mocks base method.
 func (m *MockUsersService) CreateUser(ctx context.Context, user domain.UserSignUp) error {
 	m.ctrl.T.Helper()
@@ -124,6 +169,82 @@ func (mr *MockUsersServiceMockRecorder) CreateUser(ctx, user interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockUsersService)(nil).CreateUser), ctx, user)
 }
 
+// DisableTOTP mocks base method.
+func (m *MockUsersService) DisableTOTP(ctx context.Context, email, code string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisableTOTP", ctx, email, code)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DisableTOTP indicates an expected call of DisableTOTP.
+func (mr *MockUsersServiceMockRecorder) DisableTOTP(ctx, email, code interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisableTOTP", reflect.TypeOf((*MockUsersService)(nil).DisableTOTP), ctx, email, code)
+}
+
+// EnrollTOTP mocks base method.
+func (m *MockUsersService) EnrollTOTP(ctx context.Context, email string) (string, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnrollTOTP", ctx, email)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// EnrollTOTP indicates an expected call of EnrollTOTP.
+func (mr *MockUsersServiceMockRecorder) EnrollTOTP(ctx, email interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnrollTOTP", reflect.TypeOf((*MockUsersService)(nil).EnrollTOTP), ctx, email)
+}
+
+// GetPasskey mocks base method.
+func (m *MockUsersService) GetPasskey(ctx context.Context, id string) (string, domain.Passkey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPasskey", ctx, id)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(domain.Passkey)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetPasskey indicates an expected call of GetPasskey.
+func (mr *MockUsersServiceMockRecorder) GetPasskey(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPasskey", reflect.TypeOf((*MockUsersService)(nil).GetPasskey), ctx, id)
+}
+
+// GetPasskeys mocks base method.
+func (m *MockUsersService) GetPasskeys(ctx context.Context, email string) ([]domain.Passkey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPasskeys", ctx, email)
+	ret0, _ := ret[0].([]domain.Passkey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPasskeys indicates an expected call of GetPasskeys.
+func (mr *MockUsersServiceMockRecorder) GetPasskeys(ctx, email interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPasskeys", reflect.TypeOf((*MockUsersService)(nil).GetPasskeys), ctx, email)
+}
+
+// GetRolePermissions mocks base method.
+func (m *MockUsersService) GetRolePermissions(ctx context.Context) (map[rbac.Role][]rbac.Permission, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRolePermissions", ctx)
+	ret0, _ := ret[0].(map[rbac.Role][]rbac.Permission)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRolePermissions indicates an expected call of GetRolePermissions.
+func (mr *MockUsersServiceMockRecorder) GetRolePermissions(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRolePermissions", reflect.TypeOf((*MockUsersService)(nil).GetRolePermissions), ctx)
+}
+
 // GetSubscription mocks base method.
 func (m *MockUsersService) GetSubscription(ctx context.Context, uuid string) (domain.Subscription, error) {
 	m.ctrl.T.Helper()
@@ -228,6 +349,36 @@ func (mr *MockUsersServiceMockRecorder) HasUser(ctx, email, password interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HasUser", reflect.TypeOf((*MockUsersService)(nil).HasUser), ctx, email, password)
 }
 
+// ListAuditEvents mocks base method.
+func (m *MockUsersService) ListAuditEvents(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAuditEvents", ctx, filter)
+	ret0, _ := ret[0].([]domain.AuditEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAuditEvents indicates an expected call of ListAuditEvents.
+func (mr *MockUsersServiceMockRecorder) ListAuditEvents(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAuditEvents", reflect.TypeOf((*MockUsersService)(nil).ListAuditEvents), ctx, filter)
+}
+
+// LoginWithIdentity mocks base method.
+func (m *MockUsersService) LoginWithIdentity(ctx context.Context, identity domain.Identity, linkLogin string) (domain.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoginWithIdentity", ctx, identity, linkLogin)
+	ret0, _ := ret[0].(domain.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoginWithIdentity indicates an expected call of LoginWithIdentity.
+func (mr *MockUsersServiceMockRecorder) LoginWithIdentity(ctx, identity, linkLogin interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoginWithIdentity", reflect.TypeOf((*MockUsersService)(nil).LoginWithIdentity), ctx, identity, linkLogin)
+}
+
 // PaySubscription mocks base method.
 func (m *MockUsersService) PaySubscription(ctx context.Context, uuid, subId string) (string, error) {
 	m.ctrl.T.Helper()
@@ -243,6 +394,49 @@ func (mr *MockUsersServiceMockRecorder) PaySubscription(ctx, uuid, subId interfa
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PaySubscription", reflect.TypeOf((*MockUsersService)(nil).PaySubscription), ctx, uuid, subId)
 }
 
+// RecordAuditEvent mocks base method.
+func (m *MockUsersService) RecordAuditEvent(ctx context.Context, event domain.AuditEvent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordAuditEvent", ctx, event)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecordAuditEvent indicates an expected call of RecordAuditEvent.
+func (mr *MockUsersServiceMockRecorder) RecordAuditEvent(ctx, event interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordAuditEvent", reflect.TypeOf((*MockUsersService)(nil).RecordAuditEvent), ctx, event)
+}
+
+// RegisterLoginDevice mocks base method.
+func (m *MockUsersService) RegisterLoginDevice(ctx context.Context, login, userAgent, ip string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RegisterLoginDevice", ctx, login, userAgent, ip)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RegisterLoginDevice indicates an expected call of RegisterLoginDevice.
+func (mr *MockUsersServiceMockRecorder) RegisterLoginDevice(ctx, login, userAgent, ip interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterLoginDevice", reflect.TypeOf((*MockUsersService)(nil).RegisterLoginDevice), ctx, login, userAgent, ip)
+}
+
+// RemovePasskey mocks base method.
+func (m *MockUsersService) RemovePasskey(ctx context.Context, email, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemovePasskey", ctx, email, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemovePasskey indicates an expected call of RemovePasskey.
+func (mr *MockUsersServiceMockRecorder) RemovePasskey(ctx, email, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemovePasskey", reflect.TypeOf((*MockUsersService)(nil).RemovePasskey), ctx, email, id)
+}
+
 // RemoveUser mocks base method.
 func (m *MockUsersService) RemoveUser(ctx context.Context, email string) error {
 	m.ctrl.T.Helper()
@@ -256,3 +450,118 @@ func (mr *MockUsersServiceMockRecorder) RemoveUser(ctx, email interface{}) *gomo
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveUser", reflect.TypeOf((*MockUsersService)(nil).RemoveUser), ctx, email)
 }
+
+// ReportUnknownLogin mocks base method.
+func (m *MockUsersService) ReportUnknownLogin(ctx context.Context, token string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReportUnknownLogin", ctx, token)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReportUnknownLogin indicates an expected call of ReportUnknownLogin.
+func (mr *MockUsersServiceMockRecorder) ReportUnknownLogin(ctx, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReportUnknownLogin", reflect.TypeOf((*MockUsersService)(nil).ReportUnknownLogin), ctx, token)
+}
+
+// RequestPasswordReset mocks base method.
+func (m *MockUsersService) RequestPasswordReset(ctx context.Context, email string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RequestPasswordReset", ctx, email)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RequestPasswordReset indicates an expected call of RequestPasswordReset.
+func (mr *MockUsersServiceMockRecorder) RequestPasswordReset(ctx, email interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RequestPasswordReset", reflect.TypeOf((*MockUsersService)(nil).RequestPasswordReset), ctx, email)
+}
+
+// ResendEmailVerification mocks base method.
+func (m *MockUsersService) ResendEmailVerification(ctx context.Context, email string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResendEmailVerification", ctx, email)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResendEmailVerification indicates an expected call of ResendEmailVerification.
+func (mr *MockUsersServiceMockRecorder) ResendEmailVerification(ctx, email interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResendEmailVerification", reflect.TypeOf((*MockUsersService)(nil).ResendEmailVerification), ctx, email)
+}
+
+// ResetPassword mocks base method.
+func (m *MockUsersService) ResetPassword(ctx context.Context, token, newPassword string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetPassword", ctx, token, newPassword)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResetPassword indicates an expected call of ResetPassword.
+func (mr *MockUsersServiceMockRecorder) ResetPassword(ctx, token, newPassword interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetPassword", reflect.TypeOf((*MockUsersService)(nil).ResetPassword), ctx, token, newPassword)
+}
+
+// SendMagicLink mocks base method.
+func (m *MockUsersService) SendMagicLink(ctx context.Context, email, link, tokenId string, expiresAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendMagicLink", ctx, email, link, tokenId, expiresAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendMagicLink indicates an expected call of SendMagicLink.
+func (mr *MockUsersServiceMockRecorder) SendMagicLink(ctx, email, link, tokenId, expiresAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendMagicLink", reflect.TypeOf((*MockUsersService)(nil).SendMagicLink), ctx, email, link, tokenId, expiresAt)
+}
+
+// UpdatePasskeySignCount mocks base method.
+func (m *MockUsersService) UpdatePasskeySignCount(ctx context.Context, id string, signCount uint32) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePasskeySignCount", ctx, id, signCount)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePasskeySignCount indicates an expected call of UpdatePasskeySignCount.
+func (mr *MockUsersServiceMockRecorder) UpdatePasskeySignCount(ctx, id, signCount interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePasskeySignCount", reflect.TypeOf((*MockUsersService)(nil).UpdatePasskeySignCount), ctx, id, signCount)
+}
+
+// VerifyEmail mocks base method.
+func (m *MockUsersService) VerifyEmail(ctx context.Context, token string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyEmail", ctx, token)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VerifyEmail indicates an expected call of VerifyEmail.
+func (mr *MockUsersServiceMockRecorder) VerifyEmail(ctx, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyEmail", reflect.TypeOf((*MockUsersService)(nil).VerifyEmail), ctx, token)
+}
+
+// VerifyTOTP mocks base method.
+func (m *MockUsersService) VerifyTOTP(ctx context.Context, email, code string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyTOTP", ctx, email, code)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// VerifyTOTP indicates an expected call of VerifyTOTP.
+func (mr *MockUsersServiceMockRecorder) VerifyTOTP(ctx, email, code interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyTOTP", reflect.TypeOf((*MockUsersService)(nil).VerifyTOTP), ctx, email, code)
+}
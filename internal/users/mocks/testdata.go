@@ -16,6 +16,7 @@ func NewMockUser() domain.User {
 		IsAdmin:      true,
 		RegisteredAt: time.Now(),
 		Birthday:     time.Now(),
+		Roles:        []string{"admin"},
 	}
 }
 
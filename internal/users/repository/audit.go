@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/SanExpett/diploma/internal/domain"
+	myerrors "github.com/SanExpett/diploma/internal/errors"
+)
+
+const insertAuditEvent = `
+		INSERT INTO audit_event (occurred_at, action, actor, target, ip, user_agent, request_id, details)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8);`
+
+const getAuditEvents = `
+		SELECT id, occurred_at, action, actor, target, ip, user_agent, request_id, details
+		FROM audit_event`
+
+const deleteAuditEventsBefore = `
+		DELETE FROM audit_event
+		WHERE occurred_at < $1;`
+
+// AddAuditEvent дописывает событие в журнал
+func (storage *UsersStorage) AddAuditEvent(event domain.AuditEvent) error {
+	_, err := storage.pool.Exec(context.Background(), insertAuditEvent, event.OccurredAt, event.Action,
+		event.Actor, event.Target, event.Ip, event.UserAgent, event.RequestId, event.Details)
+	if err != nil {
+		return fmt.Errorf("failed to add audit event: %w: %w", err,
+			myerrors.ErrFailInExec)
+	}
+
+	return nil
+}
+
+// GetAuditEvents возвращает не больше filter.Limit событий, подходящих под фильтр, от новых к старым
+func (storage *UsersStorage) GetAuditEvents(filter domain.AuditFilter) ([]domain.AuditEvent, error) {
+	conditions := make([]string, 0, 6)
+	args := make([]any, 0, 7)
+	addCondition := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.Actor != "" {
+		addCondition("actor = $%d", filter.Actor)
+	}
+	if filter.Target != "" {
+		addCondition("target = $%d", filter.Target)
+	}
+	if filter.Action != "" {
+		addCondition("action = $%d", filter.Action)
+	}
+	if !filter.From.IsZero() {
+		addCondition("occurred_at >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		addCondition("occurred_at < $%d", filter.To)
+	}
+	if filter.Before > 0 {
+		addCondition("id < $%d", filter.Before)
+	}
+
+	query := getAuditEvents
+	if len(conditions) > 0 {
+		query += "\n\t\tWHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf("\n\t\tORDER BY id DESC\n\t\tLIMIT $%d;", len(args))
+
+	rows, err := storage.pool.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit events: %w: %w", err,
+			myerrors.ErrFailInQuery)
+	}
+	defer rows.Close()
+
+	events := make([]domain.AuditEvent, 0)
+	for rows.Next() {
+		event := domain.AuditEvent{}
+		err = rows.Scan(
+			&event.Id,
+			&event.OccurredAt,
+			&event.Action,
+			&event.Actor,
+			&event.Target,
+			&event.Ip,
+			&event.UserAgent,
+			&event.RequestId,
+			&event.Details)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan audit event: %w: %w", err,
+				myerrors.ErrFailInQuery)
+		}
+		events = append(events, event)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get audit events: %w: %w", err,
+			myerrors.ErrFailInQuery)
+	}
+
+	return events, nil
+}
+
+// RemoveAuditEventsBefore удаляет события старше before и возвращает их количество
+func (storage *UsersStorage) RemoveAuditEventsBefore(before time.Time) (int64, error) {
+	tag, err := storage.pool.Exec(context.Background(), deleteAuditEventsBefore, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to remove audit events: %w: %w", err,
+			myerrors.ErrFailInExec)
+	}
+
+	return tag.RowsAffected(), nil
+}
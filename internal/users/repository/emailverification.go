@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/SanExpett/diploma/internal/domain"
+	myerrors "github.com/SanExpett/diploma/internal/errors"
+)
+
+const getEmailVerificationStatus = `
+		SELECT users.email_verified_at IS NOT NULL,
+			COUNT(token.token_hash) FILTER (WHERE token.created_at > $2),
+			COALESCE(MAX(token.created_at), TO_TIMESTAMP(0))
+		FROM users
+		LEFT JOIN email_verification_token token ON token.user_id = users.id
+		WHERE users.email = $1
+		GROUP BY users.id;`
+
+const insertEmailVerificationToken = `
+		INSERT INTO email_verification_token (token_hash, user_id, expires_at)
+		SELECT $1, id, $3
+		FROM users
+		WHERE email = $2 AND email_verified_at IS NULL;`
+
+const deleteExpiredEmailVerificationTokens = `
+		DELETE FROM email_verification_token
+		WHERE expires_at <= NOW();`
+
+const consumeEmailVerificationToken = `
+		DELETE FROM email_verification_token
+		WHERE token_hash = $1 AND expires_at > NOW()
+		RETURNING user_id;`
+
+const putEmailVerifiedById = `
+		UPDATE users
+		SET email_verified_at = COALESCE(email_verified_at, NOW())
+		WHERE id = $1
+		RETURNING email;`
+
+const deleteUserEmailVerificationTokens = `
+		DELETE FROM email_verification_token
+		WHERE user_id = $1;`
+
+// GetEmailVerificationStatus возвращает, подтверждена ли почта, и сколько писем с подтверждением
+// было отправлено после since
+func (storage *UsersStorage) GetEmailVerificationStatus(email string,
+	since time.Time) (domain.EmailVerificationStatus, error) {
+	var status domain.EmailVerificationStatus
+	err := storage.pool.QueryRow(context.Background(), getEmailVerificationStatus, email, since).Scan(
+		&status.Verified,
+		&status.SentRecently,
+		&status.LastSentAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.EmailVerificationStatus{}, myerrors.ErrNoSuchUser
+	}
+	if err != nil {
+		return domain.EmailVerificationStatus{}, fmt.Errorf("failed to get email verification status: %w: %w",
+			err, myerrors.ErrFailInQueryRow)
+	}
+
+	return status, nil
+}
+
+// SaveEmailVerificationToken сохраняет хеш токена подтверждения почты. Если пользователя с таким email
+// нет или почта уже подтверждена, возвращает ErrNoSuchUser
+func (storage *UsersStorage) SaveEmailVerificationToken(email, tokenHash string, expiresAt time.Time) error {
+	_, err := storage.pool.Exec(context.Background(), deleteExpiredEmailVerificationTokens)
+	if err != nil {
+		return fmt.Errorf("failed to delete expired verification tokens: %w: %w", err,
+			myerrors.ErrFailInExec)
+	}
+
+	tag, err := storage.pool.Exec(context.Background(), insertEmailVerificationToken, tokenHash, email, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to save verification token: %w: %w", err,
+			myerrors.ErrFailInExec)
+	}
+	if tag.RowsAffected() == 0 {
+		return myerrors.ErrNoSuchUser
+	}
+
+	return nil
+}
+
+// VerifyEmail по токену из письма отмечает почту подтвержденной и возвращает email пользователя.
+// Токен и остальные токены пользователя удаляются в той же транзакции
+func (storage *UsersStorage) VerifyEmail(tokenHash string) (string, error) {
+	tx, err := storage.pool.BeginTx(context.Background(), pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction to verify email: %w: %w", err,
+			myerrors.ErrFailedToBeginTransaction)
+	}
+	// после Commit откат ничего не делает
+	defer func() {
+		_ = tx.Rollback(context.Background())
+	}()
+
+	var userId int
+	err = tx.QueryRow(context.Background(), consumeEmailVerificationToken, tokenHash).Scan(&userId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", myerrors.ErrInvalidVerificationToken
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to consume verification token: %w: %w", err,
+			myerrors.ErrFailInQueryRow)
+	}
+
+	var email string
+	err = tx.QueryRow(context.Background(), putEmailVerifiedById, userId).Scan(&email)
+	if err != nil {
+		return "", fmt.Errorf("failed to mark email as verified: %w: %w", err,
+			myerrors.ErrFailInQueryRow)
+	}
+
+	_, err = tx.Exec(context.Background(), deleteUserEmailVerificationTokens, userId)
+	if err != nil {
+		return "", fmt.Errorf("failed to delete verification tokens: %w: %w", err,
+			myerrors.ErrFailInExec)
+	}
+
+	err = tx.Commit(context.Background())
+	if err != nil {
+		return "", fmt.Errorf("failed to commit transaction: %w: %w", err,
+			myerrors.ErrFailedToCommitTransaction)
+	}
+
+	return email, nil
+}
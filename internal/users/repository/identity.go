@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/SanExpett/diploma/internal/domain"
+	myerrors "github.com/SanExpett/diploma/internal/errors"
+)
+
+const getUserEmailByIdentity = `
+		SELECT users.email
+		FROM user_identities
+		JOIN users ON users.id = user_identities.user_id
+		WHERE user_identities.provider = $1 AND user_identities.subject = $2;`
+
+// повторная привязка того же аккаунта к тому же пользователю обновляет почту, привязка к другому
+// пользователю не затрагивает ни одной строки
+const linkIdentity = `
+		INSERT INTO user_identities (provider, subject, user_id, email)
+		SELECT $2, $3, id, $4
+		FROM users
+		WHERE email = $1
+		ON CONFLICT (provider, subject) DO UPDATE
+		SET email = EXCLUDED.email
+		WHERE user_identities.user_id = EXCLUDED.user_id;`
+
+const insertUserWithIdentity = `
+		INSERT INTO users (email, name, password, email_verified_at)
+		VALUES ($1, $2, $3, CASE WHEN $4 THEN NOW() END)
+		ON CONFLICT (email) DO NOTHING
+		RETURNING id;`
+
+const insertIdentity = `
+		INSERT INTO user_identities (provider, subject, user_id, email)
+		VALUES ($1, $2, $3, $4);`
+
+const markEmailVerified = `
+		UPDATE users
+		SET email_verified_at = NOW()
+		WHERE email = $1 AND email_verified_at IS NULL;`
+
+// GetUserByIdentity возвращает пользователя, к которому привязан внешний аккаунт, или ErrNoSuchUser
+func (storage *UsersStorage) GetUserByIdentity(provider, subject string) (domain.User, error) {
+	var email string
+	err := storage.pool.QueryRow(context.Background(), getUserEmailByIdentity, provider, subject).Scan(&email)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.User{}, myerrors.ErrNoSuchUser
+	}
+	if err != nil {
+		return domain.User{}, fmt.Errorf("failed to get user by identity: %w: %w", err,
+			myerrors.ErrFailInQueryRow)
+	}
+
+	return storage.GetUser(email)
+}
+
+// LinkIdentity привязывает внешний аккаунт к пользователю. Если аккаунт уже привязан к другому пользователю
+// или пользователя нет, возвращает ErrIdentityAlreadyLinked
+func (storage *UsersStorage) LinkIdentity(email string, identity domain.Identity) error {
+	tag, err := storage.pool.Exec(context.Background(), linkIdentity, email, identity.Provider, identity.Subject,
+		identity.Email)
+	if err != nil {
+		return fmt.Errorf("failed to link identity: %w: %w", err,
+			myerrors.ErrFailInExec)
+	}
+	if tag.RowsAffected() == 0 {
+		return myerrors.ErrIdentityAlreadyLinked
+	}
+
+	return nil
+}
+
+// CreateUserWithIdentity создает пользователя сразу с привязанным внешним аккаунтом. Почта считается
+// подтвержденной, если ее подтвердил провайдер. Если почта занята, возвращает ErrUserAlreadyExists
+func (storage *UsersStorage) CreateUserWithIdentity(user domain.UserSignUp, identity domain.Identity) error {
+	tx, err := storage.pool.BeginTx(context.Background(), pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction to create user with identity: %w: %w", err,
+			myerrors.ErrFailedToBeginTransaction)
+	}
+	// после Commit откат ничего не делает
+	defer func() {
+		_ = tx.Rollback(context.Background())
+	}()
+
+	var userId int
+	err = tx.QueryRow(context.Background(), insertUserWithIdentity, user.Email, user.Name, user.Password,
+		identity.EmailVerified).Scan(&userId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return myerrors.ErrUserAlreadyExists
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create user with identity: %w: %w", err,
+			myerrors.ErrFailInQueryRow)
+	}
+
+	_, err = tx.Exec(context.Background(), insertIdentity, identity.Provider, identity.Subject, userId,
+		identity.Email)
+	if err != nil {
+		return fmt.Errorf("failed to save identity: %w: %w", err,
+			myerrors.ErrFailInExec)
+	}
+
+	err = tx.Commit(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %w: %w", err,
+			myerrors.ErrFailedToCommitTransaction)
+	}
+
+	return nil
+}
+
+// MarkEmailVerified подтверждает почту пользователя, например когда ее владение подтвердил провайдер
+func (storage *UsersStorage) MarkEmailVerified(email string) error {
+	_, err := storage.pool.Exec(context.Background(), markEmailVerified, email)
+	if err != nil {
+		return fmt.Errorf("failed to mark email verified: %w: %w", err,
+			myerrors.ErrFailInExec)
+	}
+
+	return nil
+}
@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/SanExpett/diploma/internal/domain"
+	myerrors "github.com/SanExpett/diploma/internal/errors"
+)
+
+const getKnownDevices = `
+		SELECT known_device.fingerprint, known_device.user_agent, known_device.ip,
+			known_device.first_seen_at, known_device.last_seen_at
+		FROM known_device
+		JOIN users ON users.id = known_device.user_id
+		WHERE users.email = $1
+		ORDER BY known_device.last_seen_at DESC;`
+
+const upsertKnownDevice = `
+		INSERT INTO known_device (user_id, fingerprint, user_agent, ip, first_seen_at, last_seen_at)
+		SELECT id, $2, $3, $4, $5, $5
+		FROM users
+		WHERE email = $1
+		ON CONFLICT (user_id, fingerprint) DO UPDATE
+		SET user_agent = EXCLUDED.user_agent, ip = EXCLUDED.ip, last_seen_at = EXCLUDED.last_seen_at;`
+
+const insertUnknownLoginToken = `
+		INSERT INTO unknown_login_token (token_hash, user_id, fingerprint, expires_at)
+		SELECT $1, id, $3, $4
+		FROM users
+		WHERE email = $2;`
+
+const deleteExpiredUnknownLoginTokens = `
+		DELETE FROM unknown_login_token
+		WHERE expires_at <= NOW();`
+
+const consumeUnknownLoginToken = `
+		DELETE FROM unknown_login_token
+		WHERE token_hash = $1 AND expires_at > NOW()
+		RETURNING user_id, fingerprint;`
+
+const deleteKnownDevice = `
+		DELETE FROM known_device
+		WHERE user_id = $1 AND fingerprint = $2;`
+
+const deleteUserUnknownLoginTokens = `
+		DELETE FROM unknown_login_token
+		WHERE user_id = $1;`
+
+// GetKnownDevices возвращает устройства пользователя, начиная с последнего использованного
+func (storage *UsersStorage) GetKnownDevices(email string) ([]domain.KnownDevice, error) {
+	rows, err := storage.pool.Query(context.Background(), getKnownDevices, email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get known devices: %w: %w", err,
+			myerrors.ErrFailInQuery)
+	}
+	defer rows.Close()
+
+	devices := make([]domain.KnownDevice, 0)
+	for rows.Next() {
+		device := domain.KnownDevice{}
+		err = rows.Scan(
+			&device.Fingerprint,
+			&device.UserAgent,
+			&device.Ip,
+			&device.FirstSeenAt,
+			&device.LastSeenAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan known device: %w: %w", err,
+				myerrors.ErrFailInQuery)
+		}
+		devices = append(devices, device)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get known devices: %w: %w", err,
+			myerrors.ErrFailInQuery)
+	}
+
+	return devices, nil
+}
+
+// SaveKnownDevice запоминает устройство пользователя или обновляет время последнего входа с него.
+// Если пользователя с таким email нет, возвращает ErrNoSuchUser
+func (storage *UsersStorage) SaveKnownDevice(email string, device domain.KnownDevice) error {
+	tag, err := storage.pool.Exec(context.Background(), upsertKnownDevice, email, device.Fingerprint,
+		device.UserAgent, device.Ip, device.LastSeenAt)
+	if err != nil {
+		return fmt.Errorf("failed to save known device: %w: %w", err,
+			myerrors.ErrFailInExec)
+	}
+	if tag.RowsAffected() == 0 {
+		return myerrors.ErrNoSuchUser
+	}
+
+	return nil
+}
+
+// SaveUnknownLoginToken сохраняет хеш токена из письма о входе с нового устройства fingerprint
+func (storage *UsersStorage) SaveUnknownLoginToken(email, tokenHash, fingerprint string, expiresAt time.Time) error {
+	_, err := storage.pool.Exec(context.Background(), deleteExpiredUnknownLoginTokens)
+	if err != nil {
+		return fmt.Errorf("failed to delete expired unknown login tokens: %w: %w", err,
+			myerrors.ErrFailInExec)
+	}
+
+	tag, err := storage.pool.Exec(context.Background(), insertUnknownLoginToken, tokenHash, email, fingerprint,
+		expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to save unknown login token: %w: %w", err,
+			myerrors.ErrFailInExec)
+	}
+	if tag.RowsAffected() == 0 {
+		return myerrors.ErrNoSuchUser
+	}
+
+	return nil
+}
+
+// ReportUnknownLogin по токену из письма забывает устройство, с которого был вход, заменяет пароль
+// на passwordHash и возвращает email пользователя. Все действия выполняются в одной транзакции,
+// поэтому токеном можно воспользоваться только один раз
+func (storage *UsersStorage) ReportUnknownLogin(tokenHash, passwordHash string) (string, error) {
+	tx, err := storage.pool.BeginTx(context.Background(), pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction to report unknown login: %w: %w", err,
+			myerrors.ErrFailedToBeginTransaction)
+	}
+	// после Commit откат ничего не делает
+	defer func() {
+		_ = tx.Rollback(context.Background())
+	}()
+
+	var (
+		userId      int
+		fingerprint string
+	)
+	err = tx.QueryRow(context.Background(), consumeUnknownLoginToken, tokenHash).Scan(&userId, &fingerprint)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", myerrors.ErrInvalidUnknownLoginToken
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to consume unknown login token: %w: %w", err,
+			myerrors.ErrFailInQueryRow)
+	}
+
+	_, err = tx.Exec(context.Background(), deleteKnownDevice, userId, fingerprint)
+	if err != nil {
+		return "", fmt.Errorf("failed to delete known device: %w: %w", err,
+			myerrors.ErrFailInExec)
+	}
+
+	var email string
+	err = tx.QueryRow(context.Background(), putNewUserPasswordById, passwordHash, userId).Scan(&email)
+	if err != nil {
+		return "", fmt.Errorf("failed to update password: %w: %w", err,
+			myerrors.ErrFailInQueryRow)
+	}
+
+	// остальные ссылки из писем о новых устройствах больше не нужны: пароль уже сброшен
+	_, err = tx.Exec(context.Background(), deleteUserUnknownLoginTokens, userId)
+	if err != nil {
+		return "", fmt.Errorf("failed to delete unknown login tokens: %w: %w", err,
+			myerrors.ErrFailInExec)
+	}
+
+	err = tx.Commit(context.Background())
+	if err != nil {
+		return "", fmt.Errorf("failed to commit transaction: %w: %w", err,
+			myerrors.ErrFailedToCommitTransaction)
+	}
+
+	return email, nil
+}
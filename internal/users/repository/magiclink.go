@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	myerrors "github.com/SanExpett/diploma/internal/errors"
+)
+
+const upsertMagicLinkToken = `
+		INSERT INTO magic_link_token (user_id, token_hash, expires_at)
+		SELECT id, $1, $3
+		FROM users
+		WHERE email = $2
+		ON CONFLICT (user_id) DO UPDATE
+		SET token_hash = EXCLUDED.token_hash, expires_at = EXCLUDED.expires_at;`
+
+const consumeMagicLinkToken = `
+		DELETE FROM magic_link_token
+		USING users
+		WHERE magic_link_token.user_id = users.id AND users.email = $1
+			AND magic_link_token.token_hash = $2 AND magic_link_token.expires_at > NOW();`
+
+// SaveMagicLinkToken сохраняет хеш идентификатора ссылки для входа вместо выданной раньше, поэтому
+// действует только последняя ссылка. Если пользователя с таким email нет, возвращает ErrNoSuchUser
+func (storage *UsersStorage) SaveMagicLinkToken(email, tokenHash string, expiresAt time.Time) error {
+	tag, err := storage.pool.Exec(context.Background(), upsertMagicLinkToken, tokenHash, email, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to save magic link token: %w: %w", err,
+			myerrors.ErrFailInExec)
+	}
+	if tag.RowsAffected() == 0 {
+		return myerrors.ErrNoSuchUser
+	}
+
+	return nil
+}
+
+// ConsumeMagicLinkToken удаляет ссылку для входа пользователя, если она совпадает с tokenHash и не истекла.
+// Удаление и проверка выполняются одним запросом, поэтому ссылкой можно воспользоваться только один раз
+func (storage *UsersStorage) ConsumeMagicLinkToken(email, tokenHash string) error {
+	tag, err := storage.pool.Exec(context.Background(), consumeMagicLinkToken, email, tokenHash)
+	if err != nil {
+		return fmt.Errorf("failed to consume magic link token: %w: %w", err,
+			myerrors.ErrFailInExec)
+	}
+	if tag.RowsAffected() == 0 {
+		return myerrors.ErrInvalidMagicLink
+	}
+
+	return nil
+}
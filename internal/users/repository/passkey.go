@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/SanExpett/diploma/internal/domain"
+	myerrors "github.com/SanExpett/diploma/internal/errors"
+)
+
+const insertPasskey = `
+		INSERT INTO webauthn_credentials (id, user_id, public_key, sign_count, transports, name)
+		SELECT $1, id, $3, $4, $5, $6
+		FROM users
+		WHERE email = $2
+		ON CONFLICT (id) DO NOTHING;`
+
+const getUserPasskeys = `
+		SELECT credential.id, credential.public_key, credential.sign_count, credential.transports, credential.name,
+			credential.created_at, credential.last_used_at
+		FROM webauthn_credentials credential
+		JOIN users ON users.id = credential.user_id
+		WHERE users.email = $1
+		ORDER BY credential.created_at;`
+
+const getPasskeyById = `
+		SELECT users.email, credential.id, credential.public_key, credential.sign_count, credential.transports,
+			credential.name, credential.created_at, credential.last_used_at
+		FROM webauthn_credentials credential
+		JOIN users ON users.id = credential.user_id
+		WHERE credential.id = $1;`
+
+// счетчик обновляется только вперед, поэтому из двух одновременных входов с одним значением пройдет один.
+// Аутентификаторы без счетчика всегда присылают ноль
+const putPasskeySignCount = `
+		UPDATE webauthn_credentials
+		SET sign_count = $2, last_used_at = NOW()
+		WHERE id = $1 AND (sign_count < $2 OR (sign_count = 0 AND $2 = 0));`
+
+const deletePasskey = `
+		DELETE FROM webauthn_credentials credential
+		USING users
+		WHERE credential.user_id = users.id AND users.email = $1 AND credential.id = $2;`
+
+// AddPasskey сохраняет passkey пользователя. Если ключ с таким id уже зарегистрирован или пользователя нет,
+// возвращает ErrPasskeyAlreadyRegistered
+func (storage *UsersStorage) AddPasskey(email string, passkey domain.Passkey) error {
+	transports := passkey.Transports
+	if transports == nil {
+		transports = []string{}
+	}
+	tag, err := storage.pool.Exec(context.Background(), insertPasskey, passkey.Id, email, passkey.PublicKey,
+		int64(passkey.SignCount), transports, passkey.Name)
+	if err != nil {
+		return fmt.Errorf("failed to add passkey: %w: %w", err,
+			myerrors.ErrFailInExec)
+	}
+	if tag.RowsAffected() == 0 {
+		return myerrors.ErrPasskeyAlreadyRegistered
+	}
+
+	return nil
+}
+
+// GetPasskeys возвращает passkey пользователя в порядке регистрации
+func (storage *UsersStorage) GetPasskeys(email string) ([]domain.Passkey, error) {
+	rows, err := storage.pool.Query(context.Background(), getUserPasskeys, email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get passkeys: %w: %w", err,
+			myerrors.ErrFailInQuery)
+	}
+	defer rows.Close()
+
+	passkeys := make([]domain.Passkey, 0)
+	for rows.Next() {
+		passkey, err := scanPasskey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan passkey: %w: %w", err,
+				myerrors.ErrFailInQuery)
+		}
+		passkeys = append(passkeys, passkey)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get passkeys: %w: %w", err,
+			myerrors.ErrFailInQuery)
+	}
+
+	return passkeys, nil
+}
+
+// GetPasskey возвращает passkey по id вместе с email владельца или ErrNoSuchPasskey
+func (storage *UsersStorage) GetPasskey(id string) (string, domain.Passkey, error) {
+	var email string
+	var signCount int64
+	var lastUsedAt *time.Time
+	passkey := domain.Passkey{}
+	err := storage.pool.QueryRow(context.Background(), getPasskeyById, id).Scan(
+		&email,
+		&passkey.Id,
+		&passkey.PublicKey,
+		&signCount,
+		&passkey.Transports,
+		&passkey.Name,
+		&passkey.CreatedAt,
+		&lastUsedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", domain.Passkey{}, myerrors.ErrNoSuchPasskey
+	}
+	if err != nil {
+		return "", domain.Passkey{}, fmt.Errorf("failed to get passkey: %w: %w", err,
+			myerrors.ErrFailInQueryRow)
+	}
+	passkey.SignCount = uint32(signCount)
+	if lastUsedAt != nil {
+		passkey.LastUsedAt = *lastUsedAt
+	}
+
+	return email, passkey, nil
+}
+
+// UpdatePasskeySignCount запоминает счетчик подписей после входа. Если счетчик не вырос, возвращает
+// ErrPasskeySignCountRegression: ответ аутентификатора повторен или ключ скопирован
+func (storage *UsersStorage) UpdatePasskeySignCount(id string, signCount uint32) error {
+	tag, err := storage.pool.Exec(context.Background(), putPasskeySignCount, id, int64(signCount))
+	if err != nil {
+		return fmt.Errorf("failed to update passkey sign count: %w: %w", err,
+			myerrors.ErrFailInExec)
+	}
+	if tag.RowsAffected() == 0 {
+		return myerrors.ErrPasskeySignCountRegression
+	}
+
+	return nil
+}
+
+// RemovePasskey удаляет passkey пользователя. Чужой или несуществующий ключ дает ErrNoSuchPasskey
+func (storage *UsersStorage) RemovePasskey(email, id string) error {
+	tag, err := storage.pool.Exec(context.Background(), deletePasskey, email, id)
+	if err != nil {
+		return fmt.Errorf("failed to remove passkey: %w: %w", err,
+			myerrors.ErrFailInExec)
+	}
+	if tag.RowsAffected() == 0 {
+		return myerrors.ErrNoSuchPasskey
+	}
+
+	return nil
+}
+
+func scanPasskey(rows pgx.Rows) (domain.Passkey, error) {
+	var signCount int64
+	var lastUsedAt *time.Time
+	passkey := domain.Passkey{}
+	err := rows.Scan(
+		&passkey.Id,
+		&passkey.PublicKey,
+		&signCount,
+		&passkey.Transports,
+		&passkey.Name,
+		&passkey.CreatedAt,
+		&lastUsedAt)
+	if err != nil {
+		return domain.Passkey{}, err
+	}
+	passkey.SignCount = uint32(signCount)
+	if lastUsedAt != nil {
+		passkey.LastUsedAt = *lastUsedAt
+	}
+	return passkey, nil
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	myerrors "github.com/SanExpett/diploma/internal/errors"
+)
+
+const insertPasswordResetToken = `
+		INSERT INTO password_reset_token (token_hash, user_id, expires_at)
+		SELECT $1, id, $3
+		FROM users
+		WHERE email = $2;`
+
+const deleteExpiredPasswordResetTokens = `
+		DELETE FROM password_reset_token
+		WHERE expires_at <= NOW();`
+
+const consumePasswordResetToken = `
+		DELETE FROM password_reset_token
+		WHERE token_hash = $1 AND expires_at > NOW()
+		RETURNING user_id;`
+
+const putNewUserPasswordById = `
+		UPDATE users
+		SET password = $1
+		WHERE id = $2
+		RETURNING email;`
+
+const deleteUserPasswordResetTokens = `
+		DELETE FROM password_reset_token
+		WHERE user_id = $1;`
+
+// SavePasswordResetToken сохраняет хеш токена восстановления пароля. Если пользователя с таким email
+// нет, возвращает ErrNoSuchUser
+func (storage *UsersStorage) SavePasswordResetToken(email, tokenHash string, expiresAt time.Time) error {
+	_, err := storage.pool.Exec(context.Background(), deleteExpiredPasswordResetTokens)
+	if err != nil {
+		return fmt.Errorf("failed to delete expired reset tokens: %w: %w", err,
+			myerrors.ErrFailInExec)
+	}
+
+	tag, err := storage.pool.Exec(context.Background(), insertPasswordResetToken, tokenHash, email, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to save reset token: %w: %w", err,
+			myerrors.ErrFailInExec)
+	}
+	if tag.RowsAffected() == 0 {
+		return myerrors.ErrNoSuchUser
+	}
+
+	return nil
+}
+
+// ResetPassword по токену восстановления меняет пароль и возвращает email пользователя. Токен удаляется
+// в той же транзакции, поэтому воспользоваться им можно только один раз. Остальные выданные
+// пользователю токены тоже удаляются
+func (storage *UsersStorage) ResetPassword(tokenHash, passwordHash string) (string, error) {
+	tx, err := storage.pool.BeginTx(context.Background(), pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction to reset password: %w: %w", err,
+			myerrors.ErrFailedToBeginTransaction)
+	}
+	// после Commit откат ничего не делает
+	defer func() {
+		_ = tx.Rollback(context.Background())
+	}()
+
+	var userId int
+	err = tx.QueryRow(context.Background(), consumePasswordResetToken, tokenHash).Scan(&userId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", myerrors.ErrInvalidResetToken
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to consume reset token: %w: %w", err,
+			myerrors.ErrFailInQueryRow)
+	}
+
+	var email string
+	err = tx.QueryRow(context.Background(), putNewUserPasswordById, passwordHash, userId).Scan(&email)
+	if err != nil {
+		return "", fmt.Errorf("failed to update password: %w: %w", err,
+			myerrors.ErrFailInQueryRow)
+	}
+
+	_, err = tx.Exec(context.Background(), deleteUserPasswordResetTokens, userId)
+	if err != nil {
+		return "", fmt.Errorf("failed to delete reset tokens: %w: %w", err,
+			myerrors.ErrFailInExec)
+	}
+
+	err = tx.Commit(context.Background())
+	if err != nil {
+		return "", fmt.Errorf("failed to commit transaction: %w: %w", err,
+			myerrors.ErrFailedToCommitTransaction)
+	}
+
+	return email, nil
+}
@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/SanExpett/diploma/internal/domain"
+	myerrors "github.com/SanExpett/diploma/internal/errors"
+)
+
+const getTOTP = `
+		SELECT user_totp.secret, user_totp.confirmed_at IS NOT NULL, user_totp.last_used_step
+		FROM user_totp
+		JOIN users ON users.id = user_totp.user_id
+		WHERE users.email = $1;`
+
+const upsertTOTPSecret = `
+		INSERT INTO user_totp (user_id, secret)
+		SELECT id, $2
+		FROM users
+		WHERE email = $1
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, last_used_step = 0
+		WHERE user_totp.confirmed_at IS NULL;`
+
+const confirmTOTP = `
+		UPDATE user_totp
+		SET confirmed_at = NOW(), last_used_step = $2
+		FROM users
+		WHERE users.id = user_totp.user_id AND users.email = $1 AND user_totp.confirmed_at IS NULL
+		RETURNING user_totp.user_id;`
+
+const deleteRecoveryCodes = `
+		DELETE FROM totp_recovery_code
+		WHERE user_id = $1;`
+
+const insertRecoveryCode = `
+		INSERT INTO totp_recovery_code (user_id, code_hash)
+		VALUES ($1, $2);`
+
+const useTOTPStep = `
+		UPDATE user_totp
+		SET last_used_step = $2
+		FROM users
+		WHERE users.id = user_totp.user_id AND users.email = $1 AND user_totp.last_used_step < $2;`
+
+const useRecoveryCode = `
+		DELETE FROM totp_recovery_code
+		USING users
+		WHERE users.id = totp_recovery_code.user_id AND users.email = $1 AND totp_recovery_code.code_hash = $2;`
+
+const deleteTOTP = `
+		DELETE FROM user_totp
+		USING users
+		WHERE users.id = user_totp.user_id AND users.email = $1;`
+
+// GetTOTP возвращает второй фактор пользователя или ErrTOTPNotEnabled, если секрет не выдавался
+func (storage *UsersStorage) GetTOTP(email string) (domain.TOTP, error) {
+	var userTOTP domain.TOTP
+	err := storage.pool.QueryRow(context.Background(), getTOTP, email).Scan(
+		&userTOTP.Secret,
+		&userTOTP.Confirmed,
+		&userTOTP.LastUsedStep)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.TOTP{}, myerrors.ErrTOTPNotEnabled
+	}
+	if err != nil {
+		return domain.TOTP{}, fmt.Errorf("failed to get totp: %w: %w", err,
+			myerrors.ErrFailInQueryRow)
+	}
+
+	return userTOTP, nil
+}
+
+// SaveTOTPSecret сохраняет новый неподтвержденный секрет. Подтвержденный второй фактор не перезаписывается,
+// в этом случае возвращается ErrTOTPAlreadyEnabled
+func (storage *UsersStorage) SaveTOTPSecret(email, secret string) error {
+	tag, err := storage.pool.Exec(context.Background(), upsertTOTPSecret, email, secret)
+	if err != nil {
+		return fmt.Errorf("failed to save totp secret: %w: %w", err,
+			myerrors.ErrFailInExec)
+	}
+	if tag.RowsAffected() == 0 {
+		return myerrors.ErrTOTPAlreadyEnabled
+	}
+
+	return nil
+}
+
+// ConfirmTOTP включает второй фактор, запоминает шаг кода, которым он подтвержден, и заменяет
+// коды восстановления на новые
+func (storage *UsersStorage) ConfirmTOTP(email string, step int64, recoveryCodeHashes []string) error {
+	tx, err := storage.pool.BeginTx(context.Background(), pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction to confirm totp: %w: %w", err,
+			myerrors.ErrFailedToBeginTransaction)
+	}
+	// после Commit откат ничего не делает
+	defer func() {
+		_ = tx.Rollback(context.Background())
+	}()
+
+	var userId int
+	err = tx.QueryRow(context.Background(), confirmTOTP, email, step).Scan(&userId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return myerrors.ErrTOTPAlreadyEnabled
+	}
+	if err != nil {
+		return fmt.Errorf("failed to confirm totp: %w: %w", err,
+			myerrors.ErrFailInQueryRow)
+	}
+
+	_, err = tx.Exec(context.Background(), deleteRecoveryCodes, userId)
+	if err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w: %w", err,
+			myerrors.ErrFailInExec)
+	}
+
+	for _, codeHash := range recoveryCodeHashes {
+		_, err = tx.Exec(context.Background(), insertRecoveryCode, userId, codeHash)
+		if err != nil {
+			return fmt.Errorf("failed to save recovery code: %w: %w", err,
+				myerrors.ErrFailInExec)
+		}
+	}
+
+	err = tx.Commit(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %w: %w", err,
+			myerrors.ErrFailedToCommitTransaction)
+	}
+
+	return nil
+}
+
+// UseTOTPStep отмечает шаг принятого кода. Если код этого или более позднего шага уже принимался,
+// возвращает ErrInvalidTOTPCode, поэтому один код нельзя использовать дважды
+func (storage *UsersStorage) UseTOTPStep(email string, step int64) error {
+	tag, err := storage.pool.Exec(context.Background(), useTOTPStep, email, step)
+	if err != nil {
+		return fmt.Errorf("failed to use totp step: %w: %w", err,
+			myerrors.ErrFailInExec)
+	}
+	if tag.RowsAffected() == 0 {
+		return myerrors.ErrInvalidTOTPCode
+	}
+
+	return nil
+}
+
+// UseRecoveryCode удаляет использованный код восстановления или возвращает ErrInvalidTOTPCode,
+// если такого кода нет
+func (storage *UsersStorage) UseRecoveryCode(email, codeHash string) error {
+	tag, err := storage.pool.Exec(context.Background(), useRecoveryCode, email, codeHash)
+	if err != nil {
+		return fmt.Errorf("failed to use recovery code: %w: %w", err,
+			myerrors.ErrFailInExec)
+	}
+	if tag.RowsAffected() == 0 {
+		return myerrors.ErrInvalidTOTPCode
+	}
+
+	return nil
+}
+
+// DisableTOTP отключает второй фактор вместе с кодами восстановления
+func (storage *UsersStorage) DisableTOTP(email string) error {
+	_, err := storage.pool.Exec(context.Background(), deleteTOTP, email)
+	if err != nil {
+		return fmt.Errorf("failed to disable totp: %w: %w", err,
+			myerrors.ErrFailInExec)
+	}
+
+	return nil
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
 	"time"
@@ -11,6 +12,8 @@ import (
 
 	"github.com/SanExpett/diploma/internal/domain"
 	myerrors "github.com/SanExpett/diploma/internal/errors"
+	"github.com/SanExpett/diploma/internal/rbac"
+	rbacRepository "github.com/SanExpett/diploma/internal/rbac/repository"
 )
 
 type PgxIface interface {
@@ -35,7 +38,9 @@ func NewUsersStorage(pool PgxIface) (*UsersStorage, error) {
 const insertUser = `INSERT INTO users (email, name, password) VALUES ($1, $2, $3);`
 
 const getUserData = `
-		SELECT external_id, email, avatar, name, password, registered_at, birthday, is_admin
+		SELECT external_id, email, avatar, name, password, registered_at, birthday, is_admin, email_verified_at IS NOT NULL,
+			EXISTS(SELECT 1 FROM user_totp WHERE user_totp.user_id = users.id AND confirmed_at IS NOT NULL),
+			ARRAY(SELECT role FROM user_role WHERE user_role.user_id = users.id ORDER BY role)
 		FROM users
 		WHERE email = $1;`
 
@@ -43,7 +48,7 @@ const deleteUser = `
 		DELETE FROM users
 		WHERE email = $1;`
 
-const getAmountOfUserByName = `
+const getPasswordByEmail = `
 		SELECT password
 		FROM users
 		WHERE email = $1;`
@@ -74,7 +79,9 @@ const putNewUsernameByUuid = `
 		WHERE external_id = $2;`
 
 const getUserDataByUuid = `
-		SELECT external_id, email, avatar, name, password, registered_at, birthday, is_admin
+		SELECT external_id, email, avatar, name, password, registered_at, birthday, is_admin, email_verified_at IS NOT NULL,
+			EXISTS(SELECT 1 FROM user_totp WHERE user_totp.user_id = users.id AND confirmed_at IS NOT NULL),
+			ARRAY(SELECT role FROM user_role WHERE user_role.user_id = users.id ORDER BY role)
 		FROM users
 		WHERE external_id = $1;`
 
@@ -123,7 +130,10 @@ func (storage *UsersStorage) GetUser(email string) (domain.User, error) {
 		&user.Password,
 		&user.RegisteredAt,
 		&user.Birthday,
-		&user.IsAdmin)
+		&user.IsAdmin,
+		&user.EmailVerified,
+		&user.TotpEnabled,
+		&user.Roles)
 	if err != nil {
 		return domain.User{}, fmt.Errorf("failed to get user: %w: %w", err,
 			myerrors.ErrFailInQueryRow)
@@ -141,17 +151,26 @@ func (storage *UsersStorage) RemoveUser(email string) error {
 	return nil
 }
 
-func (storage *UsersStorage) HasUser(email, password string) error {
-	var passwordFromDB string
-	err := storage.pool.QueryRow(context.Background(), getAmountOfUserByName, email).Scan(&passwordFromDB)
+func (storage *UsersStorage) GetPasswordHash(email string) (string, error) {
+	var passwordHash string
+	err := storage.pool.QueryRow(context.Background(), getPasswordByEmail, email).Scan(&passwordHash)
+	// неизвестный логин не отличается от неверного пароля, чтобы по ответу нельзя было перебирать email
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", fmt.Errorf("failed to get user for password check: %w", myerrors.ErrIncorrectLoginOrPassword)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to get user for password check: %w: %w", err,
+		return "", fmt.Errorf("failed to get user for password check: %w: %w", err,
 			myerrors.ErrFailInQuery)
 	}
 
-	if passwordFromDB != password {
-		return fmt.Errorf("failed to compare passwords: %w",
-			myerrors.ErrIncorrectLoginOrPassword)
+	return passwordHash, nil
+}
+
+func (storage *UsersStorage) UpdatePasswordHash(email, passwordHash string) error {
+	_, err := storage.pool.Exec(context.Background(), putNewUserPassword, passwordHash, email)
+	if err != nil {
+		return fmt.Errorf("failed to update password hash: %w: %w", err,
+			myerrors.ErrFailInExec)
 	}
 
 	return nil
@@ -173,7 +192,10 @@ func (storage *UsersStorage) ChangeUserPassword(email, newPassword string) (doma
 		&user.Password,
 		&user.RegisteredAt,
 		&user.Birthday,
-		&user.IsAdmin)
+		&user.IsAdmin,
+		&user.EmailVerified,
+		&user.TotpEnabled,
+		&user.Roles)
 	if err != nil {
 		return domain.User{}, fmt.Errorf("failed to get new user data: %w: %w", err,
 			myerrors.ErrFailInQueryRow)
@@ -198,7 +220,10 @@ func (storage *UsersStorage) ChangeUserName(email, newUsername string) (domain.U
 		&user.Password,
 		&user.RegisteredAt,
 		&user.Birthday,
-		&user.IsAdmin)
+		&user.IsAdmin,
+		&user.EmailVerified,
+		&user.TotpEnabled,
+		&user.Roles)
 	if err != nil {
 		return domain.User{}, fmt.Errorf("failed to get new user data: %w: %w", err,
 			myerrors.ErrFailInQueryRow)
@@ -217,7 +242,10 @@ func (storage *UsersStorage) GetUserDataByUuid(uuid string) (domain.User, error)
 		&user.Password,
 		&user.RegisteredAt,
 		&user.Birthday,
-		&user.IsAdmin)
+		&user.IsAdmin,
+		&user.EmailVerified,
+		&user.TotpEnabled,
+		&user.Roles)
 	if err != nil {
 		return domain.User{}, fmt.Errorf("failed to get user data by uuid: %w: %w", err,
 			myerrors.ErrFailInQueryRow)
@@ -253,7 +281,10 @@ func (storage *UsersStorage) ChangeUserPasswordByUuid(uuid, newPassword string)
 		&user.Password,
 		&user.RegisteredAt,
 		&user.Birthday,
-		&user.IsAdmin)
+		&user.IsAdmin,
+		&user.EmailVerified,
+		&user.TotpEnabled,
+		&user.Roles)
 	if err != nil {
 		return domain.User{}, fmt.Errorf("failed to get new user data: %w: %w", err,
 			myerrors.ErrFailInQueryRow)
@@ -278,7 +309,10 @@ func (storage *UsersStorage) ChangeUserNameByUuid(uuid, newUsername string) (dom
 		&user.Password,
 		&user.RegisteredAt,
 		&user.Birthday,
-		&user.IsAdmin)
+		&user.IsAdmin,
+		&user.EmailVerified,
+		&user.TotpEnabled,
+		&user.Roles)
 	if err != nil {
 		return domain.User{}, fmt.Errorf("failed to get new user data: %w: %w", err,
 			myerrors.ErrFailInQueryRow)
@@ -303,7 +337,10 @@ func (storage *UsersStorage) ChangeUserAvatarByUuid(uuid, filename string) (doma
 		&user.Password,
 		&user.RegisteredAt,
 		&user.Birthday,
-		&user.IsAdmin)
+		&user.IsAdmin,
+		&user.EmailVerified,
+		&user.TotpEnabled,
+		&user.Roles)
 	if err != nil {
 		return domain.User{}, fmt.Errorf("failed to get new user data: %w: %w", err,
 			myerrors.ErrFailInQueryRow)
@@ -381,3 +418,7 @@ func (storage *UsersStorage) GetSubscription(uuid string) (domain.Subscription,
 	}
 	return sub, nil
 }
+
+func (storage *UsersStorage) LoadPolicy(ctx context.Context) (map[rbac.Role][]rbac.Permission, error) {
+	return rbacRepository.NewRbacStorage(storage.pool).LoadPolicy(ctx)
+}
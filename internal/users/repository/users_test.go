@@ -5,12 +5,15 @@ import (
 	"os"
 	"regexp"
 	"testing"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/pashagolub/pgxmock/v3"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/SanExpett/diploma/internal/domain"
+	myerrors "github.com/SanExpett/diploma/internal/errors"
 	"github.com/SanExpett/diploma/internal/users/mocks"
 )
 
@@ -44,9 +47,9 @@ func TestUsersStorage_GetUser(t *testing.T) {
 	newUser := mocks.NewMockUser()
 
 	mockRows := pgxmock.NewRows([]string{"uuid", "email", "avatar", "name", "password", "registered_at", "birthday",
-		"is_admin"}).
+		"is_admin", "email_verified", "totp_enabled", "roles"}).
 		AddRow(newUser.Uuid, newUser.Email, newUser.Avatar, newUser.Name, newUser.Password, newUser.RegisteredAt,
-			newUser.Birthday, newUser.IsAdmin)
+			newUser.Birthday, newUser.IsAdmin, newUser.EmailVerified, newUser.TotpEnabled, newUser.Roles)
 
 	mock.ExpectQuery("SELECT").
 		WithArgs("cakethefake@gmail.com").
@@ -80,7 +83,7 @@ func TestUsersStorage_RemoveUser(t *testing.T) {
 	require.NoError(t, err)
 }
 
-func TestUsersStorage_HasUser(t *testing.T) {
+func TestUsersStorage_GetPasswordHash(t *testing.T) {
 	mock, err := pgxmock.NewPool()
 	require.NoError(t, err)
 	defer mock.Close()
@@ -89,7 +92,6 @@ func TestUsersStorage_HasUser(t *testing.T) {
 
 	newUser := mocks.NewMockUser()
 	email := "cakethefake@gmail.com"
-	password := "123456789"
 
 	mockRows := pgxmock.NewRows([]string{"password"}).
 		AddRow(newUser.Password)
@@ -98,7 +100,36 @@ func TestUsersStorage_HasUser(t *testing.T) {
 		WithArgs(email).
 		WillReturnRows(mockRows)
 
-	err = storage.HasUser(email, password)
+	passwordHash, err := storage.GetPasswordHash(email)
+	require.Equal(t, nil, err)
+	require.Equal(t, newUser.Password, passwordHash)
+
+	mock.ExpectQuery("SELECT").
+		WithArgs("unknown@gmail.com").
+		WillReturnError(pgx.ErrNoRows)
+
+	_, err = storage.GetPasswordHash("unknown@gmail.com")
+	require.ErrorIs(t, err, myerrors.ErrIncorrectLoginOrPassword)
+
+	err = mock.ExpectationsWereMet()
+	require.NoError(t, err)
+}
+
+func TestUsersStorage_UpdatePasswordHash(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	storage, err := NewUsersStorage(mock)
+
+	email := "cakethefake@gmail.com"
+	passwordHash := "$argon2id$v=19$m=65536,t=3,p=2$c2FsdA$aGFzaA"
+
+	mock.ExpectExec("UPDATE").
+		WithArgs(passwordHash, email).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	err = storage.UpdatePasswordHash(email, passwordHash)
 	require.Equal(t, nil, err)
 
 	err = mock.ExpectationsWereMet()
@@ -121,9 +152,9 @@ func TestUsersStorage_ChangeUserPassword(t *testing.T) {
 
 	newUser := mocks.NewMockUser()
 	mockRows := pgxmock.NewRows([]string{"uuid", "email", "name", "avatar", "password", "registered_at", "birthday",
-		"is_admin"}).
+		"is_admin", "email_verified", "totp_enabled", "roles"}).
 		AddRow(newUser.Uuid, newUser.Email, newUser.Avatar, newUser.Name, newUser.Password, newUser.RegisteredAt,
-			newUser.Birthday, newUser.IsAdmin)
+			newUser.Birthday, newUser.IsAdmin, newUser.EmailVerified, newUser.TotpEnabled, newUser.Roles)
 	mock.ExpectQuery("SELECT").
 		WithArgs(email).
 		WillReturnRows(mockRows)
@@ -145,8 +176,8 @@ func TestUsersStorage_GetUserDataByUuid(t *testing.T) {
 	newUser := mocks.NewMockUser()
 	uuid := "1"
 
-	mockRows := pgxmock.NewRows([]string{"uuid", "email", "avatar", "name", "password", "registered_at", "birthday", "is_admin"}).
-		AddRow(newUser.Uuid, newUser.Email, newUser.Avatar, newUser.Name, newUser.Password, newUser.RegisteredAt, newUser.Birthday, newUser.IsAdmin)
+	mockRows := pgxmock.NewRows([]string{"uuid", "email", "avatar", "name", "password", "registered_at", "birthday", "is_admin", "email_verified", "totp_enabled", "roles"}).
+		AddRow(newUser.Uuid, newUser.Email, newUser.Avatar, newUser.Name, newUser.Password, newUser.RegisteredAt, newUser.Birthday, newUser.IsAdmin, newUser.EmailVerified, newUser.TotpEnabled, newUser.Roles)
 
 	mock.ExpectQuery("SELECT").
 		WithArgs(uuid).
@@ -176,9 +207,9 @@ func TestUsersStorage_ChangeUserPasswordByUuid(t *testing.T) {
 
 	newUser := mocks.NewMockUser()
 	mockRows := pgxmock.NewRows([]string{"uuid", "email", "avatar", "name", "password", "registered_at", "birthday",
-		"is_admin"}).
+		"is_admin", "email_verified", "totp_enabled", "roles"}).
 		AddRow(newUser.Uuid, newUser.Email, newUser.Avatar, newUser.Name, newUser.Password, newUser.RegisteredAt,
-			newUser.Birthday, newUser.IsAdmin)
+			newUser.Birthday, newUser.IsAdmin, newUser.EmailVerified, newUser.TotpEnabled, newUser.Roles)
 	mock.ExpectQuery("SELECT").
 		WithArgs(uuid).
 		WillReturnRows(mockRows)
@@ -206,9 +237,9 @@ func TestUsersStorage_ChangeUserName(t *testing.T) {
 
 	newUser := mocks.NewMockUser()
 	mockRows := pgxmock.NewRows([]string{"uuid", "email", "avatar", "name", "password", "registered_at", "birthday",
-		"is_admin"}).
+		"is_admin", "email_verified", "totp_enabled", "roles"}).
 		AddRow(newUser.Uuid, newUser.Email, newUser.Avatar, newUser.Name, newUser.Password, newUser.RegisteredAt,
-			newUser.Birthday, newUser.IsAdmin)
+			newUser.Birthday, newUser.IsAdmin, newUser.EmailVerified, newUser.TotpEnabled, newUser.Roles)
 	mock.ExpectQuery("SELECT").
 		WithArgs(email).
 		WillReturnRows(mockRows)
@@ -239,9 +270,9 @@ func TestUsersStorage_ChangeUserNameByUuid(t *testing.T) {
 
 	newUser := mocks.NewMockUser()
 	mockRows := pgxmock.NewRows([]string{"uuid", "email", "avatar", "name", "password", "registered_at", "birthday",
-		"is_admin"}).
+		"is_admin", "email_verified", "totp_enabled", "roles"}).
 		AddRow(newUser.Uuid, newUser.Email, newUser.Avatar, newUser.Name, newUser.Password, newUser.RegisteredAt,
-			newUser.Birthday, newUser.IsAdmin)
+			newUser.Birthday, newUser.IsAdmin, newUser.EmailVerified, newUser.TotpEnabled, newUser.Roles)
 	mock.ExpectQuery("SELECT").
 		WithArgs(uuid).
 		WillReturnRows(mockRows)
@@ -278,3 +309,469 @@ func TestUsersStorage_GetUserPreview(t *testing.T) {
 	err = mock.ExpectationsWereMet()
 	require.NoError(t, err)
 }
+
+func TestUsersStorage_SavePasswordResetToken(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	storage, err := NewUsersStorage(mock)
+	require.NoError(t, err)
+
+	expiresAt := time.Now().Add(time.Hour)
+
+	mock.ExpectExec("DELETE FROM password_reset_token").
+		WillReturnResult(pgxmock.NewResult("DELETE", 0))
+	mock.ExpectExec("INSERT INTO password_reset_token").
+		WithArgs("hash", "cakethefake@gmail.com", expiresAt).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+	err = storage.SavePasswordResetToken("cakethefake@gmail.com", "hash", expiresAt)
+	require.NoError(t, err)
+
+	mock.ExpectExec("DELETE FROM password_reset_token").
+		WillReturnResult(pgxmock.NewResult("DELETE", 0))
+	mock.ExpectExec("INSERT INTO password_reset_token").
+		WithArgs("hash", "unknown@gmail.com", expiresAt).
+		WillReturnResult(pgxmock.NewResult("INSERT", 0))
+
+	err = storage.SavePasswordResetToken("unknown@gmail.com", "hash", expiresAt)
+	require.ErrorIs(t, err, myerrors.ErrNoSuchUser)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUsersStorage_ResetPassword(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	storage, err := NewUsersStorage(mock)
+	require.NoError(t, err)
+
+	mock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	mock.ExpectQuery("DELETE FROM password_reset_token").
+		WithArgs("hash").
+		WillReturnRows(pgxmock.NewRows([]string{"user_id"}).AddRow(1))
+	mock.ExpectQuery("UPDATE users").
+		WithArgs("passwordHash", 1).
+		WillReturnRows(pgxmock.NewRows([]string{"email"}).AddRow("cakethefake@gmail.com"))
+	mock.ExpectExec("DELETE FROM password_reset_token").
+		WithArgs(1).
+		WillReturnResult(pgxmock.NewResult("DELETE", 2))
+	mock.ExpectCommit()
+
+	email, err := storage.ResetPassword("hash", "passwordHash")
+	require.NoError(t, err)
+	require.Equal(t, "cakethefake@gmail.com", email)
+
+	mock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	mock.ExpectQuery("DELETE FROM password_reset_token").
+		WithArgs("hash").
+		WillReturnError(pgx.ErrNoRows)
+	mock.ExpectRollback()
+
+	_, err = storage.ResetPassword("hash", "passwordHash")
+	require.ErrorIs(t, err, myerrors.ErrInvalidResetToken)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUsersStorage_GetEmailVerificationStatus(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	storage, err := NewUsersStorage(mock)
+	require.NoError(t, err)
+
+	since := time.Now().Add(-time.Hour)
+	lastSentAt := time.Now().Add(-time.Minute)
+
+	mock.ExpectQuery("SELECT").
+		WithArgs("cakethefake@gmail.com", since).
+		WillReturnRows(pgxmock.NewRows([]string{"verified", "sent_recently", "last_sent_at"}).
+			AddRow(false, uint32(2), lastSentAt))
+
+	status, err := storage.GetEmailVerificationStatus("cakethefake@gmail.com", since)
+	require.NoError(t, err)
+	require.Equal(t, domain.EmailVerificationStatus{SentRecently: 2, LastSentAt: lastSentAt}, status)
+
+	mock.ExpectQuery("SELECT").
+		WithArgs("unknown@gmail.com", since).
+		WillReturnError(pgx.ErrNoRows)
+
+	_, err = storage.GetEmailVerificationStatus("unknown@gmail.com", since)
+	require.ErrorIs(t, err, myerrors.ErrNoSuchUser)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUsersStorage_VerifyEmail(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	storage, err := NewUsersStorage(mock)
+	require.NoError(t, err)
+
+	mock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	mock.ExpectQuery("DELETE FROM email_verification_token").
+		WithArgs("hash").
+		WillReturnRows(pgxmock.NewRows([]string{"user_id"}).AddRow(1))
+	mock.ExpectQuery("UPDATE users").
+		WithArgs(1).
+		WillReturnRows(pgxmock.NewRows([]string{"email"}).AddRow("cakethefake@gmail.com"))
+	mock.ExpectExec("DELETE FROM email_verification_token").
+		WithArgs(1).
+		WillReturnResult(pgxmock.NewResult("DELETE", 0))
+	mock.ExpectCommit()
+
+	email, err := storage.VerifyEmail("hash")
+	require.NoError(t, err)
+	require.Equal(t, "cakethefake@gmail.com", email)
+
+	mock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	mock.ExpectQuery("DELETE FROM email_verification_token").
+		WithArgs("hash").
+		WillReturnError(pgx.ErrNoRows)
+	mock.ExpectRollback()
+
+	_, err = storage.VerifyEmail("hash")
+	require.ErrorIs(t, err, myerrors.ErrInvalidVerificationToken)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUsersStorage_ConfirmTOTP(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	storage, err := NewUsersStorage(mock)
+	require.NoError(t, err)
+
+	mock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	mock.ExpectQuery("UPDATE user_totp").
+		WithArgs("cakethefake@gmail.com", int64(100)).
+		WillReturnRows(pgxmock.NewRows([]string{"user_id"}).AddRow(1))
+	mock.ExpectExec("DELETE FROM totp_recovery_code").
+		WithArgs(1).
+		WillReturnResult(pgxmock.NewResult("DELETE", 0))
+	mock.ExpectExec("INSERT INTO totp_recovery_code").
+		WithArgs(1, "first").
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec("INSERT INTO totp_recovery_code").
+		WithArgs(1, "second").
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectCommit()
+
+	err = storage.ConfirmTOTP("cakethefake@gmail.com", 100, []string{"first", "second"})
+	require.NoError(t, err)
+
+	mock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	mock.ExpectQuery("UPDATE user_totp").
+		WithArgs("cakethefake@gmail.com", int64(100)).
+		WillReturnError(pgx.ErrNoRows)
+	mock.ExpectRollback()
+
+	err = storage.ConfirmTOTP("cakethefake@gmail.com", 100, []string{"first"})
+	require.ErrorIs(t, err, myerrors.ErrTOTPAlreadyEnabled)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUsersStorage_UseTOTPStep(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	storage, err := NewUsersStorage(mock)
+	require.NoError(t, err)
+
+	mock.ExpectExec("UPDATE user_totp").
+		WithArgs("cakethefake@gmail.com", int64(101)).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	require.NoError(t, storage.UseTOTPStep("cakethefake@gmail.com", 101))
+
+	mock.ExpectExec("UPDATE user_totp").
+		WithArgs("cakethefake@gmail.com", int64(101)).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+	err = storage.UseTOTPStep("cakethefake@gmail.com", 101)
+	require.ErrorIs(t, err, myerrors.ErrInvalidTOTPCode, "код уже использованного шага не принимается")
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUsersStorage_LinkIdentity(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	storage, err := NewUsersStorage(mock)
+	require.NoError(t, err)
+
+	identity := domain.Identity{Provider: "mock", Subject: "42", Email: "cakethefake@gmail.com"}
+
+	mock.ExpectExec("INSERT INTO user_identities").
+		WithArgs("cakethefake@gmail.com", "mock", "42", "cakethefake@gmail.com").
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+	require.NoError(t, storage.LinkIdentity("cakethefake@gmail.com", identity))
+
+	mock.ExpectExec("INSERT INTO user_identities").
+		WithArgs("other@gmail.com", "mock", "42", "cakethefake@gmail.com").
+		WillReturnResult(pgxmock.NewResult("INSERT", 0))
+
+	err = storage.LinkIdentity("other@gmail.com", identity)
+	require.ErrorIs(t, err, myerrors.ErrIdentityAlreadyLinked)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUsersStorage_CreateUserWithIdentity(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	storage, err := NewUsersStorage(mock)
+	require.NoError(t, err)
+
+	user := domain.UserSignUp{Email: "cakethefake@gmail.com", Name: "user", Password: "hash"}
+	identity := domain.Identity{Provider: "mock", Subject: "42", Email: "cakethefake@gmail.com", EmailVerified: true}
+
+	mock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	mock.ExpectQuery("INSERT INTO users").
+		WithArgs("cakethefake@gmail.com", "user", "hash", true).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec("INSERT INTO user_identities").
+		WithArgs("mock", "42", 1, "cakethefake@gmail.com").
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectCommit()
+
+	require.NoError(t, storage.CreateUserWithIdentity(user, identity))
+
+	mock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	mock.ExpectQuery("INSERT INTO users").
+		WithArgs("cakethefake@gmail.com", "user", "hash", true).
+		WillReturnError(pgx.ErrNoRows)
+	mock.ExpectRollback()
+
+	err = storage.CreateUserWithIdentity(user, identity)
+	require.ErrorIs(t, err, myerrors.ErrUserAlreadyExists)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUsersStorage_MagicLinkToken(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	storage, err := NewUsersStorage(mock)
+	require.NoError(t, err)
+
+	expiresAt := time.Now().Add(15 * time.Minute)
+
+	mock.ExpectExec("INSERT INTO magic_link_token").
+		WithArgs("hash", "cakethefake@gmail.com", expiresAt).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec("INSERT INTO magic_link_token").
+		WithArgs("hash", "unknown@gmail.com", expiresAt).
+		WillReturnResult(pgxmock.NewResult("INSERT", 0))
+	mock.ExpectExec("DELETE FROM magic_link_token").
+		WithArgs("cakethefake@gmail.com", "hash").
+		WillReturnResult(pgxmock.NewResult("DELETE", 1))
+	mock.ExpectExec("DELETE FROM magic_link_token").
+		WithArgs("cakethefake@gmail.com", "hash").
+		WillReturnResult(pgxmock.NewResult("DELETE", 0))
+
+	require.NoError(t, storage.SaveMagicLinkToken("cakethefake@gmail.com", "hash", expiresAt))
+	require.ErrorIs(t, storage.SaveMagicLinkToken("unknown@gmail.com", "hash", expiresAt), myerrors.ErrNoSuchUser)
+	require.NoError(t, storage.ConsumeMagicLinkToken("cakethefake@gmail.com", "hash"))
+	require.ErrorIs(t, storage.ConsumeMagicLinkToken("cakethefake@gmail.com", "hash"), myerrors.ErrInvalidMagicLink,
+		"ссылкой можно воспользоваться только один раз")
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUsersStorage_Passkeys(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	storage, err := NewUsersStorage(mock)
+	require.NoError(t, err)
+
+	passkey := domain.Passkey{
+		Id:         "credential",
+		PublicKey:  []byte{0xa5},
+		Transports: []string{"internal"},
+		Name:       "Ноутбук",
+	}
+	createdAt := time.Now()
+
+	mock.ExpectExec("INSERT INTO webauthn_credentials").
+		WithArgs("credential", "cakethefake@gmail.com", passkey.PublicKey, int64(0), passkey.Transports, "Ноутбук").
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec("INSERT INTO webauthn_credentials").
+		WithArgs("credential", "cakethefake@gmail.com", passkey.PublicKey, int64(0), passkey.Transports, "Ноутбук").
+		WillReturnResult(pgxmock.NewResult("INSERT", 0))
+	mock.ExpectQuery("SELECT users.email").
+		WithArgs("credential").
+		WillReturnRows(pgxmock.NewRows([]string{"email", "id", "public_key", "sign_count", "transports", "name",
+			"created_at", "last_used_at"}).
+			AddRow("cakethefake@gmail.com", "credential", passkey.PublicKey, int64(3), passkey.Transports, "Ноутбук",
+				createdAt, nil))
+	mock.ExpectExec("UPDATE webauthn_credentials").
+		WithArgs("credential", int64(4)).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec("UPDATE webauthn_credentials").
+		WithArgs("credential", int64(4)).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+	mock.ExpectExec("DELETE FROM webauthn_credentials").
+		WithArgs("other@gmail.com", "credential").
+		WillReturnResult(pgxmock.NewResult("DELETE", 0))
+
+	require.NoError(t, storage.AddPasskey("cakethefake@gmail.com", passkey))
+	require.ErrorIs(t, storage.AddPasskey("cakethefake@gmail.com", passkey), myerrors.ErrPasskeyAlreadyRegistered)
+
+	email, stored, err := storage.GetPasskey("credential")
+	require.NoError(t, err)
+	require.Equal(t, "cakethefake@gmail.com", email)
+	require.Equal(t, uint32(3), stored.SignCount)
+	require.True(t, stored.LastUsedAt.IsZero())
+
+	require.NoError(t, storage.UpdatePasskeySignCount("credential", 4))
+	require.ErrorIs(t, storage.UpdatePasskeySignCount("credential", 4), myerrors.ErrPasskeySignCountRegression,
+		"повторный ответ аутентификатора не проходит")
+	require.ErrorIs(t, storage.RemovePasskey("other@gmail.com", "credential"), myerrors.ErrNoSuchPasskey,
+		"чужой ключ удалить нельзя")
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUsersStorage_AuditEvents(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	storage, err := NewUsersStorage(mock)
+	require.NoError(t, err)
+
+	occurredAt := time.Now()
+	event := domain.AuditEvent{
+		OccurredAt: occurredAt,
+		Action:     "login.succeeded",
+		Actor:      "cakethefake@gmail.com",
+		Target:     "cakethefake@gmail.com",
+		Ip:         "192.0.2.1",
+		UserAgent:  "curl/8.0",
+		RequestId:  "request-id",
+	}
+
+	mock.ExpectExec("INSERT INTO audit_event").
+		WithArgs(occurredAt, "login.succeeded", "cakethefake@gmail.com", "cakethefake@gmail.com", "192.0.2.1",
+			"curl/8.0", "request-id", "").
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE actor = $1 AND id < $2\n\t\tORDER BY id DESC\n\t\tLIMIT $3;")).
+		WithArgs("cakethefake@gmail.com", int64(100), 20).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "occurred_at", "action", "actor", "target", "ip",
+			"user_agent", "request_id", "details"}).
+			AddRow(int64(99), occurredAt, "login.succeeded", "cakethefake@gmail.com", "cakethefake@gmail.com",
+				"192.0.2.1", "curl/8.0", "request-id", ""))
+	mock.ExpectQuery(regexp.QuoteMeta("FROM audit_event\n\t\tORDER BY id DESC\n\t\tLIMIT $1;")).
+		WithArgs(50).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "occurred_at", "action", "actor", "target", "ip",
+			"user_agent", "request_id", "details"}))
+	mock.ExpectExec("DELETE FROM audit_event").
+		WithArgs(occurredAt).
+		WillReturnResult(pgxmock.NewResult("DELETE", 3))
+
+	require.NoError(t, storage.AddAuditEvent(event))
+
+	events, err := storage.GetAuditEvents(domain.AuditFilter{Actor: "cakethefake@gmail.com", Before: 100, Limit: 20})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, int64(99), events[0].Id)
+
+	events, err = storage.GetAuditEvents(domain.AuditFilter{Limit: 50})
+	require.NoError(t, err)
+	require.Empty(t, events)
+
+	removed, err := storage.RemoveAuditEventsBefore(occurredAt)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), removed)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUsersStorage_KnownDevices(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	storage, err := NewUsersStorage(mock)
+	require.NoError(t, err)
+
+	seenAt := time.Now()
+	mock.ExpectQuery("FROM known_device").
+		WithArgs("cakethefake@gmail.com").
+		WillReturnRows(pgxmock.NewRows([]string{"fingerprint", "user_agent", "ip", "first_seen_at", "last_seen_at"}).
+			AddRow("fingerprint", "Firefox", "192.0.2.1", seenAt, seenAt))
+	mock.ExpectExec("INSERT INTO known_device").
+		WithArgs("unknown@gmail.com", "fingerprint", "Firefox", "192.0.2.1", seenAt).
+		WillReturnResult(pgxmock.NewResult("INSERT", 0))
+
+	devices, err := storage.GetKnownDevices("cakethefake@gmail.com")
+	require.NoError(t, err)
+	require.Equal(t, []domain.KnownDevice{{Fingerprint: "fingerprint", UserAgent: "Firefox", Ip: "192.0.2.1",
+		FirstSeenAt: seenAt, LastSeenAt: seenAt}}, devices)
+
+	err = storage.SaveKnownDevice("unknown@gmail.com", domain.KnownDevice{Fingerprint: "fingerprint",
+		UserAgent: "Firefox", Ip: "192.0.2.1", LastSeenAt: seenAt})
+	require.ErrorIs(t, err, myerrors.ErrNoSuchUser)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUsersStorage_ReportUnknownLogin(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	storage, err := NewUsersStorage(mock)
+	require.NoError(t, err)
+
+	mock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	mock.ExpectQuery("DELETE FROM unknown_login_token").
+		WithArgs("hash").
+		WillReturnRows(pgxmock.NewRows([]string{"user_id", "fingerprint"}).AddRow(1, "fingerprint"))
+	mock.ExpectExec("DELETE FROM known_device").
+		WithArgs(1, "fingerprint").
+		WillReturnResult(pgxmock.NewResult("DELETE", 1))
+	mock.ExpectQuery("UPDATE users").
+		WithArgs("passwordHash", 1).
+		WillReturnRows(pgxmock.NewRows([]string{"email"}).AddRow("cakethefake@gmail.com"))
+	mock.ExpectExec("DELETE FROM unknown_login_token").
+		WithArgs(1).
+		WillReturnResult(pgxmock.NewResult("DELETE", 0))
+	mock.ExpectCommit()
+
+	email, err := storage.ReportUnknownLogin("hash", "passwordHash")
+	require.NoError(t, err)
+	require.Equal(t, "cakethefake@gmail.com", email)
+
+	mock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	mock.ExpectQuery("DELETE FROM unknown_login_token").
+		WithArgs("hash").
+		WillReturnError(pgx.ErrNoRows)
+	mock.ExpectRollback()
+
+	_, err = storage.ReportUnknownLogin("hash", "passwordHash")
+	require.ErrorIs(t, err, myerrors.ErrInvalidUnknownLoginToken)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/SanExpett/diploma/internal/domain"
+	"github.com/SanExpett/diploma/internal/requestId"
+	"github.com/SanExpett/diploma/internal/tracing"
+)
+
+const (
+	defaultAuditEventsLimit = 50
+	maxAuditEventsLimit     = 200
+)
+
+// RecordAuditEvent дописывает в журнал событие, которое произошло в gateway, например вход или выход
+func (service *UsersService) RecordAuditEvent(ctx context.Context, event domain.AuditEvent) error {
+	service.metrics.IncRequestsTotal("RecordAuditEvent")
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+	err := service.storage.AddAuditEvent(event)
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to record audit event: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return err
+	}
+	return nil
+}
+
+// ListAuditEvents возвращает страницу журнала от новых событий к старым. Размер страницы по умолчанию
+// defaultAuditEventsLimit и не больше maxAuditEventsLimit
+func (service *UsersService) ListAuditEvents(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEvent,
+	error) {
+	service.metrics.IncRequestsTotal("ListAuditEvents")
+	if filter.Limit <= 0 {
+		filter.Limit = defaultAuditEventsLimit
+	}
+	if filter.Limit > maxAuditEventsLimit {
+		filter.Limit = maxAuditEventsLimit
+	}
+	events, err := service.storage.GetAuditEvents(filter)
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to list audit events: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return nil, err
+	}
+	return events, nil
+}
+
+// recordAuditEvent пишет в журнал событие сервиса. Действие к этому моменту уже выполнено,
+// поэтому ошибка записи только логируется
+func (service *UsersService) recordAuditEvent(ctx context.Context, event domain.AuditEvent) {
+	err := service.storage.AddAuditEvent(event)
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to record audit event %s: %v",
+			ctx.Value(requestId.ReqIDKey), event.Action, err)
+	}
+}
+
+// StartAuditRetention раз в interval удаляет события старше retention, пока не отменен ctx
+func (service *UsersService) StartAuditRetention(ctx context.Context, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				removed, err := service.storage.RemoveAuditEventsBefore(time.Now().Add(-retention))
+				if err != nil {
+					service.logger.With(tracing.TraceField(ctx)).Errorf("failed to remove old audit events: %v", err)
+					continue
+				}
+				if removed > 0 {
+					service.logger.With(tracing.TraceField(ctx)).Infof("removed %d audit events older than %s", removed, retention)
+				}
+			}
+		}
+	}()
+}
@@ -2,21 +2,28 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"os"
 	"time"
 
 	"go.uber.org/zap"
 
+	"github.com/SanExpett/diploma/internal/audit"
 	"github.com/SanExpett/diploma/internal/domain"
+	myerrors "github.com/SanExpett/diploma/internal/errors"
+	"github.com/SanExpett/diploma/internal/mailer"
 	"github.com/SanExpett/diploma/internal/metrics"
+	"github.com/SanExpett/diploma/internal/passwords"
+	"github.com/SanExpett/diploma/internal/rbac"
 	"github.com/SanExpett/diploma/internal/requestId"
+	"github.com/SanExpett/diploma/internal/tracing"
 )
 
 type usersStorage interface {
 	CreateUser(user domain.UserSignUp) error
 	RemoveUser(email string) error
-	HasUser(email, password string) error
+	GetPasswordHash(email string) (string, error)
+	UpdatePasswordHash(email, passwordHash string) error
 	GetUser(email string) (domain.User, error)
 	ChangeUserPassword(email, newPassword string) (domain.User, error)
 	ChangeUserName(email, newName string) (domain.User, error)
@@ -29,33 +36,83 @@ type usersStorage interface {
 	AddSubscription(uuid string, newDate string) error
 	GetSubscriptions() ([]domain.Subscription, error)
 	GetSubscription(uuid string) (domain.Subscription, error)
+	LoadPolicy(ctx context.Context) (map[rbac.Role][]rbac.Permission, error)
+	SavePasswordResetToken(email, tokenHash string, expiresAt time.Time) error
+	ResetPassword(tokenHash, passwordHash string) (string, error)
+	GetEmailVerificationStatus(email string, since time.Time) (domain.EmailVerificationStatus, error)
+	SaveEmailVerificationToken(email, tokenHash string, expiresAt time.Time) error
+	VerifyEmail(tokenHash string) (string, error)
+	GetTOTP(email string) (domain.TOTP, error)
+	SaveTOTPSecret(email, secret string) error
+	ConfirmTOTP(email string, step int64, recoveryCodeHashes []string) error
+	UseTOTPStep(email string, step int64) error
+	UseRecoveryCode(email, codeHash string) error
+	DisableTOTP(email string) error
+	GetUserByIdentity(provider, subject string) (domain.User, error)
+	LinkIdentity(email string, identity domain.Identity) error
+	CreateUserWithIdentity(user domain.UserSignUp, identity domain.Identity) error
+	MarkEmailVerified(email string) error
+	SaveMagicLinkToken(email, tokenHash string, expiresAt time.Time) error
+	ConsumeMagicLinkToken(email, tokenHash string) error
+	AddPasskey(email string, passkey domain.Passkey) error
+	GetPasskeys(email string) ([]domain.Passkey, error)
+	GetPasskey(id string) (string, domain.Passkey, error)
+	UpdatePasskeySignCount(id string, signCount uint32) error
+	RemovePasskey(email, id string) error
+	AddAuditEvent(event domain.AuditEvent) error
+	GetAuditEvents(filter domain.AuditFilter) ([]domain.AuditEvent, error)
+	RemoveAuditEventsBefore(before time.Time) (int64, error)
+	GetKnownDevices(email string) ([]domain.KnownDevice, error)
+	SaveKnownDevice(email string, device domain.KnownDevice) error
+	SaveUnknownLoginToken(email, tokenHash, fingerprint string, expiresAt time.Time) error
+	ReportUnknownLogin(tokenHash, passwordHash string) (string, error)
 }
 
 type UsersService struct {
-	storage   usersStorage
-	metrics   *metrics.GrpcMetrics
-	secretKey string
-	logger    *zap.SugaredLogger
+	storage         usersStorage
+	mailer          mailer.Mailer
+	resetURL        string
+	verifyURL       string
+	unknownLoginURL string
+	metrics         *metrics.GrpcMetrics
+	logger          *zap.SugaredLogger
 }
 
-func NewUsersService(storage usersStorage, metrics *metrics.GrpcMetrics,
-	logger *zap.SugaredLogger) *UsersService {
+// NewUsersService создает сервис пользователей. resetURL, verifyURL и unknownLoginURL адреса страниц фронтенда,
+// на которые ведут ссылки из писем восстановления пароля, подтверждения почты и входа с нового устройства
+func NewUsersService(storage usersStorage, mailer mailer.Mailer, resetURL, verifyURL, unknownLoginURL string,
+	metrics *metrics.GrpcMetrics, logger *zap.SugaredLogger) *UsersService {
 	return &UsersService{
-		storage:   storage,
-		metrics:   metrics,
-		logger:    logger,
-		secretKey: os.Getenv("SECRETKEY"),
+		storage:         storage,
+		mailer:          mailer,
+		resetURL:        resetURL,
+		verifyURL:       verifyURL,
+		unknownLoginURL: unknownLoginURL,
+		metrics:         metrics,
+		logger:          logger,
 	}
 }
 
 func (service *UsersService) CreateUser(ctx context.Context, user domain.UserSignUp) error {
 	service.metrics.IncRequestsTotal("CreateUser")
-	err := service.storage.CreateUser(user)
+	passwordHash, err := passwords.Hash(user.Password)
 	if err != nil {
-		service.logger.Errorf("[reqid=%s] failed to create user: %v", ctx.Value(requestId.ReqIDKey),
-			err)
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to hash password: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return err
+	}
+	user.Password = passwordHash
+
+	err = service.storage.CreateUser(user)
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to create user: %v",
+			ctx.Value(requestId.ReqIDKey), err)
 		return err
 	}
+
+	// пользователь уже создан, поэтому ошибка отправки письма только логируется,
+	// письмо можно запросить повторно
+	_ = service.sendEmailVerification(ctx, user.Email)
 	return nil
 }
 
@@ -63,28 +120,71 @@ func (service *UsersService) RemoveUser(ctx context.Context, login string) error
 	service.metrics.IncRequestsTotal("RemoveUser")
 	err := service.storage.RemoveUser(login)
 	if err != nil {
-		service.logger.Errorf("[reqid=%s] failed to remove user: %v", ctx.Value(requestId.ReqIDKey),
-			err)
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to remove user: %v",
+			ctx.Value(requestId.ReqIDKey), err)
 		return err
 	}
+
+	service.recordAuditEvent(ctx, audit.NewEvent(ctx, audit.ActionAccountRemoved, login, ""))
 	return nil
 }
 
 func (service *UsersService) HasUser(ctx context.Context, login, password string) error {
 	service.metrics.IncRequestsTotal("HasUser")
-	err := service.storage.HasUser(login, password)
+	passwordHash, err := service.storage.GetPasswordHash(login)
 	if err != nil {
-		service.logger.Errorf("[reqid=%s] failed to has user: %v", ctx.Value(requestId.ReqIDKey), err)
+		// неизвестный логин проверяется так же долго, как неверный пароль, чтобы email нельзя было
+		// перебирать по времени ответа
+		if errors.Is(err, myerrors.ErrIncorrectLoginOrPassword) {
+			passwords.VerifyDummy(password)
+		}
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to has user: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return err
+	}
+
+	ok, needsRehash, err := passwords.Verify(password, passwordHash)
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to verify password: %v",
+			ctx.Value(requestId.ReqIDKey), err)
 		return err
 	}
+	if !ok {
+		err = fmt.Errorf("failed to compare passwords: %w", myerrors.ErrIncorrectLoginOrPassword)
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to has user: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return err
+	}
+
+	if needsRehash {
+		service.rehashPassword(ctx, login, password)
+	}
 	return nil
 }
 
+// rehashPassword обновляет пароль, сохраненный открытым текстом или устаревшим хешем.
+// Ошибка только логируется, чтобы не мешать пользователю войти
+func (service *UsersService) rehashPassword(ctx context.Context, login, password string) {
+	passwordHash, err := passwords.Hash(password)
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to rehash password: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return
+	}
+
+	err = service.storage.UpdatePasswordHash(login, passwordHash)
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to update password hash: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+	}
+}
+
 func (service *UsersService) GetUser(ctx context.Context, login string) (domain.User, error) {
 	service.metrics.IncRequestsTotal("GetUser")
 	user, err := service.storage.GetUser(login)
 	if err != nil {
-		service.logger.Errorf("[reqid=%s] failed to get user: %v", ctx.Value(requestId.ReqIDKey), err)
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get user: %v",
+			ctx.Value(requestId.ReqIDKey), err)
 		return domain.User{}, err
 	}
 	return user, nil
@@ -92,12 +192,21 @@ func (service *UsersService) GetUser(ctx context.Context, login string) (domain.
 
 func (service *UsersService) ChangeUserPassword(ctx context.Context, login, newPassword string) (domain.User, error) {
 	service.metrics.IncRequestsTotal("ChangeUserPassword")
-	user, err := service.storage.ChangeUserPassword(login, newPassword)
+	passwordHash, err := passwords.Hash(newPassword)
 	if err != nil {
-		service.logger.Errorf("[reqid=%s] failed to change password: %v",
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to hash password: %v",
 			ctx.Value(requestId.ReqIDKey), err)
 		return domain.User{}, err
 	}
+
+	user, err := service.storage.ChangeUserPassword(login, passwordHash)
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to change password: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return domain.User{}, err
+	}
+
+	service.recordAuditEvent(ctx, audit.NewEvent(ctx, audit.ActionPasswordChanged, user.Email, ""))
 	return user, nil
 }
 
@@ -105,10 +214,12 @@ func (service *UsersService) ChangeUserName(ctx context.Context, login, newName
 	service.metrics.IncRequestsTotal("ChangeUserName")
 	user, err := service.storage.ChangeUserName(login, newName)
 	if err != nil {
-		service.logger.Errorf("[reqid=%s] failed to change username: %v", ctx.Value(requestId.ReqIDKey),
-			err)
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to change username: %v",
+			ctx.Value(requestId.ReqIDKey), err)
 		return domain.User{}, err
 	}
+
+	service.recordAuditEvent(ctx, audit.NewEvent(ctx, audit.ActionUsernameChanged, user.Email, ""))
 	return user, nil
 }
 
@@ -116,7 +227,7 @@ func (service *UsersService) GetUserDataByUuid(ctx context.Context, uuid string)
 	service.metrics.IncRequestsTotal("GetUserDataByUuid")
 	user, err := service.storage.GetUserDataByUuid(uuid)
 	if err != nil {
-		service.logger.Errorf("[reqid=%s] failed to get user data: %v",
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get user data: %v",
 			ctx.Value(requestId.ReqIDKey), err)
 		return domain.User{}, err
 	}
@@ -124,8 +235,8 @@ func (service *UsersService) GetUserDataByUuid(ctx context.Context, uuid string)
 	service.metrics.IncRequestsTotal("HasSubscription")
 	stat, err := service.storage.HasSubscription(uuid)
 	if err != nil {
-		service.logger.Errorf("[reqid=%s] failed to check subscription: %v", ctx.Value(requestId.ReqIDKey),
-			err)
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to check subscription: %v",
+			ctx.Value(requestId.ReqIDKey), err)
 		return domain.User{}, err
 	}
 
@@ -138,8 +249,8 @@ func (service *UsersService) GetUserPreview(ctx context.Context, uuid string) (d
 	service.metrics.IncRequestsTotal("GetUserPreview")
 	userPreview, err := service.storage.GetUserPreview(uuid)
 	if err != nil {
-		service.logger.Errorf("[reqid=%s] failed to get user preview: %v", ctx.Value(requestId.ReqIDKey),
-			err)
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get user preview: %v",
+			ctx.Value(requestId.ReqIDKey), err)
 		return domain.UserPreview{}, err
 	}
 	return userPreview, nil
@@ -148,12 +259,21 @@ func (service *UsersService) GetUserPreview(ctx context.Context, uuid string) (d
 func (service *UsersService) ChangeUserPasswordByUuid(ctx context.Context, uuid, newPassword string) (domain.User,
 	error) {
 	service.metrics.IncRequestsTotal("ChangeUserPasswordByUuid")
-	user, err := service.storage.ChangeUserPasswordByUuid(uuid, newPassword)
+	passwordHash, err := passwords.Hash(newPassword)
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to hash password: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return domain.User{}, err
+	}
+
+	user, err := service.storage.ChangeUserPasswordByUuid(uuid, passwordHash)
 	if err != nil {
-		service.logger.Errorf("[reqid=%s] failed to change password: %v",
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to change password: %v",
 			ctx.Value(requestId.ReqIDKey), err)
 		return domain.User{}, err
 	}
+
+	service.recordAuditEvent(ctx, audit.NewEvent(ctx, audit.ActionPasswordChanged, user.Email, ""))
 	return user, nil
 }
 
@@ -161,10 +281,12 @@ func (service *UsersService) ChangeUserNameByUuid(ctx context.Context, uuid, new
 	service.metrics.IncRequestsTotal("ChangeUserNameByUuid")
 	user, err := service.storage.ChangeUserNameByUuid(uuid, newName)
 	if err != nil {
-		service.logger.Errorf("[reqid=%s] failed to change username: %v", ctx.Value(requestId.ReqIDKey),
-			err)
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to change username: %v",
+			ctx.Value(requestId.ReqIDKey), err)
 		return domain.User{}, err
 	}
+
+	service.recordAuditEvent(ctx, audit.NewEvent(ctx, audit.ActionUsernameChanged, user.Email, ""))
 	return user, nil
 }
 
@@ -172,8 +294,8 @@ func (service *UsersService) ChangeUserAvatarByUuid(ctx context.Context, uuid, n
 	service.metrics.IncRequestsTotal("ChangeUserAvatarByUuid")
 	user, err := service.storage.ChangeUserAvatarByUuid(uuid, newAvatar)
 	if err != nil {
-		service.logger.Errorf("[reqid=%s] failed to change username: %v", ctx.Value(requestId.ReqIDKey),
-			err)
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to change username: %v",
+			ctx.Value(requestId.ReqIDKey), err)
 		return domain.User{}, err
 	}
 	return user, nil
@@ -183,8 +305,8 @@ func (service *UsersService) HasSubscription(ctx context.Context, uuid string) (
 	service.metrics.IncRequestsTotal("HasSubscription")
 	stat, err := service.storage.HasSubscription(uuid)
 	if err != nil {
-		service.logger.Errorf("[reqid=%s] failed to check subscription: %v", ctx.Value(requestId.ReqIDKey),
-			err)
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to check subscription: %v",
+			ctx.Value(requestId.ReqIDKey), err)
 		return false, err
 	}
 	return stat, nil
@@ -195,8 +317,8 @@ func (service *UsersService) PaySubscription(ctx context.Context, uuid, subId st
 
 	sub, err := service.storage.GetSubscription(subId)
 	if err != nil {
-		service.logger.Errorf("[reqid=%s] failed to get subscription: %v", ctx.Value(requestId.ReqIDKey),
-			err)
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get subscription: %v",
+			ctx.Value(requestId.ReqIDKey), err)
 		return "", err
 	}
 
@@ -311,6 +433,12 @@ func (service *UsersService) PaySubscription(ctx context.Context, uuid, subId st
 		return "", err
 	}
 
+	// оплатить подписку можно только себе, поэтому владелец аккаунта и есть автор события
+	event := audit.NewEvent(ctx, audit.ActionSubscriptionPaid, "",
+		fmt.Sprintf("subscription=%s user=%s", subId, uuid))
+	event.Target = event.Actor
+	service.recordAuditEvent(ctx, event)
+
 	//return string(c["confirmation_url"])[1 : len(c["confirmation_url"])-1], nil
 	return "", nil
 }
@@ -319,8 +447,8 @@ func (service *UsersService) GetSubscriptions(ctx context.Context) ([]domain.Sub
 	service.metrics.IncRequestsTotal("AddSubscription")
 	subs, err := service.storage.GetSubscriptions()
 	if err != nil {
-		service.logger.Errorf("[reqid=%s] failed to get subscriptions: %v", ctx.Value(requestId.ReqIDKey),
-			err)
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get subscriptions: %v",
+			ctx.Value(requestId.ReqIDKey), err)
 		return nil, err
 	}
 	return subs, nil
@@ -330,9 +458,20 @@ func (service *UsersService) GetSubscription(ctx context.Context, uuid string) (
 	service.metrics.IncRequestsTotal("GetSubscription")
 	sub, err := service.storage.GetSubscription(uuid)
 	if err != nil {
-		service.logger.Errorf("[reqid=%s] failed to get subscription: %v", ctx.Value(requestId.ReqIDKey),
-			err)
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get subscription: %v",
+			ctx.Value(requestId.ReqIDKey), err)
 		return domain.Subscription{}, err
 	}
 	return sub, nil
 }
+
+func (service *UsersService) GetRolePermissions(ctx context.Context) (map[rbac.Role][]rbac.Permission, error) {
+	service.metrics.IncRequestsTotal("GetRolePermissions")
+	rolePermissions, err := service.storage.LoadPolicy(ctx)
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get role permissions: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return nil, err
+	}
+	return rolePermissions, nil
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	"testing"
 	"time"
 
@@ -10,8 +11,12 @@ import (
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap/zaptest"
 
+	"github.com/SanExpett/diploma/internal/audit"
 	"github.com/SanExpett/diploma/internal/domain"
+	myerrors "github.com/SanExpett/diploma/internal/errors"
 	"github.com/SanExpett/diploma/internal/metrics"
+	"github.com/SanExpett/diploma/internal/passwords"
+	"github.com/SanExpett/diploma/internal/principal"
 	mockService "github.com/SanExpett/diploma/internal/users/mocks"
 )
 
@@ -25,23 +30,77 @@ func TestAuthService_HasUser(t *testing.T) {
 	login := "cakethefake@gmail.com"
 	password := "123456789"
 
-	mockStorage.EXPECT().HasUser(login, password).Return(nil)
+	passwordHash, err := passwords.Hash(password)
+	assert.NoError(t, err)
+
+	mockStorage.EXPECT().GetPasswordHash(login).Return(passwordHash, nil)
 
 	metrics := metrics.NewGrpcMetrics("users")
 
-	authService := NewUsersService(mockStorage, metrics, mockLogger)
-	err := authService.HasUser(context.Background(), login, password)
+	authService := NewUsersService(mockStorage, nil, "", "", "", metrics, mockLogger)
+	err = authService.HasUser(context.Background(), login, password)
 
 	assert.NoError(t, err)
 
-	mockStorage.EXPECT().HasUser(login, password).Return(errors.New(""))
+	mockStorage.EXPECT().GetPasswordHash(login).Return(passwordHash, nil)
+
+	err = authService.HasUser(context.Background(), login, "wrongPassword")
+
+	assert.ErrorIs(t, err, myerrors.ErrIncorrectLoginOrPassword)
+
+	mockStorage.EXPECT().GetPasswordHash(login).Return("", fmt.Errorf("no user: %w",
+		myerrors.ErrIncorrectLoginOrPassword))
+
+	err = authService.HasUser(context.Background(), login, password)
+
+	assert.ErrorIs(t, err, myerrors.ErrIncorrectLoginOrPassword)
+
+	mockStorage.EXPECT().GetPasswordHash(login).Return("", errors.New(""))
 
-	authService = NewUsersService(mockStorage, metrics, mockLogger)
+	authService = NewUsersService(mockStorage, nil, "", "", "", metrics, mockLogger)
 	err = authService.HasUser(context.Background(), login, password)
 
 	assert.Error(t, err)
 }
 
+func TestAuthService_HasUserRehashesLegacyPassword(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mockService.NewMockusersStorage(ctrl)
+	mockLogger := zaptest.NewLogger(t).Sugar()
+
+	login := "cakethefake@gmail.com"
+	password := "123456789"
+
+	var newHash string
+	mockStorage.EXPECT().GetPasswordHash(login).Return(password, nil)
+	mockStorage.EXPECT().UpdatePasswordHash(login, gomock.Any()).DoAndReturn(
+		func(login, passwordHash string) error {
+			newHash = passwordHash
+			return nil
+		})
+
+	metrics := metrics.NewGrpcMetrics("users")
+
+	authService := NewUsersService(mockStorage, nil, "", "", "", metrics, mockLogger)
+	err := authService.HasUser(context.Background(), login, password)
+
+	assert.NoError(t, err)
+
+	ok, needsRehash, err := passwords.Verify(password, newHash)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.False(t, needsRehash)
+
+	mockStorage.EXPECT().GetPasswordHash(login).Return(password, nil)
+	mockStorage.EXPECT().UpdatePasswordHash(login, gomock.Any()).Return(errors.New(""))
+
+	err = authService.HasUser(context.Background(), login, password)
+
+	assert.NoError(t, err)
+}
+
 func TestAuthService_ChangeUserPassword(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -52,18 +111,19 @@ func TestAuthService_ChangeUserPassword(t *testing.T) {
 	login := "cakethefake@gmail.com"
 	newPassword := "newPassword123"
 
-	mockStorage.EXPECT().ChangeUserPassword(login, newPassword).Return(domain.User{}, nil)
+	mockStorage.EXPECT().ChangeUserPassword(login, gomock.Not(newPassword)).Return(domain.User{Email: login}, nil)
+	mockStorage.EXPECT().AddAuditEvent(gomock.Any()).Return(nil)
 
 	metrics := metrics.NewGrpcMetrics("users")
 
-	authService := NewUsersService(mockStorage, metrics, mockLogger)
+	authService := NewUsersService(mockStorage, nil, "", "", "", metrics, mockLogger)
 	_, err := authService.ChangeUserPassword(context.Background(), login, newPassword)
 
 	assert.NoError(t, err)
 
-	mockStorage.EXPECT().ChangeUserPassword(login, newPassword).Return(domain.User{}, errors.New(""))
+	mockStorage.EXPECT().ChangeUserPassword(login, gomock.Not(newPassword)).Return(domain.User{}, errors.New(""))
 
-	authService = NewUsersService(mockStorage, metrics, mockLogger)
+	authService = NewUsersService(mockStorage, nil, "", "", "", metrics, mockLogger)
 	_, err = authService.ChangeUserPassword(context.Background(), login, newPassword)
 
 	assert.Error(t, err)
@@ -80,10 +140,11 @@ func TestAuthService_ChangeUserName(t *testing.T) {
 	newName := "New Name"
 
 	mockStorage.EXPECT().ChangeUserName(login, newName).Return(domain.User{}, nil)
+	mockStorage.EXPECT().AddAuditEvent(gomock.Any()).Return(nil)
 
 	metrics := metrics.NewGrpcMetrics("users")
 
-	authService := NewUsersService(mockStorage, metrics, mockLogger)
+	authService := NewUsersService(mockStorage, nil, "", "", "", metrics, mockLogger)
 	_, err := authService.ChangeUserName(context.Background(), login, newName)
 
 	assert.NoError(t, err)
@@ -115,7 +176,7 @@ func TestAuthService_GetUserDataByUuid(t *testing.T) {
 
 	metrics := metrics.NewGrpcMetrics("users")
 
-	authService := NewUsersService(mockStorage, metrics, mockLogger)
+	authService := NewUsersService(mockStorage, nil, "", "", "", metrics, mockLogger)
 	retrievedUser, err := authService.GetUserDataByUuid(context.Background(), uuid)
 
 	assert.NoError(t, err)
@@ -123,7 +184,7 @@ func TestAuthService_GetUserDataByUuid(t *testing.T) {
 
 	mockStorage.EXPECT().GetUserDataByUuid(uuid).Return(user, errors.New(""))
 
-	authService = NewUsersService(mockStorage, metrics, mockLogger)
+	authService = NewUsersService(mockStorage, nil, "", "", "", metrics, mockLogger)
 	_, err = authService.GetUserDataByUuid(context.Background(), uuid)
 
 	assert.Error(t, err)
@@ -147,7 +208,7 @@ func TestAuthService_GetUserPreview(t *testing.T) {
 
 	metrics := metrics.NewGrpcMetrics("users")
 
-	authService := NewUsersService(mockStorage, metrics, mockLogger)
+	authService := NewUsersService(mockStorage, nil, "", "", "", metrics, mockLogger)
 	retrievedUserPreview, err := authService.GetUserPreview(context.Background(), uuid)
 
 	assert.NoError(t, err)
@@ -155,7 +216,7 @@ func TestAuthService_GetUserPreview(t *testing.T) {
 
 	mockStorage.EXPECT().GetUserPreview(uuid).Return(userPreview, errors.New(""))
 
-	authService = NewUsersService(mockStorage, metrics, mockLogger)
+	authService = NewUsersService(mockStorage, nil, "", "", "", metrics, mockLogger)
 	retrievedUserPreview, err = authService.GetUserPreview(context.Background(), uuid)
 
 	assert.Error(t, err)
@@ -171,11 +232,12 @@ func TestAuthService_ChangeUserPasswordByUuid(t *testing.T) {
 	uuid := "1"
 	newPassword := "newPassword123"
 
-	mockStorage.EXPECT().ChangeUserPasswordByUuid(uuid, newPassword).Return(domain.User{}, nil)
+	mockStorage.EXPECT().ChangeUserPasswordByUuid(uuid, gomock.Not(newPassword)).Return(domain.User{}, nil)
+	mockStorage.EXPECT().AddAuditEvent(gomock.Any()).Return(nil)
 
 	metrics := metrics.NewGrpcMetrics("users")
 
-	authService := NewUsersService(mockStorage, metrics, mockLogger)
+	authService := NewUsersService(mockStorage, nil, "", "", "", metrics, mockLogger)
 	_, err := authService.ChangeUserPasswordByUuid(context.Background(), uuid, newPassword)
 
 	assert.NoError(t, err)
@@ -192,10 +254,11 @@ func TestAuthService_ChangeUserNameByUuid(t *testing.T) {
 	newName := "New Name"
 
 	mockStorage.EXPECT().ChangeUserNameByUuid(uuid, newName).Return(domain.User{}, nil)
+	mockStorage.EXPECT().AddAuditEvent(gomock.Any()).Return(nil)
 
 	metrics := metrics.NewGrpcMetrics("users")
 
-	authService := NewUsersService(mockStorage, metrics, mockLogger)
+	authService := NewUsersService(mockStorage, nil, "", "", "", metrics, mockLogger)
 	_, err := authService.ChangeUserNameByUuid(context.Background(), uuid, newName)
 
 	assert.NoError(t, err)
@@ -215,7 +278,7 @@ func TestAuthService_GetUser(t *testing.T) {
 
 	metrics := metrics.NewGrpcMetrics("users")
 
-	authService := NewUsersService(mockStorage, metrics, mockLogger)
+	authService := NewUsersService(mockStorage, nil, "", "", "", metrics, mockLogger)
 	user, err := authService.GetUser(context.Background(), login)
 
 	assert.NoError(t, err)
@@ -235,14 +298,21 @@ func TestAuthService_CreateUser(t *testing.T) {
 		Password: "password",
 	}
 
-	mockStorage.EXPECT().CreateUser(user).Return(nil)
+	mockStorage.EXPECT().CreateUser(gomock.Any()).DoAndReturn(func(created domain.UserSignUp) error {
+		assert.Equal(t, user.Email, created.Email)
+		assert.NotEqual(t, user.Password, created.Password)
+		return nil
+	})
+	mockStorage.EXPECT().SaveEmailVerificationToken(user.Email, gomock.Any(), gomock.Any()).Return(nil)
 
 	metrics := metrics.NewGrpcMetrics("users")
+	recorder := &recordingMailer{}
 
-	authService := NewUsersService(mockStorage, metrics, mockLogger)
+	authService := NewUsersService(mockStorage, recorder, "", "https://nimbus.test/verify", "", metrics, mockLogger)
 	err := authService.CreateUser(context.Background(), user)
 
 	assert.NoError(t, err)
+	assert.Len(t, recorder.messages, 1, "после регистрации отправляется письмо с подтверждением почты")
 }
 
 func TestAuthService_RemoveUser(t *testing.T) {
@@ -255,11 +325,22 @@ func TestAuthService_RemoveUser(t *testing.T) {
 	login := "test@example.com"
 
 	mockStorage.EXPECT().RemoveUser(login).Return(nil)
+	mockStorage.EXPECT().AddAuditEvent(gomock.Any()).DoAndReturn(func(event domain.AuditEvent) error {
+		assert.Equal(t, string(audit.ActionAccountRemoved), event.Action)
+		assert.Equal(t, "admin@example.com", event.Actor, "автор события администратор из метаданных запроса")
+		assert.Equal(t, login, event.Target)
+		assert.Equal(t, "192.0.2.1", event.Ip)
+		assert.Equal(t, "request-id", event.RequestId)
+		return nil
+	})
 
 	metrics := metrics.NewGrpcMetrics("users")
 
-	authService := NewUsersService(mockStorage, metrics, mockLogger)
-	err := authService.RemoveUser(context.Background(), login)
+	ctx := principal.WithPrincipal(context.Background(), principal.Principal{Login: "admin@example.com"})
+	ctx = audit.WithRequestInfo(ctx, audit.RequestInfo{Ip: "192.0.2.1", RequestId: "request-id"})
+
+	authService := NewUsersService(mockStorage, nil, "", "", "", metrics, mockLogger)
+	err := authService.RemoveUser(ctx, login)
 
 	assert.NoError(t, err)
 }
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	myerrors "github.com/SanExpett/diploma/internal/errors"
+	"github.com/SanExpett/diploma/internal/mailer"
+	"github.com/SanExpett/diploma/internal/requestId"
+	"github.com/SanExpett/diploma/internal/tracing"
+)
+
+const (
+	emailVerificationTokenTTL = 24 * time.Hour
+	// повторно письмо можно запросить не раньше, чем через verificationResendCooldown после предыдущего,
+	// и не больше verificationResendLimit раз за verificationResendWindow
+	verificationResendCooldown = time.Minute
+	verificationResendWindow   = time.Hour
+	verificationResendLimit    = 5
+)
+
+// ResendEmailVerification повторно отправляет письмо с подтверждением почты
+func (service *UsersService) ResendEmailVerification(ctx context.Context, login string) error {
+	service.metrics.IncRequestsTotal("ResendEmailVerification")
+	now := time.Now()
+	status, err := service.storage.GetEmailVerificationStatus(login, now.Add(-verificationResendWindow))
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get email verification status: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return err
+	}
+	if status.Verified {
+		return myerrors.ErrEmailAlreadyVerified
+	}
+	if status.SentRecently >= verificationResendLimit || now.Sub(status.LastSentAt) < verificationResendCooldown {
+		service.logger.With(tracing.TraceField(ctx)).Infof("[reqid=%s] verification email resend is throttled",
+			ctx.Value(requestId.ReqIDKey))
+		return myerrors.ErrTooManyVerificationRequests
+	}
+
+	return service.sendEmailVerification(ctx, login)
+}
+
+// VerifyEmail подтверждает почту по токену из письма и возвращает email пользователя
+func (service *UsersService) VerifyEmail(ctx context.Context, token string) (string, error) {
+	service.metrics.IncRequestsTotal("VerifyEmail")
+	login, err := service.storage.VerifyEmail(hashToken(token))
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to verify email: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return "", err
+	}
+	return login, nil
+}
+
+func (service *UsersService) sendEmailVerification(ctx context.Context, login string) error {
+	token, link, err := newMailToken(service.verifyURL)
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to generate verification token: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return err
+	}
+
+	err = service.storage.SaveEmailVerificationToken(login, hashToken(token),
+		time.Now().Add(emailVerificationTokenTTL))
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to save verification token: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return err
+	}
+
+	err = service.mailer.Send(ctx, mailer.Message{
+		To:      login,
+		Subject: "Подтверждение почты",
+		Body: fmt.Sprintf("Чтобы подтвердить адрес, перейдите по ссылке:\n%s\n\n"+
+			"Ссылка действует %d часа. Пока адрес не подтвержден, нельзя оставлять комментарии "+
+			"и оплачивать подписку.", link, int(emailVerificationTokenTTL.Hours())),
+	})
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to send verification mail: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return err
+	}
+	return nil
+}
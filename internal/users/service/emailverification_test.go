@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/SanExpett/diploma/internal/domain"
+	myerrors "github.com/SanExpett/diploma/internal/errors"
+	"github.com/SanExpett/diploma/internal/metrics"
+	mockService "github.com/SanExpett/diploma/internal/users/mocks"
+)
+
+func TestUsersService_ResendEmailVerification(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mockService.NewMockusersStorage(ctrl)
+	recorder := &recordingMailer{}
+	usersService := NewUsersService(mockStorage, recorder, "", "https://nimbus.test/verify", "",
+		metrics.NewGrpcMetrics("users"), zaptest.NewLogger(t).Sugar())
+
+	tests := []struct {
+		name          string
+		status        domain.EmailVerificationStatus
+		expectedErr   error
+		expectedMails int
+	}{
+		{
+			name:          "Письмо отправляется",
+			status:        domain.EmailVerificationStatus{SentRecently: 1, LastSentAt: time.Now().Add(-10 * time.Minute)},
+			expectedMails: 1,
+		},
+		{
+			name:        "Почта уже подтверждена",
+			status:      domain.EmailVerificationStatus{Verified: true},
+			expectedErr: myerrors.ErrEmailAlreadyVerified,
+		},
+		{
+			name:        "Письмо отправлено только что",
+			status:      domain.EmailVerificationStatus{SentRecently: 1, LastSentAt: time.Now()},
+			expectedErr: myerrors.ErrTooManyVerificationRequests,
+		},
+		{
+			name: "Превышен лимит писем за час",
+			status: domain.EmailVerificationStatus{SentRecently: verificationResendLimit,
+				LastSentAt: time.Now().Add(-10 * time.Minute)},
+			expectedErr: myerrors.ErrTooManyVerificationRequests,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder.messages = nil
+			mockStorage.EXPECT().GetEmailVerificationStatus("test@test.com", gomock.Any()).Return(tt.status, nil)
+			if tt.expectedErr == nil {
+				mockStorage.EXPECT().SaveEmailVerificationToken("test@test.com", gomock.Any(), gomock.Any()).
+					Return(nil)
+			}
+
+			err := usersService.ResendEmailVerification(context.Background(), "test@test.com")
+			assert.ErrorIs(t, err, tt.expectedErr)
+			assert.Len(t, recorder.messages, tt.expectedMails)
+		})
+	}
+}
+
+func TestUsersService_VerifyEmail(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mockService.NewMockusersStorage(ctrl)
+	usersService := NewUsersService(mockStorage, nil, "", "", "", metrics.NewGrpcMetrics("users"),
+		zaptest.NewLogger(t).Sugar())
+
+	mockStorage.EXPECT().VerifyEmail(hashToken("token")).Return("test@test.com", nil)
+
+	login, err := usersService.VerifyEmail(context.Background(), "token")
+	require.NoError(t, err)
+	assert.Equal(t, "test@test.com", login)
+
+	mockStorage.EXPECT().VerifyEmail(hashToken("used")).Return("", myerrors.ErrInvalidVerificationToken)
+
+	_, err = usersService.VerifyEmail(context.Background(), "used")
+	assert.ErrorIs(t, err, myerrors.ErrInvalidVerificationToken)
+}
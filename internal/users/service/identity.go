@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+
+	"github.com/SanExpett/diploma/internal/domain"
+	myerrors "github.com/SanExpett/diploma/internal/errors"
+	"github.com/SanExpett/diploma/internal/passwords"
+	"github.com/SanExpett/diploma/internal/requestId"
+	"github.com/SanExpett/diploma/internal/tracing"
+)
+
+const identityDefaultName = "user"
+
+// LoginWithIdentity находит или создает пользователя для внешнего аккаунта. Если передан linkLogin,
+// аккаунт привязывается к этому, уже вошедшему пользователю. К существующему пользователю с той же почтой
+// аккаунт привязывается сам, только если почту подтвердили и провайдер, и мы, иначе чужой аккаунт
+// с неподтвержденной почтой можно было бы захватить
+func (service *UsersService) LoginWithIdentity(ctx context.Context, identity domain.Identity,
+	linkLogin string) (domain.User, error) {
+	service.metrics.IncRequestsTotal("LoginWithIdentity")
+	if linkLogin != "" {
+		return service.linkIdentity(ctx, identity, linkLogin)
+	}
+
+	user, err := service.storage.GetUserByIdentity(identity.Provider, identity.Subject)
+	if err == nil {
+		return user, nil
+	}
+	if !errors.Is(err, myerrors.ErrNoSuchUser) {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get user by identity: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return domain.User{}, err
+	}
+	if identity.Email == "" {
+		return domain.User{}, myerrors.ErrLoginIsNotValid
+	}
+
+	err = service.createUserWithIdentity(ctx, identity)
+	if errors.Is(err, myerrors.ErrUserAlreadyExists) {
+		user, err = service.storage.GetUser(identity.Email)
+		if err != nil {
+			service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get user: %v",
+				ctx.Value(requestId.ReqIDKey), err)
+			return domain.User{}, err
+		}
+		if !identity.EmailVerified || !user.EmailVerified {
+			return domain.User{}, myerrors.ErrAccountExistsForIdentity
+		}
+		return service.linkIdentity(ctx, identity, identity.Email)
+	}
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	user, err = service.storage.GetUser(identity.Email)
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get user: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return domain.User{}, err
+	}
+	return user, nil
+}
+
+func (service *UsersService) linkIdentity(ctx context.Context, identity domain.Identity,
+	login string) (domain.User, error) {
+	err := service.storage.LinkIdentity(login, identity)
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to link identity: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return domain.User{}, err
+	}
+
+	// провайдер подтвердил владение той же почтой, письмо можно не отправлять
+	if identity.EmailVerified && identity.Email == login {
+		err = service.storage.MarkEmailVerified(login)
+		if err != nil {
+			service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to mark email verified: %v",
+				ctx.Value(requestId.ReqIDKey), err)
+		}
+	}
+
+	user, err := service.storage.GetUser(login)
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get user: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return domain.User{}, err
+	}
+	return user, nil
+}
+
+// createUserWithIdentity регистрирует пользователя без пароля. Вместо пароля сохраняется хеш случайной строки,
+// войти по паролю можно будет после его восстановления через почту
+func (service *UsersService) createUserWithIdentity(ctx context.Context, identity domain.Identity) error {
+	passwordHash, err := randomPasswordHash()
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to generate password: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return err
+	}
+
+	name := identity.Name
+	if name == "" {
+		name = identityDefaultName
+	}
+
+	err = service.storage.CreateUserWithIdentity(domain.UserSignUp{
+		Email:    identity.Email,
+		Name:     name,
+		Password: passwordHash,
+	}, identity)
+	if err != nil {
+		if !errors.Is(err, myerrors.ErrUserAlreadyExists) {
+			service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to create user with identity: %v",
+				ctx.Value(requestId.ReqIDKey), err)
+		}
+		return err
+	}
+
+	if !identity.EmailVerified {
+		// как и при обычной регистрации, письмо можно запросить повторно
+		_ = service.sendEmailVerification(ctx, identity.Email)
+	}
+	return nil
+}
+
+// randomPasswordHash возвращает хеш случайного пароля, который никто не знает
+func randomPasswordHash() (string, error) {
+	randomPassword := make([]byte, 32)
+	_, err := rand.Read(randomPassword)
+	if err != nil {
+		return "", err
+	}
+	return passwords.Hash(base64.RawURLEncoding.EncodeToString(randomPassword))
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/SanExpett/diploma/internal/domain"
+	myerrors "github.com/SanExpett/diploma/internal/errors"
+	"github.com/SanExpett/diploma/internal/metrics"
+	mockService "github.com/SanExpett/diploma/internal/users/mocks"
+)
+
+func TestUsersService_LoginWithIdentity(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mockService.NewMockusersStorage(ctrl)
+	recorder := &recordingMailer{}
+	usersService := NewUsersService(mockStorage, recorder, "", "http://localhost/verify", "",
+		metrics.NewGrpcMetrics("users"), zaptest.NewLogger(t).Sugar())
+
+	identity := domain.Identity{Provider: "mock", Subject: "42", Email: "test@test.com", EmailVerified: true}
+	unverified := identity
+	unverified.EmailVerified = false
+
+	tests := []struct {
+		name         string
+		identity     domain.Identity
+		linkLogin    string
+		setupMocks   func()
+		expectedUser domain.User
+		expectedErr  error
+		expectedMail int
+	}{
+		{
+			name:     "Аккаунт уже привязан",
+			identity: identity,
+			setupMocks: func() {
+				mockStorage.EXPECT().GetUserByIdentity("mock", "42").Return(domain.User{Email: "other@test.com"}, nil)
+			},
+			expectedUser: domain.User{Email: "other@test.com"},
+		},
+		{
+			name:     "Новый пользователь с подтвержденной почтой",
+			identity: identity,
+			setupMocks: func() {
+				mockStorage.EXPECT().GetUserByIdentity("mock", "42").Return(domain.User{}, myerrors.ErrNoSuchUser)
+				mockStorage.EXPECT().CreateUserWithIdentity(gomock.Any(), identity).
+					DoAndReturn(func(user domain.UserSignUp, _ domain.Identity) error {
+						assert.Equal(t, "test@test.com", user.Email)
+						assert.Equal(t, identityDefaultName, user.Name)
+						assert.NotEmpty(t, user.Password)
+						return nil
+					})
+				mockStorage.EXPECT().GetUser("test@test.com").Return(domain.User{Email: "test@test.com"}, nil)
+			},
+			expectedUser: domain.User{Email: "test@test.com"},
+		},
+		{
+			name:     "Новый пользователь с неподтвержденной почтой получает письмо",
+			identity: unverified,
+			setupMocks: func() {
+				mockStorage.EXPECT().GetUserByIdentity("mock", "42").Return(domain.User{}, myerrors.ErrNoSuchUser)
+				mockStorage.EXPECT().CreateUserWithIdentity(gomock.Any(), unverified).Return(nil)
+				mockStorage.EXPECT().SaveEmailVerificationToken("test@test.com", gomock.Any(), gomock.Any()).
+					Return(nil)
+				mockStorage.EXPECT().GetUser("test@test.com").Return(domain.User{Email: "test@test.com"}, nil)
+			},
+			expectedUser: domain.User{Email: "test@test.com"},
+			expectedMail: 1,
+		},
+		{
+			name:     "Почта занята пользователем с подтвержденной почтой",
+			identity: identity,
+			setupMocks: func() {
+				mockStorage.EXPECT().GetUserByIdentity("mock", "42").Return(domain.User{}, myerrors.ErrNoSuchUser)
+				mockStorage.EXPECT().CreateUserWithIdentity(gomock.Any(), identity).
+					Return(myerrors.ErrUserAlreadyExists)
+				mockStorage.EXPECT().GetUser("test@test.com").
+					Return(domain.User{Email: "test@test.com", EmailVerified: true}, nil)
+				mockStorage.EXPECT().LinkIdentity("test@test.com", identity).Return(nil)
+				mockStorage.EXPECT().MarkEmailVerified("test@test.com").Return(nil)
+				mockStorage.EXPECT().GetUser("test@test.com").
+					Return(domain.User{Email: "test@test.com", EmailVerified: true}, nil)
+			},
+			expectedUser: domain.User{Email: "test@test.com", EmailVerified: true},
+		},
+		{
+			name:     "Почта занята, провайдер ее не подтвердил",
+			identity: unverified,
+			setupMocks: func() {
+				mockStorage.EXPECT().GetUserByIdentity("mock", "42").Return(domain.User{}, myerrors.ErrNoSuchUser)
+				mockStorage.EXPECT().CreateUserWithIdentity(gomock.Any(), unverified).
+					Return(myerrors.ErrUserAlreadyExists)
+				mockStorage.EXPECT().GetUser("test@test.com").
+					Return(domain.User{Email: "test@test.com", EmailVerified: true}, nil)
+			},
+			expectedErr: myerrors.ErrAccountExistsForIdentity,
+		},
+		{
+			name:     "Почта занята пользователем с неподтвержденной почтой",
+			identity: identity,
+			setupMocks: func() {
+				mockStorage.EXPECT().GetUserByIdentity("mock", "42").Return(domain.User{}, myerrors.ErrNoSuchUser)
+				mockStorage.EXPECT().CreateUserWithIdentity(gomock.Any(), identity).
+					Return(myerrors.ErrUserAlreadyExists)
+				mockStorage.EXPECT().GetUser("test@test.com").Return(domain.User{Email: "test@test.com"}, nil)
+			},
+			expectedErr: myerrors.ErrAccountExistsForIdentity,
+		},
+		{
+			name:      "Привязка к вошедшему пользователю",
+			identity:  identity,
+			linkLogin: "me@test.com",
+			setupMocks: func() {
+				mockStorage.EXPECT().LinkIdentity("me@test.com", identity).Return(nil)
+				mockStorage.EXPECT().GetUser("me@test.com").Return(domain.User{Email: "me@test.com"}, nil)
+			},
+			expectedUser: domain.User{Email: "me@test.com"},
+		},
+		{
+			name:      "Аккаунт привязан к другому пользователю",
+			identity:  identity,
+			linkLogin: "me@test.com",
+			setupMocks: func() {
+				mockStorage.EXPECT().LinkIdentity("me@test.com", identity).Return(myerrors.ErrIdentityAlreadyLinked)
+			},
+			expectedErr: myerrors.ErrIdentityAlreadyLinked,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder.messages = nil
+			test.setupMocks()
+
+			user, err := usersService.LoginWithIdentity(context.Background(), test.identity, test.linkLogin)
+			if test.expectedErr != nil {
+				assert.ErrorIs(t, err, test.expectedErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expectedUser, user)
+			}
+			assert.Len(t, recorder.messages, test.expectedMail)
+		})
+	}
+}
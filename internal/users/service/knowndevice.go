@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"time"
+
+	"github.com/SanExpett/diploma/internal/audit"
+	"github.com/SanExpett/diploma/internal/domain"
+	"github.com/SanExpett/diploma/internal/mailer"
+	"github.com/SanExpett/diploma/internal/requestId"
+	"github.com/SanExpett/diploma/internal/tracing"
+)
+
+const unknownLoginTokenTTL = 7 * 24 * time.Hour
+
+// RegisterLoginDevice запоминает устройство, с которого вошел пользователь, и возвращает true, если раньше
+// с него не входили. О новом устройстве пользователю отправляется письмо со ссылкой «это был не я».
+// Первое устройство аккаунта запоминается без письма
+func (service *UsersService) RegisterLoginDevice(ctx context.Context, login, userAgent, ip string) (bool, error) {
+	service.metrics.IncRequestsTotal("RegisterLoginDevice")
+	devices, err := service.storage.GetKnownDevices(login)
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get known devices: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return false, err
+	}
+
+	fingerprint := deviceFingerprint(userAgent, ip)
+	newDevice := true
+	for _, device := range devices {
+		if device.Fingerprint == fingerprint {
+			newDevice = false
+			break
+		}
+	}
+
+	err = service.storage.SaveKnownDevice(login, domain.KnownDevice{
+		Fingerprint: fingerprint,
+		UserAgent:   userAgent,
+		Ip:          ip,
+		LastSeenAt:  time.Now(),
+	})
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to save known device: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return false, err
+	}
+
+	if !newDevice || len(devices) == 0 {
+		return newDevice, nil
+	}
+	return true, service.sendUnknownLoginAlert(ctx, login, fingerprint, userAgent, ip)
+}
+
+// ReportUnknownLogin обрабатывает ссылку «это был не я»: забывает устройство, заменяет пароль случайным
+// и отправляет письмо восстановления пароля. Возвращает email пользователя, чтобы gateway завершил его сессии
+func (service *UsersService) ReportUnknownLogin(ctx context.Context, token string) (string, error) {
+	service.metrics.IncRequestsTotal("ReportUnknownLogin")
+	passwordHash, err := randomPasswordHash()
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to generate password: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return "", err
+	}
+
+	login, err := service.storage.ReportUnknownLogin(hashToken(token), passwordHash)
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to report unknown login: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return "", err
+	}
+
+	// ссылку открывает владелец почты, он не вошел
+	event := audit.NewEvent(ctx, audit.ActionUnknownLoginReported, login, "")
+	event.Actor = login
+	service.recordAuditEvent(ctx, event)
+
+	// пароль уже заменен, поэтому без письма пользователь восстановит его обычным запросом
+	err = service.RequestPasswordReset(ctx, login)
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to request password reset: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+	}
+	return login, nil
+}
+
+func (service *UsersService) sendUnknownLoginAlert(ctx context.Context, login, fingerprint, userAgent,
+	ip string) error {
+	token, link, err := newMailToken(service.unknownLoginURL)
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to generate unknown login token: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return err
+	}
+
+	err = service.storage.SaveUnknownLoginToken(login, hashToken(token), fingerprint,
+		time.Now().Add(unknownLoginTokenTTL))
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to save unknown login token: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return err
+	}
+
+	err = service.mailer.Send(ctx, mailer.Message{
+		To:      login,
+		Subject: "Вход с нового устройства",
+		Body: fmt.Sprintf("В ваш аккаунт вошли с нового устройства:\n%s\nIP: %s\n\n"+
+			"Если это были не вы, перейдите по ссылке:\n%s\n\n"+
+			"Мы завершим все сеансы и попросим задать новый пароль. Ссылка действует %d дней.",
+			userAgent, ip, link, int(unknownLoginTokenTTL.Hours()/24)),
+	})
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to send unknown login mail: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return err
+	}
+	return nil
+}
+
+// deviceFingerprint хеш user agent и сети клиента. Берется сеть, а не адрес, чтобы смена адреса
+// у того же провайдера не считалась новым устройством
+func deviceFingerprint(userAgent, ip string) string {
+	network := ip
+	addr, err := netip.ParseAddr(ip)
+	if err == nil {
+		addr = addr.Unmap()
+		bits := 64
+		if addr.Is4() {
+			bits = 24
+		}
+		prefix, err := addr.Prefix(bits)
+		if err == nil {
+			network = prefix.String()
+		}
+	}
+	return hashToken(userAgent + "\n" + network)
+}
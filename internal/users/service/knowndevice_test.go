@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/SanExpett/diploma/internal/domain"
+	myerrors "github.com/SanExpett/diploma/internal/errors"
+	"github.com/SanExpett/diploma/internal/metrics"
+	mockService "github.com/SanExpett/diploma/internal/users/mocks"
+)
+
+func TestUsersService_RegisterLoginDevice(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mockService.NewMockusersStorage(ctrl)
+	recorder := &recordingMailer{}
+	usersService := NewUsersService(mockStorage, recorder, "", "", "https://nimbus.test/not-me",
+		metrics.NewGrpcMetrics("users"), zaptest.NewLogger(t).Sugar())
+
+	laptop := domain.KnownDevice{Fingerprint: deviceFingerprint("Firefox", "192.0.2.1")}
+
+	tests := []struct {
+		name              string
+		userAgent         string
+		ip                string
+		devices           []domain.KnownDevice
+		expectedNewDevice bool
+		expectedMessages  int
+	}{
+		{
+			name:              "Первое устройство аккаунта",
+			userAgent:         "Firefox",
+			ip:                "192.0.2.1",
+			expectedNewDevice: true,
+		},
+		{
+			name:      "Знакомое устройство с другим адресом той же сети",
+			userAgent: "Firefox",
+			ip:        "192.0.2.77",
+			devices:   []domain.KnownDevice{laptop},
+		},
+		{
+			name:              "Новое устройство",
+			userAgent:         "Chrome",
+			ip:                "198.51.100.7",
+			devices:           []domain.KnownDevice{laptop},
+			expectedNewDevice: true,
+			expectedMessages:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder.messages = nil
+			fingerprint := deviceFingerprint(tt.userAgent, tt.ip)
+
+			mockStorage.EXPECT().GetKnownDevices("test@test.com").Return(tt.devices, nil)
+			mockStorage.EXPECT().SaveKnownDevice("test@test.com", gomock.Any()).
+				DoAndReturn(func(_ string, device domain.KnownDevice) error {
+					assert.Equal(t, fingerprint, device.Fingerprint)
+					assert.Equal(t, tt.ip, device.Ip)
+					return nil
+				})
+			var savedHash string
+			if tt.expectedMessages > 0 {
+				mockStorage.EXPECT().SaveUnknownLoginToken("test@test.com", gomock.Any(), fingerprint, gomock.Any()).
+					DoAndReturn(func(_, tokenHash, _ string, expiresAt time.Time) error {
+						savedHash = tokenHash
+						assert.WithinDuration(t, time.Now().Add(unknownLoginTokenTTL), expiresAt, time.Minute)
+						return nil
+					})
+			}
+
+			newDevice, err := usersService.RegisterLoginDevice(context.Background(), "test@test.com",
+				tt.userAgent, tt.ip)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedNewDevice, newDevice)
+			require.Len(t, recorder.messages, tt.expectedMessages)
+			if tt.expectedMessages == 0 {
+				return
+			}
+
+			body := recorder.messages[0].Body
+			assert.Contains(t, body, tt.userAgent)
+			linkStart := strings.Index(body, "https://")
+			require.NotEqual(t, -1, linkStart)
+			link, err := url.Parse(strings.Fields(body[linkStart:])[0])
+			require.NoError(t, err)
+			assert.Equal(t, "/not-me", link.Path)
+			assert.Equal(t, hashToken(link.Query().Get("token")), savedHash)
+		})
+	}
+}
+
+func TestUsersService_ReportUnknownLogin(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mockService.NewMockusersStorage(ctrl)
+	recorder := &recordingMailer{}
+	usersService := NewUsersService(mockStorage, recorder, "https://nimbus.test/reset", "", "",
+		metrics.NewGrpcMetrics("users"), zaptest.NewLogger(t).Sugar())
+
+	mockStorage.EXPECT().ReportUnknownLogin(hashToken("token"), gomock.Any()).Return("test@test.com", nil)
+	mockStorage.EXPECT().AddAuditEvent(gomock.Any()).
+		DoAndReturn(func(event domain.AuditEvent) error {
+			assert.Equal(t, "login.reported", event.Action)
+			assert.Equal(t, "test@test.com", event.Actor)
+			return nil
+		})
+	mockStorage.EXPECT().SavePasswordResetToken("test@test.com", gomock.Any(), gomock.Any()).Return(nil)
+
+	login, err := usersService.ReportUnknownLogin(context.Background(), "token")
+	require.NoError(t, err)
+	assert.Equal(t, "test@test.com", login)
+	require.Len(t, recorder.messages, 1)
+	assert.Contains(t, recorder.messages[0].Body, "https://nimbus.test/reset")
+
+	mockStorage.EXPECT().ReportUnknownLogin(hashToken("used"), gomock.Any()).
+		Return("", myerrors.ErrInvalidUnknownLoginToken)
+
+	_, err = usersService.ReportUnknownLogin(context.Background(), "used")
+	assert.ErrorIs(t, err, myerrors.ErrInvalidUnknownLoginToken)
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	myerrors "github.com/SanExpett/diploma/internal/errors"
+	"github.com/SanExpett/diploma/internal/mailer"
+	"github.com/SanExpett/diploma/internal/requestId"
+	"github.com/SanExpett/diploma/internal/tracing"
+)
+
+// SendMagicLink запоминает ссылку для входа, выданную gateway, и отправляет ее на email. Ранее выданные
+// ссылки перестают действовать. Для незарегистрированного адреса ошибка не возвращается, чтобы по ответу
+// нельзя было узнать, есть ли такой пользователь
+func (service *UsersService) SendMagicLink(ctx context.Context, login, link, tokenId string,
+	expiresAt time.Time) error {
+	service.metrics.IncRequestsTotal("SendMagicLink")
+	err := service.storage.SaveMagicLinkToken(login, hashToken(tokenId), expiresAt)
+	if errors.Is(err, myerrors.ErrNoSuchUser) {
+		service.logger.With(tracing.TraceField(ctx)).Infof("[reqid=%s] magic link requested for unknown user",
+			ctx.Value(requestId.ReqIDKey))
+		return nil
+	}
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to save magic link: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return err
+	}
+
+	err = service.mailer.Send(ctx, mailer.Message{
+		To:      login,
+		Subject: "Вход без пароля",
+		Body: fmt.Sprintf("Чтобы войти, перейдите по ссылке:\n%s\n\n"+
+			"Ссылка действует %d минут, работает один раз и только в браузере, в котором вы ее запросили. "+
+			"Если вы не запрашивали вход, просто проигнорируйте это письмо.", link,
+			int(time.Until(expiresAt).Round(time.Minute).Minutes())),
+	})
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to send magic link mail: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return err
+	}
+	return nil
+}
+
+// ConsumeMagicLink гасит ссылку для входа. Повторное использование и ссылка, замененная более новой,
+// дают ErrInvalidMagicLink
+func (service *UsersService) ConsumeMagicLink(ctx context.Context, login, tokenId string) error {
+	service.metrics.IncRequestsTotal("ConsumeMagicLink")
+	err := service.storage.ConsumeMagicLinkToken(login, hashToken(tokenId))
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to consume magic link: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return err
+	}
+	return nil
+}
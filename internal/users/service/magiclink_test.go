@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	myerrors "github.com/SanExpett/diploma/internal/errors"
+	"github.com/SanExpett/diploma/internal/metrics"
+	mockService "github.com/SanExpett/diploma/internal/users/mocks"
+)
+
+func TestUsersService_SendMagicLink(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mockService.NewMockusersStorage(ctrl)
+	recorder := &recordingMailer{}
+	usersService := NewUsersService(mockStorage, recorder, "", "", "", metrics.NewGrpcMetrics("users"),
+		zaptest.NewLogger(t).Sugar())
+
+	expiresAt := time.Now().Add(15 * time.Minute)
+	link := "https://nimbus.test/magic-link?token=signed"
+
+	mockStorage.EXPECT().SaveMagicLinkToken("test@test.com", hashToken("token-id"), expiresAt).Return(nil)
+
+	require.NoError(t, usersService.SendMagicLink(context.Background(), "test@test.com", link, "token-id",
+		expiresAt))
+	require.Len(t, recorder.messages, 1)
+	assert.Equal(t, "test@test.com", recorder.messages[0].To)
+	assert.Contains(t, recorder.messages[0].Body, link)
+	assert.Contains(t, recorder.messages[0].Body, "15 минут")
+
+	mockStorage.EXPECT().SaveMagicLinkToken("unknown@test.com", gomock.Any(), expiresAt).
+		Return(myerrors.ErrNoSuchUser)
+
+	assert.NoError(t, usersService.SendMagicLink(context.Background(), "unknown@test.com", link, "token-id",
+		expiresAt), "ответ не выдает, зарегистрирован ли адрес")
+	assert.Len(t, recorder.messages, 1)
+}
+
+func TestUsersService_ConsumeMagicLink(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mockService.NewMockusersStorage(ctrl)
+	usersService := NewUsersService(mockStorage, nil, "", "", "", metrics.NewGrpcMetrics("users"),
+		zaptest.NewLogger(t).Sugar())
+
+	mockStorage.EXPECT().ConsumeMagicLinkToken("test@test.com", hashToken("token-id")).Return(nil)
+	mockStorage.EXPECT().ConsumeMagicLinkToken("test@test.com", hashToken("token-id")).
+		Return(myerrors.ErrInvalidMagicLink)
+
+	assert.NoError(t, usersService.ConsumeMagicLink(context.Background(), "test@test.com", "token-id"))
+	assert.ErrorIs(t, usersService.ConsumeMagicLink(context.Background(), "test@test.com", "token-id"),
+		myerrors.ErrInvalidMagicLink)
+}
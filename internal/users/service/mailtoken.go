@@ -0,0 +1,36 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/url"
+)
+
+// newMailToken создает одноразовый токен для письма и ссылку на страницу фронтенда baseURL с этим токеном
+func newMailToken(baseURL string) (string, string, error) {
+	tokenBytes := make([]byte, 32)
+	_, err := rand.Read(tokenBytes)
+	if err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(tokenBytes)
+
+	link, err := url.Parse(baseURL)
+	if err != nil {
+		return "", "", err
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	return token, link.String(), nil
+}
+
+// hashToken возвращает хеш токена или кода восстановления, под которым он хранится в базе, чтобы утечка
+// базы не давала воспользоваться выданными токенами
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+
+	"github.com/SanExpett/diploma/internal/domain"
+	"github.com/SanExpett/diploma/internal/requestId"
+	"github.com/SanExpett/diploma/internal/tracing"
+)
+
+// AddPasskey сохраняет passkey, регистрацию которого проверил gateway
+func (service *UsersService) AddPasskey(ctx context.Context, login string, passkey domain.Passkey) error {
+	service.metrics.IncRequestsTotal("AddPasskey")
+	err := service.storage.AddPasskey(login, passkey)
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to add passkey: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return err
+	}
+	return nil
+}
+
+func (service *UsersService) GetPasskeys(ctx context.Context, login string) ([]domain.Passkey, error) {
+	service.metrics.IncRequestsTotal("GetPasskeys")
+	passkeys, err := service.storage.GetPasskeys(login)
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get passkeys: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return nil, err
+	}
+	return passkeys, nil
+}
+
+// GetPasskey возвращает passkey по id вместе с логином владельца, по нему gateway проверяет вход
+func (service *UsersService) GetPasskey(ctx context.Context, id string) (string, domain.Passkey, error) {
+	service.metrics.IncRequestsTotal("GetPasskey")
+	login, passkey, err := service.storage.GetPasskey(id)
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get passkey: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return "", domain.Passkey{}, err
+	}
+	return login, passkey, nil
+}
+
+func (service *UsersService) UpdatePasskeySignCount(ctx context.Context, id string, signCount uint32) error {
+	service.metrics.IncRequestsTotal("UpdatePasskeySignCount")
+	err := service.storage.UpdatePasskeySignCount(id, signCount)
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to update passkey sign count: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return err
+	}
+	return nil
+}
+
+func (service *UsersService) RemovePasskey(ctx context.Context, login, id string) error {
+	service.metrics.IncRequestsTotal("RemovePasskey")
+	err := service.storage.RemovePasskey(login, id)
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to remove passkey: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/SanExpett/diploma/internal/audit"
+	myerrors "github.com/SanExpett/diploma/internal/errors"
+	"github.com/SanExpett/diploma/internal/mailer"
+	"github.com/SanExpett/diploma/internal/passwords"
+	"github.com/SanExpett/diploma/internal/requestId"
+	"github.com/SanExpett/diploma/internal/tracing"
+)
+
+const passwordResetTokenTTL = time.Hour
+
+// RequestPasswordReset отправляет на email ссылку для восстановления пароля. Для незарегистрированного
+// адреса ошибка не возвращается, чтобы по ответу нельзя было узнать, есть ли такой пользователь
+func (service *UsersService) RequestPasswordReset(ctx context.Context, login string) error {
+	service.metrics.IncRequestsTotal("RequestPasswordReset")
+	token, link, err := newMailToken(service.resetURL)
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to generate reset token: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return err
+	}
+
+	err = service.storage.SavePasswordResetToken(login, hashToken(token),
+		time.Now().Add(passwordResetTokenTTL))
+	if errors.Is(err, myerrors.ErrNoSuchUser) {
+		service.logger.With(tracing.TraceField(ctx)).Infof("[reqid=%s] password reset requested for unknown user",
+			ctx.Value(requestId.ReqIDKey))
+		return nil
+	}
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to save reset token: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return err
+	}
+
+	err = service.mailer.Send(ctx, mailer.Message{
+		To:      login,
+		Subject: "Восстановление пароля",
+		Body: fmt.Sprintf("Чтобы задать новый пароль, перейдите по ссылке:\n%s\n\n"+
+			"Ссылка действует %d минут и работает один раз. Если вы не запрашивали восстановление, "+
+			"просто проигнорируйте это письмо.", link, int(passwordResetTokenTTL.Minutes())),
+	})
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to send reset mail: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return err
+	}
+	return nil
+}
+
+// ResetPassword меняет пароль по токену из письма и возвращает email пользователя
+func (service *UsersService) ResetPassword(ctx context.Context, token, newPassword string) (string, error) {
+	service.metrics.IncRequestsTotal("ResetPassword")
+	passwordHash, err := passwords.Hash(newPassword)
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to hash password: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return "", err
+	}
+
+	login, err := service.storage.ResetPassword(hashToken(token), passwordHash)
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to reset password: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return "", err
+	}
+
+	// сброс выполняет владелец почты, он еще не вошел
+	event := audit.NewEvent(ctx, audit.ActionPasswordReset, login, "")
+	event.Actor = login
+	service.recordAuditEvent(ctx, event)
+	return login, nil
+}
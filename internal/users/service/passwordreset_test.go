@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	myerrors "github.com/SanExpett/diploma/internal/errors"
+	"github.com/SanExpett/diploma/internal/mailer"
+	"github.com/SanExpett/diploma/internal/metrics"
+	"github.com/SanExpett/diploma/internal/passwords"
+	mockService "github.com/SanExpett/diploma/internal/users/mocks"
+)
+
+type recordingMailer struct {
+	messages []mailer.Message
+}
+
+func (recorder *recordingMailer) Send(_ context.Context, message mailer.Message) error {
+	recorder.messages = append(recorder.messages, message)
+	return nil
+}
+
+func TestUsersService_RequestPasswordReset(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mockService.NewMockusersStorage(ctrl)
+	recorder := &recordingMailer{}
+	usersService := NewUsersService(mockStorage, recorder, "https://nimbus.test/reset", "", "",
+		metrics.NewGrpcMetrics("users"), zaptest.NewLogger(t).Sugar())
+
+	var savedHash string
+	mockStorage.EXPECT().SavePasswordResetToken("test@test.com", gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ string, tokenHash string, expiresAt time.Time) error {
+			savedHash = tokenHash
+			assert.WithinDuration(t, time.Now().Add(passwordResetTokenTTL), expiresAt, time.Minute)
+			return nil
+		})
+
+	require.NoError(t, usersService.RequestPasswordReset(context.Background(), "test@test.com"))
+	require.Len(t, recorder.messages, 1)
+	assert.Equal(t, "test@test.com", recorder.messages[0].To)
+
+	linkStart := strings.Index(recorder.messages[0].Body, "https://")
+	require.NotEqual(t, -1, linkStart)
+	link, err := url.Parse(strings.Fields(recorder.messages[0].Body[linkStart:])[0])
+	require.NoError(t, err)
+	assert.Equal(t, "/reset", link.Path)
+	token := link.Query().Get("token")
+	assert.NotEqual(t, token, savedHash, "в базе хранится хеш токена")
+	assert.Equal(t, hashToken(token), savedHash)
+
+	mockStorage.EXPECT().SavePasswordResetToken("unknown@test.com", gomock.Any(), gomock.Any()).
+		Return(myerrors.ErrNoSuchUser)
+
+	assert.NoError(t, usersService.RequestPasswordReset(context.Background(), "unknown@test.com"),
+		"ответ не выдает, зарегистрирован ли адрес")
+	assert.Len(t, recorder.messages, 1)
+}
+
+func TestUsersService_ResetPassword(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mockService.NewMockusersStorage(ctrl)
+	usersService := NewUsersService(mockStorage, nil, "", "", "", metrics.NewGrpcMetrics("users"),
+		zaptest.NewLogger(t).Sugar())
+
+	mockStorage.EXPECT().ResetPassword(hashToken("token"), gomock.Any()).
+		DoAndReturn(func(_ string, passwordHash string) (string, error) {
+			ok, _, err := passwords.Verify("newPassword", passwordHash)
+			assert.NoError(t, err)
+			assert.True(t, ok)
+			return "test@test.com", nil
+		})
+	mockStorage.EXPECT().AddAuditEvent(gomock.Any()).Return(nil)
+
+	login, err := usersService.ResetPassword(context.Background(), "token", "newPassword")
+	assert.NoError(t, err)
+	assert.Equal(t, "test@test.com", login)
+
+	mockStorage.EXPECT().ResetPassword(hashToken("used"), gomock.Any()).
+		Return("", myerrors.ErrInvalidResetToken)
+
+	_, err = usersService.ResetPassword(context.Background(), "used", "newPassword")
+	assert.ErrorIs(t, err, myerrors.ErrInvalidResetToken)
+}
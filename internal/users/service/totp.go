@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	myerrors "github.com/SanExpett/diploma/internal/errors"
+	"github.com/SanExpett/diploma/internal/requestId"
+	"github.com/SanExpett/diploma/internal/totp"
+	"github.com/SanExpett/diploma/internal/tracing"
+)
+
+const (
+	totpIssuer = "Nimbus"
+
+	recoveryCodesCount = 10
+	// recoveryCodeLength длина кода восстановления без дефиса, в нем 48 случайных бит
+	recoveryCodeLength = 10
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// EnrollTOTP выдает новый секрет второго фактора и ссылку для QR кода. Второй фактор начинает
+// требоваться при входе только после подтверждения кодом из приложения
+func (service *UsersService) EnrollTOTP(ctx context.Context, login string) (string, string, error) {
+	service.metrics.IncRequestsTotal("EnrollTOTP")
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to generate totp secret: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return "", "", err
+	}
+
+	err = service.storage.SaveTOTPSecret(login, secret)
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to save totp secret: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return "", "", err
+	}
+
+	return secret, totp.ProvisioningURI(totpIssuer, login, secret), nil
+}
+
+// ConfirmTOTP включает второй фактор, если код из приложения подходит к выданному секрету,
+// и возвращает коды восстановления. Они показываются пользователю только один раз
+func (service *UsersService) ConfirmTOTP(ctx context.Context, login, code string) ([]string, error) {
+	service.metrics.IncRequestsTotal("ConfirmTOTP")
+	userTOTP, err := service.storage.GetTOTP(login)
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get totp: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return nil, err
+	}
+	if userTOTP.Confirmed {
+		return nil, myerrors.ErrTOTPAlreadyEnabled
+	}
+
+	step, ok, err := totp.Verify(userTOTP.Secret, code, time.Now())
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to verify totp code: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return nil, err
+	}
+	if !ok {
+		return nil, myerrors.ErrInvalidTOTPCode
+	}
+
+	recoveryCodes := make([]string, 0, recoveryCodesCount)
+	recoveryCodeHashes := make([]string, 0, recoveryCodesCount)
+	for i := 0; i < recoveryCodesCount; i++ {
+		recoveryCode, err := newRecoveryCode()
+		if err != nil {
+			service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to generate recovery code: %v",
+				ctx.Value(requestId.ReqIDKey), err)
+			return nil, err
+		}
+		recoveryCodes = append(recoveryCodes, recoveryCode)
+		recoveryCodeHashes = append(recoveryCodeHashes, hashToken(normalizeRecoveryCode(recoveryCode)))
+	}
+
+	err = service.storage.ConfirmTOTP(login, step, recoveryCodeHashes)
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to confirm totp: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return nil, err
+	}
+
+	return recoveryCodes, nil
+}
+
+// VerifyTOTP проверяет код из приложения или одноразовый код восстановления. Каждый код
+// принимается только один раз
+func (service *UsersService) VerifyTOTP(ctx context.Context, login, code string) error {
+	service.metrics.IncRequestsTotal("VerifyTOTP")
+	return service.verifyTOTP(ctx, login, code)
+}
+
+// DisableTOTP отключает второй фактор. Для этого нужен действующий код, чтобы второй фактор
+// не мог снять тот, кто узнал только пароль или завладел сессией
+func (service *UsersService) DisableTOTP(ctx context.Context, login, code string) error {
+	service.metrics.IncRequestsTotal("DisableTOTP")
+	err := service.verifyTOTP(ctx, login, code)
+	if err != nil {
+		return err
+	}
+
+	err = service.storage.DisableTOTP(login)
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to disable totp: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return err
+	}
+	return nil
+}
+
+func (service *UsersService) verifyTOTP(ctx context.Context, login, code string) error {
+	userTOTP, err := service.storage.GetTOTP(login)
+	if err != nil && !errors.Is(err, myerrors.ErrTOTPNotEnabled) {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to get totp: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return err
+	}
+	if err != nil || !userTOTP.Confirmed {
+		return myerrors.ErrTOTPNotEnabled
+	}
+
+	if len(code) != totp.Digits {
+		err = service.storage.UseRecoveryCode(login, hashToken(normalizeRecoveryCode(code)))
+		if err != nil {
+			service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to use recovery code: %v",
+				ctx.Value(requestId.ReqIDKey), err)
+			return err
+		}
+		service.logger.With(tracing.TraceField(ctx)).Infof("[reqid=%s] recovery code used", ctx.Value(requestId.ReqIDKey))
+		return nil
+	}
+
+	step, ok, err := totp.Verify(userTOTP.Secret, code, time.Now())
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to verify totp code: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return err
+	}
+	if !ok || step <= userTOTP.LastUsedStep {
+		return myerrors.ErrInvalidTOTPCode
+	}
+
+	err = service.storage.UseTOTPStep(login, step)
+	if err != nil {
+		service.logger.With(tracing.TraceField(ctx)).Errorf("[reqid=%s] failed to use totp step: %v",
+			ctx.Value(requestId.ReqIDKey), err)
+		return err
+	}
+	return nil
+}
+
+// newRecoveryCode создает код восстановления вида xxxxx-xxxxx
+func newRecoveryCode() (string, error) {
+	codeBytes := make([]byte, recoveryCodeLength*5/8)
+	_, err := rand.Read(codeBytes)
+	if err != nil {
+		return "", err
+	}
+	code := strings.ToLower(recoveryCodeEncoding.EncodeToString(codeBytes))
+	return code[:recoveryCodeLength/2] + "-" + code[recoveryCodeLength/2:], nil
+}
+
+// normalizeRecoveryCode приводит введенный пользователем код к виду, в котором хранится его хеш
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.ReplaceAll(code, "-", "")
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/SanExpett/diploma/internal/domain"
+	myerrors "github.com/SanExpett/diploma/internal/errors"
+	"github.com/SanExpett/diploma/internal/metrics"
+	"github.com/SanExpett/diploma/internal/totp"
+	mockService "github.com/SanExpett/diploma/internal/users/mocks"
+)
+
+func TestUsersService_ConfirmTOTP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mockService.NewMockusersStorage(ctrl)
+	usersService := NewUsersService(mockStorage, nil, "", "", "", metrics.NewGrpcMetrics("users"),
+		zaptest.NewLogger(t).Sugar())
+
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+	step := totp.Step(time.Now())
+	code, err := totp.Code(secret, step)
+	require.NoError(t, err)
+
+	var savedHashes []string
+	mockStorage.EXPECT().GetTOTP("test@test.com").Return(domain.TOTP{Secret: secret}, nil)
+	mockStorage.EXPECT().ConfirmTOTP("test@test.com", step, gomock.Any()).
+		DoAndReturn(func(_ string, _ int64, recoveryCodeHashes []string) error {
+			savedHashes = recoveryCodeHashes
+			return nil
+		})
+
+	recoveryCodes, err := usersService.ConfirmTOTP(context.Background(), "test@test.com", code)
+	require.NoError(t, err)
+	require.Len(t, recoveryCodes, recoveryCodesCount)
+	assert.Equal(t, hashToken(normalizeRecoveryCode(recoveryCodes[0])), savedHashes[0],
+		"в базе хранятся хеши кодов восстановления")
+
+	mockStorage.EXPECT().GetTOTP("test@test.com").Return(domain.TOTP{Secret: secret}, nil)
+
+	_, err = usersService.ConfirmTOTP(context.Background(), "test@test.com", "000000")
+	assert.ErrorIs(t, err, myerrors.ErrInvalidTOTPCode)
+
+	mockStorage.EXPECT().GetTOTP("test@test.com").Return(domain.TOTP{Secret: secret, Confirmed: true}, nil)
+
+	_, err = usersService.ConfirmTOTP(context.Background(), "test@test.com", code)
+	assert.ErrorIs(t, err, myerrors.ErrTOTPAlreadyEnabled)
+}
+
+func TestUsersService_VerifyTOTP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mockService.NewMockusersStorage(ctrl)
+	usersService := NewUsersService(mockStorage, nil, "", "", "", metrics.NewGrpcMetrics("users"),
+		zaptest.NewLogger(t).Sugar())
+
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+	step := totp.Step(time.Now())
+	code, err := totp.Code(secret, step)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		totp        domain.TOTP
+		totpErr     error
+		code        string
+		setupMocks  func()
+		expectedErr error
+	}{
+		{
+			name: "Верный код",
+			totp: domain.TOTP{Secret: secret, Confirmed: true},
+			code: code,
+			setupMocks: func() {
+				mockStorage.EXPECT().UseTOTPStep("test@test.com", step).Return(nil)
+			},
+		},
+		{
+			name:        "Код уже использован",
+			totp:        domain.TOTP{Secret: secret, Confirmed: true, LastUsedStep: step},
+			code:        code,
+			expectedErr: myerrors.ErrInvalidTOTPCode,
+		},
+		{
+			name:        "Неверный код",
+			totp:        domain.TOTP{Secret: secret, Confirmed: true},
+			code:        "000000",
+			expectedErr: myerrors.ErrInvalidTOTPCode,
+		},
+		{
+			name: "Код восстановления",
+			totp: domain.TOTP{Secret: secret, Confirmed: true},
+			code: "ABCDE-fghij",
+			setupMocks: func() {
+				mockStorage.EXPECT().UseRecoveryCode("test@test.com", hashToken("abcdefghij")).Return(nil)
+			},
+		},
+		{
+			name:        "Второй фактор не подтвержден",
+			totp:        domain.TOTP{Secret: secret},
+			code:        code,
+			expectedErr: myerrors.ErrTOTPNotEnabled,
+		},
+		{
+			name:        "Второй фактор не включен",
+			totpErr:     myerrors.ErrTOTPNotEnabled,
+			code:        code,
+			expectedErr: myerrors.ErrTOTPNotEnabled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage.EXPECT().GetTOTP("test@test.com").Return(tt.totp, tt.totpErr)
+			if tt.setupMocks != nil {
+				tt.setupMocks()
+			}
+
+			err := usersService.VerifyTOTP(context.Background(), "test@test.com", tt.code)
+			assert.ErrorIs(t, err, tt.expectedErr)
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// maxCBORDepth ограничивает вложенность, чтобы вредоносные данные не исчерпали стек
+const maxCBORDepth = 16
+
+var errCBORTruncated = errors.New("cbor: unexpected end of data")
+
+// decodeCBOR разбирает одно значение CBOR (RFC 8949) из начала data и возвращает его вместе с количеством
+// прочитанных байт. Поддерживается только то, что встречается в WebAuthn: целые числа, байтовые и текстовые
+// строки, массивы, словари и простые значения false, true и null. Неопределенная длина не поддерживается.
+// Целые числа возвращаются как int64, словари как map[any]any
+func decodeCBOR(data []byte) (any, int, error) {
+	return decodeCBORItem(data, 0)
+}
+
+func decodeCBORItem(data []byte, depth int) (any, int, error) {
+	if depth > maxCBORDepth {
+		return nil, 0, fmt.Errorf("cbor: nesting is too deep")
+	}
+	if len(data) == 0 {
+		return nil, 0, errCBORTruncated
+	}
+
+	majorType := data[0] >> 5
+	argument, offset, err := decodeCBORArgument(data)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	switch majorType {
+	case 0:
+		if argument > 1<<63-1 {
+			return nil, 0, fmt.Errorf("cbor: integer overflows int64")
+		}
+		return int64(argument), offset, nil
+	case 1:
+		if argument > 1<<63-1 {
+			return nil, 0, fmt.Errorf("cbor: integer overflows int64")
+		}
+		return -1 - int64(argument), offset, nil
+	case 2, 3:
+		if argument > uint64(len(data)-offset) {
+			return nil, 0, errCBORTruncated
+		}
+		end := offset + int(argument)
+		if majorType == 3 {
+			return string(data[offset:end]), end, nil
+		}
+		return append([]byte(nil), data[offset:end]...), end, nil
+	case 4:
+		// каждый элемент занимает хотя бы байт, поэтому длина больше оставшихся данных заведомо неверна
+		if argument > uint64(len(data)-offset) {
+			return nil, 0, errCBORTruncated
+		}
+		items := make([]any, 0, argument)
+		for i := uint64(0); i < argument; i++ {
+			item, n, err := decodeCBORItem(data[offset:], depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			items = append(items, item)
+			offset += n
+		}
+		return items, offset, nil
+	case 5:
+		if argument > uint64(len(data)-offset)/2 {
+			return nil, 0, errCBORTruncated
+		}
+		items := make(map[any]any, argument)
+		for i := uint64(0); i < argument; i++ {
+			key, n, err := decodeCBORItem(data[offset:], depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			offset += n
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, 0, fmt.Errorf("cbor: unsupported map key type %T", key)
+			}
+			if _, ok := items[key]; ok {
+				return nil, 0, fmt.Errorf("cbor: duplicate map key %v", key)
+			}
+
+			value, n, err := decodeCBORItem(data[offset:], depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			offset += n
+			items[key] = value
+		}
+		return items, offset, nil
+	case 7:
+		switch data[0] & 0x1f {
+		case 20:
+			return false, 1, nil
+		case 21:
+			return true, 1, nil
+		case 22:
+			return nil, 1, nil
+		}
+		return nil, 0, fmt.Errorf("cbor: unsupported simple value 0x%x", data[0])
+	}
+	return nil, 0, fmt.Errorf("cbor: unsupported major type %d", majorType)
+}
+
+// decodeCBORArgument читает аргумент заголовка: значение числа или длину строки, массива или словаря
+func decodeCBORArgument(data []byte) (uint64, int, error) {
+	additional := data[0] & 0x1f
+	switch {
+	case additional < 24:
+		return uint64(additional), 1, nil
+	case additional == 24:
+		if len(data) < 2 {
+			return 0, 0, errCBORTruncated
+		}
+		return uint64(data[1]), 2, nil
+	case additional == 25:
+		if len(data) < 3 {
+			return 0, 0, errCBORTruncated
+		}
+		return uint64(binary.BigEndian.Uint16(data[1:3])), 3, nil
+	case additional == 26:
+		if len(data) < 5 {
+			return 0, 0, errCBORTruncated
+		}
+		return uint64(binary.BigEndian.Uint32(data[1:5])), 5, nil
+	case additional == 27:
+		if len(data) < 9 {
+			return 0, 0, errCBORTruncated
+		}
+		return binary.BigEndian.Uint64(data[1:9]), 9, nil
+	}
+	return 0, 0, fmt.Errorf("cbor: indefinite length and reserved values are not supported")
+}
@@ -0,0 +1,70 @@
+package webauthn
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeCBOR(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		want    any
+		wantLen int
+		wantErr bool
+	}{
+		{
+			name:    "Отрицательное число",
+			data:    []byte{0x38, 0x18},
+			want:    int64(-25),
+			wantLen: 2,
+		},
+		{
+			name:    "Словарь с числовыми ключами",
+			data:    []byte{0xa2, 0x01, 0x02, 0x20, 0x42, 0xab, 0xcd, 0xff},
+			want:    map[any]any{int64(1): int64(2), int64(-1): []byte{0xab, 0xcd}},
+			wantLen: 7,
+		},
+		{
+			name:    "Строка и массив",
+			data:    []byte{0x82, 0x63, 'f', 'm', 't', 0xf5},
+			want:    []any{"fmt", true},
+			wantLen: 6,
+		},
+		{
+			name:    "Обрезанная байтовая строка",
+			data:    []byte{0x58, 0x20, 0x01},
+			wantErr: true,
+		},
+		{
+			name:    "Неопределенная длина",
+			data:    []byte{0x9f, 0x01, 0xff},
+			wantErr: true,
+		},
+		{
+			name:    "Повторяющийся ключ",
+			data:    []byte{0xa2, 0x01, 0x01, 0x01, 0x02},
+			wantErr: true,
+		},
+		{
+			name:    "Огромная длина массива",
+			data:    []byte{0x9b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, n, err := decodeCBOR(tt.data)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantLen, n)
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+)
+
+// Алгоритмы COSE (RFC 9053), которые мы принимаем для ключей passkey
+const (
+	AlgorithmES256 = -7
+	AlgorithmEdDSA = -8
+	AlgorithmRS256 = -257
+)
+
+// Параметры ключа COSE
+const (
+	coseKeyType      = 1
+	coseKeyAlgorithm = 3
+	coseKeyCurve     = -1
+	coseKeyX         = -2
+	coseKeyY         = -3
+	coseKeyRSAN      = -1
+	coseKeyRSAE      = -2
+
+	coseKeyTypeOKP = 1
+	coseKeyTypeEC2 = 2
+	coseKeyTypeRSA = 3
+
+	coseCurveP256    = 1
+	coseCurveEd25519 = 6
+)
+
+// minRSAKeyBits ключи короче не принимаются
+const minRSAKeyBits = 2048
+
+// publicKey открытый ключ credential вместе с алгоритмом, которым им подписываются assertion
+type publicKey struct {
+	algorithm int64
+	key       crypto.PublicKey
+}
+
+// parsePublicKey разбирает открытый ключ credential в формате COSE_Key
+func parsePublicKey(coseKey []byte) (publicKey, error) {
+	decoded, n, err := decodeCBOR(coseKey)
+	if err != nil {
+		return publicKey{}, err
+	}
+	if n != len(coseKey) {
+		return publicKey{}, fmt.Errorf("trailing data after COSE key")
+	}
+	return publicKeyFromCOSE(decoded)
+}
+
+func publicKeyFromCOSE(decoded any) (publicKey, error) {
+	params, ok := decoded.(map[any]any)
+	if !ok {
+		return publicKey{}, fmt.Errorf("COSE key is not a map")
+	}
+	keyType, _ := params[int64(coseKeyType)].(int64)
+	algorithm, _ := params[int64(coseKeyAlgorithm)].(int64)
+
+	switch {
+	case keyType == coseKeyTypeEC2 && algorithm == AlgorithmES256:
+		curve, _ := params[int64(coseKeyCurve)].(int64)
+		x, _ := params[int64(coseKeyX)].([]byte)
+		y, _ := params[int64(coseKeyY)].([]byte)
+		if curve != coseCurveP256 || len(x) != 32 || len(y) != 32 {
+			return publicKey{}, fmt.Errorf("invalid ES256 key")
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return publicKey{}, fmt.Errorf("ES256 key is not on curve")
+		}
+		return publicKey{algorithm: algorithm, key: key}, nil
+	case keyType == coseKeyTypeOKP && algorithm == AlgorithmEdDSA:
+		curve, _ := params[int64(coseKeyCurve)].(int64)
+		x, _ := params[int64(coseKeyX)].([]byte)
+		if curve != coseCurveEd25519 || len(x) != ed25519.PublicKeySize {
+			return publicKey{}, fmt.Errorf("invalid EdDSA key")
+		}
+		return publicKey{algorithm: algorithm, key: ed25519.PublicKey(x)}, nil
+	case keyType == coseKeyTypeRSA && algorithm == AlgorithmRS256:
+		n, _ := params[int64(coseKeyRSAN)].([]byte)
+		e, _ := params[int64(coseKeyRSAE)].([]byte)
+		if len(e) == 0 || len(e) > 4 {
+			return publicKey{}, fmt.Errorf("invalid RS256 key exponent")
+		}
+		key := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		if key.N.BitLen() < minRSAKeyBits {
+			return publicKey{}, fmt.Errorf("RS256 key is shorter than %d bits", minRSAKeyBits)
+		}
+		return publicKey{algorithm: algorithm, key: key}, nil
+	}
+	return publicKey{}, fmt.Errorf("unsupported COSE key type %d with algorithm %d", keyType, algorithm)
+}
+
+// verify проверяет подпись signature над signed
+func (key publicKey) verify(signed, signature []byte) error {
+	switch key.algorithm {
+	case AlgorithmES256:
+		hash := sha256.Sum256(signed)
+		if !ecdsa.VerifyASN1(key.key.(*ecdsa.PublicKey), hash[:], signature) {
+			return fmt.Errorf("invalid ES256 signature")
+		}
+		return nil
+	case AlgorithmEdDSA:
+		if !ed25519.Verify(key.key.(ed25519.PublicKey), signed, signature) {
+			return fmt.Errorf("invalid EdDSA signature")
+		}
+		return nil
+	case AlgorithmRS256:
+		hash := sha256.Sum256(signed)
+		return rsa.VerifyPKCS1v15(key.key.(*rsa.PublicKey), crypto.SHA256, hash[:], signature)
+	}
+	return fmt.Errorf("unsupported algorithm %d", key.algorithm)
+}
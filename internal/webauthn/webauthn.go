@@ -0,0 +1,440 @@
+// Package webauthn проверка регистрации и входа по passkey (WebAuthn Level 3) на стороне relying party.
+// Аттестация не запрашивается, поэтому attStmt не проверяется: сервер доверяет только подписям ключом
+// credential, полученным при регистрации
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// CeremonyTimeout сколько браузер ждет ответа аутентификатора, столько же живет выданный challenge
+const CeremonyTimeout = 5 * time.Minute
+
+const (
+	credentialType = "public-key"
+
+	clientDataTypeCreate = "webauthn.create"
+	clientDataTypeGet    = "webauthn.get"
+
+	// maxCredentialIdLength ограничение длины идентификатора credential из спецификации
+	maxCredentialIdLength = 1023
+)
+
+// Флаги authenticator data
+const (
+	flagUserPresent            = 0x01
+	flagUserVerified           = 0x04
+	flagAttestedCredentialData = 0x40
+	flagExtensionData          = 0x80
+)
+
+var ErrVerificationFailed = errors.New("webauthn verification failed")
+
+type Config struct {
+	// RPID домен, к которому привязаны passkey, например example.com
+	RPID   string
+	RPName string
+	// Origin origin фронтенда, с которого идут церемонии, например https://example.com
+	Origin string
+	// AllowCounterless принимает аутентификаторы без счетчика подписей, которые всегда присылают ноль.
+	// Клон такого ключа не обнаружить, а повтор ответа не пройдет, потому что challenge одноразовый
+	AllowCounterless bool
+}
+
+type RelyingPartyEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// UserEntity пользователь в запросе регистрации. ID user handle в base64url, аутентификатор возвращает
+// его при входе
+type UserEntity struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type CredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int64  `json:"alg"`
+}
+
+type CredentialDescriptor struct {
+	Type       string   `json:"type"`
+	ID         string   `json:"id"`
+	Transports []string `json:"transports,omitempty"`
+}
+
+type AuthenticatorSelection struct {
+	ResidentKey        string `json:"residentKey"`
+	RequireResidentKey bool   `json:"requireResidentKey"`
+	UserVerification   string `json:"userVerification"`
+}
+
+// CreationOptions параметры navigator.credentials.create в JSON виде (PublicKeyCredentialCreationOptionsJSON)
+type CreationOptions struct {
+	RP                     RelyingPartyEntity     `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	Challenge              string                 `json:"challenge"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// RequestOptions параметры navigator.credentials.get в JSON виде (PublicKeyCredentialRequestOptionsJSON).
+// Список credential пустой: passkey хранят user handle, поэтому пользователь выбирается на аутентификаторе
+type RequestOptions struct {
+	Challenge        string                 `json:"challenge"`
+	Timeout          int64                  `json:"timeout"`
+	RPID             string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+type AuthenticatorAttestationResponse struct {
+	ClientDataJSON    string   `json:"clientDataJSON"`
+	AttestationObject string   `json:"attestationObject"`
+	Transports        []string `json:"transports"`
+}
+
+// AttestationResponse результат navigator.credentials.create в JSON виде (RegistrationResponseJSON)
+type AttestationResponse struct {
+	ID       string                           `json:"id"`
+	RawID    string                           `json:"rawId"`
+	Type     string                           `json:"type"`
+	Response AuthenticatorAttestationResponse `json:"response"`
+}
+
+type AuthenticatorAssertionResponse struct {
+	ClientDataJSON    string `json:"clientDataJSON"`
+	AuthenticatorData string `json:"authenticatorData"`
+	Signature         string `json:"signature"`
+	UserHandle        string `json:"userHandle"`
+}
+
+// AssertionResponse результат navigator.credentials.get в JSON виде (AuthenticationResponseJSON)
+type AssertionResponse struct {
+	ID       string                         `json:"id"`
+	RawID    string                         `json:"rawId"`
+	Type     string                         `json:"type"`
+	Response AuthenticatorAssertionResponse `json:"response"`
+}
+
+// Credential зарегистрированный passkey. ID в base64url, PublicKey открытый ключ в формате COSE_Key
+type Credential struct {
+	ID         string
+	PublicKey  []byte
+	SignCount  uint32
+	Transports []string
+}
+
+type clientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+type authenticatorData struct {
+	rpIdHash     []byte
+	flags        byte
+	signCount    uint32
+	credentialId []byte
+	publicKey    []byte
+}
+
+type RelyingParty struct {
+	config Config
+}
+
+func NewRelyingParty(config Config) *RelyingParty {
+	return &RelyingParty{config: config}
+}
+
+// NewChallenge случайный challenge для одной церемонии в base64url
+func NewChallenge() (string, error) {
+	challenge := make([]byte, 32)
+	_, err := rand.Read(challenge)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(challenge), nil
+}
+
+// EncodeUserHandle user handle в том виде, в каком он передается в UserEntity.ID и возвращается при входе
+func EncodeUserHandle(userHandle []byte) string {
+	return base64.RawURLEncoding.EncodeToString(userHandle)
+}
+
+// CreationOptions параметры регистрации нового passkey. exclude уже зарегистрированные passkey
+// пользователя, чтобы аутентификатор не создавал второй ключ для того же аккаунта
+func (rp *RelyingParty) CreationOptions(user UserEntity, challenge string,
+	exclude []CredentialDescriptor) CreationOptions {
+	if exclude == nil {
+		exclude = []CredentialDescriptor{}
+	}
+	return CreationOptions{
+		RP:        RelyingPartyEntity{ID: rp.config.RPID, Name: rp.config.RPName},
+		User:      user,
+		Challenge: challenge,
+		PubKeyCredParams: []CredentialParameter{
+			{Type: credentialType, Alg: AlgorithmES256},
+			{Type: credentialType, Alg: AlgorithmEdDSA},
+			{Type: credentialType, Alg: AlgorithmRS256},
+		},
+		Timeout:            CeremonyTimeout.Milliseconds(),
+		ExcludeCredentials: exclude,
+		AuthenticatorSelection: AuthenticatorSelection{
+			ResidentKey:        "required",
+			RequireResidentKey: true,
+			UserVerification:   "required",
+		},
+		Attestation: "none",
+	}
+}
+
+// RequestOptions параметры входа по passkey
+func (rp *RelyingParty) RequestOptions(challenge string) RequestOptions {
+	return RequestOptions{
+		Challenge:        challenge,
+		Timeout:          CeremonyTimeout.Milliseconds(),
+		RPID:             rp.config.RPID,
+		AllowCredentials: []CredentialDescriptor{},
+		UserVerification: "required",
+	}
+}
+
+// VerifyRegistration проверяет ответ аутентификатора на CreationOptions с тем же challenge и возвращает
+// новый credential
+func (rp *RelyingParty) VerifyRegistration(response AttestationResponse, challenge string) (Credential, error) {
+	rawId, err := checkCredentialId(response.ID, response.RawID, response.Type)
+	if err != nil {
+		return Credential{}, err
+	}
+
+	clientDataJSON, err := decodeBase64URL(response.Response.ClientDataJSON)
+	if err != nil {
+		return Credential{}, fmt.Errorf("%w: invalid clientDataJSON encoding", ErrVerificationFailed)
+	}
+	err = rp.checkClientData(clientDataJSON, clientDataTypeCreate, challenge)
+	if err != nil {
+		return Credential{}, err
+	}
+
+	attestationObject, err := decodeBase64URL(response.Response.AttestationObject)
+	if err != nil {
+		return Credential{}, fmt.Errorf("%w: invalid attestationObject encoding", ErrVerificationFailed)
+	}
+	decoded, n, err := decodeCBOR(attestationObject)
+	if err != nil || n != len(attestationObject) {
+		return Credential{}, fmt.Errorf("%w: invalid attestation object: %v", ErrVerificationFailed, err)
+	}
+	attestation, ok := decoded.(map[any]any)
+	if !ok {
+		return Credential{}, fmt.Errorf("%w: attestation object is not a map", ErrVerificationFailed)
+	}
+	rawAuthData, ok := attestation["authData"].([]byte)
+	if !ok {
+		return Credential{}, fmt.Errorf("%w: attestation object has no authData", ErrVerificationFailed)
+	}
+
+	authData, err := rp.checkAuthenticatorData(rawAuthData)
+	if err != nil {
+		return Credential{}, err
+	}
+	if authData.flags&flagAttestedCredentialData == 0 {
+		return Credential{}, fmt.Errorf("%w: no attested credential data", ErrVerificationFailed)
+	}
+	if !bytes.Equal(authData.credentialId, rawId) {
+		return Credential{}, fmt.Errorf("%w: credential id mismatch", ErrVerificationFailed)
+	}
+	_, err = parsePublicKey(authData.publicKey)
+	if err != nil {
+		return Credential{}, fmt.Errorf("%w: %v", ErrVerificationFailed, err)
+	}
+
+	return Credential{
+		ID:         base64.RawURLEncoding.EncodeToString(rawId),
+		PublicKey:  authData.publicKey,
+		SignCount:  authData.signCount,
+		Transports: response.Response.Transports,
+	}, nil
+}
+
+// VerifyAssertion проверяет ответ аутентификатора на RequestOptions с тем же challenge подписью credential
+// и возвращает новое значение счетчика подписей. Счетчик, который не вырос, означает клон аутентификатора
+func (rp *RelyingParty) VerifyAssertion(response AssertionResponse, challenge string,
+	credential Credential) (uint32, error) {
+	rawId, err := checkCredentialId(response.ID, response.RawID, response.Type)
+	if err != nil {
+		return 0, err
+	}
+	if base64.RawURLEncoding.EncodeToString(rawId) != credential.ID {
+		return 0, fmt.Errorf("%w: credential id mismatch", ErrVerificationFailed)
+	}
+
+	clientDataJSON, err := decodeBase64URL(response.Response.ClientDataJSON)
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid clientDataJSON encoding", ErrVerificationFailed)
+	}
+	err = rp.checkClientData(clientDataJSON, clientDataTypeGet, challenge)
+	if err != nil {
+		return 0, err
+	}
+
+	rawAuthData, err := decodeBase64URL(response.Response.AuthenticatorData)
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid authenticatorData encoding", ErrVerificationFailed)
+	}
+	authData, err := rp.checkAuthenticatorData(rawAuthData)
+	if err != nil {
+		return 0, err
+	}
+
+	signature, err := decodeBase64URL(response.Response.Signature)
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid signature encoding", ErrVerificationFailed)
+	}
+	key, err := parsePublicKey(credential.PublicKey)
+	if err != nil {
+		return 0, fmt.Errorf("%w: stored key: %v", ErrVerificationFailed, err)
+	}
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(append([]byte(nil), rawAuthData...), clientDataHash[:]...)
+	err = key.verify(signed, signature)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrVerificationFailed, err)
+	}
+
+	counterless := authData.signCount == 0 && credential.SignCount == 0
+	if (!counterless || !rp.config.AllowCounterless) && authData.signCount <= credential.SignCount {
+		return 0, fmt.Errorf("%w: sign count did not increase, authenticator may be cloned", ErrVerificationFailed)
+	}
+	return authData.signCount, nil
+}
+
+// UserHandle user handle из ответа на вход, пустой, если аутентификатор его не вернул
+func (response AssertionResponse) UserHandle() ([]byte, error) {
+	if response.Response.UserHandle == "" {
+		return nil, nil
+	}
+	userHandle, err := decodeBase64URL(response.Response.UserHandle)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid userHandle encoding", ErrVerificationFailed)
+	}
+	return userHandle, nil
+}
+
+func checkCredentialId(id, rawId, responseType string) ([]byte, error) {
+	if responseType != credentialType {
+		return nil, fmt.Errorf("%w: unexpected credential type %q", ErrVerificationFailed, responseType)
+	}
+	decoded, err := decodeBase64URL(rawId)
+	if err != nil || len(decoded) == 0 || len(decoded) > maxCredentialIdLength {
+		return nil, fmt.Errorf("%w: invalid credential id", ErrVerificationFailed)
+	}
+	if id != base64.RawURLEncoding.EncodeToString(decoded) {
+		return nil, fmt.Errorf("%w: id does not match rawId", ErrVerificationFailed)
+	}
+	return decoded, nil
+}
+
+func (rp *RelyingParty) checkClientData(clientDataJSON []byte, expectedType, challenge string) error {
+	var data clientData
+	err := json.Unmarshal(clientDataJSON, &data)
+	if err != nil {
+		return fmt.Errorf("%w: invalid clientDataJSON: %v", ErrVerificationFailed, err)
+	}
+	if data.Type != expectedType {
+		return fmt.Errorf("%w: unexpected client data type %q", ErrVerificationFailed, data.Type)
+	}
+	if challenge == "" || subtle.ConstantTimeCompare([]byte(strings.TrimRight(data.Challenge, "=")),
+		[]byte(challenge)) != 1 {
+		return fmt.Errorf("%w: challenge mismatch", ErrVerificationFailed)
+	}
+	if data.Origin != rp.config.Origin || data.CrossOrigin {
+		return fmt.Errorf("%w: unexpected origin %q", ErrVerificationFailed, data.Origin)
+	}
+	return nil
+}
+
+// checkAuthenticatorData разбирает authenticator data и проверяет, что подпись сделана для нашего RP ID
+// и пользователь подтвердил ее биометрией или PIN
+func (rp *RelyingParty) checkAuthenticatorData(data []byte) (authenticatorData, error) {
+	authData, err := parseAuthenticatorData(data)
+	if err != nil {
+		return authenticatorData{}, fmt.Errorf("%w: %v", ErrVerificationFailed, err)
+	}
+	rpIdHash := sha256.Sum256([]byte(rp.config.RPID))
+	if subtle.ConstantTimeCompare(authData.rpIdHash, rpIdHash[:]) != 1 {
+		return authenticatorData{}, fmt.Errorf("%w: rp id hash mismatch", ErrVerificationFailed)
+	}
+	if authData.flags&flagUserPresent == 0 || authData.flags&flagUserVerified == 0 {
+		return authenticatorData{}, fmt.Errorf("%w: user was not verified", ErrVerificationFailed)
+	}
+	return authData, nil
+}
+
+func parseAuthenticatorData(data []byte) (authenticatorData, error) {
+	if len(data) < 37 {
+		return authenticatorData{}, fmt.Errorf("authenticator data is too short")
+	}
+	authData := authenticatorData{
+		rpIdHash:  data[:32],
+		flags:     data[32],
+		signCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+	offset := 37
+
+	if authData.flags&flagAttestedCredentialData != 0 {
+		// aaguid аутентификатора не используется, поэтому пропускается
+		if len(data) < offset+18 {
+			return authenticatorData{}, fmt.Errorf("attested credential data is too short")
+		}
+		offset += 16
+		idLength := int(binary.BigEndian.Uint16(data[offset : offset+2]))
+		offset += 2
+		if idLength == 0 || idLength > maxCredentialIdLength || len(data) < offset+idLength {
+			return authenticatorData{}, fmt.Errorf("invalid credential id length")
+		}
+		authData.credentialId = data[offset : offset+idLength]
+		offset += idLength
+
+		_, n, err := decodeCBOR(data[offset:])
+		if err != nil {
+			return authenticatorData{}, fmt.Errorf("invalid credential public key: %w", err)
+		}
+		authData.publicKey = data[offset : offset+n]
+		offset += n
+	}
+
+	if authData.flags&flagExtensionData != 0 {
+		_, n, err := decodeCBOR(data[offset:])
+		if err != nil {
+			return authenticatorData{}, fmt.Errorf("invalid extensions: %w", err)
+		}
+		offset += n
+	}
+
+	if offset != len(data) {
+		return authenticatorData{}, fmt.Errorf("trailing data after authenticator data")
+	}
+	return authData, nil
+}
+
+// decodeBase64URL принимает base64url как без выравнивания, так и с ним
+func decodeBase64URL(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+}
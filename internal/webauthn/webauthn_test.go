@@ -0,0 +1,183 @@
+package webauthn_test
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/SanExpett/diploma/internal/webauthn"
+	"github.com/SanExpett/diploma/internal/webauthn/webauthntest"
+)
+
+const origin = "https://nimbus.test"
+
+func newRelyingParty() *webauthn.RelyingParty {
+	return webauthn.NewRelyingParty(webauthn.Config{RPID: "nimbus.test", RPName: "Nimbus", Origin: origin})
+}
+
+func newCounterlessRelyingParty() *webauthn.RelyingParty {
+	return webauthn.NewRelyingParty(webauthn.Config{RPID: "nimbus.test", RPName: "Nimbus", Origin: origin,
+		AllowCounterless: true})
+}
+
+// register регистрирует passkey программным аутентификатором и возвращает сохраненный credential
+func register(t *testing.T, rp *webauthn.RelyingParty, authenticator *webauthntest.Authenticator) webauthn.Credential {
+	challenge, err := webauthn.NewChallenge()
+	require.NoError(t, err)
+	options := rp.CreationOptions(webauthn.UserEntity{ID: webauthn.EncodeUserHandle([]byte("user-uuid")),
+		Name: "user@example.com", DisplayName: "User"}, challenge, nil)
+
+	response, err := authenticator.Register(options)
+	require.NoError(t, err)
+
+	credential, err := rp.VerifyRegistration(response, challenge)
+	require.NoError(t, err)
+	return credential
+}
+
+func TestRelyingParty_Registration(t *testing.T) {
+	rp := newRelyingParty()
+	authenticator := webauthntest.New(origin)
+
+	credential := register(t, rp, authenticator)
+
+	assert.Equal(t, base64.RawURLEncoding.EncodeToString(authenticator.Credentials()[0].ID), credential.ID)
+	assert.NotEmpty(t, credential.PublicKey)
+	assert.Equal(t, uint32(0), credential.SignCount)
+	assert.Equal(t, []string{"internal"}, credential.Transports)
+
+	tests := []struct {
+		name      string
+		origin    string
+		challenge string
+		rpId      string
+	}{
+		{
+			name:      "Чужой challenge",
+			origin:    origin,
+			challenge: "other",
+			rpId:      "nimbus.test",
+		},
+		{
+			name:      "Фишинговый origin",
+			origin:    "https://nimbus.example",
+			challenge: "challenge",
+			rpId:      "nimbus.test",
+		},
+		{
+			name:      "Другой RP ID",
+			origin:    origin,
+			challenge: "challenge",
+			rpId:      "nimbus.example",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := rp.CreationOptions(webauthn.UserEntity{ID: webauthn.EncodeUserHandle([]byte("user-uuid"))},
+				"challenge", nil)
+			options.RP.ID = tt.rpId
+
+			response, err := webauthntest.New(tt.origin).Register(options)
+			require.NoError(t, err)
+
+			_, err = rp.VerifyRegistration(response, tt.challenge)
+			assert.ErrorIs(t, err, webauthn.ErrVerificationFailed)
+		})
+	}
+}
+
+func TestRelyingParty_Assertion(t *testing.T) {
+	rp := newRelyingParty()
+	authenticator := webauthntest.New(origin)
+	credential := register(t, rp, authenticator)
+
+	challenge, err := webauthn.NewChallenge()
+	require.NoError(t, err)
+	response, err := authenticator.Login(rp.RequestOptions(challenge))
+	require.NoError(t, err)
+
+	userHandle, err := response.UserHandle()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("user-uuid"), userHandle)
+
+	signCount, err := rp.VerifyAssertion(response, challenge, credential)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1), signCount)
+
+	_, err = rp.VerifyAssertion(response, "other-challenge", credential)
+	assert.ErrorIs(t, err, webauthn.ErrVerificationFailed, "ответ привязан к challenge")
+
+	credential.SignCount = signCount
+	_, err = rp.VerifyAssertion(response, challenge, credential)
+	assert.ErrorIs(t, err, webauthn.ErrVerificationFailed, "повтор ответа с тем же счетчиком отклоняется")
+
+	tampered := response
+	tampered.Response.Signature = base64.RawURLEncoding.EncodeToString([]byte("not a signature"))
+	_, err = rp.VerifyAssertion(tampered, challenge, webauthn.Credential{ID: credential.ID,
+		PublicKey: credential.PublicKey})
+	assert.ErrorIs(t, err, webauthn.ErrVerificationFailed)
+
+	other := register(t, rp, webauthntest.New(origin))
+	_, err = rp.VerifyAssertion(response, challenge, webauthn.Credential{ID: credential.ID,
+		PublicKey: other.PublicKey})
+	assert.ErrorIs(t, err, webauthn.ErrVerificationFailed, "подпись проверяется ключом из регистрации")
+}
+
+func TestRelyingParty_Counterless(t *testing.T) {
+	tests := []struct {
+		name          string
+		rp            *webauthn.RelyingParty
+		counterless   bool
+		storedCount   uint32
+		expectedError bool
+	}{
+		{
+			name:          "Ключ без счетчика разрешен",
+			rp:            newCounterlessRelyingParty(),
+			counterless:   true,
+			expectedError: false,
+		},
+		{
+			name:          "Ключ без счетчика запрещен",
+			rp:            newRelyingParty(),
+			counterless:   true,
+			expectedError: true,
+		},
+		{
+			name:          "Счетчик сброшен в ноль",
+			rp:            newCounterlessRelyingParty(),
+			counterless:   true,
+			storedCount:   5,
+			expectedError: true,
+		},
+		{
+			name:          "Счетчик растет",
+			rp:            newRelyingParty(),
+			expectedError: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authenticator := webauthntest.New(origin)
+			authenticator.Counterless = tt.counterless
+			credential := register(t, tt.rp, authenticator)
+			credential.SignCount = tt.storedCount
+
+			challenge, err := webauthn.NewChallenge()
+			require.NoError(t, err)
+			response, err := authenticator.Login(tt.rp.RequestOptions(challenge))
+			require.NoError(t, err)
+
+			_, err = tt.rp.VerifyAssertion(response, challenge, credential)
+			if tt.expectedError {
+				assert.ErrorIs(t, err, webauthn.ErrVerificationFailed)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
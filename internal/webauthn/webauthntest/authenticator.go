@@ -0,0 +1,211 @@
+// Package webauthntest программный аутентификатор для тестов входа по passkey. Он создает ES256 ключи,
+// хранит их как discoverable credential и отвечает на церемонии так же, как браузер с аутентификатором
+package webauthntest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/SanExpett/diploma/internal/webauthn"
+)
+
+const (
+	flagUserPresent            = 0x01
+	flagUserVerified           = 0x04
+	flagAttestedCredentialData = 0x40
+)
+
+// Credential ключ, созданный аутентификатором. SignCount увеличивается при каждом входе
+type Credential struct {
+	ID         []byte
+	UserHandle []byte
+	PrivateKey *ecdsa.PrivateKey
+	SignCount  uint32
+}
+
+type Authenticator struct {
+	// Origin origin страницы, от имени которой браузер вызывает аутентификатор
+	Origin string
+	// Counterless аутентификатор без счетчика подписей, при входе всегда присылает ноль
+	Counterless bool
+
+	mu          sync.Mutex
+	credentials []*Credential
+}
+
+func New(origin string) *Authenticator {
+	return &Authenticator{Origin: origin}
+}
+
+// Credentials созданные аутентификатором ключи, их можно менять, например, чтобы сбросить счетчик
+func (authenticator *Authenticator) Credentials() []*Credential {
+	authenticator.mu.Lock()
+	defer authenticator.mu.Unlock()
+	return append([]*Credential(nil), authenticator.credentials...)
+}
+
+// Register выполняет navigator.credentials.create с параметрами options
+func (authenticator *Authenticator) Register(options webauthn.CreationOptions) (webauthn.AttestationResponse, error) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return webauthn.AttestationResponse{}, err
+	}
+	credentialId := make([]byte, 16)
+	_, err = rand.Read(credentialId)
+	if err != nil {
+		return webauthn.AttestationResponse{}, err
+	}
+	userHandle, err := base64.RawURLEncoding.DecodeString(options.User.ID)
+	if err != nil {
+		return webauthn.AttestationResponse{}, fmt.Errorf("invalid user id: %w", err)
+	}
+
+	credential := &Credential{ID: credentialId, UserHandle: userHandle, PrivateKey: privateKey}
+	authenticator.mu.Lock()
+	authenticator.credentials = append(authenticator.credentials, credential)
+	authenticator.mu.Unlock()
+
+	// credential id, открытый ключ и счетчик, aaguid нулевой
+	authData := authenticatorData(options.RP.ID, flagUserPresent|flagUserVerified|flagAttestedCredentialData, 0)
+	authData = append(authData, make([]byte, 16)...)
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(credentialId)))
+	authData = append(authData, credentialId...)
+	authData = append(authData, encodeCBOR(map[int64]any{
+		1:  int64(2),
+		3:  int64(webauthn.AlgorithmES256),
+		-1: int64(1),
+		-2: privateKey.X.FillBytes(make([]byte, 32)),
+		-3: privateKey.Y.FillBytes(make([]byte, 32)),
+	})...)
+
+	clientDataJSON, err := authenticator.clientData("webauthn.create", options.Challenge)
+	if err != nil {
+		return webauthn.AttestationResponse{}, err
+	}
+
+	attestationObject := encodeCBOR(map[string]any{
+		"fmt":      "none",
+		"attStmt":  map[string]any{},
+		"authData": authData,
+	})
+
+	id := base64.RawURLEncoding.EncodeToString(credentialId)
+	return webauthn.AttestationResponse{
+		ID:    id,
+		RawID: id,
+		Type:  "public-key",
+		Response: webauthn.AuthenticatorAttestationResponse{
+			ClientDataJSON:    base64.RawURLEncoding.EncodeToString(clientDataJSON),
+			AttestationObject: base64.RawURLEncoding.EncodeToString(attestationObject),
+			Transports:        []string{"internal"},
+		},
+	}, nil
+}
+
+// Login выполняет navigator.credentials.get с параметрами options последним созданным ключом
+func (authenticator *Authenticator) Login(options webauthn.RequestOptions) (webauthn.AssertionResponse, error) {
+	authenticator.mu.Lock()
+	if len(authenticator.credentials) == 0 {
+		authenticator.mu.Unlock()
+		return webauthn.AssertionResponse{}, fmt.Errorf("authenticator has no credentials")
+	}
+	credential := authenticator.credentials[len(authenticator.credentials)-1]
+	if !authenticator.Counterless {
+		credential.SignCount++
+	}
+	signCount := credential.SignCount
+	authenticator.mu.Unlock()
+
+	authData := authenticatorData(options.RPID, flagUserPresent|flagUserVerified, signCount)
+	clientDataJSON, err := authenticator.clientData("webauthn.get", options.Challenge)
+	if err != nil {
+		return webauthn.AssertionResponse{}, err
+	}
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signedHash := sha256.Sum256(append(append([]byte(nil), authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, credential.PrivateKey, signedHash[:])
+	if err != nil {
+		return webauthn.AssertionResponse{}, err
+	}
+
+	id := base64.RawURLEncoding.EncodeToString(credential.ID)
+	return webauthn.AssertionResponse{
+		ID:    id,
+		RawID: id,
+		Type:  "public-key",
+		Response: webauthn.AuthenticatorAssertionResponse{
+			ClientDataJSON:    base64.RawURLEncoding.EncodeToString(clientDataJSON),
+			AuthenticatorData: base64.RawURLEncoding.EncodeToString(authData),
+			Signature:         base64.RawURLEncoding.EncodeToString(signature),
+			UserHandle:        base64.RawURLEncoding.EncodeToString(credential.UserHandle),
+		},
+	}, nil
+}
+
+func (authenticator *Authenticator) clientData(ceremonyType, challenge string) ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"type":        ceremonyType,
+		"challenge":   challenge,
+		"origin":      authenticator.Origin,
+		"crossOrigin": false,
+	})
+}
+
+func authenticatorData(rpId string, flags byte, signCount uint32) []byte {
+	rpIdHash := sha256.Sum256([]byte(rpId))
+	authData := append(rpIdHash[:], flags)
+	return binary.BigEndian.AppendUint32(authData, signCount)
+}
+
+// encodeCBOR кодирует значения, из которых состоят ответы аутентификатора. Порядок ключей словаря
+// не важен для разбора, поэтому канонической сортировки нет
+func encodeCBOR(value any) []byte {
+	switch typed := value.(type) {
+	case int64:
+		if typed < 0 {
+			return cborHeader(1, uint64(-1-typed))
+		}
+		return cborHeader(0, uint64(typed))
+	case []byte:
+		return append(cborHeader(2, uint64(len(typed))), typed...)
+	case string:
+		return append(cborHeader(3, uint64(len(typed))), typed...)
+	case map[int64]any:
+		encoded := cborHeader(5, uint64(len(typed)))
+		for key, item := range typed {
+			encoded = append(encoded, encodeCBOR(key)...)
+			encoded = append(encoded, encodeCBOR(item)...)
+		}
+		return encoded
+	case map[string]any:
+		encoded := cborHeader(5, uint64(len(typed)))
+		for key, item := range typed {
+			encoded = append(encoded, encodeCBOR(key)...)
+			encoded = append(encoded, encodeCBOR(item)...)
+		}
+		return encoded
+	}
+	panic(fmt.Sprintf("webauthntest: unsupported cbor type %T", value))
+}
+
+func cborHeader(majorType byte, argument uint64) []byte {
+	switch {
+	case argument < 24:
+		return []byte{majorType<<5 | byte(argument)}
+	case argument <= 0xff:
+		return []byte{majorType<<5 | 24, byte(argument)}
+	case argument <= 0xffff:
+		return binary.BigEndian.AppendUint16([]byte{majorType<<5 | 25}, uint16(argument))
+	case argument <= 0xffffffff:
+		return binary.BigEndian.AppendUint32([]byte{majorType<<5 | 26}, uint32(argument))
+	}
+	return binary.BigEndian.AppendUint64([]byte{majorType<<5 | 27}, argument)
+}